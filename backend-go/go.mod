@@ -3,6 +3,7 @@ module github.com/BenedictKing/claude-proxy
 go 1.22
 
 require (
+	github.com/coder/websocket v1.8.12
 	github.com/fsnotify/fsnotify v1.7.0
 	github.com/gin-gonic/gin v1.10.0
 	github.com/google/uuid v1.6.0
@@ -0,0 +1,760 @@
+// Package batch 在代理内模拟 Anthropic Message Batches API
+//
+// 批次在本地排队，每个请求通过 Messages 处理器（含渠道调度与故障转移）重放，
+// 全局并发受限；批次元信息、请求与结果以 JSON/JSONL 文件持久化，进程重启后使用创建者的请求头继续处理未完成的请求。
+// 已结束的批次在创建后保留 29 天，到期后自动清理。
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// MaxRequestsPerBatch 单个批次最大请求数（与 Anthropic 限制一致）
+	MaxRequestsPerBatch = 100000
+	// batchTTL 批次过期时间，超过后未处理的请求记为 expired
+	batchTTL = 24 * time.Hour
+	// resultsRetention 批次保留时长（自创建起算，与 Anthropic 一致），到期后已结束的批次及其文件被清理
+	resultsRetention = 29 * 24 * time.Hour
+	// cleanupInterval 过期批次清理间隔
+	cleanupInterval = time.Hour
+
+	StatusInProgress = "in_progress"
+	StatusCanceling  = "canceling"
+	StatusEnded      = "ended"
+
+	ResultSucceeded = "succeeded"
+	ResultErrored   = "errored"
+	ResultCanceled  = "canceled"
+	ResultExpired   = "expired"
+)
+
+var (
+	ErrNotFound = errors.New("batch not found")
+	ErrNotEnded = errors.New("batch has not ended")
+
+	customIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+)
+
+// ValidationError 批次请求校验失败
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+// Request 批次中的单个请求
+type Request struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// RequestCounts 各处理状态的请求数
+type RequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// Batch Message Batch 对象（字段与 Anthropic API 一致）
+type Batch struct {
+	ID                string        `json:"id"`
+	Type              string        `json:"type"`
+	ProcessingStatus  string        `json:"processing_status"`
+	RequestCounts     RequestCounts `json:"request_counts"`
+	EndedAt           *time.Time    `json:"ended_at"`
+	CreatedAt         time.Time     `json:"created_at"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	ArchivedAt        *time.Time    `json:"archived_at"`
+	CancelInitiatedAt *time.Time    `json:"cancel_initiated_at"`
+	ResultsURL        *string       `json:"results_url"`
+}
+
+// Result 单个请求的处理结果
+type Result struct {
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+type resultLine struct {
+	CustomID string `json:"custom_id"`
+	Result   Result `json:"result"`
+}
+
+type entry struct {
+	mu       sync.Mutex
+	batch    Batch
+	requests []Request
+	header   http.Header
+	canceled bool
+}
+
+// Manager 批次管理器
+type Manager struct {
+	mu        sync.RWMutex
+	dir       string
+	handler   http.Handler
+	retention time.Duration
+	sem       chan struct{}
+	batches   map[string]*entry
+	wg        sync.WaitGroup
+}
+
+// NewManager 创建批次管理器，恢复 dir 中未完成的批次并启动过期批次清理
+// handler 为 POST /v1/messages 的处理器
+func NewManager(dir string, concurrency int, handler http.Handler) (*Manager, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建批次目录失败: %w", err)
+	}
+
+	m := &Manager{
+		dir:       dir,
+		handler:   handler,
+		retention: resultsRetention,
+		sem:       make(chan struct{}, concurrency),
+		batches:   make(map[string]*entry),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	m.purgeExpired()
+	go m.cleanupLoop()
+	return m, nil
+}
+
+// Create 创建批次并开始后台处理
+func (m *Manager) Create(requests []Request, header http.Header) (*Batch, error) {
+	if err := validateRequests(requests); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	e := &entry{
+		batch: Batch{
+			ID:               "msgbatch_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+			Type:             "message_batch",
+			ProcessingStatus: StatusInProgress,
+			RequestCounts:    RequestCounts{Processing: len(requests)},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchTTL),
+		},
+		requests: requests,
+		header:   sanitizeHeader(header),
+	}
+
+	if err := m.writeRequests(e); err != nil {
+		return nil, err
+	}
+	if err := m.writeHeader(e); err != nil {
+		return nil, err
+	}
+	if err := m.writeMeta(e); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.batches[e.batch.ID] = e
+	m.mu.Unlock()
+
+	log.Printf("[Batch-Create] 已创建批次 %s (%d 个请求)", e.batch.ID, len(requests))
+	m.start(e, nil)
+
+	b := e.snapshot()
+	return &b, nil
+}
+
+// Get 获取批次
+func (m *Manager) Get(id string) (*Batch, error) {
+	e := m.get(id)
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	b := e.snapshot()
+	return &b, nil
+}
+
+// List 按创建时间倒序分页列出批次
+// afterID: 返回该批次之后（更早）的一页；beforeID: 返回该批次之前（更新）的一页
+func (m *Manager) List(limit int, beforeID, afterID string) ([]Batch, bool) {
+	m.mu.RLock()
+	all := make([]Batch, 0, len(m.batches))
+	for _, e := range m.batches {
+		all = append(all, e.snapshot())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID > all[j].ID
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+
+	indexOf := func(id string) int {
+		for i := range all {
+			if all[i].ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start, end := 0, len(all)
+	switch {
+	case afterID != "":
+		if idx := indexOf(afterID); idx >= 0 {
+			start = idx + 1
+		}
+	case beforeID != "":
+		if idx := indexOf(beforeID); idx >= 0 {
+			end = idx
+			if end-limit > 0 {
+				start = end - limit
+			}
+			return all[start:end], start > 0
+		}
+	}
+	if end-start > limit {
+		return all[start : start+limit], true
+	}
+	return all[start:end], false
+}
+
+// Cancel 取消批次：尚未发送的请求记为 canceled，已在处理中的请求继续完成
+func (m *Manager) Cancel(id string) (*Batch, error) {
+	e := m.get(id)
+	if e == nil {
+		return nil, ErrNotFound
+	}
+
+	e.mu.Lock()
+	if e.batch.ProcessingStatus == StatusInProgress {
+		now := time.Now().UTC()
+		e.canceled = true
+		e.batch.ProcessingStatus = StatusCanceling
+		e.batch.CancelInitiatedAt = &now
+		log.Printf("[Batch-Cancel] 批次 %s 已请求取消", id)
+	}
+	b := e.batch
+	e.mu.Unlock()
+
+	if err := m.writeMeta(e); err != nil {
+		log.Printf("[Batch-Cancel] 警告: 保存批次 %s 失败: %v", id, err)
+	}
+	return &b, nil
+}
+
+// Delete 删除已结束的批次及其结果文件
+func (m *Manager) Delete(id string) error {
+	e := m.get(id)
+	if e == nil {
+		return ErrNotFound
+	}
+	if e.snapshot().ProcessingStatus != StatusEnded {
+		return ErrNotEnded
+	}
+
+	m.mu.Lock()
+	delete(m.batches, id)
+	m.mu.Unlock()
+
+	m.removeFiles(id)
+	return nil
+}
+
+// removeFiles 删除批次的全部持久化文件
+func (m *Manager) removeFiles(id string) {
+	for _, p := range []string{m.metaPath(id), m.requestsPath(id), m.headerPath(id), m.resultsPath(id)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.Printf("[Batch-Delete] 警告: 删除文件 %s 失败: %v", p, err)
+		}
+	}
+}
+
+// cleanupLoop 定期清理过期批次
+func (m *Manager) cleanupLoop() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.purgeExpired()
+	}
+}
+
+// purgeExpired 清理超过保留期的已结束批次及其结果文件
+func (m *Manager) purgeExpired() {
+	cutoff := time.Now().Add(-m.retention)
+
+	var expired []string
+	m.mu.Lock()
+	for id, e := range m.batches {
+		b := e.snapshot()
+		if b.ProcessingStatus == StatusEnded && b.CreatedAt.Before(cutoff) {
+			delete(m.batches, id)
+			expired = append(expired, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range expired {
+		m.removeFiles(id)
+	}
+	if len(expired) > 0 {
+		log.Printf("[Batch-Cleanup] 已清理 %d 个过期批次", len(expired))
+	}
+}
+
+// OpenResults 打开已结束批次的 JSONL 结果文件
+func (m *Manager) OpenResults(id string) (io.ReadCloser, error) {
+	e := m.get(id)
+	if e == nil {
+		return nil, ErrNotFound
+	}
+	if e.snapshot().ProcessingStatus != StatusEnded {
+		return nil, ErrNotEnded
+	}
+	f, err := os.Open(m.resultsPath(id))
+	if os.IsNotExist(err) {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	return f, err
+}
+
+// Wait 等待所有批次处理完成（用于优雅关闭与测试）
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) get(id string) *entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.batches[id]
+}
+
+func (e *entry) snapshot() Batch {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.batch
+}
+
+// start 后台处理批次中尚未完成的请求（done 为已有结果的 custom_id）
+func (m *Manager) start(e *entry, done map[string]bool) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		var inflight sync.WaitGroup
+		for _, req := range e.requests {
+			if done[req.CustomID] {
+				continue
+			}
+
+			m.sem <- struct{}{}
+			if skipType := e.skipType(); skipType != "" {
+				<-m.sem
+				m.recordResult(e, req.CustomID, Result{Type: skipType})
+				continue
+			}
+
+			inflight.Add(1)
+			go func(req Request) {
+				defer inflight.Done()
+				defer func() { <-m.sem }()
+				m.recordResult(e, req.CustomID, m.replay(e, req))
+			}(req)
+		}
+		inflight.Wait()
+		m.finish(e)
+	}()
+}
+
+// skipType 返回未发送请求应记录的结果类型（取消/过期），正常处理返回空字符串
+func (e *entry) skipType() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.canceled {
+		return ResultCanceled
+	}
+	if time.Now().After(e.batch.ExpiresAt) {
+		return ResultExpired
+	}
+	return ""
+}
+
+// replay 通过 Messages 处理器重放单个请求（强制非流式）
+func (m *Manager) replay(e *entry, req Request) Result {
+	body, err := sjson.DeleteBytes(req.Params, "stream")
+	if err != nil {
+		body = req.Params
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return erroredResult(http.StatusInternalServerError, []byte(err.Error()))
+	}
+	httpReq.Header = e.header.Clone()
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	w := newResponseRecorder()
+	m.handler.ServeHTTP(w, httpReq)
+
+	respBody := w.body.Bytes()
+	if w.status >= 200 && w.status < 300 && gjson.ValidBytes(respBody) {
+		return Result{Type: ResultSucceeded, Message: json.RawMessage(respBody)}
+	}
+	return erroredResult(w.status, respBody)
+}
+
+// erroredResult 将失败响应整理为 Anthropic 错误对象
+func erroredResult(status int, body []byte) Result {
+	if gjson.ValidBytes(body) {
+		root := gjson.ParseBytes(body)
+		if root.Get("type").String() == "error" && root.Get("error").IsObject() {
+			return Result{Type: ResultErrored, Error: json.RawMessage(body)}
+		}
+		if errObj := root.Get("error"); errObj.IsObject() {
+			out, _ := sjson.SetRawBytes([]byte(`{"type":"error"}`), "error", []byte(errObj.Raw))
+			return Result{Type: ResultErrored, Error: out}
+		}
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = fmt.Sprintf("http status %d", status)
+	}
+	out := []byte(`{"type":"error","error":{}}`)
+	out, _ = sjson.SetBytes(out, "error.type", errorTypeForStatus(status))
+	out, _ = sjson.SetBytes(out, "error.message", msg)
+	return Result{Type: ResultErrored, Error: out}
+}
+
+// errorTypeForStatus 按 HTTP 状态码推断 Anthropic 错误类型
+func errorTypeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	}
+	return "api_error"
+}
+
+// recordResult 追加结果行并更新计数
+func (m *Manager) recordResult(e *entry, customID string, result Result) {
+	line, err := json.Marshal(resultLine{CustomID: customID, Result: result})
+	if err != nil {
+		log.Printf("[Batch-Result] 警告: 序列化结果失败: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := appendLine(m.resultsPath(e.batch.ID), line); err != nil {
+		log.Printf("[Batch-Result] 警告: 写入批次 %s 结果失败: %v", e.batch.ID, err)
+	}
+	applyResult(&e.batch.RequestCounts, result.Type)
+}
+
+func applyResult(counts *RequestCounts, resultType string) {
+	counts.Processing--
+	switch resultType {
+	case ResultSucceeded:
+		counts.Succeeded++
+	case ResultErrored:
+		counts.Errored++
+	case ResultCanceled:
+		counts.Canceled++
+	case ResultExpired:
+		counts.Expired++
+	}
+}
+
+// finish 标记批次结束
+func (m *Manager) finish(e *entry) {
+	e.mu.Lock()
+	now := time.Now().UTC()
+	resultsURL := fmt.Sprintf("/v1/messages/batches/%s/results", e.batch.ID)
+	e.batch.ProcessingStatus = StatusEnded
+	e.batch.EndedAt = &now
+	e.batch.ResultsURL = &resultsURL
+	counts := e.batch.RequestCounts
+	e.mu.Unlock()
+
+	if err := m.writeMeta(e); err != nil {
+		log.Printf("[Batch-Finish] 警告: 保存批次 %s 失败: %v", e.batch.ID, err)
+	}
+	// 批次结束后不再需要重放凭据
+	if err := os.Remove(m.headerPath(e.batch.ID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("[Batch-Finish] 警告: 删除批次 %s 请求头失败: %v", e.batch.ID, err)
+	}
+	log.Printf("[Batch-Finish] 批次 %s 已结束 (成功: %d, 失败: %d, 取消: %d, 过期: %d)",
+		e.batch.ID, counts.Succeeded, counts.Errored, counts.Canceled, counts.Expired)
+}
+
+// ============== 持久化 ==============
+
+func (m *Manager) metaPath(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
+
+func (m *Manager) requestsPath(id string) string {
+	return filepath.Join(m.dir, id+".requests.jsonl")
+}
+
+// headerPath 创建者请求头（含认证信息，仅批次未结束时存在）
+func (m *Manager) headerPath(id string) string {
+	return filepath.Join(m.dir, id+".header")
+}
+
+func (m *Manager) resultsPath(id string) string {
+	return filepath.Join(m.dir, id+".results.jsonl")
+}
+
+func (m *Manager) writeMeta(e *entry) error {
+	b := e.snapshot()
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.metaPath(b.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.metaPath(b.ID))
+}
+
+func (m *Manager) writeRequests(e *entry) error {
+	var buf bytes.Buffer
+	for _, req := range e.requests {
+		line, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(m.requestsPath(e.batch.ID), buf.Bytes(), 0644)
+}
+
+// writeHeader 保存创建者请求头，仅所有者可读写
+func (m *Manager) writeHeader(e *entry) error {
+	data, err := json.Marshal(e.header)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.headerPath(e.batch.ID), data, 0600)
+}
+
+func (m *Manager) readHeader(id string) (http.Header, error) {
+	data, err := os.ReadFile(m.headerPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var header http.Header
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// load 加载已持久化的批次，未结束的批次使用创建者请求头继续处理剩余请求
+// 创建者请求头缺失时无法以正确身份重放，剩余请求记为 errored
+func (m *Manager) load() error {
+	metaFiles, err := filepath.Glob(filepath.Join(m.dir, "msgbatch_*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, metaFile := range metaFiles {
+		data, err := os.ReadFile(metaFile)
+		if err != nil {
+			log.Printf("[Batch-Load] 警告: 读取 %s 失败: %v", metaFile, err)
+			continue
+		}
+		var b Batch
+		if err := json.Unmarshal(data, &b); err != nil || b.ID == "" {
+			log.Printf("[Batch-Load] 警告: 解析 %s 失败: %v", metaFile, err)
+			continue
+		}
+
+		e := &entry{batch: b}
+		m.batches[b.ID] = e
+		if b.ProcessingStatus == StatusEnded {
+			continue
+		}
+
+		requests, err := readJSONLines[Request](m.requestsPath(b.ID))
+		if err != nil {
+			log.Printf("[Batch-Load] 警告: 读取批次 %s 请求失败: %v", b.ID, err)
+		}
+		results, err := readJSONLines[resultLine](m.resultsPath(b.ID))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("[Batch-Load] 警告: 读取批次 %s 结果失败: %v", b.ID, err)
+		}
+
+		// 以结果文件为准重新计算计数
+		done := make(map[string]bool, len(results))
+		e.batch.RequestCounts = RequestCounts{Processing: len(requests)}
+		for _, r := range results {
+			done[r.CustomID] = true
+			applyResult(&e.batch.RequestCounts, r.Result.Type)
+		}
+		e.requests = requests
+		e.canceled = b.ProcessingStatus == StatusCanceling
+
+		header, err := m.readHeader(b.ID)
+		if err != nil {
+			log.Printf("[Batch-Load] 警告: 批次 %s 缺少创建者请求头，剩余 %d 个请求记为 errored: %v", b.ID, e.batch.RequestCounts.Processing, err)
+			m.abandon(e, done)
+			continue
+		}
+		e.header = header
+
+		log.Printf("[Batch-Load] 恢复未完成批次 %s (剩余 %d 个请求)", b.ID, e.batch.RequestCounts.Processing)
+		m.start(e, done)
+	}
+	return nil
+}
+
+// abandon 不重放剩余请求直接结束批次（取消/过期照常记录，其余记为 errored）
+func (m *Manager) abandon(e *entry, done map[string]bool) {
+	interrupted := erroredResult(http.StatusServiceUnavailable, []byte("batch processing was interrupted by a proxy restart and the original credentials are unavailable"))
+	for _, req := range e.requests {
+		if done[req.CustomID] {
+			continue
+		}
+		result := interrupted
+		if skipType := e.skipType(); skipType != "" {
+			result = Result{Type: skipType}
+		}
+		m.recordResult(e, req.CustomID, result)
+	}
+	m.finish(e)
+}
+
+func readJSONLines[T any](path string) ([]T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []T
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out, scanner.Err()
+}
+
+// ============== 校验与辅助 ==============
+
+func validateRequests(requests []Request) error {
+	if len(requests) == 0 {
+		return &ValidationError{Message: "requests: must contain at least one request"}
+	}
+	if len(requests) > MaxRequestsPerBatch {
+		return &ValidationError{Message: fmt.Sprintf("requests: must contain at most %d requests", MaxRequestsPerBatch)}
+	}
+
+	seen := make(map[string]bool, len(requests))
+	for i, req := range requests {
+		if !customIDPattern.MatchString(req.CustomID) {
+			return &ValidationError{Message: fmt.Sprintf("requests.%d.custom_id: must match ^[a-zA-Z0-9_-]{1,64}$", i)}
+		}
+		if seen[req.CustomID] {
+			return &ValidationError{Message: fmt.Sprintf("requests.%d.custom_id: duplicate custom_id %q", i, req.CustomID)}
+		}
+		seen[req.CustomID] = true
+
+		params := gjson.ParseBytes(req.Params)
+		if !params.IsObject() {
+			return &ValidationError{Message: fmt.Sprintf("requests.%d.params: must be an object", i)}
+		}
+		if params.Get("model").String() == "" {
+			return &ValidationError{Message: fmt.Sprintf("requests.%d.params.model: field required", i)}
+		}
+	}
+	return nil
+}
+
+// sanitizeHeader 复制创建批次时的请求头用于重放（去除与原始请求体相关的字段及 Cookie）
+func sanitizeHeader(header http.Header) http.Header {
+	out := header.Clone()
+	if out == nil {
+		out = http.Header{}
+	}
+	out.Del("Content-Length")
+	out.Del("Accept-Encoding")
+	out.Del("Cookie")
+	return out
+}
+
+// responseRecorder 收集 Messages 处理器的响应
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(p []byte) (int, error) { return r.body.Write(p) }
+
+func (r *responseRecorder) WriteHeader(status int) { r.status = status }
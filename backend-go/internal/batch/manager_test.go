@@ -0,0 +1,422 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func newTestManager(t *testing.T, dir string, concurrency int, handler http.HandlerFunc) *Manager {
+	t.Helper()
+	m, err := NewManager(dir, concurrency, handler)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+func echoHandler(calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if gjson.GetBytes(body, "model").String() == "bad-model" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"unknown model"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stream":` +
+			boolJSON(gjson.GetBytes(body, "stream").Exists()) + `,"key":"` + r.Header.Get("X-Api-Key") + `"}`))
+	}
+}
+
+func boolJSON(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func readResults(t *testing.T, m *Manager, id string) map[string]gjson.Result {
+	t.Helper()
+	rc, err := m.OpenResults(id)
+	if err != nil {
+		t.Fatalf("OpenResults: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+
+	out := make(map[string]gjson.Result)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		out[gjson.Get(line, "custom_id").String()] = gjson.Get(line, "result")
+	}
+	return out
+}
+
+func TestManager_ProcessesRequestsAndStoresResults(t *testing.T) {
+	var calls int32
+	m := newTestManager(t, t.TempDir(), 2, echoHandler(&calls))
+
+	b, err := m.Create([]Request{
+		{CustomID: "a", Params: json.RawMessage(`{"model":"claude","stream":true,"messages":[]}`)},
+		{CustomID: "b", Params: json.RawMessage(`{"model":"bad-model","messages":[]}`)},
+	}, http.Header{"X-Api-Key": []string{"client"}, "Content-Length": []string{"99"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(b.ID, "msgbatch_") || b.Type != "message_batch" || b.RequestCounts.Processing != 2 {
+		t.Fatalf("unexpected batch: %+v", b)
+	}
+	m.Wait()
+
+	got, _ := m.Get(b.ID)
+	if got.ProcessingStatus != StatusEnded || got.EndedAt == nil || got.ResultsURL == nil {
+		t.Fatalf("batch not ended: %+v", got)
+	}
+	if got.RequestCounts != (RequestCounts{Succeeded: 1, Errored: 1}) {
+		t.Fatalf("counts = %+v", got.RequestCounts)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+
+	results := readResults(t, m, b.ID)
+	if results["a"].Get("type").String() != ResultSucceeded {
+		t.Fatalf("result a = %s", results["a"].Raw)
+	}
+	if results["a"].Get("message.stream").Bool() {
+		t.Fatalf("stream must be stripped before replay: %s", results["a"].Raw)
+	}
+	if results["a"].Get("message.key").String() != "client" {
+		t.Fatalf("original headers must be replayed: %s", results["a"].Raw)
+	}
+	if results["b"].Get("type").String() != ResultErrored || results["b"].Get("error.error.message").String() != "unknown model" {
+		t.Fatalf("result b = %s", results["b"].Raw)
+	}
+}
+
+func TestManager_CancelSkipsPendingRequests(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	m := newTestManager(t, t.TempDir(), 1, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte(`{"type":"message"}`))
+	})
+
+	b, err := m.Create([]Request{
+		{CustomID: "first", Params: json.RawMessage(`{"model":"m"}`)},
+		{CustomID: "second", Params: json.RawMessage(`{"model":"m"}`)},
+		{CustomID: "third", Params: json.RawMessage(`{"model":"m"}`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	<-started
+	canceled, err := m.Cancel(b.ID)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if canceled.ProcessingStatus != StatusCanceling || canceled.CancelInitiatedAt == nil {
+		t.Fatalf("unexpected cancel response: %+v", canceled)
+	}
+	close(release)
+	m.Wait()
+
+	got, _ := m.Get(b.ID)
+	if got.ProcessingStatus != StatusEnded {
+		t.Fatalf("status = %s", got.ProcessingStatus)
+	}
+	if got.RequestCounts != (RequestCounts{Succeeded: 1, Canceled: 2}) {
+		t.Fatalf("counts = %+v", got.RequestCounts)
+	}
+	results := readResults(t, m, b.ID)
+	if results["third"].Get("type").String() != ResultCanceled {
+		t.Fatalf("result third = %s", results["third"].Raw)
+	}
+}
+
+func TestManager_ResumesUnfinishedBatchAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	var calls int32
+	m := newTestManager(t, dir, 1, echoHandler(&calls))
+
+	now := time.Now().UTC()
+	e := &entry{
+		batch: Batch{
+			ID:               "msgbatch_resume",
+			Type:             "message_batch",
+			ProcessingStatus: StatusInProgress,
+			RequestCounts:    RequestCounts{Processing: 2},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchTTL),
+		},
+		requests: []Request{
+			{CustomID: "done", Params: json.RawMessage(`{"model":"m"}`)},
+			{CustomID: "todo", Params: json.RawMessage(`{"model":"m"}`)},
+		},
+		header: http.Header{"X-Api-Key": []string{"creator"}},
+	}
+	if err := m.writeRequests(e); err != nil {
+		t.Fatalf("writeRequests: %v", err)
+	}
+	if err := m.writeHeader(e); err != nil {
+		t.Fatalf("writeHeader: %v", err)
+	}
+	if err := m.writeMeta(e); err != nil {
+		t.Fatalf("writeMeta: %v", err)
+	}
+	if err := appendLine(m.resultsPath(e.batch.ID), []byte(`{"custom_id":"done","result":{"type":"succeeded","message":{}}}`)); err != nil {
+		t.Fatalf("appendLine: %v", err)
+	}
+
+	restarted := newTestManager(t, dir, 1, echoHandler(&calls))
+	restarted.Wait()
+
+	got, err := restarted.Get("msgbatch_resume")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ProcessingStatus != StatusEnded || got.RequestCounts != (RequestCounts{Succeeded: 2}) {
+		t.Fatalf("unexpected resumed batch: %+v", got)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Fatalf("handler calls = %d, want only the unfinished request", calls)
+	}
+	if key := readResults(t, restarted, "msgbatch_resume")["todo"].Get("message.key").String(); key != "creator" {
+		t.Fatalf("resumed requests must use the creator's headers, got %q", key)
+	}
+	if _, err := os.Stat(restarted.headerPath("msgbatch_resume")); !os.IsNotExist(err) {
+		t.Fatalf("header file should be removed once the batch ends, stat err = %v", err)
+	}
+}
+
+func TestManager_UnfinishedBatchWithoutHeaderIsErrored(t *testing.T) {
+	dir := t.TempDir()
+	var calls int32
+	m := newTestManager(t, dir, 1, echoHandler(&calls))
+
+	now := time.Now().UTC()
+	e := &entry{
+		batch: Batch{
+			ID:               "msgbatch_noheader",
+			Type:             "message_batch",
+			ProcessingStatus: StatusInProgress,
+			RequestCounts:    RequestCounts{Processing: 2},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchTTL),
+		},
+		requests: []Request{
+			{CustomID: "a", Params: json.RawMessage(`{"model":"m"}`)},
+			{CustomID: "b", Params: json.RawMessage(`{"model":"m"}`)},
+		},
+	}
+	if err := m.writeRequests(e); err != nil {
+		t.Fatalf("writeRequests: %v", err)
+	}
+	if err := m.writeMeta(e); err != nil {
+		t.Fatalf("writeMeta: %v", err)
+	}
+
+	restarted := newTestManager(t, dir, 1, echoHandler(&calls))
+	restarted.Wait()
+
+	if calls := atomic.LoadInt32(&calls); calls != 0 {
+		t.Fatalf("handler calls = %d, want 0 without the creator's credentials", calls)
+	}
+	got, err := restarted.Get("msgbatch_noheader")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ProcessingStatus != StatusEnded || got.RequestCounts != (RequestCounts{Errored: 2}) {
+		t.Fatalf("unexpected batch: %+v", got)
+	}
+	if errType := readResults(t, restarted, "msgbatch_noheader")["a"].Get("error.error.type").String(); errType != "api_error" {
+		t.Fatalf("error type = %q, want api_error", errType)
+	}
+}
+
+func TestManager_PurgesExpiredEndedBatches(t *testing.T) {
+	m := newTestManager(t, t.TempDir(), 1, echoHandler(new(int32)))
+
+	b, err := m.Create([]Request{{CustomID: "a", Params: json.RawMessage(`{"model":"m"}`)}}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	m.Wait()
+
+	now := time.Now().UTC()
+	m.batches["msgbatch_running"] = &entry{batch: Batch{ID: "msgbatch_running", ProcessingStatus: StatusInProgress, CreatedAt: now.Add(-2 * resultsRetention)}}
+
+	m.purgeExpired()
+	if _, err := m.Get(b.ID); err != nil {
+		t.Fatalf("batch within retention should be kept: %v", err)
+	}
+
+	m.batches[b.ID].batch.CreatedAt = now.Add(-resultsRetention - time.Minute)
+	m.purgeExpired()
+	if _, err := m.Get(b.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after purge err = %v, want ErrNotFound", err)
+	}
+	for _, p := range []string{m.metaPath(b.ID), m.requestsPath(b.ID), m.resultsPath(b.ID)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed, stat err = %v", p, err)
+		}
+	}
+	if _, err := m.Get("msgbatch_running"); err != nil {
+		t.Fatalf("unfinished batch must not be purged: %v", err)
+	}
+}
+
+func TestManager_ExpiredRequestsAreNotReplayed(t *testing.T) {
+	var calls int32
+	m := newTestManager(t, t.TempDir(), 1, echoHandler(&calls))
+
+	now := time.Now().UTC().Add(-2 * batchTTL)
+	e := &entry{
+		batch: Batch{
+			ID:               "msgbatch_expired",
+			ProcessingStatus: StatusInProgress,
+			RequestCounts:    RequestCounts{Processing: 1},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchTTL),
+		},
+		requests: []Request{{CustomID: "late", Params: json.RawMessage(`{"model":"m"}`)}},
+	}
+	m.batches[e.batch.ID] = e
+	m.start(e, nil)
+	m.Wait()
+
+	if calls := atomic.LoadInt32(&calls); calls != 0 {
+		t.Fatalf("handler calls = %d, want 0", calls)
+	}
+	if got, _ := m.Get(e.batch.ID); got.RequestCounts != (RequestCounts{Expired: 1}) {
+		t.Fatalf("counts = %+v", got.RequestCounts)
+	}
+}
+
+func TestManager_ListPagination(t *testing.T) {
+	m := newTestManager(t, t.TempDir(), 1, echoHandler(new(int32)))
+
+	base := time.Now().UTC()
+	for i, id := range []string{"msgbatch_1", "msgbatch_2", "msgbatch_3"} {
+		m.batches[id] = &entry{batch: Batch{ID: id, CreatedAt: base.Add(time.Duration(i) * time.Second)}}
+	}
+
+	page, hasMore := m.List(2, "", "")
+	if len(page) != 2 || page[0].ID != "msgbatch_3" || page[1].ID != "msgbatch_2" || !hasMore {
+		t.Fatalf("first page = %+v, hasMore=%v", page, hasMore)
+	}
+	page, hasMore = m.List(2, "", "msgbatch_2")
+	if len(page) != 1 || page[0].ID != "msgbatch_1" || hasMore {
+		t.Fatalf("after page = %+v, hasMore=%v", page, hasMore)
+	}
+	page, hasMore = m.List(1, "msgbatch_1", "")
+	if len(page) != 1 || page[0].ID != "msgbatch_2" || !hasMore {
+		t.Fatalf("before page = %+v, hasMore=%v", page, hasMore)
+	}
+}
+
+func TestManager_DeleteRequiresEndedBatch(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir, 1, echoHandler(new(int32)))
+
+	m.batches["msgbatch_running"] = &entry{batch: Batch{ID: "msgbatch_running", ProcessingStatus: StatusInProgress}}
+	if err := m.Delete("msgbatch_running"); !errors.Is(err, ErrNotEnded) {
+		t.Fatalf("Delete running err = %v, want ErrNotEnded", err)
+	}
+	if _, err := m.OpenResults("msgbatch_running"); !errors.Is(err, ErrNotEnded) {
+		t.Fatalf("OpenResults running err = %v, want ErrNotEnded", err)
+	}
+
+	b, err := m.Create([]Request{{CustomID: "a", Params: json.RawMessage(`{"model":"m"}`)}}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	m.Wait()
+	if err := m.Delete(b.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := m.Get(b.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete err = %v", err)
+	}
+	if _, err := os.Stat(m.resultsPath(b.ID)); !os.IsNotExist(err) {
+		t.Fatalf("results file should be removed, stat err = %v", err)
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []Request
+		wantErr  string
+	}{
+		{"empty", nil, "at least one"},
+		{"bad custom_id", []Request{{CustomID: "has space", Params: json.RawMessage(`{"model":"m"}`)}}, "custom_id"},
+		{"duplicate", []Request{
+			{CustomID: "a", Params: json.RawMessage(`{"model":"m"}`)},
+			{CustomID: "a", Params: json.RawMessage(`{"model":"m"}`)},
+		}, "duplicate"},
+		{"params not object", []Request{{CustomID: "a", Params: json.RawMessage(`[]`)}}, "must be an object"},
+		{"missing model", []Request{{CustomID: "a", Params: json.RawMessage(`{}`)}}, "model"},
+		{"ok", []Request{{CustomID: "a-1_B", Params: json.RawMessage(`{"model":"m"}`)}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequests(tt.requests)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestErroredResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		errType string
+		message string
+	}{
+		{"anthropic error", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, "invalid_request_error", "bad"},
+		{"wrapped error object", 503, `{"error":{"type":"overloaded_error","message":"busy"}}`, "overloaded_error", "busy"},
+		{"plain text 5xx", 502, `upstream down`, "api_error", "upstream down"},
+		{"empty 429", 429, ``, "rate_limit_error", "http status 429"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := erroredResult(tt.status, []byte(tt.body))
+			if r.Type != ResultErrored {
+				t.Fatalf("type = %s", r.Type)
+			}
+			if got := gjson.GetBytes(r.Error, "type").String(); got != "error" {
+				t.Fatalf("error.type = %q", got)
+			}
+			if got := gjson.GetBytes(r.Error, "error.type").String(); got != tt.errType {
+				t.Fatalf("error.error.type = %q, want %q", got, tt.errType)
+			}
+			if got := gjson.GetBytes(r.Error, "error.message").String(); got != tt.message {
+				t.Fatalf("error.error.message = %q, want %q", got, tt.message)
+			}
+		})
+	}
+}
@@ -11,7 +11,7 @@ func sanitizeAPIKeyMeta(meta map[string]APIKeyMeta, apiKeys []string) map[string
 		if !ok {
 			continue
 		}
-		if !m.Disabled && m.Description == "" {
+		if m.isZero() {
 			continue
 		}
 		cleaned[apiKey] = m
@@ -23,6 +23,11 @@ func sanitizeAPIKeyMeta(meta map[string]APIKeyMeta, apiKeys []string) map[string
 	return cleaned
 }
 
+// isZero 元信息是否全部为默认值（无需保存）
+func (m APIKeyMeta) isZero() bool {
+	return !m.Disabled && m.Description == "" && m.MaxConcurrency <= 0
+}
+
 func (u *UpstreamConfig) IsAPIKeyDisabled(apiKey string) bool {
 	if u == nil || u.APIKeyMeta == nil {
 		return false
@@ -48,3 +53,27 @@ func (u *UpstreamConfig) GetEnabledAPIKeys() []string {
 	}
 	return enabled
 }
+
+// GetKeyMaxConcurrency 返回 Key 的最大并发数（0 表示不限制）
+func (u *UpstreamConfig) GetKeyMaxConcurrency(apiKey string) int {
+	if u == nil || u.APIKeyMeta == nil {
+		return 0
+	}
+	return u.APIKeyMeta[apiKey].MaxConcurrency
+}
+
+// HasConcurrencyLimit 渠道或其任一 Key 是否配置了并发上限
+func (u *UpstreamConfig) HasConcurrencyLimit() bool {
+	if u == nil {
+		return false
+	}
+	if u.MaxConcurrency > 0 {
+		return true
+	}
+	for _, meta := range u.APIKeyMeta {
+		if meta.MaxConcurrency > 0 {
+			return true
+		}
+	}
+	return false
+}
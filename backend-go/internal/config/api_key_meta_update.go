@@ -3,6 +3,23 @@
 import "fmt"
 
 func (cm *ConfigManager) SetAPIKeyDisabled(apiType string, upstreamIndex int, keyIndex int, disabled bool) error {
+	return cm.updateAPIKeyMeta(apiType, upstreamIndex, keyIndex, func(meta *APIKeyMeta) {
+		meta.Disabled = disabled
+	})
+}
+
+// SetAPIKeyMaxConcurrency 设置 Key 的最大并发数（0 表示不限制）
+func (cm *ConfigManager) SetAPIKeyMaxConcurrency(apiType string, upstreamIndex int, keyIndex int, maxConcurrency int) error {
+	if maxConcurrency < 0 {
+		maxConcurrency = 0
+	}
+	return cm.updateAPIKeyMeta(apiType, upstreamIndex, keyIndex, func(meta *APIKeyMeta) {
+		meta.MaxConcurrency = maxConcurrency
+	})
+}
+
+// updateAPIKeyMeta 修改指定 Key 的元信息，全部为默认值时移除该条目
+func (cm *ConfigManager) updateAPIKeyMeta(apiType string, upstreamIndex int, keyIndex int, mutate func(meta *APIKeyMeta)) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -14,6 +31,8 @@ func (cm *ConfigManager) SetAPIKeyDisabled(apiType string, upstreamIndex int, ke
 		upstreams = &cm.config.ResponsesUpstream
 	case "gemini":
 		upstreams = &cm.config.GeminiUpstream
+	case "embeddings":
+		upstreams = &cm.config.EmbeddingsUpstream
 	default:
 		return fmt.Errorf("invalid api type: %s", apiType)
 	}
@@ -34,9 +53,9 @@ func (cm *ConfigManager) SetAPIKeyDisabled(apiType string, upstreamIndex int, ke
 			meta = existing
 		}
 	}
-	meta.Disabled = disabled
+	mutate(&meta)
 
-	if !meta.Disabled && meta.Description == "" {
+	if meta.isZero() {
 		if upstream.APIKeyMeta != nil {
 			delete(upstream.APIKeyMeta, apiKey)
 			if len(upstream.APIKeyMeta) == 0 {
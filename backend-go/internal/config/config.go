@@ -15,8 +15,9 @@
 
 // APIKeyMeta API Key 元信息
 type APIKeyMeta struct {
-	Disabled    bool   `json:"disabled,omitempty"`
-	Description string `json:"description,omitempty"`
+	Disabled       bool   `json:"disabled,omitempty"`
+	Description    string `json:"description,omitempty"`
+	MaxConcurrency int    `json:"maxConcurrency,omitempty"` // 该 Key 同时进行的最大请求数（0 表示不限制）
 }
 
 // UpstreamConfig 上游配置
@@ -25,17 +26,23 @@ type UpstreamConfig struct {
 	BaseURLs           []string              `json:"baseUrls,omitempty"` // 多 BaseURL 支持（failover 模式）
 	APIKeys            []string              `json:"apiKeys"`
 	APIKeyMeta         map[string]APIKeyMeta `json:"apiKeyMeta,omitempty"` // key 元信息（默认启用）
-	ServiceType        string                `json:"serviceType"`          // gemini, openai, claude
+	ServiceType        string                `json:"serviceType"`          // gemini, openai, claude, responses, completions, bedrock, azure-openai, vertex
 	Name               string                `json:"name,omitempty"`
 	Description        string                `json:"description,omitempty"`
 	Website            string                `json:"website,omitempty"`
 	InsecureSkipVerify bool                  `json:"insecureSkipVerify,omitempty"`
 	ModelMapping       map[string]string     `json:"modelMapping,omitempty"`
+	SupportedModels    []string              `json:"supportedModels,omitempty"` // 可服务的模型（通配符或 /正则/，按重定向后的模型匹配，为空表示全部）
 	// 多渠道调度相关字段
-	Priority       int        `json:"priority"`                 // 渠道优先级（数字越小优先级越高，默认按索引）
-	Status         string     `json:"status"`                   // 渠道状态：active（正常）, suspended（暂停）, disabled（备用池）
-	PromotionUntil *time.Time `json:"promotionUntil,omitempty"` // 促销期截止时间，在此期间内优先使用此渠道（忽略trace亲和）
-	LowQuality     bool       `json:"lowQuality,omitempty"`     // 低质量渠道标记：启用后强制本地估算 token，偏差>5%时使用本地值
+	Priority        int        `json:"priority"`                  // 渠道优先级（数字越小优先级越高，默认按索引）
+	Status          string     `json:"status"`                    // 渠道状态：active（正常）, suspended（暂停）, disabled（备用池）
+	Weight          int        `json:"weight,omitempty"`          // 渠道权重（weighted 负载均衡模式下按权重分配流量，默认 1）
+	PriceMultiplier float64    `json:"priceMultiplier,omitempty"` // 价格倍率（相对官方价格的折扣，如 0.3 表示三折，默认 1）
+	MaxConcurrency  int        `json:"maxConcurrency,omitempty"`  // 渠道同时进行的最大请求数（所有 Key 合计，0 表示不限制）
+	PromotionUntil  *time.Time `json:"promotionUntil,omitempty"`  // 促销期截止时间，在此期间内优先使用此渠道（忽略trace亲和）
+	LowQuality      bool       `json:"lowQuality,omitempty"`      // 低质量渠道标记：启用后强制本地估算 token，偏差>5%时使用本地值
+	// Azure OpenAI 特定配置
+	APIVersion string `json:"apiVersion,omitempty"` // api-version 查询参数（为空时使用默认版本）
 	// Gemini 特定配置
 	InjectDummyThoughtSignature bool `json:"injectDummyThoughtSignature,omitempty"` // 给空 thoughtSignature 注入 dummy 值（兼容要求必须有该字段的上游）
 	StripThoughtSignature       bool `json:"stripThoughtSignature,omitempty"`       // 移除 thoughtSignature 字段（兼容不支持该字段的旧版 Gemini API）
@@ -53,11 +60,17 @@ type UpstreamUpdate struct {
 	Website            *string               `json:"website"`
 	InsecureSkipVerify *bool                 `json:"insecureSkipVerify"`
 	ModelMapping       map[string]string     `json:"modelMapping"`
+	SupportedModels    []string              `json:"supportedModels"`
 	// 多渠道调度相关字段
-	Priority       *int       `json:"priority"`
-	Status         *string    `json:"status"`
-	PromotionUntil *time.Time `json:"promotionUntil"`
-	LowQuality     *bool      `json:"lowQuality"`
+	Priority        *int       `json:"priority"`
+	Status          *string    `json:"status"`
+	Weight          *int       `json:"weight"`
+	PriceMultiplier *float64   `json:"priceMultiplier"`
+	MaxConcurrency  *int       `json:"maxConcurrency"`
+	PromotionUntil  *time.Time `json:"promotionUntil"`
+	LowQuality      *bool      `json:"lowQuality"`
+	// Azure OpenAI 特定配置
+	APIVersion *string `json:"apiVersion"`
 	// Gemini 特定配置
 	InjectDummyThoughtSignature *bool `json:"injectDummyThoughtSignature"`
 	StripThoughtSignature       *bool `json:"stripThoughtSignature"`
@@ -67,7 +80,7 @@ type UpstreamUpdate struct {
 type Config struct {
 	Upstream        []UpstreamConfig `json:"upstream"`
 	CurrentUpstream int              `json:"currentUpstream,omitempty"` // 已废弃：旧格式兼容用
-	LoadBalance     string           `json:"loadBalance"`               // round-robin, random, failover
+	LoadBalance     string           `json:"loadBalance"`               // failover, weighted, latency, cheapest（round-robin, random 为兼容旧配置保留）
 
 	// Responses 接口专用配置（独立于 /v1/messages）
 	ResponsesUpstream        []UpstreamConfig `json:"responsesUpstream"`
@@ -78,9 +91,14 @@ type Config struct {
 	GeminiUpstream    []UpstreamConfig `json:"geminiUpstream"`
 	GeminiLoadBalance string           `json:"geminiLoadBalance"`
 
+	// Embeddings 接口专用配置（独立的渠道池与 Key 健康状态）
+	EmbeddingsUpstream    []UpstreamConfig `json:"embeddingsUpstream"`
+	EmbeddingsLoadBalance string           `json:"embeddingsLoadBalance"`
+
 	// 全局重定向配置（优先级低于单渠道配置）
 	GlobalModelMapping     map[string]string `json:"globalModelMapping,omitempty"`
 	GlobalReasoningMapping map[string]string `json:"globalReasoningMapping,omitempty"`
+	GlobalReasoningBudgets map[string]int    `json:"globalReasoningBudgets,omitempty"` // effort 档位 → 思考预算（跨协议转换使用）
 
 	// Fuzzy 模式：启用时模糊处理错误，所有非 2xx 错误都尝试 failover
 	FuzzyModeEnabled bool `json:"fuzzyModeEnabled"`
@@ -138,6 +156,14 @@ func (cm *ConfigManager) GetConfig() Config {
 			cloned.GeminiUpstream[i] = *cm.config.GeminiUpstream[i].Clone()
 		}
 	}
+
+	// 深拷贝 EmbeddingsUpstream slice
+	if cm.config.EmbeddingsUpstream != nil {
+		cloned.EmbeddingsUpstream = make([]UpstreamConfig, len(cm.config.EmbeddingsUpstream))
+		for i := range cm.config.EmbeddingsUpstream {
+			cloned.EmbeddingsUpstream[i] = *cm.config.EmbeddingsUpstream[i].Clone()
+		}
+	}
 	// 深拷贝全局映射配置
 	if cm.config.GlobalModelMapping != nil {
 		cloned.GlobalModelMapping = make(map[string]string, len(cm.config.GlobalModelMapping))
@@ -151,6 +177,12 @@ func (cm *ConfigManager) GetConfig() Config {
 			cloned.GlobalReasoningMapping[source] = target
 		}
 	}
+	if cm.config.GlobalReasoningBudgets != nil {
+		cloned.GlobalReasoningBudgets = make(map[string]int, len(cm.config.GlobalReasoningBudgets))
+		for level, budget := range cm.config.GlobalReasoningBudgets {
+			cloned.GlobalReasoningBudgets[level] = budget
+		}
+	}
 
 	return cloned
 }
@@ -45,6 +45,11 @@ func (cm *ConfigManager) AddGeminiUpstream(upstream UpstreamConfig) error {
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.APIKeyMeta = sanitizeAPIKeyMeta(upstream.APIKeyMeta, upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
+	supportedModels, err := normalizeSupportedModels(upstream.SupportedModels)
+	if err != nil {
+		return err
+	}
+	upstream.SupportedModels = supportedModels
 
 	cm.config.GeminiUpstream = append(cm.config.GeminiUpstream, upstream)
 
@@ -68,6 +73,14 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 
 	upstream := &cm.config.GeminiUpstream[index]
 
+	if updates.SupportedModels != nil {
+		supportedModels, err := normalizeSupportedModels(updates.SupportedModels)
+		if err != nil {
+			return false, err
+		}
+		upstream.SupportedModels = supportedModels
+	}
+
 	if updates.Name != nil {
 		upstream.Name = *updates.Name
 	}
@@ -125,6 +138,18 @@ func (cm *ConfigManager) UpdateGeminiUpstream(index int, updates UpstreamUpdate)
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.PriceMultiplier != nil {
+		upstream.PriceMultiplier = *updates.PriceMultiplier
+	}
+	if updates.MaxConcurrency != nil {
+		upstream.MaxConcurrency = *updates.MaxConcurrency
+	}
+	if updates.APIVersion != nil {
+		upstream.APIVersion = *updates.APIVersion
+	}
 	if updates.InjectDummyThoughtSignature != nil {
 		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
 	}
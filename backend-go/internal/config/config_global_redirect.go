@@ -58,6 +58,26 @@ func normalizeGlobalReasoningMapping(mapping map[string]string) map[string]strin
 	return normalized
 }
 
+func normalizeGlobalReasoningBudgets(budgets map[string]int) map[string]int {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	normalized := make(map[string]int, len(budgets))
+	for level, budget := range budgets {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if level == "" || budget <= 0 {
+			continue
+		}
+		normalized[level] = budget
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
 // GetGlobalModelMapping 获取全局模型重定向映射（深拷贝）
 func (cm *ConfigManager) GetGlobalModelMapping() map[string]string {
 	cm.mu.RLock()
@@ -99,3 +119,31 @@ func (cm *ConfigManager) SetGlobalReasoningMapping(mapping map[string]string) er
 	log.Printf("[Config-ReasoningMapping] 已更新全局思考重定向规则，数量=%d", len(cm.config.GlobalReasoningMapping))
 	return nil
 }
+
+// GetGlobalReasoningBudgets 获取全局思考预算表（深拷贝）
+func (cm *ConfigManager) GetGlobalReasoningBudgets() map[string]int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	if len(cm.config.GlobalReasoningBudgets) == 0 {
+		return nil
+	}
+	cloned := make(map[string]int, len(cm.config.GlobalReasoningBudgets))
+	for level, budget := range cm.config.GlobalReasoningBudgets {
+		cloned[level] = budget
+	}
+	return cloned
+}
+
+// SetGlobalReasoningBudgets 设置全局思考预算表（effort 档位 → 思考预算）
+func (cm *ConfigManager) SetGlobalReasoningBudgets(budgets map[string]int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.config.GlobalReasoningBudgets = normalizeGlobalReasoningBudgets(budgets)
+	if err := cm.saveConfigLocked(cm.config); err != nil {
+		return err
+	}
+
+	log.Printf("[Config-ReasoningMapping] 已更新全局思考预算表，数量=%d", len(cm.config.GlobalReasoningBudgets))
+	return nil
+}
@@ -103,6 +103,8 @@ func (cm *ConfigManager) createDefaultConfig() error {
 		ResponsesLoadBalance:     "failover",
 		GeminiUpstream:           []UpstreamConfig{},
 		GeminiLoadBalance:        "failover",
+		EmbeddingsUpstream:       []UpstreamConfig{},
+		EmbeddingsLoadBalance:    "failover",
 		FuzzyModeEnabled:         true, // 默认启用 Fuzzy 模式
 	}
 
@@ -128,6 +130,9 @@ func (cm *ConfigManager) applyConfigDefaults(rawJSON []byte) bool {
 	if cm.config.GeminiLoadBalance == "" {
 		cm.config.GeminiLoadBalance = "failover"
 	}
+	if cm.config.EmbeddingsLoadBalance == "" {
+		cm.config.EmbeddingsLoadBalance = "failover"
+	}
 
 	// FuzzyModeEnabled 默认值处理：
 	// 由于 bool 零值是 false，无法区分"用户设为 false"和"字段不存在"
@@ -252,6 +257,22 @@ func (cm *ConfigManager) validateChannelKeys() bool {
 		}
 	}
 
+	// 检查 Embeddings 渠道
+	for i := range cm.config.EmbeddingsUpstream {
+		upstream := &cm.config.EmbeddingsUpstream[i]
+		status := upstream.Status
+		if status == "" {
+			status = "active"
+		}
+
+		// 如果是 active 状态但没有配置 key，自动设为 suspended
+		if status == "active" && len(upstream.APIKeys) == 0 {
+			upstream.Status = "suspended"
+			modified = true
+			log.Printf("[Config-Validate] 警告: Embeddings 渠道 [%d] %s 没有配置 API key，已自动暂停", i, upstream.Name)
+		}
+	}
+
 	return modified
 }
 
@@ -47,6 +47,11 @@ func (cm *ConfigManager) AddUpstream(upstream UpstreamConfig) error {
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.APIKeyMeta = sanitizeAPIKeyMeta(upstream.APIKeyMeta, upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
+	supportedModels, err := normalizeSupportedModels(upstream.SupportedModels)
+	if err != nil {
+		return err
+	}
+	upstream.SupportedModels = supportedModels
 
 	cm.config.Upstream = append(cm.config.Upstream, upstream)
 
@@ -70,6 +75,14 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 
 	upstream := &cm.config.Upstream[index]
 
+	if updates.SupportedModels != nil {
+		supportedModels, err := normalizeSupportedModels(updates.SupportedModels)
+		if err != nil {
+			return false, err
+		}
+		upstream.SupportedModels = supportedModels
+	}
+
 	if updates.Name != nil {
 		upstream.Name = *updates.Name
 	}
@@ -127,6 +140,18 @@ func (cm *ConfigManager) UpdateUpstream(index int, updates UpstreamUpdate) (shou
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.PriceMultiplier != nil {
+		upstream.PriceMultiplier = *updates.PriceMultiplier
+	}
+	if updates.MaxConcurrency != nil {
+		upstream.MaxConcurrency = *updates.MaxConcurrency
+	}
+	if updates.APIVersion != nil {
+		upstream.APIVersion = *updates.APIVersion
+	}
 	if updates.InjectDummyThoughtSignature != nil {
 		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
 	}
@@ -45,6 +45,11 @@ func (cm *ConfigManager) AddResponsesUpstream(upstream UpstreamConfig) error {
 	upstream.APIKeys = deduplicateStrings(upstream.APIKeys)
 	upstream.APIKeyMeta = sanitizeAPIKeyMeta(upstream.APIKeyMeta, upstream.APIKeys)
 	upstream.BaseURLs = deduplicateBaseURLs(upstream.BaseURLs)
+	supportedModels, err := normalizeSupportedModels(upstream.SupportedModels)
+	if err != nil {
+		return err
+	}
+	upstream.SupportedModels = supportedModels
 
 	cm.config.ResponsesUpstream = append(cm.config.ResponsesUpstream, upstream)
 
@@ -68,6 +73,14 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 
 	upstream := &cm.config.ResponsesUpstream[index]
 
+	if updates.SupportedModels != nil {
+		supportedModels, err := normalizeSupportedModels(updates.SupportedModels)
+		if err != nil {
+			return false, err
+		}
+		upstream.SupportedModels = supportedModels
+	}
+
 	if updates.Name != nil {
 		upstream.Name = *updates.Name
 	}
@@ -125,6 +138,18 @@ func (cm *ConfigManager) UpdateResponsesUpstream(index int, updates UpstreamUpda
 	if updates.LowQuality != nil {
 		upstream.LowQuality = *updates.LowQuality
 	}
+	if updates.Weight != nil {
+		upstream.Weight = *updates.Weight
+	}
+	if updates.PriceMultiplier != nil {
+		upstream.PriceMultiplier = *updates.PriceMultiplier
+	}
+	if updates.MaxConcurrency != nil {
+		upstream.MaxConcurrency = *updates.MaxConcurrency
+	}
+	if updates.APIVersion != nil {
+		upstream.APIVersion = *updates.APIVersion
+	}
 	if updates.InjectDummyThoughtSignature != nil {
 		upstream.InjectDummyThoughtSignature = *updates.InjectDummyThoughtSignature
 	}
@@ -43,9 +43,12 @@ func deduplicateBaseURLs(urls []string) []string {
 
 // validateLoadBalanceStrategy 验证负载均衡策略
 func validateLoadBalanceStrategy(strategy string) error {
-	// 只接受 failover 策略（round-robin 和 random 已移除）
-	// 为兼容旧配置，仍允许旧值但静默忽略
-	if strategy != "failover" && strategy != "round-robin" && strategy != "random" {
+	// failover：按优先级/亲和性调度；weighted：按渠道权重分配流量；latency：最高优先级档内 TTFB 最快优先；
+	// cheapest：按模型价格 × 渠道价格倍率选择成本最低的渠道
+	// round-robin 和 random 已移除，为兼容旧配置仍允许旧值但静默忽略
+	switch strategy {
+	case "failover", "weighted", "latency", "cheapest", "round-robin", "random":
+	default:
 		return &ConfigError{Message: "无效的负载均衡策略: " + strategy}
 	}
 	return nil
@@ -149,6 +152,14 @@ func GetChannelPriority(upstream *UpstreamConfig, index int) int {
 	return upstream.Priority
 }
 
+// GetPriceMultiplier 获取渠道价格倍率（未配置或非正数时为 1）
+func (u *UpstreamConfig) GetPriceMultiplier() float64 {
+	if u.PriceMultiplier <= 0 {
+		return 1
+	}
+	return u.PriceMultiplier
+}
+
 // IsChannelInPromotion 检查渠道是否处于促销期
 func IsChannelInPromotion(upstream *UpstreamConfig) bool {
 	if upstream.PromotionUntil == nil {
@@ -186,6 +197,10 @@ func (u *UpstreamConfig) Clone() *UpstreamConfig {
 			cloned.ModelMapping[k] = v
 		}
 	}
+	if u.SupportedModels != nil {
+		cloned.SupportedModels = make([]string, len(u.SupportedModels))
+		copy(cloned.SupportedModels, u.SupportedModels)
+	}
 	if u.PromotionUntil != nil {
 		t := *u.PromotionUntil
 		cloned.PromotionUntil = &t
@@ -220,3 +235,14 @@ func (u *UpstreamConfig) GetAllBaseURLs() []string {
 	}
 	return nil
 }
+
+// DefaultAzureAPIVersion Azure OpenAI 默认 api-version
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// GetAzureAPIVersion 获取 Azure OpenAI 渠道的 api-version（未配置时使用默认版本）
+func (u *UpstreamConfig) GetAzureAPIVersion() string {
+	if v := strings.TrimSpace(u.APIVersion); v != "" {
+		return v
+	}
+	return DefaultAzureAPIVersion
+}
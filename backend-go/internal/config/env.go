@@ -29,6 +29,9 @@ type EnvConfig struct {
 	MetricsRetentionDays int // 数据保留天数（1-7）
 	// HTTP 客户端配置
 	ResponseHeaderTimeout int // 等待响应头超时时间（秒）
+	// 并发排队配置
+	ConcurrencyQueueSize    int // 所有槽位并发已满时每个渠道池的排队上限（0 表示不排队）
+	ConcurrencyQueueTimeout int // 排队等待空闲槽位的超时时间（秒）
 	// 日志文件相关配置
 	LogDir        string
 	LogFile       string
@@ -41,6 +44,11 @@ type EnvConfig struct {
 	SweAgentBillingURL    string // swe-agent 计费服务 URL
 	PreAuthAmountCents    int64  // 预授权金额 (cents)
 	PricingUpdateInterval string // 价格表更新间隔
+	// 批处理配置
+	BatchConcurrency int    // Message Batches 全局并发请求数
+	BatchDir         string // 批次元信息与结果文件目录
+	// Vertex AI 配置
+	VertexTokenURL string // 服务账号换取 access token 的端点（为空时使用服务账号 JSON 中的 token_uri）
 }
 
 const DefaultProxyAccessKey = "123456"
@@ -77,6 +85,9 @@ func NewEnvConfig() *EnvConfig {
 		MetricsRetentionDays: clampInt(getEnvAsInt("METRICS_RETENTION_DAYS", 7), 1, 7),
 		// HTTP 客户端配置
 		ResponseHeaderTimeout: clampInt(getEnvAsInt("RESPONSE_HEADER_TIMEOUT", 300), 30, 600), // 30-600 秒
+		// 并发排队配置
+		ConcurrencyQueueSize:    clampInt(getEnvAsInt("CONCURRENCY_QUEUE_SIZE", 100), 0, 10000),
+		ConcurrencyQueueTimeout: clampInt(getEnvAsInt("CONCURRENCY_QUEUE_TIMEOUT", 30), 1, 600), // 1-600 秒
 		// 日志文件配置
 		LogDir:        getEnv("LOG_DIR", "logs"),
 		LogFile:       getEnv("LOG_FILE", "app.log"),
@@ -89,6 +100,11 @@ func NewEnvConfig() *EnvConfig {
 		SweAgentBillingURL:    getEnv("SWE_AGENT_BILLING_URL", ""),
 		PreAuthAmountCents:    getEnvAsInt64("PRE_AUTH_AMOUNT_CENTS", 500), // 默认 $5.00
 		PricingUpdateInterval: getEnv("PRICING_UPDATE_INTERVAL", "24h"),
+		// 批处理配置
+		BatchConcurrency: clampInt(getEnvAsInt("BATCH_CONCURRENCY", 4), 1, 64),
+		BatchDir:         getEnv("BATCH_DIR", ".config/batches"),
+		// Vertex AI 配置
+		VertexTokenURL: getEnv("VERTEX_TOKEN_URL", ""),
 	}
 }
 
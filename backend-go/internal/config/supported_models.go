@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// supportedModelPatterns 已编译的 supportedModels 模式缓存（调度热路径避免重复编译）
+var supportedModelPatterns sync.Map // map[string]*regexp.Regexp
+
+// compileSupportedModelPattern 编译单个 supportedModels 模式（不区分大小写）
+// "/.../" 包裹的条目按正则表达式解析；其余条目按通配符解析：* 匹配任意字符，? 匹配单个字符
+func compileSupportedModelPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := supportedModelPatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	var expr string
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expr = "(?i)" + pattern[1:len(pattern)-1]
+	} else {
+		quoted := regexp.QuoteMeta(pattern)
+		quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+		quoted = strings.ReplaceAll(quoted, `\?`, ".")
+		expr = "(?i)^" + quoted + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	supportedModelPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// normalizeSupportedModels 去除空白条目并去重，全部为空时返回 nil（表示支持所有模型）
+func normalizeSupportedModels(patterns []string) ([]string, error) {
+	normalized := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := compileSupportedModelPattern(p); err != nil {
+			return nil, &ConfigError{Message: fmt.Sprintf("无效的 supportedModels 模式 %q: %v", p, err)}
+		}
+		normalized = append(normalized, p)
+	}
+	normalized = deduplicateStrings(normalized)
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// SupportsModel 判断渠道是否可服务指定模型（model 应为 ModelMapping 重定向后的上游模型名）
+// 未配置 supportedModels 或 model 为空时视为支持
+func (u *UpstreamConfig) SupportsModel(model string) bool {
+	if len(u.SupportedModels) == 0 || model == "" {
+		return true
+	}
+	for _, p := range u.SupportedModels {
+		re, err := compileSupportedModelPattern(p)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(model) {
+			return true
+		}
+	}
+	return false
+}
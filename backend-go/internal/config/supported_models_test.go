@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestSupportsModel(t *testing.T) {
+	upstream := &UpstreamConfig{SupportedModels: []string{"claude-*-4-5", "gpt-4.1", "/^gemini-2\\.5-(pro|flash)$/"}}
+
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"claude-sonnet-4-5", true},
+		{"Claude-Opus-4-5", true},
+		{"claude-sonnet-4", false},
+		{"gpt-4.1", true},
+		{"gpt-4x1", false},
+		{"gemini-2.5-pro", true},
+		{"gemini-2.5-pro-preview", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if got := upstream.SupportsModel(tt.model); got != tt.want {
+			t.Errorf("SupportsModel(%q)=%v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	if !(&UpstreamConfig{}).SupportsModel("anything") {
+		t.Fatalf("empty supportedModels should support every model")
+	}
+}
+
+func TestNormalizeSupportedModels(t *testing.T) {
+	got, err := normalizeSupportedModels([]string{" gpt-4o ", "", "gpt-4o", "claude-*"})
+	if err != nil {
+		t.Fatalf("normalizeSupportedModels: %v", err)
+	}
+	if len(got) != 2 || got[0] != "gpt-4o" || got[1] != "claude-*" {
+		t.Fatalf("got %v", got)
+	}
+
+	if got, err := normalizeSupportedModels([]string{" "}); err != nil || got != nil {
+		t.Fatalf("blank patterns should normalize to nil, got %v (%v)", got, err)
+	}
+
+	if _, err := normalizeSupportedModels([]string{"/gpt-(4/"}); err == nil {
+		t.Fatalf("expected error for invalid regex")
+	}
+}
@@ -0,0 +1,382 @@
+package converters
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// defaultChatToClaudeMaxTokens Chat 请求未指定 max_tokens 时的默认值（Claude 要求必填）
+const defaultChatToClaudeMaxTokens = 8192
+
+// ConvertOpenAIChatToClaudeRequest 将 OpenAI Chat Completions 请求转换为 Claude Messages 请求
+// 转换内容包括:
+// 1. system/developer 消息 → system
+// 2. user/assistant 消息 → messages（图片 image_url → image，文件 file → document）
+// 3. assistant.tool_calls → tool_use，tool 消息 → tool_result（连续 tool 消息合并为一条 user 消息）
+// 4. tools / tool_choice / stop / reasoning_effort 等参数映射
+// 5. response_format → 强制调用 structured_output 工具（模拟结构化输出）
+func ConvertOpenAIChatToClaudeRequest(inputRawJSON []byte) []byte {
+	out := `{"model":"","max_tokens":0,"messages":[]}`
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out, _ = sjson.Set(out, "model", root.Get("model").String())
+
+	maxTokens := int64(defaultChatToClaudeMaxTokens)
+	if v := root.Get("max_completion_tokens"); v.Exists() && v.Int() > 0 {
+		maxTokens = v.Int()
+	} else if v := root.Get("max_tokens"); v.Exists() && v.Int() > 0 {
+		maxTokens = v.Int()
+	}
+
+	if stream := root.Get("stream"); stream.Exists() {
+		out, _ = sjson.Set(out, "stream", stream.Bool())
+	}
+	if user := root.Get("user"); user.Exists() && user.String() != "" {
+		out, _ = sjson.Set(out, "metadata.user_id", user.String())
+	}
+
+	// stop → stop_sequences
+	if stop := root.Get("stop"); stop.Exists() {
+		if stop.IsArray() {
+			stop.ForEach(func(_, s gjson.Result) bool {
+				out, _ = sjson.Set(out, "stop_sequences.-1", s.String())
+				return true
+			})
+		} else if stop.String() != "" {
+			out, _ = sjson.Set(out, "stop_sequences.-1", stop.String())
+		}
+	}
+
+	// reasoning_effort → thinking（开启 thinking 时 Claude 不接受自定义 temperature）
+	thinkingEnabled := false
+	if effort := root.Get("reasoning_effort"); effort.Exists() {
+		if budget := ReasoningEffortToBudget(effort.String()); budget > 0 {
+			thinkingEnabled = true
+			out, _ = sjson.Set(out, "thinking.type", "enabled")
+			out, _ = sjson.Set(out, "thinking.budget_tokens", budget)
+			if maxTokens <= int64(budget) {
+				maxTokens = int64(budget) + defaultChatToClaudeMaxTokens
+			}
+		}
+	}
+	out, _ = sjson.Set(out, "max_tokens", maxTokens)
+
+	if !thinkingEnabled {
+		if temperature := root.Get("temperature"); temperature.Exists() {
+			out, _ = sjson.Set(out, "temperature", temperature.Float())
+		}
+		if topP := root.Get("top_p"); topP.Exists() {
+			out, _ = sjson.Set(out, "top_p", topP.Float())
+		}
+	}
+
+	// messages
+	var systemParts []string
+	pendingToolResults := ""
+	flushToolResults := func() {
+		if pendingToolResults == "" {
+			return
+		}
+		msg := `{"role":"user","content":[]}`
+		msg, _ = sjson.SetRaw(msg, "content", "["+pendingToolResults+"]")
+		out, _ = sjson.SetRaw(out, "messages.-1", msg)
+		pendingToolResults = ""
+	}
+
+	root.Get("messages").ForEach(func(_, m gjson.Result) bool {
+		role := m.Get("role").String()
+		switch role {
+		case "system", "developer":
+			if text := extractChatMessageText(m.Get("content")); text != "" {
+				systemParts = append(systemParts, text)
+			}
+		case "tool":
+			block := `{"type":"tool_result","tool_use_id":"","content":""}`
+			block, _ = sjson.Set(block, "tool_use_id", m.Get("tool_call_id").String())
+			block, _ = sjson.Set(block, "content", extractChatMessageText(m.Get("content")))
+			if pendingToolResults != "" {
+				pendingToolResults += ","
+			}
+			pendingToolResults += block
+		case "assistant":
+			flushToolResults()
+			msg := `{"role":"assistant","content":[]}`
+			msg = appendChatContentAsClaudeBlocks(msg, m.Get("content"))
+			m.Get("tool_calls").ForEach(func(_, tc gjson.Result) bool {
+				block := `{"type":"tool_use","id":"","name":"","input":{}}`
+				block, _ = sjson.Set(block, "id", tc.Get("id").String())
+				block, _ = sjson.Set(block, "name", tc.Get("function.name").String())
+				if args := tc.Get("function.arguments").String(); args != "" && gjson.Valid(args) {
+					block, _ = sjson.SetRaw(block, "input", args)
+				}
+				msg, _ = sjson.SetRaw(msg, "content.-1", block)
+				return true
+			})
+			if len(gjson.Get(msg, "content").Array()) > 0 {
+				out, _ = sjson.SetRaw(out, "messages.-1", msg)
+			}
+		default:
+			flushToolResults()
+			msg := `{"role":"user","content":[]}`
+			msg = appendChatContentAsClaudeBlocks(msg, m.Get("content"))
+			if len(gjson.Get(msg, "content").Array()) > 0 {
+				out, _ = sjson.SetRaw(out, "messages.-1", msg)
+			}
+		}
+		return true
+	})
+	flushToolResults()
+
+	if len(systemParts) > 0 {
+		out, _ = sjson.Set(out, "system", strings.Join(systemParts, "\n\n"))
+	}
+
+	// tools
+	if tools := root.Get("tools"); tools.IsArray() {
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			if tool.Get("type").String() != "function" {
+				return true
+			}
+			fn := tool.Get("function")
+			t := `{"name":"","input_schema":{"type":"object","properties":{}}}`
+			t, _ = sjson.Set(t, "name", fn.Get("name").String())
+			if desc := fn.Get("description").String(); desc != "" {
+				t, _ = sjson.Set(t, "description", desc)
+			}
+			if params := fn.Get("parameters"); params.Exists() && params.IsObject() {
+				t, _ = sjson.SetRaw(t, "input_schema", params.Raw)
+			}
+			out, _ = sjson.SetRaw(out, "tools.-1", t)
+			return true
+		})
+	}
+	// web_search_options → web_search 服务端工具
+	if st := ServerToolFromChat(root.Get("web_search_options")); st != nil {
+		out, _ = sjson.Set(out, "tools.-1", st.ClaudeTool())
+	}
+
+	// tool_choice
+	if toolChoice := root.Get("tool_choice"); toolChoice.Exists() {
+		switch {
+		case toolChoice.IsObject():
+			if name := toolChoice.Get("function.name").String(); name != "" {
+				out, _ = sjson.Set(out, "tool_choice.type", "tool")
+				out, _ = sjson.Set(out, "tool_choice.name", name)
+			}
+		case toolChoice.String() == "required":
+			out, _ = sjson.Set(out, "tool_choice.type", "any")
+		case toolChoice.String() == "none":
+			out, _ = sjson.Set(out, "tool_choice.type", "none")
+		case toolChoice.String() == "auto":
+			out, _ = sjson.Set(out, "tool_choice.type", "auto")
+		}
+	}
+	if parallel := root.Get("parallel_tool_calls"); parallel.Exists() && !parallel.Bool() && gjson.Get(out, "tool_choice").Exists() {
+		out, _ = sjson.Set(out, "tool_choice.disable_parallel_tool_use", true)
+	}
+
+	// response_format → 强制工具调用（与 extended thinking 不兼容，模拟时关闭 thinking）
+	if so := StructuredOutputFromChat(root.Get("response_format").Value()); so != nil {
+		hasOtherTools := gjson.Get(out, "tools.#").Int() > 0
+		out, _ = sjson.Set(out, "tools.-1", so.ClaudeTool())
+		out, _ = sjson.Set(out, "tool_choice", so.ClaudeToolChoice(hasOtherTools))
+		out, _ = sjson.Delete(out, "thinking")
+	}
+
+	return []byte(out)
+}
+
+// ConvertOpenAIChatToResponsesRequest 将 OpenAI Chat Completions 请求转换为 Responses API 请求
+// system/developer 消息合并为 instructions，其余消息转换为 input items（store 固定为 false，不依赖会话）
+func ConvertOpenAIChatToResponsesRequest(inputRawJSON []byte) []byte {
+	out := `{"model":"","input":[],"store":false}`
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out, _ = sjson.Set(out, "model", root.Get("model").String())
+
+	if stream := root.Get("stream"); stream.Exists() {
+		out, _ = sjson.Set(out, "stream", stream.Bool())
+	}
+	if v := root.Get("max_completion_tokens"); v.Exists() && v.Int() > 0 {
+		out, _ = sjson.Set(out, "max_output_tokens", v.Int())
+	} else if v := root.Get("max_tokens"); v.Exists() && v.Int() > 0 {
+		out, _ = sjson.Set(out, "max_output_tokens", v.Int())
+	}
+	if temperature := root.Get("temperature"); temperature.Exists() {
+		out, _ = sjson.Set(out, "temperature", temperature.Float())
+	}
+	if topP := root.Get("top_p"); topP.Exists() {
+		out, _ = sjson.Set(out, "top_p", topP.Float())
+	}
+	if user := root.Get("user"); user.Exists() && user.String() != "" {
+		out, _ = sjson.Set(out, "user", user.String())
+	}
+	if parallel := root.Get("parallel_tool_calls"); parallel.Exists() {
+		out, _ = sjson.Set(out, "parallel_tool_calls", parallel.Bool())
+	}
+	if effort := root.Get("reasoning_effort"); effort.Exists() && effort.String() != "" {
+		out, _ = sjson.Set(out, "reasoning.effort", effort.String())
+	}
+	if so := StructuredOutputFromChat(root.Get("response_format").Value()); so != nil {
+		out, _ = sjson.Set(out, "text.format", so.ResponsesTextFormat())
+	}
+
+	var instructions []string
+	root.Get("messages").ForEach(func(_, m gjson.Result) bool {
+		role := m.Get("role").String()
+		switch role {
+		case "system", "developer":
+			if text := extractChatMessageText(m.Get("content")); text != "" {
+				instructions = append(instructions, text)
+			}
+		case "tool":
+			item := `{"type":"function_call_output","call_id":"","output":""}`
+			item, _ = sjson.Set(item, "call_id", m.Get("tool_call_id").String())
+			item, _ = sjson.Set(item, "output", extractChatMessageText(m.Get("content")))
+			out, _ = sjson.SetRaw(out, "input.-1", item)
+		default:
+			if role != "assistant" {
+				role = "user"
+			}
+			item := `{"type":"message","role":"","content":[]}`
+			item, _ = sjson.Set(item, "role", role)
+			item = appendChatContentAsResponsesParts(item, m.Get("content"), role)
+			if len(gjson.Get(item, "content").Array()) > 0 {
+				out, _ = sjson.SetRaw(out, "input.-1", item)
+			}
+			m.Get("tool_calls").ForEach(func(_, tc gjson.Result) bool {
+				call := `{"type":"function_call","call_id":"","name":"","arguments":""}`
+				call, _ = sjson.Set(call, "call_id", tc.Get("id").String())
+				call, _ = sjson.Set(call, "name", tc.Get("function.name").String())
+				call, _ = sjson.Set(call, "arguments", tc.Get("function.arguments").String())
+				out, _ = sjson.SetRaw(out, "input.-1", call)
+				return true
+			})
+		}
+		return true
+	})
+
+	if len(instructions) > 0 {
+		out, _ = sjson.Set(out, "instructions", strings.Join(instructions, "\n\n"))
+	}
+
+	if tools := root.Get("tools"); tools.IsArray() {
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			if tool.Get("type").String() != "function" {
+				return true
+			}
+			fn := tool.Get("function")
+			t := `{"type":"function","name":""}`
+			t, _ = sjson.Set(t, "name", fn.Get("name").String())
+			if desc := fn.Get("description").String(); desc != "" {
+				t, _ = sjson.Set(t, "description", desc)
+			}
+			if params := fn.Get("parameters"); params.Exists() {
+				t, _ = sjson.SetRaw(t, "parameters", params.Raw)
+			}
+			out, _ = sjson.SetRaw(out, "tools.-1", t)
+			return true
+		})
+	}
+	if st := ServerToolFromChat(root.Get("web_search_options")); st != nil {
+		out, _ = sjson.Set(out, "tools.-1", st.ResponsesTool())
+	}
+
+	if toolChoice := root.Get("tool_choice"); toolChoice.Exists() {
+		if toolChoice.IsObject() {
+			if name := toolChoice.Get("function.name").String(); name != "" {
+				out, _ = sjson.Set(out, "tool_choice.type", "function")
+				out, _ = sjson.Set(out, "tool_choice.name", name)
+			}
+		} else {
+			out, _ = sjson.Set(out, "tool_choice", toolChoice.String())
+		}
+	}
+
+	return []byte(out)
+}
+
+// extractChatMessageText 提取 Chat 消息 content 中的文本（字符串或 text 片段数组）
+func extractChatMessageText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return content.String()
+	}
+	if !content.IsArray() {
+		return ""
+	}
+	var parts []string
+	content.ForEach(func(_, part gjson.Result) bool {
+		if part.Get("type").String() == "text" {
+			parts = append(parts, part.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
+
+// appendChatContentAsClaudeBlocks 将 Chat content 追加为 Claude content blocks
+func appendChatContentAsClaudeBlocks(msg string, content gjson.Result) string {
+	if content.Type == gjson.String {
+		if content.String() != "" {
+			block := `{"type":"text","text":""}`
+			block, _ = sjson.Set(block, "text", content.String())
+			msg, _ = sjson.SetRaw(msg, "content.-1", block)
+		}
+		return msg
+	}
+	content.ForEach(func(_, part gjson.Result) bool {
+		switch part.Get("type").String() {
+		case "text":
+			block := `{"type":"text","text":""}`
+			block, _ = sjson.Set(block, "text", part.Get("text").String())
+			msg, _ = sjson.SetRaw(msg, "content.-1", block)
+		case "image_url", "file":
+			if raw, ok := part.Value().(map[string]interface{}); ok {
+				if m, ok := mediaFromChatPart(raw); ok {
+					if block, ok := m.claudeBlock(); ok {
+						msg, _ = sjson.Set(msg, "content.-1", block)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return msg
+}
+
+// appendChatContentAsResponsesParts 将 Chat content 追加为 Responses message content parts
+func appendChatContentAsResponsesParts(item string, content gjson.Result, role string) string {
+	textType := "input_text"
+	if role == "assistant" {
+		textType = "output_text"
+	}
+	appendText := func(text string) {
+		part := `{"type":"","text":""}`
+		part, _ = sjson.Set(part, "type", textType)
+		part, _ = sjson.Set(part, "text", text)
+		item, _ = sjson.SetRaw(item, "content.-1", part)
+	}
+
+	if content.Type == gjson.String {
+		if content.String() != "" {
+			appendText(content.String())
+		}
+		return item
+	}
+	content.ForEach(func(_, part gjson.Result) bool {
+		switch part.Get("type").String() {
+		case "text":
+			appendText(part.Get("text").String())
+		case "image_url", "file":
+			if raw, ok := part.Value().(map[string]interface{}); ok {
+				if m, ok := mediaFromChatPart(raw); ok {
+					item, _ = sjson.Set(item, "content.-1", m.responsesPart())
+				}
+			}
+		}
+		return true
+	})
+	return item
+}
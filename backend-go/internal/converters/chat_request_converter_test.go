@@ -0,0 +1,123 @@
+package converters
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIChatToClaudeRequest(t *testing.T) {
+	input := `{
+		"model": "claude-3",
+		"stream": true,
+		"max_tokens": 100,
+		"temperature": 0.3,
+		"stop": "END",
+		"user": "u1",
+		"messages": [
+			{"role": "system", "content": "be nice"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "look"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"SF\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "description": "d", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`
+
+	root := gjson.ParseBytes(ConvertOpenAIChatToClaudeRequest([]byte(input)))
+
+	if root.Get("model").String() != "claude-3" || !root.Get("stream").Bool() {
+		t.Fatalf("model/stream mismatch: %s", root.Raw)
+	}
+	if root.Get("max_tokens").Int() != 100 || root.Get("temperature").Float() != 0.3 {
+		t.Fatalf("generation params mismatch: %s", root.Raw)
+	}
+	if root.Get("system").String() != "be nice" {
+		t.Fatalf("system = %q", root.Get("system").String())
+	}
+	if root.Get("stop_sequences.0").String() != "END" || root.Get("metadata.user_id").String() != "u1" {
+		t.Fatalf("stop/user mismatch: %s", root.Raw)
+	}
+
+	msgs := root.Get("messages").Array()
+	if len(msgs) != 3 {
+		t.Fatalf("messages len = %d, want 3: %s", len(msgs), root.Get("messages").Raw)
+	}
+	if msgs[0].Get("content.1.source.type").String() != "base64" || msgs[0].Get("content.1.source.media_type").String() != "image/png" {
+		t.Fatalf("image block mismatch: %s", msgs[0].Raw)
+	}
+	if msgs[1].Get("content.0.type").String() != "tool_use" || msgs[1].Get("content.0.input.city").String() != "SF" {
+		t.Fatalf("tool_use mismatch: %s", msgs[1].Raw)
+	}
+	if msgs[2].Get("role").String() != "user" || msgs[2].Get("content.0.tool_use_id").String() != "call_1" {
+		t.Fatalf("tool_result mismatch: %s", msgs[2].Raw)
+	}
+	if root.Get("tools.0.input_schema.type").String() != "object" || root.Get("tool_choice.type").String() != "any" {
+		t.Fatalf("tools mismatch: %s", root.Raw)
+	}
+}
+
+func TestConvertOpenAIChatToClaudeRequest_DefaultsAndThinking(t *testing.T) {
+	input := `{"model":"m","temperature":0.5,"reasoning_effort":"high","messages":[{"role":"user","content":"hi"}]}`
+	root := gjson.ParseBytes(ConvertOpenAIChatToClaudeRequest([]byte(input)))
+
+	if root.Get("thinking.budget_tokens").Int() != 16384 {
+		t.Fatalf("thinking budget = %d", root.Get("thinking.budget_tokens").Int())
+	}
+	if root.Get("max_tokens").Int() <= root.Get("thinking.budget_tokens").Int() {
+		t.Fatalf("max_tokens must exceed thinking budget: %s", root.Raw)
+	}
+	if root.Get("temperature").Exists() {
+		t.Fatalf("temperature should be dropped when thinking is enabled")
+	}
+
+	plain := gjson.ParseBytes(ConvertOpenAIChatToClaudeRequest([]byte(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`)))
+	if plain.Get("max_tokens").Int() != defaultChatToClaudeMaxTokens {
+		t.Fatalf("default max_tokens = %d", plain.Get("max_tokens").Int())
+	}
+}
+
+func TestConvertOpenAIChatToResponsesRequest(t *testing.T) {
+	input := `{
+		"model": "gpt-5",
+		"max_completion_tokens": 64,
+		"reasoning_effort": "low",
+		"messages": [
+			{"role": "developer", "content": "rules"},
+			{"role": "user", "content": "hi"},
+			{"role": "assistant", "content": "calling", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "ok"}
+		],
+		"tools": [{"type": "function", "function": {"name": "f", "parameters": {"type": "object"}}}],
+		"tool_choice": {"type": "function", "function": {"name": "f"}}
+	}`
+
+	root := gjson.ParseBytes(ConvertOpenAIChatToResponsesRequest([]byte(input)))
+
+	if root.Get("instructions").String() != "rules" || root.Get("store").Bool() {
+		t.Fatalf("instructions/store mismatch: %s", root.Raw)
+	}
+	if root.Get("max_output_tokens").Int() != 64 || root.Get("reasoning.effort").String() != "low" {
+		t.Fatalf("params mismatch: %s", root.Raw)
+	}
+	items := root.Get("input").Array()
+	if len(items) != 4 {
+		t.Fatalf("input len = %d, want 4: %s", len(items), root.Get("input").Raw)
+	}
+	if items[0].Get("content.0.type").String() != "input_text" || items[1].Get("content.0.type").String() != "output_text" {
+		t.Fatalf("message parts mismatch: %s", root.Get("input").Raw)
+	}
+	if items[2].Get("type").String() != "function_call" || items[3].Get("type").String() != "function_call_output" {
+		t.Fatalf("tool items mismatch: %s", root.Get("input").Raw)
+	}
+	if root.Get("tools.0.name").String() != "f" || root.Get("tool_choice.name").String() != "f" {
+		t.Fatalf("tools mismatch: %s", root.Raw)
+	}
+}
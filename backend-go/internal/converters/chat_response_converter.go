@@ -0,0 +1,435 @@
+package converters
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// toChatState Claude/Responses SSE → Chat Completions chunk 的流式转换状态
+type toChatState struct {
+	ID        string
+	Model     string
+	Created   int64
+	RoleSent  bool
+	Done      bool
+	ToolIndex map[string]int // 源 block/output 索引 → tool_calls 索引
+	NextTool  int
+	// usage
+	InputTokens  int64
+	OutputTokens int64
+	CachedTokens int64
+	FinishReason string
+	// Structured 模拟结构化输出的工具调用还原状态
+	Structured StructuredOutputStream
+}
+
+func newToChatState(modelName string) *toChatState {
+	return &toChatState{
+		ID:        fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano()),
+		Model:     modelName,
+		Created:   time.Now().Unix(),
+		ToolIndex: make(map[string]int),
+	}
+}
+
+func getToChatState(modelName string, param *any) *toChatState {
+	if param != nil {
+		if st, ok := (*param).(*toChatState); ok && st != nil {
+			return st
+		}
+	}
+	st := newToChatState(modelName)
+	if param != nil {
+		*param = st
+	}
+	return st
+}
+
+func emitChatChunk(payload string) string {
+	return fmt.Sprintf("data: %s\n\n", payload)
+}
+
+// chunk 构建只含一个 delta 的 chat.completion.chunk
+func (st *toChatState) chunk(delta string, finishReason string) string {
+	out := `{"id":"","object":"chat.completion.chunk","created":0,"model":"","choices":[{"index":0,"delta":{},"finish_reason":null}]}`
+	out, _ = sjson.Set(out, "id", st.ID)
+	out, _ = sjson.Set(out, "created", st.Created)
+	out, _ = sjson.Set(out, "model", st.Model)
+	if delta != "" {
+		out, _ = sjson.SetRaw(out, "choices.0.delta", delta)
+	}
+	if finishReason != "" {
+		out, _ = sjson.Set(out, "choices.0.finish_reason", finishReason)
+	}
+	return emitChatChunk(out)
+}
+
+// roleChunk 首个 chunk 携带 role
+func (st *toChatState) roleChunk() []string {
+	if st.RoleSent {
+		return nil
+	}
+	st.RoleSent = true
+	return []string{st.chunk(`{"role":"assistant","content":""}`, "")}
+}
+
+// finalChunks 结束 chunk（finish_reason + usage）与 [DONE]
+func (st *toChatState) finalChunks() []string {
+	if st.Done {
+		return nil
+	}
+	st.Done = true
+	finishReason := st.FinishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	last := st.chunk("", finishReason)
+	payload := strings.TrimSuffix(strings.TrimPrefix(last, "data: "), "\n\n")
+	payload, _ = sjson.SetRaw(payload, "usage", buildChatUsage(st.InputTokens, st.OutputTokens, st.CachedTokens))
+	return append(st.roleChunk(), emitChatChunk(payload), "data: [DONE]\n\n")
+}
+
+func buildChatUsage(promptTokens, completionTokens, cachedTokens int64) string {
+	usage := `{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}`
+	usage, _ = sjson.Set(usage, "prompt_tokens", promptTokens)
+	usage, _ = sjson.Set(usage, "completion_tokens", completionTokens)
+	usage, _ = sjson.Set(usage, "total_tokens", promptTokens+completionTokens)
+	if cachedTokens > 0 {
+		usage, _ = sjson.Set(usage, "prompt_tokens_details.cached_tokens", cachedTokens)
+	}
+	return usage
+}
+
+func toolCallDelta(index int, id, name, arguments string) string {
+	tc := `{"index":0,"function":{"arguments":""}}`
+	tc, _ = sjson.Set(tc, "index", index)
+	if id != "" {
+		tc, _ = sjson.Set(tc, "id", id)
+		tc, _ = sjson.Set(tc, "type", "function")
+	}
+	if name != "" {
+		tc, _ = sjson.Set(tc, "function.name", name)
+	}
+	tc, _ = sjson.Set(tc, "function.arguments", arguments)
+	delta := `{"tool_calls":[]}`
+	delta, _ = sjson.SetRaw(delta, "tool_calls.-1", tc)
+	return delta
+}
+
+func textDelta(field, text string) string {
+	delta, _ := sjson.Set(`{}`, field, text)
+	return delta
+}
+
+// extractSSEData 提取 SSE 行中的 data 负载（非 data 行返回 nil）
+func extractSSEData(rawJSON []byte) []byte {
+	line := bytes.TrimSpace(rawJSON)
+	if !bytes.HasPrefix(line, chatDataTag) {
+		return nil
+	}
+	return bytes.TrimSpace(line[len(chatDataTag):])
+}
+
+// ConvertClaudeToOpenAIChat 将 Claude Messages SSE 行转换为 Chat Completions chunk
+// rawJSON: 单行 SSE（仅处理 data 行）；param: 状态指针（在多次调用间保持状态）
+func ConvertClaudeToOpenAIChat(modelName string, rawJSON []byte, param *any) []string {
+	data := extractSSEData(rawJSON)
+	if len(data) == 0 || !gjson.ValidBytes(data) {
+		return nil
+	}
+	st := getToChatState(modelName, param)
+	if st.Done {
+		return nil
+	}
+	root := gjson.ParseBytes(st.Structured.RewriteClaudeEvent(data))
+
+	var out []string
+	switch root.Get("type").String() {
+	case "message_start":
+		if id := root.Get("message.id").String(); id != "" {
+			st.ID = "chatcmpl-" + strings.TrimPrefix(id, "msg_")
+		}
+		st.InputTokens = root.Get("message.usage.input_tokens").Int() +
+			root.Get("message.usage.cache_read_input_tokens").Int() +
+			root.Get("message.usage.cache_creation_input_tokens").Int()
+		st.CachedTokens = root.Get("message.usage.cache_read_input_tokens").Int()
+		st.OutputTokens = root.Get("message.usage.output_tokens").Int()
+		out = append(out, st.roleChunk()...)
+	case "content_block_start":
+		out = append(out, st.roleChunk()...)
+		block := root.Get("content_block")
+		if block.Get("type").String() == "tool_use" {
+			idx := st.NextTool
+			st.ToolIndex[root.Get("index").String()] = idx
+			st.NextTool++
+			out = append(out, st.chunk(toolCallDelta(idx, block.Get("id").String(), block.Get("name").String(), ""), ""))
+		}
+	case "content_block_delta":
+		out = append(out, st.roleChunk()...)
+		delta := root.Get("delta")
+		switch delta.Get("type").String() {
+		case "text_delta":
+			out = append(out, st.chunk(textDelta("content", delta.Get("text").String()), ""))
+		case "thinking_delta":
+			out = append(out, st.chunk(textDelta("reasoning_content", delta.Get("thinking").String()), ""))
+		case "input_json_delta":
+			if idx, ok := st.ToolIndex[root.Get("index").String()]; ok {
+				out = append(out, st.chunk(toolCallDelta(idx, "", "", delta.Get("partial_json").String()), ""))
+			}
+		}
+	case "message_delta":
+		if reason := root.Get("delta.stop_reason").String(); reason != "" {
+			st.FinishReason = AnthropicStopReasonToOpenAI(reason)
+		}
+		if usage := root.Get("usage"); usage.Exists() {
+			if v := usage.Get("output_tokens"); v.Exists() {
+				st.OutputTokens = v.Int()
+			}
+			if v := usage.Get("input_tokens"); v.Exists() && v.Int() > 0 {
+				st.InputTokens = v.Int() + usage.Get("cache_read_input_tokens").Int() + usage.Get("cache_creation_input_tokens").Int()
+				st.CachedTokens = usage.Get("cache_read_input_tokens").Int()
+			}
+		}
+	case "message_stop":
+		out = append(out, st.finalChunks()...)
+	case "error":
+		st.Done = true
+		out = append(out, emitChatChunk(ConvertErrorToOpenAIChat(data)), "data: [DONE]\n\n")
+	}
+	return out
+}
+
+// ConvertClaudeToOpenAIChatNonStream 将 Claude Messages 非流式响应转换为 Chat Completions 响应
+func ConvertClaudeToOpenAIChatNonStream(modelName string, rawJSON []byte) []byte {
+	root := gjson.ParseBytes(UnwrapClaudeStructuredOutputJSON(rawJSON))
+	st := newToChatState(modelName)
+	if id := root.Get("id").String(); id != "" {
+		st.ID = "chatcmpl-" + strings.TrimPrefix(id, "msg_")
+	}
+
+	message := `{"role":"assistant","content":null}`
+	var text, reasoning strings.Builder
+	root.Get("content").ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "text":
+			text.WriteString(block.Get("text").String())
+		case "thinking":
+			reasoning.WriteString(block.Get("thinking").String())
+		case "tool_use":
+			tc := `{"id":"","type":"function","function":{"name":"","arguments":"{}"}}`
+			tc, _ = sjson.Set(tc, "id", block.Get("id").String())
+			tc, _ = sjson.Set(tc, "function.name", block.Get("name").String())
+			if input := block.Get("input"); input.Exists() {
+				tc, _ = sjson.Set(tc, "function.arguments", input.Raw)
+			}
+			message, _ = sjson.SetRaw(message, "tool_calls.-1", tc)
+		}
+		return true
+	})
+	if text.Len() > 0 {
+		message, _ = sjson.Set(message, "content", text.String())
+	}
+	if reasoning.Len() > 0 {
+		message, _ = sjson.Set(message, "reasoning_content", reasoning.String())
+	}
+	// 文本块 citations → url_citation 标注
+	_, citations := ClaudeTextCitations(root.Get("content"))
+	message = setChatAnnotations(message, citations)
+
+	usage := root.Get("usage")
+	cached := usage.Get("cache_read_input_tokens").Int()
+	prompt := usage.Get("input_tokens").Int() + cached + usage.Get("cache_creation_input_tokens").Int()
+
+	return buildChatCompletion(st, message, AnthropicStopReasonToOpenAI(root.Get("stop_reason").String()),
+		buildChatUsage(prompt, usage.Get("output_tokens").Int(), cached))
+}
+
+// ConvertResponsesToOpenAIChat 将 Responses API SSE 行转换为 Chat Completions chunk
+// rawJSON: 单行 SSE（仅处理 data 行）；param: 状态指针（在多次调用间保持状态）
+func ConvertResponsesToOpenAIChat(modelName string, rawJSON []byte, param *any) []string {
+	data := extractSSEData(rawJSON)
+	if len(data) == 0 || !gjson.ValidBytes(data) {
+		return nil
+	}
+	st := getToChatState(modelName, param)
+	if st.Done {
+		return nil
+	}
+	root := gjson.ParseBytes(data)
+
+	var out []string
+	switch root.Get("type").String() {
+	case "response.created":
+		if id := root.Get("response.id").String(); id != "" {
+			st.ID = "chatcmpl-" + strings.TrimPrefix(id, "resp_")
+		}
+		out = append(out, st.roleChunk()...)
+	case "response.output_text.delta":
+		out = append(out, st.roleChunk()...)
+		out = append(out, st.chunk(textDelta("content", root.Get("delta").String()), ""))
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		out = append(out, st.roleChunk()...)
+		out = append(out, st.chunk(textDelta("reasoning_content", root.Get("delta").String()), ""))
+	case "response.output_item.added":
+		item := root.Get("item")
+		if item.Get("type").String() == "function_call" {
+			out = append(out, st.roleChunk()...)
+			idx := st.NextTool
+			st.ToolIndex[root.Get("output_index").String()] = idx
+			st.NextTool++
+			out = append(out, st.chunk(toolCallDelta(idx, item.Get("call_id").String(), item.Get("name").String(), ""), ""))
+		}
+	case "response.function_call_arguments.delta":
+		if idx, ok := st.ToolIndex[root.Get("output_index").String()]; ok {
+			out = append(out, st.chunk(toolCallDelta(idx, "", "", root.Get("delta").String()), ""))
+		}
+	case "response.completed", "response.incomplete":
+		resp := root.Get("response")
+		st.FinishReason = responsesToChatFinishReason(resp, st.NextTool > 0)
+		usage := resp.Get("usage")
+		st.InputTokens = usage.Get("input_tokens").Int()
+		st.OutputTokens = usage.Get("output_tokens").Int()
+		st.CachedTokens = usage.Get("input_tokens_details.cached_tokens").Int()
+		out = append(out, st.finalChunks()...)
+	case "response.failed", "error":
+		st.Done = true
+		errPayload := data
+		if e := root.Get("response.error"); e.Exists() {
+			errPayload = []byte(`{"error":` + e.Raw + `}`)
+		}
+		out = append(out, emitChatChunk(ConvertErrorToOpenAIChat(errPayload)), "data: [DONE]\n\n")
+	}
+	return out
+}
+
+// ConvertResponsesToOpenAIChatNonStream 将 Responses API 非流式响应转换为 Chat Completions 响应
+func ConvertResponsesToOpenAIChatNonStream(modelName string, rawJSON []byte) []byte {
+	root := gjson.ParseBytes(rawJSON)
+	st := newToChatState(modelName)
+	if id := root.Get("id").String(); id != "" {
+		st.ID = "chatcmpl-" + strings.TrimPrefix(id, "resp_")
+	}
+
+	message := `{"role":"assistant","content":null}`
+	var text, reasoning strings.Builder
+	var citations []WebCitation
+	hasToolCalls := false
+	root.Get("output").ForEach(func(_, item gjson.Result) bool {
+		switch item.Get("type").String() {
+		case "message":
+			item.Get("content").ForEach(func(_, part gjson.Result) bool {
+				if t := part.Get("type").String(); t == "output_text" || t == "text" {
+					partText := part.Get("text").String()
+					offset := utf8.RuneCountInString(text.String())
+					citations = append(citations, WebCitationsFromAnnotations(part.Get("annotations"), partText, offset)...)
+					text.WriteString(partText)
+				}
+				return true
+			})
+			if item.Get("content").Type == gjson.String {
+				text.WriteString(item.Get("content").String())
+			}
+		case "reasoning":
+			item.Get("summary").ForEach(func(_, part gjson.Result) bool {
+				reasoning.WriteString(part.Get("text").String())
+				return true
+			})
+		case "function_call":
+			hasToolCalls = true
+			tc := `{"id":"","type":"function","function":{"name":"","arguments":""}}`
+			tc, _ = sjson.Set(tc, "id", item.Get("call_id").String())
+			tc, _ = sjson.Set(tc, "function.name", item.Get("name").String())
+			tc, _ = sjson.Set(tc, "function.arguments", item.Get("arguments").String())
+			message, _ = sjson.SetRaw(message, "tool_calls.-1", tc)
+		}
+		return true
+	})
+	if text.Len() > 0 {
+		message, _ = sjson.Set(message, "content", text.String())
+	}
+	if reasoning.Len() > 0 {
+		message, _ = sjson.Set(message, "reasoning_content", reasoning.String())
+	}
+	message = setChatAnnotations(message, citations)
+
+	usage := root.Get("usage")
+	return buildChatCompletion(st, message, responsesToChatFinishReason(root, hasToolCalls),
+		buildChatUsage(usage.Get("input_tokens").Int(), usage.Get("output_tokens").Int(), usage.Get("input_tokens_details.cached_tokens").Int()))
+}
+
+// FinishOpenAIChatStream 上游流异常结束（未收到 message_stop/response.completed）时补齐结束 chunk 与 [DONE]
+func FinishOpenAIChatStream(modelName string, param *any) []string {
+	return getToChatState(modelName, param).finalChunks()
+}
+
+// ConvertErrorToOpenAIChat 将任意上游/网关错误体转换为 OpenAI 错误格式 {"error":{"message","type"}}
+func ConvertErrorToOpenAIChat(rawJSON []byte) string {
+	out := `{"error":{"message":"","type":"api_error"}}`
+	root := gjson.ParseBytes(rawJSON)
+	errField := root.Get("error")
+
+	message := ""
+	errType := ""
+	switch {
+	case errField.IsObject():
+		message = errField.Get("message").String()
+		errType = errField.Get("type").String()
+		if errType == "" {
+			errType = errField.Get("status").String()
+		}
+	case errField.Exists():
+		errType = errField.String()
+		message = root.Get("message").String()
+		if message == "" {
+			message = errField.String()
+		}
+	default:
+		message = root.Get("message").String()
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(rawJSON))
+	}
+	out, _ = sjson.Set(out, "error.message", message)
+	if errType != "" {
+		out, _ = sjson.Set(out, "error.type", errType)
+	}
+	return out
+}
+
+func responsesToChatFinishReason(resp gjson.Result, hasToolCalls bool) string {
+	if resp.Get("status").String() == "incomplete" {
+		if resp.Get("incomplete_details.reason").String() == "content_filter" {
+			return "content_filter"
+		}
+		return "length"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// setChatAnnotations 将联网搜索引用写入 Chat message 的 annotations
+func setChatAnnotations(message string, citations []WebCitation) string {
+	for _, c := range citations {
+		message, _ = sjson.Set(message, "annotations.-1", c.ChatAnnotation())
+	}
+	return message
+}
+
+func buildChatCompletion(st *toChatState, message, finishReason, usage string) []byte {
+	out := `{"id":"","object":"chat.completion","created":0,"model":"","choices":[{"index":0,"message":{},"finish_reason":""}]}`
+	out, _ = sjson.Set(out, "id", st.ID)
+	out, _ = sjson.Set(out, "created", st.Created)
+	out, _ = sjson.Set(out, "model", st.Model)
+	out, _ = sjson.SetRaw(out, "choices.0.message", message)
+	out, _ = sjson.Set(out, "choices.0.finish_reason", finishReason)
+	out, _ = sjson.SetRaw(out, "usage", usage)
+	return []byte(out)
+}
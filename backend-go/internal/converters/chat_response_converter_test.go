@@ -0,0 +1,140 @@
+package converters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func collectChatChunks(t *testing.T, lines []string, convert func(line []byte, param *any) []string) []gjson.Result {
+	t.Helper()
+	var state any
+	var chunks []gjson.Result
+	done := false
+	for _, line := range lines {
+		for _, c := range convert([]byte(line), &state) {
+			payload := strings.TrimSpace(strings.TrimPrefix(c, "data: "))
+			if payload == "[DONE]" {
+				done = true
+				continue
+			}
+			chunks = append(chunks, gjson.Parse(payload))
+		}
+	}
+	if !done {
+		t.Fatalf("stream should end with [DONE]")
+	}
+	return chunks
+}
+
+func TestConvertClaudeToOpenAIChat_Stream(t *testing.T) {
+	lines := []string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":5,"cache_read_input_tokens":2}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hel"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f"}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"a\":1}"}}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+		`data: {"type":"message_stop"}`,
+	}
+	chunks := collectChatChunks(t, lines, func(line []byte, param *any) []string {
+		return ConvertClaudeToOpenAIChat("m", line, param)
+	})
+
+	var text, args strings.Builder
+	for _, c := range chunks {
+		if c.Get("id").String() != "chatcmpl-1" || c.Get("object").String() != "chat.completion.chunk" {
+			t.Fatalf("chunk header mismatch: %s", c.Raw)
+		}
+		text.WriteString(c.Get("choices.0.delta.content").String())
+		args.WriteString(c.Get("choices.0.delta.tool_calls.0.function.arguments").String())
+	}
+	if text.String() != "hello" || args.String() != `{"a":1}` {
+		t.Fatalf("text=%q args=%q", text.String(), args.String())
+	}
+
+	last := chunks[len(chunks)-1]
+	if last.Get("choices.0.finish_reason").String() != "tool_calls" {
+		t.Fatalf("finish_reason = %q", last.Get("choices.0.finish_reason").String())
+	}
+	if last.Get("usage.prompt_tokens").Int() != 7 || last.Get("usage.completion_tokens").Int() != 7 || last.Get("usage.prompt_tokens_details.cached_tokens").Int() != 2 {
+		t.Fatalf("usage mismatch: %s", last.Get("usage").Raw)
+	}
+}
+
+func TestConvertClaudeToOpenAIChatNonStream(t *testing.T) {
+	body := `{"id":"msg_9","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"hi"},{"type":"tool_use","id":"toolu_1","name":"f","input":{"x":"y"}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":4}}`
+	root := gjson.ParseBytes(ConvertClaudeToOpenAIChatNonStream("m", []byte(body)))
+
+	if root.Get("id").String() != "chatcmpl-9" || root.Get("model").String() != "m" {
+		t.Fatalf("header mismatch: %s", root.Raw)
+	}
+	msg := root.Get("choices.0.message")
+	if msg.Get("content").String() != "hi" || msg.Get("reasoning_content").String() != "hmm" {
+		t.Fatalf("message mismatch: %s", msg.Raw)
+	}
+	if msg.Get("tool_calls.0.function.arguments").String() != `{"x":"y"}` {
+		t.Fatalf("tool_calls mismatch: %s", msg.Raw)
+	}
+	if root.Get("choices.0.finish_reason").String() != "tool_calls" || root.Get("usage.total_tokens").Int() != 7 {
+		t.Fatalf("finish/usage mismatch: %s", root.Raw)
+	}
+}
+
+func TestConvertResponsesToOpenAIChat_Stream(t *testing.T) {
+	lines := []string{
+		`event: response.created`,
+		`data: {"type":"response.created","response":{"id":"resp_1"}}`,
+		`data: {"type":"response.output_text.delta","delta":"hi"}`,
+		`data: {"type":"response.output_item.added","output_index":1,"item":{"type":"function_call","call_id":"call_1","name":"f"}}`,
+		`data: {"type":"response.function_call_arguments.delta","output_index":1,"delta":"{}"}`,
+		`data: {"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":2,"output_tokens":3}}}`,
+	}
+	chunks := collectChatChunks(t, lines, func(line []byte, param *any) []string {
+		return ConvertResponsesToOpenAIChat("m", line, param)
+	})
+
+	if chunks[0].Get("id").String() != "chatcmpl-1" || chunks[0].Get("choices.0.delta.role").String() != "assistant" {
+		t.Fatalf("first chunk mismatch: %s", chunks[0].Raw)
+	}
+	last := chunks[len(chunks)-1]
+	if last.Get("choices.0.finish_reason").String() != "tool_calls" || last.Get("usage.total_tokens").Int() != 5 {
+		t.Fatalf("last chunk mismatch: %s", last.Raw)
+	}
+}
+
+func TestConvertResponsesToOpenAIChatNonStream(t *testing.T) {
+	body := `{"id":"resp_2","status":"incomplete","output":[{"type":"reasoning","summary":[{"type":"summary_text","text":"r"}]},{"type":"message","content":[{"type":"output_text","text":"partial"}]}],"usage":{"input_tokens":1,"output_tokens":2}}`
+	root := gjson.ParseBytes(ConvertResponsesToOpenAIChatNonStream("m", []byte(body)))
+
+	if root.Get("choices.0.message.content").String() != "partial" || root.Get("choices.0.message.reasoning_content").String() != "r" {
+		t.Fatalf("message mismatch: %s", root.Raw)
+	}
+	if root.Get("choices.0.finish_reason").String() != "length" {
+		t.Fatalf("finish_reason = %q", root.Get("choices.0.finish_reason").String())
+	}
+}
+
+func TestConvertErrorToOpenAIChat(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantMsg  string
+		wantType string
+	}{
+		{"claude error", `{"type":"error","error":{"type":"overloaded_error","message":"busy"}}`, "busy", "overloaded_error"},
+		{"gateway string error", `{"error":"insufficient_balance","message":"余额不足"}`, "余额不足", "insufficient_balance"},
+		{"plain text", `upstream down`, "upstream down", "api_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := gjson.Parse(ConvertErrorToOpenAIChat([]byte(tt.body)))
+			if root.Get("error.message").String() != tt.wantMsg || root.Get("error.type").String() != tt.wantType {
+				t.Fatalf("got %s", root.Raw)
+			}
+		})
+	}
+}
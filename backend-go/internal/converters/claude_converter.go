@@ -44,6 +44,32 @@ func (c *ClaudeConverter) ToProviderRequest(sess *session.Session, req *types.Re
 		claudeReq["stop_sequences"] = req.Stop // Claude 使用 stop_sequences
 	}
 
+	// reasoning.effort → thinking（开启 thinking 时 Claude 不接受自定义 temperature/top_p）
+	if budget := ReasoningEffortToBudget(extractResponsesReasoningEffort(req)); budget > 0 {
+		claudeReq["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		delete(claudeReq, "temperature")
+		delete(claudeReq, "top_p")
+		if req.MaxTokens <= budget {
+			claudeReq["max_tokens"] = budget + defaultChatToClaudeMaxTokens
+		}
+	}
+
+	// 服务端工具（函数工具暂不转换）
+	serverTools, err := serverToolsFromResponsesTools(req.Tools, ToolProtocolClaude)
+	if err != nil {
+		return nil, err
+	}
+	if len(serverTools) > 0 {
+		tools := make([]map[string]interface{}, 0, len(serverTools))
+		for _, st := range serverTools {
+			tools = append(tools, st.ClaudeTool())
+		}
+		claudeReq["tools"] = tools
+	}
+
+	// text.format → 强制调用 structured_output 工具
+	applyClaudeStructuredOutput(claudeReq, StructuredOutputFromResponses(req.Text))
+
 	return claudeReq, nil
 }
 
@@ -0,0 +1,540 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== Claude Messages 请求 → Responses 请求 ==============
+
+// ConvertClaudeToResponsesRequest 将 Claude Messages 请求转换为 Responses 请求
+// 转换内容包括:
+// 1. system → instructions
+// 2. text/image/document 内容块 → message 条目（user: input_text/input_image/input_file，assistant: output_text）
+// 3. tool_use → function_call，tool_result → function_call_output
+// 4. thinking.budget_tokens → reasoning.effort（thinking 内容块无法回放，跳过）
+// 5. tools / tool_choice / max_tokens 等参数映射
+// 6. output_format → text.format
+// 服务端工具没有 Responses 等价物时返回 *UnsupportedServerToolError
+func ConvertClaudeToResponsesRequest(inputRawJSON []byte, model string) ([]byte, error) {
+	out := `{"model":"","input":[],"store":false,"parallel_tool_calls":true}`
+	root := gjson.ParseBytes(inputRawJSON)
+
+	out, _ = sjson.Set(out, "model", model)
+	if stream := root.Get("stream"); stream.Exists() {
+		out, _ = sjson.Set(out, "stream", stream.Bool())
+	}
+	if maxTokens := root.Get("max_tokens"); maxTokens.Int() > 0 {
+		out, _ = sjson.Set(out, "max_output_tokens", maxTokens.Int())
+	}
+
+	// system → instructions
+	if system := root.Get("system"); system.Exists() {
+		if text := extractClaudeText(system); text != "" {
+			out, _ = sjson.Set(out, "instructions", text)
+		}
+	}
+
+	// thinking → reasoning
+	if thinking := root.Get("thinking"); thinking.Get("type").String() == "enabled" {
+		if effort := ReasoningBudgetToEffort(int(thinking.Get("budget_tokens").Int())); effort != "" {
+			out, _ = sjson.Set(out, "reasoning.effort", effort)
+			out, _ = sjson.Set(out, "reasoning.summary", "auto")
+		}
+	} else {
+		if temperature := root.Get("temperature"); temperature.Exists() {
+			out, _ = sjson.Set(out, "temperature", temperature.Float())
+		}
+		if topP := root.Get("top_p"); topP.Exists() {
+			out, _ = sjson.Set(out, "top_p", topP.Float())
+		}
+	}
+
+	// messages → input
+	root.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		out = appendClaudeMessageAsResponsesItems(out, msg)
+		return true
+	})
+
+	// tools（服务端工具经注册表映射，客户端内置工具如 bash_20250124 跳过）
+	var toolErr error
+	root.Get("tools").ForEach(func(_, tool gjson.Result) bool {
+		st, err := ServerToolFromClaudeJSON(tool, ToolProtocolResponses)
+		if err != nil {
+			toolErr = err
+			return false
+		}
+		if st != nil {
+			out, _ = sjson.Set(out, "tools.-1", st.ResponsesTool())
+			return true
+		}
+		toolType := tool.Get("type").String()
+		if toolType != "" && toolType != "custom" {
+			return true
+		}
+		if tool.Get("name").String() == "" {
+			return true
+		}
+		t := `{"type":"function","name":""}`
+		t, _ = sjson.Set(t, "name", tool.Get("name").String())
+		if desc := tool.Get("description").String(); desc != "" {
+			t, _ = sjson.Set(t, "description", desc)
+		}
+		if schema := tool.Get("input_schema"); schema.Exists() {
+			t, _ = sjson.SetRaw(t, "parameters", schema.Raw)
+		} else {
+			t, _ = sjson.SetRaw(t, "parameters", `{"type":"object","properties":{}}`)
+		}
+		out, _ = sjson.SetRaw(out, "tools.-1", t)
+		return true
+	})
+	if toolErr != nil {
+		return nil, toolErr
+	}
+
+	// tool_choice
+	if tc := root.Get("tool_choice"); tc.Exists() {
+		switch tc.Get("type").String() {
+		case "auto":
+			out, _ = sjson.Set(out, "tool_choice", "auto")
+		case "any":
+			out, _ = sjson.Set(out, "tool_choice", "required")
+		case "none":
+			out, _ = sjson.Set(out, "tool_choice", "none")
+		case "tool":
+			out, _ = sjson.Set(out, "tool_choice.type", "function")
+			out, _ = sjson.Set(out, "tool_choice.name", tc.Get("name").String())
+		}
+		if tc.Get("disable_parallel_tool_use").Bool() {
+			out, _ = sjson.Set(out, "parallel_tool_calls", false)
+		}
+	}
+
+	if so := StructuredOutputFromClaude(root.Get("output_format").Value()); so != nil {
+		out, _ = sjson.Set(out, "text.format", so.ResponsesTextFormat())
+	}
+
+	return []byte(out), nil
+}
+
+// appendClaudeMessageAsResponsesItems 将单条 Claude 消息追加为 Responses input 条目
+// 内容块按原顺序转换：连续的 text/image/document 合并为一个 message 条目，tool_use/tool_result 单独成条
+func appendClaudeMessageAsResponsesItems(out string, msg gjson.Result) string {
+	role := msg.Get("role").String()
+	if role != "assistant" {
+		role = "user"
+	}
+	textType := "input_text"
+	if role == "assistant" {
+		textType = "output_text"
+	}
+
+	content := msg.Get("content")
+	if content.Type == gjson.String {
+		if content.String() == "" {
+			return out
+		}
+		item := `{"type":"message","role":"","content":[]}`
+		item, _ = sjson.Set(item, "role", role)
+		item, _ = sjson.Set(item, "content.0.type", textType)
+		item, _ = sjson.Set(item, "content.0.text", content.String())
+		out, _ = sjson.SetRaw(out, "input.-1", item)
+		return out
+	}
+
+	pending := ""
+	flush := func() {
+		if pending == "" {
+			return
+		}
+		out, _ = sjson.SetRaw(out, "input.-1", pending)
+		pending = ""
+	}
+	appendPart := func(part string) {
+		if pending == "" {
+			pending = `{"type":"message","role":"","content":[]}`
+			pending, _ = sjson.Set(pending, "role", role)
+		}
+		pending, _ = sjson.SetRaw(pending, "content.-1", part)
+	}
+
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "text":
+			if text := block.Get("text").String(); text != "" {
+				part := `{"type":"","text":""}`
+				part, _ = sjson.Set(part, "type", textType)
+				part, _ = sjson.Set(part, "text", text)
+				appendPart(part)
+			}
+		case "image", "document":
+			if role == "user" {
+				if raw, ok := block.Value().(map[string]interface{}); ok {
+					if m, ok := mediaFromClaudeBlock(raw); ok {
+						part, _ := json.Marshal(m.responsesPart())
+						appendPart(string(part))
+					}
+				}
+			}
+		case "tool_use":
+			flush()
+			args := block.Get("input").Raw
+			if args == "" {
+				args = "{}"
+			}
+			item := `{"type":"function_call","call_id":"","name":"","arguments":""}`
+			item, _ = sjson.Set(item, "call_id", block.Get("id").String())
+			item, _ = sjson.Set(item, "name", block.Get("name").String())
+			item, _ = sjson.Set(item, "arguments", args)
+			out, _ = sjson.SetRaw(out, "input.-1", item)
+		case "tool_result":
+			flush()
+			output := extractClaudeText(block.Get("content"))
+			if block.Get("is_error").Bool() && output != "" {
+				output = "Error: " + output
+			}
+			item := `{"type":"function_call_output","call_id":"","output":""}`
+			item, _ = sjson.Set(item, "call_id", block.Get("tool_use_id").String())
+			item, _ = sjson.Set(item, "output", output)
+			out, _ = sjson.SetRaw(out, "input.-1", item)
+		}
+		return true
+	})
+	flush()
+	return out
+}
+
+// extractClaudeText 提取 Claude content（string 或内容块数组）中的文本
+func extractClaudeText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return content.String()
+	}
+	if content.IsObject() {
+		return content.Get("text").String()
+	}
+	var parts []string
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() == "text" {
+			parts = append(parts, block.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
+
+// ============== Responses SSE → Claude SSE ==============
+
+// responsesToClaudeState Responses → Claude 流式转换状态
+type responsesToClaudeState struct {
+	Model        string
+	Started      bool
+	Finished     bool
+	BlockIndex   int         // 当前（最近打开的）内容块索引
+	NextIndex    int         // 下一个内容块索引
+	OpenBlock    string      // 当前打开的内容块类型：text / thinking / tool_use
+	ToolBlocks   map[int]int // output_index → content block index
+	ToolArgsSent map[int]bool
+	HasToolUse   bool
+}
+
+// ConvertResponsesToClaude 将 Responses SSE 行转换为 Claude Messages SSE 事件
+// param 用于跨调用保存状态（首次调用传入指向 nil 的指针）
+func ConvertResponsesToClaude(modelName string, rawJSON []byte, param *any) []string {
+	if *param == nil {
+		*param = &responsesToClaudeState{
+			Model:        modelName,
+			ToolBlocks:   make(map[int]int),
+			ToolArgsSent: make(map[int]bool),
+		}
+	}
+	st := (*param).(*responsesToClaudeState)
+
+	data := extractSSEData(rawJSON)
+	if len(data) == 0 || !gjson.ValidBytes(data) || st.Finished {
+		return nil
+	}
+	root := gjson.ParseBytes(data)
+
+	var out []string
+	switch root.Get("type").String() {
+	case "response.created", "response.in_progress":
+		out = append(out, st.start(root.Get("response"))...)
+
+	case "response.output_text.delta":
+		out = append(out, st.start(gjson.Result{})...)
+		out = append(out, st.ensureBlock("text", `{"type":"text","text":""}`)...)
+		delta := `{"type":"text_delta","text":""}`
+		delta, _ = sjson.Set(delta, "text", root.Get("delta").String())
+		out = append(out, st.blockDelta(st.BlockIndex, delta))
+
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		out = append(out, st.start(gjson.Result{})...)
+		out = append(out, st.ensureBlock("thinking", `{"type":"thinking","thinking":""}`)...)
+		delta := `{"type":"thinking_delta","thinking":""}`
+		delta, _ = sjson.Set(delta, "thinking", root.Get("delta").String())
+		out = append(out, st.blockDelta(st.BlockIndex, delta))
+
+	case "response.output_item.added":
+		item := root.Get("item")
+		if item.Get("type").String() != "function_call" {
+			break
+		}
+		out = append(out, st.start(gjson.Result{})...)
+		out = append(out, st.closeBlock()...)
+		block := `{"type":"tool_use","id":"","name":"","input":{}}`
+		block, _ = sjson.Set(block, "id", item.Get("call_id").String())
+		block, _ = sjson.Set(block, "name", item.Get("name").String())
+		out = append(out, st.openBlock("tool_use", block))
+		st.ToolBlocks[int(root.Get("output_index").Int())] = st.BlockIndex
+		st.HasToolUse = true
+
+	case "response.function_call_arguments.delta":
+		outputIndex := int(root.Get("output_index").Int())
+		index, ok := st.ToolBlocks[outputIndex]
+		if !ok || root.Get("delta").String() == "" {
+			break
+		}
+		delta := `{"type":"input_json_delta","partial_json":""}`
+		delta, _ = sjson.Set(delta, "partial_json", root.Get("delta").String())
+		out = append(out, st.blockDelta(index, delta))
+		st.ToolArgsSent[outputIndex] = true
+
+	case "response.output_text.annotation.added":
+		// url_citation → 当前文本块的 citations_delta
+		if st.OpenBlock != "text" {
+			break
+		}
+		for _, citation := range WebCitationsFromAnnotations(gjson.Parse("["+root.Get("annotation").Raw+"]"), "", 0) {
+			out = append(out, ClaudeCitationDeltaEvent(st.BlockIndex, citation))
+		}
+
+	case "response.output_item.done":
+		// 服务端工具调用完成后一次性输出 server_tool_use 与结果块
+		if call := ServerToolCallFromResponsesItem(root.Get("item")); call != nil {
+			out = append(out, st.start(gjson.Result{})...)
+			out = append(out, st.closeBlock()...)
+			for _, block := range call.ClaudeBlocks() {
+				out = append(out, ClaudeBlockEvents(st.NextIndex, block)...)
+				st.NextIndex++
+			}
+			break
+		}
+		outputIndex := int(root.Get("output_index").Int())
+		index, ok := st.ToolBlocks[outputIndex]
+		if !ok {
+			break
+		}
+		// 部分上游只在 done 事件中给出完整 arguments
+		if !st.ToolArgsSent[outputIndex] {
+			if args := root.Get("item.arguments").String(); args != "" {
+				delta := `{"type":"input_json_delta","partial_json":""}`
+				delta, _ = sjson.Set(delta, "partial_json", args)
+				out = append(out, st.blockDelta(index, delta))
+			}
+		}
+		if st.OpenBlock == "tool_use" && index == st.BlockIndex {
+			out = append(out, st.closeBlock()...)
+		}
+		delete(st.ToolBlocks, outputIndex)
+
+	case "response.completed", "response.incomplete":
+		resp := root.Get("response")
+		out = append(out, st.start(resp)...)
+		out = append(out, st.closeBlock()...)
+		out = append(out, st.finish(resp)...)
+
+	case "response.failed", "error":
+		msg := root.Get("response.error.message").String()
+		if msg == "" {
+			msg = root.Get("message").String()
+		}
+		if msg == "" {
+			msg = root.Get("error.message").String()
+		}
+		if msg == "" {
+			msg = "upstream response failed"
+		}
+		ev := `{"type":"error","error":{"type":"api_error","message":""}}`
+		ev, _ = sjson.Set(ev, "error.message", msg)
+		out = append(out, claudeSSE("error", ev))
+		st.Finished = true
+	}
+	return out
+}
+
+// start 在首个事件前输出 message_start
+func (st *responsesToClaudeState) start(resp gjson.Result) []string {
+	if st.Started {
+		return nil
+	}
+	st.Started = true
+
+	msgID := resp.Get("id").String()
+	if msgID == "" {
+		msgID = fmt.Sprintf("resp_%d", getCurrentTimestamp())
+	}
+	model := st.Model
+	if model == "" {
+		model = resp.Get("model").String()
+	}
+	ev := `{"type":"message_start","message":{"id":"","type":"message","role":"assistant","model":"","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}}`
+	ev, _ = sjson.Set(ev, "message.id", "msg_"+strings.TrimPrefix(msgID, "resp_"))
+	ev, _ = sjson.Set(ev, "message.model", model)
+	return []string{claudeSSE("message_start", ev)}
+}
+
+// ensureBlock 确保当前打开的是指定类型的内容块
+func (st *responsesToClaudeState) ensureBlock(blockType, contentBlock string) []string {
+	if st.OpenBlock == blockType {
+		return nil
+	}
+	out := st.closeBlock()
+	return append(out, st.openBlock(blockType, contentBlock))
+}
+
+func (st *responsesToClaudeState) openBlock(blockType, contentBlock string) string {
+	st.BlockIndex = st.NextIndex
+	st.NextIndex++
+	st.OpenBlock = blockType
+	ev := `{"type":"content_block_start","index":0,"content_block":{}}`
+	ev, _ = sjson.Set(ev, "index", st.BlockIndex)
+	ev, _ = sjson.SetRaw(ev, "content_block", contentBlock)
+	return claudeSSE("content_block_start", ev)
+}
+
+func (st *responsesToClaudeState) closeBlock() []string {
+	if st.OpenBlock == "" {
+		return nil
+	}
+	st.OpenBlock = ""
+	ev := `{"type":"content_block_stop","index":0}`
+	ev, _ = sjson.Set(ev, "index", st.BlockIndex)
+	return []string{claudeSSE("content_block_stop", ev)}
+}
+
+func (st *responsesToClaudeState) blockDelta(index int, delta string) string {
+	ev := `{"type":"content_block_delta","index":0,"delta":{}}`
+	ev, _ = sjson.Set(ev, "index", index)
+	ev, _ = sjson.SetRaw(ev, "delta", delta)
+	return claudeSSE("content_block_delta", ev)
+}
+
+// finish 输出 message_delta（stop_reason 与 usage）与 message_stop
+func (st *responsesToClaudeState) finish(resp gjson.Result) []string {
+	st.Finished = true
+
+	ev := `{"type":"message_delta","delta":{"stop_reason":"","stop_sequence":null},"usage":{}}`
+	ev, _ = sjson.Set(ev, "delta.stop_reason", responsesToClaudeStopReason(resp, st.HasToolUse))
+	ev, _ = sjson.SetRaw(ev, "usage", buildClaudeUsageFromResponses(resp.Get("usage")))
+	return []string{
+		claudeSSE("message_delta", ev),
+		claudeSSE("message_stop", `{"type":"message_stop"}`),
+	}
+}
+
+// responsesToClaudeStopReason 根据 Responses 状态推断 Claude stop_reason
+func responsesToClaudeStopReason(resp gjson.Result, hasToolUse bool) string {
+	if hasToolUse {
+		return "tool_use"
+	}
+	if resp.Get("status").String() == "incomplete" {
+		if reason := resp.Get("incomplete_details.reason").String(); reason == "" || reason == "max_output_tokens" {
+			return "max_tokens"
+		}
+	}
+	return "end_turn"
+}
+
+// buildClaudeUsageFromResponses 将 Responses usage 转换为 Claude usage
+// Responses 的 input_tokens 包含缓存命中部分，Claude 的 input_tokens 不包含
+func buildClaudeUsageFromResponses(usage gjson.Result) string {
+	cached := usage.Get("input_tokens_details.cached_tokens").Int()
+	input := usage.Get("input_tokens").Int() - cached
+	if input < 0 {
+		input = 0
+	}
+	out := `{"input_tokens":0,"output_tokens":0}`
+	out, _ = sjson.Set(out, "input_tokens", input)
+	out, _ = sjson.Set(out, "output_tokens", usage.Get("output_tokens").Int())
+	if cached > 0 {
+		out, _ = sjson.Set(out, "cache_read_input_tokens", cached)
+	}
+	return out
+}
+
+func claudeSSE(event, data string) string {
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+}
+
+// ============== Responses 响应 → Claude 响应 ==============
+
+// ResponsesToClaudeResponse 将 Responses 非流式响应转换为 Claude 响应
+func ResponsesToClaudeResponse(rawJSON []byte) (*types.ClaudeResponse, error) {
+	if !gjson.ValidBytes(rawJSON) {
+		return nil, fmt.Errorf("无效的 Responses 响应")
+	}
+	root := gjson.ParseBytes(rawJSON)
+
+	claudeResp := &types.ClaudeResponse{
+		ID:      "msg_" + strings.TrimPrefix(root.Get("id").String(), "resp_"),
+		Type:    "message",
+		Role:    "assistant",
+		Content: []types.ClaudeContent{},
+	}
+
+	hasToolUse := false
+	root.Get("output").ForEach(func(_, item gjson.Result) bool {
+		switch item.Get("type").String() {
+		case "reasoning":
+			var parts []string
+			item.Get("summary").ForEach(func(_, s gjson.Result) bool {
+				parts = append(parts, s.Get("text").String())
+				return true
+			})
+			if text := strings.Join(parts, "\n\n"); text != "" {
+				claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{Type: "thinking", Thinking: text})
+			}
+		case "message":
+			item.Get("content").ForEach(func(_, part gjson.Result) bool {
+				if t := part.Get("type").String(); (t == "output_text" || t == "text") && part.Get("text").String() != "" {
+					text := part.Get("text").String()
+					block := []types.ClaudeContent{{Type: "text", Text: text}}
+					citations := WebCitationsFromAnnotations(part.Get("annotations"), text, 0)
+					claudeResp.Content = append(claudeResp.Content, ApplyWebCitations(block, citations)...)
+				}
+				return true
+			})
+		case "web_search_call", "code_interpreter_call":
+			claudeResp.Content = append(claudeResp.Content, ServerToolCallFromResponsesItem(item).ClaudeBlocks()...)
+		case "function_call":
+			var input interface{}
+			if err := json.Unmarshal([]byte(item.Get("arguments").String()), &input); err != nil || input == nil {
+				input = map[string]interface{}{}
+			}
+			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+				Type:  "tool_use",
+				ID:    item.Get("call_id").String(),
+				Name:  item.Get("name").String(),
+				Input: input,
+			})
+			hasToolUse = true
+		}
+		return true
+	})
+
+	claudeResp.StopReason = responsesToClaudeStopReason(root, hasToolUse)
+
+	if usage := root.Get("usage"); usage.Exists() {
+		var u types.Usage
+		if err := json.Unmarshal([]byte(buildClaudeUsageFromResponses(usage)), &u); err == nil {
+			claudeResp.Usage = &u
+		}
+	}
+
+	return claudeResp, nil
+}
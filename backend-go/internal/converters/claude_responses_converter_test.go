@@ -0,0 +1,197 @@
+package converters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertClaudeToResponsesRequest(t *testing.T) {
+	body := `{
+		"model":"claude-sonnet-4",
+		"max_tokens":4096,
+		"stream":true,
+		"system":[{"type":"text","text":"You are Claude Code."}],
+		"thinking":{"type":"enabled","budget_tokens":10000},
+		"temperature":1,
+		"messages":[
+			{"role":"user","content":[{"type":"text","text":"list files"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}]},
+			{"role":"assistant","content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"text","text":"Sure."},{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"ls"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"a.go"}]},{"type":"text","text":"thanks"}]}
+		],
+		"tools":[{"name":"Bash","description":"run","input_schema":{"type":"object","properties":{"command":{"type":"string"}}}},{"type":"web_search_20250305","name":"web_search"}],
+		"tool_choice":{"type":"any","disable_parallel_tool_use":true}
+	}`
+	converted, err := ConvertClaudeToResponsesRequest([]byte(body), "gpt-5-codex")
+	if err != nil {
+		t.Fatalf("ConvertClaudeToResponsesRequest: %v", err)
+	}
+	root := gjson.ParseBytes(converted)
+
+	if root.Get("model").String() != "gpt-5-codex" || root.Get("instructions").String() != "You are Claude Code." {
+		t.Fatalf("model/instructions mismatch: %s", root.Raw)
+	}
+	if !root.Get("stream").Bool() || root.Get("store").Bool() || root.Get("max_output_tokens").Int() != 4096 {
+		t.Fatalf("params mismatch: %s", root.Raw)
+	}
+	if root.Get("reasoning.effort").String() != "medium" || root.Get("temperature").Exists() {
+		t.Fatalf("reasoning mismatch: %s", root.Raw)
+	}
+
+	input := root.Get("input").Array()
+	wantTypes := []string{"message", "message", "function_call", "function_call_output", "message"}
+	if len(input) != len(wantTypes) {
+		t.Fatalf("input len = %d: %s", len(input), root.Get("input").Raw)
+	}
+	for i, want := range wantTypes {
+		if got := input[i].Get("type").String(); got != want {
+			t.Fatalf("input[%d].type = %q, want %q", i, got, want)
+		}
+	}
+	if input[0].Get("content.1.image_url").String() != "data:image/png;base64,AAAA" {
+		t.Fatalf("image mismatch: %s", input[0].Raw)
+	}
+	if input[1].Get("role").String() != "assistant" || input[1].Get("content.0.type").String() != "output_text" || input[1].Get("content.#").Int() != 1 {
+		t.Fatalf("assistant message mismatch: %s", input[1].Raw)
+	}
+	if input[2].Get("call_id").String() != "toolu_1" || input[2].Get("arguments").String() != `{"command":"ls"}` {
+		t.Fatalf("function_call mismatch: %s", input[2].Raw)
+	}
+	if input[3].Get("output").String() != "a.go" {
+		t.Fatalf("function_call_output mismatch: %s", input[3].Raw)
+	}
+
+	if root.Get("tools.#").Int() != 2 || root.Get("tools.0.parameters.properties.command.type").String() != "string" || root.Get("tools.1.type").String() != "web_search" {
+		t.Fatalf("tools mismatch: %s", root.Get("tools").Raw)
+	}
+	if root.Get("tool_choice").String() != "required" || root.Get("parallel_tool_calls").Bool() {
+		t.Fatalf("tool_choice mismatch: %s", root.Raw)
+	}
+}
+
+func TestConvertResponsesToClaude_Stream(t *testing.T) {
+	lines := []string{
+		`event: response.created`,
+		`data: {"type":"response.created","response":{"id":"resp_abc","model":"gpt-5-codex"}}`,
+		`data: {"type":"response.output_item.added","output_index":0,"item":{"type":"reasoning"}}`,
+		`data: {"type":"response.reasoning_summary_text.delta","output_index":0,"delta":"think"}`,
+		`data: {"type":"response.output_text.delta","output_index":1,"delta":"Run"}`,
+		`data: {"type":"response.output_text.delta","output_index":1,"delta":"ning"}`,
+		`data: {"type":"response.output_item.added","output_index":2,"item":{"type":"function_call","call_id":"call_1","name":"Bash"}}`,
+		`data: {"type":"response.function_call_arguments.delta","output_index":2,"delta":"{\"command\":"}`,
+		`data: {"type":"response.function_call_arguments.delta","output_index":2,"delta":"\"ls\"}"}`,
+		`data: {"type":"response.output_item.done","output_index":2,"item":{"type":"function_call","arguments":"{\"command\":\"ls\"}"}}`,
+		`data: {"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":10,"input_tokens_details":{"cached_tokens":4},"output_tokens":6}}}`,
+	}
+
+	var state any
+	var events []gjson.Result
+	var names []string
+	for _, line := range lines {
+		for _, ev := range ConvertResponsesToClaude("claude-sonnet-4", []byte(line), &state) {
+			parts := strings.SplitN(strings.TrimSuffix(ev, "\n\n"), "\n", 2)
+			names = append(names, strings.TrimPrefix(parts[0], "event: "))
+			events = append(events, gjson.Parse(strings.TrimPrefix(parts[1], "data: ")))
+		}
+	}
+
+	want := []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v", names)
+	}
+
+	if events[0].Get("message.id").String() != "msg_abc" || events[0].Get("message.model").String() != "claude-sonnet-4" {
+		t.Fatalf("message_start mismatch: %s", events[0].Raw)
+	}
+	if events[1].Get("content_block.type").String() != "thinking" || events[2].Get("delta.thinking").String() != "think" {
+		t.Fatalf("thinking block mismatch: %s / %s", events[1].Raw, events[2].Raw)
+	}
+	if events[4].Get("index").Int() != 1 || events[4].Get("content_block.type").String() != "text" {
+		t.Fatalf("text block mismatch: %s", events[4].Raw)
+	}
+	if events[8].Get("index").Int() != 2 || events[8].Get("content_block.id").String() != "call_1" {
+		t.Fatalf("tool block mismatch: %s", events[8].Raw)
+	}
+	args := events[9].Get("delta.partial_json").String() + events[10].Get("delta.partial_json").String()
+	if args != `{"command":"ls"}` {
+		t.Fatalf("tool args = %q", args)
+	}
+
+	delta := events[12]
+	if delta.Get("delta.stop_reason").String() != "tool_use" {
+		t.Fatalf("stop_reason mismatch: %s", delta.Raw)
+	}
+	if delta.Get("usage.input_tokens").Int() != 6 || delta.Get("usage.cache_read_input_tokens").Int() != 4 || delta.Get("usage.output_tokens").Int() != 6 {
+		t.Fatalf("usage mismatch: %s", delta.Raw)
+	}
+}
+
+func TestConvertResponsesToClaude_ArgumentsOnlyInDone(t *testing.T) {
+	lines := []string{
+		`data: {"type":"response.output_item.added","output_index":0,"item":{"type":"function_call","call_id":"call_1","name":"f"}}`,
+		`data: {"type":"response.output_item.done","output_index":0,"item":{"type":"function_call","arguments":"{\"a\":1}"}}`,
+		`data: {"type":"response.incomplete","response":{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}}`,
+	}
+	var state any
+	var all strings.Builder
+	for _, line := range lines {
+		for _, ev := range ConvertResponsesToClaude("m", []byte(line), &state) {
+			all.WriteString(ev)
+		}
+	}
+	out := all.String()
+	if !strings.HasPrefix(out, "event: message_start") {
+		t.Fatalf("stream should start with message_start: %s", out)
+	}
+	if !strings.Contains(out, `"partial_json":"{\"a\":1}"`) || !strings.Contains(out, `"stop_reason":"tool_use"`) {
+		t.Fatalf("unexpected stream: %s", out)
+	}
+}
+
+func TestConvertResponsesToClaude_Failed(t *testing.T) {
+	var state any
+	out := ConvertResponsesToClaude("m", []byte(`data: {"type":"response.failed","response":{"status":"failed","error":{"code":"server_error","message":"boom"}}}`), &state)
+	if len(out) != 1 || !strings.HasPrefix(out[0], "event: error") || !strings.Contains(out[0], `"message":"boom"`) {
+		t.Fatalf("unexpected events: %v", out)
+	}
+	if more := ConvertResponsesToClaude("m", []byte(`data: {"type":"response.output_text.delta","delta":"x"}`), &state); len(more) != 0 {
+		t.Fatalf("events after failure should be dropped: %v", more)
+	}
+}
+
+func TestResponsesToClaudeResponse(t *testing.T) {
+	body := `{"id":"resp_9","status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"output":[
+		{"type":"reasoning","summary":[{"type":"summary_text","text":"plan"}]},
+		{"type":"message","role":"assistant","content":[{"type":"output_text","text":"partial"}]}
+	],"usage":{"input_tokens":8,"output_tokens":3}}`
+
+	resp, err := ResponsesToClaudeResponse([]byte(body))
+	if err != nil {
+		t.Fatalf("ResponsesToClaudeResponse: %v", err)
+	}
+	if resp.ID != "msg_9" || resp.StopReason != "max_tokens" {
+		t.Fatalf("id/stop_reason mismatch: %+v", resp)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != "plan" || resp.Content[1].Text != "partial" {
+		t.Fatalf("content mismatch: %+v", resp.Content)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 8 || resp.Usage.OutputTokens != 3 {
+		t.Fatalf("usage mismatch: %+v", resp.Usage)
+	}
+
+	toolBody := `{"id":"resp_10","status":"completed","output":[{"type":"function_call","call_id":"call_1","name":"f","arguments":"{\"x\":1}"}]}`
+	resp, err = ResponsesToClaudeResponse([]byte(toolBody))
+	if err != nil {
+		t.Fatalf("ResponsesToClaudeResponse: %v", err)
+	}
+	if resp.StopReason != "tool_use" || resp.Content[0].Type != "tool_use" || resp.Content[0].ID != "call_1" {
+		t.Fatalf("tool response mismatch: %+v", resp)
+	}
+}
@@ -0,0 +1,161 @@
+package converters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== OpenAI Completions（/v1/completions）==============
+//
+// 文本补全接口只接受单个 prompt，对话历史按以下格式渲染（适用于仅提供 completions 的基座模型）：
+//
+//	{system}
+//
+//	User: ...
+//
+//	Assistant: ...
+//
+//	Assistant:
+//
+// 最后一轮为 assistant 时视为预填充，直接在其后续写。
+
+// CompletionsTurn prompt 渲染使用的单轮对话
+type CompletionsTurn struct {
+	Role string // user, assistant, tool
+	Text string
+}
+
+// CompletionsStopSequences 客户端未指定 stop 时使用的默认停止序列（防止模型续写下一轮用户发言）
+var CompletionsStopSequences = []string{"\nUser:"}
+
+// completionsRoleLabel 返回角色在 prompt 中的前缀
+func completionsRoleLabel(role string) string {
+	switch role {
+	case "assistant":
+		return "Assistant"
+	case "tool":
+		return "Tool"
+	default:
+		return "User"
+	}
+}
+
+// RenderCompletionsPrompt 将 system 与对话历史渲染为 Completions prompt
+func RenderCompletionsPrompt(system string, turns []CompletionsTurn) string {
+	var sections []string
+	if s := strings.TrimSpace(system); s != "" {
+		sections = append(sections, s)
+	}
+
+	prefill := false
+	for i, turn := range turns {
+		text := strings.TrimSpace(turn.Text)
+		if text == "" {
+			continue
+		}
+		sections = append(sections, completionsRoleLabel(turn.Role)+": "+text)
+		prefill = i == len(turns)-1 && turn.Role == "assistant"
+	}
+	if !prefill {
+		sections = append(sections, "Assistant:")
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// ResponsesToCompletionsPrompt 将会话历史与新输入渲染为 Completions prompt
+func ResponsesToCompletionsPrompt(sess *session.Session, newInput interface{}, instructions string) (string, error) {
+	var turns []CompletionsTurn
+	if sess != nil {
+		for _, item := range sess.Messages {
+			if turn, ok := responsesItemToCompletionsTurn(item); ok {
+				turns = append(turns, turn)
+			}
+		}
+	}
+
+	newItems, err := parseResponsesInput(newInput)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range newItems {
+		if turn, ok := responsesItemToCompletionsTurn(item); ok {
+			turns = append(turns, turn)
+		}
+	}
+
+	return RenderCompletionsPrompt(instructions, turns), nil
+}
+
+// responsesItemToCompletionsTurn 单个 ResponsesItem 转换为 prompt 轮次
+// 函数调用与结果以文本形式保留，推理条目不参与渲染
+func responsesItemToCompletionsTurn(item types.ResponsesItem) (CompletionsTurn, bool) {
+	switch item.Type {
+	case "message", "text":
+		role := "user"
+		if item.Role == "assistant" {
+			role = "assistant"
+		}
+		text := extractTextFromContent(item.Content)
+		return CompletionsTurn{Role: role, Text: text}, text != ""
+	case "function_call":
+		return CompletionsTurn{Role: "assistant", Text: fmt.Sprintf("[tool call] %s(%s)", item.Name, item.Arguments)}, true
+	case "function_call_output":
+		output, _ := item.Output.(string)
+		if output == "" {
+			output = extractTextFromContent(item.Output)
+		}
+		return CompletionsTurn{Role: "tool", Text: output}, output != ""
+	}
+	return CompletionsTurn{}, false
+}
+
+// CompletionsChunkToChatChunk 将 Completions 流式 chunk 映射为等价的 Chat Completions chunk
+// 便于复用 Chat → Claude / Chat → Responses 的流式状态机
+func CompletionsChunkToChatChunk(data []byte) []byte {
+	root := gjson.ParseBytes(data)
+	chunk := `{"id":"","object":"chat.completion.chunk","model":"","choices":[]}`
+	chunk, _ = sjson.Set(chunk, "id", root.Get("id").String())
+	chunk, _ = sjson.Set(chunk, "model", root.Get("model").String())
+	if created := root.Get("created"); created.Exists() {
+		chunk, _ = sjson.Set(chunk, "created", created.Int())
+	}
+
+	root.Get("choices").ForEach(func(_, choice gjson.Result) bool {
+		out := `{"index":0,"delta":{}}`
+		out, _ = sjson.Set(out, "index", choice.Get("index").Int())
+		if text := choice.Get("text").String(); text != "" {
+			out, _ = sjson.Set(out, "delta.content", text)
+		}
+		if reason := choice.Get("finish_reason"); reason.Exists() && reason.Type != gjson.Null {
+			out, _ = sjson.Set(out, "finish_reason", reason.String())
+		}
+		chunk, _ = sjson.SetRaw(chunk, "choices.-1", out)
+		return true
+	})
+
+	if usage := root.Get("usage"); usage.Exists() {
+		chunk, _ = sjson.SetRaw(chunk, "usage", usage.Raw)
+	}
+	if errObj := root.Get("error"); errObj.Exists() {
+		chunk, _ = sjson.SetRaw(chunk, "error", errObj.Raw)
+	}
+	return []byte(chunk)
+}
+
+// ConvertOpenAICompletionsToResponses 将 Completions SSE 行转换为 Responses SSE 事件
+// 参数含义同 ConvertOpenAIChatToResponses
+func ConvertOpenAICompletionsToResponses(ctx context.Context, modelName string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
+	data := extractSSEData(rawJSON)
+	if len(data) == 0 || !gjson.ValidBytes(data) {
+		// [DONE] 等非 JSON 行交给 Chat 状态机处理
+		return ConvertOpenAIChatToResponses(ctx, modelName, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+	}
+	chunk := append([]byte("data: "), CompletionsChunkToChatChunk(data)...)
+	return ConvertOpenAIChatToResponses(ctx, modelName, originalRequestRawJSON, requestRawJSON, chunk, param)
+}
@@ -0,0 +1,101 @@
+package converters
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+func TestRenderCompletionsPrompt(t *testing.T) {
+	got := RenderCompletionsPrompt("be brief", []CompletionsTurn{
+		{Role: "user", Text: "hi"},
+		{Role: "assistant", Text: "hello"},
+		{Role: "tool", Text: "42"},
+		{Role: "user", Text: " "},
+	})
+	want := "be brief\n\nUser: hi\n\nAssistant: hello\n\nTool: 42\n\nAssistant:"
+	if got != want {
+		t.Fatalf("prompt = %q, want %q", got, want)
+	}
+
+	// 最后一轮为 assistant 时视为预填充，不再追加 Assistant: 引导
+	got = RenderCompletionsPrompt("", []CompletionsTurn{{Role: "user", Text: "count"}, {Role: "assistant", Text: "1, 2,"}})
+	if got != "User: count\n\nAssistant: 1, 2," {
+		t.Fatalf("prefill prompt = %q", got)
+	}
+}
+
+func TestOpenAICompletionsConverter_RendersHistory(t *testing.T) {
+	sess := &session.Session{Messages: []types.ResponsesItem{
+		{Type: "message", Role: "user", Content: "what is 6*7?"},
+		{Type: "message", Role: "assistant", Content: []types.ContentBlock{{Type: "output_text", Text: "42"}}},
+	}}
+	var req types.ResponsesRequest
+	_ = json.Unmarshal([]byte(`{"model":"base","instructions":"math tutor","stream":true,"input":[
+		{"role":"user","content":[{"type":"input_text","text":"and 6*8?"}]},
+		{"type":"function_call","call_id":"c1","name":"calc","arguments":"{\"x\":48}"},
+		{"type":"function_call_output","call_id":"c1","output":"48"}
+	]}`), &req)
+
+	out, err := NewConverter("completions").ToProviderRequest(sess, &req)
+	if err != nil {
+		t.Fatalf("ToProviderRequest: %v", err)
+	}
+	root := mustJSON(t, out)
+	want := "math tutor\n\nUser: what is 6*7?\n\nAssistant: 42\n\nUser: and 6*8?\n\nAssistant: [tool call] calc({\"x\":48})\n\nTool: 48\n\nAssistant:"
+	if got := root.Get("prompt").String(); got != want {
+		t.Fatalf("prompt = %q, want %q", got, want)
+	}
+	if root.Get("stop.0").String() != "\nUser:" || !root.Get("stream_options.include_usage").Bool() {
+		t.Fatalf("unexpected request: %s", root.Raw)
+	}
+}
+
+func TestOpenAICompletionsResponseToResponses(t *testing.T) {
+	resp, err := OpenAICompletionsResponseToResponses(map[string]interface{}{
+		"model":   "base",
+		"choices": []interface{}{map[string]interface{}{"text": " 48", "finish_reason": "length"}},
+		"usage":   map[string]interface{}{"prompt_tokens": float64(10), "completion_tokens": float64(2), "total_tokens": float64(12)},
+	}, "")
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if resp.Status != "incomplete" || resp.Usage.InputTokens != 10 || len(resp.Output) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if item := resp.Output[0]; item.Type != "message" || item.Role != "assistant" || extractTextFromContent(item.Content) != " 48" {
+		t.Fatalf("unexpected output item: %+v", item)
+	}
+}
+
+func TestConvertOpenAICompletionsToResponses_Stream(t *testing.T) {
+	var state any
+	var events []string
+	for _, line := range []string{
+		`data: {"id":"cmpl_1","model":"base","choices":[{"index":0,"text":"4","finish_reason":null}]}`,
+		`data: {"id":"cmpl_1","model":"base","choices":[{"index":0,"text":"8","finish_reason":"stop"}]}`,
+		`data: {"id":"cmpl_1","model":"base","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+		`data: [DONE]`,
+	} {
+		events = append(events, ConvertOpenAICompletionsToResponses(context.Background(), "base", []byte(`{"model":"base"}`), nil, []byte(line), &state)...)
+	}
+
+	joined := strings.Join(events, "")
+	if !strings.Contains(joined, "response.created") || !strings.Contains(joined, "response.completed") {
+		t.Fatalf("missing lifecycle events: %s", joined)
+	}
+	var completed gjson.Result
+	for _, ev := range events {
+		if data := extractSSEData([]byte(ev[strings.Index(ev, "data:"):])); gjson.GetBytes(data, "type").String() == "response.completed" {
+			completed = gjson.ParseBytes(data)
+		}
+	}
+	if completed.Get("response.output.0.content.0.text").String() != "48" {
+		t.Fatalf("unexpected completed event: %s", completed.Raw)
+	}
+}
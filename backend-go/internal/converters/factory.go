@@ -4,15 +4,19 @@
 // 根据上游服务类型返回对应的转换器实例
 
 // NewConverter 创建转换器实例
-// serviceType: "openai", "claude", "responses"
+// serviceType: "openai", "azure-openai", "claude", "responses", "gemini", "completions"
 func NewConverter(serviceType string) ResponsesConverter {
 	switch serviceType {
-	case "openai":
+	case "openai", "azure-openai":
 		return &OpenAIChatConverter{}
 	case "claude":
 		return &ClaudeConverter{}
 	case "responses":
 		return &ResponsesPassthroughConverter{}
+	case "gemini":
+		return &GeminiConverter{}
+	case "completions":
+		return &OpenAICompletionsConverter{}
 	default:
 		// 默认使用 OpenAI Chat 转换器
 		return &OpenAIChatConverter{}
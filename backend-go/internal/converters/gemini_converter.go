@@ -6,6 +6,7 @@
 	"strings"
 
 	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
 )
 
 // dummyThoughtSignature 用于跳过 Gemini 3 thought_signature 验证
@@ -59,12 +60,30 @@ func GeminiToClaudeRequest(geminiReq *types.GeminiRequest, model string) (map[st
 		if len(cfg.StopSequences) > 0 {
 			claudeReq["stop_sequences"] = cfg.StopSequences
 		}
+		// thinkingConfig → thinking（开启 thinking 时 Claude 不接受自定义 temperature/top_p/top_k）
+		if budget, enabled := GeminiThinkingToBudget(cfg.ThinkingConfig); enabled {
+			budget = ClaudeThinkingBudget(budget)
+			claudeReq["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+			delete(claudeReq, "temperature")
+			delete(claudeReq, "top_p")
+			delete(claudeReq, "top_k")
+			if cfg.MaxOutputTokens <= budget {
+				claudeReq["max_tokens"] = budget + defaultChatToClaudeMaxTokens
+			}
+		}
 	}
 
 	// 4. 转换 tools -> tools
 	if len(geminiReq.Tools) > 0 {
 		claudeTools := []map[string]interface{}{}
 		for _, tool := range geminiReq.Tools {
+			serverTools, err := ServerToolsFromGemini(tool, ToolProtocolClaude)
+			if err != nil {
+				return nil, err
+			}
+			for _, st := range serverTools {
+				claudeTools = append(claudeTools, st.ClaudeTool())
+			}
 			for _, fn := range tool.FunctionDeclarations {
 				claudeTool := map[string]interface{}{
 					"name": fn.Name,
@@ -89,6 +108,9 @@ func GeminiToClaudeRequest(geminiReq *types.GeminiRequest, model string) (map[st
 		}
 	}
 
+	// 5. responseMimeType/responseSchema → 强制调用 structured_output 工具
+	applyClaudeStructuredOutput(claudeReq, StructuredOutputFromGemini(geminiReq.GenerationConfig))
+
 	return claudeReq, nil
 }
 
@@ -138,12 +160,28 @@ func GeminiToOpenAIRequest(geminiReq *types.GeminiRequest, model string) (map[st
 		if len(cfg.StopSequences) > 0 {
 			openaiReq["stop"] = cfg.StopSequences
 		}
+		// thinkingConfig → reasoning_effort（关闭思考时不下发，并非所有模型都支持 none）
+		if effort := GeminiThinkingToReasoningEffort(cfg.ThinkingConfig); effort != "" && effort != "none" {
+			openaiReq["reasoning_effort"] = effort
+		}
+		// responseMimeType/responseSchema → response_format
+		if so := StructuredOutputFromGemini(cfg); so != nil {
+			openaiReq["response_format"] = so.ChatResponseFormat()
+		}
 	}
 
 	// 4. 转换 tools -> tools
 	if len(geminiReq.Tools) > 0 {
 		openaiTools := []map[string]interface{}{}
 		for _, tool := range geminiReq.Tools {
+			// 服务端工具：Chat 仅支持联网搜索（web_search_options）
+			serverTools, err := ServerToolsFromGemini(tool, ToolProtocolChat)
+			if err != nil {
+				return nil, err
+			}
+			for _, st := range serverTools {
+				openaiReq["web_search_options"] = st.ChatWebSearchOptions()
+			}
 			for _, fn := range tool.FunctionDeclarations {
 				openaiTool := map[string]interface{}{
 					"type": "function",
@@ -172,12 +210,24 @@ func ClaudeResponseToGemini(claudeResp map[string]interface{}) (*types.GeminiRes
 		Candidates: []types.GeminiCandidate{},
 	}
 
+	// 模拟结构化输出的工具调用还原为文本
+	UnwrapClaudeStructuredOutput(claudeResp)
+
 	// 1. 转换 content -> candidates[0].content.parts
 	content, ok := claudeResp["content"].([]interface{})
 	if !ok {
 		return geminiResp, nil
 	}
 
+	// 服务端工具：代码执行 → executableCode/codeExecutionResult，联网搜索与引用 → groundingMetadata
+	contentJSON, _ := json.Marshal(content)
+	serverCalls := ServerToolCallsFromClaude(gjson.ParseBytes(contentJSON))
+	fullText, citations := ClaudeTextCitations(gjson.ParseBytes(contentJSON))
+	serverCallByID := map[string]*ServerToolCall{}
+	for _, call := range serverCalls {
+		serverCallByID[call.ID] = call
+	}
+
 	parts := []types.GeminiPart{}
 	for _, c := range content {
 		contentBlock, ok := c.(map[string]interface{})
@@ -187,6 +237,18 @@ func ClaudeResponseToGemini(claudeResp map[string]interface{}) (*types.GeminiRes
 
 		blockType, _ := contentBlock["type"].(string)
 		switch blockType {
+		case "server_tool_use":
+			id, _ := contentBlock["id"].(string)
+			if call := serverCallByID[id]; call != nil {
+				parts = append(parts, call.GeminiParts()...)
+			}
+		case "thinking":
+			if thinking, _ := contentBlock["thinking"].(string); thinking != "" {
+				parts = append(parts, types.GeminiPart{
+					Text:    thinking,
+					Thought: true,
+				})
+			}
 		case "text":
 			text, _ := contentBlock["text"].(string)
 			parts = append(parts, types.GeminiPart{
@@ -227,8 +289,9 @@ functionCall.ThoughtSignature = dummyThoughtSignature
 			Parts: parts,
 			Role:  "model",
 		},
-		FinishReason: finishReason,
-		Index:        0,
+		FinishReason:      finishReason,
+		Index:             0,
+		GroundingMetadata: GeminiGrounding(serverCalls, citations, fullText),
 	}
 	geminiResp.Candidates = append(geminiResp.Candidates, candidate)
 
@@ -268,14 +331,27 @@ func OpenAIResponseToGemini(openaiResp map[string]interface{}) (*types.GeminiRes
 
 	parts := []types.GeminiPart{}
 	finishReason := "STOP"
+	var grounding *types.GeminiGroundingMetadata
 
 	// 处理 message
 	if message, ok := choice["message"].(map[string]interface{}); ok {
-		// 文本内容
+		// 推理内容 → thought part
+		if reasoning, ok := message["reasoning_content"].(string); ok && reasoning != "" {
+			parts = append(parts, types.GeminiPart{
+				Text:    reasoning,
+				Thought: true,
+			})
+		}
+
+		// 文本内容（url_citation 标注 → groundingMetadata）
 		if content, ok := message["content"].(string); ok && content != "" {
 			parts = append(parts, types.GeminiPart{
 				Text: content,
 			})
+			if annotations, ok := message["annotations"].([]interface{}); ok && len(annotations) > 0 {
+				annotationsJSON, _ := json.Marshal(annotations)
+				grounding = GeminiGrounding(nil, WebCitationsFromAnnotations(gjson.ParseBytes(annotationsJSON), content, 0), content)
+			}
 		}
 
 		// 工具调用
@@ -318,8 +394,9 @@ function, ok := toolCall["function"].(map[string]interface{})
 			Parts: parts,
 			Role:  "model",
 		},
-		FinishReason: finishReason,
-		Index:        0,
+		FinishReason:      finishReason,
+		Index:             0,
+		GroundingMetadata: grounding,
 	}
 	geminiResp.Candidates = append(geminiResp.Candidates, candidate)
 
@@ -358,23 +435,21 @@ func geminiContentToClaudeMessage(content *types.GeminiContent) (map[string]inte
 	claudeContent := []map[string]interface{}{}
 
 	for i, part := range content.Parts {
-		if part.Text != "" {
+		// 历史中的思考片段缺少 Claude 签名，无法回放，直接跳过
+		if part.Text != "" && !part.Thought {
 			claudeContent = append(claudeContent, map[string]interface{}{
 				"type": "text",
 				"text": part.Text,
 			})
 		}
 
-		if part.InlineData != nil {
-			// 图片转换
-			claudeContent = append(claudeContent, map[string]interface{}{
-				"type": "image",
-				"source": map[string]interface{}{
-					"type":       "base64",
-					"media_type": part.InlineData.MimeType,
-					"data":       part.InlineData.Data,
-				},
-			})
+		if part.InlineData != nil || part.FileData != nil {
+			// 图片/PDF 转换（inlineData → base64 来源，fileData → url 来源）
+			if m, ok := mediaFromGeminiPart(part); ok {
+				if block, ok := m.claudeBlock(); ok {
+					claudeContent = append(claudeContent, block)
+				}
+			}
 		}
 
 		if part.FunctionCall != nil {
@@ -426,13 +501,21 @@ func geminiContentToOpenAIMessage(content *types.GeminiContent) (map[string]inte
 	// 检查是否有工具调用
 	var toolCalls []map[string]interface{}
 	var textParts []string
+	var contentParts []map[string]interface{}
+	var hasMedia bool
 	var hasToolResponse bool
 	var toolResponseName string
 	var toolResponseContent interface{}
 
 	for i, part := range content.Parts {
-		if part.Text != "" {
+		if part.Text != "" && !part.Thought {
 			textParts = append(textParts, part.Text)
+			contentParts = append(contentParts, map[string]interface{}{"type": "text", "text": part.Text})
+		}
+
+		if m, ok := mediaFromGeminiPart(part); ok {
+			hasMedia = true
+			contentParts = append(contentParts, m.chatPart())
 		}
 
 		if part.FunctionCall != nil {
@@ -483,6 +566,9 @@ func geminiContentToOpenAIMessage(content *types.GeminiContent) (map[string]inte
 			msg["content"] = nil
 		}
 		msg["tool_calls"] = toolCalls
+	} else if hasMedia && role == "user" {
+		// 包含图片/文件的用户消息使用 content parts 数组
+		msg["content"] = contentParts
 	} else {
 		// 普通消息
 		msg["content"] = strings.Join(textParts, "\n")
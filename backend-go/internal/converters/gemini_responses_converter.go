@@ -0,0 +1,502 @@
+package converters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== Gemini generateContent 转换器 ==============
+
+// GeminiConverter 实现 Responses → Gemini generateContent 转换
+type GeminiConverter struct{}
+
+// ToProviderRequest 将 Responses 请求转换为 Gemini 格式
+func (c *GeminiConverter) ToProviderRequest(sess *session.Session, req *types.ResponsesRequest) (interface{}, error) {
+	contents, systemTexts, err := ResponsesToGeminiContents(sess, req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	geminiReq := &types.GeminiRequest{Contents: contents}
+
+	if req.Instructions != "" {
+		systemTexts = append([]string{req.Instructions}, systemTexts...)
+	}
+	if len(systemTexts) > 0 {
+		geminiReq.SystemInstruction = &types.GeminiContent{
+			Parts: []types.GeminiPart{{Text: strings.Join(systemTexts, "\n\n")}},
+		}
+	}
+
+	genConfig := &types.GeminiGenerationConfig{}
+	hasGenConfig := false
+	if req.MaxOutputTokens > 0 {
+		genConfig.MaxOutputTokens = req.MaxOutputTokens
+		hasGenConfig = true
+	} else if req.MaxTokens > 0 {
+		genConfig.MaxOutputTokens = req.MaxTokens
+		hasGenConfig = true
+	}
+	if req.Temperature > 0 {
+		temperature := req.Temperature
+		genConfig.Temperature = &temperature
+		hasGenConfig = true
+	}
+	if req.TopP > 0 {
+		topP := req.TopP
+		genConfig.TopP = &topP
+		hasGenConfig = true
+	}
+	if stops := normalizeStopSequences(req.Stop); len(stops) > 0 {
+		genConfig.StopSequences = stops
+		hasGenConfig = true
+	}
+	if thinking := ReasoningEffortToGeminiThinking(extractResponsesReasoningEffort(req)); thinking != nil {
+		genConfig.ThinkingConfig = thinking
+		hasGenConfig = true
+	}
+	if so := StructuredOutputFromResponses(req.Text); so != nil {
+		so.ApplyToGeminiConfig(genConfig)
+		hasGenConfig = true
+	}
+	if hasGenConfig {
+		geminiReq.GenerationConfig = genConfig
+	}
+
+	if decls := responsesToolsToGeminiDeclarations(req.Tools); len(decls) > 0 {
+		geminiReq.Tools = []types.GeminiTool{{FunctionDeclarations: decls}}
+	}
+	serverTools, err := serverToolsFromResponsesTools(req.Tools, ToolProtocolGemini)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range serverTools {
+		geminiReq.Tools = append(geminiReq.Tools, st.GeminiTool())
+	}
+
+	return geminiReq, nil
+}
+
+// FromProviderResponse 将 Gemini 响应转换为 Responses 格式
+func (c *GeminiConverter) FromProviderResponse(resp map[string]interface{}, sessionID string) (*types.ResponsesResponse, error) {
+	return GeminiResponseToResponses(resp, sessionID)
+}
+
+// GetProviderName 获取上游服务名称
+func (c *GeminiConverter) GetProviderName() string {
+	return "Gemini generateContent API"
+}
+
+// ============== Responses → Gemini Contents ==============
+
+// ResponsesToGeminiContents 将会话历史与新输入转换为 Gemini contents
+// 返回：contents、input 中 system/developer 消息的文本、错误
+func ResponsesToGeminiContents(sess *session.Session, newInput interface{}) ([]types.GeminiContent, []string, error) {
+	var items []map[string]interface{}
+
+	if sess != nil {
+		for _, item := range sess.Messages {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, nil, fmt.Errorf("转换历史消息失败: %w", err)
+			}
+			var m map[string]interface{}
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, nil, fmt.Errorf("转换历史消息失败: %w", err)
+			}
+			items = append(items, m)
+		}
+	}
+
+	switch v := newInput.(type) {
+	case nil:
+	case string:
+		items = append(items, map[string]interface{}{"type": "message", "role": "user", "content": v})
+	case []interface{}:
+		for _, raw := range v {
+			if m, ok := raw.(map[string]interface{}); ok {
+				items = append(items, m)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("不支持的 input 类型: %T", newInput)
+	}
+
+	// call_id → 函数名（Gemini functionResponse 需要函数名）
+	callNames := make(map[string]string)
+	for _, item := range items {
+		if t, _ := item["type"].(string); t == "function_call" {
+			callID, _ := item["call_id"].(string)
+			name, _ := item["name"].(string)
+			if callID != "" && name != "" {
+				callNames[callID] = name
+			}
+		}
+	}
+
+	var contents []types.GeminiContent
+	var systemTexts []string
+	appendPart := func(role string, part types.GeminiPart) {
+		// 合并相邻同角色内容，保证 functionCall/functionResponse 成组出现
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, part)
+			return
+		}
+		contents = append(contents, types.GeminiContent{Role: role, Parts: []types.GeminiPart{part}})
+	}
+
+	for _, item := range items {
+		itemType, _ := item["type"].(string)
+		role, _ := item["role"].(string)
+		if itemType == "" && role != "" {
+			itemType = "message"
+		}
+
+		switch itemType {
+		case "message", "text":
+			if role == "system" || role == "developer" {
+				if text := extractTextFromContent(item["content"]); text != "" {
+					systemTexts = append(systemTexts, text)
+				}
+				continue
+			}
+			geminiRole := "user"
+			if role == "assistant" {
+				geminiRole = "model"
+			}
+			for _, part := range responsesContentToGeminiParts(item["content"]) {
+				appendPart(geminiRole, part)
+			}
+
+		case "function_call":
+			name, _ := item["name"].(string)
+			arguments, _ := item["arguments"].(string)
+			args := map[string]interface{}{}
+			if arguments != "" {
+				_ = json.Unmarshal([]byte(arguments), &args)
+			}
+			appendPart("model", types.GeminiPart{
+				FunctionCall: &types.GeminiFunctionCall{
+					Name: name,
+					Args: args,
+					// 原始 thoughtSignature 无法跨协议保留，使用官方跳过校验的占位值
+					ThoughtSignature: types.DummyThoughtSignature,
+				},
+			})
+
+		case "function_call_output":
+			callID, _ := item["call_id"].(string)
+			name := callNames[callID]
+			if name == "" {
+				name = callID
+			}
+			appendPart("user", types.GeminiPart{
+				FunctionResponse: &types.GeminiFunctionResponse{
+					Name:     name,
+					Response: functionOutputToGeminiResponse(item["output"]),
+				},
+			})
+
+		default:
+			// reasoning 等条目无法回放给 Gemini，跳过
+			continue
+		}
+	}
+
+	return contents, systemTexts, nil
+}
+
+// responsesContentToGeminiParts 将 Responses message content 转换为 Gemini parts
+func responsesContentToGeminiParts(content interface{}) []types.GeminiPart {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []types.GeminiPart{{Text: v}}
+	case []interface{}:
+		var parts []types.GeminiPart
+		for _, raw := range v {
+			block, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			blockType, _ := block["type"].(string)
+			switch blockType {
+			case "input_text", "output_text", "text":
+				if text, _ := block["text"].(string); text != "" {
+					parts = append(parts, types.GeminiPart{Text: text})
+				}
+			case "input_image", "input_file":
+				if m, ok := mediaFromResponsesPart(block); ok {
+					parts = append(parts, m.geminiPart())
+				}
+			}
+		}
+		return parts
+	default:
+		if text := extractTextFromContent(content); text != "" {
+			return []types.GeminiPart{{Text: text}}
+		}
+		return nil
+	}
+}
+
+// functionOutputToGeminiResponse 将 function_call_output.output 转换为 Gemini functionResponse.response
+func functionOutputToGeminiResponse(output interface{}) map[string]interface{} {
+	switch v := output.(type) {
+	case map[string]interface{}:
+		return v
+	case string:
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &obj); err == nil && obj != nil {
+			return obj
+		}
+		return map[string]interface{}{"result": v}
+	case nil:
+		return map[string]interface{}{}
+	default:
+		return map[string]interface{}{"result": v}
+	}
+}
+
+// responsesToolsToGeminiDeclarations 将 Responses function tools 转换为 Gemini functionDeclarations
+func responsesToolsToGeminiDeclarations(tools []interface{}) []types.GeminiFunctionDeclaration {
+	var decls []types.GeminiFunctionDeclaration
+	for _, raw := range tools {
+		tool, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _ := tool["type"].(string); t != "" && t != "function" {
+			continue
+		}
+		name, _ := tool["name"].(string)
+		if name == "" {
+			continue
+		}
+		desc, _ := tool["description"].(string)
+		decl := types.GeminiFunctionDeclaration{Name: name, Description: desc}
+		if params, ok := tool["parameters"]; ok && params != nil {
+			decl.Parameters = sanitizeGeminiParameters(params)
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// sanitizeGeminiParameters 复用 GeminiFunctionDeclaration 的 schema 清洗逻辑
+func sanitizeGeminiParameters(params interface{}) interface{} {
+	raw, err := json.Marshal(map[string]interface{}{"name": "_", "parameters": params})
+	if err != nil {
+		return params
+	}
+	var decl types.GeminiFunctionDeclaration
+	if err := json.Unmarshal(raw, &decl); err != nil {
+		return params
+	}
+	return decl.Parameters
+}
+
+// normalizeStopSequences 将 stop（string 或 []string）规范为字符串切片
+func normalizeStopSequences(stop interface{}) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []string:
+		return v
+	case []interface{}:
+		var out []string
+		for _, s := range v {
+			if str, ok := s.(string); ok && str != "" {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// ============== Gemini Response → Responses ==============
+
+// GeminiResponseToResponses 将 Gemini 非流式响应转换为 Responses 格式
+func GeminiResponseToResponses(geminiResp map[string]interface{}, sessionID string) (*types.ResponsesResponse, error) {
+	data, err := json.Marshal(geminiResp)
+	if err != nil {
+		return nil, err
+	}
+	var resp types.GeminiResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	responseID := generateResponseID()
+	output := []types.ResponsesItem{}
+	status := "completed"
+
+	if len(resp.Candidates) > 0 {
+		candidate := resp.Candidates[0]
+		var reasoning, text strings.Builder
+		var calls []types.ResponsesItem
+
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					args, _ := json.Marshal(part.FunctionCall.Args)
+					if part.FunctionCall.Args == nil {
+						args = []byte("{}")
+					}
+					callID := fmt.Sprintf("call_%s_%d", responseID, len(calls))
+					calls = append(calls, types.ResponsesItem{
+						Type:      "function_call",
+						ID:        "fc_" + callID,
+						Status:    "completed",
+						CallID:    callID,
+						Name:      part.FunctionCall.Name,
+						Arguments: string(args),
+					})
+				case part.Thought:
+					reasoning.WriteString(part.Text)
+				case part.Text != "":
+					text.WriteString(part.Text)
+				}
+			}
+		}
+
+		if reasoning.Len() > 0 {
+			output = append(output, types.ResponsesItem{
+				Type:    "reasoning",
+				ID:      fmt.Sprintf("rs_%s_0", responseID),
+				Summary: []types.ContentBlock{{Type: "summary_text", Text: reasoning.String()}},
+			})
+		}
+		// 服务端工具：搜索调用与代码执行在正文之前输出，引用转换为 url_citation 标注
+		searchCalls, citations := WebSearchFromGemini(candidate.GroundingMetadata, text.String(), "ws_"+responseID)
+		for _, call := range searchCalls {
+			output = append(output, call.ResponsesItem())
+		}
+		if candidate.Content != nil {
+			for _, call := range GeminiCodeExecutionCalls(candidate.Content.Parts, "ci_"+responseID) {
+				output = append(output, call.ResponsesItem())
+			}
+		}
+		if text.Len() > 0 {
+			block := types.ContentBlock{Type: "output_text", Text: text.String()}
+			for _, c := range citations {
+				block.Annotations = append(block.Annotations, c.ResponsesAnnotation())
+			}
+			output = append(output, types.ResponsesItem{
+				Type:    "message",
+				ID:      fmt.Sprintf("msg_%s_%d", responseID, len(output)),
+				Status:  "completed",
+				Role:    "assistant",
+				Content: []types.ContentBlock{block},
+			})
+		}
+		output = append(output, calls...)
+
+		if candidate.FinishReason != "" {
+			status = OpenAIFinishReasonToResponses(geminiFinishReasonToOpenAI(candidate.FinishReason))
+		}
+	}
+
+	usage := ExtractUsageMetrics(geminiResp["usageMetadata"])
+
+	return &types.ResponsesResponse{
+		ID:      responseID,
+		Model:   resp.ModelVersion,
+		Output:  output,
+		Status:  status,
+		Usage:   usage,
+		Created: time.Now().Unix(),
+	}, nil
+}
+
+// ============== Gemini SSE → Responses SSE ==============
+
+// geminiToResponsesState Gemini 流式转换状态
+// 复用 Chat Completions → Responses 的事件状态机：每个 Gemini chunk 先映射为等价的 Chat chunk
+type geminiToResponsesState struct {
+	chat      any
+	ID        string
+	ToolIndex int
+	Done      bool
+}
+
+// ConvertGeminiToResponses 将 Gemini streamGenerateContent（alt=sse）行转换为 Responses SSE 事件
+// 参数含义同 ConvertOpenAIChatToResponses
+func ConvertGeminiToResponses(ctx context.Context, modelName string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
+	if *param == nil {
+		*param = &geminiToResponsesState{ID: fmt.Sprintf("resp_%d", time.Now().UnixNano())}
+	}
+	st := (*param).(*geminiToResponsesState)
+	if st.Done {
+		return nil
+	}
+
+	data := extractSSEData(rawJSON)
+	if len(data) == 0 || !gjson.ValidBytes(data) {
+		return nil
+	}
+	root := gjson.ParseBytes(data)
+
+	chunk := `{"id":"","choices":[{"index":0,"delta":{}}]}`
+	chunk, _ = sjson.Set(chunk, "id", st.ID)
+
+	var text, reasoning strings.Builder
+	root.Get("candidates.0.content.parts").ForEach(func(_, part gjson.Result) bool {
+		switch {
+		case part.Get("functionCall").Exists():
+			fc := part.Get("functionCall")
+			args := fc.Get("args").Raw
+			if args == "" {
+				args = "{}"
+			}
+			tc := `{"index":0,"id":"","type":"function","function":{"name":"","arguments":""}}`
+			tc, _ = sjson.Set(tc, "index", st.ToolIndex)
+			tc, _ = sjson.Set(tc, "id", fmt.Sprintf("call_%s_%d", st.ID, st.ToolIndex))
+			tc, _ = sjson.Set(tc, "function.name", fc.Get("name").String())
+			tc, _ = sjson.Set(tc, "function.arguments", args)
+			chunk, _ = sjson.SetRaw(chunk, "choices.0.delta.tool_calls.-1", tc)
+			st.ToolIndex++
+		case part.Get("thought").Bool():
+			reasoning.WriteString(part.Get("text").String())
+		default:
+			text.WriteString(part.Get("text").String())
+		}
+		return true
+	})
+	if reasoning.Len() > 0 {
+		chunk, _ = sjson.Set(chunk, "choices.0.delta.reasoning_content", reasoning.String())
+	}
+	if text.Len() > 0 {
+		chunk, _ = sjson.Set(chunk, "choices.0.delta.content", text.String())
+	}
+
+	finishReason := root.Get("candidates.0.finishReason").String()
+	if finishReason != "" {
+		chunk, _ = sjson.Set(chunk, "choices.0.finish_reason", geminiFinishReasonToOpenAI(finishReason))
+	}
+	if usage := root.Get("usageMetadata"); usage.Exists() {
+		chunk, _ = sjson.SetRaw(chunk, "usage", usage.Raw)
+	}
+
+	out := ConvertOpenAIChatToResponses(ctx, modelName, originalRequestRawJSON, requestRawJSON, []byte("data: "+chunk), &st.chat)
+	if finishReason != "" {
+		// Gemini 没有 [DONE] 结束标记，以 finishReason 作为流结束
+		st.Done = true
+		out = append(out, ConvertOpenAIChatToResponses(ctx, modelName, originalRequestRawJSON, requestRawJSON, []byte("data: [DONE]"), &st.chat)...)
+	}
+	return out
+}
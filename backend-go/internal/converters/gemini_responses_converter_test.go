@@ -0,0 +1,176 @@
+package converters
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiConverter_ToProviderRequest(t *testing.T) {
+	var req types.ResponsesRequest
+	body := `{
+		"model":"gemini-2.5-pro",
+		"instructions":"be brief",
+		"max_output_tokens":256,
+		"reasoning":{"effort":"high"},
+		"input":[
+			{"type":"message","role":"developer","content":"use tools"},
+			{"type":"message","role":"user","content":[{"type":"input_text","text":"weather?"},{"type":"input_image","image_url":"data:image/png;base64,AAAA"}]},
+			{"type":"reasoning","summary":[{"type":"summary_text","text":"thinking"}]},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"}
+		],
+		"tools":[{"type":"function","name":"get_weather","description":"weather","parameters":{"type":"object","properties":{"city":{"type":"string"}},"additionalProperties":false}},{"type":"web_search"}]
+	}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	out, err := (&GeminiConverter{}).ToProviderRequest(nil, &req)
+	if err != nil {
+		t.Fatalf("ToProviderRequest: %v", err)
+	}
+	raw, _ := json.Marshal(out)
+	root := gjson.ParseBytes(raw)
+
+	if got := root.Get("systemInstruction.parts.0.text").String(); got != "be brief\n\nuse tools" {
+		t.Fatalf("systemInstruction = %q", got)
+	}
+	contents := root.Get("contents").Array()
+	if len(contents) != 3 {
+		t.Fatalf("contents len = %d, raw = %s", len(contents), root.Get("contents").Raw)
+	}
+	if contents[0].Get("role").String() != "user" || contents[0].Get("parts.1.inlineData.mimeType").String() != "image/png" {
+		t.Fatalf("user content mismatch: %s", contents[0].Raw)
+	}
+	call := contents[1].Get("parts.0")
+	if contents[1].Get("role").String() != "model" || call.Get("functionCall.args.city").String() != "Paris" || call.Get("thoughtSignature").String() != types.DummyThoughtSignature {
+		t.Fatalf("function call mismatch: %s", contents[1].Raw)
+	}
+	if fr := contents[2].Get("parts.0.functionResponse"); fr.Get("name").String() != "get_weather" || fr.Get("response.result").String() != "sunny" {
+		t.Fatalf("function response mismatch: %s", contents[2].Raw)
+	}
+
+	if root.Get("generationConfig.maxOutputTokens").Int() != 256 {
+		t.Fatalf("maxOutputTokens mismatch: %s", root.Get("generationConfig").Raw)
+	}
+	if !root.Get("generationConfig.thinkingConfig.includeThoughts").Bool() || root.Get("generationConfig.thinkingConfig.thinkingBudget").Int() != 16384 {
+		t.Fatalf("thinkingConfig mismatch: %s", root.Get("generationConfig").Raw)
+	}
+	decls := root.Get("tools.0.functionDeclarations").Array()
+	if len(decls) != 1 || decls[0].Get("name").String() != "get_weather" {
+		t.Fatalf("tools mismatch: %s", root.Get("tools").Raw)
+	}
+	if decls[0].Get("parameters.additionalProperties").Exists() {
+		t.Fatalf("unsupported schema keys should be removed: %s", decls[0].Raw)
+	}
+}
+
+func TestGeminiConverter_FromProviderResponse(t *testing.T) {
+	body := `{
+		"candidates":[{"content":{"role":"model","parts":[
+			{"text":"plan","thought":true},
+			{"text":"Checking."},
+			{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}
+		]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15},
+		"modelVersion":"gemini-2.5-pro"
+	}`
+	respMap, err := JSONToMap([]byte(body))
+	if err != nil {
+		t.Fatalf("JSONToMap: %v", err)
+	}
+
+	resp, err := (&GeminiConverter{}).FromProviderResponse(respMap, "sess_1")
+	if err != nil {
+		t.Fatalf("FromProviderResponse: %v", err)
+	}
+	if resp.Status != "completed" || resp.Model != "gemini-2.5-pro" {
+		t.Fatalf("status/model mismatch: %+v", resp)
+	}
+	if len(resp.Output) != 3 {
+		t.Fatalf("output len = %d: %+v", len(resp.Output), resp.Output)
+	}
+	if resp.Output[0].Type != "reasoning" || resp.Output[0].Summary[0].Text != "plan" {
+		t.Fatalf("reasoning item mismatch: %+v", resp.Output[0])
+	}
+	if resp.Output[1].Type != "message" || extractTextFromContent(resp.Output[1].Content) != "Checking." {
+		t.Fatalf("message item mismatch: %+v", resp.Output[1])
+	}
+	fc := resp.Output[2]
+	if fc.Type != "function_call" || fc.Name != "get_weather" || fc.CallID == "" || fc.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("function_call item mismatch: %+v", fc)
+	}
+	if resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 5 {
+		t.Fatalf("usage mismatch: %+v", resp.Usage)
+	}
+}
+
+func TestGeminiConverter_FromProviderResponse_MaxTokens(t *testing.T) {
+	respMap, _ := JSONToMap([]byte(`{"candidates":[{"content":{"parts":[{"text":"cut"}]},"finishReason":"MAX_TOKENS"}]}`))
+	resp, err := (&GeminiConverter{}).FromProviderResponse(respMap, "")
+	if err != nil {
+		t.Fatalf("FromProviderResponse: %v", err)
+	}
+	if resp.Status != "incomplete" {
+		t.Fatalf("status = %q", resp.Status)
+	}
+}
+
+func TestConvertGeminiToResponses_Stream(t *testing.T) {
+	lines := []string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"hmm","thought":true}]}}]}`,
+		``,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"},{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":3,"totalTokenCount":7}}`,
+		`data: {"candidates":[{"content":{"parts":[{"text":"ignored"}]}}]}`,
+	}
+
+	var state any
+	var events []string
+	for _, line := range lines {
+		events = append(events, ConvertGeminiToResponses(context.Background(), "gemini-2.5-pro", nil, nil, []byte(line), &state)...)
+	}
+	all := strings.Join(events, "")
+
+	for _, want := range []string{
+		"event: response.created",
+		"event: response.reasoning_summary_text.delta",
+		"event: response.output_text.delta",
+		"event: response.function_call_arguments.done",
+		"event: response.completed",
+	} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("missing %q in stream:\n%s", want, all)
+		}
+	}
+	if strings.Contains(all, "ignored") {
+		t.Fatalf("chunks after finishReason should be dropped:\n%s", all)
+	}
+
+	var completed gjson.Result
+	for _, ev := range events {
+		for _, line := range strings.Split(ev, "\n") {
+			if data := strings.TrimPrefix(line, "data: "); data != line && gjson.Get(data, "type").String() == "response.completed" {
+				completed = gjson.Get(data, "response")
+			}
+		}
+	}
+	if completed.Get("usage.input_tokens").Int() != 4 || completed.Get("usage.output_tokens").Int() != 3 {
+		t.Fatalf("usage mismatch: %s", completed.Get("usage").Raw)
+	}
+	var text strings.Builder
+	completed.Get("output").ForEach(func(_, item gjson.Result) bool {
+		if item.Get("type").String() == "message" {
+			text.WriteString(item.Get("content.0.text").String())
+		}
+		return true
+	})
+	if text.String() != "Hello" {
+		t.Fatalf("completed text = %q, output = %s", text.String(), completed.Get("output").Raw)
+	}
+}
@@ -0,0 +1,314 @@
+package converters
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// ============== 多模态内容（图片 / PDF）跨协议转换 ==============
+//
+// 各协议的表示方式：
+//   - Claude:    {"type":"image"|"document","source":{"type":"base64","media_type","data"} | {"type":"url","url"}}
+//   - Chat:      {"type":"image_url","image_url":{"url","detail"}} / {"type":"file","file":{"filename","file_data"}}
+//   - Responses: {"type":"input_image","image_url","detail"} / {"type":"input_file","filename","file_data"|"file_url"}
+//   - Gemini:    {"inlineData":{"mimeType","data"}} / {"fileData":{"mimeType","fileUri"}}
+//
+// 所有方向统一先解析为 mediaPart，再输出为目标协议格式。
+// file_id 等上游私有引用无法跨协议使用，解析时直接跳过。
+
+// mediaPart 协议无关的图片/文件内容（Data 与 URL 二选一）
+type mediaPart struct {
+	MediaType string // MIME 类型，如 image/png、application/pdf
+	Data      string // base64 数据
+	URL       string // 远程 URL
+	Filename  string
+	Detail    string // OpenAI 图片精度（low/high/auto）
+	isFile    bool   // 文件（PDF 等）而非图片
+}
+
+// parseDataURL 解析 data URL，返回媒体类型与 base64 数据
+func parseDataURL(u string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(u, "data:") {
+		return "", "", false
+	}
+	header, data, ok := strings.Cut(strings.TrimPrefix(u, "data:"), ",")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), data, true
+}
+
+// newMediaFromURL 从 URL（data URL 或远程 URL）构建 mediaPart
+func newMediaFromURL(u string, isFile bool) (mediaPart, bool) {
+	if u == "" {
+		return mediaPart{}, false
+	}
+	if mediaType, data, ok := parseDataURL(u); ok {
+		return mediaPart{MediaType: mediaType, Data: data, isFile: isFile}, true
+	}
+	if strings.HasPrefix(u, "data:") {
+		return mediaPart{}, false
+	}
+	return mediaPart{URL: u, isFile: isFile}, true
+}
+
+// mediaFromFileData 解析 file_data（data URL 或裸 base64）
+func mediaFromFileData(fileData string) mediaPart {
+	if mediaType, data, ok := parseDataURL(fileData); ok {
+		return mediaPart{MediaType: mediaType, Data: data, isFile: true}
+	}
+	return mediaPart{Data: fileData, isFile: true}
+}
+
+// dataURL 返回 base64 内容的 data URL，远程内容返回原 URL
+func (m mediaPart) dataURL() string {
+	if m.URL != "" {
+		return m.URL
+	}
+	return fmt.Sprintf("data:%s;base64,%s", m.mediaType(), m.Data)
+}
+
+// mediaType 返回媒体类型，缺失时根据文件名或 URL 扩展名推断
+func (m mediaPart) mediaType() string {
+	if m.MediaType != "" {
+		return m.MediaType
+	}
+	candidates := []string{m.Filename}
+	if parsed, err := url.Parse(m.URL); err == nil && m.URL != "" {
+		candidates = append(candidates, parsed.Path)
+	}
+	for _, name := range candidates {
+		if guessed := mime.TypeByExtension(strings.ToLower(path.Ext(name))); name != "" && guessed != "" {
+			mediaType, _, _ := strings.Cut(guessed, ";")
+			return mediaType
+		}
+	}
+	if m.isFile {
+		return "application/pdf"
+	}
+	return "image/jpeg"
+}
+
+// filename 返回文件名，缺失时按媒体类型生成
+func (m mediaPart) filename() string {
+	if m.Filename != "" {
+		return m.Filename
+	}
+	if m.URL != "" {
+		if parsed, err := url.Parse(m.URL); err == nil {
+			if base := path.Base(parsed.Path); base != "" && base != "/" && base != "." {
+				return base
+			}
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(m.mediaType()); len(exts) > 0 {
+		return "file" + exts[0]
+	}
+	return "file"
+}
+
+// isImage 判断内容是否为图片（文件类型但 MIME 为 image/* 时同样视为图片）
+func (m mediaPart) isImage() bool {
+	if !m.isFile {
+		return true
+	}
+	return strings.HasPrefix(m.mediaType(), "image/")
+}
+
+// ---------- 解析 ----------
+
+// mediaFromClaudeBlock 解析 Claude image/document 内容块
+func mediaFromClaudeBlock(block map[string]interface{}) (mediaPart, bool) {
+	blockType, _ := block["type"].(string)
+	if blockType != "image" && blockType != "document" {
+		return mediaPart{}, false
+	}
+	source, ok := block["source"].(map[string]interface{})
+	if !ok {
+		return mediaPart{}, false
+	}
+
+	m := mediaPart{isFile: blockType == "document"}
+	m.Filename, _ = block["title"].(string)
+	switch source["type"] {
+	case "base64":
+		m.MediaType, _ = source["media_type"].(string)
+		m.Data, _ = source["data"].(string)
+		return m, m.Data != ""
+	case "url":
+		m.URL, _ = source["url"].(string)
+		return m, m.URL != ""
+	}
+	return mediaPart{}, false
+}
+
+// mediaFromResponsesPart 解析 Responses input_image/input_file 内容块
+func mediaFromResponsesPart(block map[string]interface{}) (mediaPart, bool) {
+	switch block["type"] {
+	case "input_image":
+		// image_url 通常为字符串，兼容 {"url": "..."} 写法
+		imageURL, _ := block["image_url"].(string)
+		if obj, ok := block["image_url"].(map[string]interface{}); ok {
+			imageURL, _ = obj["url"].(string)
+		}
+		m, ok := newMediaFromURL(imageURL, false)
+		m.Detail, _ = block["detail"].(string)
+		return m, ok
+	case "input_file":
+		var m mediaPart
+		if fileData, _ := block["file_data"].(string); fileData != "" {
+			m = mediaFromFileData(fileData)
+		} else if fileURL, _ := block["file_url"].(string); fileURL != "" {
+			m = mediaPart{URL: fileURL, isFile: true}
+		} else {
+			return mediaPart{}, false
+		}
+		m.Filename, _ = block["filename"].(string)
+		return m, true
+	}
+	return mediaPart{}, false
+}
+
+// mediaFromChatPart 解析 Chat Completions image_url/file 内容片段
+func mediaFromChatPart(part map[string]interface{}) (mediaPart, bool) {
+	switch part["type"] {
+	case "image_url":
+		var imageURL, detail string
+		switch v := part["image_url"].(type) {
+		case string:
+			imageURL = v
+		case map[string]interface{}:
+			imageURL, _ = v["url"].(string)
+			detail, _ = v["detail"].(string)
+		}
+		m, ok := newMediaFromURL(imageURL, false)
+		m.Detail = detail
+		return m, ok
+	case "file":
+		file, ok := part["file"].(map[string]interface{})
+		if !ok {
+			return mediaPart{}, false
+		}
+		fileData, _ := file["file_data"].(string)
+		if fileData == "" {
+			return mediaPart{}, false
+		}
+		m := mediaFromFileData(fileData)
+		m.Filename, _ = file["filename"].(string)
+		return m, true
+	}
+	return mediaPart{}, false
+}
+
+// mediaFromGeminiPart 解析 Gemini inlineData/fileData part
+func mediaFromGeminiPart(part types.GeminiPart) (mediaPart, bool) {
+	if part.InlineData != nil && part.InlineData.Data != "" {
+		m := mediaPart{MediaType: part.InlineData.MimeType, Data: part.InlineData.Data}
+		m.isFile = !strings.HasPrefix(m.mediaType(), "image/")
+		return m, true
+	}
+	if part.FileData != nil && part.FileData.FileURI != "" {
+		m := mediaPart{MediaType: part.FileData.MimeType, URL: part.FileData.FileURI}
+		m.isFile = !strings.HasPrefix(m.mediaType(), "image/")
+		return m, true
+	}
+	return mediaPart{}, false
+}
+
+// ---------- 输出 ----------
+
+// claudeBlock 输出 Claude image/document 内容块
+// 仅 http(s) URL 可作为 url 来源（Gemini 的 gs:// 等文件引用 Claude 无法访问）
+func (m mediaPart) claudeBlock() (map[string]interface{}, bool) {
+	blockType := "image"
+	if !m.isImage() {
+		blockType = "document"
+	}
+
+	var source map[string]interface{}
+	if m.URL != "" {
+		if !strings.HasPrefix(m.URL, "http://") && !strings.HasPrefix(m.URL, "https://") {
+			return nil, false
+		}
+		source = map[string]interface{}{"type": "url", "url": m.URL}
+	} else {
+		source = map[string]interface{}{"type": "base64", "media_type": m.mediaType(), "data": m.Data}
+	}
+
+	block := map[string]interface{}{"type": blockType, "source": source}
+	if blockType == "document" && m.Filename != "" {
+		block["title"] = m.Filename
+	}
+	return block, true
+}
+
+// chatPart 输出 Chat Completions 内容片段
+// Chat 的 file 片段仅支持内联数据，远程文件降级为包含链接的文本片段
+func (m mediaPart) chatPart() map[string]interface{} {
+	if m.isImage() {
+		imageURL := map[string]interface{}{"url": m.dataURL()}
+		if m.Detail != "" {
+			imageURL["detail"] = m.Detail
+		}
+		return map[string]interface{}{"type": "image_url", "image_url": imageURL}
+	}
+	if m.URL != "" {
+		return map[string]interface{}{"type": "text", "text": fmt.Sprintf("[file: %s]", m.URL)}
+	}
+	return map[string]interface{}{
+		"type": "file",
+		"file": map[string]interface{}{
+			"filename":  m.filename(),
+			"file_data": m.dataURL(),
+		},
+	}
+}
+
+// responsesPart 输出 Responses input_image/input_file 内容块
+func (m mediaPart) responsesPart() map[string]interface{} {
+	if m.isImage() {
+		part := map[string]interface{}{"type": "input_image", "image_url": m.dataURL()}
+		if m.Detail != "" {
+			part["detail"] = m.Detail
+		}
+		return part
+	}
+	part := map[string]interface{}{"type": "input_file", "filename": m.filename()}
+	if m.URL != "" {
+		part["file_url"] = m.URL
+	} else {
+		part["file_data"] = m.dataURL()
+	}
+	return part
+}
+
+// geminiPart 输出 Gemini inlineData/fileData part
+func (m mediaPart) geminiPart() types.GeminiPart {
+	if m.URL != "" {
+		return types.GeminiPart{FileData: &types.GeminiFileData{MimeType: m.mediaType(), FileURI: m.URL}}
+	}
+	return types.GeminiPart{InlineData: &types.GeminiInlineData{MimeType: m.mediaType(), Data: m.Data}}
+}
+
+// ClaudeMediaBlockToOpenAIPart 将 Claude image/document 内容块转换为 Chat Completions 内容片段
+func ClaudeMediaBlockToOpenAIPart(block map[string]interface{}) (map[string]interface{}, bool) {
+	m, ok := mediaFromClaudeBlock(block)
+	if !ok {
+		return nil, false
+	}
+	return m.chatPart(), true
+}
+
+// ClaudeMediaBlockToGeminiPart 将 Claude image/document 内容块转换为 Gemini part
+func ClaudeMediaBlockToGeminiPart(block map[string]interface{}) (types.GeminiPart, bool) {
+	m, ok := mediaFromClaudeBlock(block)
+	if !ok {
+		return types.GeminiPart{}, false
+	}
+	return m.geminiPart(), true
+}
@@ -0,0 +1,265 @@
+package converters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+const testPDFData = "JVBERi0xLjQ="
+
+func mustJSON(t *testing.T, v interface{}) gjson.Result {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return gjson.ParseBytes(raw)
+}
+
+func TestMediaPart_RoundTripsAcrossProtocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		claude    map[string]interface{}
+		chat      string
+		responses string
+		gemini    string
+	}{
+		{
+			name: "base64_image",
+			claude: map[string]interface{}{"type": "image", "source": map[string]interface{}{
+				"type": "base64", "media_type": "image/png", "data": "AAAA",
+			}},
+			chat:      `{"image_url":{"url":"data:image/png;base64,AAAA"},"type":"image_url"}`,
+			responses: `{"image_url":"data:image/png;base64,AAAA","type":"input_image"}`,
+			gemini:    `{"inlineData":{"mimeType":"image/png","data":"AAAA"}}`,
+		},
+		{
+			name: "url_image",
+			claude: map[string]interface{}{"type": "image", "source": map[string]interface{}{
+				"type": "url", "url": "https://example.com/cat.png",
+			}},
+			chat:      `{"image_url":{"url":"https://example.com/cat.png"},"type":"image_url"}`,
+			responses: `{"image_url":"https://example.com/cat.png","type":"input_image"}`,
+			gemini:    `{"fileData":{"mimeType":"image/png","fileUri":"https://example.com/cat.png"}}`,
+		},
+		{
+			name: "base64_pdf",
+			claude: map[string]interface{}{"type": "document", "title": "spec.pdf", "source": map[string]interface{}{
+				"type": "base64", "media_type": "application/pdf", "data": testPDFData,
+			}},
+			chat:      `{"file":{"file_data":"data:application/pdf;base64,` + testPDFData + `","filename":"spec.pdf"},"type":"file"}`,
+			responses: `{"file_data":"data:application/pdf;base64,` + testPDFData + `","filename":"spec.pdf","type":"input_file"}`,
+			gemini:    `{"inlineData":{"mimeType":"application/pdf","data":"` + testPDFData + `"}}`,
+		},
+		{
+			name: "url_pdf",
+			claude: map[string]interface{}{"type": "document", "source": map[string]interface{}{
+				"type": "url", "url": "https://example.com/docs/spec.pdf",
+			}},
+			chat:      `{"text":"[file: https://example.com/docs/spec.pdf]","type":"text"}`,
+			responses: `{"file_url":"https://example.com/docs/spec.pdf","filename":"spec.pdf","type":"input_file"}`,
+			gemini:    `{"fileData":{"mimeType":"application/pdf","fileUri":"https://example.com/docs/spec.pdf"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := mediaFromClaudeBlock(tt.claude)
+			if !ok {
+				t.Fatalf("mediaFromClaudeBlock failed")
+			}
+			if got := mustJSON(t, m.chatPart()).Raw; got != tt.chat {
+				t.Errorf("chat = %s, want %s", got, tt.chat)
+			}
+			if got := mustJSON(t, m.responsesPart()).Raw; got != tt.responses {
+				t.Errorf("responses = %s, want %s", got, tt.responses)
+			}
+			if got := mustJSON(t, m.geminiPart()).Raw; got != tt.gemini {
+				t.Errorf("gemini = %s, want %s", got, tt.gemini)
+			}
+
+			// 反向解析后应还原为等价的 Claude 内容块
+			var responsesPart map[string]interface{}
+			_ = json.Unmarshal([]byte(tt.responses), &responsesPart)
+			back, ok := mediaFromResponsesPart(responsesPart)
+			if !ok {
+				t.Fatalf("mediaFromResponsesPart failed")
+			}
+			block, ok := back.claudeBlock()
+			if !ok {
+				t.Fatalf("claudeBlock failed")
+			}
+			got, want := mustJSON(t, block), mustJSON(t, tt.claude)
+			if got.Get("type").String() != want.Get("type").String() || got.Get("source").Raw != want.Get("source").Raw {
+				t.Errorf("round trip = %s, want %s", got.Raw, want.Raw)
+			}
+
+			var geminiPart types.GeminiPart
+			_ = json.Unmarshal([]byte(tt.gemini), &geminiPart)
+			back, ok = mediaFromGeminiPart(geminiPart)
+			if !ok {
+				t.Fatalf("mediaFromGeminiPart failed")
+			}
+			if got := mustJSON(t, back.responsesPart()); got.Get("type").String() != gjson.Get(tt.responses, "type").String() {
+				t.Errorf("gemini → responses = %s, want type of %s", got.Raw, tt.responses)
+			}
+		})
+	}
+}
+
+func TestMediaPart_ClaudeRejectsNonHTTPFileURI(t *testing.T) {
+	m, ok := mediaFromGeminiPart(types.GeminiPart{FileData: &types.GeminiFileData{
+		MimeType: "application/pdf",
+		FileURI:  "gs://bucket/spec.pdf",
+	}})
+	if !ok {
+		t.Fatalf("mediaFromGeminiPart failed")
+	}
+	if _, ok := m.claudeBlock(); ok {
+		t.Fatalf("gs:// URIs must not be forwarded to Claude")
+	}
+}
+
+func TestResponsesToClaudeMessages_Multimodal(t *testing.T) {
+	sess := &session.Session{Messages: []types.ResponsesItem{}}
+	input := []interface{}{
+		map[string]interface{}{
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_text", "text": "what is in these?"},
+				map[string]interface{}{"type": "input_image", "image_url": "data:image/jpeg;base64,BBBB"},
+				map[string]interface{}{"type": "input_file", "filename": "a.pdf", "file_data": "data:application/pdf;base64," + testPDFData},
+			},
+		},
+	}
+
+	messages, _, err := ResponsesToClaudeMessages(sess, input, "")
+	if err != nil {
+		t.Fatalf("ResponsesToClaudeMessages: %v", err)
+	}
+	root := mustJSON(t, messages)
+	if root.Get("#").Int() != 1 || root.Get("0.role").String() != "user" {
+		t.Fatalf("unexpected messages: %s", root.Raw)
+	}
+	content := root.Get("0.content")
+	if content.Get("0.text").String() != "what is in these?" ||
+		content.Get("1.type").String() != "image" || content.Get("1.source.media_type").String() != "image/jpeg" ||
+		content.Get("2.type").String() != "document" || content.Get("2.source.data").String() != testPDFData {
+		t.Fatalf("unexpected content blocks: %s", content.Raw)
+	}
+}
+
+func TestResponsesToOpenAIChatMessages_Multimodal(t *testing.T) {
+	sess := &session.Session{Messages: []types.ResponsesItem{}}
+	input := []interface{}{
+		map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []interface{}{
+				map[string]interface{}{"type": "input_text", "text": "describe"},
+				map[string]interface{}{"type": "input_image", "image_url": "https://example.com/a.png", "detail": "high"},
+			},
+		},
+	}
+
+	messages, err := ResponsesToOpenAIChatMessages(sess, input, "")
+	if err != nil {
+		t.Fatalf("ResponsesToOpenAIChatMessages: %v", err)
+	}
+	content := mustJSON(t, messages).Get("0.content")
+	if content.Get("0.type").String() != "text" ||
+		content.Get("1.image_url.url").String() != "https://example.com/a.png" ||
+		content.Get("1.image_url.detail").String() != "high" {
+		t.Fatalf("unexpected content parts: %s", content.Raw)
+	}
+}
+
+func TestConvertResponsesToOpenAIChatRequest_Multimodal(t *testing.T) {
+	body := `{"input":[
+		{"role":"user","content":[{"type":"input_text","text":"read"},{"type":"input_file","filename":"a.pdf","file_data":"data:application/pdf;base64,` + testPDFData + `"}]},
+		{"role":"assistant","content":[{"type":"output_text","text":"ok"}]}
+	]}`
+
+	out := gjson.ParseBytes(ConvertResponsesToOpenAIChatRequest("gpt-4o", []byte(body), false))
+	user := out.Get("messages.0.content")
+	if user.Get("1.type").String() != "file" || user.Get("1.file.filename").String() != "a.pdf" ||
+		user.Get("1.file.file_data").String() != "data:application/pdf;base64,"+testPDFData {
+		t.Fatalf("unexpected user content: %s", user.Raw)
+	}
+	if out.Get("messages.1.content").String() != "ok" {
+		t.Fatalf("text-only messages should keep string content: %s", out.Get("messages.1").Raw)
+	}
+}
+
+func TestConvertClaudeToResponsesRequest_Document(t *testing.T) {
+	body := `{"model":"claude","messages":[{"role":"user","content":[
+		{"type":"document","source":{"type":"url","url":"https://example.com/r.pdf"}},
+		{"type":"text","text":"summarize"}
+	]}]}`
+
+	converted, err := ConvertClaudeToResponsesRequest([]byte(body), "gpt-4o")
+	if err != nil {
+		t.Fatalf("ConvertClaudeToResponsesRequest: %v", err)
+	}
+	out := gjson.ParseBytes(converted)
+	part := out.Get("input.0.content.0")
+	if part.Get("type").String() != "input_file" || part.Get("file_url").String() != "https://example.com/r.pdf" {
+		t.Fatalf("unexpected input_file part: %s", out.Get("input").Raw)
+	}
+}
+
+func TestConvertOpenAIChatRequests_FilePart(t *testing.T) {
+	body := `{"model":"m","messages":[{"role":"user","content":[
+		{"type":"text","text":"check"},
+		{"type":"file","file":{"filename":"a.pdf","file_data":"data:application/pdf;base64,` + testPDFData + `"}}
+	]}]}`
+
+	claude := gjson.ParseBytes(ConvertOpenAIChatToClaudeRequest([]byte(body)))
+	if block := claude.Get("messages.0.content.1"); block.Get("type").String() != "document" ||
+		block.Get("source.media_type").String() != "application/pdf" || block.Get("title").String() != "a.pdf" {
+		t.Fatalf("unexpected Claude document block: %s", claude.Get("messages").Raw)
+	}
+
+	responses := gjson.ParseBytes(ConvertOpenAIChatToResponsesRequest([]byte(body)))
+	if part := responses.Get("input.0.content.1"); part.Get("type").String() != "input_file" || part.Get("filename").String() != "a.pdf" {
+		t.Fatalf("unexpected Responses input_file part: %s", responses.Get("input").Raw)
+	}
+}
+
+func TestGeminiToOpenAIRequest_InlineData(t *testing.T) {
+	req := &types.GeminiRequest{Contents: []types.GeminiContent{{
+		Role: "user",
+		Parts: []types.GeminiPart{
+			{Text: "what is this"},
+			{InlineData: &types.GeminiInlineData{MimeType: "image/webp", Data: "CCCC"}},
+		},
+	}}}
+
+	out, err := GeminiToOpenAIRequest(req, "gpt-4o")
+	if err != nil {
+		t.Fatalf("GeminiToOpenAIRequest: %v", err)
+	}
+	content := mustJSON(t, out).Get("messages.0.content")
+	if content.Get("1.image_url.url").String() != "data:image/webp;base64,CCCC" {
+		t.Fatalf("unexpected content: %s", content.Raw)
+	}
+}
+
+func TestGeminiToClaudeRequest_PDFBecomesDocument(t *testing.T) {
+	req := &types.GeminiRequest{Contents: []types.GeminiContent{{
+		Role:  "user",
+		Parts: []types.GeminiPart{{InlineData: &types.GeminiInlineData{MimeType: "application/pdf", Data: testPDFData}}},
+	}}}
+
+	out, err := GeminiToClaudeRequest(req, "claude")
+	if err != nil {
+		t.Fatalf("GeminiToClaudeRequest: %v", err)
+	}
+	if block := mustJSON(t, out).Get("messages.0.content.0"); block.Get("type").String() != "document" {
+		t.Fatalf("unexpected block: %s", block.Raw)
+	}
+}
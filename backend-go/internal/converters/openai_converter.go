@@ -92,6 +92,17 @@ func (c *OpenAIChatConverter) ToProviderRequest(sess *session.Session, req *type
 	if effort := extractResponsesReasoningEffort(req); effort != "" {
 		openaiReq["reasoning_effort"] = effort
 	}
+	if so := StructuredOutputFromResponses(req.Text); so != nil {
+		openaiReq["response_format"] = so.ChatResponseFormat()
+	}
+	// 服务端工具：Chat 仅支持联网搜索（web_search_options）
+	serverTools, err := serverToolsFromResponsesTools(req.Tools, ToolProtocolChat)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range serverTools {
+		openaiReq["web_search_options"] = st.ChatWebSearchOptions()
+	}
 	return openaiReq, nil
 }
 
@@ -112,15 +123,15 @@ type OpenAICompletionsConverter struct{
 
 // ToProviderRequest 将 Responses 请求转换为 OpenAI Completions 格式
 func (c *OpenAICompletionsConverter) ToProviderRequest(sess *session.Session, req *types.ResponsesRequest) (interface{}, error) {
-	// 提取纯文本（Completions API 不支持 messages）
-	prompt, err := ExtractTextFromResponses(sess, req.Input)
+	// 渲染对话历史为纯文本 prompt（Completions API 不支持 messages）
+	prompt, err := ResponsesToCompletionsPrompt(sess, req.Input, req.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	// 如果有 instructions，添加到 prompt 前面
-	if req.Instructions != "" {
-		prompt = req.Instructions + "\n\n" + prompt
+	// Completions API 没有任何服务端工具
+	if _, err := serverToolsFromResponsesTools(req.Tools, ToolProtocolCompletions); err != nil {
+		return nil, err
 	}
 
 	// 构建 OpenAI Completions 请求
@@ -146,12 +157,16 @@ func (c *OpenAICompletionsConverter) ToProviderRequest(sess *session.Session, re
 	if req.PresencePenalty != 0 {
 		completionsReq["presence_penalty"] = req.PresencePenalty
 	}
-	if req.Stop != nil {
-		completionsReq["stop"] = req.Stop
-	}
+	// 默认停止序列之后追加客户端指定的 stop
+	completionsReq["stop"] = append(append([]string{}, CompletionsStopSequences...), normalizeStopSequences(req.Stop)...)
 	if req.User != "" {
 		completionsReq["user"] = req.User
 	}
+	if req.StreamOptions != nil {
+		completionsReq["stream_options"] = req.StreamOptions
+	} else if req.Stream {
+		completionsReq["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
 
 	return completionsReq, nil
 }
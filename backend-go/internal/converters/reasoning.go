@@ -0,0 +1,165 @@
+package converters
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+// ============== 思考深度跨协议映射 ==============
+//
+// 各协议的表示方式：
+//   - Claude:    {"thinking":{"type":"enabled","budget_tokens":N}}
+//   - Chat:      {"reasoning_effort":"low|medium|high"}
+//   - Responses: {"reasoning":{"effort":"low|medium|high","summary":"auto"}}
+//   - Gemini:    {"generationConfig":{"thinkingConfig":{"thinkingBudget":N,"thinkingLevel":"low|high","includeThoughts":true}}}
+//
+// effort 档位与 token 预算之间通过预算表互转，预算表可由全局配置覆盖（未配置的档位使用默认值）。
+// 预算 → 档位时取最接近的档位，且最高只映射到 high（xhigh 仅部分模型支持）。
+
+// DefaultReasoningBudgets 默认的 effort 档位 → 思考预算表
+var DefaultReasoningBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     1024,
+	"medium":  8192,
+	"high":    16384,
+	"xhigh":   32768,
+}
+
+// reverseReasoningLevels 预算 → 档位时参与匹配的档位
+var reverseReasoningLevels = []string{"low", "medium", "high"}
+
+// claudeMinThinkingBudget Claude thinking.budget_tokens 的最小值
+const claudeMinThinkingBudget = 1024
+
+// geminiDynamicThinkingBudget Gemini 动态思考预算（由模型自行决定）
+const geminiDynamicThinkingBudget = -1
+
+var (
+	reasoningBudgetsMu     sync.RWMutex
+	reasoningBudgetsSource func() map[string]int
+)
+
+// SetReasoningBudgetsSource 设置预算表覆盖来源（通常为配置管理器的 GetGlobalReasoningBudgets）
+func SetReasoningBudgetsSource(source func() map[string]int) {
+	reasoningBudgetsMu.Lock()
+	defer reasoningBudgetsMu.Unlock()
+	reasoningBudgetsSource = source
+}
+
+// reasoningBudgets 返回合并配置覆盖后的预算表
+func reasoningBudgets() map[string]int {
+	budgets := make(map[string]int, len(DefaultReasoningBudgets))
+	for level, budget := range DefaultReasoningBudgets {
+		budgets[level] = budget
+	}
+
+	reasoningBudgetsMu.RLock()
+	source := reasoningBudgetsSource
+	reasoningBudgetsMu.RUnlock()
+	if source == nil {
+		return budgets
+	}
+	for level, budget := range source() {
+		if _, known := budgets[level]; known && budget > 0 {
+			budgets[level] = budget
+		}
+	}
+	return budgets
+}
+
+// ReasoningEffortToBudget 将 effort 档位映射为思考预算（0 表示不开启或无法识别）
+func ReasoningEffortToBudget(effort string) int {
+	return reasoningBudgets()[strings.ToLower(strings.TrimSpace(effort))]
+}
+
+// ReasoningBudgetToEffort 将思考预算映射为最接近的 effort 档位（预算 <= 0 返回空字符串）
+func ReasoningBudgetToEffort(budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+
+	budgets := reasoningBudgets()
+	levels := append([]string{}, reverseReasoningLevels...)
+	sort.SliceStable(levels, func(i, j int) bool { return budgets[levels[i]] < budgets[levels[j]] })
+
+	// 以相邻档位预算的中点为分界
+	for i := 0; i < len(levels)-1; i++ {
+		if budget <= (budgets[levels[i]]+budgets[levels[i+1]])/2 {
+			return levels[i]
+		}
+	}
+	return levels[len(levels)-1]
+}
+
+// ClaudeThinkingBudget 返回开启思考时 Claude 可接受的预算（不低于最小值）
+func ClaudeThinkingBudget(budget int) int {
+	if budget < claudeMinThinkingBudget {
+		return claudeMinThinkingBudget
+	}
+	return budget
+}
+
+// ReasoningEffortToGeminiThinking 将 effort 档位转换为 Gemini thinkingConfig
+// none 显式关闭思考，auto/空值/无法识别的档位返回 nil（沿用上游默认）
+func ReasoningEffortToGeminiThinking(effort string) *types.GeminiThinkingConfig {
+	switch effort = strings.ToLower(strings.TrimSpace(effort)); effort {
+	case "", "auto":
+		return nil
+	case "none":
+		budget := int32(0)
+		return &types.GeminiThinkingConfig{ThinkingBudget: &budget}
+	}
+	budget := int32(ReasoningEffortToBudget(effort))
+	if budget <= 0 {
+		return nil
+	}
+	return &types.GeminiThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget}
+}
+
+// GeminiThinkingToBudget 将 Gemini thinkingConfig 转换为思考预算
+// 返回 enabled=false 表示未配置或显式关闭；动态预算（-1）按 medium 档位处理
+func GeminiThinkingToBudget(cfg *types.GeminiThinkingConfig) (budget int, enabled bool) {
+	if cfg == nil {
+		return 0, false
+	}
+	if cfg.ThinkingLevel != "" {
+		budget = ReasoningEffortToBudget(cfg.ThinkingLevel)
+		return budget, budget > 0
+	}
+	if cfg.ThinkingBudget == nil {
+		if cfg.IncludeThoughts {
+			return ReasoningEffortToBudget("medium"), true
+		}
+		return 0, false
+	}
+	switch b := int(*cfg.ThinkingBudget); {
+	case b == geminiDynamicThinkingBudget:
+		return ReasoningEffortToBudget("medium"), true
+	case b <= 0:
+		return 0, false
+	default:
+		return b, true
+	}
+}
+
+// GeminiThinkingToReasoningEffort 将 Gemini thinkingConfig 转换为 effort 档位
+// 显式关闭（thinkingBudget=0）返回 none，未配置返回空字符串
+func GeminiThinkingToReasoningEffort(cfg *types.GeminiThinkingConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.ThinkingLevel != "" {
+		return mapReasoningEffortToOpenAI(cfg.ThinkingLevel)
+	}
+	if cfg.ThinkingBudget != nil && *cfg.ThinkingBudget == 0 {
+		return "none"
+	}
+	budget, enabled := GeminiThinkingToBudget(cfg)
+	if !enabled {
+		return ""
+	}
+	return ReasoningBudgetToEffort(budget)
+}
@@ -0,0 +1,194 @@
+package converters
+
+import (
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+)
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func TestReasoningBudgetTable_Bidirectional(t *testing.T) {
+	for effort, budget := range map[string]int{"low": 1024, "medium": 8192, "high": 16384, "xhigh": 32768, "none": 0, "auto": 0} {
+		if got := ReasoningEffortToBudget(effort); got != budget {
+			t.Errorf("ReasoningEffortToBudget(%q) = %d, want %d", effort, got, budget)
+		}
+	}
+	for budget, effort := range map[int]string{0: "", 1024: "low", 4608: "low", 4609: "medium", 10000: "medium", 16384: "high", 31999: "high"} {
+		if got := ReasoningBudgetToEffort(budget); got != effort {
+			t.Errorf("ReasoningBudgetToEffort(%d) = %q, want %q", budget, got, effort)
+		}
+	}
+}
+
+func TestReasoningBudgetTable_ConfigOverride(t *testing.T) {
+	SetReasoningBudgetsSource(func() map[string]int {
+		return map[string]int{"low": 2048, "high": 64000, "unknown": 1, "medium": -1}
+	})
+	defer SetReasoningBudgetsSource(nil)
+
+	if got := ReasoningEffortToBudget("high"); got != 64000 {
+		t.Fatalf("high = %d, want override 64000", got)
+	}
+	if got := ReasoningEffortToBudget("medium"); got != 8192 {
+		t.Fatalf("medium = %d, invalid override must fall back to default", got)
+	}
+	// medium/high 分界点随预算表变化：(8192+64000)/2
+	if got := ReasoningBudgetToEffort(36000); got != "medium" {
+		t.Fatalf("36000 → %q, want medium", got)
+	}
+	if got := ReasoningBudgetToEffort(36097); got != "high" {
+		t.Fatalf("36097 → %q, want high", got)
+	}
+}
+
+func TestGeminiThinkingConfig_Mapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.GeminiThinkingConfig
+		budget  int
+		enabled bool
+		effort  string
+	}{
+		{name: "nil", cfg: nil, effort: ""},
+		{name: "disabled", cfg: &types.GeminiThinkingConfig{ThinkingBudget: int32Ptr(0)}, effort: "none"},
+		{name: "dynamic", cfg: &types.GeminiThinkingConfig{ThinkingBudget: int32Ptr(-1)}, budget: 8192, enabled: true, effort: "medium"},
+		{name: "budget", cfg: &types.GeminiThinkingConfig{ThinkingBudget: int32Ptr(20000), IncludeThoughts: true}, budget: 20000, enabled: true, effort: "high"},
+		{name: "level", cfg: &types.GeminiThinkingConfig{ThinkingLevel: "LOW"}, budget: 1024, enabled: true, effort: "low"},
+		{name: "include_only", cfg: &types.GeminiThinkingConfig{IncludeThoughts: true}, budget: 8192, enabled: true, effort: "medium"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget, enabled := GeminiThinkingToBudget(tt.cfg)
+			if budget != tt.budget || enabled != tt.enabled {
+				t.Errorf("GeminiThinkingToBudget = (%d, %v), want (%d, %v)", budget, enabled, tt.budget, tt.enabled)
+			}
+			if got := GeminiThinkingToReasoningEffort(tt.cfg); got != tt.effort {
+				t.Errorf("GeminiThinkingToReasoningEffort = %q, want %q", got, tt.effort)
+			}
+		})
+	}
+
+	if cfg := ReasoningEffortToGeminiThinking("none"); cfg == nil || *cfg.ThinkingBudget != 0 || cfg.IncludeThoughts {
+		t.Fatalf("none should disable thinking: %+v", cfg)
+	}
+	if cfg := ReasoningEffortToGeminiThinking("auto"); cfg != nil {
+		t.Fatalf("auto should keep upstream default: %+v", cfg)
+	}
+}
+
+func TestClaudeConverter_ReasoningEffortToThinking(t *testing.T) {
+	req := &types.ResponsesRequest{
+		Model:       "claude-sonnet",
+		Input:       "hi",
+		MaxTokens:   4096,
+		Temperature: 0.3,
+		Reasoning:   map[string]interface{}{"effort": "high"},
+	}
+
+	out, err := (&ClaudeConverter{}).ToProviderRequest(&session.Session{}, req)
+	if err != nil {
+		t.Fatalf("ToProviderRequest: %v", err)
+	}
+	root := mustJSON(t, out)
+	if root.Get("thinking.type").String() != "enabled" || root.Get("thinking.budget_tokens").Int() != 16384 {
+		t.Fatalf("unexpected thinking: %s", root.Raw)
+	}
+	if root.Get("max_tokens").Int() <= 16384 || root.Get("temperature").Exists() {
+		t.Fatalf("max_tokens must exceed budget and temperature must be dropped: %s", root.Raw)
+	}
+}
+
+func TestGeminiToClaudeAndOpenAI_ThinkingConfig(t *testing.T) {
+	temperature := 0.5
+	req := &types.GeminiRequest{
+		Contents: []types.GeminiContent{
+			{Role: "user", Parts: []types.GeminiPart{{Text: "hi"}}},
+			{Role: "model", Parts: []types.GeminiPart{{Text: "pondering", Thought: true}, {Text: "hello"}}},
+		},
+		GenerationConfig: &types.GeminiGenerationConfig{
+			Temperature:    &temperature,
+			ThinkingConfig: &types.GeminiThinkingConfig{ThinkingBudget: int32Ptr(512), IncludeThoughts: true},
+		},
+	}
+
+	claude, err := GeminiToClaudeRequest(req, "claude")
+	if err != nil {
+		t.Fatalf("GeminiToClaudeRequest: %v", err)
+	}
+	root := mustJSON(t, claude)
+	if root.Get("thinking.budget_tokens").Int() != 1024 || root.Get("temperature").Exists() {
+		t.Fatalf("unexpected Claude thinking (budget must be clamped to 1024): %s", root.Raw)
+	}
+	if root.Get("messages.1.content.#").Int() != 1 || root.Get("messages.1.content.0.text").String() != "hello" {
+		t.Fatalf("thought parts must not be replayed: %s", root.Get("messages").Raw)
+	}
+
+	openai, err := GeminiToOpenAIRequest(req, "gpt")
+	if err != nil {
+		t.Fatalf("GeminiToOpenAIRequest: %v", err)
+	}
+	root = mustJSON(t, openai)
+	if root.Get("reasoning_effort").String() != "low" || root.Get("messages.1.content").String() != "hello" {
+		t.Fatalf("unexpected OpenAI request: %s", root.Raw)
+	}
+
+	req.GenerationConfig.ThinkingConfig = &types.GeminiThinkingConfig{ThinkingBudget: int32Ptr(0)}
+	openai, err = GeminiToOpenAIRequest(req, "gpt")
+	if err != nil {
+		t.Fatalf("GeminiToOpenAIRequest: %v", err)
+	}
+	if mustJSON(t, openai).Get("reasoning_effort").Exists() {
+		t.Fatalf("disabled thinking should not emit reasoning_effort")
+	}
+}
+
+func TestReasoningOutputs_MapBackToClientProtocol(t *testing.T) {
+	claudeResp := map[string]interface{}{
+		"content": []interface{}{
+			map[string]interface{}{"type": "thinking", "thinking": "let me think", "signature": "sig"},
+			map[string]interface{}{"type": "text", "text": "answer"},
+		},
+	}
+
+	gemini, err := ClaudeResponseToGemini(claudeResp)
+	if err != nil {
+		t.Fatalf("ClaudeResponseToGemini: %v", err)
+	}
+	parts := gemini.Candidates[0].Content.Parts
+	if len(parts) != 2 || !parts[0].Thought || parts[0].Text != "let me think" || parts[1].Thought {
+		t.Fatalf("unexpected Gemini parts: %+v", parts)
+	}
+
+	responses, err := ClaudeResponseToResponses(claudeResp, "")
+	if err != nil {
+		t.Fatalf("ClaudeResponseToResponses: %v", err)
+	}
+	if out := mustJSON(t, responses.Output); out.Get("0.type").String() != "reasoning" ||
+		out.Get("0.summary.0.type").String() != "summary_text" || out.Get("0.summary.0.text").String() != "let me think" ||
+		out.Get("1.content").String() != "answer" {
+		t.Fatalf("unexpected Responses output: %s", out.Raw)
+	}
+
+	openaiResp := map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message":       map[string]interface{}{"role": "assistant", "content": "answer", "reasoning_content": "step by step"},
+			"finish_reason": "stop",
+		}},
+	}
+	gemini, err = OpenAIResponseToGemini(openaiResp)
+	if err != nil {
+		t.Fatalf("OpenAIResponseToGemini: %v", err)
+	}
+	if parts := gemini.Candidates[0].Content.Parts; len(parts) != 2 || !parts[0].Thought || parts[0].Text != "step by step" {
+		t.Fatalf("unexpected Gemini parts: %+v", parts)
+	}
+	responses, err = OpenAIChatResponseToResponses(openaiResp, "")
+	if err != nil {
+		t.Fatalf("OpenAIChatResponseToResponses: %v", err)
+	}
+	if out := mustJSON(t, responses.Output); out.Get("0.type").String() != "reasoning" || out.Get("0.summary.0.text").String() != "step by step" {
+		t.Fatalf("unexpected Responses output: %s", out.Raw)
+	}
+}
@@ -4,9 +4,11 @@
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
 )
 
 // ============== Responses → Claude Messages ==============
@@ -52,8 +54,15 @@ func responsesItemToClaudeMessage(item types.ResponsesItem) (*types.ClaudeMessag
 	case "message":
 		// 新格式：嵌套结构（type=message, role=user/assistant, content=[]ContentBlock）
 		role := item.Role
-		if role == "" {
-			role = "user" // 默认为 user
+		if role != "assistant" {
+			role = "user" // Claude messages 仅支持 user/assistant，system/developer 按 user 处理
+		}
+
+		// 包含图片/文件时按原顺序输出多模态内容块
+		if role == "user" {
+			if blocks := responsesContentToClaudeBlocks(item.Content); blocks != nil {
+				return &types.ClaudeMessage{Role: role, Content: blocks}, nil
+			}
 		}
 
 		contentText := extractTextFromContent(item.Content)
@@ -102,6 +111,14 @@ func responsesItemToClaudeMessage(item types.ResponsesItem) (*types.ClaudeMessag
 		// 工具结果（暂时简化处理）
 		return nil, nil
 
+	case "function_call", "function_call_output", "reasoning":
+		// 其他上游（如 Gemini）产生的函数调用与推理条目，暂不回放
+		return nil, nil
+
+	case "web_search_call", "code_interpreter_call":
+		// 服务端工具调用由上游执行，结果已体现在后续文本中，无需回放
+		return nil, nil
+
 	default:
 		return nil, fmt.Errorf("未知的 item type: %s", item.Type)
 	}
@@ -111,10 +128,23 @@ func responsesItemToClaudeMessage(item types.ResponsesItem) (*types.ClaudeMessag
 
 // ClaudeResponseToResponses 将 Claude 响应转换为 Responses 格式
 func ClaudeResponseToResponses(claudeResp map[string]interface{}, sessionID string) (*types.ResponsesResponse, error) {
+	// 模拟结构化输出的工具调用还原为文本
+	UnwrapClaudeStructuredOutput(claudeResp)
+
 	// 提取字段
 	model, _ := claudeResp["model"].(string)
 	content, _ := claudeResp["content"].([]interface{})
 
+	// 生成 response ID
+	responseID := generateResponseID()
+
+	// 服务端工具调用（server_tool_use 与结果块按 ID 关联）
+	contentJSON, _ := json.Marshal(content)
+	serverCalls := map[string]*ServerToolCall{}
+	for _, call := range ServerToolCallsFromClaude(gjson.ParseBytes(contentJSON)) {
+		serverCalls[call.ID] = call
+	}
+
 	// 转换 output
 	output := []types.ResponsesItem{}
 	for _, c := range content {
@@ -124,21 +154,43 @@ func ClaudeResponseToResponses(claudeResp map[string]interface{}, sessionID stri
 		}
 
 		blockType, _ := contentBlock["type"].(string)
-		if blockType == "text" {
+		switch blockType {
+		case "text":
 			text, _ := contentBlock["text"].(string)
+			// 带引用的文本块 → message 条目（output_text + url_citation 标注）
+			blockJSON, _ := json.Marshal([]interface{}{contentBlock})
+			if _, citations := ClaudeTextCitations(gjson.ParseBytes(blockJSON)); len(citations) > 0 {
+				part := types.ContentBlock{Type: "output_text", Text: text}
+				for _, citation := range citations {
+					part.Annotations = append(part.Annotations, citation.ResponsesAnnotation())
+				}
+				output = append(output, types.ResponsesItem{Type: "message", Role: "assistant", Status: "completed", Content: []types.ContentBlock{part}})
+				continue
+			}
 			output = append(output, types.ResponsesItem{
 				Type:    "text",
 				Content: text,
 			})
+		case "server_tool_use":
+			id, _ := contentBlock["id"].(string)
+			if call := serverCalls[id]; call != nil {
+				output = append(output, call.ResponsesItem())
+			}
+		case "thinking":
+			// thinking 块 → reasoning 条目（summary_text）
+			if thinking, _ := contentBlock["thinking"].(string); thinking != "" {
+				output = append(output, types.ResponsesItem{
+					Type:    "reasoning",
+					ID:      fmt.Sprintf("rs_%s_%d", responseID, len(output)),
+					Summary: []types.ContentBlock{{Type: "summary_text", Text: thinking}},
+				})
+			}
 		}
 	}
 
 	// 提取 usage（使用统一入口自动检测格式）
 	usage := ExtractUsageMetrics(claudeResp["usage"])
 
-	// 生成 response ID
-	responseID := generateResponseID()
-
 	return &types.ResponsesResponse{
 		ID:         responseID,
 		Model:      model,
@@ -197,6 +249,16 @@ func responsesItemToOpenAIMessage(item types.ResponsesItem) map[string]interface
 			role = "user"
 		}
 
+		// 包含图片/文件时使用 content parts 数组
+		if role == "user" {
+			if parts := responsesContentToChatParts(item.Content); parts != nil {
+				return map[string]interface{}{
+					"role":    role,
+					"content": parts,
+				}
+			}
+		}
+
 		contentText := extractTextFromContent(item.Content)
 		if contentText == "" {
 			return nil
@@ -236,26 +298,44 @@ func OpenAIChatResponseToResponses(openaiResp map[string]interface{}, sessionID
 	model, _ := openaiResp["model"].(string)
 	choices, _ := openaiResp["choices"].([]interface{})
 
+	// 生成 response ID
+	responseID := generateResponseID()
+
 	// 提取第一个 choice 的 message
 	output := []types.ResponsesItem{}
 	if len(choices) > 0 {
 		choice, ok := choices[0].(map[string]interface{})
 		if ok {
 			message, _ := choice["message"].(map[string]interface{})
+			// reasoning_content → reasoning 条目（summary_text）
+			if reasoning, _ := message["reasoning_content"].(string); reasoning != "" {
+				output = append(output, types.ResponsesItem{
+					Type:    "reasoning",
+					ID:      fmt.Sprintf("rs_%s_0", responseID),
+					Summary: []types.ContentBlock{{Type: "summary_text", Text: reasoning}},
+				})
+			}
 			content, _ := message["content"].(string)
-			output = append(output, types.ResponsesItem{
-				Type:    "text",
-				Content: content,
-			})
+			// url_citation 标注 → message 条目（output_text + annotations）
+			if annotations, _ := message["annotations"].([]interface{}); len(annotations) > 0 {
+				annotationsJSON, _ := json.Marshal(annotations)
+				part := types.ContentBlock{Type: "output_text", Text: content}
+				for _, citation := range WebCitationsFromAnnotations(gjson.ParseBytes(annotationsJSON), content, 0) {
+					part.Annotations = append(part.Annotations, citation.ResponsesAnnotation())
+				}
+				output = append(output, types.ResponsesItem{Type: "message", Role: "assistant", Status: "completed", Content: []types.ContentBlock{part}})
+			} else {
+				output = append(output, types.ResponsesItem{
+					Type:    "text",
+					Content: content,
+				})
+			}
 		}
 	}
 
 	// 提取 usage（使用统一入口自动检测格式）
 	usage := ExtractUsageMetrics(openaiResp["usage"])
 
-	// 生成 response ID
-	responseID := generateResponseID()
-
 	return &types.ResponsesResponse{
 		ID:         responseID,
 		Model:      model,
@@ -309,6 +389,74 @@ func extractTextFromContent(content interface{}) string {
 	return ""
 }
 
+// responsesContentToClaudeBlocks 将 Responses content 按原顺序转换为 Claude text/image/document 块
+// 不包含图片/文件时返回 nil，由调用方按纯文本处理
+func responsesContentToClaudeBlocks(content interface{}) []map[string]interface{} {
+	arr, ok := content.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	blocks := []map[string]interface{}{}
+	hasMedia := false
+	for _, c := range arr {
+		block, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "input_text", "output_text":
+			if text, _ := block["text"].(string); text != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+			}
+		case "input_image", "input_file":
+			if m, ok := mediaFromResponsesPart(block); ok {
+				if claudeBlock, ok := m.claudeBlock(); ok {
+					blocks = append(blocks, claudeBlock)
+					hasMedia = true
+				}
+			}
+		}
+	}
+	if !hasMedia {
+		return nil
+	}
+	return blocks
+}
+
+// responsesContentToChatParts 将 Responses content 按原顺序转换为 Chat content parts
+// 不包含图片/文件时返回 nil，由调用方按纯文本处理
+func responsesContentToChatParts(content interface{}) []map[string]interface{} {
+	arr, ok := content.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	parts := []map[string]interface{}{}
+	hasMedia := false
+	for _, c := range arr {
+		block, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "input_text", "output_text":
+			if text, _ := block["text"].(string); text != "" {
+				parts = append(parts, map[string]interface{}{"type": "text", "text": text})
+			}
+		case "input_image", "input_file":
+			if m, ok := mediaFromResponsesPart(block); ok {
+				parts = append(parts, m.chatPart())
+				hasMedia = true
+			}
+		}
+	}
+	if !hasMedia {
+		return nil
+	}
+	return parts
+}
+
 // parseResponsesInput 解析 input 字段（可能是 string 或 []ResponsesItem）
 func parseResponsesInput(input interface{}) ([]types.ResponsesItem, error) {
 	switch v := input.(type) {
@@ -331,11 +479,26 @@ func parseResponsesInput(input interface{}) ([]types.ResponsesItem, error) {
 			}
 
 			itemType, _ := itemMap["type"].(string)
+			role, _ := itemMap["role"].(string)
 			content := itemMap["content"]
 
+			// 省略 type 的消息简写（{"role":"user","content":...}）
+			if itemType == "" && role != "" {
+				itemType = "message"
+			}
+
+			callID, _ := itemMap["call_id"].(string)
+			name, _ := itemMap["name"].(string)
+			arguments, _ := itemMap["arguments"].(string)
+
 			items = append(items, types.ResponsesItem{
-				Type:    itemType,
-				Content: content,
+				Type:      itemType,
+				Role:      role,
+				Content:   content,
+				CallID:    callID,
+				Name:      name,
+				Arguments: arguments,
+				Output:    itemMap["output"],
 			})
 		}
 		return items, nil
@@ -356,53 +519,29 @@ func generateResponseID() string {
 
 // getCurrentTimestamp 获取当前时间戳（毫秒）
 func getCurrentTimestamp() int64 {
-	return 0 // 占位符，实际应使用 time.Now().UnixNano() / 1e6
-}
-
-// ExtractTextFromResponses 从 Responses 消息中提取纯文本（用于 OpenAI Completions）
-func ExtractTextFromResponses(sess *session.Session, newInput interface{}) (string, error) {
-	texts := []string{}
-
-	// 历史消息
-	for _, item := range sess.Messages {
-		if item.Type == "text" {
-			if text, ok := item.Content.(string); ok {
-				texts = append(texts, text)
-			}
-		}
-	}
-
-	// 新输入
-	newItems, err := parseResponsesInput(newInput)
-	if err != nil {
-		return "", err
-	}
-
-	for _, item := range newItems {
-		if item.Type == "text" {
-			if text, ok := item.Content.(string); ok {
-				texts = append(texts, text)
-			}
-		}
-	}
-
-	return strings.Join(texts, "\n"), nil
+	return time.Now().UnixNano() / 1e6
 }
 
 // OpenAICompletionsResponseToResponses OpenAI Completions 响应转 Responses
+// 补全文本作为 assistant message 输出，便于通过 previous_response_id 继续对话
 func OpenAICompletionsResponseToResponses(completionsResp map[string]interface{}, sessionID string) (*types.ResponsesResponse, error) {
 	model, _ := completionsResp["model"].(string)
 	choices, _ := completionsResp["choices"].([]interface{})
 
 	output := []types.ResponsesItem{}
+	status := "completed"
 	if len(choices) > 0 {
 		choice, ok := choices[0].(map[string]interface{})
 		if ok {
 			text, _ := choice["text"].(string)
 			output = append(output, types.ResponsesItem{
-				Type:    "text",
-				Content: text,
+				Type:    "message",
+				Role:    "assistant",
+				Content: []types.ContentBlock{{Type: "output_text", Text: text}},
 			})
+			if reason, _ := choice["finish_reason"].(string); reason != "" {
+				status = OpenAIFinishReasonToResponses(reason)
+			}
 		}
 	}
 
@@ -415,7 +554,7 @@ func OpenAICompletionsResponseToResponses(completionsResp map[string]interface{}
 		ID:         responseID,
 		Model:      model,
 		Output:     output,
-		Status:     "completed",
+		Status:     status,
 		PreviousID: "",
 		Usage:      usage,
 	}, nil
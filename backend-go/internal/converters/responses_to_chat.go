@@ -9,7 +9,7 @@
 // 转换内容包括:
 // 1. model 和 stream 配置
 // 2. instructions → system message
-// 3. input 数组 → messages 数组
+// 3. input 数组 → messages 数组（input_image/input_file → image_url/file）
 // 4. tools 定义转换
 // 5. function_call 和 function_call_output 处理
 // 6. 生成参数映射 (max_tokens, reasoning 等)
@@ -111,6 +111,11 @@ func ConvertResponsesToOpenAIChatRequest(modelName string, inputRawJSON []byte,
 		out, _ = sjson.Set(out, "tool_choice", toolChoice.String())
 	}
 
+	// 转换 text.format → response_format
+	if so := StructuredOutputFromResponses(root.Get("text").Value()); so != nil {
+		out, _ = sjson.Set(out, "response_format", so.ChatResponseFormat())
+	}
+
 	return []byte(out)
 }
 
@@ -154,9 +159,11 @@ func convertMessageItem(item gjson.Result, out string) string {
 	content := item.Get("content")
 	if content.Exists() {
 		if content.IsArray() {
-			// content 是数组，需要提取文本
+			// content 是数组，需要提取文本；user 消息中的图片/文件转换为 content parts
 			var messageContent string
 			var toolCalls []interface{}
+			var parts []interface{}
+			hasMedia := false
 
 			content.ForEach(func(_, contentItem gjson.Result) bool {
 				contentType := contentItem.Get("type").String()
@@ -172,11 +179,21 @@ func convertMessageItem(item gjson.Result, out string) string {
 					} else {
 						messageContent = text
 					}
+					parts = append(parts, map[string]interface{}{"type": "text", "text": text})
+				case "input_image", "input_file":
+					if block, ok := contentItem.Value().(map[string]interface{}); ok {
+						if m, ok := mediaFromResponsesPart(block); ok {
+							parts = append(parts, m.chatPart())
+							hasMedia = true
+						}
+					}
 				}
 				return true
 			})
 
-			if messageContent != "" {
+			if hasMedia && role == "user" {
+				message, _ = sjson.Set(message, "content", parts)
+			} else if messageContent != "" {
 				message, _ = sjson.Set(message, "content", messageContent)
 			}
 
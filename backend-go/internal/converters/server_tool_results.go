@@ -0,0 +1,605 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+// ============== 服务端工具结果跨协议映射 ==============
+//
+// 搜索来源、正文引用与代码执行结果先解析为协议无关的结构，再按目标协议输出。
+// 引用区间统一使用字符（rune）偏移，基于完整回复文本（所有文本块按顺序拼接）；
+// Gemini segment 使用 UTF-8 字节偏移，解析与生成时换算。
+
+// WebSearchSource 联网搜索来源
+type WebSearchSource struct {
+	URL   string
+	Title string
+}
+
+// WebCitation 正文中的联网搜索引用
+type WebCitation struct {
+	URL       string
+	Title     string
+	CitedText string
+	Start     int // 片段在完整回复文本中的起始字符偏移
+	End       int // 片段结束字符偏移（不含）
+}
+
+// ServerToolCall 一次服务端工具调用及其结果
+type ServerToolCall struct {
+	ID        string
+	Kind      ServerToolKind
+	Query     string            // web_search: 搜索词
+	Sources   []WebSearchSource // web_search: 搜索来源
+	Code      string            // code_execution: 执行的代码
+	Output    string            // code_execution: 输出（失败时为错误输出）
+	Failed    bool              // code_execution: 是否执行失败
+	HasResult bool
+}
+
+// ============== Claude ==============
+
+// ClaudeBlocks 生成 server_tool_use 及对应的结果块
+func (c *ServerToolCall) ClaudeBlocks() []types.ClaudeContent {
+	spec := serverToolRegistry[c.Kind]
+	use := types.ClaudeContent{Type: "server_tool_use", ID: c.ID, Name: spec.claudeName}
+	var result interface{}
+	switch c.Kind {
+	case ServerToolWebSearch:
+		use.Input = map[string]interface{}{"query": c.Query}
+		results := []interface{}{}
+		for _, s := range c.Sources {
+			results = append(results, map[string]interface{}{
+				"type":              "web_search_result",
+				"url":               s.URL,
+				"title":             s.Title,
+				"encrypted_content": "",
+			})
+		}
+		result = results
+	case ServerToolCodeExecution:
+		use.Input = map[string]interface{}{"code": c.Code}
+		stdout, stderr, returnCode := c.Output, "", 0
+		if c.Failed {
+			stdout, stderr, returnCode = "", c.Output, 1
+		}
+		result = map[string]interface{}{
+			"type":        "code_execution_result",
+			"stdout":      stdout,
+			"stderr":      stderr,
+			"return_code": returnCode,
+			"content":     []interface{}{},
+		}
+	}
+	blocks := []types.ClaudeContent{use}
+	if c.HasResult {
+		blocks = append(blocks, types.ClaudeContent{Type: spec.claudeResultType, ToolUseID: c.ID, Content: result})
+	}
+	return blocks
+}
+
+// ClaudeCitation 生成 Claude 文本块的 web_search_result_location 引用
+func (w WebCitation) ClaudeCitation() map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "web_search_result_location",
+		"url":             w.URL,
+		"title":           w.Title,
+		"cited_text":      w.CitedText,
+		"encrypted_index": "",
+	}
+}
+
+// ClaudeBlockEvents 生成完整内容块的流式事件（server_tool_use 的 input 通过 input_json_delta 下发）
+func ClaudeBlockEvents(index int, block types.ClaudeContent) []string {
+	var events []string
+	start := block
+	var inputJSON []byte
+	if block.Type == "server_tool_use" {
+		inputJSON, _ = json.Marshal(block.Input)
+		start.Input = map[string]interface{}{}
+	}
+	startJSON, _ := json.Marshal(map[string]interface{}{"type": "content_block_start", "index": index, "content_block": start})
+	events = append(events, claudeSSE("content_block_start", string(startJSON)))
+	if inputJSON != nil {
+		deltaJSON, _ := json.Marshal(map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": index,
+			"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": string(inputJSON)},
+		})
+		events = append(events, claudeSSE("content_block_delta", string(deltaJSON)))
+	}
+	stopJSON, _ := json.Marshal(map[string]interface{}{"type": "content_block_stop", "index": index})
+	return append(events, claudeSSE("content_block_stop", string(stopJSON)))
+}
+
+// ClaudeCitationDeltaEvent 生成文本块的 citations_delta 事件
+func ClaudeCitationDeltaEvent(index int, citation WebCitation) string {
+	deltaJSON, _ := json.Marshal(map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]interface{}{"type": "citations_delta", "citation": citation.ClaudeCitation()},
+	})
+	return claudeSSE("content_block_delta", string(deltaJSON))
+}
+
+// ServerToolCallsFromClaude 解析 Claude content 中的服务端工具调用，结果块按 tool_use_id 关联
+func ServerToolCallsFromClaude(content gjson.Result) []*ServerToolCall {
+	var calls []*ServerToolCall
+	byID := map[string]*ServerToolCall{}
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "server_tool_use":
+			if call := serverToolCallFromClaudeBlock(block); call != nil {
+				calls = append(calls, call)
+				byID[call.ID] = call
+			}
+		case "web_search_tool_result", "code_execution_tool_result":
+			if call := byID[block.Get("tool_use_id").String()]; call != nil {
+				applyClaudeToolResult(call, block)
+			}
+		}
+		return true
+	})
+	return calls
+}
+
+// serverToolCallFromClaudeBlock 解析 server_tool_use 块，无法识别的工具返回 nil
+func serverToolCallFromClaudeBlock(block gjson.Result) *ServerToolCall {
+	for kind, spec := range serverToolRegistry {
+		if block.Get("name").String() == spec.claudeName {
+			call := &ServerToolCall{ID: block.Get("id").String(), Kind: kind}
+			call.setInput(block.Get("input"))
+			return call
+		}
+	}
+	return nil
+}
+
+// setInput 从 server_tool_use.input 中提取搜索词或代码
+func (c *ServerToolCall) setInput(input gjson.Result) {
+	c.Query = input.Get("query").String()
+	c.Code = input.Get("code").String()
+}
+
+// applyClaudeToolResult 将 Claude 结果块写入调用
+func applyClaudeToolResult(call *ServerToolCall, block gjson.Result) {
+	call.HasResult = true
+	result := block.Get("content")
+	if block.Get("type").String() == "web_search_tool_result" {
+		result.ForEach(func(_, r gjson.Result) bool {
+			if r.Get("type").String() == "web_search_result" {
+				call.Sources = append(call.Sources, WebSearchSource{URL: r.Get("url").String(), Title: r.Get("title").String()})
+			}
+			return true
+		})
+		return
+	}
+	call.Output = result.Get("stdout").String() + result.Get("stderr").String()
+	call.Failed = result.Get("return_code").Int() != 0 || result.Get("type").String() != "code_execution_result"
+}
+
+// ClaudeServerToolStream 从 Claude 流式事件中收集服务端工具调用与文本引用
+type ClaudeServerToolStream struct {
+	Calls     []*ServerToolCall
+	Citations []WebCitation
+
+	blocks     map[int]*ServerToolCall // 内容块索引 → server_tool_use
+	inputs     map[int]string          // 内容块索引 → 累积的 input_json_delta
+	byID       map[string]*ServerToolCall
+	textLen    int         // 已输出文本的字符数
+	textStarts map[int]int // 文本块索引 → 起始字符偏移
+	pending    map[int][]int
+}
+
+// Observe 处理一条 Claude 流式事件，结果块到达时返回对应的已完成调用
+func (s *ClaudeServerToolStream) Observe(event gjson.Result) *ServerToolCall {
+	if s.blocks == nil {
+		s.blocks = map[int]*ServerToolCall{}
+		s.inputs = map[int]string{}
+		s.byID = map[string]*ServerToolCall{}
+		s.textStarts = map[int]int{}
+		s.pending = map[int][]int{}
+	}
+	index := int(event.Get("index").Int())
+	switch event.Get("type").String() {
+	case "content_block_start":
+		block := event.Get("content_block")
+		switch block.Get("type").String() {
+		case "text":
+			s.textStarts[index] = s.textLen
+		case "server_tool_use":
+			if call := serverToolCallFromClaudeBlock(block); call != nil {
+				s.Calls = append(s.Calls, call)
+				s.blocks[index] = call
+				s.byID[call.ID] = call
+			}
+		case "web_search_tool_result", "code_execution_tool_result":
+			if call := s.byID[block.Get("tool_use_id").String()]; call != nil {
+				applyClaudeToolResult(call, block)
+				return call
+			}
+		}
+	case "content_block_delta":
+		delta := event.Get("delta")
+		switch delta.Get("type").String() {
+		case "text_delta":
+			s.textLen += utf8.RuneCountInString(delta.Get("text").String())
+		case "input_json_delta":
+			if _, ok := s.blocks[index]; ok {
+				s.inputs[index] += delta.Get("partial_json").String()
+			}
+		case "citations_delta":
+			if c := delta.Get("citation"); c.Get("type").String() == "web_search_result_location" {
+				s.pending[index] = append(s.pending[index], len(s.Citations))
+				s.Citations = append(s.Citations, WebCitation{
+					URL:       c.Get("url").String(),
+					Title:     c.Get("title").String(),
+					CitedText: c.Get("cited_text").String(),
+					Start:     s.textStarts[index],
+				})
+			}
+		}
+	case "content_block_stop":
+		if call, ok := s.blocks[index]; ok && s.inputs[index] != "" {
+			call.setInput(gjson.Parse(s.inputs[index]))
+		}
+		// 引用区间为所属文本块的完整区间
+		for _, i := range s.pending[index] {
+			s.Citations[i].End = s.textLen
+		}
+		delete(s.pending, index)
+	}
+	return nil
+}
+
+// ClaudeTextCitations 拼接 Claude content 中的文本块并解析 web_search_result_location 引用
+// 引用区间为所属文本块在拼接文本中的区间
+func ClaudeTextCitations(content gjson.Result) (string, []WebCitation) {
+	var text strings.Builder
+	var citations []WebCitation
+	offset := 0
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() != "text" {
+			return true
+		}
+		blockText := block.Get("text").String()
+		end := offset + utf8.RuneCountInString(blockText)
+		block.Get("citations").ForEach(func(_, c gjson.Result) bool {
+			if c.Get("type").String() == "web_search_result_location" {
+				citations = append(citations, WebCitation{
+					URL:       c.Get("url").String(),
+					Title:     c.Get("title").String(),
+					CitedText: c.Get("cited_text").String(),
+					Start:     offset,
+					End:       end,
+				})
+			}
+			return true
+		})
+		text.WriteString(blockText)
+		offset = end
+		return true
+	})
+	return text.String(), citations
+}
+
+// ApplyWebCitations 按引用区间拆分 Claude 文本块并挂载 citations
+// 引用区间基于所有文本块拼接后的文本；无引用时原样返回
+func ApplyWebCitations(content []types.ClaudeContent, citations []WebCitation) []types.ClaudeContent {
+	if len(citations) == 0 {
+		return content
+	}
+	out := make([]types.ClaudeContent, 0, len(content))
+	offset := 0
+	for _, block := range content {
+		if block.Type != "text" {
+			out = append(out, block)
+			continue
+		}
+		runes := []rune(block.Text)
+		start, end := offset, offset+len(runes)
+		offset = end
+
+		// 收集落在块内部的切分点
+		cuts := []int{start, end}
+		for _, c := range citations {
+			for _, p := range []int{c.Start, c.End} {
+				if p > start && p < end {
+					cuts = append(cuts, p)
+				}
+			}
+		}
+		sort.Ints(cuts)
+		for i := 0; i+1 < len(cuts); i++ {
+			segStart, segEnd := cuts[i], cuts[i+1]
+			if segStart == segEnd {
+				continue
+			}
+			seg := types.ClaudeContent{Type: "text", Text: string(runes[segStart-start : segEnd-start])}
+			for _, c := range citations {
+				if c.Start <= segStart && c.End >= segEnd && c.End > c.Start {
+					seg.Citations = append(seg.Citations, c.ClaudeCitation())
+				}
+			}
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+// ============== Responses ==============
+
+// ResponsesItem 生成 web_search_call / code_interpreter_call 输出项
+func (c *ServerToolCall) ResponsesItem() types.ResponsesItem {
+	item := types.ResponsesItem{Type: serverToolRegistry[c.Kind].responsesCallType, ID: c.ID, Status: "completed"}
+	switch c.Kind {
+	case ServerToolWebSearch:
+		sources := []interface{}{}
+		for _, s := range c.Sources {
+			sources = append(sources, map[string]interface{}{"type": "url", "url": s.URL})
+		}
+		item.Action = map[string]interface{}{"type": "search", "query": c.Query, "sources": sources}
+	case ServerToolCodeExecution:
+		item.Code = c.Code
+		if c.HasResult {
+			item.Outputs = []interface{}{map[string]interface{}{"type": "logs", "logs": c.Output}}
+		}
+		if c.Failed {
+			item.Status = "failed"
+		}
+	}
+	return item
+}
+
+// ResponsesAnnotation 生成 output_text 的 url_citation 标注
+func (w WebCitation) ResponsesAnnotation() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "url_citation",
+		"url":         w.URL,
+		"title":       w.Title,
+		"start_index": w.Start,
+		"end_index":   w.End,
+	}
+}
+
+// ServerToolCallFromResponsesItem 解析 Responses 输出项，非服务端工具调用返回 nil
+func ServerToolCallFromResponsesItem(item gjson.Result) *ServerToolCall {
+	switch item.Get("type").String() {
+	case "web_search_call":
+		call := &ServerToolCall{ID: item.Get("id").String(), Kind: ServerToolWebSearch, Query: item.Get("action.query").String(), HasResult: true}
+		item.Get("action.sources").ForEach(func(_, s gjson.Result) bool {
+			call.Sources = append(call.Sources, WebSearchSource{URL: s.Get("url").String(), Title: s.Get("title").String()})
+			return true
+		})
+		return call
+	case "code_interpreter_call":
+		call := &ServerToolCall{ID: item.Get("id").String(), Kind: ServerToolCodeExecution, Code: item.Get("code").String(), Failed: item.Get("status").String() == "failed"}
+		item.Get("outputs").ForEach(func(_, o gjson.Result) bool {
+			if o.Get("type").String() == "logs" {
+				call.HasResult = true
+				call.Output += o.Get("logs").String()
+			}
+			return true
+		})
+		return call
+	}
+	return nil
+}
+
+// WebCitationsFromAnnotations 解析 url_citation 标注（兼容 Responses 扁平格式与 Chat 的 url_citation 嵌套格式）
+// text 为标注所属文本，offset 为该文本在完整回复文本中的字符偏移
+func WebCitationsFromAnnotations(annotations gjson.Result, text string, offset int) []WebCitation {
+	runes := []rune(text)
+	var citations []WebCitation
+	annotations.ForEach(func(_, a gjson.Result) bool {
+		if a.Get("type").String() != "url_citation" {
+			return true
+		}
+		if nested := a.Get("url_citation"); nested.IsObject() {
+			a = nested
+		}
+		start, end := int(a.Get("start_index").Int()), int(a.Get("end_index").Int())
+		if start < 0 || end > len(runes) || start > end {
+			start, end = 0, len(runes)
+		}
+		citations = append(citations, WebCitation{
+			URL:       a.Get("url").String(),
+			Title:     a.Get("title").String(),
+			CitedText: string(runes[start:end]),
+			Start:     offset + start,
+			End:       offset + end,
+		})
+		return true
+	})
+	return citations
+}
+
+// ============== Chat ==============
+
+// ChatAnnotation 生成 Chat message 的 url_citation 标注
+func (w WebCitation) ChatAnnotation() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "url_citation",
+		"url_citation": map[string]interface{}{
+			"url":         w.URL,
+			"title":       w.Title,
+			"start_index": w.Start,
+			"end_index":   w.End,
+		},
+	}
+}
+
+// ============== Gemini ==============
+
+// GeminiGroundingFromMap 从 map 形式的 candidate.groundingMetadata 解析搜索元数据
+func GeminiGroundingFromMap(v interface{}) *types.GeminiGroundingMetadata {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var gm types.GeminiGroundingMetadata
+	if err := json.Unmarshal(raw, &gm); err != nil {
+		return nil
+	}
+	return &gm
+}
+
+// WebSearchFromGemini 将 groundingMetadata 解析为搜索调用与正文引用
+// 每个搜索词对应一次调用，来源无法按搜索词区分，统一挂在最后一次调用上；text 为完整回复文本
+func WebSearchFromGemini(gm *types.GeminiGroundingMetadata, text, idPrefix string) ([]*ServerToolCall, []WebCitation) {
+	if gm == nil || (len(gm.WebSearchQueries) == 0 && len(gm.GroundingChunks) == 0) {
+		return nil, nil
+	}
+	queries := gm.WebSearchQueries
+	if len(queries) == 0 {
+		queries = []string{""}
+	}
+	var calls []*ServerToolCall
+	for i, q := range queries {
+		calls = append(calls, &ServerToolCall{ID: fmt.Sprintf("%s_%d", idPrefix, i), Kind: ServerToolWebSearch, Query: q, HasResult: true})
+	}
+	last := calls[len(calls)-1]
+	for _, chunk := range gm.GroundingChunks {
+		if chunk.Web != nil {
+			last.Sources = append(last.Sources, WebSearchSource{URL: chunk.Web.URI, Title: chunk.Web.Title})
+		}
+	}
+
+	var citations []WebCitation
+	for _, support := range gm.GroundingSupports {
+		start := byteToRuneOffset(text, support.Segment.StartIndex)
+		end := byteToRuneOffset(text, support.Segment.EndIndex)
+		for _, idx := range support.GroundingChunkIndices {
+			if idx < 0 || idx >= len(gm.GroundingChunks) || gm.GroundingChunks[idx].Web == nil {
+				continue
+			}
+			web := gm.GroundingChunks[idx].Web
+			citations = append(citations, WebCitation{URL: web.URI, Title: web.Title, CitedText: support.Segment.Text, Start: start, End: end})
+		}
+	}
+	return calls, citations
+}
+
+// GeminiGrounding 由搜索调用与正文引用生成 groundingMetadata；text 为完整回复文本，无搜索时返回 nil
+func GeminiGrounding(calls []*ServerToolCall, citations []WebCitation, text string) *types.GeminiGroundingMetadata {
+	gm := &types.GeminiGroundingMetadata{}
+	chunkIndex := map[string]int{}
+	addChunk := func(url, title string) int {
+		if idx, ok := chunkIndex[url]; ok {
+			return idx
+		}
+		chunkIndex[url] = len(gm.GroundingChunks)
+		gm.GroundingChunks = append(gm.GroundingChunks, types.GeminiGroundingChunk{Web: &types.GeminiGroundingWeb{URI: url, Title: title}})
+		return chunkIndex[url]
+	}
+	for _, call := range calls {
+		if call.Kind != ServerToolWebSearch {
+			continue
+		}
+		if call.Query != "" {
+			gm.WebSearchQueries = append(gm.WebSearchQueries, call.Query)
+		}
+		for _, s := range call.Sources {
+			addChunk(s.URL, s.Title)
+		}
+	}
+
+	// 相同区间的引用合并为一个 groundingSupport
+	supportIndex := map[[2]int]int{}
+	runes := []rune(text)
+	for _, c := range citations {
+		key := [2]int{c.Start, c.End}
+		idx, ok := supportIndex[key]
+		if !ok {
+			segText := c.CitedText
+			if c.Start >= 0 && c.End <= len(runes) && c.Start < c.End {
+				segText = string(runes[c.Start:c.End])
+			}
+			idx = len(gm.GroundingSupports)
+			supportIndex[key] = idx
+			gm.GroundingSupports = append(gm.GroundingSupports, types.GeminiGroundingSupport{
+				Segment: types.GeminiSegment{
+					StartIndex: runeToByteOffset(text, c.Start),
+					EndIndex:   runeToByteOffset(text, c.End),
+					Text:       segText,
+				},
+			})
+		}
+		support := &gm.GroundingSupports[idx]
+		support.GroundingChunkIndices = append(support.GroundingChunkIndices, addChunk(c.URL, c.Title))
+	}
+
+	if len(gm.WebSearchQueries) == 0 && len(gm.GroundingChunks) == 0 {
+		return nil
+	}
+	return gm
+}
+
+// GeminiParts 生成代码执行的 executableCode / codeExecutionResult part（联网搜索通过 groundingMetadata 表达）
+func (c *ServerToolCall) GeminiParts() []types.GeminiPart {
+	if c.Kind != ServerToolCodeExecution {
+		return nil
+	}
+	parts := []types.GeminiPart{{ExecutableCode: &types.GeminiExecutableCode{Language: "PYTHON", Code: c.Code}}}
+	if c.HasResult {
+		outcome := "OUTCOME_OK"
+		if c.Failed {
+			outcome = "OUTCOME_FAILED"
+		}
+		parts = append(parts, types.GeminiPart{CodeExecutionResult: &types.GeminiCodeExecutionResult{Outcome: outcome, Output: c.Output}})
+	}
+	return parts
+}
+
+// GeminiCodeExecutionCalls 解析 Gemini parts 中的代码执行，结果 part 关联到前一个 executableCode
+func GeminiCodeExecutionCalls(parts []types.GeminiPart, idPrefix string) []*ServerToolCall {
+	var calls []*ServerToolCall
+	for _, part := range parts {
+		if part.ExecutableCode != nil {
+			calls = append(calls, &ServerToolCall{ID: fmt.Sprintf("%s_%d", idPrefix, len(calls)), Kind: ServerToolCodeExecution, Code: part.ExecutableCode.Code})
+		}
+		if part.CodeExecutionResult != nil && len(calls) > 0 {
+			last := calls[len(calls)-1]
+			last.HasResult = true
+			last.Output = part.CodeExecutionResult.Output
+			last.Failed = part.CodeExecutionResult.Outcome != "OUTCOME_OK"
+		}
+	}
+	return calls
+}
+
+// byteToRuneOffset 将 UTF-8 字节偏移换算为字符偏移
+func byteToRuneOffset(text string, byteOffset int) int {
+	if byteOffset <= 0 {
+		return 0
+	}
+	if byteOffset > len(text) {
+		byteOffset = len(text)
+	}
+	return utf8.RuneCountInString(text[:byteOffset])
+}
+
+// runeToByteOffset 将字符偏移换算为 UTF-8 字节偏移
+func runeToByteOffset(text string, runeOffset int) int {
+	if runeOffset <= 0 {
+		return 0
+	}
+	count := 0
+	for i := range text {
+		if count == runeOffset {
+			return i
+		}
+		count++
+	}
+	return len(text)
+}
@@ -0,0 +1,343 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+// ============== 服务端工具跨协议映射 ==============
+//
+// 服务端工具由上游自行执行（客户端无需回传结果），各协议的表示方式：
+//   - Claude:    tools: [{"type":"web_search_20250305","name":"web_search"}] / [{"type":"code_execution_20250522","name":"code_execution"}]
+//     结果为 server_tool_use + web_search_tool_result / code_execution_tool_result 内容块，
+//     引用挂在 text 块的 citations（web_search_result_location）上
+//   - Responses: tools: [{"type":"web_search"}] / [{"type":"code_interpreter","container":{"type":"auto"}}]
+//     结果为 web_search_call / code_interpreter_call 输出项，引用为 output_text.annotations（url_citation）
+//   - Gemini:    tools: [{"googleSearch":{}}] / [{"codeExecution":{}}]
+//     结果为 candidate.groundingMetadata 与 executableCode / codeExecutionResult part
+//   - Chat:      仅支持联网搜索，顶层 web_search_options；引用为 message.annotations（url_citation）
+//
+// 目标协议没有等价工具时返回 *UnsupportedServerToolError，由 handler 以 400 返回给客户端，
+// 不再静默丢弃工具。客户端执行的内置工具（如 Claude bash/text_editor、Responses local_shell）
+// 不属于服务端工具，仍按各转换器原有逻辑处理。
+
+// ServerToolKind 协议无关的服务端工具种类
+type ServerToolKind string
+
+const (
+	ServerToolWebSearch     ServerToolKind = "web_search"
+	ServerToolCodeExecution ServerToolKind = "code_execution"
+)
+
+// 目标协议名称（用于错误信息）
+const (
+	ToolProtocolClaude      = "Claude Messages"
+	ToolProtocolResponses   = "OpenAI Responses"
+	ToolProtocolChat        = "OpenAI Chat Completions"
+	ToolProtocolGemini      = "Gemini"
+	ToolProtocolCompletions = "OpenAI Completions"
+)
+
+// serverToolSpec 服务端工具在各协议中的表示（空值表示该协议没有等价工具）
+type serverToolSpec struct {
+	claudeTypePrefix  string   // Claude 工具类型前缀（类型带日期版本）
+	claudeType        string   // 转换为 Claude 时使用的版本
+	claudeName        string   // Claude 工具名（server_tool_use.name）
+	claudeResultType  string   // Claude 结果块类型
+	responsesTypes    []string // Responses 工具类型（首个为转换时使用的类型）
+	responsesCallType string   // Responses 输出项类型
+	geminiFields      []string // Gemini 工具字段（首个为转换时使用的字段）
+	chatParam         string   // Chat Completions 顶层参数
+}
+
+// serverToolRegistry 服务端工具注册表
+var serverToolRegistry = map[ServerToolKind]serverToolSpec{
+	ServerToolWebSearch: {
+		claudeTypePrefix:  "web_search_",
+		claudeType:        "web_search_20250305",
+		claudeName:        "web_search",
+		claudeResultType:  "web_search_tool_result",
+		responsesTypes:    []string{"web_search", "web_search_preview", "web_search_2025_08_26", "web_search_preview_2025_03_11"},
+		responsesCallType: "web_search_call",
+		geminiFields:      []string{"googleSearch", "googleSearchRetrieval"},
+		chatParam:         "web_search_options",
+	},
+	ServerToolCodeExecution: {
+		claudeTypePrefix:  "code_execution_",
+		claudeType:        "code_execution_20250522",
+		claudeName:        "code_execution",
+		claudeResultType:  "code_execution_tool_result",
+		responsesTypes:    []string{"code_interpreter"},
+		responsesCallType: "code_interpreter_call",
+		geminiFields:      []string{"codeExecution"},
+	},
+}
+
+// claudeHostedToolPrefixes 没有跨协议等价物的 Claude 服务端工具
+var claudeHostedToolPrefixes = []string{"web_fetch_", "mcp_toolset"}
+
+// responsesHostedToolTypes 没有跨协议等价物的 Responses 托管工具
+var responsesHostedToolTypes = []string{"file_search", "image_generation", "mcp"}
+
+// ServerTool 协议无关的服务端工具
+type ServerTool struct {
+	Kind ServerToolKind
+
+	// 以下为联网搜索选项，目标协议不支持的选项会被忽略
+	MaxUses           int
+	AllowedDomains    []string
+	BlockedDomains    []string
+	UserLocation      map[string]interface{} // {"type":"approximate","city","region","country","timezone"}
+	SearchContextSize string                 // low / medium / high
+}
+
+// UnsupportedServerToolError 目标协议没有与之等价的服务端工具
+type UnsupportedServerToolError struct {
+	Tool   string // 源协议中的工具类型
+	Target string // 目标协议
+}
+
+func (e *UnsupportedServerToolError) Error() string {
+	return fmt.Sprintf("server tool %q is not supported by %s upstream", e.Tool, e.Target)
+}
+
+// supportedBy 目标协议是否有等价工具
+func (t *ServerTool) supportedBy(target string) bool {
+	spec := serverToolRegistry[t.Kind]
+	switch target {
+	case ToolProtocolClaude:
+		return spec.claudeType != ""
+	case ToolProtocolResponses:
+		return len(spec.responsesTypes) > 0
+	case ToolProtocolGemini:
+		return len(spec.geminiFields) > 0
+	case ToolProtocolChat:
+		return spec.chatParam != ""
+	}
+	return false
+}
+
+// checkServerTool 校验工具在目标协议中可用
+func checkServerTool(t *ServerTool, toolType, target string) (*ServerTool, error) {
+	if t == nil || !t.supportedBy(target) {
+		return nil, &UnsupportedServerToolError{Tool: toolType, Target: target}
+	}
+	return t, nil
+}
+
+// ServerToolFromClaude 解析 Claude 工具定义
+// 函数工具及客户端执行的内置工具返回 (nil, nil)
+func ServerToolFromClaude(tool types.ClaudeTool, target string) (*ServerTool, error) {
+	if tool.Type == "" || tool.Type == "custom" {
+		return nil, nil
+	}
+	for _, prefix := range claudeHostedToolPrefixes {
+		if strings.HasPrefix(tool.Type, prefix) {
+			return nil, &UnsupportedServerToolError{Tool: tool.Type, Target: target}
+		}
+	}
+	for kind, spec := range serverToolRegistry {
+		if !strings.HasPrefix(tool.Type, spec.claudeTypePrefix) {
+			continue
+		}
+		st := &ServerTool{Kind: kind}
+		if kind == ServerToolWebSearch {
+			st.MaxUses = tool.MaxUses
+			st.AllowedDomains = tool.AllowedDomains
+			st.BlockedDomains = tool.BlockedDomains
+			st.UserLocation = tool.UserLocation
+		}
+		return checkServerTool(st, tool.Type, target)
+	}
+	return nil, nil
+}
+
+// ServerToolFromClaudeJSON 解析 gjson 形式的 Claude 工具定义
+func ServerToolFromClaudeJSON(tool gjson.Result, target string) (*ServerTool, error) {
+	var t types.ClaudeTool
+	if err := json.Unmarshal([]byte(tool.Raw), &t); err != nil {
+		return nil, nil
+	}
+	return ServerToolFromClaude(t, target)
+}
+
+// ServerToolFromResponses 解析 Responses 工具定义
+// 函数工具及客户端执行的内置工具返回 (nil, nil)
+func ServerToolFromResponses(tool map[string]interface{}, target string) (*ServerTool, error) {
+	toolType, _ := tool["type"].(string)
+	for _, hosted := range responsesHostedToolTypes {
+		if toolType == hosted {
+			return nil, &UnsupportedServerToolError{Tool: toolType, Target: target}
+		}
+	}
+	for kind, spec := range serverToolRegistry {
+		if !containsString(spec.responsesTypes, toolType) {
+			continue
+		}
+		st := &ServerTool{Kind: kind}
+		if kind == ServerToolWebSearch {
+			if filters, ok := tool["filters"].(map[string]interface{}); ok {
+				st.AllowedDomains = toStringSlice(filters["allowed_domains"])
+			}
+			st.UserLocation, _ = tool["user_location"].(map[string]interface{})
+			st.SearchContextSize, _ = tool["search_context_size"].(string)
+		}
+		return checkServerTool(st, toolType, target)
+	}
+	return nil, nil
+}
+
+// serverToolsFromResponsesTools 提取 Responses 请求 tools 中的服务端工具
+func serverToolsFromResponsesTools(tools []interface{}, target string) ([]*ServerTool, error) {
+	var out []*ServerTool
+	for _, raw := range tools {
+		tool, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		st, err := ServerToolFromResponses(tool, target)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+// ServerToolsFromGemini 解析 Gemini 工具定义中的服务端工具
+// 无法识别的 Gemini 内置工具（如 urlContext、googleMaps）均视为不支持
+func ServerToolsFromGemini(tool types.GeminiTool, target string) ([]*ServerTool, error) {
+	var tools []*ServerTool
+	add := func(present bool, kind ServerToolKind, field string) error {
+		if !present {
+			return nil
+		}
+		st, err := checkServerTool(&ServerTool{Kind: kind}, field, target)
+		if err != nil {
+			return err
+		}
+		tools = append(tools, st)
+		return nil
+	}
+	if err := add(tool.GoogleSearch != nil || tool.GoogleSearchRetrieval != nil, ServerToolWebSearch, "googleSearch"); err != nil {
+		return nil, err
+	}
+	if err := add(tool.CodeExecution != nil, ServerToolCodeExecution, "codeExecution"); err != nil {
+		return nil, err
+	}
+	for field := range tool.Others {
+		return nil, &UnsupportedServerToolError{Tool: field, Target: target}
+	}
+	return tools, nil
+}
+
+// ServerToolFromChat 解析 Chat 请求的 web_search_options，未设置时返回 nil
+func ServerToolFromChat(webSearchOptions gjson.Result) *ServerTool {
+	if !webSearchOptions.IsObject() {
+		return nil
+	}
+	st := &ServerTool{Kind: ServerToolWebSearch, SearchContextSize: webSearchOptions.Get("search_context_size").String()}
+	if approx := webSearchOptions.Get("user_location.approximate"); approx.IsObject() {
+		loc := map[string]interface{}{"type": "approximate"}
+		approx.ForEach(func(k, v gjson.Result) bool {
+			loc[k.String()] = v.Value()
+			return true
+		})
+		st.UserLocation = loc
+	}
+	return st
+}
+
+// ClaudeTool 生成 Claude 服务端工具定义
+func (t *ServerTool) ClaudeTool() map[string]interface{} {
+	spec := serverToolRegistry[t.Kind]
+	tool := map[string]interface{}{"type": spec.claudeType, "name": spec.claudeName}
+	if t.MaxUses > 0 {
+		tool["max_uses"] = t.MaxUses
+	}
+	// Claude 不允许同时设置 allowed_domains 与 blocked_domains
+	if len(t.AllowedDomains) > 0 {
+		tool["allowed_domains"] = t.AllowedDomains
+	} else if len(t.BlockedDomains) > 0 {
+		tool["blocked_domains"] = t.BlockedDomains
+	}
+	if len(t.UserLocation) > 0 {
+		tool["user_location"] = t.UserLocation
+	}
+	return tool
+}
+
+// ResponsesTool 生成 Responses 工具定义
+func (t *ServerTool) ResponsesTool() map[string]interface{} {
+	spec := serverToolRegistry[t.Kind]
+	tool := map[string]interface{}{"type": spec.responsesTypes[0]}
+	switch t.Kind {
+	case ServerToolCodeExecution:
+		tool["container"] = map[string]interface{}{"type": "auto"}
+	case ServerToolWebSearch:
+		if len(t.AllowedDomains) > 0 {
+			tool["filters"] = map[string]interface{}{"allowed_domains": t.AllowedDomains}
+		}
+		if len(t.UserLocation) > 0 {
+			tool["user_location"] = t.UserLocation
+		}
+		if t.SearchContextSize != "" {
+			tool["search_context_size"] = t.SearchContextSize
+		}
+	}
+	return tool
+}
+
+// GeminiTool 生成 Gemini 工具定义（Gemini 内置工具不支持额外选项）
+func (t *ServerTool) GeminiTool() types.GeminiTool {
+	if t.Kind == ServerToolCodeExecution {
+		return types.GeminiTool{CodeExecution: json.RawMessage(`{}`)}
+	}
+	return types.GeminiTool{GoogleSearch: json.RawMessage(`{}`)}
+}
+
+// ChatWebSearchOptions 生成 Chat 请求的 web_search_options
+func (t *ServerTool) ChatWebSearchOptions() map[string]interface{} {
+	opts := map[string]interface{}{}
+	if t.SearchContextSize != "" {
+		opts["search_context_size"] = t.SearchContextSize
+	}
+	if len(t.UserLocation) > 0 {
+		approx := map[string]interface{}{}
+		for k, v := range t.UserLocation {
+			if k != "type" {
+				approx[k] = v
+			}
+		}
+		opts["user_location"] = map[string]interface{}{"type": "approximate", "approximate": approx}
+	}
+	return opts
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// toStringSlice 将 []interface{} 转换为 []string（忽略非字符串元素）
+func toStringSlice(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
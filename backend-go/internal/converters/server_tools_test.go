@@ -0,0 +1,291 @@
+package converters
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+func TestServerToolFromClaude(t *testing.T) {
+	tool := types.ClaudeTool{Type: "web_search_20250305", Name: "web_search", MaxUses: 3, AllowedDomains: []string{"go.dev"}}
+	st, err := ServerToolFromClaude(tool, ToolProtocolGemini)
+	if err != nil || st == nil || st.Kind != ServerToolWebSearch || st.MaxUses != 3 {
+		t.Fatalf("unexpected result: %+v, %v", st, err)
+	}
+
+	// 函数工具与客户端内置工具不属于服务端工具
+	for _, tool := range []types.ClaudeTool{{Name: "get_weather"}, {Type: "custom", Name: "x"}, {Type: "bash_20250124", Name: "bash"}} {
+		if st, err := ServerToolFromClaude(tool, ToolProtocolChat); st != nil || err != nil {
+			t.Fatalf("tool %+v should be ignored, got %+v, %v", tool, st, err)
+		}
+	}
+
+	cases := []struct {
+		tool   types.ClaudeTool
+		target string
+	}{
+		{types.ClaudeTool{Type: "code_execution_20250522", Name: "code_execution"}, ToolProtocolChat},
+		{types.ClaudeTool{Type: "web_fetch_20250910", Name: "web_fetch"}, ToolProtocolResponses},
+		{types.ClaudeTool{Type: "web_search_20250305", Name: "web_search"}, ToolProtocolCompletions},
+	}
+	for _, c := range cases {
+		_, err := ServerToolFromClaude(c.tool, c.target)
+		var unsupported *UnsupportedServerToolError
+		if !errors.As(err, &unsupported) || unsupported.Tool != c.tool.Type || unsupported.Target != c.target {
+			t.Fatalf("%s -> %s: expected UnsupportedServerToolError, got %v", c.tool.Type, c.target, err)
+		}
+	}
+}
+
+func TestServerToolFromResponses(t *testing.T) {
+	st, err := ServerToolFromResponses(mustMap(t, `{"type":"web_search","search_context_size":"high","filters":{"allowed_domains":["go.dev"]},"user_location":{"type":"approximate","country":"US"}}`), ToolProtocolClaude)
+	if err != nil || st == nil {
+		t.Fatalf("unexpected result: %+v, %v", st, err)
+	}
+	claudeTool, _ := JSONMarshal(st.ClaudeTool())
+	if gjson.GetBytes(claudeTool, "type").String() != "web_search_20250305" ||
+		gjson.GetBytes(claudeTool, "allowed_domains.0").String() != "go.dev" ||
+		gjson.GetBytes(claudeTool, "user_location.country").String() != "US" {
+		t.Fatalf("unexpected Claude tool: %s", claudeTool)
+	}
+	chatOptions, _ := JSONMarshal(st.ChatWebSearchOptions())
+	if gjson.GetBytes(chatOptions, "search_context_size").String() != "high" ||
+		gjson.GetBytes(chatOptions, "user_location.approximate.country").String() != "US" {
+		t.Fatalf("unexpected web_search_options: %s", chatOptions)
+	}
+
+	if st, err := ServerToolFromResponses(mustMap(t, `{"type":"function","name":"f"}`), ToolProtocolClaude); st != nil || err != nil {
+		t.Fatalf("function tool should be ignored, got %+v, %v", st, err)
+	}
+	if _, err := ServerToolFromResponses(mustMap(t, `{"type":"file_search","vector_store_ids":["vs"]}`), ToolProtocolClaude); err == nil {
+		t.Fatal("expected file_search to be rejected")
+	}
+	if _, err := ServerToolFromResponses(mustMap(t, `{"type":"code_interpreter","container":{"type":"auto"}}`), ToolProtocolChat); err == nil {
+		t.Fatal("expected code_interpreter to be rejected for Chat upstream")
+	}
+}
+
+func TestServerToolsFromGemini(t *testing.T) {
+	var tool types.GeminiTool
+	if err := tool.UnmarshalJSON([]byte(`{"googleSearch":{},"codeExecution":{}}`)); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	tools, err := ServerToolsFromGemini(tool, ToolProtocolClaude)
+	if err != nil || len(tools) != 2 {
+		t.Fatalf("unexpected result: %+v, %v", tools, err)
+	}
+
+	var urlContext types.GeminiTool
+	if err := urlContext.UnmarshalJSON([]byte(`{"urlContext":{}}`)); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	if _, err := ServerToolsFromGemini(urlContext, ToolProtocolClaude); err == nil || !strings.Contains(err.Error(), "urlContext") {
+		t.Fatalf("expected urlContext to be rejected, got %v", err)
+	}
+}
+
+func TestServerToolRequestConversion(t *testing.T) {
+	sess := &session.Session{}
+	responsesReq := &types.ResponsesRequest{
+		Model: "m",
+		Input: "what's new in go?",
+		Tools: []interface{}{
+			mustMap(t, `{"type":"function","name":"lookup","parameters":{"type":"object"}}`),
+			mustMap(t, `{"type":"web_search_preview"}`),
+		},
+	}
+
+	t.Run("responses to claude", func(t *testing.T) {
+		req, err := (&ClaudeConverter{}).ToProviderRequest(sess, responsesReq)
+		if err != nil {
+			t.Fatalf("ClaudeConverter: %v", err)
+		}
+		out, _ := JSONMarshal(req)
+		if gjson.GetBytes(out, `tools.#(type=="web_search_20250305").name`).String() != "web_search" {
+			t.Fatalf("unexpected Claude request: %s", out)
+		}
+	})
+
+	t.Run("responses to gemini", func(t *testing.T) {
+		req, err := (&GeminiConverter{}).ToProviderRequest(sess, responsesReq)
+		if err != nil {
+			t.Fatalf("GeminiConverter: %v", err)
+		}
+		out, _ := JSONMarshal(req)
+		if !gjson.GetBytes(out, "tools.#.googleSearch").IsArray() || !strings.Contains(string(out), `"googleSearch":{}`) {
+			t.Fatalf("unexpected Gemini request: %s", out)
+		}
+	})
+
+	t.Run("responses to completions rejected", func(t *testing.T) {
+		_, err := (&OpenAICompletionsConverter{}).ToProviderRequest(sess, responsesReq)
+		var unsupported *UnsupportedServerToolError
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("expected UnsupportedServerToolError, got %v", err)
+		}
+	})
+
+	t.Run("gemini to claude", func(t *testing.T) {
+		var search types.GeminiTool
+		_ = search.UnmarshalJSON([]byte(`{"googleSearch":{}}`))
+		req, err := GeminiToClaudeRequest(&types.GeminiRequest{
+			Contents: []types.GeminiContent{{Role: "user", Parts: []types.GeminiPart{{Text: "hi"}}}},
+			Tools:    []types.GeminiTool{search},
+		}, "claude")
+		if err != nil {
+			t.Fatalf("GeminiToClaudeRequest: %v", err)
+		}
+		out, _ := JSONMarshal(req)
+		if gjson.GetBytes(out, "tools.0.type").String() != "web_search_20250305" || gjson.GetBytes(out, "tools.0.name").String() != "web_search" {
+			t.Fatalf("unexpected Claude request: %s", out)
+		}
+	})
+
+	t.Run("chat web_search_options to claude", func(t *testing.T) {
+		out := ConvertOpenAIChatToClaudeRequest([]byte(`{"model":"x","messages":[{"role":"user","content":"hi"}],"web_search_options":{"user_location":{"type":"approximate","approximate":{"city":"Paris"}}}}`))
+		if gjson.GetBytes(out, "tools.0.type").String() != "web_search_20250305" || gjson.GetBytes(out, "tools.0.user_location.city").String() != "Paris" {
+			t.Fatalf("unexpected Claude request: %s", out)
+		}
+	})
+
+	t.Run("claude to responses", func(t *testing.T) {
+		_, err := ConvertClaudeToResponsesRequest([]byte(`{"model":"x","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"web_fetch_20250910","name":"web_fetch"}]}`), "gpt")
+		if err == nil {
+			t.Fatal("expected web_fetch to be rejected")
+		}
+	})
+}
+
+func TestWebSearchFromGeminiMultibyte(t *testing.T) {
+	text := "你好。Go 1.25 已发布。"
+	cited := "Go 1.25 已发布。"
+	start := strings.Index(text, cited)
+	gm := &types.GeminiGroundingMetadata{
+		WebSearchQueries: []string{"go 1.25"},
+		GroundingChunks:  []types.GeminiGroundingChunk{{Web: &types.GeminiGroundingWeb{URI: "https://go.dev/blog", Title: "go.dev"}}},
+		GroundingSupports: []types.GeminiGroundingSupport{{
+			Segment:               types.GeminiSegment{StartIndex: start, EndIndex: len(text), Text: cited},
+			GroundingChunkIndices: []int{0},
+		}},
+	}
+
+	calls, citations := WebSearchFromGemini(gm, text, "srvtoolu_test")
+	if len(calls) != 1 || calls[0].Query != "go 1.25" || len(calls[0].Sources) != 1 {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if len(citations) != 1 || citations[0].Start != 3 || citations[0].End != len([]rune(text)) {
+		t.Fatalf("unexpected citations: %+v", citations)
+	}
+
+	content := ApplyWebCitations([]types.ClaudeContent{{Type: "text", Text: text}}, citations)
+	if len(content) != 2 || content[0].Text != "你好。" || content[1].Text != cited || len(content[1].Citations) != 1 || len(content[0].Citations) != 0 {
+		t.Fatalf("unexpected content: %+v", content)
+	}
+
+	// 往返：引用区间还原为相同的字节偏移
+	back := GeminiGrounding(calls, citations, text)
+	if back == nil || len(back.GroundingSupports) != 1 || back.GroundingSupports[0].Segment.StartIndex != start || back.GroundingSupports[0].Segment.EndIndex != len(text) {
+		t.Fatalf("unexpected grounding: %+v", back)
+	}
+}
+
+const testClaudeSearchResponse = `{
+	"id":"msg_1","type":"message","role":"assistant","model":"claude",
+	"content":[
+		{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"go release"}},
+		{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://go.dev/doc","title":"Go Docs","encrypted_content":"x"}]},
+		{"type":"text","text":"Latest: "},
+		{"type":"text","text":"Go 1.25","citations":[{"type":"web_search_result_location","url":"https://go.dev/doc","title":"Go Docs","cited_text":"Go 1.25 is released","encrypted_index":"y"}]}
+	],
+	"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":5}
+}`
+
+func TestServerToolResponseConversion(t *testing.T) {
+	t.Run("claude to gemini", func(t *testing.T) {
+		resp, err := ClaudeResponseToGemini(mustMap(t, testClaudeSearchResponse))
+		if err != nil {
+			t.Fatalf("ClaudeResponseToGemini: %v", err)
+		}
+		gm := resp.Candidates[0].GroundingMetadata
+		if gm == nil || len(gm.WebSearchQueries) != 1 || gm.WebSearchQueries[0] != "go release" || len(gm.GroundingSupports) != 1 {
+			t.Fatalf("unexpected grounding: %+v", gm)
+		}
+		if seg := gm.GroundingSupports[0].Segment; seg.StartIndex != len("Latest: ") || seg.EndIndex != len("Latest: Go 1.25") {
+			t.Fatalf("unexpected segment: %+v", seg)
+		}
+	})
+
+	t.Run("claude to responses", func(t *testing.T) {
+		resp, err := ClaudeResponseToResponses(mustMap(t, testClaudeSearchResponse), "")
+		if err != nil {
+			t.Fatalf("ClaudeResponseToResponses: %v", err)
+		}
+		out, _ := JSONMarshal(resp)
+		if gjson.GetBytes(out, `output.#(type=="web_search_call").action.query`).String() != "go release" {
+			t.Fatalf("missing web_search_call: %s", out)
+		}
+		annotation := gjson.GetBytes(out, `output.#(type=="message").content.0.annotations.0`)
+		if annotation.Get("type").String() != "url_citation" || annotation.Get("start_index").Int() != 0 || annotation.Get("end_index").Int() != 7 {
+			t.Fatalf("unexpected annotation: %s", out)
+		}
+	})
+
+	t.Run("claude to chat", func(t *testing.T) {
+		out := ConvertClaudeToOpenAIChatNonStream("claude", []byte(testClaudeSearchResponse))
+		if gjson.GetBytes(out, "choices.0.message.content").String() != "Latest: Go 1.25" ||
+			gjson.GetBytes(out, "choices.0.message.annotations.0.url_citation.url").String() != "https://go.dev/doc" {
+			t.Fatalf("unexpected Chat response: %s", out)
+		}
+	})
+
+	t.Run("responses to claude", func(t *testing.T) {
+		resp, err := ResponsesToClaudeResponse([]byte(`{
+			"id":"resp_1","status":"completed","output":[
+				{"type":"web_search_call","id":"ws_1","status":"completed","action":{"type":"search","query":"go release","sources":[{"type":"url","url":"https://go.dev/doc"}]}},
+				{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Latest: Go 1.25","annotations":[{"type":"url_citation","url":"https://go.dev/doc","title":"Go Docs","start_index":8,"end_index":15}]}]}
+			]}`))
+		if err != nil {
+			t.Fatalf("ResponsesToClaudeResponse: %v", err)
+		}
+		out, _ := JSONMarshal(resp)
+		if gjson.GetBytes(out, "content.0.type").String() != "server_tool_use" || gjson.GetBytes(out, "content.1.type").String() != "web_search_tool_result" {
+			t.Fatalf("missing server tool blocks: %s", out)
+		}
+		cited := gjson.GetBytes(out, `content.#(citations).text`)
+		if cited.String() != "Go 1.25" {
+			t.Fatalf("unexpected cited block: %s", out)
+		}
+	})
+}
+
+func TestClaudeServerToolStream(t *testing.T) {
+	events := []string{
+		`{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"code_execution","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"code\":\"print(1)\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"code_execution_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"code_execution_result","stdout":"1\n","stderr":"","return_code":0}}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"结果是 1"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://example.com","cited_text":"1"}}}`,
+		`{"type":"content_block_stop","index":2}`,
+	}
+
+	var stream ClaudeServerToolStream
+	var completed []*ServerToolCall
+	for _, e := range events {
+		if call := stream.Observe(gjson.Parse(e)); call != nil {
+			completed = append(completed, call)
+		}
+	}
+	if len(completed) != 1 || completed[0].Code != "print(1)" || completed[0].Output != "1\n" || completed[0].Failed {
+		t.Fatalf("unexpected completed calls: %+v", completed)
+	}
+	if len(stream.Citations) != 1 || stream.Citations[0].Start != 0 || stream.Citations[0].End != 5 {
+		t.Fatalf("unexpected citations: %+v", stream.Citations)
+	}
+}
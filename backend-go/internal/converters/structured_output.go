@@ -0,0 +1,361 @@
+package converters
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== 结构化输出跨协议映射 ==============
+//
+// 各协议的表示方式：
+//   - Responses: {"text":{"format":{"type":"json_schema","name":"x","schema":{...},"strict":true}}}，或 {"type":"json_object"}
+//   - Chat:      {"response_format":{"type":"json_schema","json_schema":{"name":"x","schema":{...},"strict":true}}}，或 {"type":"json_object"}
+//   - Gemini:    {"generationConfig":{"responseMimeType":"application/json","responseSchema":{...}}}（或 responseJsonSchema）
+//   - Claude:    {"output_format":{"type":"json_schema","schema":{...}}}
+//
+// Claude 上游不保证支持 output_format，转换为 Claude 请求时使用强制工具调用模拟：
+// 注入名为 StructuredOutputToolName 的工具（input_schema 即目标 schema）并强制调用，
+// 响应转换时再将该工具调用的 input 还原为文本输出。
+
+// StructuredOutputToolName 模拟结构化输出时注入的 Claude 工具名
+const StructuredOutputToolName = "structured_output"
+
+// defaultStructuredOutputName 未指定 schema 名称时使用的默认名称
+const defaultStructuredOutputName = "response"
+
+// StructuredOutput 协议无关的结构化输出约束
+type StructuredOutput struct {
+	Name        string
+	Description string
+	Schema      map[string]interface{} // nil 表示 json_object（任意 JSON 对象）
+	Strict      bool
+}
+
+// StructuredOutputFromResponses 解析 Responses 请求的 text 字段（text.format）
+func StructuredOutputFromResponses(text interface{}) *StructuredOutput {
+	textMap, _ := text.(map[string]interface{})
+	format, _ := textMap["format"].(map[string]interface{})
+	return structuredOutputFromFormat(format)
+}
+
+// StructuredOutputFromChat 解析 Chat 请求的 response_format 字段
+func StructuredOutputFromChat(responseFormat interface{}) *StructuredOutput {
+	format, _ := responseFormat.(map[string]interface{})
+	if t, _ := format["type"].(string); t == "json_schema" {
+		if inner, ok := format["json_schema"].(map[string]interface{}); ok {
+			flat := map[string]interface{}{"type": "json_schema"}
+			for k, v := range inner {
+				flat[k] = v
+			}
+			format = flat
+		}
+	}
+	return structuredOutputFromFormat(format)
+}
+
+// StructuredOutputFromClaude 解析 Claude 请求的 output_format 字段
+func StructuredOutputFromClaude(outputFormat interface{}) *StructuredOutput {
+	format, _ := outputFormat.(map[string]interface{})
+	return structuredOutputFromFormat(format)
+}
+
+// structuredOutputFromFormat 解析扁平格式 {"type":"json_schema","name":...,"schema":...}
+// type 为 text 或无法识别时返回 nil
+func structuredOutputFromFormat(format map[string]interface{}) *StructuredOutput {
+	switch t, _ := format["type"].(string); t {
+	case "json_object":
+		return &StructuredOutput{Name: defaultStructuredOutputName}
+	case "json_schema":
+		schema, ok := format["schema"].(map[string]interface{})
+		if !ok {
+			return &StructuredOutput{Name: defaultStructuredOutputName}
+		}
+		so := &StructuredOutput{Name: defaultStructuredOutputName, Schema: schema}
+		if name, _ := format["name"].(string); name != "" {
+			so.Name = name
+		}
+		so.Description, _ = format["description"].(string)
+		so.Strict, _ = format["strict"].(bool)
+		return so
+	default:
+		return nil
+	}
+}
+
+// StructuredOutputFromGemini 解析 Gemini generationConfig 中的 responseMimeType / responseSchema
+// 仅 responseMimeType 为 application/json 时生效；responseJsonSchema 优先于 responseSchema
+func StructuredOutputFromGemini(cfg *types.GeminiGenerationConfig) *StructuredOutput {
+	if cfg == nil || !strings.EqualFold(cfg.ResponseMimeType, "application/json") {
+		return nil
+	}
+	so := &StructuredOutput{Name: defaultStructuredOutputName}
+	if schema, ok := cfg.ResponseJsonSchema.(map[string]interface{}); ok {
+		so.Schema = schema
+	} else if schema, ok := geminiSchemaToJSONSchema(cfg.ResponseSchema).(map[string]interface{}); ok {
+		so.Schema = schema
+	}
+	return so
+}
+
+// geminiSchemaToJSONSchema 将 Gemini OpenAPI 子集 schema 转换为 JSON Schema
+// type 统一为小写，nullable 转换为 type 数组，去掉 propertyOrdering 等 Gemini 专有字段
+func geminiSchemaToJSONSchema(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, val := range vv {
+			switch k {
+			case "propertyOrdering", "nullable":
+				continue
+			case "type":
+				if s, ok := val.(string); ok {
+					out[k] = strings.ToLower(s)
+					continue
+				}
+				out[k] = val
+			default:
+				out[k] = geminiSchemaToJSONSchema(val)
+			}
+		}
+		if nullable, _ := vv["nullable"].(bool); nullable {
+			if t, ok := out["type"].(string); ok {
+				out["type"] = []interface{}{t, "null"}
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i := range vv {
+			out[i] = geminiSchemaToJSONSchema(vv[i])
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// jsonObjectSchema json_object 模式下使用的宽松 schema
+func (so *StructuredOutput) jsonObjectSchema() map[string]interface{} {
+	if so.Schema != nil {
+		return so.Schema
+	}
+	return map[string]interface{}{"type": "object"}
+}
+
+// ResponsesTextFormat 转换为 Responses text.format
+func (so *StructuredOutput) ResponsesTextFormat() map[string]interface{} {
+	if so.Schema == nil {
+		return map[string]interface{}{"type": "json_object"}
+	}
+	format := map[string]interface{}{
+		"type":   "json_schema",
+		"name":   so.Name,
+		"schema": so.Schema,
+	}
+	if so.Description != "" {
+		format["description"] = so.Description
+	}
+	if so.Strict {
+		format["strict"] = true
+	}
+	return format
+}
+
+// ChatResponseFormat 转换为 Chat response_format
+func (so *StructuredOutput) ChatResponseFormat() map[string]interface{} {
+	if so.Schema == nil {
+		return map[string]interface{}{"type": "json_object"}
+	}
+	jsonSchema := map[string]interface{}{
+		"name":   so.Name,
+		"schema": so.Schema,
+	}
+	if so.Description != "" {
+		jsonSchema["description"] = so.Description
+	}
+	if so.Strict {
+		jsonSchema["strict"] = true
+	}
+	return map[string]interface{}{"type": "json_schema", "json_schema": jsonSchema}
+}
+
+// GeminiResponseSchema 转换为 Gemini responseSchema（复用工具参数的清洗逻辑，json_object 模式返回 nil）
+func (so *StructuredOutput) GeminiResponseSchema() interface{} {
+	if so.Schema == nil {
+		return nil
+	}
+	return sanitizeGeminiParameters(so.Schema)
+}
+
+// ApplyToGeminiConfig 写入 Gemini generationConfig
+func (so *StructuredOutput) ApplyToGeminiConfig(cfg *types.GeminiGenerationConfig) {
+	cfg.ResponseMimeType = "application/json"
+	cfg.ResponseSchema = so.GeminiResponseSchema()
+}
+
+// ClaudeTool 构建模拟结构化输出的 Claude 工具定义
+func (so *StructuredOutput) ClaudeTool() map[string]interface{} {
+	description := "Respond to the user by calling this tool exactly once. The tool input is the final answer and must conform to the input schema."
+	if so.Description != "" {
+		description += "\n\n" + so.Description
+	}
+	return map[string]interface{}{
+		"name":         StructuredOutputToolName,
+		"description":  description,
+		"input_schema": so.jsonObjectSchema(),
+	}
+}
+
+// ClaudeToolChoice 构建强制调用的 tool_choice
+// 请求中已有其他工具时使用 any（允许模型先调用业务工具），否则强制调用结构化输出工具
+func (so *StructuredOutput) ClaudeToolChoice(hasOtherTools bool) map[string]interface{} {
+	if hasOtherTools {
+		return map[string]interface{}{"type": "any"}
+	}
+	return map[string]interface{}{"type": "tool", "name": StructuredOutputToolName}
+}
+
+// applyClaudeStructuredOutput 在 map 形式的 Claude 请求上注入结构化输出工具
+// 强制工具调用与 extended thinking 不兼容，模拟时关闭 thinking
+func applyClaudeStructuredOutput(claudeReq map[string]interface{}, so *StructuredOutput) {
+	if so == nil {
+		return
+	}
+	hasOtherTools := false
+	switch tools := claudeReq["tools"].(type) {
+	case []map[string]interface{}:
+		hasOtherTools = len(tools) > 0
+		claudeReq["tools"] = append(tools, so.ClaudeTool())
+	case []interface{}:
+		hasOtherTools = len(tools) > 0
+		claudeReq["tools"] = append(tools, so.ClaudeTool())
+	default:
+		claudeReq["tools"] = []map[string]interface{}{so.ClaudeTool()}
+	}
+	claudeReq["tool_choice"] = so.ClaudeToolChoice(hasOtherTools)
+	delete(claudeReq, "thinking")
+}
+
+// ============== Claude 响应还原 ==============
+
+// structuredOutputText 将工具 input 序列化为文本输出
+func structuredOutputText(input interface{}) string {
+	if input == nil {
+		return "{}"
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(input); err != nil {
+		return "{}"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// UnwrapClaudeStructuredOutput 将 Claude 非流式响应（map）中的结构化输出工具调用还原为 text 块
+// 仅剩该工具调用时 stop_reason 由 tool_use 改为 end_turn
+func UnwrapClaudeStructuredOutput(claudeResp map[string]interface{}) {
+	content, ok := claudeResp["content"].([]interface{})
+	if !ok {
+		return
+	}
+	unwrapped := false
+	otherToolUse := false
+	for i, raw := range content {
+		block, ok := raw.(map[string]interface{})
+		if !ok || block["type"] != "tool_use" {
+			continue
+		}
+		if name, _ := block["name"].(string); name != StructuredOutputToolName {
+			otherToolUse = true
+			continue
+		}
+		content[i] = map[string]interface{}{"type": "text", "text": structuredOutputText(block["input"])}
+		unwrapped = true
+	}
+	if unwrapped && !otherToolUse && claudeResp["stop_reason"] == "tool_use" {
+		claudeResp["stop_reason"] = "end_turn"
+	}
+}
+
+// UnwrapClaudeStructuredOutputJSON 同 UnwrapClaudeStructuredOutput，作用于 JSON 字节
+func UnwrapClaudeStructuredOutputJSON(rawJSON []byte) []byte {
+	if !bytes.Contains(rawJSON, []byte(StructuredOutputToolName)) {
+		return rawJSON
+	}
+	out := rawJSON
+	unwrapped := false
+	otherToolUse := false
+	gjson.GetBytes(rawJSON, "content").ForEach(func(key, block gjson.Result) bool {
+		if block.Get("type").String() != "tool_use" {
+			return true
+		}
+		if block.Get("name").String() != StructuredOutputToolName {
+			otherToolUse = true
+			return true
+		}
+		// 直接使用原始 JSON 文本，保留模型输出的字段顺序
+		text := "{}"
+		if input := block.Get("input"); input.Exists() {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, []byte(input.Raw)); err == nil {
+				text = buf.String()
+			}
+		}
+		out, _ = sjson.SetBytes(out, "content."+key.String(), map[string]interface{}{"type": "text", "text": text})
+		unwrapped = true
+		return true
+	})
+	if unwrapped && !otherToolUse && gjson.GetBytes(out, "stop_reason").String() == "tool_use" {
+		out, _ = sjson.SetBytes(out, "stop_reason", "end_turn")
+	}
+	return out
+}
+
+// StructuredOutputStream Claude SSE 流中结构化输出工具调用的还原状态
+// tool_use 块改写为 text 块，input_json_delta 改写为 text_delta
+type StructuredOutputStream struct {
+	blocks       map[int64]bool
+	otherToolUse bool
+}
+
+// RewriteClaudeEvent 改写单个 Claude SSE 事件的 data（无需改写时原样返回）
+func (s *StructuredOutputStream) RewriteClaudeEvent(data []byte) []byte {
+	root := gjson.ParseBytes(data)
+	switch root.Get("type").String() {
+	case "content_block_start":
+		block := root.Get("content_block")
+		if block.Get("type").String() != "tool_use" {
+			return data
+		}
+		if block.Get("name").String() != StructuredOutputToolName {
+			s.otherToolUse = true
+			return data
+		}
+		if s.blocks == nil {
+			s.blocks = make(map[int64]bool)
+		}
+		s.blocks[root.Get("index").Int()] = true
+		out, _ := sjson.SetRawBytes(data, "content_block", []byte(`{"type":"text","text":""}`))
+		return out
+	case "content_block_delta":
+		if !s.blocks[root.Get("index").Int()] || root.Get("delta.type").String() != "input_json_delta" {
+			return data
+		}
+		delta, _ := sjson.Set(`{"type":"text_delta"}`, "text", root.Get("delta.partial_json").String())
+		out, _ := sjson.SetRawBytes(data, "delta", []byte(delta))
+		return out
+	case "message_delta":
+		if len(s.blocks) == 0 || s.otherToolUse || root.Get("delta.stop_reason").String() != "tool_use" {
+			return data
+		}
+		out, _ := sjson.SetBytes(data, "delta.stop_reason", "end_turn")
+		return out
+	}
+	return data
+}
@@ -0,0 +1,274 @@
+package converters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+const testPersonSchema = `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"}},"required":["name","age"],"additionalProperties":false}`
+
+func mustMap(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	m, err := JSONToMap([]byte(raw))
+	if err != nil {
+		t.Fatalf("JSONToMap: %v", err)
+	}
+	return m
+}
+
+func TestStructuredOutput_ParseAndEmit(t *testing.T) {
+	fromChat := StructuredOutputFromChat(gjson.Parse(`{"type":"json_schema","json_schema":{"name":"person","strict":true,"schema":` + testPersonSchema + `}}`).Value())
+	fromResponses := StructuredOutputFromResponses(gjson.Parse(`{"format":{"type":"json_schema","name":"person","strict":true,"schema":` + testPersonSchema + `}}`).Value())
+	fromClaude := StructuredOutputFromClaude(gjson.Parse(`{"type":"json_schema","schema":` + testPersonSchema + `}`).Value())
+
+	for name, so := range map[string]*StructuredOutput{"chat": fromChat, "responses": fromResponses, "claude": fromClaude} {
+		if so == nil || so.Schema == nil || so.Schema["type"] != "object" {
+			t.Fatalf("%s: expected parsed schema, got %+v", name, so)
+		}
+	}
+	if fromChat.Name != "person" || !fromChat.Strict || fromClaude.Name != defaultStructuredOutputName {
+		t.Fatalf("unexpected name/strict: chat=%+v claude=%+v", fromChat, fromClaude)
+	}
+
+	format, _ := JSONMarshal(fromChat.ResponsesTextFormat())
+	if gjson.GetBytes(format, "type").String() != "json_schema" || gjson.GetBytes(format, "name").String() != "person" ||
+		!gjson.GetBytes(format, "strict").Bool() || gjson.GetBytes(format, "schema.required.#").Int() != 2 {
+		t.Fatalf("unexpected text.format: %s", format)
+	}
+	responseFormat, _ := JSONMarshal(fromResponses.ChatResponseFormat())
+	if gjson.GetBytes(responseFormat, "json_schema.name").String() != "person" || !gjson.GetBytes(responseFormat, "json_schema.strict").Bool() {
+		t.Fatalf("unexpected response_format: %s", responseFormat)
+	}
+
+	// json_object 与 text
+	if so := StructuredOutputFromChat(map[string]interface{}{"type": "json_object"}); so == nil || so.Schema != nil || so.ChatResponseFormat()["type"] != "json_object" {
+		t.Fatalf("json_object should map to schemaless structured output, got %+v", so)
+	}
+	if so := StructuredOutputFromResponses(map[string]interface{}{"format": map[string]interface{}{"type": "text"}}); so != nil {
+		t.Fatalf("text format should not enable structured output")
+	}
+	if so := StructuredOutputFromChat(nil); so != nil {
+		t.Fatalf("missing response_format should return nil")
+	}
+}
+
+func TestStructuredOutputFromGemini(t *testing.T) {
+	if so := StructuredOutputFromGemini(&types.GeminiGenerationConfig{ResponseSchema: map[string]interface{}{"type": "OBJECT"}}); so != nil {
+		t.Fatalf("responseSchema without application/json must be ignored")
+	}
+
+	cfg := &types.GeminiGenerationConfig{
+		ResponseMimeType: "application/json",
+		ResponseSchema:   gjson.Parse(`{"type":"OBJECT","properties":{"name":{"type":"STRING","nullable":true},"tags":{"type":"ARRAY","items":{"type":"STRING"}}},"propertyOrdering":["name","tags"]}`).Value(),
+	}
+	so := StructuredOutputFromGemini(cfg)
+	schema, _ := JSONMarshal(so.Schema)
+	if gjson.GetBytes(schema, "type").String() != "object" || gjson.GetBytes(schema, "properties.tags.items.type").String() != "string" {
+		t.Fatalf("types should be lowercased: %s", schema)
+	}
+	if gjson.GetBytes(schema, "properties.name.type").Raw != `["string","null"]` || gjson.GetBytes(schema, "propertyOrdering").Exists() {
+		t.Fatalf("nullable/propertyOrdering not converted: %s", schema)
+	}
+
+	// responseJsonSchema 优先，且原样保留
+	cfg.ResponseJsonSchema = gjson.Parse(testPersonSchema).Value()
+	if so := StructuredOutputFromGemini(cfg); so.Schema["additionalProperties"] != false {
+		t.Fatalf("responseJsonSchema should take precedence: %+v", so.Schema)
+	}
+
+	// 转回 Gemini 时清洗不兼容字段
+	out := &types.GeminiGenerationConfig{}
+	StructuredOutputFromGemini(cfg).ApplyToGeminiConfig(out)
+	outJSON, _ := JSONMarshal(out)
+	if gjson.GetBytes(outJSON, "responseMimeType").String() != "application/json" || gjson.GetBytes(outJSON, "responseSchema.additionalProperties").Exists() ||
+		gjson.GetBytes(outJSON, "responseSchema.required.#").Int() != 2 {
+		t.Fatalf("unexpected gemini config: %s", outJSON)
+	}
+}
+
+func TestStructuredOutput_RequestConversions(t *testing.T) {
+	t.Run("chat to claude forces tool", func(t *testing.T) {
+		out := ConvertOpenAIChatToClaudeRequest([]byte(`{"model":"claude","reasoning_effort":"high","messages":[{"role":"user","content":"hi"}],
+			"response_format":{"type":"json_schema","json_schema":{"name":"person","schema":` + testPersonSchema + `}}}`))
+		root := gjson.ParseBytes(out)
+		if root.Get("tools.0.name").String() != StructuredOutputToolName || root.Get("tools.0.input_schema.required.#").Int() != 2 {
+			t.Fatalf("expected structured output tool: %s", out)
+		}
+		if root.Get("tool_choice.type").String() != "tool" || root.Get("tool_choice.name").String() != StructuredOutputToolName {
+			t.Fatalf("expected forced tool_choice: %s", out)
+		}
+		if root.Get("thinking").Exists() {
+			t.Fatalf("thinking must be disabled when forcing a tool: %s", out)
+		}
+	})
+
+	t.Run("chat to claude with other tools uses any", func(t *testing.T) {
+		out := ConvertOpenAIChatToClaudeRequest([]byte(`{"model":"claude","messages":[{"role":"user","content":"hi"}],
+			"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}}}],
+			"response_format":{"type":"json_object"}}`))
+		root := gjson.ParseBytes(out)
+		if root.Get("tools.#").Int() != 2 || root.Get("tools.1.input_schema.type").String() != "object" || root.Get("tool_choice.type").String() != "any" {
+			t.Fatalf("unexpected tools/tool_choice: %s", out)
+		}
+	})
+
+	t.Run("chat to responses", func(t *testing.T) {
+		out := ConvertOpenAIChatToResponsesRequest([]byte(`{"model":"gpt","messages":[{"role":"user","content":"hi"}],
+			"response_format":{"type":"json_schema","json_schema":{"name":"person","strict":true,"schema":` + testPersonSchema + `}}}`))
+		if gjson.GetBytes(out, "text.format.name").String() != "person" || !gjson.GetBytes(out, "text.format.strict").Bool() {
+			t.Fatalf("unexpected text.format: %s", out)
+		}
+	})
+
+	t.Run("responses to chat", func(t *testing.T) {
+		out := ConvertResponsesToOpenAIChatRequest("gpt", []byte(`{"input":"hi","text":{"format":{"type":"json_schema","name":"person","schema":`+testPersonSchema+`}}}`), false)
+		if gjson.GetBytes(out, "response_format.type").String() != "json_schema" || gjson.GetBytes(out, "response_format.json_schema.name").String() != "person" {
+			t.Fatalf("unexpected response_format: %s", out)
+		}
+	})
+
+	t.Run("claude to responses", func(t *testing.T) {
+		out, err := ConvertClaudeToResponsesRequest([]byte(`{"model":"x","messages":[{"role":"user","content":"hi"}],"output_format":{"type":"json_schema","schema":`+testPersonSchema+`}}`), "gpt")
+		if err != nil {
+			t.Fatalf("ConvertClaudeToResponsesRequest: %v", err)
+		}
+		if gjson.GetBytes(out, "text.format.type").String() != "json_schema" || gjson.GetBytes(out, "text.format.schema.required.#").Int() != 2 {
+			t.Fatalf("unexpected text.format: %s", out)
+		}
+	})
+
+	textFormat := map[string]interface{}{"format": mustMap(t, `{"type":"json_schema","name":"person","schema":`+testPersonSchema+`}`)}
+	responsesReq := &types.ResponsesRequest{Model: "m", Input: "hi", Text: textFormat}
+
+	t.Run("responses converters", func(t *testing.T) {
+		sess := &session.Session{}
+
+		claudeReq, err := (&ClaudeConverter{}).ToProviderRequest(sess, responsesReq)
+		if err != nil {
+			t.Fatalf("ClaudeConverter: %v", err)
+		}
+		claudeJSON, _ := JSONMarshal(claudeReq)
+		if gjson.GetBytes(claudeJSON, "tool_choice.name").String() != StructuredOutputToolName || gjson.GetBytes(claudeJSON, "tools.0.name").String() != StructuredOutputToolName {
+			t.Fatalf("unexpected Claude request: %s", claudeJSON)
+		}
+
+		chatReq, err := (&OpenAIChatConverter{}).ToProviderRequest(sess, responsesReq)
+		if err != nil {
+			t.Fatalf("OpenAIChatConverter: %v", err)
+		}
+		chatJSON, _ := JSONMarshal(chatReq)
+		if gjson.GetBytes(chatJSON, "response_format.json_schema.name").String() != "person" {
+			t.Fatalf("unexpected Chat request: %s", chatJSON)
+		}
+
+		geminiReq, err := (&GeminiConverter{}).ToProviderRequest(sess, responsesReq)
+		if err != nil {
+			t.Fatalf("GeminiConverter: %v", err)
+		}
+		geminiJSON, _ := JSONMarshal(geminiReq)
+		if gjson.GetBytes(geminiJSON, "generationConfig.responseMimeType").String() != "application/json" ||
+			gjson.GetBytes(geminiJSON, "generationConfig.responseSchema.properties.name.type").String() != "string" {
+			t.Fatalf("unexpected Gemini request: %s", geminiJSON)
+		}
+	})
+
+	geminiReq := &types.GeminiRequest{
+		Contents: []types.GeminiContent{{Role: "user", Parts: []types.GeminiPart{{Text: "hi"}}}},
+		GenerationConfig: &types.GeminiGenerationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   gjson.Parse(`{"type":"OBJECT","properties":{"name":{"type":"STRING"}}}`).Value(),
+		},
+	}
+
+	t.Run("gemini to claude", func(t *testing.T) {
+		claudeReq, err := GeminiToClaudeRequest(geminiReq, "claude")
+		if err != nil {
+			t.Fatalf("GeminiToClaudeRequest: %v", err)
+		}
+		out, _ := JSONMarshal(claudeReq)
+		if gjson.GetBytes(out, "tools.0.input_schema.properties.name.type").String() != "string" || gjson.GetBytes(out, "tool_choice.type").String() != "tool" {
+			t.Fatalf("unexpected Claude request: %s", out)
+		}
+	})
+
+	t.Run("gemini to openai", func(t *testing.T) {
+		openaiReq, err := GeminiToOpenAIRequest(geminiReq, "gpt")
+		if err != nil {
+			t.Fatalf("GeminiToOpenAIRequest: %v", err)
+		}
+		out, _ := JSONMarshal(openaiReq)
+		if gjson.GetBytes(out, "response_format.json_schema.schema.type").String() != "object" {
+			t.Fatalf("unexpected OpenAI request: %s", out)
+		}
+	})
+}
+
+const testStructuredClaudeResponse = `{"id":"msg_1","model":"claude","stop_reason":"tool_use","content":[
+	{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{"name":"Ada","age":36}}
+],"usage":{"input_tokens":10,"output_tokens":5}}`
+
+func TestStructuredOutput_UnwrapNonStream(t *testing.T) {
+	chat := ConvertClaudeToOpenAIChatNonStream("claude", []byte(testStructuredClaudeResponse))
+	if gjson.GetBytes(chat, "choices.0.message.content").String() != `{"name":"Ada","age":36}` ||
+		gjson.GetBytes(chat, "choices.0.message.tool_calls").Exists() || gjson.GetBytes(chat, "choices.0.finish_reason").String() != "stop" {
+		t.Fatalf("unexpected chat completion: %s", chat)
+	}
+
+	// map 形式的响应已丢失字段顺序，按规范化编码比较
+	resp, err := ClaudeResponseToResponses(mustMap(t, testStructuredClaudeResponse), "")
+	if err != nil || len(resp.Output) != 1 || resp.Output[0].Content != `{"age":36,"name":"Ada"}` {
+		t.Fatalf("unexpected responses output: %+v err=%v", resp, err)
+	}
+
+	gemini, err := ClaudeResponseToGemini(mustMap(t, testStructuredClaudeResponse))
+	if err != nil || gemini.Candidates[0].Content.Parts[0].Text != `{"age":36,"name":"Ada"}` || gemini.Candidates[0].FinishReason != "STOP" {
+		t.Fatalf("unexpected gemini response: %+v err=%v", gemini, err)
+	}
+
+	// 与业务工具同时出现时保留 tool_use 停止原因
+	mixed := mustMap(t, `{"stop_reason":"tool_use","content":[
+		{"type":"tool_use","id":"a","name":"lookup","input":{}},
+		{"type":"tool_use","id":"b","name":"structured_output","input":{"ok":true}}]}`)
+	UnwrapClaudeStructuredOutput(mixed)
+	if mixed["stop_reason"] != "tool_use" || mixed["content"].([]interface{})[1].(map[string]interface{})["type"] != "text" {
+		t.Fatalf("unexpected mixed unwrap: %+v", mixed)
+	}
+}
+
+func TestStructuredOutput_UnwrapStream(t *testing.T) {
+	lines := []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10,"output_tokens":0}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{}}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"name\":"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Ada\"}"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+		`data: {"type":"message_stop"}`,
+	}
+
+	var state any
+	var content strings.Builder
+	finish := ""
+	for _, line := range lines {
+		for _, chunk := range ConvertClaudeToOpenAIChat("claude", []byte(line), &state) {
+			payload := strings.TrimSpace(strings.TrimPrefix(chunk, "data: "))
+			if payload == "[DONE]" {
+				continue
+			}
+			if gjson.Get(payload, "choices.0.delta.tool_calls").Exists() {
+				t.Fatalf("structured output must not surface as tool call: %s", payload)
+			}
+			content.WriteString(gjson.Get(payload, "choices.0.delta.content").String())
+			if r := gjson.Get(payload, "choices.0.finish_reason").String(); r != "" {
+				finish = r
+			}
+		}
+	}
+	if content.String() != `{"name":"Ada"}` || finish != "stop" {
+		t.Fatalf("content=%q finish=%q", content.String(), finish)
+	}
+}
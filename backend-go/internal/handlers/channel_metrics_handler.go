@@ -81,6 +81,7 @@ func GetChannelMetricsWithConfig(metricsManager *metrics.MetricsManager, cfgMana
 				"latency":             resp.Latency,
 				"keyMetrics":          resp.KeyMetrics,  // 各 Key 的详细指标
 				"timeWindows":         resp.TimeWindows, // 分时段统计 (15m, 1h, 6h, 24h)
+				"ttfb":                resp.TTFB,        // 首字节时间统计
 			}
 
 			if resp.LastSuccessAt != nil {
@@ -615,16 +616,22 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
 				"modelMapping":       up.ModelMapping,
+				"supportedModels":    up.SupportedModels,
 				"latency":            nil,
 				"status":             status,
 				"priority":           priority,
+				"weight":             up.Weight,
+				"priceMultiplier":    up.PriceMultiplier,
+				"maxConcurrency":     up.MaxConcurrency,
 				"promotionUntil":     up.PromotionUntil,
 				"lowQuality":         up.LowQuality,
+				"apiVersion":         up.APIVersion,
 			}
 		}
 
 		// 2. 构建 metrics 数据
 		metricsResult := make([]gin.H, 0, len(upstreams))
+		var estimatedSavingsCents int64
 		for i, upstream := range upstreams {
 			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0)
 
@@ -662,6 +669,9 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 				}
 			}
 
+			savings := metrics.EstimateCostSavings(resp.TimeWindows, upstream.GetPriceMultiplier())
+			estimatedSavingsCents += savings.SavingsCents
+
 			item := gin.H{
 				"channelIndex":        i,
 				"channelName":         upstream.Name,
@@ -673,7 +683,9 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 				"consecutiveFailures": resp.ConsecutiveFailures,
 				"latency":             resp.Latency,
 				"keyMetrics":          resp.KeyMetrics,
+				"costSavings":         savings,
 				"timeWindows":         resp.TimeWindows,
+				"ttfb":                resp.TTFB,
 			}
 
 			if resp.LastSuccessAt != nil {
@@ -698,6 +710,9 @@ func GetChannelDashboard(cfgManager *config.ConfigManager, sch *scheduler.Channe
 			"failureThreshold":    metricsManager.GetFailureThreshold() * 100,
 			"windowSize":          metricsManager.GetWindowSize(),
 			"circuitRecoveryTime": metricsManager.GetCircuitRecoveryTime().String(),
+			// 按渠道价格倍率估算的节省金额（相对官方价格）
+			"estimatedSavingsCents": estimatedSavingsCents,
+			"savingsWindow":         metrics.SavingsWindow,
 		}
 
 		// 4. 构建 recentActivity 数据（最近 15 分钟分段活跃度）
@@ -954,6 +969,7 @@ func GetGeminiChannelMetrics(metricsManager *metrics.MetricsManager, cfgManager
 				"latency":             resp.Latency,
 				"keyMetrics":          resp.KeyMetrics,  // 各 Key 的详细指标
 				"timeWindows":         resp.TimeWindows, // 分时段统计 (15m, 1h, 6h, 24h)
+				"ttfb":                resp.TTFB,        // 首字节时间统计
 			}
 
 			if resp.LastSuccessAt != nil {
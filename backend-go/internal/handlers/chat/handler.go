@@ -0,0 +1,100 @@
+// Package chat 提供 OpenAI Chat Completions 入口（/v1/chat/completions）
+//
+// 请求被转换为 Claude Messages 或 Responses 格式后，交由对应渠道池的处理器完成调度、
+// 故障转移、指标与请求日志记录，响应再转换回 Chat Completions 格式。
+package chat
+
+import (
+	"log"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	poolMessages  = "messages"
+	poolResponses = "responses"
+)
+
+type Handler struct {
+	envCfg           *config.EnvConfig
+	channelScheduler *scheduler.ChannelScheduler
+	messagesHandler  gin.HandlerFunc
+	responsesHandler gin.HandlerFunc
+}
+
+// NewHandler 创建 Chat Completions 处理器
+// messagesHandler/responsesHandler 为对应渠道池的代理处理器（复用其调度与故障转移逻辑）
+func NewHandler(
+	envCfg *config.EnvConfig,
+	channelScheduler *scheduler.ChannelScheduler,
+	messagesHandler gin.HandlerFunc,
+	responsesHandler gin.HandlerFunc,
+) gin.HandlerFunc {
+	h := &Handler{
+		envCfg:           envCfg,
+		channelScheduler: channelScheduler,
+		messagesHandler:  messagesHandler,
+		responsesHandler: responsesHandler,
+	}
+	return h.Handle
+}
+
+// Handle Chat Completions 代理处理器
+// 优先使用 Messages 渠道池；Messages 池无可用渠道时使用 Responses 渠道池
+func (h *Handler) Handle(c *gin.Context) {
+	bodyBytes, err := common.ReadRequestBody(c, h.envCfg.MaxRequestBodySize)
+	if err != nil {
+		return
+	}
+	if !gjson.ValidBytes(bodyBytes) {
+		c.JSON(400, gin.H{"error": gin.H{"message": "Invalid JSON", "type": "invalid_request_error"}})
+		return
+	}
+
+	root := gjson.ParseBytes(bodyBytes)
+	model := root.Get("model").String()
+	stream := root.Get("stream").Bool()
+	pool := h.selectPool()
+
+	// 改写为目标渠道池的请求（Claude 透传 Provider 依据请求路径拼接上游端点）
+	var convertedBody []byte
+	var next gin.HandlerFunc
+	if pool == poolResponses {
+		convertedBody = converters.ConvertOpenAIChatToResponsesRequest(bodyBytes)
+		c.Request.URL.Path = "/v1/responses"
+		next = h.responsesHandler
+	} else {
+		convertedBody = converters.ConvertOpenAIChatToClaudeRequest(bodyBytes)
+		c.Request.URL.Path = "/v1/messages"
+		next = h.messagesHandler
+	}
+
+	if h.envCfg.EnableRequestLogs && h.envCfg.ShouldLog("debug") {
+		log.Printf("[Chat-Convert] Chat Completions 请求转换至 %s 渠道池, model=%s, stream=%v", pool, model, stream)
+	}
+
+	common.RestoreRequestBody(c, convertedBody)
+	c.Request.ContentLength = int64(len(convertedBody))
+
+	w := newChatResponseWriter(c.Writer, pool, model, stream)
+	c.Writer = w
+
+	next(c)
+	w.finish()
+}
+
+// selectPool 选择承载 Chat Completions 请求的渠道池
+func (h *Handler) selectPool() string {
+	if h.channelScheduler == nil {
+		return poolMessages
+	}
+	if h.channelScheduler.GetActiveChannelCount(false) == 0 && h.channelScheduler.GetActiveChannelCount(true) > 0 {
+		return poolResponses
+	}
+	return poolMessages
+}
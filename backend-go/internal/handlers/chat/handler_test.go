@@ -0,0 +1,245 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/messages"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/responses"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/warmup"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func createTestConfigManager(t *testing.T, cfg config.Config) (*config.ConfigManager, func()) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.json")
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal config: %v", err)
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("NewConfigManager: %v", err)
+	}
+	return cfgManager, func() { cfgManager.Close() }
+}
+
+func createTestScheduler(t *testing.T, cfgManager *config.ConfigManager) (*scheduler.ChannelScheduler, func()) {
+	t.Helper()
+
+	messagesMetrics := metrics.NewMetricsManager()
+	responsesMetrics := metrics.NewMetricsManager()
+	geminiMetrics := metrics.NewMetricsManager()
+	traceAffinity := session.NewTraceAffinityManager()
+	urlManager := warmup.NewURLManager(30*time.Second, 3)
+
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
+	return sch, func() {
+		messagesMetrics.Stop()
+		responsesMetrics.Stop()
+		geminiMetrics.Stop()
+		traceAffinity.Stop()
+	}
+}
+
+type testEnv struct {
+	router      *gin.Engine
+	requestLogs *metrics.MemoryRequestLogStore
+	envCfg      *config.EnvConfig
+}
+
+func setupChatRouter(t *testing.T, cfg config.Config) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	t.Cleanup(cleanupCfg)
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	t.Cleanup(cleanupSch)
+
+	envCfg := &config.EnvConfig{
+		ProxyAccessKey:     "secret",
+		MaxRequestBodySize: 1024 * 1024,
+	}
+	requestLogs := metrics.NewMemoryRequestLogStore(20)
+	sessionManager := session.NewSessionManager(time.Hour, 100, 100000)
+
+	messagesHandler := messages.NewHandler(envCfg, cfgManager, sch, nil, nil, nil, nil, requestLogs)
+	responsesHandler := responses.NewHandler(envCfg, cfgManager, sessionManager, sch, nil, nil, nil, nil, requestLogs)
+
+	r := gin.New()
+	r.POST("/v1/chat/completions", NewHandler(envCfg, sch, messagesHandler, responsesHandler))
+	return &testEnv{router: r, requestLogs: requestLogs, envCfg: envCfg}
+}
+
+func (e *testEnv) post(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+e.envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestChatHandler_MessagesPool_NonStream(t *testing.T) {
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	env := setupChatRouter(t, config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "claude", BaseURL: upstream.URL, APIKeys: []string{"k1"}, ServiceType: "claude", Status: "active"},
+		},
+	})
+
+	w := env.post(t, `{"model":"claude-3","messages":[{"role":"system","content":"sys"},{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if got := gjson.GetBytes(upstreamBody, "system").String(); got != "sys" {
+		t.Fatalf("upstream system = %q, body = %s", got, upstreamBody)
+	}
+	root := gjson.ParseBytes(w.Body.Bytes())
+	if root.Get("object").String() != "chat.completion" || root.Get("choices.0.message.content").String() != "hello" {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+	if root.Get("model").String() != "claude-3" || root.Get("usage.total_tokens").Int() != 5 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	logs, total, err := env.requestLogs.QueryRequestLogs("messages", 10, 0)
+	if err != nil || total != 1 || !logs[0].Success {
+		t.Fatalf("request logs total=%d err=%v logs=%+v", total, err, logs)
+	}
+}
+
+func TestChatHandler_MessagesPool_Stream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_2","usage":{"input_tokens":3,"output_tokens":0}}}`,
+			`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi there"}}`,
+			`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+			`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`event: message_stop` + "\n" + `data: {"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			_, _ = w.Write([]byte(e + "\n\n"))
+		}
+	}))
+	defer upstream.Close()
+
+	env := setupChatRouter(t, config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "claude", BaseURL: upstream.URL, APIKeys: []string{"k1"}, ServiceType: "claude", Status: "active"},
+		},
+	})
+
+	w := env.post(t, `{"model":"claude-3","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("content-type = %q", ct)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `"content":"hi there"`) || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("unexpected stream body: %s", body)
+	}
+	if strings.Contains(body, "event: ") {
+		t.Fatalf("claude events leaked into chat stream: %s", body)
+	}
+}
+
+func TestChatHandler_ResponsesPoolFallback(t *testing.T) {
+	var upstreamPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_1","object":"response","status":"completed","model":"gpt-5","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"pong"}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	env := setupChatRouter(t, config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{
+			{Name: "codex", BaseURL: upstream.URL, APIKeys: []string{"k1"}, ServiceType: "responses", Status: "active"},
+		},
+	})
+
+	w := env.post(t, `{"model":"gpt-5","messages":[{"role":"user","content":"ping"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if upstreamPath != "/v1/responses" {
+		t.Fatalf("upstream path = %q", upstreamPath)
+	}
+	if got := gjson.GetBytes(w.Body.Bytes(), "choices.0.message.content").String(); got != "pong" {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestChatHandler_ErrorsUseOpenAIFormat(t *testing.T) {
+	env := setupChatRouter(t, config.Config{})
+
+	t.Run("invalid json", func(t *testing.T) {
+		w := env.post(t, `{`)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d", w.Code)
+		}
+		if gjson.GetBytes(w.Body.Bytes(), "error.message").String() == "" {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("no channels", func(t *testing.T) {
+		w := env.post(t, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if gjson.GetBytes(w.Body.Bytes(), "error.message").String() == "" {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"m","messages":[]}`))
+		w := httptest.NewRecorder()
+		env.router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d", w.Code)
+		}
+		if gjson.GetBytes(w.Body.Bytes(), "error.message").String() != "Invalid proxy access key" {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+}
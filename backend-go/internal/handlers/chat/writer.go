@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// chatResponseWriter 将渠道池处理器输出（Claude/Responses 格式）转换为 Chat Completions 格式
+// - 流式成功响应：逐行转换 SSE 并即时转发
+// - 非流式响应与错误响应：缓冲完整响应体，finish 时统一转换
+type chatResponseWriter struct {
+	gin.ResponseWriter
+	pool   string
+	model  string
+	stream bool
+
+	status    int
+	body      bytes.Buffer
+	lineBuf   bytes.Buffer
+	streaming bool
+	state     any
+}
+
+func newChatResponseWriter(w gin.ResponseWriter, pool, model string, stream bool) *chatResponseWriter {
+	return &chatResponseWriter{
+		ResponseWriter: w,
+		pool:           pool,
+		model:          model,
+		stream:         stream,
+	}
+}
+
+func (w *chatResponseWriter) WriteHeader(code int) {
+	if w.streaming {
+		return
+	}
+	w.status = code
+}
+
+func (w *chatResponseWriter) WriteHeaderNow() {}
+
+func (w *chatResponseWriter) Status() int {
+	if w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *chatResponseWriter) Written() bool {
+	return w.status != 0 || w.streaming || w.body.Len() > 0
+}
+
+func (w *chatResponseWriter) Write(data []byte) (int, error) {
+	if !w.stream || !w.isSuccessStatus() {
+		return w.body.Write(data)
+	}
+	if !w.streaming {
+		w.startStream()
+	}
+	w.lineBuf.Write(data)
+	if err := w.processLines(false); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *chatResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *chatResponseWriter) Flush() {
+	if w.streaming {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *chatResponseWriter) isSuccessStatus() bool {
+	return w.status == 0 || (w.status >= 200 && w.status < 300)
+}
+
+func (w *chatResponseWriter) startStream() {
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "text/event-stream")
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(status)
+	w.streaming = true
+}
+
+// processLines 转换缓冲区内的完整 SSE 行；final 为 true 时同时处理末尾不完整行
+func (w *chatResponseWriter) processLines(final bool) error {
+	for {
+		data := w.lineBuf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			if !final || len(data) == 0 {
+				return nil
+			}
+			idx = len(data)
+		}
+		line := make([]byte, idx)
+		copy(line, data[:idx])
+		w.lineBuf.Next(min(idx+1, len(data)))
+
+		var chunks []string
+		if w.pool == poolResponses {
+			chunks = converters.ConvertResponsesToOpenAIChat(w.model, line, &w.state)
+		} else {
+			chunks = converters.ConvertClaudeToOpenAIChat(w.model, line, &w.state)
+		}
+		for _, chunk := range chunks {
+			if _, err := w.ResponseWriter.Write([]byte(chunk)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// finish 在渠道池处理器返回后输出剩余内容
+func (w *chatResponseWriter) finish() {
+	if w.streaming {
+		if err := w.processLines(true); err != nil {
+			return
+		}
+		for _, chunk := range converters.FinishOpenAIChatStream(w.model, &w.state) {
+			if _, err := w.ResponseWriter.Write([]byte(chunk)); err != nil {
+				return
+			}
+		}
+		w.ResponseWriter.Flush()
+		return
+	}
+
+	if w.status == 0 && w.body.Len() == 0 {
+		return
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	var out []byte
+	switch {
+	case w.body.Len() == 0:
+		out = []byte(converters.ConvertErrorToOpenAIChat([]byte(http.StatusText(status))))
+	case status >= 400 || !gjson.ValidBytes(w.body.Bytes()):
+		out = []byte(converters.ConvertErrorToOpenAIChat(w.body.Bytes()))
+	case w.pool == poolResponses:
+		out = converters.ConvertResponsesToOpenAIChatNonStream(w.model, w.body.Bytes())
+	default:
+		out = converters.ConvertClaudeToOpenAIChatNonStream(w.model, w.body.Bytes())
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(out)
+}
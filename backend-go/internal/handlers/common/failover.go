@@ -3,9 +3,11 @@
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,6 +15,35 @@
 type FailoverError struct {
 	Status int
 	Body   []byte
+	// ClientError 请求本身无法被渠道处理（如服务端工具不受支持），
+	// 所有渠道失败时原样返回给客户端，不受 Fuzzy 模式影响
+	ClientError bool
+}
+
+// ConversionFailoverError 识别请求转换阶段的客户端错误
+// 目标协议不支持请求中的服务端工具时返回 400 invalid_request_error；其余错误返回 nil，按渠道故障处理
+func ConversionFailoverError(err error) *FailoverError {
+	var unsupported *converters.UnsupportedServerToolError
+	if !errors.As(err, &unsupported) {
+		return nil
+	}
+	body, _ := json.Marshal(gin.H{
+		"type": "error",
+		"error": gin.H{
+			"type":    "invalid_request_error",
+			"message": unsupported.Error(),
+		},
+	})
+	return &FailoverError{Status: 400, Body: body, ClientError: true}
+}
+
+// KeepClientError 合并多渠道故障转移中的错误：一旦出现 ClientError 即保留，
+// 避免后续渠道的 5xx 覆盖后在 Fuzzy 模式下退化为通用 503
+func KeepClientError(last, next *FailoverError) *FailoverError {
+	if last != nil && last.ClientError {
+		return last
+	}
+	return next
 }
 
 // ShouldRetryWithNextKey 判断是否应该使用下一个密钥重试
@@ -362,8 +393,8 @@ func classifyErrorType(errType string) (bool, bool) {
 // lastError: 最后一个错误
 // apiType: API 类型（用于错误消息）
 func HandleAllChannelsFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *FailoverError, lastError error, apiType string) {
-	// Fuzzy 模式下返回通用错误，不透传上游详情
-	if fuzzyMode {
+	// Fuzzy 模式下返回通用错误，不透传上游详情（客户端请求错误除外）
+	if fuzzyMode && (lastFailoverError == nil || !lastFailoverError.ClientError) {
 		c.JSON(503, gin.H{
 			"type": "error",
 			"error": gin.H{
@@ -400,8 +431,8 @@ func HandleAllChannelsFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *
 
 // HandleAllKeysFailed 处理所有密钥都失败的情况（单渠道模式）
 func HandleAllKeysFailed(c *gin.Context, fuzzyMode bool, lastFailoverError *FailoverError, lastError error, apiType string) {
-	// Fuzzy 模式下返回通用错误
-	if fuzzyMode {
+	// Fuzzy 模式下返回通用错误（客户端请求错误除外）
+	if fuzzyMode && (lastFailoverError == nil || !lastFailoverError.ClientError) {
 		c.JSON(503, gin.H{
 			"type": "error",
 			"error": gin.H{
@@ -4,8 +4,10 @@
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/gin-gonic/gin"
 )
 
@@ -91,3 +93,39 @@ func TestHandleAllKeysFailed_CoversBranches(t *testing.T) {
 		}
 	})
 }
+
+func TestConversionFailoverError_ReturnsClientError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	if ConversionFailoverError(errors.New("boom")) != nil {
+		t.Fatalf("plain conversion error should not be a client error")
+	}
+
+	failoverErr := ConversionFailoverError(&converters.UnsupportedServerToolError{Tool: "code_interpreter", Target: converters.ToolProtocolChat})
+	if failoverErr == nil || failoverErr.Status != http.StatusBadRequest || !failoverErr.ClientError {
+		t.Fatalf("unexpected failover error: %+v", failoverErr)
+	}
+
+	// fuzzy 模式下客户端错误仍透传 400，而非通用 503
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	HandleAllChannelsFailed(c, true, failoverErr, nil, "Responses")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "code_interpreter") {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestKeepClientError_PreservesFirstClientError(t *testing.T) {
+	clientErr := &FailoverError{Status: http.StatusBadRequest, ClientError: true}
+	upstreamErr := &FailoverError{Status: http.StatusBadGateway}
+
+	if got := KeepClientError(nil, upstreamErr); got != upstreamErr {
+		t.Fatalf("KeepClientError(nil, 502) = %+v, want 502", got)
+	}
+	if got := KeepClientError(upstreamErr, clientErr); got != clientErr {
+		t.Fatalf("KeepClientError(502, 400) = %+v, want 400", got)
+	}
+	if got := KeepClientError(clientErr, upstreamErr); got != clientErr {
+		t.Fatalf("KeepClientError(400, 502) = %+v, want 400 kept", got)
+	}
+}
@@ -80,6 +80,31 @@ func SendRequest(req *http.Request, upstream *config.UpstreamConfig, envCfg *con
 	return client.Do(req)
 }
 
+// TrackFirstByte 包装上游响应体，在读到首个字节时以 start 为起点回调 TTFB（首字节时间）
+// 流式响应的首字节即首个 SSE 事件，比响应头到达时间更能反映用户感知的等待时长
+func TrackFirstByte(resp *http.Response, start time.Time, record func(ttfb time.Duration)) {
+	if resp == nil || resp.Body == nil || record == nil {
+		return
+	}
+	resp.Body = &firstByteReader{ReadCloser: resp.Body, start: start, record: record}
+}
+
+type firstByteReader struct {
+	io.ReadCloser
+	start  time.Time
+	record func(time.Duration)
+	seen   bool
+}
+
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && !r.seen {
+		r.seen = true
+		r.record(time.Since(r.start))
+	}
+	return n, err
+}
+
 // IsClientCanceled 判断错误是否由请求方取消导致（例如客户端断开/取消请求）。
 // 约定：该类取消视为正常，不应计入失败率/熔断。
 func IsClientCanceled(err error) bool {
@@ -162,6 +162,34 @@ func TestSendRequest_StandardAndStream(t *testing.T) {
 	})
 }
 
+func TestTrackFirstByte_RecordsOnce(t *testing.T) {
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader("data: hello\n\n"))}
+	start := time.Now().Add(-50 * time.Millisecond)
+
+	var calls int
+	var ttfb time.Duration
+	TrackFirstByte(resp, start, func(d time.Duration) {
+		calls++
+		ttfb = d
+	})
+	if calls != 0 {
+		t.Fatalf("should not record before reading body")
+	}
+
+	buf := make([]byte, 4)
+	for {
+		if _, err := resp.Body.Read(buf); err != nil {
+			break
+		}
+	}
+	if calls != 1 || ttfb < 50*time.Millisecond {
+		t.Fatalf("calls=%d ttfb=%v, want single record >= 50ms", calls, ttfb)
+	}
+
+	// nil 安全
+	TrackFirstByte(nil, start, func(time.Duration) {})
+}
+
 func TestLogOriginalRequest_CoversBranches(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -61,7 +61,7 @@ func createTestSchedulerForStream(t *testing.T) (*scheduler.ChannelScheduler, fu
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	sch := scheduler.NewChannelScheduler(nil, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(nil, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 	cleanup := func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
@@ -0,0 +1,124 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestEmbeddingsChannelsHandlers_CRUD(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "chat", BaseURL: "http://chat.invalid", APIKeys: []string{"c1"}, Status: "active"},
+		},
+	})
+	defer cleanupCfg()
+
+	sch, _, _, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	r := gin.New()
+	r.GET("/channels", GetUpstreams(cfgManager))
+	r.POST("/channels", AddUpstream(cfgManager))
+	r.PUT("/channels/:id", UpdateUpstream(cfgManager, sch))
+	r.DELETE("/channels/:id", DeleteUpstream(cfgManager))
+	r.POST("/channels/:id/keys", AddApiKey(cfgManager))
+	r.PATCH("/channels/:id/status", SetChannelStatus(cfgManager))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/channels", `{"name":"e0","baseUrl":"http://embed.invalid","apiKeys":["k1"],"serviceType":"openai"}`); w.Code != http.StatusOK {
+		t.Fatalf("add status = %d, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/channels/0/keys", `{"apiKey":"k2"}`); w.Code != http.StatusOK {
+		t.Fatalf("add key status = %d, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPatch, "/channels/0/status", `{"status":"suspended"}`); w.Code != http.StatusOK {
+		t.Fatalf("set status = %d, body=%s", w.Code, w.Body.String())
+	}
+
+	w := do(http.MethodGet, "/channels", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d", w.Code)
+	}
+	var resp struct {
+		Channels []struct {
+			Name    string   `json:"name"`
+			APIKeys []string `json:"apiKeys"`
+			Status  string   `json:"status"`
+		} `json:"channels"`
+		LoadBalance string `json:"loadBalance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp.Channels) != 1 || resp.Channels[0].Name != "e0" || len(resp.Channels[0].APIKeys) != 2 || resp.Channels[0].Status != "suspended" {
+		t.Fatalf("unexpected channels: %+v", resp.Channels)
+	}
+	if resp.LoadBalance != "failover" {
+		t.Fatalf("loadBalance = %q, want failover", resp.LoadBalance)
+	}
+
+	// Embeddings 渠道独立存储，不影响 Messages 渠道
+	cfg := cfgManager.GetConfig()
+	if len(cfg.Upstream) != 1 || cfg.Upstream[0].Name != "chat" {
+		t.Fatalf("messages upstreams changed: %+v", cfg.Upstream)
+	}
+
+	if w := do(http.MethodDelete, "/channels/0", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d", w.Code)
+	}
+	if len(cfgManager.GetConfig().EmbeddingsUpstream) != 0 {
+		t.Fatalf("expected embeddings upstream removed")
+	}
+}
+
+// TestEmbeddingsChannelsHandlers_InvalidSupportedModelsReturns400 无效的 supportedModels 模式属于配置错误，返回 400
+func TestEmbeddingsChannelsHandlers_InvalidSupportedModelsReturns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		EmbeddingsUpstream: []config.UpstreamConfig{
+			{Name: "c0", BaseURL: "http://example.invalid", APIKeys: []string{"k1"}, ServiceType: "openai", Status: "active"},
+		},
+	})
+	defer cleanupCfg()
+
+	sch, _, _, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	r := gin.New()
+	r.POST("/channels", AddUpstream(cfgManager))
+	r.PUT("/channels/:id", UpdateUpstream(cfgManager, sch))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/channels", `{"name":"c1","baseUrl":"http://example.invalid","apiKeys":["k2"],"serviceType":"openai","supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("add status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("update status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["gpt-*"]}`); w.Code != http.StatusOK {
+		t.Fatalf("valid update status = %d, body=%s", w.Code, w.Body.String())
+	}
+}
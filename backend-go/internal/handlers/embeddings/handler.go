@@ -0,0 +1,417 @@
+// Package embeddings 提供 OpenAI 兼容的 Embeddings API 处理器（/v1/embeddings）
+//
+// Embeddings 使用独立的渠道池（embeddingsUpstream）与指标管理器，
+// 高频向量请求的 Key 健康状态不会影响对话类渠道的熔断判断。
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/monitor"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const apiType = "embeddings"
+
+var versionSuffixPattern = regexp.MustCompile(`/v\d+[a-z]*$`)
+
+type requestLogContext struct {
+	requestID string
+	startTime time.Time
+
+	model string
+
+	channelIndex int
+	channelName  string
+	apiKey       string
+
+	queuedAt  *time.Time    // 排队等待槽位中（非 nil 时）的入队时间
+	queueWait time.Duration // 获得槽位前的排队时长
+
+	usage *types.Usage
+
+	success  bool
+	errorMsg string
+
+	liveRequestManager *monitor.LiveRequestManager
+}
+
+func (r *requestLogContext) updateLive() {
+	if r == nil || r.liveRequestManager == nil {
+		return
+	}
+	r.liveRequestManager.StartRequest(&monitor.LiveRequest{
+		RequestID:    r.requestID,
+		ChannelIndex: r.channelIndex,
+		ChannelName:  r.channelName,
+		KeyMask:      utils.MaskAPIKey(r.apiKey),
+		Model:        r.model,
+		StartTime:    r.startTime,
+		APIType:      apiType,
+		QueuedAt:     r.queuedAt,
+		QueueWaitMs:  r.queueWait.Milliseconds(),
+	})
+}
+
+// withQueueHook 为槽位选择附加排队回调：所有槽位并发已满时在实时请求中标记为排队中
+func (r *requestLogContext) withQueueHook(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return scheduler.WithQueueHook(ctx, func(queuedAt time.Time) {
+		r.queuedAt = &queuedAt
+		r.updateLive()
+	})
+}
+
+func truncateErrorMessage(msg string) string {
+	const maxLen = 1024
+	if len(msg) <= maxLen {
+		return msg
+	}
+	return msg[:maxLen] + "..."
+}
+
+type Handler struct {
+	envCfg           *config.EnvConfig
+	cfgManager       *config.ConfigManager
+	channelScheduler *scheduler.ChannelScheduler
+
+	liveRequestManager *monitor.LiveRequestManager
+	circuitLogStore    metrics.KeyCircuitLogStore
+	requestLogStore    metrics.RequestLogStore
+}
+
+// NewHandler 创建 Embeddings 处理器
+func NewHandler(
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	liveRequestManager *monitor.LiveRequestManager,
+	circuitLogStore metrics.KeyCircuitLogStore,
+	requestLogStore metrics.RequestLogStore,
+) gin.HandlerFunc {
+	h := &Handler{
+		envCfg:             envCfg,
+		cfgManager:         cfgManager,
+		channelScheduler:   channelScheduler,
+		liveRequestManager: liveRequestManager,
+		circuitLogStore:    circuitLogStore,
+		requestLogStore:    requestLogStore,
+	}
+	return h.Handle
+}
+
+// Handle Embeddings API 代理处理器
+// 所有 (渠道,key) 扁平化为槽位调度，失败时切换到下一个槽位
+func (h *Handler) Handle(c *gin.Context) {
+	middleware.ProxyAuthMiddleware(h.envCfg)(c)
+	if c.IsAborted() {
+		return
+	}
+
+	startTime := time.Now()
+	requestID := uuid.New().String()
+
+	reqSnapshot := common.CaptureRequestSnapshot(c, nil)
+	responseSnapshotWriter := common.CaptureResponseSnapshot(c)
+
+	reqCtx := &requestLogContext{
+		requestID:          requestID,
+		startTime:          startTime,
+		liveRequestManager: h.liveRequestManager,
+	}
+	if h.liveRequestManager != nil {
+		reqCtx.updateLive()
+		defer h.liveRequestManager.EndRequest(requestID)
+	}
+	defer func() {
+		responseSnapshot := common.ResponseSnapshot{}
+		if responseSnapshotWriter != nil {
+			responseSnapshot = responseSnapshotWriter.Snapshot()
+		}
+		h.addRequestLog(c, reqCtx, reqSnapshot, responseSnapshot)
+	}()
+
+	bodyBytes, err := common.ReadRequestBody(c, h.envCfg.MaxRequestBodySize)
+	if err != nil {
+		reqCtx.errorMsg = truncateErrorMessage(err.Error())
+		return
+	}
+	reqSnapshot = common.CaptureRequestSnapshot(c, bodyBytes)
+
+	if !gjson.ValidBytes(bodyBytes) {
+		reqCtx.errorMsg = "Invalid JSON"
+		c.JSON(400, gin.H{"error": gin.H{"message": "Invalid JSON", "type": "invalid_request_error"}})
+		return
+	}
+	model := gjson.GetBytes(bodyBytes, "model").String()
+	if model == "" {
+		reqCtx.errorMsg = "model 为空"
+		c.JSON(400, gin.H{"error": gin.H{"message": "model is required", "type": "invalid_request_error"}})
+		return
+	}
+	reqCtx.model = model
+	reqCtx.updateLive()
+
+	common.LogOriginalRequest(c, bodyBytes, h.envCfg, "Embeddings")
+
+	userID := common.ExtractConversationID(c, bodyBytes)
+	h.handleSlots(c, bodyBytes, model, userID, reqCtx)
+}
+
+// handleSlots 按槽位（渠道+Key）依次尝试，直到成功或全部失败
+func (h *Handler) handleSlots(c *gin.Context, bodyBytes []byte, model, userID string, reqCtx *requestLogContext) {
+	failedSlots := make(map[string]bool)
+	var lastError error
+	var lastFailoverError *common.FailoverError
+
+	maxSlotAttempts := h.channelScheduler.GetActiveEmbeddingsSlotCount()
+	globalModelMapping := h.cfgManager.GetGlobalModelMapping()
+
+	for slotAttempt := 0; slotAttempt < maxSlotAttempts; slotAttempt++ {
+		// 请求方已取消，停止重试（不计失败）
+		if c.Request.Context().Err() != nil {
+			return
+		}
+
+		selection, err := h.channelScheduler.SelectEmbeddingsSlot(reqCtx.withQueueHook(scheduler.WithRequestModel(c.Request.Context(), model)), userID, failedSlots)
+		if err != nil {
+			lastError = err
+			break
+		}
+
+		upstream := selection.Upstream
+		reqCtx.channelIndex = selection.ChannelIndex
+		reqCtx.channelName = upstream.Name
+		reqCtx.apiKey = selection.APIKey
+		reqCtx.queuedAt = nil
+		reqCtx.queueWait = selection.QueueWait
+		reqCtx.updateLive()
+
+		if h.envCfg.ShouldLog("info") {
+			log.Printf("[Embeddings-Select] 选择槽位: [%d] %s (原因: %s, 尝试 %d/%d)",
+				selection.ChannelIndex, upstream.Name, selection.Reason, slotAttempt+1, maxSlotAttempts)
+		}
+
+		mappedModel := config.RedirectModelWithGlobal(model, upstream, globalModelMapping)
+		// 本槽位尝试结束（含 panic）即归还并发额度
+		done, failoverErr := func() (bool, *common.FailoverError) {
+			defer selection.Release()
+			return h.trySlot(c, upstream, selection.ChannelIndex, selection.APIKey, bodyBytes, mappedModel, reqCtx)
+		}()
+		if done {
+			if reqCtx.success {
+				h.channelScheduler.SetTraceAffinitySlot(userID, selection.ChannelIndex, selection.KeyIndex)
+			}
+			return
+		}
+
+		failedSlots[fmt.Sprintf("%d:%s", selection.ChannelIndex, selection.APIKey)] = true
+		if failoverErr != nil {
+			lastFailoverError = failoverErr
+		}
+		lastError = fmt.Errorf("槽位 [%d] %s 失败", selection.ChannelIndex, upstream.Name)
+		log.Printf("[Embeddings-Failover] 警告: 槽位 [%d] %s key=%s 失败，尝试下一个槽位", selection.ChannelIndex, upstream.Name, utils.MaskAPIKey(selection.APIKey))
+	}
+
+	log.Printf("[Embeddings-Error] 所有渠道都失败了")
+	reqCtx.success = false
+	if lastFailoverError != nil {
+		reqCtx.errorMsg = truncateErrorMessage(string(lastFailoverError.Body))
+	} else if lastError != nil {
+		reqCtx.errorMsg = truncateErrorMessage(lastError.Error())
+	}
+	common.HandleAllChannelsFailed(c, h.cfgManager.GetFuzzyModeEnabled(), lastFailoverError, lastError, "Embeddings")
+}
+
+// trySlot 使用单个槽位请求上游（按 BaseURL 动态排序依次尝试）
+// 返回 done=true 表示已写出响应（成功、不可重试的上游错误或请求方取消）
+func (h *Handler) trySlot(
+	c *gin.Context,
+	upstream *config.UpstreamConfig,
+	channelIndex int,
+	apiKey string,
+	bodyBytes []byte,
+	mappedModel string,
+	reqCtx *requestLogContext,
+) (bool, *common.FailoverError) {
+	metricsManager := h.channelScheduler.GetEmbeddingsMetricsManager()
+	requestBody := bodyBytes
+	if mappedModel != reqCtx.model {
+		requestBody, _ = sjson.SetBytes(bodyBytes, "model", mappedModel)
+	}
+
+	var lastFailoverError *common.FailoverError
+	sortedURLResults := h.channelScheduler.GetSortedURLsForChannel(channelIndex, upstream.GetAllBaseURLs())
+	for _, urlResult := range sortedURLResults {
+		if c.Request.Context().Err() != nil {
+			return true, nil
+		}
+		currentBaseURL := urlResult.URL
+
+		req, err := http.NewRequestWithContext(c.Request.Context(), "POST", buildEmbeddingsURL(currentBaseURL, "/embeddings"), bytes.NewReader(requestBody))
+		if err != nil {
+			common.RecordFailureAndStoreLastFailureLog(h.circuitLogStore, metricsManager, apiType, currentBaseURL, apiKey, 0, nil, err, func() {
+				h.channelScheduler.RecordEmbeddingsFailure(currentBaseURL, apiKey)
+			})
+			continue
+		}
+		req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		common.SetUpstreamRequestSnapshot(c, req)
+
+		requestStart := time.Now()
+		resp, err := common.SendRequest(req, upstream, h.envCfg, false)
+		if err != nil {
+			// 请求方取消：视为正常，不计失败，不继续 failover
+			if common.IsClientCanceled(err) || c.Request.Context().Err() != nil {
+				return true, nil
+			}
+			h.cfgManager.MarkKeyAsFailed(apiKey)
+			common.RecordFailureAndStoreLastFailureLog(h.circuitLogStore, metricsManager, apiType, currentBaseURL, apiKey, 0, nil, err, func() {
+				h.channelScheduler.RecordEmbeddingsFailure(currentBaseURL, apiKey)
+			})
+			h.channelScheduler.MarkURLFailure(channelIndex, currentBaseURL)
+			log.Printf("[Embeddings-Key] 警告: API密钥失败: %v", err)
+			continue
+		}
+
+		// 记录 TTFB（首字节时间），仅成功响应计入 latency 负载均衡
+		var ttfb time.Duration
+		common.TrackFirstByte(resp, requestStart, func(d time.Duration) { ttfb = d })
+		respBodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		respBodyBytes = utils.DecompressGzipIfNeeded(resp, respBodyBytes)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			shouldFailover, isQuotaRelated := common.ShouldRetryWithNextKey(resp.StatusCode, respBodyBytes, h.cfgManager.GetFuzzyModeEnabled())
+			common.RecordFailureAndStoreLastFailureLog(h.circuitLogStore, metricsManager, apiType, currentBaseURL, apiKey, resp.StatusCode, respBodyBytes, fmt.Errorf("上游错误: %d", resp.StatusCode), func() {
+				h.channelScheduler.RecordEmbeddingsFailureWithStatus(currentBaseURL, apiKey, resp.StatusCode)
+			})
+			if !shouldFailover {
+				reqCtx.success = false
+				reqCtx.errorMsg = truncateErrorMessage(string(respBodyBytes))
+				c.Data(resp.StatusCode, "application/json", respBodyBytes)
+				return true, nil
+			}
+
+			// 余额不足：硬熔断到本地时区 0 点自动恢复
+			if isQuotaRelated && common.IsInsufficientBalanceResponse(respBodyBytes) {
+				metricsManager.SuspendKeyUntil(currentBaseURL, apiKey, utils.NextLocalMidnight(time.Now()), "insufficient_balance")
+			}
+			h.cfgManager.MarkKeyAsFailed(apiKey)
+			h.channelScheduler.MarkURLFailure(channelIndex, currentBaseURL)
+			log.Printf("[Embeddings-Key] 警告: API密钥失败 (状态: %d)", resp.StatusCode)
+			lastFailoverError = &common.FailoverError{Status: resp.StatusCode, Body: respBodyBytes}
+			continue
+		}
+
+		h.channelScheduler.MarkURLSuccess(channelIndex, currentBaseURL)
+		h.channelScheduler.RecordEmbeddingsLatency(currentBaseURL, apiKey, ttfb)
+
+		usage := extractEmbeddingsUsage(respBodyBytes)
+		h.channelScheduler.RecordEmbeddingsSuccessWithUsage(currentBaseURL, apiKey, usage, reqCtx.model, 0)
+		reqCtx.usage = usage
+		reqCtx.success = true
+		reqCtx.errorMsg = ""
+
+		c.Data(resp.StatusCode, "application/json", respBodyBytes)
+		return true, nil
+	}
+
+	return false, lastFailoverError
+}
+
+// extractEmbeddingsUsage 从 OpenAI embeddings 响应提取 usage（仅输入 token）
+func extractEmbeddingsUsage(bodyBytes []byte) *types.Usage {
+	promptTokens := gjson.GetBytes(bodyBytes, "usage.prompt_tokens")
+	if !promptTokens.Exists() {
+		return nil
+	}
+	return &types.Usage{InputTokens: int(promptTokens.Int())}
+}
+
+// buildEmbeddingsURL 拼接 OpenAI 兼容端点（版本号规则同 OpenAIProvider：已带 /vN 或以 # 结尾时不追加 /v1）
+func buildEmbeddingsURL(baseURL, endpoint string) string {
+	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
+	if skipVersionPrefix {
+		baseURL = strings.TrimSuffix(baseURL, "#")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !skipVersionPrefix && !versionSuffixPattern.MatchString(baseURL) {
+		endpoint = "/v1" + endpoint
+	}
+	return baseURL + endpoint
+}
+
+// addRequestLog 写入请求日志
+func (h *Handler) addRequestLog(c *gin.Context, reqCtx *requestLogContext, reqSnapshot common.RequestSnapshot, responseSnapshot common.ResponseSnapshot) {
+	if h.requestLogStore == nil {
+		return
+	}
+
+	statusCode := responseSnapshot.StatusCode
+	if statusCode <= 0 {
+		statusCode = c.Writer.Status()
+	}
+
+	errorMsg := reqCtx.errorMsg
+	if !reqCtx.success && errorMsg == "" && statusCode >= 400 {
+		errorMsg = fmt.Sprintf("http status %d", statusCode)
+	}
+
+	var usage types.Usage
+	if reqCtx.usage != nil {
+		usage = *reqCtx.usage
+	}
+	keyID := ""
+	if reqCtx.apiKey != "" {
+		keyID = metrics.HashAPIKey(reqCtx.apiKey)
+	}
+	finalSnapshot := common.ResolveRequestSnapshot(c, reqSnapshot)
+
+	if err := h.requestLogStore.AddRequestLog(metrics.RequestLogRecord{
+		RequestID:             reqCtx.requestID,
+		RequestMethod:         finalSnapshot.Method,
+		RequestURL:            finalSnapshot.URL,
+		RequestHeaders:        finalSnapshot.Headers,
+		RequestBody:           finalSnapshot.Body,
+		RequestBodyTruncated:  finalSnapshot.BodyTruncated,
+		ResponseBody:          responseSnapshot.Body,
+		ResponseBodyTruncated: responseSnapshot.BodyTruncated,
+		ChannelIndex:          reqCtx.channelIndex,
+		ChannelName:           reqCtx.channelName,
+		KeyMask:               utils.MaskAPIKey(reqCtx.apiKey),
+		KeyID:                 keyID,
+		Timestamp:             reqCtx.startTime,
+		DurationMs:            time.Since(reqCtx.startTime).Milliseconds(),
+		StatusCode:            statusCode,
+		Success:               reqCtx.success,
+		Model:                 reqCtx.model,
+		InputTokens:           int64(usage.InputTokens),
+		ErrorMessage:          truncateErrorMessage(errorMsg),
+		APIType:               apiType,
+	}); err != nil {
+		log.Printf("[Embeddings-RequestLog] 警告: AddRequestLog 失败: %v", err)
+	}
+}
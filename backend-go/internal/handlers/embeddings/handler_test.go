@@ -0,0 +1,223 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/warmup"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func createTestConfigManager(t *testing.T, cfg config.Config) (*config.ConfigManager, func()) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "config.json")
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal config: %v", err)
+	}
+	if err := os.WriteFile(configFile, data, 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfgManager, err := config.NewConfigManager(configFile)
+	if err != nil {
+		t.Fatalf("NewConfigManager: %v", err)
+	}
+	return cfgManager, func() { cfgManager.Close() }
+}
+
+func createTestScheduler(t *testing.T, cfgManager *config.ConfigManager) (*scheduler.ChannelScheduler, *metrics.MetricsManager, *metrics.MetricsManager, func()) {
+	t.Helper()
+
+	messagesMetrics := metrics.NewMetricsManager()
+	responsesMetrics := metrics.NewMetricsManager()
+	geminiMetrics := metrics.NewMetricsManager()
+	embeddingsMetrics := metrics.NewMetricsManager()
+	traceAffinity := session.NewTraceAffinityManager()
+	urlManager := warmup.NewURLManager(30*time.Second, 3)
+
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, embeddingsMetrics, traceAffinity, urlManager)
+	return sch, messagesMetrics, embeddingsMetrics, func() {
+		messagesMetrics.Stop()
+		responsesMetrics.Stop()
+		geminiMetrics.Stop()
+		embeddingsMetrics.Stop()
+		traceAffinity.Stop()
+	}
+}
+
+func newTestRouter(t *testing.T, cfg config.Config) (*gin.Engine, *metrics.MetricsManager, *metrics.MetricsManager, *metrics.MemoryRequestLogStore, func()) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	sch, messagesMetrics, embeddingsMetrics, cleanupSch := createTestScheduler(t, cfgManager)
+	logStore := metrics.NewMemoryRequestLogStore(20)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1/embeddings", NewHandler(envCfg, cfgManager, sch, nil, nil, logStore))
+	return r, messagesMetrics, embeddingsMetrics, logStore, func() {
+		cleanupSch()
+		cleanupCfg()
+	}
+}
+
+func doEmbeddingsRequest(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestEmbeddingsHandler_FailoverToNextKeyWithIsolatedMetrics(t *testing.T) {
+	var calls int32
+	var gotPath, gotModel string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") == "Bearer k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid api key","type":"authentication_error"}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotPath = r.URL.Path
+		gotModel = gjson.GetBytes(body, "model").String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],"model":"text-embedding-3-small","usage":{"prompt_tokens":5,"total_tokens":5}}`))
+	}))
+	defer upstream.Close()
+
+	r, messagesMetrics, embeddingsMetrics, logStore, cleanup := newTestRouter(t, config.Config{
+		EmbeddingsUpstream: []config.UpstreamConfig{{
+			Name:         "e0",
+			BaseURL:      upstream.URL,
+			APIKeys:      []string{"k1", "k2"},
+			ServiceType:  "openai",
+			Status:       "active",
+			ModelMapping: map[string]string{"embed-small": "text-embedding-3-small"},
+		}},
+	})
+	defer cleanup()
+
+	w := doEmbeddingsRequest(r, `{"model":"embed-small","input":"hello"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("upstream calls = %d, want 2", got)
+	}
+	if gotPath != "/v1/embeddings" {
+		t.Fatalf("path = %q, want /v1/embeddings", gotPath)
+	}
+	if gotModel != "text-embedding-3-small" {
+		t.Fatalf("model = %q, want mapped model", gotModel)
+	}
+	if got := gjson.Get(w.Body.String(), "data.0.embedding.1").Float(); got != 0.2 {
+		t.Fatalf("embedding passthrough mismatch: %s", w.Body.String())
+	}
+
+	if failures := embeddingsMetrics.GetKeyMetrics(upstream.URL, "k1"); failures == nil || failures.FailureCount != 1 {
+		t.Fatalf("expected 1 failure recorded on embeddings metrics for k1, got %+v", failures)
+	}
+	if success := embeddingsMetrics.GetKeyMetrics(upstream.URL, "k2"); success == nil || success.SuccessCount != 1 {
+		t.Fatalf("expected 1 success recorded on embeddings metrics for k2, got %+v", success)
+	}
+	if m := messagesMetrics.GetKeyMetrics(upstream.URL, "k1"); m != nil && m.RequestCount > 0 {
+		t.Fatalf("embeddings traffic must not touch messages metrics, got %+v", m)
+	}
+
+	logs, total, err := logStore.QueryRequestLogs("embeddings", 10, 0)
+	if err != nil || total != 1 {
+		t.Fatalf("request logs total = %d, err = %v", total, err)
+	}
+	if !logs[0].Success || logs[0].InputTokens != 5 {
+		t.Fatalf("unexpected request log: %+v", logs[0])
+	}
+}
+
+func TestEmbeddingsHandler_NonRetryableErrorPassthrough(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"input too long","type":"invalid_request_error"}}`))
+	}))
+	defer upstream.Close()
+
+	r, _, _, _, cleanup := newTestRouter(t, config.Config{
+		EmbeddingsUpstream: []config.UpstreamConfig{{
+			Name:    "e0",
+			BaseURL: upstream.URL,
+			APIKeys: []string{"k1", "k2"},
+			Status:  "active",
+		}},
+	})
+	defer cleanup()
+
+	w := doEmbeddingsRequest(r, `{"model":"text-embedding-3-small","input":"hello"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("upstream calls = %d, want 1", got)
+	}
+	if !strings.Contains(w.Body.String(), "input too long") {
+		t.Fatalf("expected upstream error body, got %s", w.Body.String())
+	}
+}
+
+func TestEmbeddingsHandler_NoUpstreamConfiguredReturns503(t *testing.T) {
+	r, _, _, _, cleanup := newTestRouter(t, config.Config{FuzzyModeEnabled: true})
+	defer cleanup()
+
+	w := doEmbeddingsRequest(r, `{"model":"text-embedding-3-small","input":"hello"}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestEmbeddingsHandler_MissingModelReturns400(t *testing.T) {
+	r, _, _, _, cleanup := newTestRouter(t, config.Config{})
+	defer cleanup()
+
+	w := doEmbeddingsRequest(r, `{"input":"hello"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestBuildEmbeddingsURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.openai.com", "https://api.openai.com/v1/embeddings"},
+		{"https://api.openai.com/", "https://api.openai.com/v1/embeddings"},
+		{"https://example.com/api/v3", "https://example.com/api/v3/embeddings"},
+		{"https://example.com/custom#", "https://example.com/custom/embeddings"},
+	}
+	for _, tt := range tests {
+		if got := buildEmbeddingsURL(tt.baseURL, "/embeddings"); got != tt.want {
+			t.Errorf("buildEmbeddingsURL(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
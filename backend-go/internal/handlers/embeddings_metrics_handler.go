@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// GetEmbeddingsChannelMetricsHistory 获取 Embeddings 渠道指标历史数据（用于时间序列图表）
+// Query params:
+//   - duration: 时间范围 (1h, 6h, 24h)，默认 24h
+//   - interval: 时间间隔 (5m, 15m, 1h)，默认根据 duration 自动选择
+func GetEmbeddingsChannelMetricsHistory(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 解析 duration 参数
+		durationStr := c.DefaultQuery("duration", "24h")
+
+		var duration time.Duration
+		var err error
+
+		// 特殊处理 "today" 参数
+		if durationStr == "today" {
+			duration = metrics.CalculateTodayDuration()
+			// 如果刚过零点，duration 可能非常小，设置最小值
+			if duration < time.Minute {
+				duration = time.Minute
+			}
+		} else {
+			duration, err = parseDurationParam(durationStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid duration parameter. Use: 1h, 6h, 24h, today, 7d, or 30d"})
+				return
+			}
+		}
+
+		// 解析或自动选择 interval
+		intervalStr := c.Query("interval")
+		var interval time.Duration
+		if intervalStr != "" {
+			interval, err = time.ParseDuration(intervalStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid interval parameter"})
+				return
+			}
+			// 限制 interval 最小值为 1 分钟，防止生成过多 bucket
+			if interval < time.Minute {
+				interval = time.Minute
+			}
+		} else {
+			// 根据 duration 自动选择合适的聚合粒度
+			switch {
+			case duration <= time.Hour:
+				interval = time.Minute
+			case duration <= 6*time.Hour:
+				interval = 5 * time.Minute
+			case duration <= 24*time.Hour:
+				interval = 15 * time.Minute
+			case duration <= 7*24*time.Hour:
+				interval = 2 * time.Hour
+			default:
+				interval = 24 * time.Hour
+			}
+		}
+
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.EmbeddingsUpstream
+
+		result := make([]MetricsHistoryResponse, 0, len(upstreams))
+		for i, upstream := range upstreams {
+			// 多 URL 聚合：超过内存保留窗口会自动截断并返回 warning
+			dataPoints, warning := metricsManager.GetHistoricalStatsMultiURLWithWarning(upstream.GetAllBaseURLs(), upstream.APIKeys, duration, interval)
+
+			result = append(result, MetricsHistoryResponse{
+				ChannelIndex: i,
+				ChannelName:  upstream.Name,
+				DataPoints:   dataPoints,
+				Warning:      warning,
+			})
+		}
+
+		c.JSON(200, result)
+	}
+}
+
+// GetEmbeddingsChannelKeyMetricsHistory 获取 Embeddings 渠道下各 Key 的历史数据（用于 Key 趋势图表）
+// GET /api/embeddings/channels/:id/keys/metrics/history?duration=6h
+func GetEmbeddingsChannelKeyMetricsHistory(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 解析 duration 参数
+		durationStr := c.DefaultQuery("duration", "6h")
+
+		var duration time.Duration
+		var err error
+
+		// 特殊处理 "today" 参数
+		if durationStr == "today" {
+			duration = metrics.CalculateTodayDuration()
+			// 如果刚过零点，duration 可能非常小，设置最小值
+			if duration < time.Minute {
+				duration = time.Minute
+			}
+		} else {
+			duration, err = parseDurationParam(durationStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid duration parameter. Use: 1h, 6h, 24h, today, 7d, or 30d"})
+				return
+			}
+		}
+
+		// 解析或自动选择 interval
+		intervalStr := c.Query("interval")
+		var interval time.Duration
+		if intervalStr != "" {
+			interval, err = time.ParseDuration(intervalStr)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid interval parameter"})
+				return
+			}
+			// 限制 interval 最小值为 1 分钟，防止生成过多 bucket
+			if interval < time.Minute {
+				interval = time.Minute
+			}
+		} else {
+			// 根据 duration 自动选择合适的聚合粒度
+			switch {
+			case duration <= time.Hour:
+				interval = time.Minute
+			case duration <= 6*time.Hour:
+				interval = 5 * time.Minute
+			case duration <= 24*time.Hour:
+				interval = 15 * time.Minute
+			case duration <= 7*24*time.Hour:
+				interval = 2 * time.Hour
+			default:
+				interval = 24 * time.Hour
+			}
+		}
+
+		// 解析 channel ID
+		channelIDStr := c.Param("id")
+		channelID, err := strconv.Atoi(channelIDStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid channel ID"})
+			return
+		}
+
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.EmbeddingsUpstream
+
+		// 检查 channel ID 是否有效
+		if channelID < 0 || channelID >= len(upstreams) {
+			c.JSON(400, gin.H{"error": "Channel not found"})
+			return
+		}
+
+		upstream := upstreams[channelID]
+
+		// 获取所有 Key 的使用信息并筛选（最多显示 10 个）
+		const maxDisplayKeys = 10
+		// 使用多 URL 聚合方法获取 Key 使用信息（支持 failover 多端点场景）
+		allKeyInfos := metricsManager.GetChannelKeyUsageInfoMultiURL(upstream.GetAllBaseURLs(), upstream.APIKeys)
+		displayKeys := metrics.SelectTopKeys(allKeyInfos, maxDisplayKeys)
+
+		// 构建响应
+		result := ChannelKeyMetricsHistoryResponse{
+			ChannelIndex: channelID,
+			ChannelName:  upstream.Name,
+			Keys:         make([]KeyMetricsHistoryResult, 0, len(displayKeys)),
+		}
+
+		var warning string
+		// 为筛选后的 Key 获取历史数据
+		for i, keyInfo := range displayKeys {
+			// 多 URL 聚合：超过内存保留窗口会自动截断并返回 warning
+			dataPoints, w := metricsManager.GetKeyHistoricalStatsMultiURLWithWarning(upstream.GetAllBaseURLs(), keyInfo.APIKey, duration, interval)
+			if warning == "" {
+				warning = w
+			}
+
+			// 获取 Key 的颜色
+			color := keyColors[i%len(keyColors)]
+
+			// 获取 Key 的脱敏显示（只取前 8 个字符）
+			keyMask := truncateKeyMask(keyInfo.KeyMask, 8)
+
+			result.Keys = append(result.Keys, KeyMetricsHistoryResult{
+				KeyMask:    keyMask,
+				Color:      color,
+				DataPoints: dataPoints,
+			})
+		}
+
+		result.Warning = warning
+		c.JSON(200, result)
+	}
+}
+
+// GetEmbeddingsChannelMetrics 获取 Embeddings 渠道指标。
+// requestLogStats 可选：用于下发按请求日志口径的累计请求数（logRequestCount）。
+func GetEmbeddingsChannelMetrics(metricsManager *metrics.MetricsManager, cfgManager *config.ConfigManager, requestLogStats metrics.RequestLogStatsProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiType := "embeddings"
+
+		cfg := cfgManager.GetConfig()
+		upstreams := cfg.EmbeddingsUpstream
+
+		result := make([]gin.H, 0, len(upstreams))
+		for i, upstream := range upstreams {
+			// 使用多 URL 聚合方法获取渠道指标（支持 failover 多端点场景）
+			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0)
+
+			// 请求日志口径的累计请求数（用于对齐“请求监控”）
+			if requestLogStats != nil && resp.KeyMetrics != nil {
+				for _, km := range resp.KeyMetrics {
+					if km == nil || km.KeyID == "" {
+						continue
+					}
+					km.LogRequestCount = requestLogStats.GetKeyRequestCount(apiType, i, km.KeyID)
+				}
+			}
+
+			// 综合两套熔断机制：ConfigManager 的冷却状态 + MetricsManager 的熔断状态
+			if resp.KeyMetrics != nil {
+				for idx, km := range resp.KeyMetrics {
+					if idx < len(upstream.APIKeys) {
+						apiKey := upstream.APIKeys[idx]
+						if cfgManager.IsKeyFailed(apiKey) {
+							km.CircuitBroken = true
+						}
+					}
+				}
+			}
+
+			item := gin.H{
+				"channelIndex":        i,
+				"channelName":         upstream.Name,
+				"requestCount":        resp.RequestCount,
+				"successCount":        resp.SuccessCount,
+				"failureCount":        resp.FailureCount,
+				"successRate":         resp.SuccessRate,
+				"errorRate":           resp.ErrorRate,
+				"consecutiveFailures": resp.ConsecutiveFailures,
+				"latency":             resp.Latency,
+				"keyMetrics":          resp.KeyMetrics,  // 各 Key 的详细指标
+				"timeWindows":         resp.TimeWindows, // 分时段统计 (15m, 1h, 6h, 24h)
+				"ttfb":                resp.TTFB,        // 首字节时间统计
+			}
+
+			if resp.LastSuccessAt != nil {
+				item["lastSuccessAt"] = *resp.LastSuccessAt
+			}
+			if resp.LastFailureAt != nil {
+				item["lastFailureAt"] = *resp.LastFailureAt
+			}
+			if resp.CircuitBrokenAt != nil {
+				item["circuitBrokenAt"] = *resp.CircuitBrokenAt
+			}
+
+			result = append(result, item)
+		}
+
+		c.JSON(200, result)
+	}
+}
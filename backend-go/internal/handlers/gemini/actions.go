@@ -0,0 +1,242 @@
+package gemini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Gemini 非生成类方法（与 generateContent 共用渠道调度与 Key 故障转移）
+const (
+	actionCountTokens        = "countTokens"
+	actionEmbedContent       = "embedContent"
+	actionBatchEmbedContents = "batchEmbedContents"
+)
+
+// contextKeyGeminiAction 当前请求的 Gemini 方法名（如 countTokens）
+const contextKeyGeminiAction = "__proxy_gateway_gemini_action"
+
+// extractModelAction 从 URL 参数提取方法名
+// 输入: "gemini-2.0-flash:countTokens"
+// 输出: "countTokens"
+func extractModelAction(param string) string {
+	if idx := strings.LastIndex(param, ":"); idx > 0 {
+		return param[idx+1:]
+	}
+	return ""
+}
+
+// isAuxiliaryAction 判断是否为非生成类方法
+func isAuxiliaryAction(action string) bool {
+	switch action {
+	case actionCountTokens, actionEmbedContent, actionBatchEmbedContents:
+		return true
+	}
+	return false
+}
+
+// geminiActionFromContext 读取当前请求的非生成类方法名，生成类请求返回空字符串
+func geminiActionFromContext(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if v, ok := c.Get(contextKeyGeminiAction); ok {
+		if action, ok := v.(string); ok && isAuxiliaryAction(action) {
+			return action
+		}
+	}
+	return ""
+}
+
+// handleLocalAction 处理无需请求上游的非生成类方法，返回 true 表示已写出响应
+// - countTokens：非 Gemini 上游无对应接口，使用本地估算
+// - embedContent/batchEmbedContents：Claude / Vertex 上游不支持向量接口
+func handleLocalAction(c *gin.Context, upstream *config.UpstreamConfig, bodyBytes []byte, reqCtx *requestLogContext) bool {
+	action := geminiActionFromContext(c)
+	if action == "" || upstream.ServiceType == "gemini" || upstream.ServiceType == "" {
+		return false
+	}
+
+	switch {
+	case action == actionCountTokens:
+		c.JSON(http.StatusOK, gin.H{"totalTokens": utils.EstimateGeminiRequestTokens(bodyBytes)})
+		if reqCtx != nil {
+			reqCtx.success = true
+			reqCtx.errorMsg = ""
+		}
+		return true
+	case upstream.ServiceType == "claude" || upstream.ServiceType == "vertex":
+		msg := fmt.Sprintf("%s is not supported by upstream \"%s\"", action, upstream.Name)
+		if reqCtx != nil {
+			reqCtx.success = false
+			reqCtx.errorMsg = msg
+		}
+		c.JSON(http.StatusNotImplemented, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    http.StatusNotImplemented,
+				Message: msg,
+				Status:  "UNIMPLEMENTED",
+			},
+		})
+		return true
+	}
+	return false
+}
+
+// buildAuxiliaryRequest 构建非生成类方法的上游请求
+// Gemini 上游透传请求体（改写其中的模型名），OpenAI 上游转换为 /v1/embeddings
+func buildAuxiliaryRequest(c *gin.Context, upstream *config.UpstreamConfig, baseURL, apiKey, action, mappedModel string) (*http.Request, error) {
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	var url string
+	var requestBody []byte
+
+	if upstream.ServiceType == "openai" {
+		requestBody = geminiEmbedToOpenAIRequest(bodyBytes, mappedModel)
+		url = fmt.Sprintf("%s/v1/embeddings", strings.TrimRight(baseURL, "/"))
+	} else {
+		requestBody = rewriteGeminiActionModel(bodyBytes, action, mappedModel)
+		url = fmt.Sprintf("%s/v1beta/models/%s:%s", strings.TrimRight(baseURL, "/"), mappedModel, action)
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", url, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	req.Header.Set("Content-Type", "application/json")
+	if upstream.ServiceType == "openai" {
+		utils.SetAuthenticationHeader(req.Header, apiKey)
+	} else {
+		utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+	}
+	return req, nil
+}
+
+// rewriteGeminiActionModel 将请求体内嵌的模型名改写为映射后的模型
+// batchEmbedContents 的每个子请求、countTokens 的 generateContentRequest 都携带 model 字段
+func rewriteGeminiActionModel(bodyBytes []byte, action, mappedModel string) []byte {
+	if !gjson.ValidBytes(bodyBytes) {
+		return bodyBytes
+	}
+	out := string(bodyBytes)
+	modelRef := "models/" + mappedModel
+
+	switch action {
+	case actionBatchEmbedContents:
+		n := int(gjson.Get(out, "requests.#").Int())
+		for i := 0; i < n; i++ {
+			out, _ = sjson.Set(out, fmt.Sprintf("requests.%d.model", i), modelRef)
+		}
+	case actionCountTokens:
+		if gjson.Get(out, "generateContentRequest").Exists() {
+			out, _ = sjson.Set(out, "generateContentRequest.model", modelRef)
+		}
+	}
+	return []byte(out)
+}
+
+// geminiEmbedToOpenAIRequest 将 embedContent/batchEmbedContents 请求转换为 OpenAI embeddings 请求
+func geminiEmbedToOpenAIRequest(bodyBytes []byte, mappedModel string) []byte {
+	root := gjson.ParseBytes(bodyBytes)
+	out := `{"model":"","input":[]}`
+	out, _ = sjson.Set(out, "model", mappedModel)
+
+	appendContent := func(content gjson.Result) {
+		var texts []string
+		content.Get("parts").ForEach(func(_, part gjson.Result) bool {
+			if text := part.Get("text").String(); text != "" {
+				texts = append(texts, text)
+			}
+			return true
+		})
+		out, _ = sjson.Set(out, "input.-1", strings.Join(texts, "\n"))
+	}
+
+	if requests := root.Get("requests"); requests.IsArray() {
+		requests.ForEach(func(_, r gjson.Result) bool {
+			appendContent(r.Get("content"))
+			return true
+		})
+		if dim := requests.Get("0.outputDimensionality"); dim.Exists() {
+			out, _ = sjson.Set(out, "dimensions", dim.Int())
+		}
+	} else {
+		appendContent(root.Get("content"))
+		if dim := root.Get("outputDimensionality"); dim.Exists() {
+			out, _ = sjson.Set(out, "dimensions", dim.Int())
+		}
+	}
+	return []byte(out)
+}
+
+// openAIEmbeddingsToGemini 将 OpenAI embeddings 响应转换为 Gemini 格式
+func openAIEmbeddingsToGemini(bodyBytes []byte, action string) ([]byte, error) {
+	if !gjson.ValidBytes(bodyBytes) {
+		return nil, fmt.Errorf("无效的 embeddings 响应")
+	}
+
+	var embeddings []string
+	gjson.GetBytes(bodyBytes, "data").ForEach(func(_, item gjson.Result) bool {
+		values := item.Get("embedding").Raw
+		if values == "" {
+			values = "[]"
+		}
+		embeddings = append(embeddings, `{"values":`+values+`}`)
+		return true
+	})
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("embeddings 响应缺少 data")
+	}
+
+	if action == actionBatchEmbedContents {
+		return []byte(`{"embeddings":[` + strings.Join(embeddings, ",") + `]}`), nil
+	}
+	return []byte(`{"embedding":` + embeddings[0] + `}`), nil
+}
+
+// handleAuxiliarySuccess 处理非生成类方法的成功响应（响应体由调用方关闭）
+func handleAuxiliarySuccess(c *gin.Context, resp *http.Response, upstreamType, action string) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.JSON(500, types.GeminiError{
+			Error: types.GeminiErrorDetail{
+				Code:    500,
+				Message: "Failed to read response",
+				Status:  "INTERNAL",
+			},
+		})
+		return
+	}
+	bodyBytes = utils.DecompressGzipIfNeeded(resp, bodyBytes)
+
+	if upstreamType == "openai" {
+		converted, err := openAIEmbeddingsToGemini(bodyBytes, action)
+		if err != nil {
+			c.JSON(502, types.GeminiError{
+				Error: types.GeminiErrorDetail{
+					Code:    502,
+					Message: err.Error(),
+					Status:  "INTERNAL",
+				},
+			})
+			return
+		}
+		bodyBytes = converted
+	}
+
+	c.Data(resp.StatusCode, "application/json", bodyBytes)
+}
@@ -0,0 +1,186 @@
+package gemini
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newActionTestRouter(t *testing.T, upstreams []config.UpstreamConfig) (*gin.Engine, *config.EnvConfig) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Config{
+		GeminiUpstream:       upstreams,
+		LoadBalance:          "failover",
+		ResponsesLoadBalance: "failover",
+		GeminiLoadBalance:    "failover",
+		FuzzyModeEnabled:     true,
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	t.Cleanup(cleanupCfg)
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	t.Cleanup(cleanupSch)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1beta/models/*modelAction", NewHandler(envCfg, cfgManager, sch, nil, nil, nil))
+	return r, envCfg
+}
+
+func postAction(r *gin.Engine, envCfg *config.EnvConfig, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGeminiHandler_CountTokens_PassthroughWithKeyFailover(t *testing.T) {
+	var calls atomic.Int64
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		gotPath = r.URL.Path
+		if r.Header.Get("x-goog-api-key") == "bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":401,"message":"invalid key"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalTokens":42}`))
+	}))
+	defer upstream.Close()
+
+	r, envCfg := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "g0", BaseURL: upstream.URL, APIKeys: []string{"bad", "good"}, ServiceType: "gemini", Status: "active", Priority: 1},
+	})
+
+	w := postAction(r, envCfg, "/v1beta/models/gemini-pro:countTokens", `{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.GetBytes(w.Body.Bytes(), "totalTokens").Int() != 42 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+	if calls.Load() != 2 || gotPath != "/v1beta/models/gemini-pro:countTokens" {
+		t.Fatalf("calls=%d path=%q", calls.Load(), gotPath)
+	}
+}
+
+func TestGeminiHandler_CountTokens_NonGeminiUpstreamEstimatesLocally(t *testing.T) {
+	var calls atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	r, envCfg := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "c0", BaseURL: upstream.URL, APIKeys: []string{"k"}, ServiceType: "claude", Status: "active", Priority: 1},
+	})
+
+	w := postAction(r, envCfg, "/v1beta/models/gemini-pro:countTokens", `{"contents":[{"role":"user","parts":[{"text":"The quick brown fox jumps over the lazy dog"}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.GetBytes(w.Body.Bytes(), "totalTokens").Int() <= 0 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("upstream should not be called, calls=%d", calls.Load())
+	}
+}
+
+func TestGeminiHandler_BatchEmbedContents_RewritesModel(t *testing.T) {
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/text-embedding-004:batchEmbedContents" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"embeddings":[{"values":[0.1]},{"values":[0.2]}]}`))
+	}))
+	defer upstream.Close()
+
+	r, envCfg := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "g0", BaseURL: upstream.URL, APIKeys: []string{"k"}, ServiceType: "gemini", Status: "active", Priority: 1,
+			ModelMapping: map[string]string{"embed": "text-embedding-004"}},
+	})
+
+	body := `{"requests":[{"model":"models/embed","content":{"parts":[{"text":"a"}]}},{"model":"models/embed","content":{"parts":[{"text":"b"}]}}]}`
+	w := postAction(r, envCfg, "/v1beta/models/embed:batchEmbedContents", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.GetBytes(gotBody, "requests.1.model").String() != "models/text-embedding-004" {
+		t.Fatalf("model not rewritten: %s", gotBody)
+	}
+	if gjson.GetBytes(w.Body.Bytes(), "embeddings.#").Int() != 2 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestGeminiHandler_EmbedContent_OpenAIUpstream(t *testing.T) {
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer ok" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5,0.25]}]}`))
+	}))
+	defer upstream.Close()
+
+	r, envCfg := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "o0", BaseURL: upstream.URL, APIKeys: []string{"ok"}, ServiceType: "openai", Status: "active", Priority: 1},
+	})
+
+	w := postAction(r, envCfg, "/v1beta/models/text-embedding-3-small:embedContent", `{"content":{"parts":[{"text":"hello"}]},"outputDimensionality":2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.GetBytes(gotBody, "input.0").String() != "hello" || gjson.GetBytes(gotBody, "dimensions").Int() != 2 {
+		t.Fatalf("unexpected upstream body: %s", gotBody)
+	}
+	if got := gjson.GetBytes(w.Body.Bytes(), "embedding.values").Raw; got != "[0.5,0.25]" {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestGeminiHandler_EmbedContent_ClaudeUpstreamUnsupported(t *testing.T) {
+	r, envCfg := newActionTestRouter(t, []config.UpstreamConfig{
+		{Name: "c0", BaseURL: "http://example.invalid", APIKeys: []string{"k"}, ServiceType: "claude", Status: "active", Priority: 1},
+	})
+
+	w := postAction(r, envCfg, "/v1beta/models/embed:embedContent", `{"content":{"parts":[{"text":"hello"}]}}`)
+	if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "UNIMPLEMENTED") {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestExtractModelAction(t *testing.T) {
+	cases := map[string]string{
+		"gemini-pro:countTokens":           "countTokens",
+		"gemini-pro:streamGenerateContent": "streamGenerateContent",
+		"gemini-pro":                       "",
+	}
+	for in, want := range cases {
+		if got := extractModelAction(in); got != want {
+			t.Errorf("extractModelAction(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package gemini
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -35,9 +36,13 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 				"website":                     up.Website,
 				"insecureSkipVerify":          up.InsecureSkipVerify,
 				"modelMapping":                up.ModelMapping,
+				"supportedModels":             up.SupportedModels,
 				"latency":                     nil,
 				"status":                      status,
 				"priority":                    priority,
+				"weight":                      up.Weight,
+				"priceMultiplier":             up.PriceMultiplier,
+				"maxConcurrency":              up.MaxConcurrency,
 				"promotionUntil":              up.PromotionUntil,
 				"lowQuality":                  up.LowQuality,
 				"injectDummyThoughtSignature": up.InjectDummyThoughtSignature,
@@ -62,6 +67,11 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddGeminiUpstream(upstream); err != nil {
+			var cfgErr *config.ConfigError
+			if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
@@ -88,6 +98,11 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 
 		shouldResetMetrics, err := cfgManager.UpdateGeminiUpstream(id, updates)
 		if err != nil {
+			var cfgErr *config.ConfigError
+			if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
@@ -38,9 +38,13 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 				"website":                     up.Website,
 				"insecureSkipVerify":          up.InsecureSkipVerify,
 				"modelMapping":                up.ModelMapping,
+				"supportedModels":             up.SupportedModels,
 				"latency":                     nil,
 				"status":                      status,
 				"priority":                    priority,
+				"weight":                      up.Weight,
+				"priceMultiplier":             up.PriceMultiplier,
+				"maxConcurrency":              up.MaxConcurrency,
 				"promotionUntil":              up.PromotionUntil,
 				"lowQuality":                  up.LowQuality,
 				"injectDummyThoughtSignature": up.InjectDummyThoughtSignature,
@@ -50,6 +54,7 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 
 		// 2. 构建 metrics 数据
 		metricsResult := make([]gin.H, 0, len(upstreams))
+		var estimatedSavingsCents int64
 		for i, upstream := range upstreams {
 			resp := metricsManager.ToResponseMultiURL(i, upstream.GetAllBaseURLs(), upstream.APIKeys, 0)
 
@@ -77,6 +82,9 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 				}
 			}
 
+			savings := metrics.EstimateCostSavings(resp.TimeWindows, upstream.GetPriceMultiplier())
+			estimatedSavingsCents += savings.SavingsCents
+
 			item := gin.H{
 				"channelIndex":        i,
 				"channelName":         upstream.Name,
@@ -88,6 +96,7 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 				"consecutiveFailures": resp.ConsecutiveFailures,
 				"latency":             resp.Latency,
 				"keyMetrics":          resp.KeyMetrics,
+				"costSavings":         savings,
 				"timeWindows":         resp.TimeWindows,
 			}
 
@@ -113,6 +122,9 @@ func GetDashboard(cfgManager *config.ConfigManager, sch *scheduler.ChannelSchedu
 			"failureThreshold":    metricsManager.GetFailureThreshold() * 100,
 			"windowSize":          metricsManager.GetWindowSize(),
 			"circuitRecoveryTime": metricsManager.GetCircuitRecoveryTime().String(),
+			// 按渠道价格倍率估算的节省金额（相对官方价格）
+			"estimatedSavingsCents": estimatedSavingsCents,
+			"savingsWindow":         metrics.SavingsWindow,
 		}
 
 		// 4. 构建 recentActivity 数据（最近 15 分钟分段活跃度）
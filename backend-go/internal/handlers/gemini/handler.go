@@ -3,6 +3,7 @@
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,8 +21,10 @@
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/BenedictKing/claude-proxy/internal/vertex"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
 )
 
 type requestLogContext struct {
@@ -36,6 +39,9 @@ type requestLogContext struct {
 	channelName  string
 	apiKey       string
 
+	queuedAt  *time.Time    // 排队等待槽位中（非 nil 时）的入队时间
+	queueWait time.Duration // 获得槽位前的排队时长
+
 	usage     *types.Usage
 	costCents int64
 
@@ -58,6 +64,19 @@ func (r *requestLogContext) updateLive() {
 		StartTime:    r.startTime,
 		APIType:      r.apiType,
 		IsStreaming:  r.isStreaming,
+		QueuedAt:     r.queuedAt,
+		QueueWaitMs:  r.queueWait.Milliseconds(),
+	})
+}
+
+// withQueueHook 为槽位选择附加排队回调：所有槽位并发已满时在实时请求中标记为排队中
+func (r *requestLogContext) withQueueHook(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return scheduler.WithQueueHook(ctx, func(queuedAt time.Time) {
+		r.queuedAt = &queuedAt
+		r.updateLive()
 	})
 }
 
@@ -245,6 +264,11 @@ func (h *Handler) Handle(c *gin.Context) {
 		return
 	}
 
+	// countTokens / embedContent / batchEmbedContents 复用同一调度流程，在构建请求与处理响应时分流
+	if action := extractModelAction(modelAction); isAuxiliaryAction(action) {
+		c.Set(contextKeyGeminiAction, action)
+	}
+
 	// 判断是否流式
 	isStream := strings.Contains(c.Request.URL.Path, "streamGenerateContent")
 	reqCtx.model = model
@@ -261,6 +285,12 @@ func (h *Handler) Handle(c *gin.Context) {
 	isMultiSlot := channelScheduler.IsMultiSlotModeGemini()
 	globalModelMapping := cfgManager.GetGlobalModelMapping()
 
+	// 引用了 Files API 文件或上下文缓存：资源只存在于创建它的 Key 下，固定路由到该槽位
+	if slot := geminiResourceSlot(channelScheduler, bodyBytes); slot != nil {
+		handlePinnedSlot(c, envCfg, cfgManager, channelScheduler, h.circuitLogStore, bodyBytes, &geminiReq, model, isStream, slot, startTime, reqCtx, globalModelMapping)
+		return
+	}
+
 	if isMultiSlot {
 		handleMultiChannel(c, envCfg, cfgManager, channelScheduler, h.circuitLogStore, bodyBytes, &geminiReq, model, isStream, userID, startTime, reqCtx, globalModelMapping)
 	} else {
@@ -353,7 +383,7 @@ func handleMultiChannel(
 			return
 		}
 
-		selection, err := channelScheduler.SelectGeminiSlot(c.Request.Context(), userID, failedSlots)
+		selection, err := channelScheduler.SelectGeminiSlot(reqCtx.withQueueHook(scheduler.WithRequestModel(c.Request.Context(), model)), userID, failedSlots)
 		if err != nil {
 			lastError = err
 			break
@@ -364,6 +394,8 @@ func handleMultiChannel(
 		if reqCtx != nil {
 			reqCtx.channelIndex = channelIndex
 			reqCtx.channelName = upstream.Name
+			reqCtx.queuedAt = nil
+			reqCtx.queueWait = selection.QueueWait
 			reqCtx.updateLive()
 		}
 
@@ -374,11 +406,15 @@ func handleMultiChannel(
 
 		upstreamOneKey := upstream.Clone()
 		upstreamOneKey.APIKeys = []string{selection.APIKey}
-		success, successKey, _, failoverErr, usage := tryChannelWithAllKeys(
-			c, envCfg, cfgManager, channelScheduler, circuitLogStore, upstreamOneKey, channelIndex,
-			bodyBytes, geminiReq, model, isStream, startTime,
-			reqCtx, globalModelMapping,
-		)
+		// 本槽位尝试结束（含 panic）即归还并发额度
+		success, successKey, _, failoverErr, usage := func() (bool, string, int, *common.FailoverError, *types.Usage) {
+			defer selection.Release()
+			return tryChannelWithAllKeys(
+				c, envCfg, cfgManager, channelScheduler, circuitLogStore, upstreamOneKey, channelIndex,
+				bodyBytes, geminiReq, model, isStream, startTime,
+				reqCtx, globalModelMapping,
+			)
+		}()
 
 		if success {
 			// successKey 为空表示请求方取消导致的提前退出：不记录成功/亲和，也不再继续。
@@ -401,7 +437,7 @@ func handleMultiChannel(
 		failedSlots[fmt.Sprintf("%d:%s", channelIndex, selection.APIKey)] = true
 
 		if failoverErr != nil {
-			lastFailoverError = failoverErr
+			lastFailoverError = common.KeepClientError(lastFailoverError, failoverErr)
 			lastError = fmt.Errorf("槽位 [%d] %s 失败", channelIndex, upstream.Name)
 		}
 
@@ -420,6 +456,58 @@ func handleMultiChannel(
 	handleAllChannelsFailed(c, lastFailoverError, lastError)
 }
 
+// handlePinnedSlot 处理固定槽位的 Gemini 请求（资源亲和），失败时不切换到其他槽位
+func handlePinnedSlot(
+	c *gin.Context,
+	envCfg *config.EnvConfig,
+	cfgManager *config.ConfigManager,
+	channelScheduler *scheduler.ChannelScheduler,
+	circuitLogStore metrics.KeyCircuitLogStore,
+	bodyBytes []byte,
+	geminiReq *types.GeminiRequest,
+	model string,
+	isStream bool,
+	slot *scheduler.SlotSelectionResult,
+	startTime time.Time,
+	reqCtx *requestLogContext,
+	globalModelMapping map[string]string,
+) {
+	if reqCtx != nil {
+		reqCtx.channelIndex = slot.ChannelIndex
+		reqCtx.channelName = slot.Upstream.Name
+		reqCtx.updateLive()
+	}
+	if envCfg.ShouldLog("info") {
+		log.Printf("[Gemini-Select] 选择槽位: [%d] %s (原因: %s)", slot.ChannelIndex, slot.Upstream.Name, slot.Reason)
+	}
+
+	upstreamOneKey := slot.Upstream.Clone()
+	upstreamOneKey.APIKeys = []string{slot.APIKey}
+	success, successKey, _, failoverErr, usage := tryChannelWithAllKeys(
+		c, envCfg, cfgManager, channelScheduler, circuitLogStore, upstreamOneKey, slot.ChannelIndex,
+		bodyBytes, geminiReq, model, isStream, startTime,
+		reqCtx, globalModelMapping,
+	)
+	if success {
+		if successKey != "" && reqCtx != nil {
+			reqCtx.apiKey = successKey
+			reqCtx.usage = usage
+			reqCtx.success = true
+			reqCtx.errorMsg = ""
+			reqCtx.updateLive()
+		}
+		return
+	}
+
+	log.Printf("[Gemini-Error] 资源所在槽位 [%d] %s 请求失败", slot.ChannelIndex, slot.Upstream.Name)
+	lastError := fmt.Errorf("槽位 [%d] %s 失败", slot.ChannelIndex, slot.Upstream.Name)
+	if reqCtx != nil {
+		reqCtx.success = false
+		reqCtx.errorMsg = truncateErrorMessage(lastError.Error())
+	}
+	handleAllChannelsFailed(c, failoverErr, lastError)
+}
+
 // tryChannelWithAllKeys 尝试使用 Gemini 渠道的所有密钥
 func tryChannelWithAllKeys(
 	c *gin.Context,
@@ -451,6 +539,11 @@ func tryChannelWithAllKeys(
 	var lastFailoverError *common.FailoverError
 	deprioritizeCandidates := make(map[string]bool)
 
+	// 非 Gemini 上游的 countTokens 等方法本地处理
+	if handleLocalAction(c, upstream, bodyBytes, reqCtx) {
+		return true, "", 0, nil, nil
+	}
+
 	// 强制探测模式
 	forceProbeMode := common.AreAllKeysSoftSuspended(metricsManager, upstream.BaseURL, enabledKeys)
 	if forceProbeMode {
@@ -503,6 +596,10 @@ func tryChannelWithAllKeys(
 			providerReq, err := buildProviderRequest(c, upstream, currentBaseURL, apiKey, geminiReq, model, isStream, globalModelMapping)
 			if err != nil {
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := geminiConversionFailoverError(err); failoverErr != nil {
+					return false, "", 0, failoverErr, nil
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "gemini", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordGeminiFailure(currentBaseURL, apiKey)
 				})
@@ -510,6 +607,7 @@ func tryChannelWithAllKeys(
 			}
 			common.SetUpstreamRequestSnapshot(c, providerReq)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, isStream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不做降级，不继续 failover
@@ -568,6 +666,11 @@ func tryChannelWithAllKeys(
 				return true, "", 0, nil, nil
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordGeminiLatency(currentBaseURL, apiKey, ttfb)
+			})
+
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
 					_ = cfgManager.DeprioritizeAPIKey(key)
@@ -576,7 +679,7 @@ func tryChannelWithAllKeys(
 
 			channelScheduler.MarkURLSuccess(channelIndex, currentBaseURL)
 
-			usage := handleSuccess(c, resp, upstream.ServiceType, envCfg, startTime, geminiReq, model, isStream)
+			usage := handleSuccess(c, resp, responseUpstreamType(upstream, model, globalModelMapping), envCfg, startTime, geminiReq, model, isStream)
 			// 记录成功指标：multi-channel 路径不会走 single-channel 的记录逻辑
 			// 若请求方已取消，则不计入成功
 			if c.Request.Context().Err() == nil {
@@ -657,6 +760,11 @@ func handleSingleChannel(
 		reqCtx.updateLive()
 	}
 
+	// 非 Gemini 上游的 countTokens 等方法本地处理
+	if handleLocalAction(c, upstream, bodyBytes, reqCtx) {
+		return
+	}
+
 	var lastError error
 	var lastFailoverError *common.FailoverError
 	deprioritizeCandidates := make(map[string]bool)
@@ -709,6 +817,11 @@ func handleSingleChannel(
 			if err != nil {
 				lastError = err
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := geminiConversionFailoverError(err); failoverErr != nil {
+					lastFailoverError = failoverErr
+					break
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "gemini", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordGeminiFailure(currentBaseURL, apiKey)
 				})
@@ -716,6 +829,7 @@ func handleSingleChannel(
 			}
 			common.SetUpstreamRequestSnapshot(c, providerReq)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, isStream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不继续 failover
@@ -773,13 +887,18 @@ func handleSingleChannel(
 				return
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordGeminiLatency(currentBaseURL, apiKey, ttfb)
+			})
+
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
 					_ = cfgManager.DeprioritizeAPIKey(key)
 				}
 			}
 
-			usage := handleSuccess(c, resp, upstream.ServiceType, envCfg, startTime, geminiReq, model, isStream)
+			usage := handleSuccess(c, resp, responseUpstreamType(upstream, model, globalModelMapping), envCfg, startTime, geminiReq, model, isStream)
 			channelScheduler.RecordGeminiSuccessWithUsage(currentBaseURL, apiKey, usage, model, 0)
 			if reqCtx != nil {
 				reqCtx.usage = usage
@@ -802,6 +921,18 @@ func handleSingleChannel(
 	handleAllKeysFailed(c, lastFailoverError, lastError)
 }
 
+// responseUpstreamType 返回解析上游响应所用的协议类型
+// Vertex 渠道按模型发布方区分：Claude 模型返回 Claude 格式，其余返回 Gemini 格式
+func responseUpstreamType(upstream *config.UpstreamConfig, model string, globalModelMapping map[string]string) string {
+	if upstream.ServiceType != "vertex" {
+		return upstream.ServiceType
+	}
+	if vertex.PublisherForModel(config.RedirectModelWithGlobal(model, upstream, globalModelMapping)) == vertex.PublisherAnthropic {
+		return "claude"
+	}
+	return "gemini"
+}
+
 // buildProviderRequest 构建上游请求
 func buildProviderRequest(
 	c *gin.Context,
@@ -816,6 +947,10 @@ func buildProviderRequest(
 	// 应用模型映射
 	mappedModel := config.RedirectModelWithGlobal(model, upstream, globalModelMapping)
 
+	if action := geminiActionFromContext(c); action != "" {
+		return buildAuxiliaryRequest(c, upstream, baseURL, apiKey, action, mappedModel)
+	}
+
 	var requestBody []byte
 	var url string
 	var err error
@@ -863,6 +998,33 @@ func buildProviderRequest(
 		}
 		url = fmt.Sprintf("%s/v1/messages", strings.TrimRight(baseURL, "/"))
 
+	case "vertex":
+		// Vertex AI 上游：按模型发布方选择 Gemini 原生格式或 Claude 格式
+		sa, err := vertex.ParseServiceAccount(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		publisher := vertex.PublisherForModel(mappedModel)
+		if publisher == vertex.PublisherAnthropic {
+			claudeReq, err := converters.GeminiToClaudeRequest(geminiReq, mappedModel)
+			if err != nil {
+				return nil, err
+			}
+			delete(claudeReq, "model")
+			claudeReq["anthropic_version"] = vertex.AnthropicVersion
+			claudeReq["stream"] = isStream
+			requestBody, err = json.Marshal(claudeReq)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			requestBody, err = json.Marshal(geminiReq)
+			if err != nil {
+				return nil, err
+			}
+		}
+		url = vertex.BuildModelURL(baseURL, sa.ProjectID, publisher, mappedModel, isStream)
+
 	case "openai":
 		// OpenAI 上游：需要转换
 		openaiReq, err := converters.GeminiToOpenAIRequest(geminiReq, mappedModel)
@@ -924,6 +1086,14 @@ func buildProviderRequest(
 		req.Header.Set("anthropic-version", "2023-06-01")
 	case "openai":
 		utils.SetAuthenticationHeader(req.Header, apiKey)
+	case "vertex":
+		token, err := vertex.AccessToken(c.Request.Context(), apiKey)
+		if err != nil {
+			return nil, fmt.Errorf("获取Vertex access token失败: %w", err)
+		}
+		req.Header.Del("x-api-key")
+		req.Header.Del("x-goog-api-key")
+		req.Header.Set("Authorization", "Bearer "+token)
 	default:
 		utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
 	}
@@ -944,6 +1114,11 @@ func handleSuccess(
 ) *types.Usage {
 	defer resp.Body.Close()
 
+	if action := geminiActionFromContext(c); action != "" {
+		handleAuxiliarySuccess(c, resp, upstreamType, action)
+		return nil
+	}
+
 	if isStream {
 		return handleStreamSuccess(c, resp, upstreamType, envCfg, startTime, model)
 	}
@@ -1051,6 +1226,22 @@ func handleAllChannelsFailed(c *gin.Context, failoverErr *common.FailoverError,
 	})
 }
 
+// geminiConversionFailoverError 将请求转换阶段的客户端错误包装为 Gemini 错误格式（400 INVALID_ARGUMENT）
+func geminiConversionFailoverError(err error) *common.FailoverError {
+	failoverErr := common.ConversionFailoverError(err)
+	if failoverErr == nil {
+		return nil
+	}
+	failoverErr.Body, _ = json.Marshal(types.GeminiError{
+		Error: types.GeminiErrorDetail{
+			Code:    failoverErr.Status,
+			Message: gjson.GetBytes(failoverErr.Body, "error.message").String(),
+			Status:  "INVALID_ARGUMENT",
+		},
+	})
+	return failoverErr
+}
+
 // handleAllKeysFailed 处理所有 Key 失败的情况
 func handleAllKeysFailed(c *gin.Context, failoverErr *common.FailoverError, lastError error) {
 	if failoverErr != nil {
@@ -2,6 +2,11 @@
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"io"
 	"net/http"
@@ -322,3 +327,75 @@ func TestOpenAIFinishReasonToGemini_AllCases(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildProviderRequest_Vertex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"ya29.gemini","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	saJSON, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email": "gemini-pool@demo-project.iam.gserviceaccount.com",
+		"token_uri":    tokenSrv.URL + "/token",
+	})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-pro:generateContent", bytes.NewBufferString(`{}`))
+	c.Request.Header.Set("x-goog-api-key", "client-key")
+
+	reqBody := &types.GeminiRequest{
+		Contents: []types.GeminiContent{{Role: "user", Parts: []types.GeminiPart{{Text: "hi"}}}},
+	}
+	up := &config.UpstreamConfig{ServiceType: "vertex", ModelMapping: map[string]string{"sonnet": "claude-sonnet-4@20250514"}}
+	baseURL := "https://us-east5-aiplatform.googleapis.com"
+
+	t.Run("gemini model passthrough", func(t *testing.T) {
+		req, err := buildProviderRequest(c, up, baseURL, string(saJSON), reqBody, "gemini-2.5-pro", true, nil)
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		want := baseURL + "/v1/projects/demo-project/locations/us-east5/publishers/google/models/gemini-2.5-pro:streamGenerateContent?alt=sse"
+		if req.URL.String() != want {
+			t.Fatalf("url=%q", req.URL.String())
+		}
+		if req.Header.Get("Authorization") != "Bearer ya29.gemini" || req.Header.Get("x-goog-api-key") != "" {
+			t.Fatalf("unexpected auth headers: %v", req.Header)
+		}
+		if got := responseUpstreamType(up, "gemini-2.5-pro", nil); got != "gemini" {
+			t.Fatalf("responseUpstreamType=%q", got)
+		}
+	})
+
+	t.Run("claude model uses rawPredict", func(t *testing.T) {
+		req, err := buildProviderRequest(c, up, baseURL, string(saJSON), reqBody, "sonnet", false, nil)
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		want := baseURL + "/v1/projects/demo-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict"
+		if req.URL.String() != want {
+			t.Fatalf("url=%q", req.URL.String())
+		}
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), `"model"`) || !strings.Contains(string(body), `"anthropic_version":"vertex-2023-10-16"`) {
+			t.Fatalf("unexpected body: %s", body)
+		}
+		if got := responseUpstreamType(up, "sonnet", nil); got != "claude" {
+			t.Fatalf("responseUpstreamType=%q", got)
+		}
+	})
+
+	t.Run("static key is rejected", func(t *testing.T) {
+		if _, err := buildProviderRequest(c, up, baseURL, "AIza-static", reqBody, "gemini-2.5-pro", false, nil); err == nil {
+			t.Fatalf("expected error for non service-account key")
+		}
+	})
+}
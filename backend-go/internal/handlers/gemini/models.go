@@ -0,0 +1,267 @@
+package gemini
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/cache"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const geminiModelsRequestTimeout = 30 * time.Second
+
+// geminiModelsCacheKey 模型列表在响应缓存中的键（汇总结果不分页，与查询参数无关）
+const geminiModelsCacheKey = "/v1beta/models"
+
+// geminiModelsMaxPages Gemini 原生上游分页拉取的最大页数
+const geminiModelsMaxPages = 10
+
+// ModelsHandler 处理 GET /v1beta/models，汇总所有活跃 Gemini 渠道的模型列表
+// 非 Gemini 原生渠道（OpenAI/Claude）的模型列表转换为 Gemini Model 格式
+func ModelsHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler, respCache *cache.HTTPResponseCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ProxyAuthMiddleware(envCfg)(c)
+		if c.IsAborted() {
+			return
+		}
+
+		body, ok := loadGeminiModels(c, channelScheduler, respCache)
+		if !ok {
+			writeGeminiError(c, http.StatusNotFound, "NOT_FOUND", "models endpoint not available from any upstream")
+			return
+		}
+		c.Data(http.StatusOK, gin.MIMEJSON, body)
+	}
+}
+
+// ModelsDetailHandler 处理 GET /v1beta/models/{model}，从汇总列表中查找指定模型
+func ModelsDetailHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler, respCache *cache.HTTPResponseCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ProxyAuthMiddleware(envCfg)(c)
+		if c.IsAborted() {
+			return
+		}
+
+		modelID := strings.TrimPrefix(strings.TrimPrefix(c.Param("model"), "/"), "models/")
+		if modelID == "" {
+			writeGeminiError(c, http.StatusBadRequest, "INVALID_ARGUMENT", "model id is required")
+			return
+		}
+
+		body, ok := loadGeminiModels(c, channelScheduler, respCache)
+		if ok {
+			if model := gjson.GetBytes(body, fmt.Sprintf(`models.#(name==%q)`, "models/"+modelID)); model.Exists() {
+				c.Data(http.StatusOK, gin.MIMEJSON, []byte(model.Raw))
+				return
+			}
+		}
+		writeGeminiError(c, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("models/%s is not found", modelID))
+	}
+}
+
+// loadGeminiModels 返回汇总后的模型列表响应体，优先使用缓存
+func loadGeminiModels(c *gin.Context, channelScheduler *scheduler.ChannelScheduler, respCache *cache.HTTPResponseCache) ([]byte, bool) {
+	if cached, ok := respCache.Get(geminiModelsCacheKey); ok {
+		return cached.Body, true
+	}
+
+	models, channels := fetchGeminiModelsFromChannels(c, channelScheduler)
+	if channels == 0 {
+		return nil, false
+	}
+	body, _ := sjson.SetRawBytes([]byte(`{}`), "models", []byte(models))
+
+	log.Printf("[Gemini-Models] 汇总完成: channels=%d, models=%d", channels, gjson.GetBytes(body, "models.#").Int())
+
+	respCache.Set(geminiModelsCacheKey, cache.HTTPResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{gin.MIMEJSON}},
+		Body:       body,
+	})
+	return body, true
+}
+
+// fetchGeminiModelsFromChannels 按调度顺序查询每个活跃 Gemini 渠道（同一渠道内按 Key 故障转移）
+// 返回按名称去重的 Gemini Model 数组（JSON）以及成功返回列表的渠道数
+func fetchGeminiModelsFromChannels(c *gin.Context, channelScheduler *scheduler.ChannelScheduler) (string, int) {
+	if c == nil || c.Request == nil || channelScheduler == nil {
+		return "[]", 0
+	}
+
+	var nativeModels, convertedModels []string
+	doneChannels := make(map[int]bool)
+	failedSlots := make(map[string]bool)
+	listed := 0
+
+	// models 请求没有 userID，使用随机请求 ID 提供均衡散列；请求轻量，不受并发上限约束也不排队
+	routingID := fmt.Sprintf("models:%s", uuid.NewString())
+
+	for attempt := 0; attempt < channelScheduler.GetActiveGeminiSlotCount(); attempt++ {
+		slot, err := channelScheduler.SelectGeminiSlotUncounted(c.Request.Context(), routingID, failedSlots)
+		if err != nil {
+			break
+		}
+		failedSlots[fmt.Sprintf("%d:%s", slot.ChannelIndex, slot.APIKey)] = true
+		if doneChannels[slot.ChannelIndex] {
+			continue
+		}
+
+		models, err := fetchGeminiChannelModels(c, slot)
+		if err != nil {
+			log.Printf("[Gemini-Models] 警告: 渠道 [%d] %s key=%s 获取模型列表失败: %v",
+				slot.ChannelIndex, slot.Upstream.Name, utils.MaskAPIKey(slot.APIKey), err)
+			continue
+		}
+		doneChannels[slot.ChannelIndex] = true
+		if models == nil {
+			continue
+		}
+		listed++
+		if isGeminiNativeUpstream(slot.Upstream) {
+			nativeModels = append(nativeModels, models...)
+		} else {
+			convertedModels = append(convertedModels, models...)
+		}
+	}
+
+	// 同名模型优先保留原生条目（包含 token 上限等完整元数据）
+	merged := "[]"
+	seenNames := make(map[string]bool)
+	for _, model := range append(nativeModels, convertedModels...) {
+		name := gjson.Get(model, "name").String()
+		if name == "" || seenNames[name] {
+			continue
+		}
+		seenNames[name] = true
+		merged, _ = sjson.SetRaw(merged, "-1", model)
+	}
+	return merged, listed
+}
+
+// fetchGeminiChannelModels 获取单个槽位的模型列表（每个元素为 Gemini Model JSON）
+// 不提供模型列表接口的渠道类型返回 nil, nil
+func fetchGeminiChannelModels(c *gin.Context, slot *scheduler.SlotSelectionResult) ([]string, error) {
+	upstream := slot.Upstream
+	baseURL := strings.TrimRight(upstream.GetAllBaseURLs()[0], "/")
+
+	switch upstream.ServiceType {
+	case "vertex":
+		// Vertex AI 发布方模型列表需要按发布方单独查询，且不包含调用方可用性信息，跳过
+		return nil, nil
+
+	case "openai", "claude":
+		body, err := getGeminiModelsPage(c, slot, baseURL+"/v1/models")
+		if err != nil {
+			return nil, err
+		}
+		models := []string{}
+		gjson.GetBytes(body, "data").ForEach(func(_, item gjson.Result) bool {
+			if model := convertModelToGemini(item); model != "" {
+				models = append(models, model)
+			}
+			return true
+		})
+		return models, nil
+
+	default:
+		// Gemini 原生上游：按 nextPageToken 拉取全部分页
+		models := []string{}
+		pageToken := ""
+		for page := 0; page < geminiModelsMaxPages; page++ {
+			query := url.Values{"pageSize": []string{"1000"}}
+			if pageToken != "" {
+				query.Set("pageToken", pageToken)
+			}
+			body, err := getGeminiModelsPage(c, slot, baseURL+"/v1beta/models?"+query.Encode())
+			if err != nil {
+				if page > 0 {
+					break
+				}
+				return nil, err
+			}
+			gjson.GetBytes(body, "models").ForEach(func(_, item gjson.Result) bool {
+				if item.Get("name").String() != "" {
+					models = append(models, item.Raw)
+				}
+				return true
+			})
+			if pageToken = gjson.GetBytes(body, "nextPageToken").String(); pageToken == "" {
+				break
+			}
+		}
+		return models, nil
+	}
+}
+
+// getGeminiModelsPage 按渠道类型设置认证头并请求模型列表
+func getGeminiModelsPage(c *gin.Context, slot *scheduler.SlotSelectionResult, targetURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = utils.PrepareMinimalHeaders(req.URL.Host)
+	switch slot.Upstream.ServiceType {
+	case "claude":
+		utils.SetAuthenticationHeader(req.Header, slot.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	case "openai":
+		utils.SetAuthenticationHeader(req.Header, slot.APIKey)
+	default:
+		utils.SetGeminiAuthenticationHeader(req.Header, slot.APIKey)
+	}
+
+	client := httpclient.GetManager().GetStandardClient(geminiModelsRequestTimeout, slot.Upstream.InsecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("上游返回状态码 %d", resp.StatusCode)
+	}
+	return utils.DecompressGzipIfNeeded(resp, body), nil
+}
+
+// convertModelToGemini 将 OpenAI / Claude 模型条目转换为 Gemini Model 格式
+// 输入: {"id":"gpt-4o","object":"model"} 或 {"id":"claude-sonnet-4-5","display_name":"Claude Sonnet 4.5"}
+// 输出: {"name":"models/gpt-4o","baseModelId":"gpt-4o","version":"","displayName":"gpt-4o","supportedGenerationMethods":[...]}
+func convertModelToGemini(item gjson.Result) string {
+	id := strings.TrimPrefix(item.Get("id").String(), "models/")
+	if id == "" {
+		return ""
+	}
+	displayName := item.Get("display_name").String()
+	if displayName == "" {
+		displayName = id
+	}
+	methods := []string{"generateContent", "streamGenerateContent", "countTokens"}
+	if strings.Contains(strings.ToLower(id), "embed") {
+		methods = []string{"embedContent", "batchEmbedContents"}
+	}
+
+	model := `{}`
+	model, _ = sjson.Set(model, "name", "models/"+id)
+	model, _ = sjson.Set(model, "baseModelId", id)
+	model, _ = sjson.Set(model, "version", "")
+	model, _ = sjson.Set(model, "displayName", displayName)
+	model, _ = sjson.Set(model, "supportedGenerationMethods", methods)
+	return model
+}
@@ -0,0 +1,172 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/cache"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertModelToGemini(t *testing.T) {
+	model := convertModelToGemini(gjson.Parse(`{"id":"claude-sonnet-4-5","display_name":"Claude Sonnet 4.5"}`))
+	if gjson.Get(model, "name").String() != "models/claude-sonnet-4-5" ||
+		gjson.Get(model, "baseModelId").String() != "claude-sonnet-4-5" ||
+		gjson.Get(model, "displayName").String() != "Claude Sonnet 4.5" ||
+		gjson.Get(model, "supportedGenerationMethods.0").String() != "generateContent" {
+		t.Fatalf("unexpected model: %s", model)
+	}
+
+	embed := convertModelToGemini(gjson.Parse(`{"id":"models/text-embedding-3-small"}`))
+	if gjson.Get(embed, "name").String() != "models/text-embedding-3-small" ||
+		gjson.Get(embed, "displayName").String() != "text-embedding-3-small" ||
+		gjson.Get(embed, "supportedGenerationMethods.0").String() != "embedContent" {
+		t.Fatalf("unexpected embedding model: %s", embed)
+	}
+
+	if got := convertModelToGemini(gjson.Parse(`{"object":"model"}`)); got != "" {
+		t.Fatalf("expected empty result for entry without id, got %s", got)
+	}
+}
+
+func TestGeminiModelsHandler_AggregatesAndCaches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var geminiHits, openaiHits int32
+	geminiUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&geminiHits, 1)
+		if r.URL.Path != "/v1beta/models" || r.Header.Get("x-goog-api-key") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// 第一个 Key 失效，应切换到同渠道的下一个 Key
+		if r.Header.Get("x-goog-api-key") == "g-bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"models":[{"name":"models/gemini-2.5-pro","displayName":"Gemini 2.5 Pro","inputTokenLimit":1048576}],"nextPageToken":"p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":[{"name":"models/gemini-2.5-flash","displayName":"Gemini 2.5 Flash"}]}`))
+	}))
+	defer geminiUpstream.Close()
+
+	openaiUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&openaiHits, 1)
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer o-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o","object":"model"},{"id":"gemini-2.5-pro","object":"model"}]}`))
+	}))
+	defer openaiUpstream.Close()
+
+	cfg := config.Config{
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "native", BaseURL: geminiUpstream.URL, APIKeys: []string{"g-bad", "g-good"}, ServiceType: "gemini", Status: "active", Priority: 1},
+			{Name: "openai", BaseURL: openaiUpstream.URL, APIKeys: []string{"o-key"}, ServiceType: "openai", Status: "active", Priority: 2},
+			{Name: "vertex", BaseURL: "http://vertex.invalid", APIKeys: []string{"{}"}, ServiceType: "vertex", Status: "active", Priority: 3},
+		},
+		GeminiLoadBalance: "failover",
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
+	respCache := cache.NewHTTPResponseCache(10, time.Minute, &metrics.CacheMetrics{})
+	r := gin.New()
+	r.GET("/v1beta/models", ModelsHandler(envCfg, cfgManager, sch, respCache))
+	r.GET("/v1beta/models/*model", ModelsDetailHandler(envCfg, cfgManager, sch, respCache))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("x-goog-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/v1beta/models")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	names := gjson.Get(w.Body.String(), "models.#.name").Array()
+	if len(names) != 3 ||
+		names[0].String() != "models/gemini-2.5-pro" ||
+		names[1].String() != "models/gemini-2.5-flash" ||
+		names[2].String() != "models/gpt-4o" {
+		t.Fatalf("unexpected models: %s", w.Body.String())
+	}
+	// 原生渠道的同名模型保留原始字段，不被转换后的条目覆盖
+	if gjson.Get(w.Body.String(), "models.0.inputTokenLimit").Int() != 1048576 {
+		t.Fatalf("native model fields should be preserved: %s", w.Body.String())
+	}
+	if gjson.Get(w.Body.String(), "nextPageToken").Exists() {
+		t.Fatalf("aggregated list should not be paginated: %s", w.Body.String())
+	}
+
+	// 详情与后续列表请求命中缓存，不再访问上游
+	hitsBefore := atomic.LoadInt32(&geminiHits) + atomic.LoadInt32(&openaiHits)
+	if w := get("/v1beta/models/gpt-4o"); w.Code != http.StatusOK || gjson.Get(w.Body.String(), "name").String() != "models/gpt-4o" {
+		t.Fatalf("detail: status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w := get("/v1beta/models/models/gemini-2.5-flash"); w.Code != http.StatusOK || gjson.Get(w.Body.String(), "displayName").String() != "Gemini 2.5 Flash" {
+		t.Fatalf("detail with models/ prefix: status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w := get("/v1beta/models/unknown-model"); w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "error.status").String() != "NOT_FOUND" {
+		t.Fatalf("unknown model: status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w := get("/v1beta/models"); w.Code != http.StatusOK {
+		t.Fatalf("cached list: status=%d", w.Code)
+	}
+	if hitsAfter := atomic.LoadInt32(&geminiHits) + atomic.LoadInt32(&openaiHits); hitsAfter != hitsBefore {
+		t.Fatalf("expected cache hits, upstream requests grew from %d to %d", hitsBefore, hitsAfter)
+	}
+}
+
+func TestGeminiModelsHandler_NoUpstreamReturnsNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	cfg := config.Config{
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "g0", BaseURL: upstream.URL, APIKeys: []string{"k1"}, ServiceType: "gemini", Status: "active", Priority: 1},
+		},
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
+	respCache := cache.NewHTTPResponseCache(10, time.Minute, &metrics.CacheMetrics{})
+	r := gin.New()
+	r.GET("/v1beta/models", ModelsHandler(envCfg, cfgManager, sch, respCache))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1beta/models", nil)
+	req.Header.Set("x-goog-api-key", envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "error.status").String() != "NOT_FOUND" {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if _, ok := respCache.Get(geminiModelsCacheKey); ok {
+		t.Fatalf("failed aggregation should not be cached")
+	}
+}
@@ -0,0 +1,498 @@
+package gemini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/scheduler"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Gemini Files API / cachedContents 透传
+//
+// 文件与上下文缓存只存在于创建它的 Key 下，因此代理记录每个资源的归属槽位（渠道+Key），
+// 后续的查询、删除、生成请求中的 fileData / cachedContent 引用都路由回同一槽位。
+// 可续传上传返回的 X-Goog-Upload-URL 改写为代理地址，上传会话同样按 upload_id 保持亲和。
+// 仅 Gemini 原生上游（serviceType 为 gemini）支持这些接口。
+
+// geminiUploadSessionPrefix 上传会话在资源亲和表中的名称前缀
+const geminiUploadSessionPrefix = "uploads/"
+
+// ResourceHandler Gemini 文件与上下文缓存代理
+type ResourceHandler struct {
+	envCfg           *config.EnvConfig
+	cfgManager       *config.ConfigManager
+	channelScheduler *scheduler.ChannelScheduler
+}
+
+// NewResourceHandler 创建 Gemini 文件与上下文缓存代理处理器
+func NewResourceHandler(envCfg *config.EnvConfig, cfgManager *config.ConfigManager, channelScheduler *scheduler.ChannelScheduler) gin.HandlerFunc {
+	h := &ResourceHandler{
+		envCfg:           envCfg,
+		cfgManager:       cfgManager,
+		channelScheduler: channelScheduler,
+	}
+	return h.Handle
+}
+
+// Handle 处理 /v1beta/files*、/upload/v1beta/files、/v1beta/cachedContents* 请求
+func (h *ResourceHandler) Handle(c *gin.Context) {
+	middleware.ProxyAuthMiddleware(h.envCfg)(c)
+	if c.IsAborted() {
+		return
+	}
+
+	path := c.Request.URL.Path
+	name := geminiResourceName(path)
+
+	switch {
+	case strings.HasPrefix(path, "/upload/"):
+		h.handleUpload(c)
+	case name != "":
+		h.handleNamedResource(c, name)
+	case c.Request.Method == http.MethodGet:
+		h.handleList(c)
+	default:
+		h.handleCreate(c)
+	}
+}
+
+// handleUpload 处理文件上传（简单/multipart 上传、可续传上传的发起与分片）
+func (h *ResourceHandler) handleUpload(c *gin.Context) {
+	if uploadID := c.Query("upload_id"); uploadID != "" {
+		slot, ok := h.channelScheduler.GetGeminiResourceSlot(geminiUploadSessionPrefix + uploadID)
+		if !ok {
+			writeGeminiError(c, http.StatusNotFound, "NOT_FOUND", "Upload session not found or expired")
+			return
+		}
+		// 分片数据直接流式转发，不缓冲
+		h.forwardAndRespond(c, slot, c.Request.Body, true)
+		return
+	}
+
+	// 可续传上传的 start 请求只携带元数据，可缓冲后在槽位间重试；其余上传请求携带文件内容，只尝试一次
+	command := strings.ToLower(c.GetHeader("X-Goog-Upload-Command"))
+	if command == "start" {
+		h.forwardWithFailover(c, nil)
+		return
+	}
+
+	slot, err := h.selectSlot(c, nil, nil)
+	if err != nil {
+		writeGeminiError(c, http.StatusServiceUnavailable, "UNAVAILABLE", err.Error())
+		return
+	}
+	h.forwardAndRespond(c, slot, c.Request.Body, true)
+}
+
+// handleNamedResource 处理指定资源的查询、更新、删除与下载
+// 归属未知（如代理重启后）时依次探测各槽位，找到资源后补记归属
+func (h *ResourceHandler) handleNamedResource(c *gin.Context, name string) {
+	if slot, ok := h.channelScheduler.GetGeminiResourceSlot(name); ok {
+		h.forwardAndRespond(c, slot, c.Request.Body, strings.HasSuffix(c.Request.URL.Path, ":download"))
+		return
+	}
+
+	bodyBytes, err := common.ReadRequestBody(c, h.envCfg.MaxRequestBodySize)
+	if err != nil {
+		return
+	}
+
+	failedSlots := make(map[string]bool)
+	for attempt := 0; attempt < h.channelScheduler.GetActiveGeminiSlotCount(); attempt++ {
+		slot, err := h.selectSlot(c, bodyBytes, failedSlots)
+		if err != nil {
+			break
+		}
+		failedSlots[fmt.Sprintf("%d:%s", slot.ChannelIndex, slot.APIKey)] = true
+
+		resp, err := h.forward(c, slot, bytes.NewReader(bodyBytes))
+		if err != nil {
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden {
+			resp.Body.Close()
+			continue
+		}
+		h.respond(c, slot, resp, false)
+		return
+	}
+
+	writeGeminiError(c, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("%s not found", name))
+}
+
+// handleCreate 处理资源创建（cachedContents.create）
+// 请求引用了已知文件时路由到文件所在槽位，否则按常规调度并在槽位间故障转移
+func (h *ResourceHandler) handleCreate(c *gin.Context) {
+	bodyBytes, err := common.ReadRequestBody(c, h.envCfg.MaxRequestBodySize)
+	if err != nil {
+		return
+	}
+
+	for _, ref := range geminiResourceRefs(gjson.ParseBytes(bodyBytes)) {
+		if slot, ok := h.channelScheduler.GetGeminiResourceSlot(ref); ok {
+			h.forwardAndRespond(c, slot, bytes.NewReader(bodyBytes), false)
+			return
+		}
+	}
+	h.forwardWithFailover(c, bodyBytes)
+}
+
+// forwardWithFailover 缓冲请求体并在 Gemini 槽位间故障转移
+func (h *ResourceHandler) forwardWithFailover(c *gin.Context, bodyBytes []byte) {
+	if bodyBytes == nil {
+		var err error
+		if bodyBytes, err = common.ReadRequestBody(c, h.envCfg.MaxRequestBodySize); err != nil {
+			return
+		}
+	}
+
+	failedSlots := make(map[string]bool)
+	var lastFailoverError *common.FailoverError
+	for attempt := 0; attempt < h.channelScheduler.GetActiveGeminiSlotCount(); attempt++ {
+		slot, err := h.selectSlot(c, bodyBytes, failedSlots)
+		if err != nil {
+			break
+		}
+		failedSlots[fmt.Sprintf("%d:%s", slot.ChannelIndex, slot.APIKey)] = true
+
+		resp, err := h.forward(c, slot, bytes.NewReader(bodyBytes))
+		if err != nil {
+			if common.IsClientCanceled(err) {
+				return
+			}
+			log.Printf("[Gemini-Resource] 警告: 槽位 [%d] %s 请求失败: %v", slot.ChannelIndex, slot.Upstream.Name, err)
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			respBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			respBody = utils.DecompressGzipIfNeeded(resp, respBody)
+			if shouldFailover, _ := common.ShouldRetryWithNextKey(resp.StatusCode, respBody, h.cfgManager.GetFuzzyModeEnabled()); shouldFailover {
+				log.Printf("[Gemini-Resource] 警告: 槽位 [%d] %s 返回 %d，尝试下一个槽位", slot.ChannelIndex, slot.Upstream.Name, resp.StatusCode)
+				lastFailoverError = &common.FailoverError{Status: resp.StatusCode, Body: respBody}
+				continue
+			}
+			c.Data(resp.StatusCode, "application/json", respBody)
+			return
+		}
+		h.respond(c, slot, resp, false)
+		return
+	}
+
+	if lastFailoverError != nil {
+		c.Data(lastFailoverError.Status, "application/json", lastFailoverError.Body)
+		return
+	}
+	writeGeminiError(c, http.StatusServiceUnavailable, "UNAVAILABLE", "No Gemini upstream supports the Files API")
+}
+
+// handleList 汇总所有 Gemini 槽位的资源列表（files.list / cachedContents.list）
+// 各 Key 的分页游标互不兼容，汇总结果只包含每个槽位的第一页
+func (h *ResourceHandler) handleList(c *gin.Context) {
+	field := "files"
+	if strings.Contains(c.Request.URL.Path, "cachedContents") {
+		field = "cachedContents"
+	}
+
+	merged := "[]"
+	seenKeys := make(map[string]bool)
+	seenNames := make(map[string]bool)
+	failedSlots := make(map[string]bool)
+	listed := 0
+	for attempt := 0; attempt < h.channelScheduler.GetActiveGeminiSlotCount(); attempt++ {
+		slot, err := h.selectSlot(c, nil, failedSlots)
+		if err != nil {
+			break
+		}
+		failedSlots[fmt.Sprintf("%d:%s", slot.ChannelIndex, slot.APIKey)] = true
+		// 同一 Key 配置在多个渠道时只查询一次
+		if seenKeys[slot.APIKey] {
+			continue
+		}
+		seenKeys[slot.APIKey] = true
+
+		resp, err := h.forward(c, slot, nil)
+		if err != nil {
+			continue
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			log.Printf("[Gemini-Resource] 警告: 槽位 [%d] %s 列表查询失败 (状态: %d)", slot.ChannelIndex, slot.Upstream.Name, resp.StatusCode)
+			continue
+		}
+		listed++
+		gjson.GetBytes(respBody, field).ForEach(func(_, item gjson.Result) bool {
+			name := item.Get("name").String()
+			if name == "" || seenNames[name] {
+				return true
+			}
+			seenNames[name] = true
+			h.recordResource(name, item, slot)
+			merged, _ = sjson.SetRaw(merged, "-1", item.Raw)
+			return true
+		})
+	}
+
+	if listed == 0 {
+		writeGeminiError(c, http.StatusServiceUnavailable, "UNAVAILABLE", "No Gemini upstream supports the Files API")
+		return
+	}
+	out, _ := sjson.SetRaw("{}", field, merged)
+	c.Data(http.StatusOK, "application/json", []byte(out))
+}
+
+// selectSlot 选择 Gemini 原生上游槽位（跳过需要协议转换的渠道）
+// 资源管理请求（文件、上下文缓存）不产生生成负载，不受并发上限约束也不排队
+func (h *ResourceHandler) selectSlot(c *gin.Context, bodyBytes []byte, failedSlots map[string]bool) (*scheduler.SlotSelectionResult, error) {
+	if failedSlots == nil {
+		failedSlots = make(map[string]bool)
+	}
+	userID := common.ExtractConversationID(c, bodyBytes)
+	for attempt := 0; attempt <= h.channelScheduler.GetActiveGeminiSlotCount(); attempt++ {
+		slot, err := h.channelScheduler.SelectGeminiSlotUncounted(c.Request.Context(), userID, failedSlots)
+		if err != nil {
+			return nil, err
+		}
+		if isGeminiNativeUpstream(slot.Upstream) {
+			return slot, nil
+		}
+		failedSlots[fmt.Sprintf("%d:%s", slot.ChannelIndex, slot.APIKey)] = true
+	}
+	return nil, fmt.Errorf("没有可用的 Gemini 原生渠道")
+}
+
+// forwardAndRespond 转发到指定槽位并写回响应（不重试）
+func (h *ResourceHandler) forwardAndRespond(c *gin.Context, slot *scheduler.SlotSelectionResult, body io.Reader, streamBody bool) {
+	resp, err := h.forward(c, slot, body)
+	if err != nil {
+		if common.IsClientCanceled(err) {
+			return
+		}
+		writeGeminiError(c, http.StatusBadGateway, "UNAVAILABLE", fmt.Sprintf("Upstream request failed: %v", err))
+		return
+	}
+	h.respond(c, slot, resp, streamBody)
+}
+
+// forward 构建并发送上游请求：保留原始路径与查询参数，替换认证信息
+func (h *ResourceHandler) forward(c *gin.Context, slot *scheduler.SlotSelectionResult, body io.Reader) (*http.Response, error) {
+	query := c.Request.URL.Query()
+	query.Del("key")
+	targetURL := strings.TrimRight(slot.Upstream.GetAllBaseURLs()[0], "/") + c.Request.URL.Path
+	if encoded := query.Encode(); encoded != "" {
+		targetURL += "?" + encoded
+	}
+
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body == c.Request.Body {
+		req.ContentLength = c.Request.ContentLength
+	}
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	utils.SetGeminiAuthenticationHeader(req.Header, slot.APIKey)
+
+	if h.envCfg.ShouldLog("info") {
+		log.Printf("[Gemini-Resource] %s %s -> 渠道 [%d] %s key=%s", c.Request.Method, c.Request.URL.Path, slot.ChannelIndex, slot.Upstream.Name, utils.MaskAPIKey(slot.APIKey))
+	}
+	// 上传与下载可能耗时较长，使用无超时的流式客户端
+	return common.SendRequest(req, slot.Upstream, h.envCfg, true)
+}
+
+// respond 写回上游响应，并记录响应中出现的资源归属
+func (h *ResourceHandler) respond(c *gin.Context, slot *scheduler.SlotSelectionResult, resp *http.Response, streamBody bool) {
+	defer resp.Body.Close()
+
+	if uploadURL := resp.Header.Get("X-Goog-Upload-URL"); uploadURL != "" {
+		resp.Header.Set("X-Goog-Upload-URL", h.rewriteUploadURL(c, slot, uploadURL))
+	}
+
+	if streamBody && resp.Header.Get("Content-Type") != "" && !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		utils.ForwardResponseHeaders(resp.Header, c.Writer)
+		c.Status(resp.StatusCode)
+		_, _ = io.Copy(c.Writer, resp.Body)
+		return
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeGeminiError(c, http.StatusBadGateway, "UNAVAILABLE", "Failed to read upstream response")
+		return
+	}
+	respBody = utils.DecompressGzipIfNeeded(resp, respBody)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		h.recordResponseResources(c, slot, respBody)
+	}
+
+	utils.ForwardResponseHeaders(resp.Header, c.Writer)
+	c.Writer.Header().Del("Content-Encoding")
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+}
+
+// recordResponseResources 根据响应记录或移除资源归属
+func (h *ResourceHandler) recordResponseResources(c *gin.Context, slot *scheduler.SlotSelectionResult, respBody []byte) {
+	name := geminiResourceName(c.Request.URL.Path)
+	if c.Request.Method == http.MethodDelete {
+		if name != "" {
+			h.channelScheduler.RemoveGeminiResourceOwner(name)
+		}
+		return
+	}
+
+	root := gjson.ParseBytes(respBody)
+	// 上传完成：{"file": {...}}
+	if file := root.Get("file"); file.Get("name").Exists() {
+		h.recordResource(file.Get("name").String(), file, slot)
+		if uploadID := c.Query("upload_id"); uploadID != "" {
+			h.channelScheduler.RemoveGeminiResourceOwner(geminiUploadSessionPrefix + uploadID)
+		}
+		return
+	}
+	if resourceName := root.Get("name").String(); strings.HasPrefix(resourceName, "files/") || strings.HasPrefix(resourceName, "cachedContents/") {
+		h.recordResource(resourceName, root, slot)
+	}
+}
+
+// recordResource 记录资源归属，TTL 取资源自身的过期时间
+func (h *ResourceHandler) recordResource(name string, resource gjson.Result, slot *scheduler.SlotSelectionResult) {
+	var ttl time.Duration
+	for _, field := range []string{"expirationTime", "expireTime"} {
+		if expires, err := time.Parse(time.RFC3339Nano, resource.Get(field).String()); err == nil {
+			ttl = time.Until(expires)
+			break
+		}
+	}
+	if ttl < 0 {
+		return
+	}
+	h.channelScheduler.SetGeminiResourceOwner(name, slot.ChannelIndex, slot.APIKey, ttl)
+}
+
+// rewriteUploadURL 将上游的可续传上传地址改写为代理地址，并记录上传会话归属
+func (h *ResourceHandler) rewriteUploadURL(c *gin.Context, slot *scheduler.SlotSelectionResult, uploadURL string) string {
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return uploadURL
+	}
+	uploadID := u.Query().Get("upload_id")
+	if uploadID == "" {
+		return uploadURL
+	}
+	h.channelScheduler.SetGeminiResourceOwner(geminiUploadSessionPrefix+uploadID, slot.ChannelIndex, slot.APIKey, 0)
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
+		host = forwardedHost
+	}
+	return fmt.Sprintf("%s://%s/upload/v1beta/files?%s", scheme, host, u.RawQuery)
+}
+
+// geminiResourceSlot 返回生成请求引用的文件或上下文缓存所在的槽位，未引用已知资源时返回 nil
+func geminiResourceSlot(channelScheduler *scheduler.ChannelScheduler, bodyBytes []byte) *scheduler.SlotSelectionResult {
+	for _, ref := range geminiResourceRefs(gjson.ParseBytes(bodyBytes)) {
+		if slot, ok := channelScheduler.GetGeminiResourceSlot(ref); ok {
+			return slot
+		}
+	}
+	return nil
+}
+
+// geminiResourceRefs 提取请求体中引用的资源名（cachedContent 优先，其次为 fileData.fileUri）
+func geminiResourceRefs(body gjson.Result) []string {
+	var refs []string
+	if cached := body.Get("cachedContent").String(); cached != "" {
+		refs = append(refs, cached)
+	}
+	body.Get("contents").ForEach(func(_, content gjson.Result) bool {
+		content.Get("parts").ForEach(func(_, part gjson.Result) bool {
+			if name := geminiFileNameFromURI(part.Get("fileData.fileUri").String()); name != "" {
+				refs = append(refs, name)
+			}
+			return true
+		})
+		return true
+	})
+	return refs
+}
+
+// geminiFileNameFromURI 从文件 URI 提取资源名
+// 输入: "https://generativelanguage.googleapis.com/v1beta/files/abc-123" 或 "files/abc-123"
+// 输出: "files/abc-123"
+func geminiFileNameFromURI(uri string) string {
+	idx := strings.LastIndex(uri, "files/")
+	if idx < 0 || (idx > 0 && uri[idx-1] != '/') {
+		return ""
+	}
+	id := uri[idx+len("files/"):]
+	if end := strings.IndexAny(id, "/?#:"); end >= 0 {
+		id = id[:end]
+	}
+	if id == "" {
+		return ""
+	}
+	return "files/" + id
+}
+
+// geminiResourceName 从请求路径提取资源名
+// 输入: "/v1beta/files/abc:download" 输出: "files/abc"
+// 输入: "/v1beta/cachedContents/xyz" 输出: "cachedContents/xyz"
+func geminiResourceName(path string) string {
+	for _, collection := range []string{"files", "cachedContents"} {
+		prefix := "/v1beta/" + collection + "/"
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		id := strings.TrimPrefix(path, prefix)
+		if end := strings.IndexAny(id, "/:"); end >= 0 {
+			id = id[:end]
+		}
+		if id == "" {
+			return ""
+		}
+		return collection + "/" + id
+	}
+	return ""
+}
+
+// isGeminiNativeUpstream 判断渠道是否为 Gemini 原生协议（Files API 仅原生上游可用）
+func isGeminiNativeUpstream(upstream *config.UpstreamConfig) bool {
+	return upstream != nil && (upstream.ServiceType == "gemini" || upstream.ServiceType == "")
+}
+
+// writeGeminiError 写出 Gemini 格式错误
+func writeGeminiError(c *gin.Context, code int, status, message string) {
+	c.JSON(code, types.GeminiError{
+		Error: types.GeminiErrorDetail{
+			Code:    code,
+			Message: message,
+			Status:  status,
+		},
+	})
+}
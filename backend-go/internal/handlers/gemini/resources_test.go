@@ -0,0 +1,258 @@
+package gemini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiResourceName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1beta/files/abc-123", "files/abc-123"},
+		{"/v1beta/files/abc-123:download", "files/abc-123"},
+		{"/v1beta/cachedContents/xyz", "cachedContents/xyz"},
+		{"/v1beta/files", ""},
+		{"/v1beta/files/", ""},
+		{"/upload/v1beta/files", ""},
+	}
+	for _, tt := range tests {
+		if got := geminiResourceName(tt.path); got != tt.want {
+			t.Errorf("geminiResourceName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGeminiFileNameFromURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"https://generativelanguage.googleapis.com/v1beta/files/abc-123", "files/abc-123"},
+		{"files/abc-123", "files/abc-123"},
+		{"https://relay.example.com/gemini/v1beta/files/abc?alt=media", "files/abc"},
+		{"gs://bucket/myfiles/abc", ""},
+		{"https://example.com/doc.pdf", ""},
+	}
+	for _, tt := range tests {
+		if got := geminiFileNameFromURI(tt.uri); got != tt.want {
+			t.Errorf("geminiFileNameFromURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+// fakeGeminiFilesUpstream 模拟按 Key 隔离文件与缓存的 Gemini 上游：资源只能被创建它的 Key 访问
+// 返回的计数器记录被路由到错误 Key 的请求数
+func fakeGeminiFilesUpstream(t *testing.T) (*httptest.Server, *int64) {
+	t.Helper()
+	var mu sync.Mutex
+	var misrouted int64
+	owners := map[string]string{}
+	ownedBy := func(name, key string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if owners[name] != key {
+			misrouted++
+			return false
+		}
+		return true
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-goog-api-key")
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/upload/v1beta/files" && r.URL.Query().Get("upload_id") == "":
+			w.Header().Set("X-Goog-Upload-URL", "https://upload.googleapis.example/upload/v1beta/files?upload_id=up-"+key+"&upload_protocol=resumable")
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/upload/v1beta/files":
+			if r.URL.Query().Get("upload_id") != "up-"+key {
+				mu.Lock()
+				misrouted++
+				mu.Unlock()
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			mu.Lock()
+			owners["files/f-"+key] = key
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"file":{"name":"files/f-` + key + `","uri":"https://generativelanguage.googleapis.com/v1beta/files/f-` + key + `"}}`))
+		case r.URL.Path == "/v1beta/files":
+			out := `{"files":[`
+			mu.Lock()
+			owned := owners["files/f-"+key] == key
+			mu.Unlock()
+			if owned {
+				out += `{"name":"files/f-` + key + `"}`
+			}
+			_, _ = w.Write([]byte(out + `]}`))
+		case strings.HasPrefix(r.URL.Path, "/v1beta/files/"):
+			if !ownedBy(strings.TrimPrefix(r.URL.Path, "/v1beta/"), key) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"` + strings.TrimPrefix(r.URL.Path, "/v1beta/") + `"}`))
+		case r.URL.Path == "/v1beta/cachedContents" && r.Method == http.MethodPost:
+			fileName := geminiFileNameFromURI(gjson.GetBytes(body, "contents.0.parts.0.fileData.fileUri").String())
+			if !ownedBy(fileName, key) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			mu.Lock()
+			owners["cachedContents/c-"+key] = key
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"name":"cachedContents/c-` + key + `","expireTime":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+		case strings.HasPrefix(r.URL.Path, "/v1beta/cachedContents/"):
+			if !ownedBy(strings.TrimPrefix(r.URL.Path, "/v1beta/"), key) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case strings.HasPrefix(r.URL.Path, "/v1beta/models/"):
+			if !ownedBy(gjson.GetBytes(body, "cachedContent").String(), key) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"error":{"code":403,"message":"permission denied","status":"PERMISSION_DENIED"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})), &misrouted
+}
+
+func TestGeminiResourceHandler_KeepsKeyAffinity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream, misrouted := fakeGeminiFilesUpstream(t)
+	defer upstream.Close()
+
+	cfg := config.Config{
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "g0", BaseURL: upstream.URL, APIKeys: []string{"key-a", "key-b", "key-c"}, ServiceType: "gemini", Status: "active", Priority: 1},
+		},
+		GeminiLoadBalance: "failover",
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	resourceHandler := NewResourceHandler(envCfg, cfgManager, sch)
+	r := gin.New()
+	r.POST("/v1beta/models/*modelAction", NewHandler(envCfg, cfgManager, sch, nil, nil, nil))
+	r.GET("/v1beta/files", resourceHandler)
+	r.GET("/v1beta/files/*name", resourceHandler)
+	r.POST("/upload/v1beta/files", resourceHandler)
+	r.POST("/v1beta/cachedContents", resourceHandler)
+	r.DELETE("/v1beta/cachedContents/*name", resourceHandler)
+
+	do := func(method, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("x-goog-api-key", envCfg.ProxyAccessKey)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 1. 可续传上传：上传地址改写为代理地址
+	w := do(http.MethodPost, "/upload/v1beta/files", `{"file":{"display_name":"doc"}}`, map[string]string{
+		"X-Goog-Upload-Protocol": "resumable",
+		"X-Goog-Upload-Command":  "start",
+	})
+	uploadURL := w.Header().Get("X-Goog-Upload-URL")
+	if w.Code != http.StatusOK || !strings.HasPrefix(uploadURL, "http://example.com/upload/v1beta/files?") {
+		t.Fatalf("start: status=%d uploadURL=%q", w.Code, uploadURL)
+	}
+	u, _ := url.Parse(uploadURL)
+
+	// 2. 上传分片并完成：路由回发起上传的 Key
+	w = do(http.MethodPost, u.RequestURI(), "%PDF-1.4", map[string]string{"X-Goog-Upload-Command": "upload, finalize"})
+	fileName := gjson.Get(w.Body.String(), "file.name").String()
+	fileURI := gjson.Get(w.Body.String(), "file.uri").String()
+	if w.Code != http.StatusOK || fileName == "" {
+		t.Fatalf("finalize: status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// 3. 文件查询、缓存创建、生成请求均路由到文件所在 Key
+	// 不同会话按 rendezvous 哈希会分散到不同 Key，资源亲和应优先于会话调度
+	for i := 0; i < 5; i++ {
+		if w := do(http.MethodGet, "/v1beta/"+fileName, "", map[string]string{"Session_id": fmt.Sprintf("session-%d", i)}); w.Code != http.StatusOK {
+			t.Fatalf("get file: status=%d body=%s", w.Code, w.Body.String())
+		}
+	}
+	w = do(http.MethodPost, "/v1beta/cachedContents", `{"model":"models/gemini-2.0-flash","contents":[{"role":"user","parts":[{"fileData":{"mimeType":"application/pdf","fileUri":"`+fileURI+`"}}]}]}`, map[string]string{"Session_id": "session-cache"})
+	cacheName := gjson.Get(w.Body.String(), "name").String()
+	if w.Code != http.StatusOK || cacheName == "" {
+		t.Fatalf("create cache: status=%d body=%s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 5; i++ {
+		w := do(http.MethodPost, "/v1beta/models/gemini-2.0-flash:generateContent", `{"cachedContent":"`+cacheName+`","contents":[{"role":"user","parts":[{"text":"summarize"}]}]}`, map[string]string{"Session_id": fmt.Sprintf("session-%d", i)})
+		if w.Code != http.StatusOK {
+			t.Fatalf("generate: status=%d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	// 4. 列表汇总所有 Key
+	w = do(http.MethodGet, "/v1beta/files", "", nil)
+	if w.Code != http.StatusOK || gjson.Get(w.Body.String(), "files.#").Int() != 1 || gjson.Get(w.Body.String(), "files.0.name").String() != fileName {
+		t.Fatalf("list: status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// 5. 删除后移除归属记录
+	if w := do(http.MethodDelete, "/v1beta/"+cacheName, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("delete cache: status=%d body=%s", w.Code, w.Body.String())
+	}
+	if _, ok := sch.GetGeminiResourceSlot(cacheName); ok {
+		t.Fatalf("cache owner should be removed after delete")
+	}
+	if *misrouted != 0 {
+		t.Fatalf("%d requests were routed to a key that does not own the resource", *misrouted)
+	}
+}
+
+func TestGeminiResourceHandler_UnknownUploadSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Config{
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "g0", BaseURL: "http://example.invalid", APIKeys: []string{"k1"}, ServiceType: "gemini", Status: "active", Priority: 1},
+		},
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/upload/v1beta/files", NewResourceHandler(envCfg, cfgManager, sch))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload/v1beta/files?upload_id=missing", bytes.NewBufferString("data"))
+	req.Header.Set("x-goog-api-key", envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "error.status").String() != "NOT_FOUND" {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+}
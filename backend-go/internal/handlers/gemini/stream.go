@@ -10,8 +10,10 @@
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // handleStreamSuccess 处理流式响应
@@ -114,6 +116,10 @@ func streamClaudeToGemini(
 
 	var totalUsage *types.Usage
 	var currentText strings.Builder
+	// 模拟结构化输出的工具调用还原为文本增量
+	var structured converters.StructuredOutputStream
+	// 服务端工具：代码执行结果到达时输出 part，联网搜索与引用在最终块中输出 groundingMetadata
+	var serverTools converters.ClaudeServerToolStream
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -127,11 +133,27 @@ func streamClaudeToGemini(
 			break
 		}
 
+		rewritten := structured.RewriteClaudeEvent([]byte(jsonData))
 		var event map[string]interface{}
-		if err := json.Unmarshal([]byte(jsonData), &event); err != nil {
+		if err := json.Unmarshal(rewritten, &event); err != nil {
 			continue
 		}
 
+		if call := serverTools.Observe(gjson.ParseBytes(rewritten)); call != nil && call.Kind == converters.ServerToolCodeExecution {
+			geminiChunk := types.GeminiStreamChunk{
+				Candidates: []types.GeminiCandidate{
+					{
+						Content: &types.GeminiContent{Parts: call.GeminiParts(), Role: "model"},
+					},
+				},
+			}
+			chunkBytes, _ := json.Marshal(geminiChunk)
+			fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunkBytes))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
 		eventType, _ := event["type"].(string)
 
 		switch eventType {
@@ -142,6 +164,31 @@ func streamClaudeToGemini(
 				continue
 			}
 			deltaType, _ := delta["type"].(string)
+			if deltaType == "thinking_delta" {
+				// 思考增量 → thought part
+				thinking, _ := delta["thinking"].(string)
+				if thinking == "" {
+					continue
+				}
+				geminiChunk := types.GeminiStreamChunk{
+					Candidates: []types.GeminiCandidate{
+						{
+							Content: &types.GeminiContent{
+								Parts: []types.GeminiPart{
+									{Text: thinking, Thought: true},
+								},
+								Role: "model",
+							},
+						},
+					},
+				}
+
+				chunkBytes, _ := json.Marshal(geminiChunk)
+				fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunkBytes))
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
 			if deltaType == "text_delta" {
 				text, _ := delta["text"].(string)
 				currentText.WriteString(text)
@@ -187,7 +234,8 @@ func streamClaudeToGemini(
 				geminiChunk := types.GeminiStreamChunk{
 					Candidates: []types.GeminiCandidate{
 						{
-							FinishReason: "STOP",
+							FinishReason:      "STOP",
+							GroundingMetadata: converters.GeminiGrounding(serverTools.Calls, serverTools.Citations, currentText.String()),
 						},
 					},
 					UsageMetadata: &types.GeminiUsageMetadata{
@@ -303,6 +351,28 @@ func streamOpenAIToGemini(
 			continue
 		}
 
+		// 推理内容 → thought part
+		if reasoning, _ := delta["reasoning_content"].(string); reasoning != "" {
+			geminiChunk := types.GeminiStreamChunk{
+				Candidates: []types.GeminiCandidate{
+					{
+						Content: &types.GeminiContent{
+							Parts: []types.GeminiPart{
+								{Text: reasoning, Thought: true},
+							},
+							Role: "model",
+						},
+					},
+				},
+			}
+
+			chunkBytes, _ := json.Marshal(geminiChunk)
+			fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunkBytes))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
 		// 提取文本内容
 		content, _ := delta["content"].(string)
 		if content != "" {
@@ -37,6 +37,8 @@ func GetKeyCircuitLog(store metrics.KeyCircuitLogStore, cfgManager *config.Confi
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -88,6 +90,8 @@ func ResetKeyCircuitState(sch *scheduler.ChannelScheduler, cfgManager *config.Co
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -118,6 +122,10 @@ func ResetKeyCircuitState(sch *scheduler.ChannelScheduler, cfgManager *config.Co
 			for _, baseURL := range baseURLs {
 				sch.GetGeminiMetricsManager().ResetKey(baseURL, apiKey)
 			}
+		case "embeddings":
+			for _, baseURL := range baseURLs {
+				sch.GetEmbeddingsMetricsManager().ResetKey(baseURL, apiKey)
+			}
 		}
 
 		cfgManager.ClearFailedKey(apiKey)
@@ -156,6 +164,8 @@ func ResetKeyCircuitStatus(sch *scheduler.ChannelScheduler, cfgManager *config.C
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -186,6 +196,10 @@ func ResetKeyCircuitStatus(sch *scheduler.ChannelScheduler, cfgManager *config.C
 			for _, baseURL := range baseURLs {
 				sch.GetGeminiMetricsManager().ResetKeyState(baseURL, apiKey)
 			}
+		case "embeddings":
+			for _, baseURL := range baseURLs {
+				sch.GetEmbeddingsMetricsManager().ResetKeyState(baseURL, apiKey)
+			}
 		}
 
 		cfgManager.ClearFailedKey(apiKey)
@@ -214,6 +228,8 @@ func ResetAllKeysCircuitStatus(sch *scheduler.ChannelScheduler, cfgManager *conf
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -245,6 +261,10 @@ func ResetAllKeysCircuitStatus(sch *scheduler.ChannelScheduler, cfgManager *conf
 				for _, baseURL := range baseURLs {
 					sch.GetGeminiMetricsManager().ResetKeyState(baseURL, apiKey)
 				}
+			case "embeddings":
+				for _, baseURL := range baseURLs {
+					sch.GetEmbeddingsMetricsManager().ResetKeyState(baseURL, apiKey)
+				}
 			}
 
 			cfgManager.ClearFailedKey(apiKey)
@@ -276,6 +296,8 @@ func ResetAllKeysCircuitState(sch *scheduler.ChannelScheduler, cfgManager *confi
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -307,6 +329,10 @@ func ResetAllKeysCircuitState(sch *scheduler.ChannelScheduler, cfgManager *confi
 				for _, baseURL := range baseURLs {
 					sch.GetGeminiMetricsManager().ResetKey(baseURL, apiKey)
 				}
+			case "embeddings":
+				for _, baseURL := range baseURLs {
+					sch.GetEmbeddingsMetricsManager().ResetKey(baseURL, apiKey)
+				}
 			}
 
 			cfgManager.ClearFailedKey(apiKey)
@@ -76,7 +76,7 @@ func createTestScheduler(t *testing.T, cfgManager *config.ConfigManager) (*sched
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 	return sch, func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
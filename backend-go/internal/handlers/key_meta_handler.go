@@ -22,9 +22,10 @@ func PatchAPIKeyMeta(cfgManager *config.ConfigManager, apiType string) gin.Handl
 		}
 
 		var req struct {
-			Disabled *bool `json:"disabled"`
+			Disabled       *bool `json:"disabled"`
+			MaxConcurrency *int  `json:"maxConcurrency"`
 		}
-		if err := c.ShouldBindJSON(&req); err != nil || req.Disabled == nil {
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Disabled == nil && req.MaxConcurrency == nil) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			return
 		}
@@ -38,6 +39,8 @@ func PatchAPIKeyMeta(cfgManager *config.ConfigManager, apiType string) gin.Handl
 			upstreams = cfg.ResponsesUpstream
 		case "gemini":
 			upstreams = cfg.GeminiUpstream
+		case "embeddings":
+			upstreams = cfg.EmbeddingsUpstream
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api type"})
 			return
@@ -52,9 +55,17 @@ func PatchAPIKeyMeta(cfgManager *config.ConfigManager, apiType string) gin.Handl
 			return
 		}
 
-		if err := cfgManager.SetAPIKeyDisabled(apiType, channelIndex, keyIndex, *req.Disabled); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save config"})
-			return
+		if req.Disabled != nil {
+			if err := cfgManager.SetAPIKeyDisabled(apiType, channelIndex, keyIndex, *req.Disabled); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save config"})
+				return
+			}
+		}
+		if req.MaxConcurrency != nil {
+			if err := cfgManager.SetAPIKeyMaxConcurrency(apiType, channelIndex, keyIndex, *req.MaxConcurrency); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save config"})
+				return
+			}
 		}
 
 		c.JSON(http.StatusOK, gin.H{"success": true})
@@ -81,3 +81,56 @@ func TestPatchAPIKeyMeta_InvalidKeyIndexReturns404(t *testing.T) {
 		t.Fatalf("status=%d, want %d", w.Code, http.StatusNotFound)
 	}
 }
+
+func TestPatchAPIKeyMeta_MaxConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "m0", ServiceType: "claude", BaseURL: "https://m0.example.com", APIKeys: []string{"k1", "k2"}, Status: "active"},
+		},
+		LoadBalance:          "failover",
+		ResponsesLoadBalance: "failover",
+		GeminiLoadBalance:    "failover",
+		FuzzyModeEnabled:     true,
+	}
+	cm, _ := newTestConfigManager(t, cfg)
+
+	r := gin.New()
+	r.PATCH("/channels/:id/keys/index/:keyIndex/meta", PatchAPIKeyMeta(cm, "messages"))
+
+	patch := func(body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/channels/0/keys/index/1/meta", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := patch(`{"maxConcurrency":2}`); code != http.StatusOK {
+		t.Fatalf("status=%d, want %d", code, http.StatusOK)
+	}
+	// 修改 disabled 不影响已设置的并发上限
+	if code := patch(`{"disabled":true}`); code != http.StatusOK {
+		t.Fatalf("status=%d, want %d", code, http.StatusOK)
+	}
+	upstream := cm.GetConfig().Upstream[0]
+	if meta := upstream.APIKeyMeta["k2"]; meta.MaxConcurrency != 2 || !meta.Disabled {
+		t.Fatalf("unexpected k2 meta: %+v", meta)
+	}
+	if got := upstream.GetKeyMaxConcurrency("k2"); got != 2 || !upstream.HasConcurrencyLimit() {
+		t.Fatalf("GetKeyMaxConcurrency=%d, HasConcurrencyLimit=%v", got, upstream.HasConcurrencyLimit())
+	}
+
+	// 全部恢复默认值后移除条目
+	if code := patch(`{"disabled":false,"maxConcurrency":0}`); code != http.StatusOK {
+		t.Fatalf("status=%d, want %d", code, http.StatusOK)
+	}
+	if meta := cm.GetConfig().Upstream[0].APIKeyMeta; meta != nil {
+		t.Fatalf("expected key meta removed, got %v", meta)
+	}
+
+	if code := patch(`{}`); code != http.StatusBadRequest {
+		t.Fatalf("empty body status=%d, want %d", code, http.StatusBadRequest)
+	}
+}
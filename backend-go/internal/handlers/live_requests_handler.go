@@ -3,6 +3,7 @@
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/monitor"
 	"github.com/gin-gonic/gin"
@@ -38,9 +39,12 @@ func (h *LiveRequestsHandler) GetLiveRequests(c *gin.Context) {
 		requests = h.manager.GetRequestsByAPIType(apiType)
 	}
 
+	queueDepth, maxQueueWaitMs := monitor.QueueStats(requests, time.Now())
 	c.JSON(http.StatusOK, monitor.LiveRequestsResponse{
-		Requests: requests,
-		Count:    len(requests),
+		Requests:       requests,
+		Count:          len(requests),
+		QueueDepth:     queueDepth,
+		MaxQueueWaitMs: maxQueueWaitMs,
 	})
 }
 
@@ -57,7 +61,7 @@ func apiTypeFromAdminLivePath(path string) string {
 
 	apiType := parts[1]
 	switch apiType {
-	case "messages", "responses", "gemini":
+	case "messages", "responses", "gemini", "embeddings":
 		return apiType
 	default:
 		return ""
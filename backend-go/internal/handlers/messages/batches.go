@@ -0,0 +1,184 @@
+package messages
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/BenedictKing/claude-proxy/internal/batch"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/common"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// batchError 返回 Anthropic 格式的错误响应
+func batchError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"type": "error",
+		"error": gin.H{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
+
+// batchLookupError 将批次管理器错误映射为 HTTP 响应
+func batchLookupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, batch.ErrNotFound):
+		batchError(c, http.StatusNotFound, "not_found_error", "message batch not found")
+	case errors.Is(err, batch.ErrNotEnded):
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "message batch is still processing")
+	default:
+		batchError(c, http.StatusInternalServerError, "api_error", err.Error())
+	}
+}
+
+// batchAuth 批次接口认证，失败时已写出响应
+func batchAuth(c *gin.Context, envCfg *config.EnvConfig) bool {
+	middleware.ProxyAuthMiddleware(envCfg)(c)
+	return !c.IsAborted()
+}
+
+// CreateBatchHandler 处理 POST /v1/messages/batches
+func CreateBatchHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		bodyBytes, err := common.ReadRequestBody(c, envCfg.MaxRequestBodySize)
+		if err != nil {
+			return
+		}
+
+		var req struct {
+			Requests []batch.Request `json:"requests"`
+		}
+		if err := json.Unmarshal(bodyBytes, &req); err != nil {
+			batchError(c, http.StatusBadRequest, "invalid_request_error", "Invalid JSON")
+			return
+		}
+
+		b, err := manager.Create(req.Requests, c.Request.Header)
+		if err != nil {
+			var validationErr *batch.ValidationError
+			if errors.As(err, &validationErr) {
+				batchError(c, http.StatusBadRequest, "invalid_request_error", validationErr.Message)
+				return
+			}
+			log.Printf("[Batch-Create] 创建批次失败: %v", err)
+			batchError(c, http.StatusInternalServerError, "api_error", "failed to create message batch")
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+// GetBatchHandler 处理 GET /v1/messages/batches/:id
+func GetBatchHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		b, err := manager.Get(c.Param("id"))
+		if err != nil {
+			batchLookupError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+// ListBatchesHandler 处理 GET /v1/messages/batches（按创建时间倒序分页）
+func ListBatchesHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		limit := 20
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 1000 {
+				batchError(c, http.StatusBadRequest, "invalid_request_error", "limit: must be between 1 and 1000")
+				return
+			}
+			limit = n
+		}
+
+		data, hasMore := manager.List(limit, c.Query("before_id"), c.Query("after_id"))
+		resp := gin.H{
+			"data":     data,
+			"has_more": hasMore,
+			"first_id": nil,
+			"last_id":  nil,
+		}
+		if len(data) > 0 {
+			resp["first_id"] = data[0].ID
+			resp["last_id"] = data[len(data)-1].ID
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// CancelBatchHandler 处理 POST /v1/messages/batches/:id/cancel
+func CancelBatchHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		b, err := manager.Cancel(c.Param("id"))
+		if err != nil {
+			batchLookupError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+// DeleteBatchHandler 处理 DELETE /v1/messages/batches/:id（仅限已结束的批次）
+func DeleteBatchHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		id := c.Param("id")
+		if err := manager.Delete(id); err != nil {
+			batchLookupError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": id, "type": "message_batch_deleted"})
+	}
+}
+
+// BatchResultsHandler 处理 GET /v1/messages/batches/:id/results，以 JSONL 下载结果
+func BatchResultsHandler(envCfg *config.EnvConfig, manager *batch.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !batchAuth(c, envCfg) {
+			return
+		}
+
+		id := c.Param("id")
+		results, err := manager.OpenResults(id)
+		if err != nil {
+			batchLookupError(c, err)
+			return
+		}
+		defer results.Close()
+
+		c.Header("Content-Type", "application/x-jsonl")
+		c.Header("Content-Disposition", `attachment; filename="`+id+`.jsonl"`)
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, results); err != nil {
+			log.Printf("[Batch-Results] 警告: 发送批次 %s 结果失败: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package messages
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/batch"
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newBatchTestRouter(t *testing.T, upstreamURL string) (*gin.Engine, *batch.Manager, func()) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		Upstream: []config.UpstreamConfig{{
+			Name:        "c0",
+			BaseURL:     upstreamURL,
+			APIKeys:     []string{"k-bad", "k-good"},
+			ServiceType: "claude",
+			Status:      "active",
+		}},
+		LoadBalance:      "failover",
+		FuzzyModeEnabled: true,
+	})
+	sch, cleanupSch := createTestSchedulerWithMetricsConfig(t, cfgManager)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	messagesHandler := NewHandler(envCfg, cfgManager, sch, nil, nil, nil, nil, nil)
+
+	replay := gin.New()
+	replay.POST("/v1/messages", messagesHandler)
+	manager, err := batch.NewManager(t.TempDir(), 2, replay)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/v1/messages/batches", CreateBatchHandler(envCfg, manager))
+	r.GET("/v1/messages/batches", ListBatchesHandler(envCfg, manager))
+	r.GET("/v1/messages/batches/:id", GetBatchHandler(envCfg, manager))
+	r.DELETE("/v1/messages/batches/:id", DeleteBatchHandler(envCfg, manager))
+	r.POST("/v1/messages/batches/:id/cancel", CancelBatchHandler(envCfg, manager))
+	r.GET("/v1/messages/batches/:id/results", BatchResultsHandler(envCfg, manager))
+	return r, manager, func() {
+		manager.Wait()
+		cleanupSch()
+		cleanupCfg()
+	}
+}
+
+func doBatchRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatchHandlers_ReplayThroughMessagesFailover(t *testing.T) {
+	var calls atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.Contains(r.Header.Get("Authorization"), "k-bad") || r.Header.Get("x-api-key") == "k-bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid key"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_ok","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	r, manager, cleanup := newBatchTestRouter(t, upstream.URL)
+	defer cleanup()
+
+	w := doBatchRequest(r, http.MethodPost, "/v1/messages/batches",
+		`{"requests":[{"custom_id":"req-1","params":{"model":"claude-3","max_tokens":16,"stream":true,"messages":[{"role":"user","content":"hi"}]}}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create status=%d body=%s", w.Code, w.Body.String())
+	}
+	id := gjson.Get(w.Body.String(), "id").String()
+	if gjson.Get(w.Body.String(), "processing_status").String() != batch.StatusInProgress {
+		t.Fatalf("unexpected create response: %s", w.Body.String())
+	}
+
+	manager.Wait()
+
+	w = doBatchRequest(r, http.MethodGet, "/v1/messages/batches/"+id, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.Get(w.Body.String(), "processing_status").String() != batch.StatusEnded ||
+		gjson.Get(w.Body.String(), "request_counts.succeeded").Int() != 1 {
+		t.Fatalf("unexpected batch: %s", w.Body.String())
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("upstream calls=%d, want 2 (failover to second key)", calls.Load())
+	}
+
+	w = doBatchRequest(r, http.MethodGet, "/v1/messages/batches/"+id+"/results", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("results status=%d body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-jsonl" {
+		t.Fatalf("content-type=%q", ct)
+	}
+	line := strings.TrimSpace(w.Body.String())
+	if gjson.Get(line, "custom_id").String() != "req-1" ||
+		gjson.Get(line, "result.type").String() != batch.ResultSucceeded ||
+		gjson.Get(line, "result.message.id").String() != "msg_ok" {
+		t.Fatalf("unexpected results: %s", w.Body.String())
+	}
+
+	w = doBatchRequest(r, http.MethodGet, "/v1/messages/batches?limit=10", "")
+	if gjson.Get(w.Body.String(), "data.#").Int() != 1 || gjson.Get(w.Body.String(), "first_id").String() != id {
+		t.Fatalf("unexpected list: %s", w.Body.String())
+	}
+
+	w = doBatchRequest(r, http.MethodDelete, "/v1/messages/batches/"+id, "")
+	if w.Code != http.StatusOK || gjson.Get(w.Body.String(), "type").String() != "message_batch_deleted" {
+		t.Fatalf("delete status=%d body=%s", w.Code, w.Body.String())
+	}
+	w = doBatchRequest(r, http.MethodGet, "/v1/messages/batches/"+id, "")
+	if w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "error.type").String() != "not_found_error" {
+		t.Fatalf("get after delete status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestBatchHandlers_ValidationAndAuth(t *testing.T) {
+	r, _, cleanup := newBatchTestRouter(t, "http://127.0.0.1:1")
+	defer cleanup()
+
+	w := doBatchRequest(r, http.MethodPost, "/v1/messages/batches", `{"requests":[{"custom_id":"a","params":{}}]}`)
+	if w.Code != http.StatusBadRequest || gjson.Get(w.Body.String(), "error.type").String() != "invalid_request_error" {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doBatchRequest(r, http.MethodGet, "/v1/messages/batches?limit=0", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("limit=0 status=%d", w.Code)
+	}
+
+	w = doBatchRequest(r, http.MethodPost, "/v1/messages/batches/msgbatch_missing/cancel", "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("cancel missing status=%d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/messages/batches", nil)
+	unauth := httptest.NewRecorder()
+	r.ServeHTTP(unauth, req)
+	if unauth.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status=%d", unauth.Code)
+	}
+}
@@ -2,6 +2,7 @@
 package messages
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -36,11 +37,16 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
 				"modelMapping":       up.ModelMapping,
+				"supportedModels":    up.SupportedModels,
 				"latency":            nil,
 				"status":             status,
 				"priority":           priority,
+				"weight":             up.Weight,
+				"priceMultiplier":    up.PriceMultiplier,
+				"maxConcurrency":     up.MaxConcurrency,
 				"promotionUntil":     up.PromotionUntil,
 				"lowQuality":         up.LowQuality,
+				"apiVersion":         up.APIVersion,
 			}
 		}
 
@@ -61,7 +67,12 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddUpstream(upstream); err != nil {
-			c.JSON(500, gin.H{"error": "Failed to save config"})
+			var cfgErr *config.ConfigError
+			if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(500, gin.H{"error": "Failed to save config"})
+			}
 			return
 		}
 
@@ -90,8 +101,11 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 
 		shouldResetMetrics, err := cfgManager.UpdateUpstream(id, updates)
 		if err != nil {
+			var cfgErr *config.ConfigError
 			if strings.Contains(err.Error(), "无效的上游索引") {
 				c.JSON(404, gin.H{"error": "Upstream not found"})
+			} else if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
 			} else {
 				c.JSON(500, gin.H{"error": "Failed to save config"})
 			}
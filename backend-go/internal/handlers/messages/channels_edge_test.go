@@ -211,6 +211,19 @@ func TestMessagesChannelsHandlers_ErrorPaths(t *testing.T) {
 		}
 	})
 
+	t.Run("loadbalance weighted -> 200", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/loadbalance", bytes.NewBufferString(`{"strategy":"weighted"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		if got := cfgManager.GetConfig().LoadBalance; got != "weighted" {
+			t.Fatalf("loadBalance=%q, want weighted", got)
+		}
+	})
+
 	t.Run("reorder invalid json -> 400", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/channels/reorder", bytes.NewBufferString("{"))
 		req.Header.Set("Content-Type", "application/json")
@@ -294,3 +307,40 @@ func TestMessagesChannelsHandlers_ErrorPaths(t *testing.T) {
 		}
 	})
 }
+
+// TestMessagesChannelsHandlers_InvalidSupportedModelsReturns400 无效的 supportedModels 模式属于配置错误，返回 400
+func TestMessagesChannelsHandlers_InvalidSupportedModelsReturns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "c0", BaseURL: "http://example.invalid", APIKeys: []string{"k1"}, ServiceType: "claude", Status: "active"},
+		},
+	})
+	defer cleanupCfg()
+
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	r := gin.New()
+	r.POST("/channels", AddUpstream(cfgManager))
+	r.PUT("/channels/:id", UpdateUpstream(cfgManager, sch))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/channels", `{"name":"c1","baseUrl":"http://example.invalid","apiKeys":["k2"],"serviceType":"claude","supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("add status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("update status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["gpt-*"]}`); w.Code != http.StatusOK {
+		t.Fatalf("valid update status = %d, body=%s", w.Code, w.Body.String())
+	}
+}
@@ -2,6 +2,7 @@
 package messages
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,6 +36,9 @@ type requestLogContext struct {
 	channelName  string
 	apiKey       string
 
+	queuedAt  *time.Time    // 排队等待槽位中（非 nil 时）的入队时间
+	queueWait time.Duration // 获得槽位前的排队时长
+
 	usage     *types.Usage
 	costCents int64
 
@@ -57,6 +61,19 @@ func (r *requestLogContext) updateLive() {
 		StartTime:    r.startTime,
 		APIType:      r.apiType,
 		IsStreaming:  r.isStreaming,
+		QueuedAt:     r.queuedAt,
+		QueueWaitMs:  r.queueWait.Milliseconds(),
+	})
+}
+
+// withQueueHook 为槽位选择附加排队回调：所有槽位并发已满时在实时请求中标记为排队中
+func (r *requestLogContext) withQueueHook(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return scheduler.WithQueueHook(ctx, func(queuedAt time.Time) {
+		r.queuedAt = &queuedAt
+		r.updateLive()
 	})
 }
 
@@ -300,7 +317,7 @@ func handleMultiChannel(
 			return
 		}
 
-		selection, err := channelScheduler.SelectSlot(c.Request.Context(), userID, failedSlots, false)
+		selection, err := channelScheduler.SelectSlot(reqCtx.withQueueHook(scheduler.WithRequestModel(c.Request.Context(), claudeReq.Model)), userID, failedSlots, false)
 		if err != nil {
 			lastError = err
 			break
@@ -311,6 +328,8 @@ func handleMultiChannel(
 		if reqCtx != nil {
 			reqCtx.channelIndex = channelIndex
 			reqCtx.channelName = upstream.Name
+			reqCtx.queuedAt = nil
+			reqCtx.queueWait = selection.QueueWait
 			mappedModel := config.RedirectModelWithGlobal(claudeReq.Model, upstream, globalModelMapping)
 			if mappedModel != claudeReq.Model {
 				reqCtx.model = fmt.Sprintf("%s -> %s", claudeReq.Model, mappedModel)
@@ -327,7 +346,11 @@ func handleMultiChannel(
 
 		upstreamOneKey := upstream.Clone()
 		upstreamOneKey.APIKeys = []string{selection.APIKey}
-		success, successKey, _, failoverErr := tryChannelWithAllKeys(c, envCfg, cfgManager, channelScheduler, circuitLogStore, upstreamOneKey, channelIndex, bodyBytes, claudeReq, startTime, billingHandler, billingCtx, reqCtx, globalModelMapping)
+		// 本槽位尝试结束（含 panic）即归还并发额度
+		success, successKey, _, failoverErr := func() (bool, string, int, *common.FailoverError) {
+			defer selection.Release()
+			return tryChannelWithAllKeys(c, envCfg, cfgManager, channelScheduler, circuitLogStore, upstreamOneKey, channelIndex, bodyBytes, claudeReq, startTime, billingHandler, billingCtx, reqCtx, globalModelMapping)
+		}()
 
 		if success {
 			// successKey 为空表示请求方取消导致的提前退出：不记录成功/亲和，也不再继续。
@@ -341,7 +364,7 @@ func handleMultiChannel(
 		failedSlots[fmt.Sprintf("%d:%s", channelIndex, selection.APIKey)] = true
 
 		if failoverErr != nil {
-			lastFailoverError = failoverErr
+			lastFailoverError = common.KeepClientError(lastFailoverError, failoverErr)
 			lastError = fmt.Errorf("槽位 [%d] %s 失败", channelIndex, upstream.Name)
 		}
 
@@ -468,6 +491,10 @@ func tryChannelWithAllKeys(
 
 			if err != nil {
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := common.ConversionFailoverError(err); failoverErr != nil {
+					return false, "", 0, failoverErr
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "messages", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordFailure(currentBaseURL, apiKey, false)
 				})
@@ -475,6 +502,7 @@ func tryChannelWithAllKeys(
 			}
 			common.SetUpstreamRequestSnapshot(c, providerReq)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, claudeReq.Stream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不做降级，不继续 failover
@@ -549,6 +577,11 @@ func tryChannelWithAllKeys(
 				return true, "", 0, nil
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordLatency(currentBaseURL, apiKey, false, ttfb)
+			})
+
 			// 处理成功响应
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
@@ -730,6 +763,11 @@ func handleSingleChannel(
 			if err != nil {
 				lastError = err
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := common.ConversionFailoverError(err); failoverErr != nil {
+					lastFailoverError = failoverErr
+					break
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "messages", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordFailure(currentBaseURL, apiKey, false)
 				})
@@ -737,6 +775,7 @@ func handleSingleChannel(
 			}
 			common.SetUpstreamRequestSnapshot(c, providerReq)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, claudeReq.Stream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不继续 failover
@@ -835,6 +874,11 @@ func handleSingleChannel(
 				return
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordLatency(currentBaseURL, apiKey, false, ttfb)
+			})
+
 			// 处理成功响应
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
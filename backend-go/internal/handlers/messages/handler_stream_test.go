@@ -107,3 +107,87 @@ func TestMessagesHandler_MultiChannel_Stream_FailoverToNextChannel(t *testing.T)
 		t.Fatalf("expected wrong-model removed, got: %s", w.Body.String())
 	}
 }
+
+func TestMessagesHandler_ResponsesUpstream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var gotPath, gotAuth string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(r.Body)
+		gotBody = buf.Bytes()
+
+		if strings.Contains(buf.String(), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			sse := strings.Join([]string{
+				`data: {"type":"response.created","response":{"id":"resp_1","model":"gpt-5-codex"}}`,
+				`data: {"type":"response.output_text.delta","output_index":0,"delta":"hello"}`,
+				`data: {"type":"response.completed","response":{"status":"completed","usage":{"input_tokens":5,"output_tokens":2}}}`,
+				"",
+			}, "\n\n")
+			_, _ = w.Write([]byte(sse))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_2","status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"pong"}]}],"usage":{"input_tokens":5,"output_tokens":2}}`))
+	}))
+	defer upstream.Close()
+
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "codex", BaseURL: upstream.URL, APIKeys: []string{"sk-codex"}, ServiceType: "responses", Status: "active",
+				ModelMapping: map[string]string{"claude-3": "gpt-5-codex"}},
+		},
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1/messages", NewHandler(envCfg, cfgManager, sch, nil, nil, nil, nil, nil))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(body))
+		req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("non-stream", func(t *testing.T) {
+		w := post(`{"model":"claude-3","max_tokens":100,"messages":[{"role":"user","content":"ping"}]}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		if gotPath != "/v1/responses" || gotAuth != "Bearer sk-codex" {
+			t.Fatalf("upstream path=%q auth=%q", gotPath, gotAuth)
+		}
+		if !strings.Contains(string(gotBody), `"model":"gpt-5-codex"`) || !strings.Contains(string(gotBody), `"input_text"`) {
+			t.Fatalf("unexpected upstream body: %s", gotBody)
+		}
+		if !strings.Contains(w.Body.String(), `"text":"pong"`) || !strings.Contains(w.Body.String(), `"stop_reason":"end_turn"`) {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		w := post(`{"model":"claude-3","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"ping"}]}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		for _, want := range []string{"event: message_start", `"text":"hello"`, "event: message_stop"} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("missing %q in stream: %s", want, body)
+			}
+		}
+		if strings.Contains(body, "response.") {
+			t.Fatalf("responses events leaked into claude stream: %s", body)
+		}
+	})
+}
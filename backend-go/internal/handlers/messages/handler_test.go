@@ -28,7 +28,7 @@ func createTestScheduler(t *testing.T, cfgManager *config.ConfigManager) (*sched
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 	cleanup := func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
@@ -301,3 +301,86 @@ func TestTruncateErrorMessage_TruncatesToMaxLen(t *testing.T) {
 		t.Fatalf("expected suffix \"...\"")
 	}
 }
+
+// TestMessagesHandler_MultiChannel_KeepsClientErrorOverLaterFailure 首个渠道无法转换请求（400 客户端错误），
+// 后续渠道返回 5xx 时仍应以 400 返回，而不是 Fuzzy 模式下的通用 503
+func TestMessagesHandler_MultiChannel_KeepsClientErrorOverLaterFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var chatCalls atomic.Int64
+	chatUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chatCalls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer chatUpstream.Close()
+
+	var claudeCalls atomic.Int64
+	claudeUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claudeCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"boom"}}`))
+	}))
+	defer claudeUpstream.Close()
+
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{
+				Name:        "chat",
+				BaseURL:     chatUpstream.URL,
+				APIKeys:     []string{"k1"},
+				ServiceType: "openai",
+				Status:      "active",
+				Priority:    1,
+			},
+			{
+				Name:        "claude",
+				BaseURL:     claudeUpstream.URL,
+				APIKeys:     []string{"k2"},
+				ServiceType: "claude",
+				Status:      "active",
+				Priority:    2,
+			},
+		},
+		LoadBalance:          "failover",
+		ResponsesLoadBalance: "failover",
+		GeminiLoadBalance:    "failover",
+		FuzzyModeEnabled:     true,
+	}
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{
+		ProxyAccessKey:     "secret",
+		MaxRequestBodySize: 1024 * 1024,
+	}
+	h := NewHandler(envCfg, cfgManager, sch, nil, nil, nil, nil, nil)
+
+	r := gin.New()
+	r.POST("/v1/messages", h)
+
+	reqBody := `{"model":"claude-3","messages":[{"role":"user","content":"hi"}],"max_tokens":16,` +
+		`"tools":[{"type":"code_execution_20250522","name":"code_execution"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(reqBody))
+	req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	if chatCalls.Load() != 0 {
+		t.Fatalf("chat upstream calls = %d, want 0", chatCalls.Load())
+	}
+	if claudeCalls.Load() != 1 {
+		t.Fatalf("claude upstream calls = %d, want 1", claudeCalls.Load())
+	}
+	if !strings.Contains(w.Body.String(), "invalid_request_error") || !strings.Contains(w.Body.String(), "code_execution") {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
@@ -235,8 +235,8 @@ func tryModelsRequest(c *gin.Context, cfgManager *config.ConfigManager, channelS
 	}
 
 	for attempt := 0; attempt < maxSlotRetries; attempt++ {
-		// 使用调度器选择槽位（routingID 保证请求级的均衡散列）
-		selection, err := channelScheduler.SelectSlot(c.Request.Context(), routingID, failedSlots, isResponses)
+		// 使用调度器选择槽位（routingID 保证请求级的均衡散列；models 请求轻量，不受并发上限约束也不排队）
+		selection, err := channelScheduler.SelectSlotUncounted(c.Request.Context(), routingID, failedSlots, isResponses)
 		if err != nil {
 			log.Printf("[%s-Models] 无可用槽位: %v", channelType, err)
 			break
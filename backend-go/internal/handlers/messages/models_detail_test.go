@@ -88,7 +88,7 @@ func TestModelsDetailHandler_MessagesThenResponsesFallback(t *testing.T) {
 		traceAffinity := session.NewTraceAffinityManager()
 		defer traceAffinity.Stop()
 		urlManager := warmup.NewURLManager(30*time.Second, 3)
-		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 		envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 		r := gin.New()
@@ -141,7 +141,7 @@ func TestModelsDetailHandler_MessagesThenResponsesFallback(t *testing.T) {
 		traceAffinity := session.NewTraceAffinityManager()
 		defer traceAffinity.Stop()
 		urlManager := warmup.NewURLManager(30*time.Second, 3)
-		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 		envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 		r := gin.New()
@@ -185,7 +185,7 @@ func TestModelsDetailHandler_MessagesThenResponsesFallback(t *testing.T) {
 		traceAffinity := session.NewTraceAffinityManager()
 		defer traceAffinity.Stop()
 		urlManager := warmup.NewURLManager(30*time.Second, 3)
-		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+		sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 		envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 		r := gin.New()
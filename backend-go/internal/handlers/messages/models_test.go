@@ -1,6 +1,7 @@
 package messages
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -122,7 +123,7 @@ func TestModelsHandler_CacheMissFetchesUpstreamAndCaches(t *testing.T) {
 	traceAffinity := session.NewTraceAffinityManager()
 	defer traceAffinity.Stop()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 	cacheMetrics := &metrics.CacheMetrics{}
@@ -206,7 +207,7 @@ func TestModelsHandler_UpstreamFailureDoesNotCache(t *testing.T) {
 
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 	cacheMetrics := &metrics.CacheMetrics{}
@@ -281,7 +282,7 @@ func TestModelsHandler_InvalidJSONFromUpstream_Returns404AndDoesNotCache(t *test
 
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 
 	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
 	cacheMetrics := &metrics.CacheMetrics{}
@@ -364,3 +365,69 @@ func createTestConfigManager(t *testing.T, cfg config.Config) (*config.ConfigMan
 	}
 	return cfgManager, cleanup
 }
+
+func TestModelsHandler_SucceedsWhenAllSlotsSaturated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"m1","object":"model","created":1,"owned_by":"x"}]}`))
+	}))
+	defer upstream.Close()
+
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "messages", BaseURL: upstream.URL, APIKeys: []string{"k-msg"}, Status: "active", Priority: 1, MaxConcurrency: 1},
+		},
+		LoadBalance: "failover",
+		ResponsesUpstream: []config.UpstreamConfig{
+			{Name: "responses", BaseURL: upstream.URL, APIKeys: []string{"k-resp"}, Status: "active", Priority: 1, MaxConcurrency: 1},
+		},
+		ResponsesLoadBalance: "failover",
+		GeminiLoadBalance:    "failover",
+		FuzzyModeEnabled:     true,
+	}
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+
+	messagesMetrics := metrics.NewMetricsManager()
+	responsesMetrics := metrics.NewMetricsManager()
+	geminiMetrics := metrics.NewMetricsManager()
+	defer messagesMetrics.Stop()
+	defer responsesMetrics.Stop()
+	defer geminiMetrics.Stop()
+
+	traceAffinity := session.NewTraceAffinityManager()
+	defer traceAffinity.Stop()
+	urlManager := warmup.NewURLManager(30*time.Second, 3)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
+	// 不排队：若 models 请求经过并发限制会立即失败
+	sch.SetConcurrencyQueue(0, time.Second)
+
+	for _, isResponses := range []bool{false, true} {
+		held, err := sch.SelectSlot(context.Background(), "", nil, isResponses)
+		if err != nil {
+			t.Fatalf("SelectSlot(isResponses=%v): %v", isResponses, err)
+		}
+		defer held.Release()
+	}
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
+	respCache := cache.NewHTTPResponseCache(10, time.Minute, &metrics.CacheMetrics{})
+
+	r := gin.New()
+	r.GET("/v1/models", ModelsHandler(envCfg, cfgManager, sch, respCache))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body=%s)", w.Code, http.StatusOK, w.Body.String())
+	}
+	if depth := sch.GetConcurrencyQueueDepth("messages"); depth != 0 {
+		t.Fatalf("queue depth = %d, want 0", depth)
+	}
+}
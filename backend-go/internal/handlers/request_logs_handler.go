@@ -150,7 +150,7 @@ func apiTypeFromAdminLogsPath(path string) string {
 
 	apiType := parts[1]
 	switch apiType {
-	case "messages", "responses", "gemini":
+	case "messages", "responses", "gemini", "embeddings":
 		return apiType
 	default:
 		return ""
@@ -0,0 +1,254 @@
+package responses
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// backgroundResponseIDKey 后台任务预分配的响应 ID（注入到重放请求的 context 中）
+type backgroundResponseIDKey struct{}
+
+// backgroundResponseID 读取当前请求预分配的后台响应 ID，普通请求返回空字符串
+func backgroundResponseID(c *gin.Context) string {
+	if c == nil || c.Request == nil {
+		return ""
+	}
+	id, _ := c.Request.Context().Value(backgroundResponseIDKey{}).(string)
+	return id
+}
+
+// isBackgroundRequest 判断请求体是否启用后台模式
+func isBackgroundRequest(bodyBytes []byte) bool {
+	return gjson.GetBytes(bodyBytes, "background").Bool()
+}
+
+// handleBackground 处理 background=true 的请求：立即返回 queued 响应，上游调用在后台执行
+// 后台任务通过 replay 重新进入 Handle（去掉 background 字段、强制非流式），结果写入会话存储
+func (h *Handler) handleBackground(c *gin.Context, bodyBytes []byte) {
+	var req types.ResponsesRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "Invalid JSON", "type": "invalid_request_error"}})
+		return
+	}
+	if h.sessionManager == nil || (req.Store != nil && !*req.Store) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "background mode requires store=true", "type": "invalid_request_error", "param": "background"}})
+		return
+	}
+	if req.Stream {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "background mode does not support stream=true, poll GET /v1/responses/{id} instead", "type": "invalid_request_error", "param": "stream"}})
+		return
+	}
+
+	responseID := newBackgroundResponseID()
+	queued := backgroundResponseObject(responseID, &req, "queued", time.Now().Unix())
+	h.sessionManager.StoreResponse("", responseID, queued, storedInputItems(req.Input))
+
+	replayBody, err := sjson.DeleteBytes(bodyBytes, "background")
+	if err != nil {
+		replayBody = bodyBytes
+	}
+	header := c.Request.Header.Clone()
+	header.Del("Content-Length")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.sessionManager.RegisterBackground(responseID, cancel)
+	go h.runBackground(ctx, responseID, header, replayBody)
+
+	log.Printf("[Responses-Background] 已创建后台响应: %s (model=%s)", responseID, req.Model)
+	c.Data(http.StatusOK, "application/json", queued)
+}
+
+// runBackground 执行后台响应并更新存储状态
+func (h *Handler) runBackground(ctx context.Context, responseID string, header http.Header, body []byte) {
+	sm := h.sessionManager
+	setBackgroundStatus(sm, responseID, "in_progress")
+
+	httpReq, err := http.NewRequestWithContext(context.WithValue(ctx, backgroundResponseIDKey{}, responseID), http.MethodPost, "/v1/responses", bytes.NewReader(body))
+	if err != nil {
+		sm.FinishBackground(responseID)
+		failBackground(sm, responseID, http.StatusInternalServerError, []byte(err.Error()))
+		return
+	}
+	httpReq.Header = header
+
+	w := newBackgroundRecorder()
+	h.replayEngine().ServeHTTP(w, httpReq)
+
+	if canceled := sm.FinishBackground(responseID); canceled {
+		setBackgroundStatus(sm, responseID, "cancelled")
+		log.Printf("[Responses-Background] 后台响应已取消: %s", responseID)
+		return
+	}
+
+	if w.status < 200 || w.status >= 300 {
+		failBackground(sm, responseID, w.status, w.body.Bytes())
+		log.Printf("[Responses-Background] 后台响应失败: %s (status=%d)", responseID, w.status)
+		return
+	}
+
+	// 成功时 handleSuccess 已按预分配 ID 写入存储；仍未进入终态时使用重放响应兜底
+	_, _ = sm.UpdateStoredResponseIf(responseID, func(current json.RawMessage) ([]byte, bool) {
+		if !backgroundPending(current) {
+			return nil, false
+		}
+		result, _ := sjson.SetBytes(w.body.Bytes(), "id", responseID)
+		result = toStoredResponseObject(result, nil)
+		result, _ = sjson.SetBytes(result, "background", true)
+		return result, true
+	})
+	log.Printf("[Responses-Background] 后台响应完成: %s", responseID)
+}
+
+// replayEngine 后台任务使用的内部路由（仅挂载 Responses 处理器）
+func (h *Handler) replayEngine() http.Handler {
+	h.replayOnce.Do(func() {
+		engine := gin.New()
+		engine.Use(gin.Recovery())
+		engine.POST("/v1/responses", h.Handle)
+		h.replay = engine
+	})
+	return h.replay
+}
+
+// CancelResponseHandler 处理 POST /v1/responses/:id/cancel（仅后台响应可取消）
+func CancelResponseHandler(envCfg *config.EnvConfig, sessionManager *session.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		middleware.ProxyAuthMiddleware(envCfg)(c)
+		if c.IsAborted() {
+			return
+		}
+
+		responseID := c.Param("id")
+		if sessionManager == nil {
+			responseNotFound(c, responseID)
+			return
+		}
+		stored, err := sessionManager.GetResponse(responseID)
+		if err != nil {
+			responseNotFound(c, responseID)
+			return
+		}
+		if !gjson.GetBytes(stored.Response, "background").Bool() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "Only responses created with background=true can be cancelled.", "type": "invalid_request_error"}})
+			return
+		}
+
+		// 已结束的后台响应保持原状态（取消操作幂等）
+		if sessionManager.CancelBackground(responseID) {
+			setBackgroundStatus(sessionManager, responseID, "cancelled")
+		}
+		if stored, err = sessionManager.GetResponse(responseID); err != nil {
+			responseNotFound(c, responseID)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", stored.Response)
+	}
+}
+
+// backgroundResponseObject 构建后台响应的初始对象
+func backgroundResponseObject(responseID string, req *types.ResponsesRequest, status string, createdAt int64) []byte {
+	out := []byte(`{"object":"response","background":true,"output":[],"error":null,"usage":null}`)
+	out, _ = sjson.SetBytes(out, "id", responseID)
+	out, _ = sjson.SetBytes(out, "created_at", createdAt)
+	out, _ = sjson.SetBytes(out, "status", status)
+	out, _ = sjson.SetBytes(out, "model", req.Model)
+	if req.PreviousResponseID != "" {
+		out, _ = sjson.SetBytes(out, "previous_response_id", req.PreviousResponseID)
+	} else {
+		out, _ = sjson.SetRawBytes(out, "previous_response_id", []byte("null"))
+	}
+	return out
+}
+
+// backgroundPending 后台响应是否尚未进入终态（queued / in_progress）
+// 进入终态（completed / failed / cancelled 等）后状态不再变更
+func backgroundPending(response json.RawMessage) bool {
+	status := gjson.GetBytes(response, "status").String()
+	return status == "queued" || status == "in_progress"
+}
+
+// setBackgroundStatus 更新已存储后台响应的状态，已进入终态时保持原状态
+// 读取与写入在会话管理器锁内完成，避免与并发的取消、完成写入互相覆盖
+func setBackgroundStatus(sm *session.SessionManager, responseID, status string) {
+	_, _ = sm.UpdateStoredResponseIf(responseID, func(current json.RawMessage) ([]byte, bool) {
+		if !backgroundPending(current) {
+			return nil, false
+		}
+		out, _ := sjson.SetBytes(current, "status", status)
+		out, _ = sjson.SetBytes(out, "background", true)
+		return out, true
+	})
+}
+
+// failBackground 将后台响应标记为 failed 并记录上游错误，已进入终态时保持原状态
+func failBackground(sm *session.SessionManager, responseID string, status int, body []byte) {
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		message = gjson.GetBytes(body, "error").String()
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = fmt.Sprintf("http status %d", status)
+	}
+	code := "server_error"
+	if status == http.StatusTooManyRequests {
+		code = "rate_limit_exceeded"
+	}
+
+	errObj, _ := sjson.SetBytes([]byte(`{}`), "code", code)
+	errObj, _ = sjson.SetBytes(errObj, "message", message)
+
+	_, _ = sm.UpdateStoredResponseIf(responseID, func(current json.RawMessage) ([]byte, bool) {
+		if !backgroundPending(current) {
+			return nil, false
+		}
+		out, _ := sjson.SetBytes(current, "status", "failed")
+		out, _ = sjson.SetBytes(out, "background", true)
+		out, _ = sjson.SetRawBytes(out, "error", errObj)
+		return out, true
+	})
+}
+
+// newBackgroundResponseID 生成后台响应 ID
+func newBackgroundResponseID() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("resp_%d", time.Now().UnixNano())
+	}
+	return "resp_" + hex.EncodeToString(b)
+}
+
+// backgroundRecorder 收集后台重放请求的响应
+type backgroundRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBackgroundRecorder() *backgroundRecorder {
+	return &backgroundRecorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *backgroundRecorder) Header() http.Header { return r.header }
+
+func (r *backgroundRecorder) Write(p []byte) (int, error) { return r.body.Write(p) }
+
+func (r *backgroundRecorder) WriteHeader(status int) { r.status = status }
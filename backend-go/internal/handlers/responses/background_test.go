@@ -0,0 +1,194 @@
+package responses
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newBackgroundRouter(t *testing.T, upstream http.HandlerFunc) (*gin.Engine, func()) {
+	t.Helper()
+
+	r, sessionManager, cleanup := newStoredResponsesRouter(t, upstream)
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret"}
+	r.POST("/v1/responses/compact", CompactHandler(envCfg, nil, sessionManager, nil))
+	r.POST("/v1/responses/:id/cancel", CancelResponseHandler(envCfg, sessionManager))
+	return r, cleanup
+}
+
+// waitForStatus 轮询 GET /v1/responses/{id} 直到状态匹配
+func waitForStatus(t *testing.T, r *gin.Engine, id, want string) string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	var body string
+	for time.Now().Before(deadline) {
+		w := doStoredRequest(r, http.MethodGet, "/v1/responses/"+id, "")
+		body = w.Body.String()
+		if gjson.Get(body, "status").String() == want {
+			return body
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("status of %s never became %q, last body: %s", id, want, body)
+	return ""
+}
+
+func TestBackground_CompletesAndIsPollable(t *testing.T) {
+	release := make(chan struct{})
+	r, cleanup := newBackgroundRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_upstream","model":"gpt-4o","status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"done"}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"think hard","background":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	id := gjson.Get(w.Body.String(), "id").String()
+	if gjson.Get(w.Body.String(), "status").String() != "queued" || !gjson.Get(w.Body.String(), "background").Bool() {
+		t.Fatalf("expected queued background response, got %s", w.Body.String())
+	}
+
+	waitForStatus(t, r, id, "in_progress")
+	close(release)
+	body := waitForStatus(t, r, id, "completed")
+
+	if gjson.Get(body, "id").String() != id {
+		t.Fatalf("stored response must keep the background id, got %s", body)
+	}
+	if !gjson.Get(body, "background").Bool() || gjson.Get(body, "output.0.content.0.text").String() != "done" {
+		t.Fatalf("unexpected completed response: %s", body)
+	}
+
+	w = doStoredRequest(r, http.MethodPost, "/v1/responses/"+id+"/cancel", "")
+	if w.Code != http.StatusOK || gjson.Get(w.Body.String(), "status").String() != "completed" {
+		t.Fatalf("cancel after completion should be a no-op, status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"next","previous_response_id":"`+id+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("background response should be chainable, status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestBackground_CancelAbortsUpstream(t *testing.T) {
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	r, cleanup := newBackgroundRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		close(started)
+		<-r.Context().Done()
+		close(aborted)
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"long","background":true}`)
+	id := gjson.Get(w.Body.String(), "id").String()
+
+	<-started
+	w = doStoredRequest(r, http.MethodPost, "/v1/responses/"+id+"/cancel", "")
+	if w.Code != http.StatusOK || gjson.Get(w.Body.String(), "status").String() != "cancelled" {
+		t.Fatalf("cancel status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("upstream request was not aborted")
+	}
+	waitForStatus(t, r, id, "cancelled")
+}
+
+func TestBackground_UpstreamErrorMarksFailed(t *testing.T) {
+	r, cleanup := newBackgroundRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"context too long","type":"invalid_request_error"}}`))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"x","background":true}`)
+	id := gjson.Get(w.Body.String(), "id").String()
+
+	body := waitForStatus(t, r, id, "failed")
+	if gjson.Get(body, "error.code").String() == "" || gjson.Get(body, "error.message").String() == "" {
+		t.Fatalf("expected error details on failed response, got %s", body)
+	}
+}
+
+func TestBackground_RejectsUnsupportedRequests(t *testing.T) {
+	r, cleanup := newBackgroundRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_fg","model":"gpt-4o","status":"completed","output":[],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	})
+	defer cleanup()
+
+	for _, body := range []string{
+		`{"model":"gpt-4o","input":"x","background":true,"stream":true}`,
+		`{"model":"gpt-4o","input":"x","background":true,"store":false}`,
+	} {
+		if w := doStoredRequest(r, http.MethodPost, "/v1/responses", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s: status=%d, want 400", body, w.Code)
+		}
+	}
+
+	if w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"x"}`); w.Code != http.StatusOK {
+		t.Fatalf("foreground status=%d", w.Code)
+	}
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses/resp_fg/cancel", "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("cancel foreground response status=%d, want 400", w.Code)
+	}
+	if w := doStoredRequest(r, http.MethodPost, "/v1/responses/resp_missing/cancel", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("cancel missing status=%d, want 404", w.Code)
+	}
+}
+
+func TestSessionManager_BackgroundRegistry(t *testing.T) {
+	sm := session.NewSessionManager(time.Hour, 10, 1000)
+
+	canceled := false
+	sm.RegisterBackground("resp_a", func() { canceled = true })
+	if !sm.CancelBackground("resp_a") || !canceled {
+		t.Fatalf("expected running job to be cancelled")
+	}
+	if !sm.FinishBackground("resp_a") {
+		t.Fatalf("FinishBackground should report cancellation")
+	}
+	if sm.CancelBackground("resp_a") || sm.FinishBackground("resp_a") {
+		t.Fatalf("finished job must be unregistered")
+	}
+}
+
+func TestBackgroundStatus_TerminalStateIsFinal(t *testing.T) {
+	sm := session.NewSessionManager(time.Hour, 10, 1000)
+	sm.StoreResponse("", "resp_b", []byte(`{"id":"resp_b","status":"queued","background":true}`), nil)
+
+	setBackgroundStatus(sm, "resp_b", "in_progress")
+	setBackgroundStatus(sm, "resp_b", "cancelled")
+
+	// 取消后迟到的状态更新、失败标记与完成写入均不得覆盖终态
+	setBackgroundStatus(sm, "resp_b", "in_progress")
+	failBackground(sm, "resp_b", http.StatusBadGateway, []byte(`{"error":{"message":"late"}}`))
+	if sm.StoreResponseIf("", "resp_b", []byte(`{"id":"resp_b","status":"completed"}`), nil, backgroundPending) {
+		t.Fatalf("completed result must not overwrite cancelled response")
+	}
+
+	stored, err := sm.GetResponse("resp_b")
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if status := gjson.GetBytes(stored.Response, "status").String(); status != "cancelled" {
+		t.Fatalf("status=%s, want cancelled", status)
+	}
+	if gjson.GetBytes(stored.Response, "error.message").Exists() {
+		t.Fatalf("late failure must not be recorded: %s", stored.Response)
+	}
+}
@@ -2,6 +2,7 @@
 package responses
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -36,11 +37,16 @@ func GetUpstreams(cfgManager *config.ConfigManager) gin.HandlerFunc {
 				"website":            up.Website,
 				"insecureSkipVerify": up.InsecureSkipVerify,
 				"modelMapping":       up.ModelMapping,
+				"supportedModels":    up.SupportedModels,
 				"latency":            nil,
 				"status":             status,
 				"priority":           priority,
+				"weight":             up.Weight,
+				"priceMultiplier":    up.PriceMultiplier,
+				"maxConcurrency":     up.MaxConcurrency,
 				"promotionUntil":     up.PromotionUntil,
 				"lowQuality":         up.LowQuality,
+				"apiVersion":         up.APIVersion,
 			}
 		}
 
@@ -61,6 +67,11 @@ func AddUpstream(cfgManager *config.ConfigManager) gin.HandlerFunc {
 		}
 
 		if err := cfgManager.AddResponsesUpstream(upstream); err != nil {
+			var cfgErr *config.ConfigError
+			if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
@@ -87,6 +98,11 @@ func UpdateUpstream(cfgManager *config.ConfigManager, sch *scheduler.ChannelSche
 
 		shouldResetMetrics, err := cfgManager.UpdateResponsesUpstream(id, updates)
 		if err != nil {
+			var cfgErr *config.ConfigError
+			if errors.As(err, &cfgErr) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
@@ -222,3 +222,40 @@ func TestResponsesChannelsHandlers_ErrorPaths(t *testing.T) {
 		}
 	})
 }
+
+// TestResponsesChannelsHandlers_InvalidSupportedModelsReturns400 无效的 supportedModels 模式属于配置错误，返回 400
+func TestResponsesChannelsHandlers_InvalidSupportedModelsReturns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{
+			{Name: "c0", BaseURL: "http://example.invalid", APIKeys: []string{"k1"}, ServiceType: "responses", Status: "active"},
+		},
+	})
+	defer cleanupCfg()
+
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	r := gin.New()
+	r.POST("/channels", AddUpstream(cfgManager))
+	r.PUT("/channels/:id", UpdateUpstream(cfgManager, sch))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/channels", `{"name":"c1","baseUrl":"http://example.invalid","apiKeys":["k2"],"serviceType":"responses","supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("add status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["/(/"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("update status = %d, want 400, body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/channels/0", `{"supportedModels":["gpt-*"]}`); w.Code != http.StatusOK {
+		t.Fatalf("valid update status = %d, body=%s", w.Code, w.Body.String())
+	}
+}
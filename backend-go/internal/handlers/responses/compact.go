@@ -18,6 +18,7 @@
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // compactError 封装 compact 请求错误
@@ -142,9 +143,10 @@ func handleMultiChannelCompact(
 	failedSlots := make(map[string]bool)
 	maxAttempts := channelScheduler.GetActiveSlotCount(true)
 	var lastErr *compactError
+	selectCtx := scheduler.WithRequestModel(c.Request.Context(), gjson.GetBytes(bodyBytes, "model").String())
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		selection, err := channelScheduler.SelectSlot(c.Request.Context(), userID, failedSlots, true)
+		selection, err := channelScheduler.SelectSlot(selectCtx, userID, failedSlots, true)
 		if err != nil {
 			break
 		}
@@ -155,7 +157,11 @@ func handleMultiChannelCompact(
 		// 每个槽位仅尝试该 key（失败则迁移到下一个槽位）
 		upstreamOneKey := upstream.Clone()
 		upstreamOneKey.APIKeys = []string{selection.APIKey}
-		success, successKey, compactErr := tryCompactChannelWithAllKeys(c, upstreamOneKey, cfgManager, channelScheduler, bodyBytes, envCfg)
+		// 本槽位尝试结束（含 panic）即归还并发额度
+		success, successKey, compactErr := func() (bool, string, *compactError) {
+			defer selection.Release()
+			return tryCompactChannelWithAllKeys(c, upstreamOneKey, cfgManager, channelScheduler, bodyBytes, envCfg)
+		}()
 
 		if success {
 			// compact 不产生 usage，但仍需记录成功以更新熔断器/权重
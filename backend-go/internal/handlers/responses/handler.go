@@ -4,12 +4,14 @@
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/billing"
@@ -27,6 +29,7 @@
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 type requestLogContext struct {
@@ -42,6 +45,9 @@ type requestLogContext struct {
 	channelName  string
 	apiKey       string
 
+	queuedAt  *time.Time    // 排队等待槽位中（非 nil 时）的入队时间
+	queueWait time.Duration // 获得槽位前的排队时长
+
 	usage     *types.Usage
 	costCents int64
 
@@ -65,6 +71,19 @@ func (r *requestLogContext) updateLive() {
 		StartTime:       r.startTime,
 		APIType:         r.apiType,
 		IsStreaming:     r.isStreaming,
+		QueuedAt:        r.queuedAt,
+		QueueWaitMs:     r.queueWait.Milliseconds(),
+	})
+}
+
+// withQueueHook 为槽位选择附加排队回调：所有槽位并发已满时在实时请求中标记为排队中
+func (r *requestLogContext) withQueueHook(ctx context.Context) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return scheduler.WithQueueHook(ctx, func(queuedAt time.Time) {
+		r.queuedAt = &queuedAt
+		r.updateLive()
 	})
 }
 
@@ -115,6 +134,10 @@ type Handler struct {
 	liveRequestManager *monitor.LiveRequestManager
 	circuitLogStore    metrics.KeyCircuitLogStore
 	requestLogStore    metrics.RequestLogStore
+
+	// 后台模式重放路由（懒加载）
+	replay     http.Handler
+	replayOnce sync.Once
 }
 
 func NewHandler(
@@ -162,6 +185,24 @@ func (h *Handler) Handle(c *gin.Context) {
 		return
 	}
 
+	// WebSocket 传输：长连接上逐帧处理 response.create
+	if isWebSocketUpgrade(c.Request) {
+		h.handleWebSocket(c)
+		return
+	}
+
+	// 后台模式：立即返回 queued 响应，上游调用在后台执行
+	if c.Request.ContentLength != 0 {
+		bodyBytes, err := common.ReadRequestBody(c, envCfg.MaxRequestBodySize)
+		if err != nil {
+			return
+		}
+		if isBackgroundRequest(bodyBytes) {
+			h.handleBackground(c, bodyBytes)
+			return
+		}
+	}
+
 	startTime := time.Now()
 	requestID := uuid.New().String()
 
@@ -352,7 +393,7 @@ func handleMultiChannel(
 			return
 		}
 
-		selection, err := channelScheduler.SelectSlot(c.Request.Context(), routingKey, failedSlots, true)
+		selection, err := channelScheduler.SelectSlot(reqCtx.withQueueHook(scheduler.WithRequestModel(c.Request.Context(), responsesReq.Model)), routingKey, failedSlots, true)
 		if err != nil {
 			lastError = err
 			break
@@ -363,6 +404,8 @@ func handleMultiChannel(
 		if reqCtx != nil {
 			reqCtx.channelIndex = channelIndex
 			reqCtx.channelName = upstream.Name
+			reqCtx.queuedAt = nil
+			reqCtx.queueWait = selection.QueueWait
 			mappedModel := config.RedirectModelWithGlobal(responsesReq.Model, upstream, globalModelMapping)
 			if mappedModel != responsesReq.Model {
 				reqCtx.model = fmt.Sprintf("%s -> %s", responsesReq.Model, mappedModel)
@@ -379,7 +422,11 @@ func handleMultiChannel(
 
 		upstreamOneKey := upstream.Clone()
 		upstreamOneKey.APIKeys = []string{selection.APIKey}
-		success, successKey, _, failoverErr, usage := tryChannelWithAllKeys(c, envCfg, cfgManager, channelScheduler, circuitLogStore, sessionManager, upstreamOneKey, channelIndex, bodyBytes, responsesReq, startTime, billingHandler, billingCtx, reqCtx, globalModelMapping, globalReasoningMapping)
+		// 本槽位尝试结束（含 panic）即归还并发额度
+		success, successKey, _, failoverErr, usage := func() (bool, string, int, *common.FailoverError, *types.Usage) {
+			defer selection.Release()
+			return tryChannelWithAllKeys(c, envCfg, cfgManager, channelScheduler, circuitLogStore, sessionManager, upstreamOneKey, channelIndex, bodyBytes, responsesReq, startTime, billingHandler, billingCtx, reqCtx, globalModelMapping, globalReasoningMapping)
+		}()
 
 		if success {
 			// successKey 为空表示请求方取消导致的提前退出：不记录成功/亲和，也不再继续。
@@ -409,7 +456,7 @@ func handleMultiChannel(
 		failedSlots[fmt.Sprintf("%d:%s", channelIndex, selection.APIKey)] = true
 
 		if failoverErr != nil {
-			lastFailoverError = failoverErr
+			lastFailoverError = common.KeepClientError(lastFailoverError, failoverErr)
 			lastError = fmt.Errorf("槽位 [%d] %s 失败", channelIndex, upstream.Name)
 		}
 
@@ -538,6 +585,10 @@ func tryChannelWithAllKeys(
 
 			if err != nil {
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := common.ConversionFailoverError(err); failoverErr != nil {
+					return false, "", 0, failoverErr, nil
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "responses", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordFailure(currentBaseURL, apiKey, true)
 				})
@@ -547,6 +598,7 @@ func tryChannelWithAllKeys(
 
 			applyUpstreamReasoningEffort(c, reqCtx)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, responsesReq.Stream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不做降级，不继续 failover
@@ -607,6 +659,11 @@ func tryChannelWithAllKeys(
 				return true, "", 0, nil, nil
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordLatency(currentBaseURL, apiKey, true, ttfb)
+			})
+
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
 					_ = cfgManager.DeprioritizeAPIKey(key)
@@ -785,6 +842,11 @@ func handleSingleChannel(
 			if err != nil {
 				lastError = err
 				failedKeys[apiKey] = true
+				// 请求无法转换为该渠道协议（如服务端工具不受支持）：属于客户端错误，不计入熔断
+				if failoverErr := common.ConversionFailoverError(err); failoverErr != nil {
+					lastFailoverError = failoverErr
+					break
+				}
 				common.RecordFailureAndStoreLastFailureLog(circuitLogStore, metricsManager, "responses", currentBaseURL, apiKey, 0, nil, err, func() {
 					channelScheduler.RecordFailure(currentBaseURL, apiKey, true)
 				})
@@ -794,6 +856,7 @@ func handleSingleChannel(
 
 			applyUpstreamReasoningEffort(c, reqCtx)
 
+			requestStart := time.Now()
 			resp, err := common.SendRequest(providerReq, upstream, envCfg, responsesReq.Stream)
 			if err != nil {
 				// 请求方取消：视为正常，不计失败，不继续 failover
@@ -886,6 +949,11 @@ func handleSingleChannel(
 				return
 			}
 
+			// 记录 TTFB（首字节时间），用于 latency 负载均衡
+			common.TrackFirstByte(resp, requestStart, func(ttfb time.Duration) {
+				channelScheduler.RecordLatency(currentBaseURL, apiKey, true, ttfb)
+			})
+
 			if len(deprioritizeCandidates) > 0 {
 				for key := range deprioritizeCandidates {
 					if err := cfgManager.DeprioritizeAPIKey(key); err != nil {
@@ -944,7 +1012,7 @@ func handleSuccess(
 	isStream := originalReq != nil && originalReq.Stream
 
 	if isStream {
-		return handleStreamSuccess(c, resp, upstreamType, envCfg, startTime, originalReq, originalRequestJSON)
+		return handleStreamSuccess(c, resp, upstreamType, envCfg, sessionManager, startTime, originalReq, originalRequestJSON)
 	}
 
 	// 非流式响应处理
@@ -998,50 +1066,14 @@ func handleSuccess(
 	// Token 补全逻辑
 	patchResponsesUsage(responsesResp, originalRequestJSON, envCfg)
 
-	// 更新会话
-	if sessionManager != nil && (originalReq.Store == nil || *originalReq.Store) {
-		var sess *session.Session
-
-		if v, ok := c.Get(session.ContextKeySessionID); ok {
-			if sessionID, ok := v.(string); ok && sessionID != "" {
-				if s, err := sessionManager.GetSession(sessionID); err == nil {
-					sess = s
-				}
-			}
-		}
-
-		if sess == nil {
-			if s, err := sessionManager.GetOrCreateSession(originalReq.PreviousResponseID); err == nil {
-				sess = s
-				c.Set(session.ContextKeySessionID, sess.ID)
-			}
-		}
-
-		if sess != nil {
-			inputItems, _ := parseInputToItems(originalReq.Input)
-			for _, item := range inputItems {
-				if err := sessionManager.AppendMessage(sess.ID, item, 0); err != nil {
-					log.Printf("[Session-Append] 警告: 追加输入消息失败: %v", err)
-				}
-			}
-
-			for _, item := range responsesResp.Output {
-				if err := sessionManager.AppendMessage(sess.ID, item, responsesResp.Usage.TotalTokens); err != nil {
-					log.Printf("[Session-Append] 警告: 追加输出消息失败: %v", err)
-				}
-			}
-
-			if err := sessionManager.UpdateLastResponseID(sess.ID, responsesResp.ID); err != nil {
-				log.Printf("[Session-Update] 警告: 更新 LastResponseID 失败: %v", err)
-			}
-			sessionManager.RecordResponseMapping(responsesResp.ID, sess.ID)
-
-			if sess.LastResponseID != "" {
-				responsesResp.PreviousID = sess.LastResponseID
-			}
-		}
+	// 后台模式使用预分配的响应 ID
+	if id := backgroundResponseID(c); id != "" {
+		responsesResp.ID = id
 	}
 
+	// 更新会话
+	updateSessionWithResponse(c, sessionManager, originalReq, responsesResp, nil)
+
 	utils.ForwardResponseHeaders(resp.Header, c.Writer)
 	c.JSON(200, responsesResp)
 
@@ -1057,6 +1089,78 @@ func handleSuccess(
 	}
 }
 
+// updateSessionWithResponse 将本轮输入与响应输出写入会话，并存储响应对象供 GET /v1/responses/{id} 查询
+// rawResponse 为客户端收到的响应对象（流式 response.completed 中的 response），为空时使用 responsesResp 序列化结果
+func updateSessionWithResponse(
+	c *gin.Context,
+	sessionManager *session.SessionManager,
+	originalReq *types.ResponsesRequest,
+	responsesResp *types.ResponsesResponse,
+	rawResponse []byte,
+) {
+	if sessionManager == nil || originalReq == nil || responsesResp == nil || responsesResp.ID == "" {
+		return
+	}
+	if originalReq.Store != nil && !*originalReq.Store {
+		return
+	}
+
+	var sess *session.Session
+
+	if v, ok := c.Get(session.ContextKeySessionID); ok {
+		if sessionID, ok := v.(string); ok && sessionID != "" {
+			if s, err := sessionManager.GetSession(sessionID); err == nil {
+				sess = s
+			}
+		}
+	}
+
+	if sess == nil {
+		if s, err := sessionManager.GetOrCreateSession(originalReq.PreviousResponseID); err == nil {
+			sess = s
+			c.Set(session.ContextKeySessionID, sess.ID)
+		}
+	}
+
+	if sess == nil {
+		return
+	}
+
+	inputItems, _ := parseInputToItems(originalReq.Input)
+	for _, item := range inputItems {
+		if err := sessionManager.AppendMessage(sess.ID, item, 0); err != nil {
+			log.Printf("[Session-Append] 警告: 追加输入消息失败: %v", err)
+		}
+	}
+
+	for _, item := range responsesResp.Output {
+		if err := sessionManager.AppendMessage(sess.ID, item, responsesResp.Usage.TotalTokens); err != nil {
+			log.Printf("[Session-Append] 警告: 追加输出消息失败: %v", err)
+		}
+	}
+
+	if err := sessionManager.UpdateLastResponseID(sess.ID, responsesResp.ID); err != nil {
+		log.Printf("[Session-Update] 警告: 更新 LastResponseID 失败: %v", err)
+	}
+	sessionManager.RecordResponseMapping(responsesResp.ID, sess.ID)
+
+	if sess.LastResponseID != "" {
+		responsesResp.PreviousID = sess.LastResponseID
+	}
+
+	if len(rawResponse) == 0 {
+		rawResponse, _ = json.Marshal(responsesResp)
+	}
+	stored := toStoredResponseObject(rawResponse, originalReq)
+	if id := backgroundResponseID(c); id != "" && id == responsesResp.ID {
+		// 后台响应：已被取消或标记失败时不再覆盖终态
+		stored, _ = sjson.SetBytes(stored, "background", true)
+		sessionManager.StoreResponseIf(sess.ID, responsesResp.ID, stored, storedInputItems(originalReq.Input), backgroundPending)
+		return
+	}
+	sessionManager.StoreResponse(sess.ID, responsesResp.ID, stored, storedInputItems(originalReq.Input))
+}
+
 // patchResponsesUsage 补全 Responses 响应的 Token 统计
 func patchResponsesUsage(resp *types.ResponsesResponse, requestBody []byte, envCfg *config.EnvConfig) {
 	// 检查是否有 Claude 原生缓存 token（有时才跳过 input_tokens 修补）
@@ -1181,6 +1285,7 @@ func handleStreamSuccess(
 	resp *http.Response,
 	upstreamType string,
 	envCfg *config.EnvConfig,
+	sessionManager *session.SessionManager,
 	startTime time.Time,
 	originalReq *types.ResponsesRequest,
 	originalRequestJSON []byte,
@@ -1223,6 +1328,7 @@ func handleStreamSuccess(
 	hasUsage := false
 	needTokenPatch := false
 	clientGone := false
+	var completedResponse []byte
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -1238,7 +1344,14 @@ func handleStreamSuccess(
 		var eventsToProcess []string
 
 		if needConvert {
-			events := converters.ConvertOpenAIChatToResponses(
+			convert := converters.ConvertOpenAIChatToResponses
+			switch upstreamType {
+			case "gemini":
+				convert = converters.ConvertGeminiToResponses
+			case "completions":
+				convert = converters.ConvertOpenAICompletionsToResponses
+			}
+			events := convert(
 				c.Request.Context(),
 				originalReq.Model,
 				originalRequestJSON,
@@ -1288,6 +1401,7 @@ func handleStreamSuccess(
 					// 需要修补虚假值
 					eventToSend = patchResponsesCompletedEventUsage(event, originalRequestJSON, outputTextBuffer.String(), &collectedUsage, envCfg)
 				}
+				completedResponse = extractCompletedResponse(eventToSend)
 			}
 
 			// 转发给客户端
@@ -1311,6 +1425,14 @@ func handleStreamSuccess(
 		log.Printf("[Responses-Stream] 警告: 流式响应读取错误: %v", err)
 	}
 
+	// 更新会话（以 response.completed 中的完整响应为准）
+	if len(completedResponse) > 0 {
+		var completed types.ResponsesResponse
+		if err := json.Unmarshal(completedResponse, &completed); err == nil {
+			updateSessionWithResponse(c, sessionManager, originalReq, &completed, completedResponse)
+		}
+	}
+
 	if envCfg.EnableResponseLogs {
 		responseTime := time.Since(startTime).Milliseconds()
 		log.Printf("[Responses-Stream] Responses 流式响应完成: %dms", responseTime)
@@ -1574,6 +1696,20 @@ func isResponsesCompletedEvent(event string) bool {
 		strings.Contains(event, `"type": "response.completed"`)
 }
 
+// extractCompletedResponse 从 response.completed 事件中提取 response 对象
+func extractCompletedResponse(event string) []byte {
+	for _, line := range strings.Split(event, "\n") {
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		jsonStr := strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		if response := gjson.Get(jsonStr, "response"); response.IsObject() {
+			return []byte(response.Raw)
+		}
+	}
+	return nil
+}
+
 // isClientDisconnectError 判断是否为客户端断开连接错误
 func isClientDisconnectError(err error) bool {
 	msg := err.Error()
@@ -27,7 +27,7 @@ func createTestSchedulerWithMetricsConfig(t *testing.T, cfgManager *config.Confi
 	traceAffinity := session.NewTraceAffinityManagerWithTTL(2 * time.Minute)
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	sch := scheduler.NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlManager)
 	cleanup := func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
@@ -0,0 +1,105 @@
+package responses
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newCompletionsRouter(t *testing.T, upstream http.HandlerFunc) (*gin.Engine, func()) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(upstream)
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{{
+			Name:        "base",
+			BaseURL:     server.URL,
+			APIKeys:     []string{"ck1"},
+			ServiceType: "completions",
+			Status:      "active",
+		}},
+		ResponsesLoadBalance: "failover",
+		FuzzyModeEnabled:     true,
+	})
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	sessionManager := session.NewSessionManager(time.Hour, 100, 100000)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1/responses", NewHandler(envCfg, cfgManager, sessionManager, sch, nil, nil, nil, nil, nil))
+	return r, func() {
+		cleanupSch()
+		cleanupCfg()
+		server.Close()
+	}
+}
+
+func TestResponsesHandler_CompletionsUpstream_NonStreamChainsHistory(t *testing.T) {
+	var prompts []string
+	r, cleanup := newCompletionsRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		prompts = append(prompts, gjson.GetBytes(body, "prompt").String())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cmpl_1","model":"base","choices":[{"text":" Paris","finish_reason":"stop"}],"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"base","instructions":"answer briefly","input":"capital of France?"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.Get(w.Body.String(), "output.0.content.0.text").String() != " Paris" {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	id := gjson.Get(w.Body.String(), "id").String()
+
+	w = doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"base","input":"and Italy?","previous_response_id":"`+id+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("chained status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", len(prompts))
+	}
+	if prompts[0] != "answer briefly\n\nUser: capital of France?\n\nAssistant:" {
+		t.Fatalf("first prompt = %q", prompts[0])
+	}
+	if !strings.HasSuffix(prompts[1], "User: capital of France?\n\nAssistant: Paris\n\nUser: and Italy?\n\nAssistant:") {
+		t.Fatalf("second prompt should render history, got %q", prompts[1])
+	}
+}
+
+func TestResponsesHandler_CompletionsUpstream_Stream(t *testing.T) {
+	r, cleanup := newCompletionsRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"id\":\"cmpl_s\",\"choices\":[{\"index\":0,\"text\":\"Par\",\"finish_reason\":null}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"id\":\"cmpl_s\",\"choices\":[{\"index\":0,\"text\":\"is\",\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":4,\"completion_tokens\":2,\"total_tokens\":6}}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"base","input":"capital of France?","stream":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "response.output_text.delta") || !strings.Contains(body, "response.completed") {
+		t.Fatalf("expected converted Responses events, got %s", body)
+	}
+	if !strings.Contains(body, `"text":"Paris"`) {
+		t.Fatalf("completed output should contain full text, got %s", body)
+	}
+}
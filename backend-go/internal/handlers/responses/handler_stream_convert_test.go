@@ -107,3 +107,75 @@ func TestResponsesHandler_Stream_ConvertsOpenAIChatAndPatchesUsage(t *testing.T)
 		t.Fatalf("expected patched usage tokens >0, got: %+v", usage)
 	}
 }
+
+func TestResponsesHandler_GeminiUpstream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var gotPath, gotKey string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-goog-api-key")
+		if strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hi\"}]}}]}\n\n"))
+			_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"!\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2}}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"pong"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1}}`))
+	}))
+	defer upstream.Close()
+
+	cfg := config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{
+			{Name: "gemini", BaseURL: upstream.URL, APIKeys: []string{"gk1"}, ServiceType: "gemini", Status: "active"},
+		},
+	}
+	cfgManager, cleanupCfg := createTestConfigManager(t, cfg)
+	defer cleanupCfg()
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	defer cleanupSch()
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1/responses", NewHandler(envCfg, cfgManager, session.NewSessionManager(time.Hour, 10, 1000), sch, nil, nil, nil, nil, nil))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/responses", bytes.NewBufferString(body))
+		req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("non-stream", func(t *testing.T) {
+		w := post(`{"model":"gemini-2.5-flash","input":"ping"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		if gotPath != "/v1beta/models/gemini-2.5-flash:generateContent" || gotKey != "gk1" {
+			t.Fatalf("upstream path=%q key=%q", gotPath, gotKey)
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if !strings.Contains(w.Body.String(), `"pong"`) || resp["status"] != "completed" {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		w := post(`{"model":"gemini-2.5-flash","input":"ping","stream":true}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		if gotPath != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" {
+			t.Fatalf("upstream path=%q", gotPath)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "event: response.output_text.delta") || !strings.Contains(body, "event: response.completed") {
+			t.Fatalf("unexpected stream body: %s", body)
+		}
+	})
+}
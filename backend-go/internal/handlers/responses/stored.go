@@ -0,0 +1,235 @@
+package responses
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/middleware"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// responseNotFound 返回 OpenAI 格式的 404 错误
+func responseNotFound(c *gin.Context, responseID string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": gin.H{
+			"message": fmt.Sprintf("No response found with id '%s'.", responseID),
+			"type":    "invalid_request_error",
+			"param":   nil,
+			"code":    nil,
+		},
+	})
+}
+
+// lookupStoredResponse 认证并查找已存储的响应，失败时已写出响应
+func lookupStoredResponse(c *gin.Context, envCfg *config.EnvConfig, sessionManager *session.SessionManager) *session.StoredResponse {
+	middleware.ProxyAuthMiddleware(envCfg)(c)
+	if c.IsAborted() {
+		return nil
+	}
+
+	responseID := c.Param("id")
+	if sessionManager == nil {
+		responseNotFound(c, responseID)
+		return nil
+	}
+	stored, err := sessionManager.GetResponse(responseID)
+	if err != nil {
+		responseNotFound(c, responseID)
+		return nil
+	}
+	return stored
+}
+
+// GetResponseHandler 处理 GET /v1/responses/:id
+func GetResponseHandler(envCfg *config.EnvConfig, sessionManager *session.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored := lookupStoredResponse(c, envCfg, sessionManager)
+		if stored == nil {
+			return
+		}
+		c.Data(http.StatusOK, "application/json", stored.Response)
+	}
+}
+
+// DeleteResponseHandler 处理 DELETE /v1/responses/:id
+func DeleteResponseHandler(envCfg *config.EnvConfig, sessionManager *session.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored := lookupStoredResponse(c, envCfg, sessionManager)
+		if stored == nil {
+			return
+		}
+		if err := sessionManager.DeleteResponse(stored.ID); err != nil {
+			responseNotFound(c, stored.ID)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"id":      stored.ID,
+			"object":  "response",
+			"deleted": true,
+		})
+	}
+}
+
+// InputItemsHandler 处理 GET /v1/responses/:id/input_items
+// 支持 OpenAI 分页参数：limit（1-100，默认 20）、order（asc/desc，默认 desc）、after、before
+func InputItemsHandler(envCfg *config.EnvConfig, sessionManager *session.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stored := lookupStoredResponse(c, envCfg, sessionManager)
+		if stored == nil {
+			return
+		}
+
+		limit := 20
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "limit must be between 1 and 100", "type": "invalid_request_error", "param": "limit"}})
+				return
+			}
+			limit = n
+		}
+		order := c.DefaultQuery("order", "desc")
+		if order != "asc" && order != "desc" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"message": "order must be 'asc' or 'desc'", "type": "invalid_request_error", "param": "order"}})
+			return
+		}
+
+		items := make([]types.ResponsesItem, len(stored.InputItems))
+		copy(items, stored.InputItems)
+		if order == "desc" {
+			for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+				items[i], items[j] = items[j], items[i]
+			}
+		}
+
+		after, before := c.Query("after"), c.Query("before")
+		start, end := 0, len(items)
+		for i := range items {
+			if after != "" && items[i].ID == after {
+				start = i + 1
+			}
+			if before != "" && items[i].ID == before {
+				end = i
+			}
+		}
+		if start > end {
+			start = end
+		}
+		hasMore := end-start > limit
+		if hasMore {
+			end = start + limit
+		}
+
+		data := make([]json.RawMessage, 0, end-start)
+		for _, item := range items[start:end] {
+			data = append(data, toInputItemObject(item))
+		}
+
+		resp := gin.H{
+			"object":   "list",
+			"data":     data,
+			"first_id": nil,
+			"last_id":  nil,
+			"has_more": hasMore,
+		}
+		if end > start {
+			resp["first_id"] = items[start].ID
+			resp["last_id"] = items[end-1].ID
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// toStoredResponseObject 将响应对象规范化为 OpenAI Responses 格式
+// 内部转换结果使用 created/previous_id 字段，这里统一为 created_at/previous_response_id
+func toStoredResponseObject(raw []byte, originalReq *types.ResponsesRequest) []byte {
+	if !gjson.ValidBytes(raw) {
+		return raw
+	}
+	out := raw
+
+	if !gjson.GetBytes(out, "object").Exists() {
+		out, _ = sjson.SetBytes(out, "object", "response")
+	}
+	if !gjson.GetBytes(out, "created_at").Exists() {
+		createdAt := gjson.GetBytes(out, "created").Int()
+		if createdAt == 0 {
+			createdAt = time.Now().Unix()
+		}
+		out, _ = sjson.SetBytes(out, "created_at", createdAt)
+	}
+	out, _ = sjson.DeleteBytes(out, "created")
+	out, _ = sjson.DeleteBytes(out, "previous_id")
+
+	if !gjson.GetBytes(out, "previous_response_id").Exists() {
+		if originalReq != nil && originalReq.PreviousResponseID != "" {
+			out, _ = sjson.SetBytes(out, "previous_response_id", originalReq.PreviousResponseID)
+		} else {
+			out, _ = sjson.SetRawBytes(out, "previous_response_id", []byte("null"))
+		}
+	}
+	return out
+}
+
+// storedInputItems 完整保留请求输入项（含 role/call_id 等字段），用于 input_items 查询
+// 字符串输入视为一条 user 消息；省略 type 的消息简写补全为 message
+func storedInputItems(input interface{}) []types.ResponsesItem {
+	switch v := input.(type) {
+	case string:
+		return []types.ResponsesItem{{Type: "message", Role: "user", Content: v}}
+	case []interface{}:
+		items := make([]types.ResponsesItem, 0, len(v))
+		for _, raw := range v {
+			data, err := json.Marshal(raw)
+			if err != nil {
+				continue
+			}
+			var item types.ResponsesItem
+			if err := json.Unmarshal(data, &item); err != nil {
+				continue
+			}
+			if item.Type == "" && item.Role != "" {
+				item.Type = "message"
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+	return nil
+}
+
+// toInputItemObject 将输入项转换为 OpenAI input_items 格式
+// 字符串 content 展开为 input_text/output_text 内容块
+func toInputItemObject(item types.ResponsesItem) json.RawMessage {
+	out, err := json.Marshal(item)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	if item.Content == nil {
+		out, _ = sjson.DeleteBytes(out, "content")
+	}
+	if item.Type != "message" {
+		return out
+	}
+
+	if text, ok := item.Content.(string); ok {
+		blockType := "input_text"
+		if item.Role == "assistant" {
+			blockType = "output_text"
+		}
+		block, _ := json.Marshal([]types.ContentBlock{{Type: blockType, Text: text}})
+		out, _ = sjson.SetRawBytes(out, "content", block)
+	}
+	if !gjson.GetBytes(out, "status").Exists() {
+		out, _ = sjson.SetBytes(out, "status", "completed")
+	}
+	return out
+}
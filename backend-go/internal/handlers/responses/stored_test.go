@@ -0,0 +1,183 @@
+package responses
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newStoredResponsesRouter(t *testing.T, upstream http.HandlerFunc) (*gin.Engine, *session.SessionManager, func()) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(upstream)
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{{
+			Name:        "r0",
+			BaseURL:     server.URL,
+			APIKeys:     []string{"rk1"},
+			ServiceType: "responses",
+			Status:      "active",
+		}},
+		ResponsesLoadBalance: "failover",
+		FuzzyModeEnabled:     true,
+	})
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	sessionManager := session.NewSessionManager(time.Hour, 100, 100000)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	r := gin.New()
+	r.POST("/v1/responses", NewHandler(envCfg, cfgManager, sessionManager, sch, nil, nil, nil, nil, nil))
+	r.GET("/v1/responses/:id", GetResponseHandler(envCfg, sessionManager))
+	r.DELETE("/v1/responses/:id", DeleteResponseHandler(envCfg, sessionManager))
+	r.GET("/v1/responses/:id/input_items", InputItemsHandler(envCfg, sessionManager))
+	return r, sessionManager, func() {
+		cleanupSch()
+		cleanupCfg()
+		server.Close()
+	}
+}
+
+func doStoredRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStoredResponses_GetInputItemsAndDelete(t *testing.T) {
+	r, sessionManager, cleanup := newStoredResponsesRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_1","model":"gpt-4o","status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"hi"}]}],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses",
+		`{"model":"gpt-4o","input":[{"role":"user","content":"hello"},{"type":"function_call_output","call_id":"call_1","output":"42"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status=%d body=%s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if gjson.Get(body, "object").String() != "response" || gjson.Get(body, "created_at").Int() == 0 {
+		t.Fatalf("expected OpenAI response object, got %s", body)
+	}
+	if gjson.Get(body, "output.0.content.0.text").String() != "hi" {
+		t.Fatalf("unexpected output: %s", body)
+	}
+	if v := gjson.Get(body, "previous_response_id"); !v.Exists() || v.Type != gjson.Null {
+		t.Fatalf("previous_response_id should be null, got %s", body)
+	}
+	if gjson.Get(body, "previous_id").Exists() || gjson.Get(body, "created").Exists() {
+		t.Fatalf("internal fields must not leak: %s", body)
+	}
+
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_1/input_items?order=asc", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("input_items status=%d body=%s", w.Code, w.Body.String())
+	}
+	body = w.Body.String()
+	if gjson.Get(body, "object").String() != "list" || gjson.Get(body, "data.#").Int() != 2 {
+		t.Fatalf("unexpected input_items: %s", body)
+	}
+	if gjson.Get(body, "data.0.type").String() != "message" ||
+		gjson.Get(body, "data.0.content.0.type").String() != "input_text" ||
+		gjson.Get(body, "data.0.content.0.text").String() != "hello" {
+		t.Fatalf("message item not normalized: %s", body)
+	}
+	if gjson.Get(body, "data.1.call_id").String() != "call_1" || gjson.Get(body, "data.1.content").Exists() {
+		t.Fatalf("function_call_output item not preserved: %s", body)
+	}
+	if gjson.Get(body, "first_id").String() == "" || gjson.Get(body, "first_id").String() != gjson.Get(body, "data.0.id").String() {
+		t.Fatalf("first_id mismatch: %s", body)
+	}
+
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_1/input_items?limit=1", "")
+	if gjson.Get(w.Body.String(), "data.#").Int() != 1 || !gjson.Get(w.Body.String(), "has_more").Bool() ||
+		gjson.Get(w.Body.String(), "data.0.type").String() != "function_call_output" {
+		t.Fatalf("desc pagination mismatch: %s", w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodDelete, "/v1/responses/resp_1", "")
+	if w.Code != http.StatusOK || !gjson.Get(w.Body.String(), "deleted").Bool() {
+		t.Fatalf("delete status=%d body=%s", w.Code, w.Body.String())
+	}
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_1", "")
+	if w.Code != http.StatusNotFound || gjson.Get(w.Body.String(), "error.type").String() != "invalid_request_error" {
+		t.Fatalf("get after delete status=%d body=%s", w.Code, w.Body.String())
+	}
+	if _, err := sessionManager.GetOrCreateSession("resp_1"); err == nil {
+		t.Fatalf("deleted response must not be usable as previous_response_id")
+	}
+}
+
+func TestStoredResponses_StreamCompletedResponseIsStored(t *testing.T) {
+	r, _, cleanup := newStoredResponsesRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"type\":\"response.output_text.delta\",\"delta\":\"hi\"}\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_s\",\"object\":\"response\",\"created_at\":1700000000,\"model\":\"gpt-4o\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"hi\"}]}],\"usage\":{\"input_tokens\":1,\"output_tokens\":1,\"total_tokens\":2}}}\n"))
+	})
+	defer cleanup()
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"hello","stream":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("stream status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_s", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status=%d body=%s", w.Code, w.Body.String())
+	}
+	if gjson.Get(w.Body.String(), "created_at").Int() != 1700000000 {
+		t.Fatalf("expected completed response to be stored as-is, got %s", w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodGet, "/v1/responses/resp_s/input_items", "")
+	if gjson.Get(w.Body.String(), "data.0.role").String() != "user" ||
+		gjson.Get(w.Body.String(), "data.0.content.0.text").String() != "hello" {
+		t.Fatalf("string input should be stored as a user message: %s", w.Body.String())
+	}
+
+	w = doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"again","stream":true,"previous_response_id":"resp_s"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("streamed response should be chainable via previous_response_id, status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestStoredResponses_UnknownIDAndStoreFalse(t *testing.T) {
+	r, _, cleanup := newStoredResponsesRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"resp_nostore","model":"gpt-4o","status":"completed","output":[],"usage":{"input_tokens":1,"output_tokens":1,"total_tokens":2}}`))
+	})
+	defer cleanup()
+
+	for _, path := range []string{"/v1/responses/resp_missing", "/v1/responses/resp_missing/input_items"} {
+		if w := doStoredRequest(r, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+			t.Fatalf("GET %s status=%d, want 404", path, w.Code)
+		}
+	}
+	if w := doStoredRequest(r, http.MethodDelete, "/v1/responses/resp_missing", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("DELETE status=%d, want 404", w.Code)
+	}
+
+	w := doStoredRequest(r, http.MethodPost, "/v1/responses", `{"model":"gpt-4o","input":"hi","store":false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("create status=%d", w.Code)
+	}
+	if w := doStoredRequest(r, http.MethodGet, "/v1/responses/resp_nostore", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("store=false response must not be retrievable, status=%d", w.Code)
+	}
+}
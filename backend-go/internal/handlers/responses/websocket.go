@@ -0,0 +1,242 @@
+package responses
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/coder/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== Responses WebSocket 传输 ==============
+//
+// 客户端在 /v1/responses 上建立 WebSocket 长连接，每轮发送一个 response.create 帧
+// （请求体字段与 POST /v1/responses 相同，附加 "type":"response.create"）。
+// 每一帧通过 replay 重新进入 Handle（强制 stream=true），与 HTTP 请求共享渠道调度、
+// 会话与 Trace 亲和性；上游的 response.* SSE 事件逐条作为文本帧返回。
+
+// websocketCreateEvent 客户端发起一轮响应的帧类型
+const websocketCreateEvent = "response.create"
+
+// isWebSocketUpgrade 判断请求是否为 WebSocket 升级请求
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleWebSocket 处理 Responses WebSocket 连接（认证已在 Handle 入口完成）
+func (h *Handler) handleWebSocket(c *gin.Context) {
+	conn, err := websocket.Accept(c.Writer, c.Request, nil)
+	if err != nil {
+		// Accept 失败时已写入 HTTP 错误响应
+		log.Printf("[Responses-WebSocket] 升级失败: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(h.envCfg.MaxRequestBodySize)
+
+	header := websocketReplayHeader(c.Request.Header)
+	log.Printf("[Responses-WebSocket] 连接已建立: %s", c.ClientIP())
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// 独立读取协程：客户端关闭连接时立即取消进行中的上游请求
+	frames := make(chan []byte)
+	go func() {
+		defer cancel()
+		defer close(frames)
+		for {
+			typ, data, err := conn.Read(ctx)
+			if err != nil {
+				if status := websocket.CloseStatus(err); status != websocket.StatusNormalClosure && status != websocket.StatusGoingAway && !errors.Is(err, context.Canceled) {
+					log.Printf("[Responses-WebSocket] 读取失败: %v", err)
+				}
+				return
+			}
+			if typ != websocket.MessageText {
+				writeWebSocketError(ctx, conn, http.StatusBadRequest, "invalid_request_error", "binary frames are not supported")
+				continue
+			}
+			select {
+			case frames <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 同一连接上的响应按顺序处理
+	for frame := range frames {
+		h.serveWebSocketFrame(ctx, conn, header, frame)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+	log.Printf("[Responses-WebSocket] 连接已关闭: %s", c.ClientIP())
+}
+
+// serveWebSocketFrame 处理单个客户端帧
+func (h *Handler) serveWebSocketFrame(ctx context.Context, conn *websocket.Conn, header http.Header, frame []byte) {
+	if !gjson.ValidBytes(frame) {
+		writeWebSocketError(ctx, conn, http.StatusBadRequest, "invalid_request_error", "Invalid JSON")
+		return
+	}
+	if eventType := gjson.GetBytes(frame, "type").String(); eventType != websocketCreateEvent {
+		writeWebSocketError(ctx, conn, http.StatusBadRequest, "invalid_request_error", "unsupported event type: "+eventType)
+		return
+	}
+	if isBackgroundRequest(frame) {
+		writeWebSocketError(ctx, conn, http.StatusBadRequest, "invalid_request_error", "background mode is not supported over WebSocket")
+		return
+	}
+
+	body, _ := sjson.DeleteBytes(frame, "type")
+	body, _ = sjson.SetBytes(body, "stream", true)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/responses", bytes.NewReader(body))
+	if err != nil {
+		writeWebSocketError(ctx, conn, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	httpReq.Header = header.Clone()
+
+	w := newWebSocketEventWriter(ctx, conn)
+	h.replayEngine().ServeHTTP(w, httpReq)
+	w.finish()
+}
+
+// websocketReplayHeader 构建重放请求头：保留认证与会话标识，去掉升级相关头部
+// 客户端未携带会话标识时，使用连接级 Session_id，使同一连接的多轮请求命中同一渠道
+func websocketReplayHeader(src http.Header) http.Header {
+	header := src.Clone()
+	for _, name := range []string{"Upgrade", "Connection", "Content-Length", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol"} {
+		header.Del(name)
+	}
+	header.Set("Content-Type", "application/json")
+	if header.Get("Conversation_id") == "" && header.Get("Session_id") == "" {
+		header.Set("Session_id", "ws_"+uuid.NewString())
+	}
+	return header
+}
+
+// writeWebSocketError 向客户端发送 error 帧
+func writeWebSocketError(ctx context.Context, conn *websocket.Conn, status int, errType, message string) {
+	out := []byte(`{"type":"error"}`)
+	out, _ = sjson.SetBytes(out, "status", status)
+	out, _ = sjson.SetBytes(out, "error.type", errType)
+	out, _ = sjson.SetBytes(out, "error.message", message)
+	_ = conn.Write(ctx, websocket.MessageText, out)
+}
+
+// websocketEventWriter 将重放请求的 SSE 输出转换为 WebSocket 文本帧
+// 非流式输出（认证失败、渠道全部不可用等错误响应）在 finish 时转换为 error 帧
+type websocketEventWriter struct {
+	ctx     context.Context
+	conn    *websocket.Conn
+	header  http.Header
+	status  int
+	pending bytes.Buffer
+	body    bytes.Buffer
+	sent    bool
+}
+
+func newWebSocketEventWriter(ctx context.Context, conn *websocket.Conn) *websocketEventWriter {
+	return &websocketEventWriter{ctx: ctx, conn: conn, header: http.Header{}, status: http.StatusOK}
+}
+
+func (w *websocketEventWriter) Header() http.Header { return w.header }
+
+func (w *websocketEventWriter) WriteHeader(status int) { w.status = status }
+
+// Flush 帧在 Write 时已发送，无需额外刷新
+func (w *websocketEventWriter) Flush() {}
+
+func (w *websocketEventWriter) isEventStream() bool {
+	return w.status < http.StatusBadRequest && strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+}
+
+func (w *websocketEventWriter) Write(p []byte) (int, error) {
+	if !w.isEventStream() {
+		return w.body.Write(p)
+	}
+
+	w.pending.Write(p)
+	for {
+		buf := w.pending.Bytes()
+		idx := bytes.Index(buf, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		event := string(buf[:idx])
+		w.pending.Next(idx + 2)
+		if err := w.sendEvent(event); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// sendEvent 提取 SSE 事件的 data 并作为文本帧发送
+func (w *websocketEventWriter) sendEvent(event string) error {
+	var data []string
+	for _, line := range strings.Split(event, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	payload := strings.Join(data, "\n")
+	if payload == "" || payload == "[DONE]" {
+		return nil
+	}
+	w.sent = true
+	return w.conn.Write(w.ctx, websocket.MessageText, []byte(payload))
+}
+
+// finish 发送残留事件；未产生任何事件时将错误响应转换为 error 帧
+func (w *websocketEventWriter) finish() {
+	if w.isEventStream() {
+		if rest := strings.TrimSpace(w.pending.String()); rest != "" {
+			_ = w.sendEvent(rest)
+		}
+		if w.sent {
+			return
+		}
+	}
+
+	status := w.status
+	if status < http.StatusBadRequest {
+		status = http.StatusBadGateway
+	}
+	body := w.body.Bytes()
+	errType := gjson.GetBytes(body, "error.type").String()
+	if errType == "" {
+		errType = "server_error"
+	}
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		message = gjson.GetBytes(body, "error").String()
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	writeWebSocketError(w.ctx, w.conn, status, errType, message)
+}
@@ -0,0 +1,195 @@
+package responses
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/coder/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// newWebSocketServer 启动挂载 Responses 处理器（POST + WebSocket）的测试服务
+func newWebSocketServer(t *testing.T, upstream http.HandlerFunc) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	upstreamServer := httptest.NewServer(upstream)
+	t.Cleanup(upstreamServer.Close)
+
+	cfgManager, cleanupCfg := createTestConfigManager(t, config.Config{
+		ResponsesUpstream: []config.UpstreamConfig{{
+			Name:        "r0",
+			BaseURL:     upstreamServer.URL,
+			APIKeys:     []string{"rk1"},
+			ServiceType: "responses",
+			Status:      "active",
+		}},
+		ResponsesLoadBalance: "failover",
+		FuzzyModeEnabled:     true,
+	})
+	t.Cleanup(cleanupCfg)
+	sch, cleanupSch := createTestScheduler(t, cfgManager)
+	t.Cleanup(cleanupSch)
+
+	envCfg := &config.EnvConfig{ProxyAccessKey: "secret", MaxRequestBodySize: 1024 * 1024}
+	handler := NewHandler(envCfg, cfgManager, session.NewSessionManager(time.Hour, 100, 100000), sch, nil, nil, nil, nil, nil)
+	r := gin.New()
+	r.POST("/v1/responses", handler)
+	r.GET("/v1/responses", handler)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/responses"
+}
+
+func dialResponsesWebSocket(t *testing.T, ctx context.Context, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{HTTPHeader: http.Header{"X-Api-Key": []string{"secret"}}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+// readUntil 读取帧直到出现指定类型，返回期间收到的全部帧
+func readUntil(t *testing.T, ctx context.Context, conn *websocket.Conn, eventType string) []string {
+	t.Helper()
+	var frames []string
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Read: %v (frames so far: %v)", err, frames)
+		}
+		frames = append(frames, string(data))
+		if gjson.GetBytes(data, "type").String() == eventType {
+			return frames
+		}
+	}
+}
+
+func streamingResponsesUpstream(hits *int32, bodies chan<- string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+
+		id := fmt.Sprintf("resp_ws_%d", n)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"" + id + "\",\"status\":\"in_progress\"}}\n\n"))
+		_, _ = w.Write([]byte("event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"hi\"}\n\n"))
+		_, _ = w.Write([]byte("event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"" + id + "\",\"model\":\"gpt-4o\",\"status\":\"completed\",\"output\":[{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"hi\"}]}],\"usage\":{\"input_tokens\":1,\"output_tokens\":1,\"total_tokens\":2}}}\n\n"))
+	}
+}
+
+func TestWebSocket_MultipleTurnsOnOneConnection(t *testing.T) {
+	var hits int32
+	bodies := make(chan string, 2)
+	url := newWebSocketServer(t, streamingResponsesUpstream(&hits, bodies))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialResponsesWebSocket(t, ctx, url)
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type":"response.create","model":"gpt-4o","input":"hello"}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	frames := readUntil(t, ctx, conn, "response.completed")
+	if len(frames) != 3 || gjson.Get(frames[0], "type").String() != "response.created" || gjson.Get(frames[1], "delta").String() != "hi" {
+		t.Fatalf("unexpected frames: %v", frames)
+	}
+	firstID := gjson.Get(frames[2], "response.id").String()
+
+	upstreamBody := <-bodies
+	if !gjson.Get(upstreamBody, "stream").Bool() || gjson.Get(upstreamBody, "type").Exists() {
+		t.Fatalf("upstream should receive a streaming Responses request without the frame type: %s", upstreamBody)
+	}
+
+	// 第二轮沿用同一连接，并可通过 previous_response_id 续接会话
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"type":"response.create","model":"gpt-4o","input":"again","previous_response_id":"`+firstID+`"}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	frames = readUntil(t, ctx, conn, "response.completed")
+	if gjson.Get(frames[len(frames)-1], "response.status").String() != "completed" {
+		t.Fatalf("unexpected frames: %v", frames)
+	}
+	if upstreamBody = <-bodies; !strings.Contains(upstreamBody, "again") {
+		t.Fatalf("unexpected second upstream body: %s", upstreamBody)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("hits=%d, want 2", hits)
+	}
+
+	if err := conn.Close(websocket.StatusNormalClosure, ""); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestWebSocket_ErrorFrames(t *testing.T) {
+	url := newWebSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"context too long","type":"invalid_request_error"}}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialResponsesWebSocket(t, ctx, url)
+
+	tests := []struct {
+		frame   string
+		status  int64
+		message string
+	}{
+		{frame: `{"type":"response.cancel"}`, status: http.StatusBadRequest, message: "unsupported event type"},
+		{frame: `not json`, status: http.StatusBadRequest, message: "Invalid JSON"},
+		{frame: `{"type":"response.create","model":"gpt-4o","input":"x","background":true}`, status: http.StatusBadRequest, message: "background"},
+		// 上游错误导致渠道全部失败时，HTTP 错误响应转换为 error 帧
+		{frame: `{"type":"response.create","model":"gpt-4o","input":"x"}`, status: http.StatusServiceUnavailable, message: "unavailable"},
+	}
+	for _, tt := range tests {
+		if err := conn.Write(ctx, websocket.MessageText, []byte(tt.frame)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		frames := readUntil(t, ctx, conn, "error")
+		last := frames[len(frames)-1]
+		if gjson.Get(last, "status").Int() != tt.status || !strings.Contains(gjson.Get(last, "error.message").String(), tt.message) {
+			t.Fatalf("frame %s: unexpected error %s", tt.frame, last)
+		}
+	}
+}
+
+func TestWebSocket_RequiresAuth(t *testing.T) {
+	url := newWebSocketServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("upstream must not be called")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, resp, err := websocket.Dial(ctx, url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before upgrade, err=%v resp=%v", err, resp)
+	}
+}
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/responses", nil)
+	req.Header.Set("Upgrade", "WebSocket")
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	if !isWebSocketUpgrade(req) {
+		t.Fatalf("expected upgrade request")
+	}
+	req.Header.Set("Connection", "keep-alive")
+	if isWebSocketUpgrade(req) {
+		t.Fatalf("Connection without upgrade token must not be treated as WebSocket")
+	}
+}
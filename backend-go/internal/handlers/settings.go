@@ -13,6 +13,13 @@ func mappingOrEmpty(mapping map[string]string) map[string]string {
 	return mapping
 }
 
+func budgetsOrEmpty(budgets map[string]int) map[string]int {
+	if len(budgets) == 0 {
+		return map[string]int{}
+	}
+	return budgets
+}
+
 // GetFuzzyMode 获取 Fuzzy 模式状态
 func GetFuzzyMode(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -77,20 +84,22 @@ func SetGlobalModelMapping(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	}
 }
 
-// GetGlobalReasoningMapping 获取全局思考重定向
+// GetGlobalReasoningMapping 获取全局思考重定向与思考预算表
 func GetGlobalReasoningMapping(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"globalReasoningMapping": mappingOrEmpty(cfgManager.GetGlobalReasoningMapping()),
+			"globalReasoningBudgets": budgetsOrEmpty(cfgManager.GetGlobalReasoningBudgets()),
 		})
 	}
 }
 
-// SetGlobalReasoningMapping 设置全局思考重定向
+// SetGlobalReasoningMapping 设置全局思考重定向（请求携带 globalReasoningBudgets 时同时更新思考预算表）
 func SetGlobalReasoningMapping(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			GlobalReasoningMapping map[string]string `json:"globalReasoningMapping"`
+			GlobalReasoningBudgets *map[string]int   `json:"globalReasoningBudgets"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": "Invalid request body"})
@@ -101,10 +110,17 @@ func SetGlobalReasoningMapping(cfgManager *config.ConfigManager) gin.HandlerFunc
 			c.JSON(500, gin.H{"error": "Failed to save config"})
 			return
 		}
+		if req.GlobalReasoningBudgets != nil {
+			if err := cfgManager.SetGlobalReasoningBudgets(*req.GlobalReasoningBudgets); err != nil {
+				c.JSON(500, gin.H{"error": "Failed to save config"})
+				return
+			}
+		}
 
 		c.JSON(200, gin.H{
 			"success":                true,
 			"globalReasoningMapping": mappingOrEmpty(cfgManager.GetGlobalReasoningMapping()),
+			"globalReasoningBudgets": budgetsOrEmpty(cfgManager.GetGlobalReasoningBudgets()),
 		})
 	}
 }
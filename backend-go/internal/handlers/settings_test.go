@@ -186,6 +186,44 @@ func TestGlobalMappingHandlers_GetAndSet(t *testing.T) {
 			t.Fatalf("reasoning mapping=%v want=%v", resp.Mapping, expected)
 		}
 	}
+
+	// PUT reasoning budgets（携带 globalReasoningBudgets 时同时更新预算表）
+	{
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/settings/reasoning-mapping", bytes.NewBufferString(`{"globalReasoningMapping":{"low":"xhigh"},"globalReasoningBudgets":{" HIGH ":24000,"medium":0}}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT reasoning budgets status=%d body=%s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Mapping map[string]string `json:"globalReasoningMapping"`
+			Budgets map[string]int    `json:"globalReasoningBudgets"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal reasoning budgets: %v", err)
+		}
+		if !reflect.DeepEqual(resp.Budgets, map[string]int{"high": 24000}) {
+			t.Fatalf("reasoning budgets=%v", resp.Budgets)
+		}
+		if !reflect.DeepEqual(resp.Mapping, map[string]string{"low": "xhigh"}) {
+			t.Fatalf("reasoning mapping=%v", resp.Mapping)
+		}
+	}
+
+	// PUT without budgets keeps the existing table
+	{
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/settings/reasoning-mapping", bytes.NewBufferString(`{"globalReasoningMapping":{}}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT reasoning mapping status=%d", w.Code)
+		}
+		if got := cm.GetGlobalReasoningBudgets(); !reflect.DeepEqual(got, map[string]int{"high": 24000}) {
+			t.Fatalf("reasoning budgets should be kept, got %v", got)
+		}
+	}
 }
 
 func TestSetFuzzyMode_SaveFailReturns500(t *testing.T) {
@@ -47,7 +47,7 @@ func newTestScheduler(t *testing.T, cm *config.ConfigManager) (*scheduler.Channe
 	traceAffinity := session.NewTraceAffinityManagerWithTTL(2 * time.Minute)
 	urlMgr := warmup.NewURLManager(30*time.Second, 3)
 
-	sch := scheduler.NewChannelScheduler(cm, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlMgr)
+	sch := scheduler.NewChannelScheduler(cm, messagesMetrics, responsesMetrics, geminiMetrics, nil, traceAffinity, urlMgr)
 	cleanup := func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
@@ -9,6 +9,7 @@
 	"strings"
 	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/httpclient"
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +18,10 @@ type probeUpstreamModelsRequest struct {
 	BaseURL            string `json:"baseUrl"`
 	APIKey             string `json:"apiKey"`
 	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	// FillSupportedModels 为 true 时将探测到的模型写入指定渠道的 supportedModels
+	FillSupportedModels bool   `json:"fillSupportedModels,omitempty"`
+	ChannelType         string `json:"channelType,omitempty"` // messages, responses, gemini, embeddings
+	ChannelIndex        *int   `json:"channelIndex,omitempty"`
 }
 
 type probeUpstreamModelsResponse struct {
@@ -24,6 +29,8 @@ type probeUpstreamModelsResponse struct {
 	StatusCode    int             `json:"statusCode,omitempty"`
 	UpstreamError string          `json:"upstreamError,omitempty"`
 	Models        *modelsResponse `json:"models,omitempty"`
+	// SupportedModelsUpdated 是否已将探测结果写入渠道 supportedModels
+	SupportedModelsUpdated bool `json:"supportedModelsUpdated,omitempty"`
 }
 
 type modelsResponse struct {
@@ -40,13 +47,19 @@ type modelEntry struct {
 
 var modelsVersionSuffixPattern = regexp.MustCompile(`/v\d+[a-z]*$`)
 
-func ProbeUpstreamModels() gin.HandlerFunc {
+// ProbeUpstreamModels 探测上游 /v1/models 模型列表
+// fillSupportedModels 为 true 时同时将结果写入 channelType/channelIndex 指定渠道的 supportedModels
+func ProbeUpstreamModels(cfgManager *config.ConfigManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req probeUpstreamModelsRequest
 		if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.BaseURL) == "" || strings.TrimSpace(req.APIKey) == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			return
 		}
+		if req.FillSupportedModels && (cfgManager == nil || req.ChannelIndex == nil || !isKnownChannelType(req.ChannelType)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "channelType and channelIndex are required to fill supportedModels"})
+			return
+		}
 
 		targetURL := buildModelsURL(strings.TrimSpace(req.BaseURL))
 		if targetURL == "" {
@@ -100,12 +113,61 @@ func ProbeUpstreamModels() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, probeUpstreamModelsResponse{
+		result := probeUpstreamModelsResponse{
 			Success:    true,
 			StatusCode: http.StatusOK,
 			Models:     &models,
-		})
+		}
+		if req.FillSupportedModels {
+			ids := make([]string, 0, len(models.Data))
+			for _, m := range models.Data {
+				if id := strings.TrimSpace(m.ID); id != "" {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Upstream returned no models"})
+				return
+			}
+			if err := updateSupportedModels(cfgManager, req.ChannelType, *req.ChannelIndex, ids); err != nil {
+				if strings.Contains(err.Error(), "上游索引") {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Upstream not found"})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save config"})
+				}
+				return
+			}
+			result.SupportedModelsUpdated = true
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// isKnownChannelType 判断渠道池类型是否有效
+func isKnownChannelType(channelType string) bool {
+	switch channelType {
+	case "messages", "responses", "gemini", "embeddings":
+		return true
+	}
+	return false
+}
+
+// updateSupportedModels 将模型列表写入指定渠道池中渠道的 supportedModels
+func updateSupportedModels(cfgManager *config.ConfigManager, channelType string, index int, models []string) error {
+	updates := config.UpstreamUpdate{SupportedModels: models}
+	var err error
+	switch channelType {
+	case "responses":
+		_, err = cfgManager.UpdateResponsesUpstream(index, updates)
+	case "gemini":
+		_, err = cfgManager.UpdateGeminiUpstream(index, updates)
+	case "embeddings":
+		_, err = cfgManager.UpdateEmbeddingsUpstream(index, updates)
+	default:
+		_, err = cfgManager.UpdateUpstream(index, updates)
 	}
+	return err
 }
 
 func buildModelsURL(inputBaseURL string) string {
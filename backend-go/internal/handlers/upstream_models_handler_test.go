@@ -38,9 +38,12 @@ func TestProbeUpstreamModels(t *testing.T) {
 	defer upstream.Close()
 
 	envCfg := &config.EnvConfig{ProxyAccessKey: "secret-key", EnableWebUI: true}
+	cfgManager, _ := newTestConfigManager(t, config.Config{
+		Upstream: []config.UpstreamConfig{{Name: "a", BaseURL: upstream.URL, APIKeys: []string{"test-key"}, ServiceType: "openai"}},
+	})
 	r := gin.New()
 	r.Use(middleware.WebAuthMiddleware(envCfg, nil))
-	r.POST("/api/admin/upstream/models", ProbeUpstreamModels())
+	r.POST("/api/admin/upstream/models", ProbeUpstreamModels(cfgManager))
 
 	t.Run("missing key returns 401", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/api/admin/upstream/models", bytes.NewReader([]byte(`{}`)))
@@ -139,4 +142,57 @@ func TestProbeUpstreamModels(t *testing.T) {
 			t.Fatalf("upstreamError should not be empty")
 		}
 	})
+
+	t.Run("fillSupportedModels writes channel supportedModels", func(t *testing.T) {
+		payload := map[string]any{"baseUrl": upstream.URL, "apiKey": "test-key", "fillSupportedModels": true, "channelType": "messages", "channelIndex": 0}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/upstream/models", bytes.NewReader(body))
+		req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body=%s)", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp probeUpstreamModelsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if !resp.SupportedModelsUpdated {
+			t.Fatalf("supportedModelsUpdated = false, want true")
+		}
+		got := cfgManager.GetConfig().Upstream[0].SupportedModels
+		if len(got) != 1 || got[0] != "gpt-4o" {
+			t.Fatalf("supportedModels = %v, want [gpt-4o]", got)
+		}
+	})
+
+	t.Run("fillSupportedModels without channel returns 400", func(t *testing.T) {
+		payload := map[string]any{"baseUrl": upstream.URL, "apiKey": "test-key", "fillSupportedModels": true}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/upstream/models", bytes.NewReader(body))
+		req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("fillSupportedModels with unknown channel returns 404", func(t *testing.T) {
+		payload := map[string]any{"baseUrl": upstream.URL, "apiKey": "test-key", "fillSupportedModels": true, "channelType": "gemini", "channelIndex": 3}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/upstream/models", bytes.NewReader(body))
+		req.Header.Set("x-api-key", envCfg.ProxyAccessKey)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
 }
@@ -44,6 +44,8 @@ type KeyMetrics struct {
 	recentResults []bool // true=success, false=failure
 	// 带时间戳的请求记录（用于分时段统计，按 retention 保留）
 	requestHistory []RequestRecord
+	// TTFB 延迟统计（EWMA + 最近样本）
+	latency *latencyTracker
 }
 
 // ChannelMetrics 渠道聚合指标（用于 API 返回，兼容旧结构）
@@ -76,6 +78,8 @@ type TimeWindowStats struct {
 	// CacheHitRate 缓存命中率（Token口径），范围 0-100
 	// 定义：cacheReadTokens / (cacheReadTokens + inputTokens) * 100
 	CacheHitRate float64 `json:"cacheHitRate,omitempty"`
+	// CostCents 按官方价格估算的成本（美分，未应用渠道价格倍率）
+	CostCents int64 `json:"costCents,omitempty"`
 }
 
 // MetricsManager 指标管理器
@@ -773,6 +777,7 @@ func (m *MetricsManager) ResetKey(baseURL, apiKey string) {
 		metrics.CircuitBrokenAt = nil
 		metrics.recentResults = make([]bool, 0, m.windowSize)
 		metrics.requestHistory = nil
+		metrics.latency = nil
 		log.Printf("[Metrics-Reset] Key [%s] (%s) 指标已完全重置", metrics.KeyMask, metrics.BaseURL)
 	}
 }
@@ -964,6 +969,7 @@ type MetricsResponse struct {
 	LastFailureAt       *string                    `json:"lastFailureAt,omitempty"`
 	CircuitBrokenAt     *string                    `json:"circuitBrokenAt,omitempty"`
 	TimeWindows         map[string]TimeWindowStats `json:"timeWindows,omitempty"`
+	TTFB                *LatencyStats              `json:"ttfb,omitempty"`       // 首字节时间统计（EWMA / p50 / p95）
 	KeyMetrics          []*KeyMetricsResponse      `json:"keyMetrics,omitempty"` // 各 Key 的详细指标
 }
 
@@ -972,15 +978,16 @@ type KeyMetricsResponse struct {
 	KeyID   string `json:"keyId,omitempty"`
 	KeyMask string `json:"keyMask"`
 	// LogRequestCount 为按“请求日志”口径统计的累计请求数（由请求日志模块维护，支持重置清零）。
-	LogRequestCount     int64   `json:"logRequestCount,omitempty"`
-	RequestCount        int64   `json:"requestCount"`
-	SuccessCount        int64   `json:"successCount"`
-	FailureCount        int64   `json:"failureCount"`
-	SuccessRate         float64 `json:"successRate"`
-	ConsecutiveFailures int64   `json:"consecutiveFailures"`
-	CircuitBroken       bool    `json:"circuitBroken"`
-	SuspendUntil        *string `json:"suspendUntil,omitempty"`  // 硬熔断截止时间（例如额度不足到 0 点恢复）
-	SuspendReason       string  `json:"suspendReason,omitempty"` // 硬熔断原因
+	LogRequestCount     int64         `json:"logRequestCount,omitempty"`
+	RequestCount        int64         `json:"requestCount"`
+	SuccessCount        int64         `json:"successCount"`
+	FailureCount        int64         `json:"failureCount"`
+	SuccessRate         float64       `json:"successRate"`
+	ConsecutiveFailures int64         `json:"consecutiveFailures"`
+	CircuitBroken       bool          `json:"circuitBroken"`
+	SuspendUntil        *string       `json:"suspendUntil,omitempty"`  // 硬熔断截止时间（例如额度不足到 0 点恢复）
+	SuspendReason       string        `json:"suspendReason,omitempty"` // 硬熔断原因
+	TTFB                *LatencyStats `json:"ttfb,omitempty"`          // 首字节时间统计
 }
 
 // ToResponseMultiURL 转换为 API 响应格式（支持多 BaseURL 聚合）
@@ -1182,6 +1189,12 @@ type keyAggregation struct {
 		resp.CircuitBrokenAt = &t
 	}
 
+	// TTFB 统计（keyResponses 与 activeKeys 一一对应）
+	for i, apiKey := range activeKeys {
+		keyResponses[i].TTFB = m.latencyStatsPtrLocked(baseURLs, []string{apiKey})
+	}
+	resp.TTFB = m.latencyStatsPtrLocked(baseURLs, activeKeys)
+
 	resp.KeyMetrics = keyResponses
 
 	// 计算聚合的时间窗口统计（多 URL 版本）
@@ -1319,6 +1332,12 @@ func (m *MetricsManager) ToResponse(channelIndex int, baseURL string, activeKeys
 		resp.CircuitBrokenAt = &t
 	}
 
+	// TTFB 统计（keyResponses 与 activeKeys 一一对应）
+	for i, apiKey := range activeKeys {
+		keyResponses[i].TTFB = m.latencyStatsPtrLocked([]string{baseURL}, []string{apiKey})
+	}
+	resp.TTFB = m.latencyStatsPtrLocked([]string{baseURL}, activeKeys)
+
 	resp.KeyMetrics = keyResponses
 
 	// 计算聚合的时间窗口统计
@@ -1343,6 +1362,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 		cutoff := now.Add(-duration)
 		var requestCount, successCount, failureCount int64
 		var inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64
+		var costCents int64
 
 		for _, apiKey := range activeKeys {
 			metricsKey := generateMetricsKey(baseURL, apiKey)
@@ -1359,6 +1379,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 						outputTokens += record.OutputTokens
 						cacheCreationTokens += record.CacheCreationInputTokens
 						cacheReadTokens += record.CacheReadInputTokens
+						costCents += record.CostCents
 					}
 				}
 			}
@@ -1385,6 +1406,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsInternal(baseURL string,
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
 			CacheHitRate:        cacheHitRate,
+			CostCents:           costCents,
 		}
 	}
 
@@ -1407,6 +1429,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 		cutoff := now.Add(-duration)
 		var requestCount, successCount, failureCount int64
 		var inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int64
+		var costCents int64
 
 		// 遍历所有 BaseURL 和 Key 的组合
 		for _, baseURL := range baseURLs {
@@ -1425,6 +1448,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 							outputTokens += record.OutputTokens
 							cacheCreationTokens += record.CacheCreationInputTokens
 							cacheReadTokens += record.CacheReadInputTokens
+							costCents += record.CostCents
 						}
 					}
 				}
@@ -1452,6 +1476,7 @@ func (m *MetricsManager) calculateAggregatedTimeWindowsMultiURL(baseURLs []strin
 			CacheCreationTokens: cacheCreationTokens,
 			CacheReadTokens:     cacheReadTokens,
 			CacheHitRate:        cacheHitRate,
+			CostCents:           costCents,
 		}
 	}
 
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// latencyEWMAAlpha TTFB 指数加权移动平均的平滑系数（越大越偏向最新样本）
+	latencyEWMAAlpha = 0.3
+	// latencySampleSize 每个 Key 保留的最近 TTFB 样本数（用于计算 p50/p95）
+	latencySampleSize = 100
+)
+
+// latencyTracker 单个 Key 的 TTFB 统计
+type latencyTracker struct {
+	ewma      float64         // 毫秒
+	samples   []time.Duration // 最近 latencySampleSize 个样本
+	count     int64           // 累计样本数
+	updatedAt time.Time
+}
+
+// LatencyStats TTFB（首字节时间）统计
+type LatencyStats struct {
+	SampleCount  int64   `json:"sampleCount"`
+	EWMAMs       float64 `json:"ewmaMs"`
+	P50Ms        int64   `json:"p50Ms"`
+	P95Ms        int64   `json:"p95Ms"`
+	LastSampleAt *string `json:"lastSampleAt,omitempty"`
+	// lastSample 最近一次样本时间（调度器据此判断统计是否过期）
+	lastSample time.Time
+}
+
+// LastSample 返回最近一次样本时间，无样本时为零值
+func (s LatencyStats) LastSample() time.Time {
+	return s.lastSample
+}
+
+// RecordLatency 记录一次成功请求的 TTFB（发出请求到收到首个响应字节）
+func (m *MetricsManager) RecordLatency(baseURL, apiKey string, ttfb time.Duration) {
+	if ttfb <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := m.getOrCreateKey(baseURL, apiKey)
+	t := metrics.latency
+	if t == nil {
+		t = &latencyTracker{}
+		metrics.latency = t
+	}
+
+	ms := float64(ttfb) / float64(time.Millisecond)
+	if t.count == 0 {
+		t.ewma = ms
+	} else {
+		t.ewma = latencyEWMAAlpha*ms + (1-latencyEWMAAlpha)*t.ewma
+	}
+	t.samples = append(t.samples, ttfb)
+	if len(t.samples) > latencySampleSize {
+		t.samples = t.samples[len(t.samples)-latencySampleSize:]
+	}
+	t.count++
+	t.updatedAt = time.Now()
+}
+
+// GetKeyLatencyStats 获取单个 Key 的 TTFB 统计
+func (m *MetricsManager) GetKeyLatencyStats(baseURL, apiKey string) LatencyStats {
+	return m.GetChannelLatencyStats([]string{baseURL}, []string{apiKey})
+}
+
+// GetChannelLatencyStats 聚合渠道所有 BaseURL × Key 的 TTFB 统计
+// EWMA 按各 Key 样本数加权平均，p50/p95 基于合并后的最近样本计算
+func (m *MetricsManager) GetChannelLatencyStats(baseURLs []string, activeKeys []string) LatencyStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latencyStatsLocked(baseURLs, activeKeys)
+}
+
+// latencyStatsLocked 计算 TTFB 聚合统计（调用方需持有读锁）
+func (m *MetricsManager) latencyStatsLocked(baseURLs []string, activeKeys []string) LatencyStats {
+	var stats LatencyStats
+	var merged []time.Duration
+	var weightedSum float64
+	var weightTotal int
+	for _, baseURL := range baseURLs {
+		for _, apiKey := range activeKeys {
+			metrics, exists := m.keyMetrics[generateMetricsKey(baseURL, apiKey)]
+			if !exists || metrics.latency == nil || metrics.latency.count == 0 {
+				continue
+			}
+			t := metrics.latency
+			stats.SampleCount += t.count
+			merged = append(merged, t.samples...)
+			weightedSum += t.ewma * float64(len(t.samples))
+			weightTotal += len(t.samples)
+			if t.updatedAt.After(stats.lastSample) {
+				stats.lastSample = t.updatedAt
+			}
+		}
+	}
+	if weightTotal == 0 {
+		return stats
+	}
+
+	stats.EWMAMs = math.Round(weightedSum/float64(weightTotal)*10) / 10
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+	stats.P50Ms = percentile(merged, 0.50).Milliseconds()
+	stats.P95Ms = percentile(merged, 0.95).Milliseconds()
+	ts := stats.lastSample.Format(time.RFC3339)
+	stats.LastSampleAt = &ts
+	return stats
+}
+
+// latencyStatsPtrLocked 同 latencyStatsLocked，无样本时返回 nil（用于 API 响应省略字段）
+func (m *MetricsManager) latencyStatsPtrLocked(baseURLs []string, activeKeys []string) *LatencyStats {
+	stats := m.latencyStatsLocked(baseURLs, activeKeys)
+	if stats.SampleCount == 0 {
+		return nil
+	}
+	return &stats
+}
+
+// percentile 最近秩法计算分位数（sorted 需已升序）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordLatency_EWMAAndPercentiles(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	defer m.Stop()
+
+	baseURL := "https://example.com"
+	for i := 1; i <= 20; i++ {
+		m.RecordLatency(baseURL, "k1", time.Duration(i*100)*time.Millisecond)
+	}
+
+	stats := m.GetKeyLatencyStats(baseURL, "k1")
+	if stats.SampleCount != 20 {
+		t.Fatalf("SampleCount=%d, want 20", stats.SampleCount)
+	}
+	if stats.P50Ms != 1000 || stats.P95Ms != 1900 {
+		t.Fatalf("p50=%d p95=%d, want 1000/1900", stats.P50Ms, stats.P95Ms)
+	}
+	// EWMA 偏向最近样本：介于均值与最新值之间
+	if stats.EWMAMs <= 1050 || stats.EWMAMs >= 2000 {
+		t.Fatalf("EWMA=%.1f, want between mean and latest sample", stats.EWMAMs)
+	}
+	if stats.LastSampleAt == nil || stats.LastSample().IsZero() {
+		t.Fatalf("expected last sample timestamp")
+	}
+
+	// 非正数样本忽略
+	m.RecordLatency(baseURL, "k1", 0)
+	if got := m.GetKeyLatencyStats(baseURL, "k1").SampleCount; got != 20 {
+		t.Fatalf("zero sample should be ignored, SampleCount=%d", got)
+	}
+}
+
+func TestGetChannelLatencyStats_AggregatesKeysAndURLs(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	defer m.Stop()
+
+	for i := 0; i < 10; i++ {
+		m.RecordLatency("https://a.example.com", "k1", 100*time.Millisecond)
+		m.RecordLatency("https://b.example.com", "k2", 300*time.Millisecond)
+	}
+
+	stats := m.GetChannelLatencyStats([]string{"https://a.example.com", "https://b.example.com"}, []string{"k1", "k2"})
+	if stats.SampleCount != 20 || stats.EWMAMs != 200 || stats.P50Ms != 100 || stats.P95Ms != 300 {
+		t.Fatalf("unexpected channel stats: %+v", stats)
+	}
+
+	if empty := m.GetChannelLatencyStats([]string{"https://a.example.com"}, []string{"missing"}); empty.SampleCount != 0 || empty.LastSampleAt != nil {
+		t.Fatalf("expected empty stats, got %+v", empty)
+	}
+}
+
+func TestLatencySamplesBoundedAndReset(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	defer m.Stop()
+
+	baseURL := "https://example.com"
+	for i := 0; i < latencySampleSize+50; i++ {
+		m.RecordLatency(baseURL, "k1", 50*time.Millisecond)
+	}
+	m.RecordSuccess(baseURL, "k1")
+
+	resp := m.ToResponse(0, baseURL, []string{"k1"}, 0)
+	if resp.TTFB == nil || resp.TTFB.SampleCount != int64(latencySampleSize+50) || resp.KeyMetrics[0].TTFB == nil {
+		t.Fatalf("expected TTFB in metrics response, got %+v", resp.TTFB)
+	}
+	if n := len(m.keyMetrics[generateMetricsKey(baseURL, "k1")].latency.samples); n != latencySampleSize {
+		t.Fatalf("samples=%d, want %d", n, latencySampleSize)
+	}
+
+	m.ResetKey(baseURL, "k1")
+	if resp := m.ToResponse(0, baseURL, []string{"k1"}, 0); resp.TTFB != nil {
+		t.Fatalf("expected TTFB cleared after reset")
+	}
+}
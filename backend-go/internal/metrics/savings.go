@@ -0,0 +1,27 @@
+package metrics
+
+import "math"
+
+// SavingsWindow 仪表盘节省金额统计使用的时间窗口
+const SavingsWindow = "24h"
+
+// CostSavings 渠道在 SavingsWindow 内的成本与节省估算（美分）
+type CostSavings struct {
+	// ListCostCents 按官方价格估算的成本
+	ListCostCents int64 `json:"costCents"`
+	// EffectiveCostCents 应用渠道价格倍率后的实际成本
+	EffectiveCostCents int64 `json:"effectiveCostCents"`
+	// SavingsCents 相对官方价格节省的金额（倍率大于 1 时为负数）
+	SavingsCents int64 `json:"savingsCents"`
+}
+
+// EstimateCostSavings 根据时间窗口统计与渠道价格倍率估算成本节省
+func EstimateCostSavings(timeWindows map[string]TimeWindowStats, priceMultiplier float64) CostSavings {
+	list := timeWindows[SavingsWindow].CostCents
+	effective := int64(math.Round(float64(list) * priceMultiplier))
+	return CostSavings{
+		ListCostCents:      list,
+		EffectiveCostCents: effective,
+		SavingsCents:       list - effective,
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import "testing"
+
+func TestEstimateCostSavings(t *testing.T) {
+	windows := map[string]TimeWindowStats{
+		"1h":          {CostCents: 10},
+		SavingsWindow: {CostCents: 1000},
+	}
+
+	got := EstimateCostSavings(windows, 0.3)
+	if got.ListCostCents != 1000 || got.EffectiveCostCents != 300 || got.SavingsCents != 700 {
+		t.Fatalf("unexpected savings: %+v", got)
+	}
+
+	// 倍率大于 1 时节省为负数
+	if got := EstimateCostSavings(windows, 1.2); got.SavingsCents != -200 {
+		t.Fatalf("SavingsCents=%d, want -200", got.SavingsCents)
+	}
+
+	if got := EstimateCostSavings(nil, 0.5); got != (CostSavings{}) {
+		t.Fatalf("expected zero savings without history, got %+v", got)
+	}
+}
+
+func TestTimeWindowsAggregateCost(t *testing.T) {
+	m := NewMetricsManagerWithConfig(10, 0.5)
+	defer m.Stop()
+
+	baseURL := "https://example.com"
+	m.RecordSuccessWithUsage(baseURL, "k1", nil, "claude", 120)
+	m.RecordSuccessWithUsage(baseURL, "k1", nil, "claude", 80)
+
+	resp := m.ToResponse(0, baseURL, []string{"k1"}, 0)
+	if got := resp.TimeWindows[SavingsWindow].CostCents; got != 200 {
+		t.Fatalf("CostCents=%d, want 200", got)
+	}
+}
@@ -28,7 +28,7 @@ func WebAuthMiddleware(envCfg *config.EnvConfig, cfgManager *config.ConfigManage
 		}
 
 		// API 代理端点后续处理
-		if strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/v1beta/") {
+		if strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/v1beta/") || strings.HasPrefix(path, "/upload/v1beta/") {
 			c.Next()
 			return
 		}
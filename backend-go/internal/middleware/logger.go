@@ -20,9 +20,11 @@
 	"/api/messages/channels",
 	"/api/responses/channels",
 	"/api/gemini/channels",
+	"/api/embeddings/channels",
 	"/api/messages/global/stats",
 	"/api/responses/global/stats",
 	"/api/gemini/global/stats",
+	"/api/embeddings/global/stats",
 }
 
 // FilteredLogger 创建一个可过滤路径的 Logger 中间件
@@ -8,21 +8,25 @@
 
 // LiveRequest 正在进行的请求
 type LiveRequest struct {
-	RequestID       string    `json:"requestId"`
-	ChannelIndex    int       `json:"channelIndex"`
-	ChannelName     string    `json:"channelName"`
-	KeyMask         string    `json:"keyMask"`
-	Model           string    `json:"model"`
-	ReasoningEffort string    `json:"reasoningEffort,omitempty"`
-	StartTime       time.Time `json:"startTime"`
-	APIType         string    `json:"apiType"` // messages, responses, gemini
-	IsStreaming     bool      `json:"isStreaming"`
+	RequestID       string     `json:"requestId"`
+	ChannelIndex    int        `json:"channelIndex"`
+	ChannelName     string     `json:"channelName"`
+	KeyMask         string     `json:"keyMask"`
+	Model           string     `json:"model"`
+	ReasoningEffort string     `json:"reasoningEffort,omitempty"`
+	StartTime       time.Time  `json:"startTime"`
+	APIType         string     `json:"apiType"` // messages, responses, gemini
+	IsStreaming     bool       `json:"isStreaming"`
+	QueuedAt        *time.Time `json:"queuedAt,omitempty"`    // 所有槽位并发已满、正在排队时的入队时间
+	QueueWaitMs     int64      `json:"queueWaitMs,omitempty"` // 获得槽位前的排队时长
 }
 
 // LiveRequestsResponse API 响应
 type LiveRequestsResponse struct {
-	Requests []*LiveRequest `json:"requests"`
-	Count    int            `json:"count"`
+	Requests       []*LiveRequest `json:"requests"`
+	Count          int            `json:"count"`
+	QueueDepth     int            `json:"queueDepth"`     // 正在排队等待槽位的请求数
+	MaxQueueWaitMs int64          `json:"maxQueueWaitMs"` // 排队中请求的最长已等待时间
 }
 
 // LiveRequestManager 管理正在进行的请求
@@ -143,3 +147,17 @@ func (m *LiveRequestManager) Count() int {
 	defer m.mu.RUnlock()
 	return len(m.requests)
 }
+
+// QueueStats 统计排队中的请求数与最长已等待时间（毫秒）
+func QueueStats(requests []*LiveRequest, now time.Time) (depth int, maxWaitMs int64) {
+	for _, req := range requests {
+		if req == nil || req.QueuedAt == nil {
+			continue
+		}
+		depth++
+		if wait := now.Sub(*req.QueuedAt).Milliseconds(); wait > maxWaitMs {
+			maxWaitMs = wait
+		}
+	}
+	return depth, maxWaitMs
+}
@@ -96,3 +96,23 @@ func TestLiveRequestManager_StartRequest_InvalidInputNoop(t *testing.T) {
 		t.Fatalf("Count() = %d, want 0", got)
 	}
 }
+
+func TestQueueStats(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	queuedShort := now.Add(-200 * time.Millisecond)
+	queuedLong := now.Add(-3 * time.Second)
+
+	depth, maxWaitMs := QueueStats([]*LiveRequest{
+		{RequestID: "running", StartTime: now.Add(-5 * time.Second), QueueWaitMs: 1500},
+		{RequestID: "q1", StartTime: queuedShort, QueuedAt: &queuedShort},
+		{RequestID: "q2", StartTime: queuedLong, QueuedAt: &queuedLong},
+		nil,
+	}, now)
+	if depth != 2 || maxWaitMs != 3000 {
+		t.Fatalf("QueueStats = (%d, %d), want (2, 3000)", depth, maxWaitMs)
+	}
+
+	if depth, maxWaitMs := QueueStats(nil, now); depth != 0 || maxWaitMs != 0 {
+		t.Fatalf("QueueStats(nil) = (%d, %d), want (0, 0)", depth, maxWaitMs)
+	}
+}
@@ -162,6 +162,27 @@ func (s *Service) Calculate(model string, inputTokens, outputTokens, cacheCreati
 	return int64((inputCostUSD + outputCostUSD + cacheCreationCostUSD + cacheReadCostUSD) * 100)
 }
 
+// EstimateCostUSD 估算成本（美元，不取整），用于调度时比较不同模型/渠道的成本
+// 未知模型与 Calculate 一致按默认价格计算；模型未提供缓存价格时缓存 token 按输入价格计算
+func (s *Service) EstimateCostUSD(model string, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int) float64 {
+	pricing := s.getOrFuzzyMatch(model)
+	if pricing == nil {
+		// 默认价格：input $3/M, output $15/M, cache creation $3.75/M, cache read $0.3/M
+		return (float64(inputTokens)*3 + float64(outputTokens)*15 +
+			float64(cacheCreationTokens)*3.75 + float64(cacheReadTokens)*0.3) / 1_000_000
+	}
+	cacheCreationCost := pricing.CacheCreationInputTokenCost
+	if cacheCreationCost <= 0 {
+		cacheCreationCost = pricing.InputCostPerToken
+	}
+	cacheReadCost := pricing.CacheReadInputTokenCost
+	if cacheReadCost <= 0 {
+		cacheReadCost = pricing.InputCostPerToken
+	}
+	return float64(inputTokens)*pricing.InputCostPerToken + float64(outputTokens)*pricing.OutputCostPerToken +
+		float64(cacheCreationTokens)*cacheCreationCost + float64(cacheReadTokens)*cacheReadCost
+}
+
 // getOrFuzzyMatch 精确匹配或模糊匹配模型
 func (s *Service) getOrFuzzyMatch(model string) *ModelPricing {
 	// 拒绝空 model，避免匹配到任意 key
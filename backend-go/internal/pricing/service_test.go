@@ -31,3 +31,38 @@ func TestJSONInt_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateCostUSD(t *testing.T) {
+	s := &Service{models: map[string]*ModelPricing{
+		"anthropic/claude-haiku": {InputCostPerToken: 1e-6, OutputCostPerToken: 5e-6},
+	}}
+
+	// 带 provider 前缀的模糊匹配
+	if got := s.EstimateCostUSD("claude-haiku", 1_000_000, 100_000, 0, 0); got < 1.499 || got > 1.501 {
+		t.Fatalf("got %f want 1.5", got)
+	}
+	// 未知模型按默认价格
+	if got := s.EstimateCostUSD("unknown-model", 1_000_000, 1_000_000, 0, 0); got != 18 {
+		t.Fatalf("got %f want 18", got)
+	}
+}
+
+func TestEstimateCostUSD_CachePricing(t *testing.T) {
+	s := &Service{models: map[string]*ModelPricing{
+		"cached":   {InputCostPerToken: 3e-6, OutputCostPerToken: 15e-6, CacheCreationInputTokenCost: 3.75e-6, CacheReadInputTokenCost: 0.3e-6},
+		"uncached": {InputCostPerToken: 1e-6, OutputCostPerToken: 5e-6},
+	}}
+
+	// 缓存写入 $3.75 + 缓存读取 $0.3
+	if got := s.EstimateCostUSD("cached", 0, 0, 1_000_000, 1_000_000); got < 4.049 || got > 4.051 {
+		t.Fatalf("got %f want 4.05", got)
+	}
+	// 未提供缓存价格时按输入价格计算，缓存 token 不视为免费
+	if got := s.EstimateCostUSD("uncached", 0, 0, 1_000_000, 1_000_000); got < 1.999 || got > 2.001 {
+		t.Fatalf("got %f want 2", got)
+	}
+	// 默认价格同样计入缓存 token
+	if got := s.EstimateCostUSD("unknown-model", 0, 0, 1_000_000, 1_000_000); got < 4.049 || got > 4.051 {
+		t.Fatalf("got %f want 4.05", got)
+	}
+}
@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// AzureOpenAIProvider Azure OpenAI 提供商
+// 请求/响应格式与 OpenAI Chat Completions 一致，仅 URL 与认证方式不同：
+//   - URL: {baseURL}/openai/deployments/{deployment}/chat/completions?api-version=...
+//   - 认证: api-key 请求头
+//
+// 部署名通过 ModelMapping 由模型名映射得到（未配置映射时直接使用模型名）
+type AzureOpenAIProvider struct {
+	OpenAIProvider
+}
+
+// ConvertToProviderRequest 转换为 Azure OpenAI 请求
+func (p *AzureOpenAIProvider) ConvertToProviderRequest(c *gin.Context, upstream *config.UpstreamConfig, apiKey string) (*http.Request, []byte, error) {
+	req, originalBodyBytes, err := p.OpenAIProvider.ConvertToProviderRequest(c, upstream, apiKey)
+	if err != nil {
+		return req, originalBodyBytes, err
+	}
+
+	deployment := config.RedirectModel(gjson.GetBytes(originalBodyBytes, "model").String(), upstream)
+	targetURL, err := url.Parse(buildAzureOpenAIURL(upstream, deployment, "/chat/completions"))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("构建Azure OpenAI URL失败: %w", err)
+	}
+	req.URL = targetURL
+	req.Host = targetURL.Host
+	req.Header.Set("Host", targetURL.Host)
+	utils.SetAzureAuthenticationHeader(req.Header, apiKey)
+
+	return req, originalBodyBytes, nil
+}
+
+// buildAzureOpenAIURL 构建 Azure OpenAI 部署端点 URL
+// baseURL 为资源端点（如 https://xxx.openai.azure.com），若已包含 /openai 后缀则不重复添加
+func buildAzureOpenAIURL(upstream *config.UpstreamConfig, deployment, endpoint string) string {
+	baseURL := strings.TrimSuffix(strings.TrimSuffix(upstream.GetEffectiveBaseURL(), "#"), "/")
+	baseURL = strings.TrimSuffix(baseURL, "/openai")
+
+	return fmt.Sprintf("%s/openai/deployments/%s%s?api-version=%s",
+		baseURL, url.PathEscape(deployment), endpoint, url.QueryEscape(upstream.GetAzureAPIVersion()))
+}
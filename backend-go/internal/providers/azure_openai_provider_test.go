@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestAzureOpenAIProvider_ConvertToProviderRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-3-5-sonnet",
+		"max_tokens":64,
+		"stream":true,
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+	c.Request.Header.Set("x-api-key", "client-key")
+
+	upstream := &config.UpstreamConfig{
+		BaseURL:      "https://my-resource.openai.azure.com/",
+		ServiceType:  "azure-openai",
+		APIVersion:   "2025-01-01-preview",
+		ModelMapping: map[string]string{"claude-3-5-sonnet": "gpt-4o-prod"},
+	}
+	req, _, err := GetProvider("azure-openai").ConvertToProviderRequest(c, upstream, "azure-key")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+
+	want := "https://my-resource.openai.azure.com/openai/deployments/gpt-4o-prod/chat/completions?api-version=2025-01-01-preview"
+	if req.URL.String() != want {
+		t.Fatalf("url=%q, want %q", req.URL.String(), want)
+	}
+	if req.Header.Get("api-key") != "azure-key" || req.Header.Get("Authorization") != "" || req.Header.Get("x-api-key") != "" {
+		t.Fatalf("unexpected auth headers: %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	root := gjson.ParseBytes(body)
+	if root.Get("model").String() != "gpt-4o-prod" || !root.Get("stream").Bool() || root.Get("messages.0.content").String() != "hi" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestBuildAzureOpenAIURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream *config.UpstreamConfig
+		want     string
+	}{
+		{
+			name:     "default api version",
+			upstream: &config.UpstreamConfig{BaseURL: "https://r.openai.azure.com"},
+			want:     "https://r.openai.azure.com/openai/deployments/dep/chat/completions?api-version=" + config.DefaultAzureAPIVersion,
+		},
+		{
+			name:     "base url already ends with /openai",
+			upstream: &config.UpstreamConfig{BaseURL: "https://r.openai.azure.com/openai/", APIVersion: "2024-06-01"},
+			want:     "https://r.openai.azure.com/openai/deployments/dep/chat/completions?api-version=2024-06-01",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildAzureOpenAIURL(tt.upstream, "dep", "/chat/completions"); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponsesProvider_AzureOpenAI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/responses", bytes.NewBufferString(`{"model":"gpt-5","input":"hi","stream":true}`))
+	c.Request.Header.Set("Authorization", "Bearer client-token")
+
+	upstream := &config.UpstreamConfig{
+		BaseURL:      "https://r.openai.azure.com",
+		ServiceType:  "azure-openai",
+		ModelMapping: map[string]string{"gpt-5": "gpt5-deploy"},
+	}
+	p := &ResponsesProvider{SessionManager: session.NewSessionManager(time.Hour, 100, 100000)}
+	req, _, err := p.ConvertToProviderRequest(c, upstream, "azure-key")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+
+	want := "https://r.openai.azure.com/openai/deployments/gpt5-deploy/chat/completions?api-version=" + config.DefaultAzureAPIVersion
+	if req.URL.String() != want {
+		t.Fatalf("url=%q, want %q", req.URL.String(), want)
+	}
+	if req.Header.Get("api-key") != "azure-key" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("unexpected auth headers: %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if !gjson.GetBytes(body, "messages").IsArray() {
+		t.Fatalf("expected chat completions body: %s", body)
+	}
+}
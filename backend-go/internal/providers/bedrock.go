@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// BedrockProvider AWS Bedrock 提供商（Anthropic Messages 协议，SigV4 签名）
+//
+// API 密钥格式: ACCESS_KEY_ID:SECRET_ACCESS_KEY[:SESSION_TOKEN]
+// baseURL 示例: https://bedrock-runtime.us-east-1.amazonaws.com
+type BedrockProvider struct{}
+
+const (
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+	bedrockDefaultRegion    = "us-east-1"
+	bedrockSigningService   = "bedrock"
+)
+
+// bedrockNow 签名时间来源（测试时可替换）
+var bedrockNow = time.Now
+
+// ParseBedrockCredentials 解析 Bedrock API 密钥（ACCESS_KEY_ID:SECRET_ACCESS_KEY[:SESSION_TOKEN]）
+func ParseBedrockCredentials(apiKey string) (utils.AWSCredentials, error) {
+	parts := strings.SplitN(strings.TrimSpace(apiKey), ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return utils.AWSCredentials{}, fmt.Errorf("Bedrock 密钥格式应为 ACCESS_KEY_ID:SECRET_ACCESS_KEY[:SESSION_TOKEN]")
+	}
+	creds := utils.AWSCredentials{AccessKeyID: parts[0], SecretAccessKey: parts[1]}
+	if len(parts) == 3 {
+		creds.SessionToken = parts[2]
+	}
+	return creds, nil
+}
+
+// bedrockRegionFromHost 从 bedrock-runtime.{region}.amazonaws.com 中提取区域
+func bedrockRegionFromHost(host string) string {
+	labels := strings.Split(strings.ToLower(host), ".")
+	for i := 0; i < len(labels)-1; i++ {
+		if strings.HasPrefix(labels[i], "bedrock-runtime") {
+			return labels[i+1]
+		}
+	}
+	return bedrockDefaultRegion
+}
+
+// buildBedrockRequestBody 将 Claude Messages 请求体转换为 Bedrock InvokeModel 请求体
+// Bedrock 的模型与流式模式由 URL 决定，请求体中不能包含 model/stream
+func buildBedrockRequestBody(bodyBytes []byte, anthropicBeta string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	delete(data, "model")
+	delete(data, "stream")
+	data["anthropic_version"] = bedrockAnthropicVersion
+
+	// anthropic-beta 请求头在 Bedrock 中需放入请求体
+	if anthropicBeta != "" {
+		var betas []string
+		for _, beta := range strings.Split(anthropicBeta, ",") {
+			if beta = strings.TrimSpace(beta); beta != "" {
+				betas = append(betas, beta)
+			}
+		}
+		if len(betas) > 0 {
+			data["anthropic_beta"] = betas
+		}
+	}
+
+	return utils.MarshalJSONNoEscape(data)
+}
+
+// ConvertToProviderRequest 转换为 Bedrock InvokeModel 请求
+func (p *BedrockProvider) ConvertToProviderRequest(c *gin.Context, upstream *config.UpstreamConfig, apiKey string) (*http.Request, []byte, error) {
+	originalBodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(originalBodyBytes))
+
+	creds, err := ParseBedrockCredentials(apiKey)
+	if err != nil {
+		return nil, originalBodyBytes, err
+	}
+
+	var claudeReq types.ClaudeRequest
+	if err := json.Unmarshal(originalBodyBytes, &claudeReq); err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("解析Claude请求体失败: %w", err)
+	}
+
+	reqBodyBytes, err := buildBedrockRequestBody(originalBodyBytes, c.GetHeader("anthropic-beta"))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("构建Bedrock请求体失败: %w", err)
+	}
+
+	// 构建目标URL: {baseURL}/model/{modelId}/invoke[-with-response-stream]
+	// 模型 ID 可能包含 ':'（版本号）或 '/'（ARN），需整体作为一个路径段编码
+	model := config.RedirectModel(claudeReq.Model, upstream)
+	action := "invoke"
+	if claudeReq.Stream {
+		action = "invoke-with-response-stream"
+	}
+	target, err := url.Parse(strings.TrimSuffix(upstream.GetEffectiveBaseURL(), "/"))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("解析Bedrock baseURL失败: %w", err)
+	}
+	target.RawPath = target.EscapedPath() + "/model/" + utils.AWSURIEncode(model) + "/" + action
+	target.Path = target.Path + "/model/" + model + "/" + action
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", target.String(), bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("创建Bedrock请求失败: %w", err)
+	}
+
+	// SigV4 签名覆盖的头部必须与实际发送一致，因此不透传客户端头部
+	req.Header = utils.PrepareMinimalHeaders(req.URL.Host)
+	if claudeReq.Stream {
+		req.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	utils.SignAWSRequestV4(req, reqBodyBytes, creds, bedrockRegionFromHost(req.URL.Hostname()), bedrockSigningService, bedrockNow())
+
+	return req, originalBodyBytes, nil
+}
+
+// ConvertToClaudeResponse 转换为 Claude 响应（Bedrock 非流式响应即 Anthropic Messages 格式）
+func (p *BedrockProvider) ConvertToClaudeResponse(providerResp *types.ProviderResponse) (*types.ClaudeResponse, error) {
+	var claudeResp types.ClaudeResponse
+	if err := json.Unmarshal(providerResp.Body, &claudeResp); err != nil {
+		return nil, err
+	}
+	return &claudeResp, nil
+}
+
+// HandleStreamResponse 处理流式响应（将 AWS event-stream 二进制帧解码为 Anthropic SSE）
+func (p *BedrockProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string, <-chan error, error) {
+	eventChan := make(chan string, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+		defer body.Close()
+
+		decoder := newBedrockEventStreamDecoder(body)
+		for {
+			msg, err := decoder.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			event, err := bedrockMessageToSSE(msg)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if event != "" {
+				eventChan <- event
+			}
+		}
+	}()
+
+	return eventChan, errChan, nil
+}
@@ -0,0 +1,172 @@
+package providers
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ============== AWS event-stream 二进制帧解码 ==============
+//
+// 帧格式（大端序）：
+//   [total_length:4][headers_length:4][prelude_crc:4][headers][payload][message_crc:4]
+// 头部格式：
+//   [name_len:1][name][value_type:1][value...]
+// Bedrock 的 chunk 事件 payload 为 {"bytes":"<base64 的 Anthropic 流事件 JSON>"}
+
+const (
+	eventStreamPreludeLen  = 12
+	eventStreamTrailerLen  = 4
+	eventStreamMaxFrameLen = 16 * 1024 * 1024
+)
+
+// bedrockEventStreamMessage 解码后的 event-stream 消息
+type bedrockEventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// bedrockEventStreamDecoder 逐帧读取 event-stream
+type bedrockEventStreamDecoder struct {
+	r io.Reader
+}
+
+func newBedrockEventStreamDecoder(r io.Reader) *bedrockEventStreamDecoder {
+	return &bedrockEventStreamDecoder{r: r}
+}
+
+// Next 读取下一条消息，流正常结束时返回 io.EOF
+func (d *bedrockEventStreamDecoder) Next() (*bedrockEventStreamMessage, error) {
+	prelude := make([]byte, eventStreamPreludeLen)
+	if _, err := io.ReadFull(d.r, prelude); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("读取 event-stream 帧头失败: %w", err)
+	}
+
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, errors.New("event-stream 帧头校验失败")
+	}
+	if totalLen < eventStreamPreludeLen+eventStreamTrailerLen+headersLen || totalLen > eventStreamMaxFrameLen {
+		return nil, fmt.Errorf("event-stream 帧长度非法: total=%d headers=%d", totalLen, headersLen)
+	}
+
+	frame := make([]byte, totalLen)
+	copy(frame, prelude)
+	if _, err := io.ReadFull(d.r, frame[eventStreamPreludeLen:]); err != nil {
+		return nil, fmt.Errorf("读取 event-stream 帧失败: %w", err)
+	}
+	if crc32.ChecksumIEEE(frame[:totalLen-eventStreamTrailerLen]) != binary.BigEndian.Uint32(frame[totalLen-eventStreamTrailerLen:]) {
+		return nil, errors.New("event-stream 帧校验失败")
+	}
+
+	headersEnd := eventStreamPreludeLen + headersLen
+	headers, err := parseEventStreamHeaders(frame[eventStreamPreludeLen:headersEnd])
+	if err != nil {
+		return nil, err
+	}
+	return &bedrockEventStreamMessage{
+		Headers: headers,
+		Payload: frame[headersEnd : totalLen-eventStreamTrailerLen],
+	}, nil
+}
+
+// parseEventStreamHeaders 解析头部，仅保留字符串类型的值（其余类型跳过）
+func parseEventStreamHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errors.New("event-stream 头部截断")
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		var valueLen int
+		switch valueType {
+		case 0, 1: // bool true / false
+			valueLen = 0
+		case 2: // byte
+			valueLen = 1
+		case 3: // int16
+			valueLen = 2
+		case 4: // int32
+			valueLen = 4
+		case 5, 8: // int64 / timestamp
+			valueLen = 8
+		case 9: // uuid
+			valueLen = 16
+		case 6, 7: // byte array / string
+			if len(b) < 2 {
+				return nil, errors.New("event-stream 头部截断")
+			}
+			strLen := int(binary.BigEndian.Uint16(b[0:2]))
+			if len(b) < 2+strLen {
+				return nil, errors.New("event-stream 头部截断")
+			}
+			if valueType == 7 {
+				headers[name] = string(b[2 : 2+strLen])
+			}
+			b = b[2+strLen:]
+			continue
+		default:
+			return nil, fmt.Errorf("未知的 event-stream 头部类型: %d", valueType)
+		}
+		if len(b) < valueLen {
+			return nil, errors.New("event-stream 头部截断")
+		}
+		b = b[valueLen:]
+	}
+	return headers, nil
+}
+
+// bedrockMessageToSSE 将 event-stream 消息转换为 Anthropic SSE 事件
+// 非 chunk 事件返回空字符串；异常消息返回错误
+func bedrockMessageToSSE(msg *bedrockEventStreamMessage) (string, error) {
+	switch msg.Headers[":message-type"] {
+	case "exception", "error":
+		errType := msg.Headers[":exception-type"]
+		if errType == "" {
+			errType = msg.Headers[":error-code"]
+		}
+		errMsg := gjson.GetBytes(msg.Payload, "message").String()
+		if errMsg == "" {
+			errMsg = string(msg.Payload)
+		}
+		return "", fmt.Errorf("Bedrock 流式错误 (%s): %s", errType, errMsg)
+	}
+
+	if msg.Headers[":event-type"] != "chunk" {
+		return "", nil
+	}
+
+	encoded := gjson.GetBytes(msg.Payload, "bytes").String()
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("解码 Bedrock chunk 失败: %w", err)
+	}
+	if !json.Valid(data) {
+		return "", fmt.Errorf("Bedrock chunk 不是合法 JSON: %s", string(data))
+	}
+
+	// Bedrock 在 message_stop 中附加调用指标，Anthropic 协议中不存在该字段
+	if gjson.GetBytes(data, "amazon-bedrock-invocationMetrics").Exists() {
+		if cleaned, delErr := sjson.DeleteBytes(data, "amazon-bedrock-invocationMetrics"); delErr == nil {
+			data = cleaned
+		}
+	}
+
+	eventType := gjson.GetBytes(data, "type").String()
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data), nil
+}
@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// encodeEventStreamFrame 构造 AWS event-stream 帧（仅字符串头部）
+func encodeEventStreamFrame(headers map[string]string, payload []byte) []byte {
+	var hb bytes.Buffer
+	for name, value := range headers {
+		hb.WriteByte(byte(len(name)))
+		hb.WriteString(name)
+		hb.WriteByte(7)
+		_ = binary.Write(&hb, binary.BigEndian, uint16(len(value)))
+		hb.WriteString(value)
+	}
+
+	totalLen := uint32(eventStreamPreludeLen + hb.Len() + len(payload) + eventStreamTrailerLen)
+	var frame bytes.Buffer
+	_ = binary.Write(&frame, binary.BigEndian, totalLen)
+	_ = binary.Write(&frame, binary.BigEndian, uint32(hb.Len()))
+	_ = binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	frame.Write(hb.Bytes())
+	frame.Write(payload)
+	_ = binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	return frame.Bytes()
+}
+
+func bedrockChunkFrame(event string) []byte {
+	payload := `{"bytes":"` + base64.StdEncoding.EncodeToString([]byte(event)) + `"}`
+	return encodeEventStreamFrame(map[string]string{
+		":message-type": "event",
+		":event-type":   "chunk",
+		":content-type": "application/json",
+	}, []byte(payload))
+}
+
+func TestBedrockProvider_ConvertToProviderRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bedrockNow = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { bedrockNow = time.Now }()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-sonnet-4",
+		"max_tokens":64,
+		"temperature":0.5,
+		"stream":true,
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+	c.Request.Header.Set("anthropic-beta", "interleaved-thinking-2025-05-14, context-1m-2025-08-07")
+	c.Request.Header.Set("x-api-key", "client-key")
+
+	upstream := &config.UpstreamConfig{
+		BaseURL:      "https://bedrock-runtime.us-west-2.amazonaws.com/",
+		ServiceType:  "bedrock",
+		ModelMapping: map[string]string{"claude-sonnet-4": "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+	}
+	req, _, err := GetProvider("bedrock").ConvertToProviderRequest(c, upstream, "AKID:SECRET:TOKEN")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+
+	wantURL := "https://bedrock-runtime.us-west-2.amazonaws.com/model/us.anthropic.claude-sonnet-4-20250514-v1%3A0/invoke-with-response-stream"
+	if req.URL.String() != wantURL {
+		t.Fatalf("url=%q, want %q", req.URL.String(), wantURL)
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20250102/us-west-2/bedrock/aws4_request, ") ||
+		!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("unexpected Authorization: %s", auth)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "TOKEN" || req.Header.Get("Accept") != "application/vnd.amazon.eventstream" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+	if req.Header.Get("x-api-key") != "" || req.Header.Get("anthropic-beta") != "" {
+		t.Fatalf("client headers must not be forwarded: %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	root := gjson.ParseBytes(body)
+	if root.Get("model").Exists() || root.Get("stream").Exists() {
+		t.Fatalf("model/stream must be removed: %s", body)
+	}
+	if root.Get("anthropic_version").String() != "bedrock-2023-05-31" ||
+		root.Get("anthropic_beta.#").Int() != 2 || root.Get("anthropic_beta.1").String() != "context-1m-2025-08-07" ||
+		root.Get("temperature").Raw != "0.5" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestBedrockProvider_InvalidCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{"model":"m","messages":[]}`))
+
+	upstream := &config.UpstreamConfig{BaseURL: "https://bedrock-runtime.us-east-1.amazonaws.com", ServiceType: "bedrock"}
+	if _, _, err := (&BedrockProvider{}).ConvertToProviderRequest(c, upstream, "sk-not-aws"); err == nil {
+		t.Fatalf("expected credential format error")
+	}
+}
+
+func TestBedrockRegionFromHost(t *testing.T) {
+	cases := map[string]string{
+		"bedrock-runtime.eu-central-1.amazonaws.com":       "eu-central-1",
+		"bedrock-runtime-fips.us-gov-west-1.amazonaws.com": "us-gov-west-1",
+		"bedrock-proxy.internal":                           "us-east-1",
+	}
+	for host, want := range cases {
+		if got := bedrockRegionFromHost(host); got != want {
+			t.Errorf("bedrockRegionFromHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestBedrockProvider_HandleStreamResponse(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(bedrockChunkFrame(`{"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[]}}`))
+	stream.Write(bedrockChunkFrame(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`))
+	stream.Write(bedrockChunkFrame(`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":3}}`))
+
+	events, errs, err := (&BedrockProvider{}).HandleStreamResponse(io.NopCloser(&stream))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse: %v", err)
+	}
+
+	var got []string
+	for event := range events {
+		got = append(got, event)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d: %q", len(got), got)
+	}
+	if !strings.HasPrefix(got[0], "event: message_start\ndata: {") || !strings.HasSuffix(got[0], "\n\n") {
+		t.Fatalf("unexpected first event: %q", got[0])
+	}
+	if !strings.Contains(got[1], `"text":"hi"`) {
+		t.Fatalf("unexpected delta event: %q", got[1])
+	}
+	if got[2] != "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n" {
+		t.Fatalf("invocation metrics must be stripped: %q", got[2])
+	}
+}
+
+func TestBedrockProvider_HandleStreamResponse_Exception(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(bedrockChunkFrame(`{"type":"message_start","message":{}}`))
+	stream.Write(encodeEventStreamFrame(map[string]string{
+		":message-type":   "exception",
+		":exception-type": "throttlingException",
+	}, []byte(`{"message":"Too many requests"}`)))
+
+	events, errs, _ := (&BedrockProvider{}).HandleStreamResponse(io.NopCloser(&stream))
+	count := 0
+	for range events {
+		count++
+	}
+	err := <-errs
+	if count != 1 || err == nil || !strings.Contains(err.Error(), "throttlingException") {
+		t.Fatalf("count=%d err=%v", count, err)
+	}
+}
+
+func TestBedrockEventStreamDecoder_RejectsCorruptFrame(t *testing.T) {
+	frame := bedrockChunkFrame(`{"type":"ping"}`)
+	frame[len(frame)-6] ^= 0xFF
+
+	if _, err := newBedrockEventStreamDecoder(bytes.NewReader(frame)).Next(); err == nil {
+		t.Fatalf("expected checksum error")
+	}
+}
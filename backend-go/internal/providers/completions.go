@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// CompletionsProvider OpenAI 旧版文本补全（/v1/completions）提供商
+// 用于仅提供 completions 接口的基座模型：Claude 对话历史渲染为纯文本 prompt
+type CompletionsProvider struct{}
+
+// ConvertToProviderRequest 转换为 Completions 请求
+func (p *CompletionsProvider) ConvertToProviderRequest(c *gin.Context, upstream *config.UpstreamConfig, apiKey string) (*http.Request, []byte, error) {
+	originalBodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(originalBodyBytes))
+
+	var claudeReq types.ClaudeRequest
+	if err := json.Unmarshal(originalBodyBytes, &claudeReq); err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("解析Claude请求体失败: %w", err)
+	}
+
+	// Completions API 没有任何服务端工具（函数工具同样无法表达，沿用原逻辑忽略）
+	for _, tool := range claudeReq.Tools {
+		if _, err := converters.ServerToolFromClaude(tool, converters.ToolProtocolCompletions); err != nil {
+			return nil, originalBodyBytes, err
+		}
+	}
+
+	turns := []converters.CompletionsTurn{}
+	for _, msg := range claudeReq.Messages {
+		turns = append(turns, claudeMessageToCompletionsTurns(msg)...)
+	}
+
+	completionsReq := map[string]interface{}{
+		"model":  config.RedirectModel(claudeReq.Model, upstream),
+		"prompt": converters.RenderCompletionsPrompt(extractSystemText(claudeReq.System), turns),
+		"stream": claudeReq.Stream,
+	}
+	if claudeReq.MaxTokens > 0 {
+		completionsReq["max_tokens"] = claudeReq.MaxTokens
+	}
+	if claudeReq.Temperature > 0 {
+		completionsReq["temperature"] = claudeReq.Temperature
+	}
+	if topP := gjson.GetBytes(originalBodyBytes, "top_p"); topP.Exists() {
+		completionsReq["top_p"] = topP.Float()
+	}
+	stop := append([]string{}, converters.CompletionsStopSequences...)
+	gjson.GetBytes(originalBodyBytes, "stop_sequences").ForEach(func(_, v gjson.Result) bool {
+		stop = append(stop, v.String())
+		return true
+	})
+	completionsReq["stop"] = stop
+	if claudeReq.Stream {
+		completionsReq["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	reqBodyBytes, err := json.Marshal(completionsReq)
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("序列化Completions请求体失败: %w", err)
+	}
+
+	// 构建URL - 版本号拼接规则同 OpenAIProvider
+	baseURL := upstream.GetEffectiveBaseURL()
+	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
+	if skipVersionPrefix {
+		baseURL = strings.TrimSuffix(baseURL, "#")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	versionPattern := regexp.MustCompile(`/v\d+[a-z]*$`)
+	endpoint := "/completions"
+	if !versionPattern.MatchString(baseURL) && !skipVersionPrefix {
+		endpoint = "/v1" + endpoint
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", baseURL+endpoint, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("创建Completions请求失败: %w", err)
+	}
+
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	utils.SetAuthenticationHeader(req.Header, apiKey)
+
+	return req, originalBodyBytes, nil
+}
+
+// claudeMessageToCompletionsTurns 将单条 Claude 消息转换为 prompt 轮次
+// tool_use/tool_result 以文本形式保留，图片等非文本内容无法渲染，直接忽略
+func claudeMessageToCompletionsTurns(msg types.ClaudeMessage) []converters.CompletionsTurn {
+	role := "user"
+	if msg.Role == "assistant" {
+		role = "assistant"
+	}
+
+	if str, ok := msg.Content.(string); ok {
+		return []converters.CompletionsTurn{{Role: role, Text: str}}
+	}
+	contents, ok := msg.Content.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	turns := []converters.CompletionsTurn{}
+	texts := []string{}
+	flush := func() {
+		if len(texts) > 0 {
+			turns = append(turns, converters.CompletionsTurn{Role: role, Text: strings.Join(texts, "\n")})
+			texts = texts[:0]
+		}
+	}
+	for _, c := range contents {
+		content, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch content["type"] {
+		case "text":
+			if text, ok := content["text"].(string); ok {
+				texts = append(texts, text)
+			}
+		case "tool_use":
+			name, _ := content["name"].(string)
+			inputJSON, _ := json.Marshal(content["input"])
+			texts = append(texts, fmt.Sprintf("[tool call] %s(%s)", name, inputJSON))
+		case "tool_result":
+			flush()
+			var result string
+			if str, ok := content["content"].(string); ok {
+				result = str
+			} else {
+				result = extractSystemText(content["content"])
+			}
+			turns = append(turns, converters.CompletionsTurn{Role: "tool", Text: result})
+		}
+	}
+	flush()
+	return turns
+}
+
+// ConvertToClaudeResponse 转换为 Claude 响应
+func (p *CompletionsProvider) ConvertToClaudeResponse(providerResp *types.ProviderResponse) (*types.ClaudeResponse, error) {
+	if !gjson.ValidBytes(providerResp.Body) {
+		return nil, fmt.Errorf("无效的Completions响应")
+	}
+	root := gjson.ParseBytes(providerResp.Body)
+
+	claudeResp := &types.ClaudeResponse{
+		ID:         generateID(),
+		Type:       "message",
+		Role:       "assistant",
+		Content:    []types.ClaudeContent{},
+		StopReason: "end_turn",
+	}
+
+	choice := root.Get("choices.0")
+	if text := choice.Get("text").String(); text != "" {
+		claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+			Type: "text",
+			Text: text,
+		})
+	}
+	if reason := choice.Get("finish_reason").String(); reason != "" {
+		claudeResp.StopReason = converters.OpenAIFinishReasonToAnthropic(reason)
+	}
+
+	if usage := root.Get("usage"); usage.Exists() {
+		claudeResp.Usage = &types.Usage{
+			InputTokens:  int(usage.Get("prompt_tokens").Int()),
+			OutputTokens: int(usage.Get("completion_tokens").Int()),
+		}
+	}
+
+	return claudeResp, nil
+}
+
+// HandleStreamResponse 处理流式响应
+// 每个 Completions chunk 先映射为等价的 Chat chunk，再复用 OpenAIProvider 的流式转换
+func (p *CompletionsProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string, <-chan error, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+
+		scanner := bufio.NewScanner(body)
+		const maxScannerBufferSize = 1024 * 1024 // 1MB
+		scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBufferSize)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "data: ") {
+				data := []byte(strings.TrimPrefix(line, "data: "))
+				if gjson.ValidBytes(data) {
+					line = "data: " + string(converters.CompletionsChunkToChatChunk(data))
+				}
+			}
+			if _, err := io.WriteString(pw, line+"\n"); err != nil {
+				return
+			}
+		}
+		pw.CloseWithError(scanner.Err())
+	}()
+
+	return (&OpenAIProvider{}).HandleStreamResponse(pr)
+}
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestCompletionsProvider_ConvertToProviderRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-3",
+		"system":[{"type":"text","text":"you are terse"}],
+		"max_tokens":64,
+		"stream":true,
+		"stop_sequences":["###"],
+		"messages":[
+			{"role":"user","content":"weather?"},
+			{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"weather","input":{"city":"Paris"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"sunny"},{"type":"text","text":"so?"}]}
+		]
+	}`))
+
+	upstream := &config.UpstreamConfig{
+		BaseURL:      "http://base-model.local",
+		ServiceType:  "completions",
+		ModelMapping: map[string]string{"claude-3": "llama-base"},
+	}
+	req, _, err := GetProvider("completions").ConvertToProviderRequest(c, upstream, "k1")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+	if req.URL.String() != "http://base-model.local/v1/completions" {
+		t.Fatalf("url=%q", req.URL.String())
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer k1" {
+		t.Fatalf("Authorization=%q", got)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	root := gjson.ParseBytes(body)
+	want := "you are terse\n\nUser: weather?\n\nAssistant: [tool call] weather({\"city\":\"Paris\"})\n\nTool: sunny\n\nUser: so?\n\nAssistant:"
+	if got := root.Get("prompt").String(); got != want {
+		t.Fatalf("prompt = %q, want %q", got, want)
+	}
+	if root.Get("model").String() != "llama-base" || root.Get("max_tokens").Int() != 64 ||
+		root.Get("stop.#").Int() != 2 || root.Get("stop.1").String() != "###" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestCompletionsProvider_ConvertToClaudeResponse(t *testing.T) {
+	p := &CompletionsProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(
+		`{"id":"cmpl_1","choices":[{"text":" sunny","finish_reason":"length"}],"usage":{"prompt_tokens":9,"completion_tokens":3}}`,
+	)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Text != " sunny" || resp.StopReason != "max_tokens" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 9 || resp.Usage.OutputTokens != 3 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestCompletionsProvider_HandleStreamResponse(t *testing.T) {
+	body := io.NopCloser(strings.NewReader(strings.Join([]string{
+		`data: {"id":"cmpl_1","choices":[{"index":0,"text":"He","finish_reason":null}]}`,
+		`data: {"id":"cmpl_1","choices":[{"index":0,"text":"llo","finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+		``,
+	}, "\n")))
+
+	events, errs, err := (&CompletionsProvider{}).HandleStreamResponse(body)
+	if err != nil {
+		t.Fatalf("HandleStreamResponse: %v", err)
+	}
+
+	var text strings.Builder
+	for ev := range events {
+		for _, line := range strings.Split(ev, "\n") {
+			if data := strings.TrimPrefix(line, "data: "); gjson.Get(data, "delta.type").String() == "text_delta" {
+				text.WriteString(gjson.Get(data, "delta.text").String())
+			}
+		}
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected stream error: %v", err)
+	default:
+	}
+	if text.String() != "Hello" {
+		t.Fatalf("streamed text = %q", text.String())
+	}
+}
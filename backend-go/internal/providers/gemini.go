@@ -10,6 +10,7 @@
 	"strings"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -34,15 +35,21 @@ func (p *GeminiProvider) ConvertToProviderRequest(c *gin.Context, upstream *conf
 	}
 
 	// --- 复用旧的转换逻辑 ---
-	geminiReq := p.convertToGeminiRequest(&claudeReq, upstream)
+	geminiReq, err := p.convertToGeminiRequest(&claudeReq, upstream)
+	if err != nil {
+		return nil, originalBodyBytes, err
+	}
 	// --- 转换逻辑结束 ---
 
+	model := config.RedirectModel(claudeReq.Model, upstream)
+
+	// cache_control 断点 → Gemini cachedContents（按 Key 创建并复用）
+	p.applyContextCache(c.Request.Context(), upstream, apiKey, model, &claudeReq, geminiReq)
+
 	reqBodyBytes, err := json.Marshal(geminiReq)
 	if err != nil {
 		return nil, originalBodyBytes, fmt.Errorf("序列化Gemini请求体失败: %w", err)
 	}
-
-	model := config.RedirectModel(claudeReq.Model, upstream)
 	action := "generateContent"
 	if claudeReq.Stream {
 		action = "streamGenerateContent?alt=sse"
@@ -64,7 +71,8 @@ func (p *GeminiProvider) ConvertToProviderRequest(c *gin.Context, upstream *conf
 }
 
 // convertToGeminiRequest 转换为 Gemini 请求体
-func (p *GeminiProvider) convertToGeminiRequest(claudeReq *types.ClaudeRequest, upstream *config.UpstreamConfig) map[string]interface{} {
+// 服务端工具没有 Gemini 等价物时返回 *converters.UnsupportedServerToolError
+func (p *GeminiProvider) convertToGeminiRequest(claudeReq *types.ClaudeRequest, upstream *config.UpstreamConfig) (map[string]interface{}, error) {
 	req := map[string]interface{}{
 		"contents": p.convertMessages(claudeReq.Messages),
 	}
@@ -92,20 +100,50 @@ func (p *GeminiProvider) convertToGeminiRequest(claudeReq *types.ClaudeRequest,
 		genConfig["temperature"] = claudeReq.Temperature
 	}
 
+	// thinking.budget_tokens → thinkingConfig
+	if claudeReq.Thinking != nil && claudeReq.Thinking.Type == "enabled" && claudeReq.Thinking.BudgetTokens > 0 {
+		genConfig["thinkingConfig"] = map[string]interface{}{
+			"includeThoughts": true,
+			"thinkingBudget":  claudeReq.Thinking.BudgetTokens,
+		}
+	}
+
+	// output_format → responseMimeType/responseSchema
+	if so := converters.StructuredOutputFromClaude(claudeReq.OutputFormat); so != nil {
+		genConfig["responseMimeType"] = "application/json"
+		if schema := so.GeminiResponseSchema(); schema != nil {
+			genConfig["responseSchema"] = schema
+		}
+	}
+
 	if len(genConfig) > 0 {
 		req["generationConfig"] = genConfig
 	}
 
-	// 工具
-	if len(claudeReq.Tools) > 0 {
-		req["tools"] = []map[string]interface{}{
-			{
-				"functionDeclarations": p.convertTools(claudeReq.Tools),
-			},
+	// 工具（服务端工具映射为 googleSearch/codeExecution，各自独占一个 tool 对象）
+	var tools []interface{}
+	functionTools := make([]types.ClaudeTool, 0, len(claudeReq.Tools))
+	for _, tool := range claudeReq.Tools {
+		st, err := converters.ServerToolFromClaude(tool, converters.ToolProtocolGemini)
+		if err != nil {
+			return nil, err
+		}
+		if st != nil {
+			tools = append(tools, st.GeminiTool())
+			continue
 		}
+		functionTools = append(functionTools, tool)
+	}
+	if len(functionTools) > 0 {
+		tools = append([]interface{}{map[string]interface{}{
+			"functionDeclarations": p.convertTools(functionTools),
+		}}, tools...)
+	}
+	if len(tools) > 0 {
+		req["tools"] = tools
 	}
 
-	return req
+	return req, nil
 }
 
 // convertMessages 转换消息
@@ -164,6 +202,12 @@ func (p *GeminiProvider) convertMessage(msg types.ClaudeMessage) map[string]inte
 				})
 			}
 
+		case "image", "document":
+			// 图片/PDF → inlineData（base64）或 fileData（url）
+			if part, ok := converters.ClaudeMediaBlockToGeminiPart(content); ok {
+				parts = append(parts, part)
+			}
+
 		case "tool_use":
 			name, _ := content["name"].(string)
 			input := content["input"]
@@ -255,12 +299,49 @@ func (p *GeminiProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 	}
 
 	// 处理各个部分
+	var codeCall *converters.ServerToolCall
+	flushCodeCall := func() {
+		if codeCall != nil {
+			claudeResp.Content = append(claudeResp.Content, codeCall.ClaudeBlocks()...)
+			codeCall = nil
+		}
+	}
 	for _, p := range parts {
 		part, ok := p.(map[string]interface{})
 		if !ok {
 			continue
 		}
 
+		// 代码执行 → server_tool_use + code_execution_tool_result
+		if code, ok := part["executableCode"].(map[string]interface{}); ok {
+			flushCodeCall()
+			codeStr, _ := code["code"].(string)
+			codeCall = &converters.ServerToolCall{ID: fmt.Sprintf("srvtoolu_%d", len(claudeResp.Content)), Kind: converters.ServerToolCodeExecution, Code: codeStr}
+			continue
+		}
+		if result, ok := part["codeExecutionResult"].(map[string]interface{}); ok {
+			if codeCall != nil {
+				outcome, _ := result["outcome"].(string)
+				codeCall.Output, _ = result["output"].(string)
+				codeCall.Failed = outcome != "OUTCOME_OK"
+				codeCall.HasResult = true
+				flushCodeCall()
+			}
+			continue
+		}
+		flushCodeCall()
+
+		// 思考内容（thought=true）→ thinking 块
+		if text, ok := part["text"].(string); ok {
+			if thought, _ := part["thought"].(bool); thought {
+				claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
+					Type:     "thinking",
+					Thinking: text,
+				})
+				continue
+			}
+		}
+
 		// 文本内容
 		if text, ok := part["text"].(string); ok {
 			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
@@ -283,6 +364,18 @@ func (p *GeminiProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 		}
 	}
 
+	flushCodeCall()
+
+	// groundingMetadata → 搜索调用块（置于正文之前）与文本引用
+	if gm := converters.GeminiGroundingFromMap(candidate["groundingMetadata"]); gm != nil {
+		calls, citations := converters.WebSearchFromGemini(gm, claudeTextContent(claudeResp.Content), "srvtoolu_search")
+		var searchBlocks []types.ClaudeContent
+		for _, call := range calls {
+			searchBlocks = append(searchBlocks, call.ClaudeBlocks()...)
+		}
+		claudeResp.Content = append(searchBlocks, converters.ApplyWebCitations(claudeResp.Content, citations)...)
+	}
+
 	// 设置停止原因
 	finishReason, _ := candidate["finishReason"].(string)
 	if strings.Contains(strings.ToLower(finishReason), "stop") {
@@ -306,14 +399,7 @@ func (p *GeminiProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 
 	// 使用统计
 	if usageMetadata, ok := geminiResp["usageMetadata"].(map[string]interface{}); ok {
-		usage := &types.Usage{}
-		if promptTokens, ok := usageMetadata["promptTokenCount"].(float64); ok {
-			usage.InputTokens = int(promptTokens)
-		}
-		if candidatesTokens, ok := usageMetadata["candidatesTokenCount"].(float64); ok {
-			usage.OutputTokens = int(candidatesTokens)
-		}
-		claudeResp.Usage = usage
+		claudeResp.Usage = geminiUsageToClaude(usageMetadata)
 	}
 
 	return claudeResp, nil
@@ -340,6 +426,49 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 		textBlockStarted := false
 		textBlockIndex := 0
 
+		// 思考块状态跟踪（thought=true 的 part → thinking 块，与文本块共用索引）
+		thinkingBlockStarted := false
+		closeThinkingBlock := func() {
+			if !thinkingBlockStarted {
+				return
+			}
+			stopEvent := map[string]interface{}{
+				"type":  "content_block_stop",
+				"index": textBlockIndex,
+			}
+			stopJSON, _ := json.Marshal(stopEvent)
+			eventChan <- fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stopJSON)
+			thinkingBlockStarted = false
+			textBlockIndex++
+			if toolUseBlockIndex < textBlockIndex {
+				toolUseBlockIndex = textBlockIndex
+			}
+		}
+
+		// 服务端工具块（代码执行、联网搜索）以完整块输出，先关闭进行中的文本/思考块
+		var streamedText strings.Builder
+		lastCodeID := ""
+		groundingEmitted := false
+		emitServerBlocks := func(blocks []types.ClaudeContent) {
+			closeThinkingBlock()
+			if textBlockStarted {
+				stopJSON, _ := json.Marshal(map[string]interface{}{"type": "content_block_stop", "index": textBlockIndex})
+				eventChan <- fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stopJSON)
+				textBlockStarted = false
+				textBlockIndex++
+			}
+			if toolUseBlockIndex < textBlockIndex {
+				toolUseBlockIndex = textBlockIndex
+			}
+			for _, block := range blocks {
+				for _, event := range converters.ClaudeBlockEvents(toolUseBlockIndex, block) {
+					eventChan <- event
+				}
+				toolUseBlockIndex++
+			}
+			textBlockIndex = toolUseBlockIndex
+		}
+
 		for scanner.Scan() {
 			line := scanner.Text()
 			line = strings.TrimSpace(line)
@@ -369,15 +498,9 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 				continue
 			}
 
-			content, ok := candidate["content"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			parts, ok := content["parts"].([]interface{})
-			if !ok {
-				continue
-			}
+			// 最后一个 chunk 可能只有 finishReason / groundingMetadata，没有 content
+			content, _ := candidate["content"].(map[string]interface{})
+			parts, _ := content["parts"].([]interface{})
 
 			for _, p := range parts {
 				part, ok := p.(map[string]interface{})
@@ -385,8 +508,59 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 					continue
 				}
 
+				// 代码执行 → server_tool_use / code_execution_tool_result
+				if code, ok := part["executableCode"].(map[string]interface{}); ok {
+					codeStr, _ := code["code"].(string)
+					lastCodeID = fmt.Sprintf("srvtoolu_%d", toolUseBlockIndex)
+					call := &converters.ServerToolCall{ID: lastCodeID, Kind: converters.ServerToolCodeExecution, Code: codeStr}
+					emitServerBlocks(call.ClaudeBlocks())
+					continue
+				}
+				if result, ok := part["codeExecutionResult"].(map[string]interface{}); ok {
+					outcome, _ := result["outcome"].(string)
+					output, _ := result["output"].(string)
+					call := &converters.ServerToolCall{ID: lastCodeID, Kind: converters.ServerToolCodeExecution, Output: output, Failed: outcome != "OUTCOME_OK", HasResult: true}
+					emitServerBlocks(call.ClaudeBlocks()[1:])
+					continue
+				}
+
+				// 处理思考内容
+				if text, ok := part["text"].(string); ok {
+					if thought, _ := part["thought"].(bool); thought {
+						if textBlockStarted {
+							continue // 正文开始后的思考片段无法插入，直接丢弃
+						}
+						if !thinkingBlockStarted {
+							startEvent := map[string]interface{}{
+								"type":  "content_block_start",
+								"index": textBlockIndex,
+								"content_block": map[string]string{
+									"type":     "thinking",
+									"thinking": "",
+								},
+							}
+							startJSON, _ := json.Marshal(startEvent)
+							eventChan <- fmt.Sprintf("event: content_block_start\ndata: %s\n\n", startJSON)
+							thinkingBlockStarted = true
+						}
+
+						deltaEvent := map[string]interface{}{
+							"type":  "content_block_delta",
+							"index": textBlockIndex,
+							"delta": map[string]string{
+								"type":     "thinking_delta",
+								"thinking": text,
+							},
+						}
+						deltaJSON, _ := json.Marshal(deltaEvent)
+						eventChan <- fmt.Sprintf("event: content_block_delta\ndata: %s\n\n", deltaJSON)
+						continue
+					}
+				}
+
 				// 处理文本
 				if text, ok := part["text"].(string); ok {
+					closeThinkingBlock()
 					// 如果是第一个文本块,发送 content_block_start
 					if !textBlockStarted {
 						startEvent := map[string]interface{}{
@@ -403,6 +577,7 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 					}
 
 					// 发送 content_block_delta
+					streamedText.WriteString(text)
 					deltaEvent := map[string]interface{}{
 						"type":  "content_block_delta",
 						"index": textBlockIndex,
@@ -417,6 +592,7 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 				// 处理函数调用
 				if fc, ok := part["functionCall"].(map[string]interface{}); ok {
+					closeThinkingBlock()
 					// 如果有文本块正在进行,先关闭它
 					if textBlockStarted {
 						stopEvent := map[string]interface{}{
@@ -441,8 +617,27 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 				}
 			}
 
+			// groundingMetadata：引用挂到当前文本块，随后输出搜索调用块
+			if gm := converters.GeminiGroundingFromMap(candidate["groundingMetadata"]); gm != nil && !groundingEmitted {
+				calls, citations := converters.WebSearchFromGemini(gm, streamedText.String(), "srvtoolu_search")
+				if len(calls) > 0 {
+					groundingEmitted = true
+					if textBlockStarted {
+						for _, citation := range citations {
+							eventChan <- converters.ClaudeCitationDeltaEvent(textBlockIndex, citation)
+						}
+					}
+					var blocks []types.ClaudeContent
+					for _, call := range calls {
+						blocks = append(blocks, call.ClaudeBlocks()...)
+					}
+					emitServerBlocks(blocks)
+				}
+			}
+
 			// 处理结束原因
 			if finishReason, ok := candidate["finishReason"].(string); ok {
+				closeThinkingBlock()
 				// 如果有未关闭的文本块,先关闭它
 				if textBlockStarted {
 					stopEvent := map[string]interface{}{
@@ -461,13 +656,17 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 							"stop_reason": "end_turn",
 						},
 					}
+					if usageMetadata, ok := chunk["usageMetadata"].(map[string]interface{}); ok {
+						event["usage"] = geminiUsageToClaude(usageMetadata)
+					}
 					eventJSON, _ := json.Marshal(event)
 					eventChan <- fmt.Sprintf("event: message_delta\ndata: %s\n\n", eventJSON)
 				}
 			}
 		}
 
-		// 确保流结束时关闭任何未关闭的文本块
+		// 确保流结束时关闭任何未关闭的思考块/文本块
+		closeThinkingBlock()
 		if textBlockStarted {
 			stopEvent := map[string]interface{}{
 				"type":  "content_block_stop",
@@ -484,3 +683,14 @@ func (p *GeminiProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 
 	return eventChan, errChan, nil
 }
+
+// claudeTextContent 拼接 Claude 内容中的文本块（用于换算引用偏移）
+func claudeTextContent(content []types.ClaudeContent) string {
+	var sb strings.Builder
+	for _, block := range content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,363 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/tidwall/gjson"
+)
+
+// Gemini 自动上下文缓存
+//
+// Claude 请求中的 cache_control 断点在 Gemini 上没有等价字段。这里把最后一个断点之前的前缀
+// （systemInstruction + tools + 断点所在消息及之前的 contents）创建为 Gemini cachedContents，
+// 按 (BaseURL, API Key, 前缀哈希) 复用；生成请求改为引用 cachedContent，只发送剩余的 contents。
+// 同一前缀同时只有一个请求负责创建缓存，其余并发请求不等待，直接按未缓存方式发送。
+// Gemini 不允许引用缓存的请求再携带 systemInstruction / tools / toolConfig，因此它们总是随前缀一起缓存。
+
+const (
+	// geminiContextCacheMinTokens Gemini 显式缓存的最小 token 数，低于该值（本地估算）不创建缓存
+	geminiContextCacheMinTokens = 1024
+	// geminiContextCacheFailureCooldown 创建失败后同一前缀的重试冷却时间
+	geminiContextCacheFailureCooldown = 10 * time.Minute
+	// geminiContextCacheExpiryMargin 临近过期的缓存不再引用，避免请求到达上游时已失效
+	geminiContextCacheExpiryMargin   = 30 * time.Second
+	geminiContextCacheRequestTimeout = 30 * time.Second
+)
+
+// geminiCacheBreakpoint cache_control 断点在 Gemini 请求中的位置
+type geminiCacheBreakpoint struct {
+	contents int           // 前缀包含的 Gemini contents 数量（tools / system 上的断点为 0）
+	ttl      time.Duration // 由 cache_control.ttl 决定
+}
+
+// geminiCacheEntry 已创建的 Gemini 缓存
+type geminiCacheEntry struct {
+	name       string
+	ttl        time.Duration
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// geminiContextCache 管理自动创建的 Gemini 缓存（进程内，按 Key 隔离）
+type geminiContextCache struct {
+	mu          sync.Mutex
+	entries     map[string]*geminiCacheEntry
+	failures    map[string]time.Time
+	creating    map[string]bool // 正在创建缓存的前缀，避免并发请求重复创建（每次创建都会产生计费的 cachedContents）
+	lastCleanup time.Time
+}
+
+var geminiContextCaches = newGeminiContextCache()
+
+func newGeminiContextCache() *geminiContextCache {
+	return &geminiContextCache{
+		entries:     make(map[string]*geminiCacheEntry),
+		failures:    make(map[string]time.Time),
+		creating:    make(map[string]bool),
+		lastCleanup: time.Now(),
+	}
+}
+
+// applyContextCache 将 Claude cache_control 断点转换为 Gemini cachedContent 引用
+// 缓存不可用（前缀过短、创建失败等）时保持请求不变
+func (p *GeminiProvider) applyContextCache(ctx context.Context, upstream *config.UpstreamConfig, apiKey, model string, claudeReq *types.ClaudeRequest, geminiReq map[string]interface{}) {
+	contents, _ := geminiReq["contents"].([]map[string]interface{})
+
+	// 取最后一个可用断点；生成请求至少要保留一条 contents，落在最后一条消息上的断点无法缓存
+	target, found := geminiCacheBreakpoint{}, false
+	for _, bp := range p.geminiCacheBreakpoints(claudeReq) {
+		if bp.contents < len(contents) {
+			target, found = bp, true
+		}
+	}
+	if !found {
+		return
+	}
+
+	prefix := map[string]interface{}{
+		"model":    "models/" + model,
+		"contents": contents[:target.contents],
+	}
+	for _, field := range []string{"systemInstruction", "tools", "toolConfig"} {
+		if v, ok := geminiReq[field]; ok {
+			prefix[field] = v
+		}
+	}
+	prefixBytes, err := json.Marshal(prefix)
+	if err != nil {
+		return
+	}
+
+	baseURL := strings.TrimSuffix(upstream.GetEffectiveBaseURL(), "/")
+	name := geminiContextCaches.resolve(ctx, upstream, baseURL, apiKey, prefix, prefixBytes, target.ttl)
+	if name == "" {
+		return
+	}
+
+	geminiReq["cachedContent"] = name
+	geminiReq["contents"] = contents[target.contents:]
+	delete(geminiReq, "systemInstruction")
+	delete(geminiReq, "tools")
+	delete(geminiReq, "toolConfig")
+}
+
+// geminiCacheBreakpoints 按 Claude 前缀顺序（tools → system → messages）收集 cache_control 断点
+func (p *GeminiProvider) geminiCacheBreakpoints(claudeReq *types.ClaudeRequest) []geminiCacheBreakpoint {
+	var breakpoints []geminiCacheBreakpoint
+
+	for _, tool := range claudeReq.Tools {
+		if tool.CacheControl != nil {
+			breakpoints = append(breakpoints, geminiCacheBreakpoint{ttl: geminiCacheTTL(tool.CacheControl.TTL)})
+		}
+	}
+
+	systemBlocks, _ := claudeReq.System.([]interface{})
+	for _, block := range systemBlocks {
+		if cc, ok := cacheControlFromBlock(block); ok {
+			breakpoints = append(breakpoints, geminiCacheBreakpoint{ttl: geminiCacheTTL(cc.Get("ttl").String())})
+		}
+	}
+
+	// 部分消息转换后为空会被丢弃，断点位置按实际生成的 contents 数量计算
+	converted := 0
+	for _, msg := range claudeReq.Messages {
+		if p.convertMessage(msg) != nil {
+			converted++
+		}
+		blocks, _ := msg.Content.([]interface{})
+		for _, block := range blocks {
+			if cc, ok := cacheControlFromBlock(block); ok {
+				breakpoints = append(breakpoints, geminiCacheBreakpoint{contents: converted, ttl: geminiCacheTTL(cc.Get("ttl").String())})
+			}
+		}
+	}
+
+	return breakpoints
+}
+
+// cacheControlFromBlock 读取内容块上的 cache_control
+func cacheControlFromBlock(block interface{}) (gjson.Result, bool) {
+	obj, ok := block.(map[string]interface{})
+	if !ok || obj["cache_control"] == nil {
+		return gjson.Result{}, false
+	}
+	raw, err := json.Marshal(obj["cache_control"])
+	if err != nil {
+		return gjson.Result{}, false
+	}
+	return gjson.ParseBytes(raw), true
+}
+
+// geminiCacheTTL 将 cache_control.ttl 转换为缓存时长（默认 5 分钟）
+func geminiCacheTTL(ttl string) time.Duration {
+	if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// resolve 返回前缀对应的缓存名，不存在时创建；失败或其他请求正在创建时返回空字符串
+func (m *geminiContextCache) resolve(ctx context.Context, upstream *config.UpstreamConfig, baseURL, apiKey string, prefix map[string]interface{}, prefixBytes []byte, ttl time.Duration) string {
+	prefixHash := sha256.Sum256(prefixBytes)
+	keyHash := sha256.Sum256([]byte(apiKey))
+	entryKey := baseURL + "|" + hex.EncodeToString(keyHash[:8]) + "|" + hex.EncodeToString(prefixHash[:])
+
+	m.mu.Lock()
+	name, ok := m.lookupLocked(upstream, baseURL, apiKey, entryKey)
+	m.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	if utils.EstimateTokens(string(prefixBytes)) < geminiContextCacheMinTokens {
+		return ""
+	}
+
+	// 估算期间其他请求可能已开始或完成创建，加锁后重新检查再标记为创建中
+	m.mu.Lock()
+	if name, ok := m.lookupLocked(upstream, baseURL, apiKey, entryKey); ok {
+		m.mu.Unlock()
+		return name
+	}
+	m.creating[entryKey] = true
+	m.mu.Unlock()
+
+	name, expiresAt, err := m.create(ctx, upstream, baseURL, apiKey, prefix, ttl)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.creating, entryKey)
+	if time.Since(m.lastCleanup) > 10*time.Minute {
+		m.cleanupLocked()
+	}
+	if err != nil {
+		// 客户端取消不代表前缀不可缓存，不进入冷却
+		if ctx.Err() == nil {
+			log.Printf("[Gemini-ContextCache] 警告: 创建缓存失败 (key=%s): %v", utils.MaskAPIKey(apiKey), err)
+			m.failures[entryKey] = time.Now().Add(geminiContextCacheFailureCooldown)
+		}
+		return ""
+	}
+	m.entries[entryKey] = &geminiCacheEntry{name: name, ttl: ttl, expiresAt: expiresAt}
+	log.Printf("[Gemini-ContextCache] 已创建缓存: %s (key=%s, TTL: %v)", name, utils.MaskAPIKey(apiKey), ttl)
+	return name
+}
+
+// lookupLocked 查找可用缓存；ok 为 true 表示无需创建（命中缓存，或处于失败冷却期、其他请求正在创建时返回空名称）
+func (m *geminiContextCache) lookupLocked(upstream *config.UpstreamConfig, baseURL, apiKey, entryKey string) (name string, ok bool) {
+	now := time.Now()
+	if entry := m.entries[entryKey]; entry != nil && now.Before(entry.expiresAt.Add(-geminiContextCacheExpiryMargin)) {
+		// 与 Anthropic 一致，命中即续期：剩余时间不足一半时异步延长 TTL
+		if !entry.refreshing && entry.expiresAt.Sub(now) < entry.ttl/2 {
+			entry.refreshing = true
+			go m.refresh(upstream, baseURL, apiKey, entryKey, entry.name)
+		}
+		return entry.name, true
+	}
+	if until, exists := m.failures[entryKey]; exists && now.Before(until) {
+		return "", true
+	}
+	if m.creating[entryKey] {
+		return "", true
+	}
+	return "", false
+}
+
+// create 调用 cachedContents.create 创建缓存
+func (m *geminiContextCache) create(ctx context.Context, upstream *config.UpstreamConfig, baseURL, apiKey string, prefix map[string]interface{}, ttl time.Duration) (string, time.Time, error) {
+	body := make(map[string]interface{}, len(prefix)+1)
+	for k, v := range prefix {
+		body[k] = v
+	}
+	body["ttl"] = fmt.Sprintf("%ds", int(ttl.Seconds()))
+
+	respBody, err := m.send(ctx, upstream, http.MethodPost, baseURL+"/cachedContents", apiKey, body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	name := gjson.GetBytes(respBody, "name").String()
+	if name == "" {
+		return "", time.Time{}, fmt.Errorf("响应缺少缓存名称")
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, gjson.GetBytes(respBody, "expireTime").String())
+	if err != nil {
+		expiresAt = time.Now().Add(ttl)
+	}
+	return name, expiresAt, nil
+}
+
+// refresh 延长缓存 TTL；缓存已不存在时移除记录
+func (m *geminiContextCache) refresh(upstream *config.UpstreamConfig, baseURL, apiKey, entryKey, name string) {
+	m.mu.Lock()
+	entry := m.entries[entryKey]
+	if entry == nil || entry.name != name {
+		m.mu.Unlock()
+		return
+	}
+	ttl := entry.ttl
+	m.mu.Unlock()
+
+	respBody, err := m.send(context.Background(), upstream, http.MethodPatch, baseURL+"/"+name+"?updateMask=ttl", apiKey,
+		map[string]interface{}{"ttl": fmt.Sprintf("%ds", int(ttl.Seconds()))})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry = m.entries[entryKey]
+	if entry == nil || entry.name != name {
+		return
+	}
+	entry.refreshing = false
+	if err != nil {
+		log.Printf("[Gemini-ContextCache] 警告: 续期缓存 %s 失败，移除记录: %v", name, err)
+		delete(m.entries, entryKey)
+		return
+	}
+	if expiresAt, err := time.Parse(time.RFC3339Nano, gjson.GetBytes(respBody, "expireTime").String()); err == nil {
+		entry.expiresAt = expiresAt
+	} else {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+}
+
+// send 发送 cachedContents 管理请求
+func (m *geminiContextCache) send(ctx context.Context, upstream *config.UpstreamConfig, method, targetURL, apiKey string, body interface{}) ([]byte, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, geminiContextCacheRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = utils.PrepareMinimalHeaders(req.URL.Host)
+	utils.SetGeminiAuthenticationHeader(req.Header, apiKey)
+
+	client := httpclient.GetManager().GetStandardClient(geminiContextCacheRequestTimeout, upstream.InsecureSkipVerify)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	respBody = utils.DecompressGzipIfNeeded(resp, respBody)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("上游返回状态码 %d: %s", resp.StatusCode, utils.FormatJSONBytesForLog(respBody, 200))
+	}
+	return respBody, nil
+}
+
+func (m *geminiContextCache) cleanupLocked() {
+	now := time.Now()
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+	for key, until := range m.failures {
+		if now.After(until) {
+			delete(m.failures, key)
+		}
+	}
+	m.lastCleanup = now
+}
+
+// geminiUsageToClaude 将 Gemini usageMetadata 转换为 Claude usage
+// 命中缓存的 token（cachedContentTokenCount）计入 cache_read_input_tokens，不重复计入 input_tokens
+func geminiUsageToClaude(usageMetadata map[string]interface{}) *types.Usage {
+	usage := &types.Usage{}
+	if promptTokens, ok := usageMetadata["promptTokenCount"].(float64); ok {
+		usage.InputTokens = int(promptTokens)
+	}
+	if candidatesTokens, ok := usageMetadata["candidatesTokenCount"].(float64); ok {
+		usage.OutputTokens = int(candidatesTokens)
+	}
+	if cachedTokens, ok := usageMetadata["cachedContentTokenCount"].(float64); ok && cachedTokens > 0 {
+		usage.CacheReadInputTokens = int(cachedTokens)
+		usage.InputTokens -= int(cachedTokens)
+		if usage.InputTokens < 0 {
+			usage.InputTokens = 0
+		}
+	}
+	return usage
+}
@@ -0,0 +1,275 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// fakeGeminiCacheUpstream 记录 cachedContents.create 请求；failCreate 为 true 时创建返回 400
+func fakeGeminiCacheUpstream(t *testing.T, failCreate bool) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var creates []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/v1beta/cachedContents" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		creates = append(creates, r.Header.Get("x-goog-api-key")+" "+string(body))
+		n := len(creates)
+		mu.Unlock()
+		if failCreate {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":400,"message":"Cached content is too small","status":"INVALID_ARGUMENT"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"cachedContents/c` + string(rune('0'+n)) + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), creates...)
+	}
+}
+
+func convertGeminiCacheRequest(t *testing.T, upstream *config.UpstreamConfig, apiKey string, body map[string]interface{}) []byte {
+	t.Helper()
+	raw, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(raw))
+
+	p := &GeminiProvider{}
+	req, _, err := p.ConvertToProviderRequest(c, upstream, apiKey)
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+	reqBody, _ := io.ReadAll(req.Body)
+	return reqBody
+}
+
+func TestGeminiProvider_ContextCacheFromSystemBreakpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv, creates := fakeGeminiCacheUpstream(t, false)
+	upstream := &config.UpstreamConfig{BaseURL: srv.URL + "/v1beta", ServiceType: "gemini"}
+
+	longSystem := strings.Repeat("Follow the style guide carefully. ", 400)
+	request := func(question string) map[string]interface{} {
+		return map[string]interface{}{
+			"model":      "gemini-2.5-pro",
+			"max_tokens": 100,
+			"system": []interface{}{
+				map[string]interface{}{"type": "text", "text": longSystem, "cache_control": map[string]interface{}{"type": "ephemeral"}},
+			},
+			"tools": []interface{}{
+				map[string]interface{}{"name": "lookup", "input_schema": map[string]interface{}{"type": "object"}},
+			},
+			"messages": []interface{}{map[string]interface{}{"role": "user", "content": question}},
+		}
+	}
+
+	// 首次使用：创建缓存，生成请求引用缓存并移除 systemInstruction / tools
+	body := convertGeminiCacheRequest(t, upstream, "key-a", request("first"))
+	if gjson.GetBytes(body, "cachedContent").String() != "cachedContents/c1" ||
+		gjson.GetBytes(body, "systemInstruction").Exists() ||
+		gjson.GetBytes(body, "tools").Exists() ||
+		gjson.GetBytes(body, "contents.#").Int() != 1 ||
+		gjson.GetBytes(body, "contents.0.parts.0.text").String() != "first" {
+		t.Fatalf("unexpected generate body: %s", body)
+	}
+	got := creates()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "key-a ") {
+		t.Fatalf("expected one create with key-a, got %v", got)
+	}
+	create := gjson.Parse(strings.TrimPrefix(got[0], "key-a "))
+	if create.Get("model").String() != "models/gemini-2.5-pro" ||
+		create.Get("ttl").String() != "300s" ||
+		create.Get("systemInstruction.parts.0.text").String() != longSystem ||
+		create.Get("tools.0.functionDeclarations.0.name").String() != "lookup" ||
+		create.Get("contents.#").Int() != 0 {
+		t.Fatalf("unexpected create body: %s", create.Raw)
+	}
+
+	// 相同前缀、相同 Key：复用缓存
+	body = convertGeminiCacheRequest(t, upstream, "key-a", request("second"))
+	if gjson.GetBytes(body, "cachedContent").String() != "cachedContents/c1" || len(creates()) != 1 {
+		t.Fatalf("expected cache reuse, body=%s creates=%d", body, len(creates()))
+	}
+
+	// 不同 Key：缓存不可跨 Key 使用，需单独创建
+	body = convertGeminiCacheRequest(t, upstream, "key-b", request("third"))
+	if gjson.GetBytes(body, "cachedContent").String() != "cachedContents/c2" || len(creates()) != 2 {
+		t.Fatalf("expected separate cache per key, body=%s creates=%d", body, len(creates()))
+	}
+}
+
+func TestGeminiProvider_ContextCacheFromMessageBreakpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv, creates := fakeGeminiCacheUpstream(t, false)
+	upstream := &config.UpstreamConfig{BaseURL: srv.URL + "/v1beta", ServiceType: "gemini"}
+
+	document := strings.Repeat("Chapter text about distributed systems. ", 400)
+	body := convertGeminiCacheRequest(t, upstream, "key-a", map[string]interface{}{
+		"model": "gemini-2.5-flash",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": document, "cache_control": map[string]interface{}{"type": "ephemeral", "ttl": "1h"}},
+			}},
+			map[string]interface{}{"role": "assistant", "content": "Read it."},
+			// 最后一条消息上的断点无法缓存（生成请求至少保留一条内容），回退到上一个断点
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "Summarize chapter 1", "cache_control": map[string]interface{}{"type": "ephemeral"}},
+			}},
+		},
+	})
+
+	if gjson.GetBytes(body, "cachedContent").String() == "" ||
+		gjson.GetBytes(body, "contents.#").Int() != 2 ||
+		gjson.GetBytes(body, "contents.0.role").String() != "model" {
+		t.Fatalf("unexpected generate body: %s", body)
+	}
+	got := creates()
+	if len(got) != 1 {
+		t.Fatalf("expected one create, got %d", len(got))
+	}
+	create := gjson.Parse(strings.TrimPrefix(got[0], "key-a "))
+	if create.Get("ttl").String() != "3600s" || create.Get("contents.#").Int() != 1 || create.Get("contents.0.parts.0.text").String() != document {
+		t.Fatalf("unexpected create body: %s", create.Raw)
+	}
+}
+
+func TestGeminiProvider_ContextCacheSkipsShortOrFailingPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv, creates := fakeGeminiCacheUpstream(t, true)
+	upstream := &config.UpstreamConfig{BaseURL: srv.URL + "/v1beta", ServiceType: "gemini"}
+
+	request := func(system string) map[string]interface{} {
+		return map[string]interface{}{
+			"model": "gemini-2.5-flash",
+			"system": []interface{}{
+				map[string]interface{}{"type": "text", "text": system, "cache_control": map[string]interface{}{"type": "ephemeral"}},
+			},
+			"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+		}
+	}
+
+	// 前缀低于最小 token 数：不创建缓存，请求保持原样
+	body := convertGeminiCacheRequest(t, upstream, "key-a", request("Be brief."))
+	if gjson.GetBytes(body, "cachedContent").Exists() || gjson.GetBytes(body, "systemInstruction.parts.0.text").String() != "Be brief." {
+		t.Fatalf("short prefix should not be cached: %s", body)
+	}
+	if len(creates()) != 0 {
+		t.Fatalf("short prefix should not trigger create")
+	}
+
+	// 创建失败：回退为普通请求，冷却期内不重复创建
+	longSystem := strings.Repeat("Long instructions. ", 600)
+	for i := 0; i < 2; i++ {
+		body = convertGeminiCacheRequest(t, upstream, "key-a", request(longSystem))
+		if gjson.GetBytes(body, "cachedContent").Exists() || !gjson.GetBytes(body, "systemInstruction").Exists() {
+			t.Fatalf("failed create should fall back to inline prefix: %s", body)
+		}
+	}
+	if len(creates()) != 1 {
+		t.Fatalf("expected a single create attempt during cooldown, got %d", len(creates()))
+	}
+}
+
+func TestGeminiProvider_CachedTokensReportedAsCacheRead(t *testing.T) {
+	t.Parallel()
+
+	p := &GeminiProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":5000,"cachedContentTokenCount":4800,"candidatesTokenCount":12}}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 200 || resp.Usage.CacheReadInputTokens != 4800 || resp.Usage.OutputTokens != 12 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+
+	sse := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5000,"cachedContentTokenCount":4800,"candidatesTokenCount":12}}`
+	eventCh, _, err := p.HandleStreamResponse(io.NopCloser(strings.NewReader(sse)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse: %v", err)
+	}
+	var usage gjson.Result
+	for e := range eventCh {
+		if strings.HasPrefix(e, "event: message_delta") {
+			usage = gjson.Get(strings.TrimSpace(strings.SplitN(e, "data: ", 2)[1]), "usage")
+		}
+	}
+	if usage.Get("input_tokens").Int() != 200 || usage.Get("cache_read_input_tokens").Int() != 4800 || usage.Get("output_tokens").Int() != 12 {
+		t.Fatalf("unexpected stream usage: %s", usage.Raw)
+	}
+}
+
+func TestGeminiProvider_ContextCacheConcurrentResolveCreatesOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var creates int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1beta/cachedContents" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if atomic.AddInt32(&creates, 1) == 1 {
+			close(started)
+		}
+		<-release
+		_, _ = w.Write([]byte(`{"name":"cachedContents/shared"}`))
+	}))
+	t.Cleanup(srv.Close)
+	upstream := &config.UpstreamConfig{BaseURL: srv.URL + "/v1beta", ServiceType: "gemini"}
+
+	longSystem := strings.Repeat("Shared prefix for concurrent requests. ", 400)
+	request := map[string]interface{}{
+		"model": "gemini-2.5-pro",
+		"system": []interface{}{
+			map[string]interface{}{"type": "text", "text": longSystem, "cache_control": map[string]interface{}{"type": "ephemeral"}},
+		},
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+	}
+
+	const n = 8
+	bodies := make(chan []byte, n)
+	for i := 0; i < n; i++ {
+		go func() { bodies <- convertGeminiCacheRequest(t, upstream, "key-a", request) }()
+	}
+
+	// 创建进行中：其余请求不等待，直接按未缓存方式发送
+	<-started
+	for i := 0; i < n-1; i++ {
+		body := <-bodies
+		if gjson.GetBytes(body, "cachedContent").Exists() || !gjson.GetBytes(body, "systemInstruction").Exists() {
+			t.Fatalf("request during in-flight create should be sent uncached: %s", body)
+		}
+	}
+	close(release)
+	if body := <-bodies; gjson.GetBytes(body, "cachedContent").String() != "cachedContents/shared" {
+		t.Fatalf("creating request should reference the new cache: %s", body)
+	}
+
+	if body := convertGeminiCacheRequest(t, upstream, "key-a", request); gjson.GetBytes(body, "cachedContent").String() != "cachedContents/shared" {
+		t.Fatalf("expected cache reuse after create: %s", body)
+	}
+	if got := atomic.LoadInt32(&creates); got != 1 {
+		t.Fatalf("upstream creates = %d, want exactly 1", got)
+	}
+}
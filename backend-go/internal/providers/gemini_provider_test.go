@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiProvider_ConvertMessage_ImageAndDocument(t *testing.T) {
+	t.Parallel()
+
+	p := &GeminiProvider{}
+	msg := p.convertMessage(types.ClaudeMessage{
+		Role: "user",
+		Content: []interface{}{
+			map[string]interface{}{
+				"type":   "image",
+				"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "AAAA"},
+			},
+			map[string]interface{}{
+				"type":   "document",
+				"source": map[string]interface{}{"type": "url", "url": "https://example.com/spec.pdf"},
+			},
+			map[string]interface{}{"type": "text", "text": "summarize"},
+		},
+	})
+
+	raw, _ := json.Marshal(msg)
+	parts := gjson.GetBytes(raw, "parts")
+	if parts.Get("0.inlineData.mimeType").String() != "image/png" || parts.Get("0.inlineData.data").String() != "AAAA" {
+		t.Fatalf("unexpected image part: %s", parts.Raw)
+	}
+	if parts.Get("1.fileData.fileUri").String() != "https://example.com/spec.pdf" ||
+		parts.Get("1.fileData.mimeType").String() != "application/pdf" {
+		t.Fatalf("unexpected document part: %s", parts.Raw)
+	}
+	if parts.Get("2.text").String() != "summarize" {
+		t.Fatalf("unexpected text part: %s", parts.Raw)
+	}
+}
+
+func TestGeminiProvider_ThinkingRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	p := &GeminiProvider{}
+	req, _ := p.convertToGeminiRequest(&types.ClaudeRequest{
+		Model:    "claude-3",
+		Messages: []types.ClaudeMessage{{Role: "user", Content: "hi"}},
+		Thinking: &types.ClaudeThinking{Type: "enabled", BudgetTokens: 4096},
+	}, &config.UpstreamConfig{})
+	raw, _ := json.Marshal(req)
+	if cfg := gjson.GetBytes(raw, "generationConfig.thinkingConfig"); cfg.Get("thinkingBudget").Int() != 4096 || !cfg.Get("includeThoughts").Bool() {
+		t.Fatalf("unexpected thinkingConfig: %s", raw)
+	}
+
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"candidates":[{"content":{"role":"model","parts":[
+		{"text":"pondering","thought":true},{"text":"answer"}
+	]},"finishReason":"STOP"}]}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != "pondering" || resp.Content[1].Text != "answer" {
+		t.Fatalf("unexpected content: %+v", resp.Content)
+	}
+
+	sse := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"pondering","thought":true}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"answer"}]},"finishReason":"STOP"}]}`,
+	}, "\n")
+	eventCh, _, err := p.HandleStreamResponse(io.NopCloser(strings.NewReader(sse)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse: %v", err)
+	}
+	var events []string
+	for e := range eventCh {
+		events = append(events, e)
+	}
+	joined := strings.Join(events, "")
+	for _, want := range []string{
+		`"delta":{"thinking":"pondering","type":"thinking_delta"},"index":0`,
+		`"delta":{"text":"answer","type":"text_delta"},"index":1`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing %s in events:\n%s", want, joined)
+		}
+	}
+}
+
+func TestGeminiProvider_OutputFormatToResponseSchema(t *testing.T) {
+	t.Parallel()
+
+	req, _ := (&GeminiProvider{}).convertToGeminiRequest(&types.ClaudeRequest{
+		Model:    "claude-3",
+		Messages: []types.ClaudeMessage{{Role: "user", Content: "hi"}},
+		OutputFormat: map[string]interface{}{
+			"type": "json_schema",
+			"schema": map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+				"additionalProperties": false,
+			},
+		},
+	}, &config.UpstreamConfig{})
+	raw, _ := json.Marshal(req)
+	cfg := gjson.GetBytes(raw, "generationConfig")
+	if cfg.Get("responseMimeType").String() != "application/json" || cfg.Get("responseSchema.properties.name.type").String() != "string" {
+		t.Fatalf("unexpected generationConfig: %s", raw)
+	}
+	if cfg.Get("responseSchema.additionalProperties").Exists() {
+		t.Fatalf("unsupported schema keywords should be stripped: %s", raw)
+	}
+}
+
+func TestGeminiProvider_ServerToolResults(t *testing.T) {
+	t.Parallel()
+
+	p := &GeminiProvider{}
+	resp, err := p.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{"candidates":[{"content":{"role":"model","parts":[
+		{"executableCode":{"language":"PYTHON","code":"print(1)"}},
+		{"codeExecutionResult":{"outcome":"OUTCOME_OK","output":"1\n"}},
+		{"text":"Answer: Go 1.25"}
+	]},"finishReason":"STOP","groundingMetadata":{
+		"webSearchQueries":["go release"],
+		"groundingChunks":[{"web":{"uri":"https://go.dev","title":"Go"}}],
+		"groundingSupports":[{"segment":{"startIndex":8,"endIndex":15,"text":"Go 1.25"},"groundingChunkIndices":[0]}]
+	}}]}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	raw, _ := json.Marshal(resp)
+	blockTypes := gjson.GetBytes(raw, "content.#.type").String()
+	for _, want := range []string{"server_tool_use", "web_search_tool_result", "code_execution_tool_result"} {
+		if !strings.Contains(blockTypes, want) {
+			t.Fatalf("missing %s block: %s", want, raw)
+		}
+	}
+	if gjson.GetBytes(raw, `content.#(citations).text`).String() != "Go 1.25" {
+		t.Fatalf("unexpected cited text: %s", raw)
+	}
+	if gjson.GetBytes(raw, `content.#(type=="code_execution_tool_result").content.stdout`).String() != "1\n" {
+		t.Fatalf("unexpected code execution result: %s", raw)
+	}
+}
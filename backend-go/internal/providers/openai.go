@@ -12,9 +12,11 @@
 	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/types"
 	"github.com/BenedictKing/claude-proxy/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 // OpenAIProvider OpenAI 提供商
@@ -49,11 +51,33 @@ func (p *OpenAIProvider) ConvertToProviderRequest(c *gin.Context, upstream *conf
 		openaiReq.MaxCompletionTokens = 65535
 	}
 
-	// 转换工具
-	if len(claudeReq.Tools) > 0 {
-		openaiReq.Tools = p.convertTools(claudeReq.Tools)
+	// 转换工具（服务端工具映射为 web_search_options，无等价物时拒绝请求）
+	functionTools := make([]types.ClaudeTool, 0, len(claudeReq.Tools))
+	for _, tool := range claudeReq.Tools {
+		st, err := converters.ServerToolFromClaude(tool, converters.ToolProtocolChat)
+		if err != nil {
+			return nil, originalBodyBytes, err
+		}
+		if st != nil {
+			openaiReq.WebSearchOptions = st.ChatWebSearchOptions()
+			continue
+		}
+		functionTools = append(functionTools, tool)
+	}
+	if len(functionTools) > 0 {
+		openaiReq.Tools = p.convertTools(functionTools)
 		openaiReq.ToolChoice = "auto"
 	}
+
+	// thinking.budget_tokens → reasoning_effort
+	if claudeReq.Thinking != nil && claudeReq.Thinking.Type == "enabled" {
+		openaiReq.ReasoningEffort = converters.ReasoningBudgetToEffort(claudeReq.Thinking.BudgetTokens)
+	}
+
+	// output_format → response_format
+	if so := converters.StructuredOutputFromClaude(claudeReq.OutputFormat); so != nil {
+		openaiReq.ResponseFormat = so.ChatResponseFormat()
+	}
 	// --- 转换逻辑结束 ---
 
 	reqBodyBytes, err := json.Marshal(openaiReq)
@@ -142,6 +166,8 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 	}
 
 	textContents := []string{}
+	contentParts := []map[string]interface{}{}
+	hasMedia := false
 	toolCalls := []types.OpenAIToolCall{}
 	toolResults := []types.OpenAIMessage{}
 
@@ -157,6 +183,14 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 		case "text":
 			if text, ok := content["text"].(string); ok {
 				textContents = append(textContents, text)
+				contentParts = append(contentParts, map[string]interface{}{"type": "text", "text": text})
+			}
+
+		case "image", "document":
+			// 图片/PDF → image_url/file（OpenAI 仅允许 user 消息携带）
+			if part, ok := converters.ClaudeMediaBlockToOpenAIPart(content); ok && normalizeRole(msg.Role) == "user" {
+				contentParts = append(contentParts, part)
+				hasMedia = true
 			}
 
 		case "tool_use":
@@ -198,14 +232,16 @@ func (p *OpenAIProvider) convertMessage(msg types.ClaudeMessage) []types.OpenAIM
 	messages = append(messages, toolResults...)
 
 	// 添加文本和工具调用
-	if len(textContents) > 0 || len(toolCalls) > 0 {
+	if len(textContents) > 0 || len(toolCalls) > 0 || hasMedia {
 		role := normalizeRole(msg.Role)
 		if role != "tool" {
 			openaiMsg := types.OpenAIMessage{
 				Role: role,
 			}
 
-			if len(textContents) > 0 {
+			if hasMedia {
+				openaiMsg.Content = contentParts
+			} else if len(textContents) > 0 {
 				openaiMsg.Content = strings.Join(textContents, "\n")
 			} else {
 				openaiMsg.Content = nil
@@ -311,14 +347,24 @@ func (p *OpenAIProvider) ConvertToClaudeResponse(providerResp *types.ProviderRes
 		choice := openaiResp.Choices[0]
 		msg := choice.Message
 
-		// 添加文本内容
-		if str, ok := msg.Content.(string); ok && str != "" {
+		// 推理内容 → thinking 块
+		if msg.ReasoningContent != "" {
 			claudeResp.Content = append(claudeResp.Content, types.ClaudeContent{
-				Type: "text",
-				Text: str,
+				Type:     "thinking",
+				Thinking: msg.ReasoningContent,
 			})
 		}
 
+		// 添加文本内容（url_citation 标注 → 文本块 citations）
+		if str, ok := msg.Content.(string); ok && str != "" {
+			textBlock := []types.ClaudeContent{{Type: "text", Text: str}}
+			if len(msg.Annotations) > 0 {
+				annotations, _ := json.Marshal(msg.Annotations)
+				textBlock = converters.ApplyWebCitations(textBlock, converters.WebCitationsFromAnnotations(gjson.ParseBytes(annotations), str, 0))
+			}
+			claudeResp.Content = append(claudeResp.Content, textBlock...)
+		}
+
 		// 添加工具调用
 		for _, toolCall := range msg.ToolCalls {
 			var input interface{}
@@ -376,6 +422,9 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 		textBlockStarted := false
 		textBlockIndex := 0
 
+		// 推理块状态跟踪（reasoning_content → thinking 块）
+		thinkingBlockStarted := false
+
 		for scanner.Scan() {
 			line := scanner.Text()
 			line = strings.TrimSpace(line)
@@ -416,6 +465,51 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 				continue
 			}
 
+			// 处理推理内容（总是出现在正文之前）
+			if reasoning, ok := delta["reasoning_content"].(string); ok && reasoning != "" && !textBlockStarted {
+				if !thinkingBlockStarted {
+					startEvent := map[string]interface{}{
+						"type":  "content_block_start",
+						"index": textBlockIndex,
+						"content_block": map[string]string{
+							"type":     "thinking",
+							"thinking": "",
+						},
+					}
+					startJSON, _ := json.Marshal(startEvent)
+					eventChan <- fmt.Sprintf("event: content_block_start\ndata: %s\n\n", startJSON)
+					thinkingBlockStarted = true
+				}
+
+				deltaEvent := map[string]interface{}{
+					"type":  "content_block_delta",
+					"index": textBlockIndex,
+					"delta": map[string]string{
+						"type":     "thinking_delta",
+						"thinking": reasoning,
+					},
+				}
+				deltaJSON, _ := json.Marshal(deltaEvent)
+				eventChan <- fmt.Sprintf("event: content_block_delta\ndata: %s\n\n", deltaJSON)
+			}
+
+			// 正文或工具调用开始时关闭推理块
+			_, hasToolCalls := delta["tool_calls"]
+			_, hasFinish := choice["finish_reason"].(string)
+			if content, _ := delta["content"].(string); thinkingBlockStarted && (content != "" || hasToolCalls || hasFinish) {
+				stopEvent := map[string]interface{}{
+					"type":  "content_block_stop",
+					"index": textBlockIndex,
+				}
+				stopJSON, _ := json.Marshal(stopEvent)
+				eventChan <- fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stopJSON)
+				thinkingBlockStarted = false
+				textBlockIndex++
+				if toolUseBlockIndex < textBlockIndex {
+					toolUseBlockIndex = textBlockIndex
+				}
+			}
+
 			// 处理文本内容
 			if content, ok := delta["content"].(string); ok && content != "" {
 				// 如果是第一个文本块,发送 content_block_start
@@ -533,7 +627,15 @@ func (p *OpenAIProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string
 			}
 		}
 
-		// 确保流结束时关闭任何未关闭的文本块
+		// 确保流结束时关闭任何未关闭的思考块/文本块
+		if thinkingBlockStarted {
+			stopEvent := map[string]interface{}{
+				"type":  "content_block_stop",
+				"index": textBlockIndex,
+			}
+			stopJSON, _ := json.Marshal(stopEvent)
+			eventChan <- fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stopJSON)
+		}
 		if textBlockStarted {
 			stopEvent := map[string]interface{}{
 				"type":  "content_block_stop",
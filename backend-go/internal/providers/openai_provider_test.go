@@ -1,12 +1,17 @@
 package providers
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
 )
 
 func TestExtractSystemText_SupportsStringObjectAndArray(t *testing.T) {
@@ -214,3 +219,176 @@ func TestOpenAIProvider_HandleStreamResponse_EmitsToolUseStopOnlyOnFinishReason(
 		t.Fatalf("expected stop_reason after tool_use content_block_stop; toolStopIdx=%d stopIdx=%d; events=%v", toolStopIdx, stopIdx, events)
 	}
 }
+
+func TestOpenAIProvider_ConvertMessage_ImageAndDocument(t *testing.T) {
+	t.Parallel()
+
+	p := &OpenAIProvider{}
+	msgs := p.convertMessage(types.ClaudeMessage{
+		Role: "user",
+		Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "compare"},
+			map[string]interface{}{
+				"type":   "image",
+				"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "AAAA"},
+			},
+			map[string]interface{}{
+				"type":   "document",
+				"title":  "spec.pdf",
+				"source": map[string]interface{}{"type": "base64", "media_type": "application/pdf", "data": "JVBE"},
+			},
+		},
+	})
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+
+	raw, _ := json.Marshal(msgs[0])
+	content := gjson.GetBytes(raw, "content")
+	if content.Get("0.text").String() != "compare" ||
+		content.Get("1.image_url.url").String() != "data:image/png;base64,AAAA" ||
+		content.Get("2.file.file_data").String() != "data:application/pdf;base64,JVBE" ||
+		content.Get("2.file.filename").String() != "spec.pdf" {
+		t.Fatalf("unexpected content parts: %s", content.Raw)
+	}
+}
+
+func TestOpenAIProvider_ThinkingToReasoningEffort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-3","max_tokens":32000,
+		"thinking":{"type":"enabled","budget_tokens":10000},
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+
+	req, _, err := (&OpenAIProvider{}).ConvertToProviderRequest(c, &config.UpstreamConfig{BaseURL: "http://openai.local"}, "k1")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if got := gjson.GetBytes(body, "reasoning_effort").String(); got != "medium" {
+		t.Fatalf("reasoning_effort = %q, want medium; body=%s", got, body)
+	}
+}
+
+func TestOpenAIProvider_ReasoningContentToThinking(t *testing.T) {
+	t.Parallel()
+
+	resp, err := (&OpenAIProvider{}).ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{
+		"choices":[{"message":{"role":"assistant","content":"42","reasoning_content":"think"},"finish_reason":"stop"}]
+	}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != "think" || resp.Content[1].Text != "42" {
+		t.Fatalf("unexpected content: %+v", resp.Content)
+	}
+
+	sse := strings.Join([]string{
+		`data: {"choices":[{"delta":{"reasoning_content":"hmm"}}]}`,
+		`data: {"choices":[{"delta":{"reasoning_content":" ok"}}]}`,
+		`data: {"choices":[{"delta":{"content":"42"}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n")
+	eventCh, _, err := (&OpenAIProvider{}).HandleStreamResponse(io.NopCloser(strings.NewReader(sse)))
+	if err != nil {
+		t.Fatalf("HandleStreamResponse: %v", err)
+	}
+	var events []string
+	for e := range eventCh {
+		events = append(events, e)
+	}
+	joined := strings.Join(events, "")
+	for _, want := range []string{
+		`"content_block":{"thinking":"","type":"thinking"},"index":0`,
+		`"delta":{"thinking":"hmm","type":"thinking_delta"},"index":0`,
+		`"content_block":{"text":"","type":"text"},"index":1`,
+		`"delta":{"text":"42","type":"text_delta"},"index":1`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing %s in events:\n%s", want, joined)
+		}
+	}
+	if strings.Count(joined, "event: content_block_stop") != 2 {
+		t.Fatalf("expected thinking and text blocks to be closed once each:\n%s", joined)
+	}
+}
+
+func TestOpenAIProvider_OutputFormatToResponseFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-3","max_tokens":1024,
+		"output_format":{"type":"json_schema","schema":{"type":"object","properties":{"name":{"type":"string"}}}},
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+
+	req, _, err := (&OpenAIProvider{}).ConvertToProviderRequest(c, &config.UpstreamConfig{BaseURL: "http://openai.local"}, "k1")
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if gjson.GetBytes(body, "response_format.type").String() != "json_schema" ||
+		gjson.GetBytes(body, "response_format.json_schema.schema.properties.name.type").String() != "string" {
+		t.Fatalf("unexpected response_format: %s", body)
+	}
+}
+
+func TestOpenAIProvider_ServerTools(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(body string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(body))
+		return c
+	}
+
+	t.Run("web_search to web_search_options", func(t *testing.T) {
+		c := newContext(`{
+			"model":"claude-3","max_tokens":1024,
+			"tools":[{"type":"web_search_20250305","name":"web_search","user_location":{"type":"approximate","city":"Paris"}}],
+			"messages":[{"role":"user","content":"hi"}]
+		}`)
+		req, _, err := (&OpenAIProvider{}).ConvertToProviderRequest(c, &config.UpstreamConfig{BaseURL: "http://openai.local"}, "k1")
+		if err != nil {
+			t.Fatalf("ConvertToProviderRequest: %v", err)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if gjson.GetBytes(body, "web_search_options.user_location.approximate.city").String() != "Paris" || gjson.GetBytes(body, "tools").Exists() {
+			t.Fatalf("unexpected request: %s", body)
+		}
+	})
+
+	t.Run("code_execution rejected", func(t *testing.T) {
+		c := newContext(`{
+			"model":"claude-3","max_tokens":1024,
+			"tools":[{"type":"code_execution_20250522","name":"code_execution"}],
+			"messages":[{"role":"user","content":"hi"}]
+		}`)
+		_, _, err := (&OpenAIProvider{}).ConvertToProviderRequest(c, &config.UpstreamConfig{BaseURL: "http://openai.local"}, "k1")
+		if err == nil || !strings.Contains(err.Error(), "code_execution_20250522") {
+			t.Fatalf("expected unsupported server tool error, got %v", err)
+		}
+	})
+}
+
+func TestOpenAIProvider_AnnotationsToCitations(t *testing.T) {
+	resp, err := (&OpenAIProvider{}).ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{
+		"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"See Go 1.25",
+		"annotations":[{"type":"url_citation","url_citation":{"url":"https://go.dev","title":"Go","start_index":4,"end_index":11}}]}}]
+	}`)})
+	if err != nil {
+		t.Fatalf("ConvertToClaudeResponse: %v", err)
+	}
+	raw, _ := json.Marshal(resp)
+	if gjson.GetBytes(raw, "content.#").Int() != 2 || gjson.GetBytes(raw, "content.1.text").String() != "Go 1.25" ||
+		gjson.GetBytes(raw, "content.1.citations.0.url").String() != "https://go.dev" {
+		t.Fatalf("unexpected content: %s", raw)
+	}
+}
@@ -30,6 +30,16 @@ func GetProvider(serviceType string) Provider {
 		return &GeminiProvider{}
 	case "claude":
 		return &ClaudeProvider{}
+	case "responses":
+		return &ResponsesUpstreamProvider{}
+	case "completions":
+		return &CompletionsProvider{}
+	case "bedrock":
+		return &BedrockProvider{}
+	case "azure-openai":
+		return &AzureOpenAIProvider{}
+	case "vertex":
+		return &VertexProvider{}
 	default:
 		return nil
 	}
@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// ResponsesUpstreamProvider Messages 渠道池的 Responses 上游提供商
+// 将 Claude Messages 请求转换为 OpenAI Responses 请求，并将响应转换回 Claude 格式
+type ResponsesUpstreamProvider struct{}
+
+// ConvertToProviderRequest 转换为 Responses 请求
+func (p *ResponsesUpstreamProvider) ConvertToProviderRequest(c *gin.Context, upstream *config.UpstreamConfig, apiKey string) (*http.Request, []byte, error) {
+	originalBodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(originalBodyBytes))
+
+	if !gjson.ValidBytes(originalBodyBytes) {
+		return nil, originalBodyBytes, fmt.Errorf("解析Claude请求体失败: 无效的 JSON")
+	}
+
+	model := config.RedirectModel(gjson.GetBytes(originalBodyBytes, "model").String(), upstream)
+	reqBodyBytes, err := converters.ConvertClaudeToResponsesRequest(originalBodyBytes, model)
+	if err != nil {
+		return nil, originalBodyBytes, err
+	}
+
+	// 构建URL（版本号规则同 OpenAIProvider）
+	baseURL := upstream.GetEffectiveBaseURL()
+	skipVersionPrefix := strings.HasSuffix(baseURL, "#")
+	if skipVersionPrefix {
+		baseURL = strings.TrimSuffix(baseURL, "#")
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	versionPattern := regexp.MustCompile(`/v\d+[a-z]*$`)
+	endpoint := "/responses"
+	if !versionPattern.MatchString(baseURL) && !skipVersionPrefix {
+		endpoint = "/v1" + endpoint
+	}
+	url := baseURL + endpoint
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", url, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("创建Responses请求失败: %w", err)
+	}
+
+	req.Header = utils.PrepareUpstreamHeaders(c, req.URL.Host)
+	utils.SetAuthenticationHeader(req.Header, apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	utils.ForceSU8CodexResponsesUserAgent(req.Header, url)
+
+	return req, originalBodyBytes, nil
+}
+
+// ConvertToClaudeResponse 转换为 Claude 响应
+func (p *ResponsesUpstreamProvider) ConvertToClaudeResponse(providerResp *types.ProviderResponse) (*types.ClaudeResponse, error) {
+	return converters.ResponsesToClaudeResponse(providerResp.Body)
+}
+
+// HandleStreamResponse 处理流式响应（Responses SSE → Claude SSE）
+func (p *ResponsesUpstreamProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string, <-chan error, error) {
+	eventChan := make(chan string, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(eventChan)
+		defer body.Close()
+
+		scanner := bufio.NewScanner(body)
+		// 设置更大的 buffer (1MB) 以处理大 JSON chunk，避免默认 64KB 限制
+		const maxScannerBufferSize = 1024 * 1024 // 1MB
+		scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBufferSize)
+
+		var state any
+		for scanner.Scan() {
+			for _, event := range converters.ConvertResponsesToClaude("", scanner.Bytes(), &state) {
+				eventChan <- event
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return eventChan, errChan, nil
+}
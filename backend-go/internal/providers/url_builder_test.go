@@ -134,3 +134,29 @@ func TestBuildTargetURL_SkipVersionWithHash(t *testing.T) {
 		})
 	}
 }
+
+func TestResponsesProvider_BuildGeminiTargetURL(t *testing.T) {
+	p := &ResponsesProvider{}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		stream  bool
+		want    string
+	}{
+		{"normal", "https://generativelanguage.googleapis.com", false, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:generateContent"},
+		{"stream", "https://generativelanguage.googleapis.com", true, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse"},
+		{"with_version", "https://api.example.com/v1beta/", false, "https://api.example.com/v1beta/models/gemini-2.5-pro:generateContent"},
+		{"hash_skip", "https://api.example.com/gemini#", false, "https://api.example.com/gemini/models/gemini-2.5-pro:generateContent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &config.UpstreamConfig{BaseURL: tt.baseURL, ServiceType: "gemini"}
+			got := p.buildGeminiTargetURL(upstream, "gemini-2.5-pro", tt.stream)
+			if got != tt.want {
+				t.Errorf("buildGeminiTargetURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
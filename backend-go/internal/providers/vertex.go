@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/BenedictKing/claude-proxy/internal/utils"
+	"github.com/BenedictKing/claude-proxy/internal/vertex"
+	"github.com/gin-gonic/gin"
+)
+
+// VertexProvider Google Vertex AI 提供商
+//
+// API 密钥为服务账号 JSON，请求时换取 OAuth2 access token（Authorization: Bearer）。
+// 按映射后的模型名选择发布方：
+//   - claude-*: publishers/anthropic，Anthropic Messages 格式（rawPredict / streamRawPredict）
+//   - 其他:     publishers/google，Gemini 格式（generateContent / streamGenerateContent）
+type VertexProvider struct {
+	// publisher 由 ConvertToProviderRequest 根据模型确定，决定响应的解析方式
+	publisher string
+	gemini    GeminiProvider
+	claude    ClaudeProvider
+}
+
+// buildVertexClaudeBody 将 Claude Messages 请求体转换为 Vertex rawPredict 请求体
+// 模型由 URL 决定，请求体中不能包含 model，需要携带 anthropic_version
+func buildVertexClaudeBody(bodyBytes []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	delete(data, "model")
+	data["anthropic_version"] = vertex.AnthropicVersion
+
+	return utils.MarshalJSONNoEscape(data)
+}
+
+// ConvertToProviderRequest 转换为 Vertex AI 请求
+func (p *VertexProvider) ConvertToProviderRequest(c *gin.Context, upstream *config.UpstreamConfig, apiKey string) (*http.Request, []byte, error) {
+	originalBodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取请求体失败: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(originalBodyBytes))
+
+	sa, err := vertex.ParseServiceAccount(apiKey)
+	if err != nil {
+		return nil, originalBodyBytes, err
+	}
+
+	var claudeReq types.ClaudeRequest
+	if err := json.Unmarshal(originalBodyBytes, &claudeReq); err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("解析Claude请求体失败: %w", err)
+	}
+
+	model := config.RedirectModel(claudeReq.Model, upstream)
+	p.publisher = vertex.PublisherForModel(model)
+
+	var reqBodyBytes []byte
+	if p.publisher == vertex.PublisherAnthropic {
+		reqBodyBytes, err = buildVertexClaudeBody(originalBodyBytes)
+	} else {
+		var geminiReq map[string]interface{}
+		if geminiReq, err = p.gemini.convertToGeminiRequest(&claudeReq, upstream); err == nil {
+			reqBodyBytes, err = json.Marshal(geminiReq)
+		}
+	}
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("构建Vertex请求体失败: %w", err)
+	}
+
+	token, err := vertex.AccessToken(c.Request.Context(), apiKey)
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("获取Vertex access token失败: %w", err)
+	}
+
+	url := vertex.BuildModelURL(upstream.GetEffectiveBaseURL(), sa.ProjectID, p.publisher, model, claudeReq.Stream)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "POST", url, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, originalBodyBytes, fmt.Errorf("创建Vertex请求失败: %w", err)
+	}
+
+	req.Header = utils.PrepareMinimalHeaders(req.URL.Host)
+	if beta := c.GetHeader("anthropic-beta"); beta != "" && p.publisher == vertex.PublisherAnthropic {
+		req.Header.Set("anthropic-beta", beta)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return req, originalBodyBytes, nil
+}
+
+// ConvertToClaudeResponse 转换为 Claude 响应
+func (p *VertexProvider) ConvertToClaudeResponse(providerResp *types.ProviderResponse) (*types.ClaudeResponse, error) {
+	if p.publisher == vertex.PublisherAnthropic {
+		return p.claude.ConvertToClaudeResponse(providerResp)
+	}
+	return p.gemini.ConvertToClaudeResponse(providerResp)
+}
+
+// HandleStreamResponse 处理流式响应
+func (p *VertexProvider) HandleStreamResponse(body io.ReadCloser) (<-chan string, <-chan error, error) {
+	if p.publisher == vertex.PublisherAnthropic {
+		return p.claude.HandleStreamResponse(body)
+	}
+	return p.gemini.HandleStreamResponse(body)
+}
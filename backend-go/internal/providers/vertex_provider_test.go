@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// newVertexTestCredentials 启动 token 端点替身并返回指向它的服务账号 JSON
+func newVertexTestCredentials(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	raw, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "demo-project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email": "proxy@demo-project.iam.gserviceaccount.com",
+		"token_uri":    srv.URL + "/token",
+	})
+	return string(raw)
+}
+
+func TestVertexProvider_ClaudeModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	creds := newVertexTestCredentials(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"claude-sonnet-4",
+		"max_tokens":64,
+		"stream":true,
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+	c.Request.Header.Set("anthropic-beta", "context-1m-2025-08-07")
+
+	upstream := &config.UpstreamConfig{
+		BaseURL:      "https://us-east5-aiplatform.googleapis.com",
+		ServiceType:  "vertex",
+		ModelMapping: map[string]string{"claude-sonnet-4": "claude-sonnet-4@20250514"},
+	}
+	provider := GetProvider("vertex")
+	req, _, err := provider.ConvertToProviderRequest(c, upstream, creds)
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+
+	want := "https://us-east5-aiplatform.googleapis.com/v1/projects/demo-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict"
+	if req.URL.String() != want {
+		t.Fatalf("url=%q, want %q", req.URL.String(), want)
+	}
+	if req.Header.Get("Authorization") != "Bearer ya29.test" || req.Header.Get("anthropic-beta") != "context-1m-2025-08-07" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	root := gjson.ParseBytes(body)
+	if root.Get("model").Exists() || root.Get("anthropic_version").String() != "vertex-2023-10-16" || !root.Get("stream").Bool() {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	// Claude 发布方的流式响应按 Anthropic SSE 透传
+	events, _, _ := provider.HandleStreamResponse(io.NopCloser(strings.NewReader("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")))
+	if ev := <-events; !strings.HasPrefix(ev, "event: message_stop") {
+		t.Fatalf("unexpected event: %q", ev)
+	}
+}
+
+func TestVertexProvider_GeminiModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	creds := newVertexTestCredentials(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{
+		"model":"gemini-2.5-pro",
+		"max_tokens":64,
+		"messages":[{"role":"user","content":"hi"}]
+	}`))
+
+	upstream := &config.UpstreamConfig{BaseURL: "https://aiplatform.googleapis.com", ServiceType: "vertex"}
+	provider := GetProvider("vertex")
+	req, _, err := provider.ConvertToProviderRequest(c, upstream, creds)
+	if err != nil {
+		t.Fatalf("ConvertToProviderRequest: %v", err)
+	}
+
+	want := "https://aiplatform.googleapis.com/v1/projects/demo-project/locations/global/publishers/google/models/gemini-2.5-pro:generateContent"
+	if req.URL.String() != want {
+		t.Fatalf("url=%q, want %q", req.URL.String(), want)
+	}
+	if req.Header.Get("Authorization") != "Bearer ya29.test" || req.Header.Get("x-goog-api-key") != "" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if gjson.GetBytes(body, "contents.0.parts.0.text").String() != "hi" {
+		t.Fatalf("expected Gemini body: %s", body)
+	}
+
+	resp, err := provider.ConvertToClaudeResponse(&types.ProviderResponse{Body: []byte(`{
+		"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP"}]
+	}`)})
+	if err != nil || len(resp.Content) != 1 || resp.Content[0].Text != "hello" {
+		t.Fatalf("unexpected Claude response: %+v err=%v", resp, err)
+	}
+}
+
+func TestVertexProvider_InvalidCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{"model":"gemini-2.5-pro","messages":[]}`))
+
+	upstream := &config.UpstreamConfig{BaseURL: "https://aiplatform.googleapis.com", ServiceType: "vertex"}
+	if _, _, err := GetProvider("vertex").ConvertToProviderRequest(c, upstream, "AIza-static-key"); err == nil {
+		t.Fatalf("expected service account parse error")
+	}
+}
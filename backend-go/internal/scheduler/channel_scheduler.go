@@ -5,9 +5,9 @@
 	"fmt"
 	"hash/fnv"
 	"log"
-	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/BenedictKing/claude-proxy/internal/config"
 	"github.com/BenedictKing/claude-proxy/internal/metrics"
@@ -18,13 +18,18 @@
 
 // ChannelScheduler 多渠道调度器
 type ChannelScheduler struct {
-	mu                      sync.RWMutex
-	configManager           *config.ConfigManager
-	messagesMetricsManager  *metrics.MetricsManager // Messages 渠道指标
-	responsesMetricsManager *metrics.MetricsManager // Responses 渠道指标
-	geminiMetricsManager    *metrics.MetricsManager // Gemini 渠道指标
-	traceAffinity           *session.TraceAffinityManager
-	urlManager              *warmup.URLManager // URL 管理器（非阻塞，动态排序）
+	mu                       sync.RWMutex
+	configManager            *config.ConfigManager
+	messagesMetricsManager   *metrics.MetricsManager // Messages 渠道指标
+	responsesMetricsManager  *metrics.MetricsManager // Responses 渠道指标
+	geminiMetricsManager     *metrics.MetricsManager // Gemini 渠道指标
+	embeddingsMetricsManager *metrics.MetricsManager // Embeddings 渠道指标
+	traceAffinity            *session.TraceAffinityManager
+	geminiResources          *session.ResourceAffinityManager // Gemini 文件/缓存归属槽位
+	urlManager               *warmup.URLManager               // URL 管理器（非阻塞，动态排序）
+	weightedRR               weightedRoundRobin               // weighted 模式的平滑加权轮询状态
+	costEstimator            CostEstimator                    // cheapest 模式的模型成本估算
+	concurrency              *concurrencyLimiter              // 渠道/Key 并发上限与排队
 }
 
 // NewChannelScheduler 创建多渠道调度器
@@ -33,16 +38,20 @@ func NewChannelScheduler(
 	messagesMetrics *metrics.MetricsManager,
 	responsesMetrics *metrics.MetricsManager,
 	geminiMetrics *metrics.MetricsManager,
+	embeddingsMetrics *metrics.MetricsManager,
 	traceAffinity *session.TraceAffinityManager,
 	urlMgr *warmup.URLManager,
 ) *ChannelScheduler {
 	return &ChannelScheduler{
-		configManager:           cfgManager,
-		messagesMetricsManager:  messagesMetrics,
-		responsesMetricsManager: responsesMetrics,
-		geminiMetricsManager:    geminiMetrics,
-		traceAffinity:           traceAffinity,
-		urlManager:              urlMgr,
+		configManager:            cfgManager,
+		messagesMetricsManager:   messagesMetrics,
+		responsesMetricsManager:  responsesMetrics,
+		geminiMetricsManager:     geminiMetrics,
+		embeddingsMetricsManager: embeddingsMetrics,
+		traceAffinity:            traceAffinity,
+		geminiResources:          session.NewResourceAffinityManager(48 * time.Hour),
+		urlManager:               urlMgr,
+		concurrency:              newConcurrencyLimiter(),
 	}
 }
 
@@ -68,6 +77,9 @@ type SlotSelectionResult struct {
 	KeyIndex     int
 	APIKey       string
 	Reason       string
+	QueueWait    time.Duration // 因槽位并发已满排队等待的时长
+
+	release func() // 归还并发额度（见 Release）
 }
 
 type slotCandidate struct {
@@ -90,137 +102,29 @@ func hash64(s string) uint64 {
 
 // SelectSlot 选择最佳槽位（渠道+Key）
 // 优先级: 促销期渠道 > Trace亲和（促销渠道失败时回退） > Rendezvous Hash（稳定映射）
+// weighted 模式下最后一步改为按渠道权重选择：有 userID 时加权 Rendezvous，否则平滑加权轮询
+// latency 模式下最后一步改为在最高优先级档内选择 TTFB 最快的槽位
+// cheapest 模式下最后一步改为选择有效成本（模型价格 × 价格倍率）最低的槽位，请求模型通过 WithRequestModel 传入
+// 请求模型经 ModelMapping 重定向后不在渠道 supportedModels 内的渠道不参与选择
+// 达到渠道或 Key 并发上限（maxConcurrency）的槽位暂不参与选择；全部已满时在渠道池 FIFO 队列中等待，调用方在槽位尝试结束后需调用 Release
 func (s *ChannelScheduler) SelectSlot(
 	ctx context.Context,
 	userID string,
 	failedSlots map[string]bool,
 	isResponses bool,
 ) (*SlotSelectionResult, error) {
-	_ = ctx
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	activeChannels := s.getActiveChannels(isResponses)
-	if len(activeChannels) == 0 {
-		return nil, fmt.Errorf("没有可用的活跃渠道")
-	}
-
-	metricsManager := s.getMetricsManager(isResponses)
-
-	buildSlots := func(channels []ChannelInfo) (healthy []slotCandidate, all []slotCandidate) {
-		for _, ch := range channels {
-			if ch.Status != "active" {
-				continue
-			}
-			upstream := s.getUpstreamByIndex(ch.Index, isResponses)
-			if upstream == nil || len(upstream.APIKeys) == 0 {
-				continue
-			}
-			for keyIndex, apiKey := range upstream.APIKeys {
-				if apiKey == "" {
-					continue
-				}
-				if upstream.IsAPIKeyDisabled(apiKey) {
-					continue
-				}
-				if failedSlots != nil && failedSlots[slotID(ch.Index, apiKey)] {
-					continue
-				}
-				if s.configManager != nil && s.configManager.IsKeyFailed(apiKey) {
-					continue
-				}
-				all = append(all, slotCandidate{
-					channelIndex: ch.Index,
-					keyIndex:     keyIndex,
-					apiKey:       apiKey,
-					upstream:     upstream,
-					channel:      ch,
-				})
-				if metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey) {
-					healthy = append(healthy, slotCandidate{
-						channelIndex: ch.Index,
-						keyIndex:     keyIndex,
-						apiKey:       apiKey,
-						upstream:     upstream,
-						channel:      ch,
-					})
-				}
-			}
-		}
-		return healthy, all
-	}
-
-	// 0. 促销期：限定在促销渠道的 slots 内做选择（优先健康 slots）
-	promotedChannel := s.findPromotedChannel(activeChannels, isResponses)
-	if promotedChannel != nil {
-		if upstream := s.getUpstreamByIndex(promotedChannel.Index, isResponses); upstream != nil && len(upstream.APIKeys) > 0 {
-			promotedOnly := []ChannelInfo{*promotedChannel}
-			healthy, all := buildSlots(promotedOnly)
-			if len(healthy) == 0 {
-				healthy = all
-			}
-			if len(healthy) > 0 {
-				chosen := chooseSlotByRendezvous(userID, healthy)
-				log.Printf("[Scheduler-Promotion] 促销期优先选择槽位: [%d] %s (user: %s)", chosen.channelIndex, upstream.Name, maskUserID(userID))
-				return &SlotSelectionResult{
-					Upstream:     chosen.upstream,
-					ChannelIndex: chosen.channelIndex,
-					KeyIndex:     chosen.keyIndex,
-					APIKey:       chosen.apiKey,
-					Reason:       "promotion_priority",
-				}, nil
-			}
-		}
-	}
-
-	// 1. Trace 亲和：命中到具体槽位
-	if userID != "" {
-		if preferredCh, preferredKeyIdx, ok := s.traceAffinity.GetPreferredSlot(userID); ok && preferredCh >= 0 && preferredKeyIdx >= 0 {
-			upstream := s.getUpstreamByIndex(preferredCh, isResponses)
-			if upstream != nil && preferredKeyIdx < len(upstream.APIKeys) {
-				apiKey := upstream.APIKeys[preferredKeyIdx]
-				if apiKey != "" && (failedSlots == nil || !failedSlots[slotID(preferredCh, apiKey)]) &&
-					!upstream.IsAPIKeyDisabled(apiKey) &&
-					(s.configManager == nil || !s.configManager.IsKeyFailed(apiKey)) &&
-					(metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey)) {
-					// 仅 active 渠道可用
-					for _, ch := range activeChannels {
-						if ch.Index == preferredCh && ch.Status == "active" {
-							log.Printf("[Scheduler-Affinity] Trace亲和选择槽位: [%d] %s (user: %s)", preferredCh, upstream.Name, maskUserID(userID))
-							return &SlotSelectionResult{
-								Upstream:     upstream,
-								ChannelIndex: preferredCh,
-								KeyIndex:     preferredKeyIdx,
-								APIKey:       apiKey,
-								Reason:       "trace_affinity",
-							}, nil
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// 2. Rendezvous Hash：在所有健康槽位里稳定选择
-	healthy, all := buildSlots(activeChannels)
-	candidates := healthy
-	reason := "rendezvous_hash"
-	if len(candidates) == 0 {
-		candidates = all
-		reason = "rendezvous_fallback"
-	}
-	if len(candidates) == 0 {
-		return nil, fmt.Errorf("所有槽位都不可用")
-	}
+	return s.selectPoolSlot(ctx, s.poolSpec(slotPool(isResponses)), userID, failedSlots)
+}
 
-	chosen := chooseSlotByRendezvous(userID, candidates)
-	return &SlotSelectionResult{
-		Upstream:     chosen.upstream,
-		ChannelIndex: chosen.channelIndex,
-		KeyIndex:     chosen.keyIndex,
-		APIKey:       chosen.apiKey,
-		Reason:       reason,
-	}, nil
+// SelectSlotUncounted 同 SelectSlot 选择槽位，但忽略并发上限、不排队也不占用额度
+// 用于 models 列表等不产生生成负载的轻量请求，避免在渠道池占满时被阻塞在排队队列中
+func (s *ChannelScheduler) SelectSlotUncounted(
+	ctx context.Context,
+	userID string,
+	failedSlots map[string]bool,
+	isResponses bool,
+) (*SlotSelectionResult, error) {
+	return s.selectPoolSlotUncounted(ctx, s.poolSpec(slotPool(isResponses)), userID, failedSlots)
 }
 
 func chooseSlotByRendezvous(userID string, candidates []slotCandidate) slotCandidate {
@@ -255,7 +159,8 @@ func chooseSlotByRendezvous(userID string, candidates []slotCandidate) slotCandi
 }
 
 // SelectChannel 选择最佳渠道
-// 优先级: 促销期渠道 > Trace亲和（促销渠道失败时回退） > 渠道优先级顺序
+// 优先级: 促销期渠道 > Trace亲和（促销渠道失败时回退） > 渠道优先级顺序（weighted 模式为渠道权重，latency 模式为最高优先级档内 TTFB 最快，cheapest 模式为有效成本最低）
+// 无法服务请求模型（按 supportedModels 匹配重定向后的模型）的渠道不参与选择
 func (s *ChannelScheduler) SelectChannel(
 	ctx context.Context,
 	userID string,
@@ -270,6 +175,13 @@ func (s *ChannelScheduler) SelectChannel(
 	if len(activeChannels) == 0 {
 		return nil, fmt.Errorf("没有可用的活跃渠道")
 	}
+	model := requestModel(ctx)
+	activeChannels = s.filterChannelsByModel(activeChannels, model, func(index int) *config.UpstreamConfig {
+		return s.getUpstreamByIndex(index, isResponses)
+	})
+	if len(activeChannels) == 0 {
+		return nil, fmt.Errorf("没有可服务模型 %s 的渠道", model)
+	}
 
 	// 获取对应类型的指标管理器
 	metricsManager := s.getMetricsManager(isResponses)
@@ -330,7 +242,11 @@ func (s *ChannelScheduler) SelectChannel(
 		}
 	}
 
-	// 2. 按优先级遍历活跃渠道
+	// 2. 按优先级遍历活跃渠道（weighted/latency/cheapest 模式收集全部健康渠道后按策略选择）
+	pool := slotPool(isResponses)
+	strategy := s.loadBalanceStrategy(pool)
+	collect := strategy == "weighted" || strategy == "latency" || strategy == "cheapest"
+	var strategyCandidates []slotCandidate
 	for _, ch := range activeChannels {
 		// 跳过本次请求已经失败的渠道
 		if failedChannels[ch.Index] {
@@ -359,6 +275,11 @@ func (s *ChannelScheduler) SelectChannel(
 			continue
 		}
 
+		if collect {
+			strategyCandidates = append(strategyCandidates, slotCandidate{channelIndex: ch.Index, upstream: upstream, channel: ch})
+			continue
+		}
+
 		log.Printf("[Scheduler-Channel] 选择渠道: [%d] %s (优先级: %d)", ch.Index, upstream.Name, ch.Priority)
 		return &SelectionResult{
 			Upstream:     upstream,
@@ -366,6 +287,15 @@ func (s *ChannelScheduler) SelectChannel(
 			Reason:       "priority_order",
 		}, nil
 	}
+	if len(strategyCandidates) > 0 {
+		chosen, reason := s.chooseSlot(pool, userID, model, strategyCandidates, false)
+		log.Printf("[Scheduler-Channel] 按 %s 策略选择渠道: [%d] %s (原因: %s)", strategy, chosen.channelIndex, chosen.upstream.Name, reason)
+		return &SelectionResult{
+			Upstream:     chosen.upstream,
+			ChannelIndex: chosen.channelIndex,
+			Reason:       reason,
+		}, nil
+	}
 
 	// 3. 所有健康渠道都失败，选择失败率最低的作为降级
 	return s.selectFallbackChannel(activeChannels, failedChannels, isResponses)
@@ -373,20 +303,7 @@ func (s *ChannelScheduler) SelectChannel(
 
 // findPromotedChannel 查找处于促销期的渠道
 func (s *ChannelScheduler) findPromotedChannel(activeChannels []ChannelInfo, isResponses bool) *ChannelInfo {
-	for i := range activeChannels {
-		ch := &activeChannels[i]
-		if ch.Status != "active" {
-			continue
-		}
-		upstream := s.getUpstreamByIndex(ch.Index, isResponses)
-		if upstream != nil {
-			if config.IsChannelInPromotion(upstream) {
-				log.Printf("[Scheduler-Promotion] 找到促销渠道: [%d] %s (promotionUntil: %v)", ch.Index, upstream.Name, upstream.PromotionUntil)
-				return ch
-			}
-		}
-	}
-	return nil
+	return s.findPromotedPoolChannel(s.poolSpec(slotPool(isResponses)), activeChannels)
 }
 
 // selectFallbackChannel 选择降级渠道（失败率最低的）
@@ -445,73 +362,30 @@ func (s *ChannelScheduler) selectFallbackChannel(
 
 // ChannelInfo 渠道信息（用于排序）
 type ChannelInfo struct {
-	Index    int
-	Name     string
-	Priority int
-	Status   string
+	Index         int
+	Name          string
+	Priority      int
+	PriorityUnset bool // 未配置优先级（Priority 取渠道索引）
+	Status        string
 }
 
 // getActiveChannels 获取活跃渠道列表（按优先级排序）
 func (s *ChannelScheduler) getActiveChannels(isResponses bool) []ChannelInfo {
 	cfg := s.configManager.GetConfig()
-
-	var upstreams []config.UpstreamConfig
 	if isResponses {
-		upstreams = cfg.ResponsesUpstream
-	} else {
-		upstreams = cfg.Upstream
+		return activeChannelInfos(cfg.ResponsesUpstream)
 	}
-
-	// 筛选活跃渠道
-	var activeChannels []ChannelInfo
-	for i, upstream := range upstreams {
-		status := upstream.Status
-		if status == "" {
-			status = "active" // 默认为活跃
-		}
-
-		// 只选择 active 状态的渠道（suspended 也算在活跃序列中，但会被健康检查过滤）
-		if status != "disabled" {
-			priority := upstream.Priority
-			if priority == 0 {
-				priority = i // 默认优先级为索引
-			}
-
-			activeChannels = append(activeChannels, ChannelInfo{
-				Index:    i,
-				Name:     upstream.Name,
-				Priority: priority,
-				Status:   status,
-			})
-		}
-	}
-
-	// 按优先级排序（数字越小优先级越高）
-	sort.Slice(activeChannels, func(i, j int) bool {
-		return activeChannels[i].Priority < activeChannels[j].Priority
-	})
-
-	return activeChannels
+	return activeChannelInfos(cfg.Upstream)
 }
 
 // getUpstreamByIndex 根据索引获取上游配置
 // 注意：返回的是副本，避免指向 slice 元素的指针在 slice 重分配后失效
 func (s *ChannelScheduler) getUpstreamByIndex(index int, isResponses bool) *config.UpstreamConfig {
 	cfg := s.configManager.GetConfig()
-
-	var upstreams []config.UpstreamConfig
 	if isResponses {
-		upstreams = cfg.ResponsesUpstream
-	} else {
-		upstreams = cfg.Upstream
+		return upstreamAt(cfg.ResponsesUpstream, index)
 	}
-
-	if index >= 0 && index < len(upstreams) {
-		// 返回副本，避免返回指向 slice 元素的指针
-		upstream := upstreams[index]
-		return &upstream
-	}
-	return nil
+	return upstreamAt(cfg.Upstream, index)
 }
 
 // RecordSuccess 记录渠道成功（使用 baseURL + apiKey）
@@ -534,6 +408,11 @@ func (s *ChannelScheduler) RecordFailureWithStatus(baseURL, apiKey string, isRes
 	s.getMetricsManager(isResponses).RecordFailureWithStatus(baseURL, apiKey, statusCode)
 }
 
+// RecordLatency 记录渠道 TTFB（首字节时间）
+func (s *ChannelScheduler) RecordLatency(baseURL, apiKey string, isResponses bool, ttfb time.Duration) {
+	s.getMetricsManager(isResponses).RecordLatency(baseURL, apiKey, ttfb)
+}
+
 // SetTraceAffinity 设置 Trace 亲和
 func (s *ChannelScheduler) SetTraceAffinity(userID string, channelIndex int) {
 	if userID != "" {
@@ -603,31 +482,12 @@ func (s *ChannelScheduler) GetActiveChannelCount(isResponses bool) int {
 
 // GetActiveSlotCount 获取活跃槽位数量（active 渠道下的全部 key 数）
 func (s *ChannelScheduler) GetActiveSlotCount(isResponses bool) int {
-	activeChannels := s.getActiveChannels(isResponses)
-	count := 0
-	for _, ch := range activeChannels {
-		if ch.Status != "active" {
-			continue
-		}
-		upstream := s.getUpstreamByIndex(ch.Index, isResponses)
-		if upstream == nil {
-			continue
-		}
-		for _, k := range upstream.APIKeys {
-			if k != "" {
-				if upstream.IsAPIKeyDisabled(k) {
-					continue
-				}
-				count++
-			}
-		}
-	}
-	return count
+	return s.activeSlotCount(s.poolSpec(slotPool(isResponses)))
 }
 
 // IsMultiSlotMode 判断是否为多槽位模式（(渠道,key) 扁平化负载均衡）
 func (s *ChannelScheduler) IsMultiSlotMode(isResponses bool) bool {
-	return s.GetActiveSlotCount(isResponses) > 1
+	return s.isMultiSlotPool(s.poolSpec(slotPool(isResponses)))
 }
 
 // IsMultiChannelMode 判断是否为多渠道模式
@@ -710,6 +570,13 @@ func (s *ChannelScheduler) SelectGeminiChannel(
 	if len(activeChannels) == 0 {
 		return nil, fmt.Errorf("没有可用的活跃 Gemini 渠道")
 	}
+	model := requestModel(ctx)
+	activeChannels = s.filterChannelsByModel(activeChannels, model, func(index int) *config.UpstreamConfig {
+		return s.getGeminiUpstreamByIndex(index)
+	})
+	if len(activeChannels) == 0 {
+		return nil, fmt.Errorf("没有可服务模型 %s 的Gemini 渠道", model)
+	}
 
 	// 获取指标管理器
 	metricsManager := s.geminiMetricsManager
@@ -767,7 +634,10 @@ func (s *ChannelScheduler) SelectGeminiChannel(
 		}
 	}
 
-	// 2. 按优先级遍历活跃渠道
+	// 2. 按优先级遍历活跃渠道（weighted/latency/cheapest 模式收集全部健康渠道后按策略选择）
+	strategy := s.loadBalanceStrategy(poolGemini)
+	collect := strategy == "weighted" || strategy == "latency" || strategy == "cheapest"
+	var strategyCandidates []slotCandidate
 	for _, ch := range activeChannels {
 		if failedChannels[ch.Index] {
 			continue
@@ -793,6 +663,11 @@ func (s *ChannelScheduler) SelectGeminiChannel(
 			continue
 		}
 
+		if collect {
+			strategyCandidates = append(strategyCandidates, slotCandidate{channelIndex: ch.Index, upstream: upstream, channel: ch})
+			continue
+		}
+
 		log.Printf("[Scheduler-Gemini-Channel] 选择渠道: [%d] %s (优先级: %d)", ch.Index, upstream.Name, ch.Priority)
 		return &SelectionResult{
 			Upstream:     upstream,
@@ -800,158 +675,93 @@ func (s *ChannelScheduler) SelectGeminiChannel(
 			Reason:       "priority_order",
 		}, nil
 	}
+	if len(strategyCandidates) > 0 {
+		chosen, reason := s.chooseSlot(poolGemini, userID, model, strategyCandidates, false)
+		log.Printf("[Scheduler-Gemini-Channel] 按 %s 策略选择渠道: [%d] %s (原因: %s)", strategy, chosen.channelIndex, chosen.upstream.Name, reason)
+		return &SelectionResult{
+			Upstream:     chosen.upstream,
+			ChannelIndex: chosen.channelIndex,
+			Reason:       reason,
+		}, nil
+	}
 
 	// 3. 所有健康渠道都失败，选择失败率最低的作为降级
 	return s.selectFallbackGeminiChannel(activeChannels, failedChannels)
 }
 
-// SelectGeminiSlot 选择最佳 Gemini 槽位（渠道+Key）
+// SelectGeminiSlot 选择最佳 Gemini 槽位（渠道+Key），同 SelectSlot 按 supportedModels 过滤渠道并受并发上限约束
 func (s *ChannelScheduler) SelectGeminiSlot(
 	ctx context.Context,
 	userID string,
 	failedSlots map[string]bool,
 ) (*SlotSelectionResult, error) {
-	_ = ctx
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.selectPoolSlot(ctx, s.poolSpec(poolGemini), userID, failedSlots)
+}
 
-	activeChannels := s.getActiveGeminiChannels()
-	if len(activeChannels) == 0 {
-		return nil, fmt.Errorf("没有可用的活跃 Gemini 渠道")
-	}
+// SelectGeminiSlotUncounted 同 SelectGeminiSlot 选择槽位，但忽略并发上限、不排队也不占用额度
+// 用于 models 列表、Files / cachedContents 等资源管理请求
+func (s *ChannelScheduler) SelectGeminiSlotUncounted(
+	ctx context.Context,
+	userID string,
+	failedSlots map[string]bool,
+) (*SlotSelectionResult, error) {
+	return s.selectPoolSlotUncounted(ctx, s.poolSpec(poolGemini), userID, failedSlots)
+}
 
-	metricsManager := s.geminiMetricsManager
+// SetGeminiResourceOwner 记录 Gemini 资源（文件、上下文缓存、上传会话）的归属槽位
+// ttl <= 0 时使用默认 TTL（48 小时，与 Files API 文件保留期一致）
+func (s *ChannelScheduler) SetGeminiResourceOwner(name string, channelIndex int, apiKey string, ttl time.Duration) {
+	s.geminiResources.Set(name, channelIndex, apiKey, ttl)
+}
 
-	buildSlots := func(channels []ChannelInfo) (healthy []slotCandidate, all []slotCandidate) {
-		for _, ch := range channels {
-			if ch.Status != "active" {
-				continue
-			}
-			upstream := s.getGeminiUpstreamByIndex(ch.Index)
-			if upstream == nil || len(upstream.APIKeys) == 0 {
-				continue
-			}
-			for keyIndex, apiKey := range upstream.APIKeys {
-				if apiKey == "" {
-					continue
-				}
-				if upstream.IsAPIKeyDisabled(apiKey) {
-					continue
-				}
-				if failedSlots != nil && failedSlots[slotID(ch.Index, apiKey)] {
-					continue
-				}
-				if s.configManager != nil && s.configManager.IsKeyFailed(apiKey) {
-					continue
-				}
-				all = append(all, slotCandidate{
-					channelIndex: ch.Index,
-					keyIndex:     keyIndex,
-					apiKey:       apiKey,
-					upstream:     upstream,
-					channel:      ch,
-				})
-				if metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey) {
-					healthy = append(healthy, slotCandidate{
-						channelIndex: ch.Index,
-						keyIndex:     keyIndex,
-						apiKey:       apiKey,
-						upstream:     upstream,
-						channel:      ch,
-					})
-				}
-			}
-		}
-		return healthy, all
+// RemoveGeminiResourceOwner 移除 Gemini 资源的归属记录
+func (s *ChannelScheduler) RemoveGeminiResourceOwner(name string) {
+	s.geminiResources.Remove(name)
+}
+
+// GetGeminiResourceSlot 获取 Gemini 资源的归属槽位
+// 资源只存在于创建它的 Key 下，因此不检查健康状态；渠道调整顺序后按 Key 重新定位
+// 资源未知、渠道已禁用或 Key 已移除/禁用时返回 false
+func (s *ChannelScheduler) GetGeminiResourceSlot(name string) (*SlotSelectionResult, bool) {
+	owner, ok := s.geminiResources.Get(name)
+	if !ok {
+		return nil, false
 	}
 
-	// 0. 促销期渠道优先
-	promotedChannel := s.findPromotedGeminiChannel(activeChannels)
-	if promotedChannel != nil {
-		if upstream := s.getGeminiUpstreamByIndex(promotedChannel.Index); upstream != nil && len(upstream.APIKeys) > 0 {
-			promotedOnly := []ChannelInfo{*promotedChannel}
-			healthy, all := buildSlots(promotedOnly)
-			if len(healthy) == 0 {
-				healthy = all
-			}
-			if len(healthy) > 0 {
-				chosen := chooseSlotByRendezvous(userID, healthy)
-				log.Printf("[Scheduler-Gemini-Promotion] 促销期优先选择槽位: [%d] %s (user: %s)", chosen.channelIndex, upstream.Name, maskUserID(userID))
-				return &SlotSelectionResult{
-					Upstream:     chosen.upstream,
-					ChannelIndex: chosen.channelIndex,
-					KeyIndex:     chosen.keyIndex,
-					APIKey:       chosen.apiKey,
-					Reason:       "promotion_priority",
-				}, nil
-			}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cfg := s.configManager.GetConfig()
+	indexes := []int{owner.ChannelIndex}
+	for i := range cfg.GeminiUpstream {
+		if i != owner.ChannelIndex {
+			indexes = append(indexes, i)
 		}
 	}
-
-	// 1. Trace 亲和
-	if userID != "" {
-		if preferredCh, preferredKeyIdx, ok := s.traceAffinity.GetPreferredSlot(userID); ok && preferredCh >= 0 && preferredKeyIdx >= 0 {
-			upstream := s.getGeminiUpstreamByIndex(preferredCh)
-			if upstream != nil && preferredKeyIdx < len(upstream.APIKeys) {
-				apiKey := upstream.APIKeys[preferredKeyIdx]
-				if apiKey != "" && (failedSlots == nil || !failedSlots[slotID(preferredCh, apiKey)]) &&
-					!upstream.IsAPIKeyDisabled(apiKey) &&
-					(s.configManager == nil || !s.configManager.IsKeyFailed(apiKey)) &&
-					(metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey)) {
-					for _, ch := range activeChannels {
-						if ch.Index == preferredCh && ch.Status == "active" {
-							log.Printf("[Scheduler-Gemini-Affinity] Trace亲和选择槽位: [%d] %s (user: %s)", preferredCh, upstream.Name, maskUserID(userID))
-							return &SlotSelectionResult{
-								Upstream:     upstream,
-								ChannelIndex: preferredCh,
-								KeyIndex:     preferredKeyIdx,
-								APIKey:       apiKey,
-								Reason:       "trace_affinity",
-							}, nil
-						}
-					}
-				}
+	for _, idx := range indexes {
+		upstream := s.getGeminiUpstreamByIndex(idx)
+		if upstream == nil || upstream.Status == "disabled" || upstream.IsAPIKeyDisabled(owner.APIKey) {
+			continue
+		}
+		for keyIndex, apiKey := range upstream.APIKeys {
+			if apiKey == owner.APIKey {
+				return &SlotSelectionResult{
+					Upstream:     upstream,
+					ChannelIndex: idx,
+					KeyIndex:     keyIndex,
+					APIKey:       apiKey,
+					Reason:       "resource_affinity",
+				}, true
 			}
 		}
 	}
-
-	healthy, all := buildSlots(activeChannels)
-	candidates := healthy
-	reason := "rendezvous_hash"
-	if len(candidates) == 0 {
-		candidates = all
-		reason = "rendezvous_fallback"
-	}
-	if len(candidates) == 0 {
-		return nil, fmt.Errorf("所有 Gemini 槽位都不可用")
-	}
-
-	chosen := chooseSlotByRendezvous(userID, candidates)
-	return &SlotSelectionResult{
-		Upstream:     chosen.upstream,
-		ChannelIndex: chosen.channelIndex,
-		KeyIndex:     chosen.keyIndex,
-		APIKey:       chosen.apiKey,
-		Reason:       reason,
-	}, nil
+	log.Printf("[Scheduler-Gemini-Resource] 警告: 资源 %s 的归属槽位已不可用", name)
+	return nil, false
 }
 
 // findPromotedGeminiChannel 查找处于促销期的 Gemini 渠道
 func (s *ChannelScheduler) findPromotedGeminiChannel(activeChannels []ChannelInfo) *ChannelInfo {
-	for i := range activeChannels {
-		ch := &activeChannels[i]
-		if ch.Status != "active" {
-			continue
-		}
-		upstream := s.getGeminiUpstreamByIndex(ch.Index)
-		if upstream != nil {
-			if config.IsChannelInPromotion(upstream) {
-				log.Printf("[Scheduler-Gemini-Promotion] 找到促销渠道: [%d] %s (promotionUntil: %v)", ch.Index, upstream.Name, upstream.PromotionUntil)
-				return ch
-			}
-		}
-	}
-	return nil
+	return s.findPromotedPoolChannel(s.poolSpec(poolGemini), activeChannels)
 }
 
 // selectFallbackGeminiChannel 选择 Gemini 降级渠道（失败率最低的）
@@ -1008,48 +818,12 @@ func (s *ChannelScheduler) selectFallbackGeminiChannel(
 
 // getActiveGeminiChannels 获取活跃 Gemini 渠道列表（按优先级排序）
 func (s *ChannelScheduler) getActiveGeminiChannels() []ChannelInfo {
-	cfg := s.configManager.GetConfig()
-	upstreams := cfg.GeminiUpstream
-
-	var activeChannels []ChannelInfo
-	for i, upstream := range upstreams {
-		status := upstream.Status
-		if status == "" {
-			status = "active"
-		}
-
-		if status != "disabled" {
-			priority := upstream.Priority
-			if priority == 0 {
-				priority = i
-			}
-
-			activeChannels = append(activeChannels, ChannelInfo{
-				Index:    i,
-				Name:     upstream.Name,
-				Priority: priority,
-				Status:   status,
-			})
-		}
-	}
-
-	sort.Slice(activeChannels, func(i, j int) bool {
-		return activeChannels[i].Priority < activeChannels[j].Priority
-	})
-
-	return activeChannels
+	return activeChannelInfos(s.configManager.GetConfig().GeminiUpstream)
 }
 
 // getGeminiUpstreamByIndex 根据索引获取 Gemini 上游配置
 func (s *ChannelScheduler) getGeminiUpstreamByIndex(index int) *config.UpstreamConfig {
-	cfg := s.configManager.GetConfig()
-	upstreams := cfg.GeminiUpstream
-
-	if index >= 0 && index < len(upstreams) {
-		upstream := upstreams[index]
-		return &upstream
-	}
-	return nil
+	return upstreamAt(s.configManager.GetConfig().GeminiUpstream, index)
 }
 
 // RecordGeminiSuccess 记录 Gemini 渠道成功
@@ -1072,6 +846,11 @@ func (s *ChannelScheduler) RecordGeminiFailureWithStatus(baseURL, apiKey string,
 	s.geminiMetricsManager.RecordFailureWithStatus(baseURL, apiKey, statusCode)
 }
 
+// RecordGeminiLatency 记录 Gemini 渠道 TTFB（首字节时间）
+func (s *ChannelScheduler) RecordGeminiLatency(baseURL, apiKey string, ttfb time.Duration) {
+	s.geminiMetricsManager.RecordLatency(baseURL, apiKey, ttfb)
+}
+
 // GetGeminiMetricsManager 获取 Gemini 渠道指标管理器
 func (s *ChannelScheduler) GetGeminiMetricsManager() *metrics.MetricsManager {
 	return s.geminiMetricsManager
@@ -1099,26 +878,7 @@ func (s *ChannelScheduler) GetActiveGeminiChannelCount() int {
 
 // GetActiveGeminiSlotCount 获取活跃 Gemini 槽位数量
 func (s *ChannelScheduler) GetActiveGeminiSlotCount() int {
-	activeChannels := s.getActiveGeminiChannels()
-	count := 0
-	for _, ch := range activeChannels {
-		if ch.Status != "active" {
-			continue
-		}
-		upstream := s.getGeminiUpstreamByIndex(ch.Index)
-		if upstream == nil {
-			continue
-		}
-		for _, k := range upstream.APIKeys {
-			if k != "" {
-				if upstream.IsAPIKeyDisabled(k) {
-					continue
-				}
-				count++
-			}
-		}
-	}
-	return count
+	return s.activeSlotCount(s.poolSpec(poolGemini))
 }
 
 // IsMultiChannelModeGemini 判断 Gemini 是否为多渠道模式
@@ -1128,5 +888,81 @@ func (s *ChannelScheduler) IsMultiChannelModeGemini() bool {
 
 // IsMultiSlotModeGemini 判断 Gemini 是否为多槽位模式
 func (s *ChannelScheduler) IsMultiSlotModeGemini() bool {
-	return s.GetActiveGeminiSlotCount() > 1
+	return s.isMultiSlotPool(s.poolSpec(poolGemini))
+}
+
+// ============== Embeddings 渠道相关方法 ==============
+
+// SelectEmbeddingsSlot 选择最佳 Embeddings 槽位（渠道+Key）
+func (s *ChannelScheduler) SelectEmbeddingsSlot(
+	ctx context.Context,
+	userID string,
+	failedSlots map[string]bool,
+) (*SlotSelectionResult, error) {
+	return s.selectPoolSlot(ctx, s.poolSpec(poolEmbeddings), userID, failedSlots)
+}
+
+// getActiveEmbeddingsChannels 获取活跃 Embeddings 渠道列表（按优先级排序）
+func (s *ChannelScheduler) getActiveEmbeddingsChannels() []ChannelInfo {
+	return activeChannelInfos(s.configManager.GetConfig().EmbeddingsUpstream)
+}
+
+// getEmbeddingsUpstreamByIndex 根据索引获取 Embeddings 上游配置
+func (s *ChannelScheduler) getEmbeddingsUpstreamByIndex(index int) *config.UpstreamConfig {
+	return upstreamAt(s.configManager.GetConfig().EmbeddingsUpstream, index)
+}
+
+// RecordEmbeddingsSuccess 记录 Embeddings 渠道成功
+func (s *ChannelScheduler) RecordEmbeddingsSuccess(baseURL, apiKey string) {
+	s.embeddingsMetricsManager.RecordSuccess(baseURL, apiKey)
+}
+
+// RecordEmbeddingsSuccessWithUsage 记录 Embeddings 渠道成功（带 Usage 数据）
+func (s *ChannelScheduler) RecordEmbeddingsSuccessWithUsage(baseURL, apiKey string, usage *types.Usage, model string, costCents int64) {
+	s.embeddingsMetricsManager.RecordSuccessWithUsage(baseURL, apiKey, usage, model, costCents)
+}
+
+// RecordEmbeddingsFailure 记录 Embeddings 渠道失败
+func (s *ChannelScheduler) RecordEmbeddingsFailure(baseURL, apiKey string) {
+	s.RecordEmbeddingsFailureWithStatus(baseURL, apiKey, 0)
+}
+
+// RecordEmbeddingsFailureWithStatus 记录 Embeddings 渠道失败（带状态码）
+func (s *ChannelScheduler) RecordEmbeddingsFailureWithStatus(baseURL, apiKey string, statusCode int) {
+	s.embeddingsMetricsManager.RecordFailureWithStatus(baseURL, apiKey, statusCode)
+}
+
+// RecordEmbeddingsLatency 记录 Embeddings 渠道 TTFB（首字节时间）
+func (s *ChannelScheduler) RecordEmbeddingsLatency(baseURL, apiKey string, ttfb time.Duration) {
+	s.embeddingsMetricsManager.RecordLatency(baseURL, apiKey, ttfb)
+}
+
+// GetEmbeddingsMetricsManager 获取 Embeddings 渠道指标管理器
+func (s *ChannelScheduler) GetEmbeddingsMetricsManager() *metrics.MetricsManager {
+	return s.embeddingsMetricsManager
+}
+
+// ResetEmbeddingsChannelMetrics 重置 Embeddings 渠道所有 Key 的指标
+func (s *ChannelScheduler) ResetEmbeddingsChannelMetrics(channelIndex int) {
+	upstream := s.getEmbeddingsUpstreamByIndex(channelIndex)
+	if upstream == nil {
+		return
+	}
+	baseURLs := upstream.GetAllBaseURLs()
+	for _, baseURL := range baseURLs {
+		for _, apiKey := range upstream.APIKeys {
+			s.embeddingsMetricsManager.ResetKey(baseURL, apiKey)
+		}
+	}
+	log.Printf("[Scheduler-Embeddings-Reset] 渠道 [%d] %s 的所有 Key 指标已重置", channelIndex, upstream.Name)
+}
+
+// GetActiveEmbeddingsChannelCount 获取活跃 Embeddings 渠道数量
+func (s *ChannelScheduler) GetActiveEmbeddingsChannelCount() int {
+	return len(s.getActiveEmbeddingsChannels())
+}
+
+// GetActiveEmbeddingsSlotCount 获取活跃 Embeddings 槽位数量
+func (s *ChannelScheduler) GetActiveEmbeddingsSlotCount() int {
+	return s.activeSlotCount(s.poolSpec(poolEmbeddings))
 }
@@ -60,15 +60,17 @@ func createTestScheduler(t *testing.T, cfg config.Config) (*ChannelScheduler, fu
 	messagesMetrics := metrics.NewMetricsManager()
 	responsesMetrics := metrics.NewMetricsManager()
 	geminiMetrics := metrics.NewMetricsManager()
+	embeddingsMetrics := metrics.NewMetricsManager()
 	traceAffinity := session.NewTraceAffinityManager()
 	urlManager := warmup.NewURLManager(30*time.Second, 3)
 
-	scheduler := NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, traceAffinity, urlManager)
+	scheduler := NewChannelScheduler(cfgManager, messagesMetrics, responsesMetrics, geminiMetrics, embeddingsMetrics, traceAffinity, urlManager)
 
 	return scheduler, func() {
 		messagesMetrics.Stop()
 		responsesMetrics.Stop()
 		geminiMetrics.Stop()
+		embeddingsMetrics.Stop()
 		cleanup()
 	}
 }
@@ -462,3 +464,82 @@ func TestSelectChannel_IgnoresDisabledKeysForHealth(t *testing.T) {
 		t.Fatalf("unexpected channel: %+v", *result)
 	}
 }
+
+// TestSelectEmbeddingsSlot_IsolatedFromMessagesMetrics 测试 Embeddings 槽位选择只参考独立的指标
+func TestSelectEmbeddingsSlot_IsolatedFromMessagesMetrics(t *testing.T) {
+	baseURL := "https://embed.example.com"
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "chat", BaseURL: baseURL, APIKeys: []string{"k1", "k2"}, Status: "active", Priority: 1},
+		},
+		EmbeddingsUpstream: []config.UpstreamConfig{
+			{Name: "embed", BaseURL: baseURL, APIKeys: []string{"k1", "k2"}, Status: "active", Priority: 1},
+		},
+	}
+
+	scheduler, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	// Messages 渠道上 k1 熔断，不应影响 Embeddings 的选择
+	for i := 0; i < 10; i++ {
+		scheduler.RecordFailure(baseURL, "k1", false)
+	}
+	result, err := scheduler.SelectEmbeddingsSlot(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("SelectEmbeddingsSlot 失败: %v", err)
+	}
+	if result.APIKey != "k1" {
+		t.Fatalf("期望选择 k1（Embeddings 指标健康），实际 %s", result.APIKey)
+	}
+
+	// Embeddings 渠道上 k1 熔断后应跳过
+	for i := 0; i < 10; i++ {
+		scheduler.RecordEmbeddingsFailure(baseURL, "k1")
+	}
+	result, err = scheduler.SelectEmbeddingsSlot(context.Background(), "", nil)
+	if err != nil {
+		t.Fatalf("SelectEmbeddingsSlot 失败: %v", err)
+	}
+	if result.APIKey != "k2" {
+		t.Fatalf("期望选择 k2，实际 %s", result.APIKey)
+	}
+	if got := scheduler.GetActiveEmbeddingsSlotCount(); got != 2 {
+		t.Fatalf("GetActiveEmbeddingsSlotCount = %d, want 2", got)
+	}
+}
+
+func TestGetGeminiResourceSlot_FollowsKeyAfterChannelRemoval(t *testing.T) {
+	cfg := config.Config{
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "g0", BaseURL: "https://g0.example.com", APIKeys: []string{"g0a"}, ServiceType: "gemini", Status: "active"},
+			{Name: "g1", BaseURL: "https://g1.example.com", APIKeys: []string{"g1a", "g1b"}, ServiceType: "gemini", Status: "active"},
+		},
+	}
+
+	scheduler, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	if _, ok := scheduler.GetGeminiResourceSlot("files/unknown"); ok {
+		t.Fatalf("unknown resource should not resolve")
+	}
+
+	scheduler.SetGeminiResourceOwner("files/abc", 1, "g1b", 0)
+	got, ok := scheduler.GetGeminiResourceSlot("files/abc")
+	if !ok || got.ChannelIndex != 1 || got.KeyIndex != 1 || got.Reason != "resource_affinity" {
+		t.Fatalf("unexpected slot: %+v ok=%v", got, ok)
+	}
+
+	// 删除前一个渠道后索引前移，仍按 Key 定位到资源所在渠道
+	if _, err := scheduler.configManager.RemoveGeminiUpstream(0); err != nil {
+		t.Fatalf("RemoveGeminiUpstream: %v", err)
+	}
+	got, ok = scheduler.GetGeminiResourceSlot("files/abc")
+	if !ok || got.ChannelIndex != 0 || got.APIKey != "g1b" {
+		t.Fatalf("unexpected slot after removal: %+v ok=%v", got, ok)
+	}
+
+	scheduler.RemoveGeminiResourceOwner("files/abc")
+	if _, ok := scheduler.GetGeminiResourceSlot("files/abc"); ok {
+		t.Fatalf("removed resource should not resolve")
+	}
+}
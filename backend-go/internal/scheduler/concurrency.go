@@ -0,0 +1,356 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+const (
+	// defaultConcurrencyQueueSize 每个渠道池排队等待空闲槽位的最大请求数
+	defaultConcurrencyQueueSize = 100
+	// defaultConcurrencyQueueTimeout 排队等待空闲槽位的最长时间
+	defaultConcurrencyQueueTimeout = 30 * time.Second
+)
+
+var (
+	// ErrConcurrencyQueueFull 所有槽位达到并发上限且排队队列已满
+	ErrConcurrencyQueueFull = errors.New("所有槽位并发已满且排队队列已满")
+	// ErrConcurrencyQueueTimeout 排队等待空闲槽位超时
+	ErrConcurrencyQueueTimeout = errors.New("排队等待空闲槽位超时")
+	// errSlotsSaturated 可用槽位全部达到并发上限（内部信号，触发排队）
+	errSlotsSaturated = errors.New("所有槽位并发已满")
+)
+
+// concurrencyLimiter 渠道/Key 级在途请求计数，以及槽位全部占满时的 FIFO 排队
+type concurrencyLimiter struct {
+	mu           sync.Mutex
+	inflight     map[string]int        // key: 渠道池|渠道索引 或 渠道池|槽位
+	queues       map[string]*waitQueue // key: 渠道池
+	queueSize    int
+	queueTimeout time.Duration
+	nextSeq      uint64
+}
+
+// waitQueue 单个渠道池的排队队列
+type waitQueue struct {
+	waiters []*queueWaiter // 按 seq 升序（先到先得）
+	version uint64         // 每次释放递增，用于发现选择与入队之间发生的释放
+}
+
+// queueWaiter 排队中的请求
+type queueWaiter struct {
+	seq      uint64
+	queuedAt time.Time
+	ready    chan struct{}
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{
+		inflight:     make(map[string]int),
+		queues:       make(map[string]*waitQueue),
+		queueSize:    defaultConcurrencyQueueSize,
+		queueTimeout: defaultConcurrencyQueueTimeout,
+	}
+}
+
+// SetConcurrencyQueue 设置并发排队参数：size 为每个渠道池的队列长度（0 表示不排队，直接失败），timeout 为最长等待时间
+func (s *ChannelScheduler) SetConcurrencyQueue(size int, timeout time.Duration) {
+	l := s.concurrency
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	if timeout <= 0 {
+		timeout = defaultConcurrencyQueueTimeout
+	}
+	l.queueSize = size
+	l.queueTimeout = timeout
+}
+
+type queueHookKey struct{}
+
+// WithQueueHook 在 context 中携带排队回调：请求因槽位并发已满进入排队时调用一次
+func WithQueueHook(ctx context.Context, hook func(queuedAt time.Time)) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, queueHookKey{}, hook)
+}
+
+// Release 释放槽位的并发占用（本次槽位尝试结束后调用，可重复调用）
+func (r *SlotSelectionResult) Release() {
+	if r != nil && r.release != nil {
+		r.release()
+	}
+}
+
+func channelConcurrencyKey(pool string, channelIndex int) string {
+	return pool + "|" + strconv.Itoa(channelIndex)
+}
+
+func slotConcurrencyKey(pool string, channelIndex int, apiKey string) string {
+	return pool + "|" + slotID(channelIndex, apiKey)
+}
+
+// saturated 判断槽位是否已达到渠道或 Key 的并发上限
+func (l *concurrencyLimiter) saturated(pool string, channelIndex int, apiKey string, upstream *config.UpstreamConfig) bool {
+	if !upstream.HasConcurrencyLimit() {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.saturatedLocked(pool, channelIndex, apiKey, upstream)
+}
+
+func (l *concurrencyLimiter) saturatedLocked(pool string, channelIndex int, apiKey string, upstream *config.UpstreamConfig) bool {
+	if limit := upstream.MaxConcurrency; limit > 0 && l.inflight[channelConcurrencyKey(pool, channelIndex)] >= limit {
+		return true
+	}
+	if limit := upstream.GetKeyMaxConcurrency(apiKey); limit > 0 && l.inflight[slotConcurrencyKey(pool, channelIndex, apiKey)] >= limit {
+		return true
+	}
+	return false
+}
+
+// tryAcquire 占用槽位的并发额度，槽位已满时返回 false
+func (l *concurrencyLimiter) tryAcquire(pool string, c slotCandidate) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.saturatedLocked(pool, c.channelIndex, c.apiKey, c.upstream) {
+		return nil, false
+	}
+
+	channelKey := channelConcurrencyKey(pool, c.channelIndex)
+	slotKey := slotConcurrencyKey(pool, c.channelIndex, c.apiKey)
+	l.inflight[channelKey]++
+	l.inflight[slotKey]++
+
+	var released atomic.Bool
+	return func() {
+		if released.CompareAndSwap(false, true) {
+			l.release(pool, channelKey, slotKey)
+		}
+	}, true
+}
+
+// release 归还并发额度，并唤醒队首等待者
+func (l *concurrencyLimiter) release(pool, channelKey, slotKey string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range []string{channelKey, slotKey} {
+		if l.inflight[key] <= 1 {
+			delete(l.inflight, key)
+		} else {
+			l.inflight[key]--
+		}
+	}
+	if q := l.queues[pool]; q != nil {
+		q.version++
+		q.wakeAfterLocked(0)
+	}
+}
+
+// queueLocked 获取渠道池的排队队列（调用方需持有锁）
+func (l *concurrencyLimiter) queueLocked(pool string) *waitQueue {
+	q := l.queues[pool]
+	if q == nil {
+		q = &waitQueue{}
+		l.queues[pool] = q
+	}
+	return q
+}
+
+// version 获取渠道池当前的释放版本号
+func (l *concurrencyLimiter) version(pool string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queueLocked(pool).version
+}
+
+// enqueue 将请求加入排队队列（w 为 nil 时新建等待者）
+// 被唤醒后仍无可用槽位的等待者按原序号重新入队，并把唤醒传递给后一个等待者，避免空闲额度无人认领
+// 选择之后已发生过释放（版本号变化）时立即唤醒，避免错过通知
+func (l *concurrencyLimiter) enqueue(pool string, w *queueWaiter, version uint64) (*queueWaiter, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q := l.queueLocked(pool)
+
+	requeued := w != nil
+	if !requeued {
+		if len(q.waiters) >= l.queueSize {
+			return nil, ErrConcurrencyQueueFull
+		}
+		l.nextSeq++
+		w = &queueWaiter{seq: l.nextSeq, queuedAt: time.Now(), ready: make(chan struct{}, 1)}
+	}
+
+	pos := len(q.waiters)
+	for i, other := range q.waiters {
+		if other.seq > w.seq {
+			pos = i
+			break
+		}
+	}
+	q.waiters = append(q.waiters, nil)
+	copy(q.waiters[pos+1:], q.waiters[pos:])
+	q.waiters[pos] = w
+
+	if q.version != version {
+		q.removeLocked(w)
+		w.signal()
+	} else if requeued {
+		q.wakeAfterLocked(w.seq)
+	}
+	return w, nil
+}
+
+// leave 等待者因超时或取消离开队列；若已收到唤醒则传递给后一个等待者
+func (l *concurrencyLimiter) leave(pool string, w *queueWaiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q := l.queueLocked(pool)
+	q.removeLocked(w)
+	select {
+	case <-w.ready:
+		q.wakeAfterLocked(w.seq)
+	default:
+	}
+}
+
+// depth 获取渠道池当前排队的请求数
+func (l *concurrencyLimiter) depth(pool string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queueLocked(pool).waiters)
+}
+
+// wakeAfterLocked 唤醒序号大于 seq 的第一个等待者并将其移出队列
+func (q *waitQueue) wakeAfterLocked(seq uint64) {
+	for i, w := range q.waiters {
+		if w.seq > seq {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			w.signal()
+			return
+		}
+	}
+}
+
+func (q *waitQueue) removeLocked(w *queueWaiter) {
+	for i, other := range q.waiters {
+		if other == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *queueWaiter) signal() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// selectWithQueue 执行槽位选择；所有可用槽位都达到并发上限时在渠道池 FIFO 队列中等待释放后重试
+// 队列已满、等待超时或请求取消时返回错误，成功时 SlotSelectionResult.QueueWait 记录排队时长
+func (s *ChannelScheduler) selectWithQueue(ctx context.Context, pool string, selectOnce func() (*SlotSelectionResult, error)) (*SlotSelectionResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	l := s.concurrency
+	var w *queueWaiter
+	var timer *time.Timer
+	for {
+		version := l.version(pool)
+		result, err := selectOnce()
+		if !errors.Is(err, errSlotsSaturated) {
+			if w != nil {
+				timer.Stop()
+				if result != nil {
+					result.QueueWait = time.Since(w.queuedAt)
+				}
+			}
+			return result, err
+		}
+
+		first := w == nil
+		if w, err = l.enqueue(pool, w, version); err != nil {
+			log.Printf("[Scheduler-Queue] 警告: 所有槽位并发已满且排队队列已满 (渠道池: %s)", pool)
+			return nil, err
+		}
+		if first {
+			l.mu.Lock()
+			timeout := l.queueTimeout
+			l.mu.Unlock()
+			timer = time.NewTimer(timeout)
+			log.Printf("[Scheduler-Queue] 所有槽位并发已满，请求进入排队 (渠道池: %s, 队列长度: %d)", pool, l.depth(pool))
+			if hook, ok := ctx.Value(queueHookKey{}).(func(time.Time)); ok && hook != nil {
+				hook(w.queuedAt)
+			}
+		}
+
+		select {
+		case <-w.ready:
+		case <-timer.C:
+			l.leave(pool, w)
+			log.Printf("[Scheduler-Queue] 警告: 排队等待超时 (渠道池: %s, 等待: %v)", pool, time.Since(w.queuedAt).Round(time.Millisecond))
+			return nil, ErrConcurrencyQueueTimeout
+		case <-ctx.Done():
+			timer.Stop()
+			l.leave(pool, w)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// acquireResult 占用候选槽位的并发额度并构建选择结果；槽位恰好已满时返回 nil
+// spec.uncounted 时不占用额度，直接构建结果
+func (s *ChannelScheduler) acquireResult(spec slotPoolSpec, c slotCandidate, reason string) *SlotSelectionResult {
+	result := &SlotSelectionResult{
+		Upstream:     c.upstream,
+		ChannelIndex: c.channelIndex,
+		KeyIndex:     c.keyIndex,
+		APIKey:       c.apiKey,
+		Reason:       reason,
+	}
+	if spec.uncounted {
+		return result
+	}
+	release, ok := s.concurrency.tryAcquire(spec.name, c)
+	if !ok {
+		return nil
+	}
+	result.release = release
+	return result
+}
+
+// chooseAndAcquire 按负载均衡策略选择并占用槽位；选中槽位恰好被并发请求占满时剔除后重选
+func (s *ChannelScheduler) chooseAndAcquire(spec slotPoolSpec, userID, model string, candidates []slotCandidate, fallback bool) (*SlotSelectionResult, error) {
+	for len(candidates) > 0 {
+		chosen, reason := s.chooseSlot(spec.name, userID, model, candidates, fallback)
+		if result := s.acquireResult(spec, chosen, reason); result != nil {
+			return result, nil
+		}
+		remaining := make([]slotCandidate, 0, len(candidates)-1)
+		for _, c := range candidates {
+			if c.channelIndex != chosen.channelIndex || c.apiKey != chosen.apiKey {
+				remaining = append(remaining, c)
+			}
+		}
+		candidates = remaining
+	}
+	return nil, errSlotsSaturated
+}
+
+// GetConcurrencyQueueDepth 获取渠道池当前排队等待槽位的请求数（messages, responses, gemini, embeddings）
+func (s *ChannelScheduler) GetConcurrencyQueueDepth(pool string) int {
+	return s.concurrency.depth(pool)
+}
@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// concurrencyTestConfig 单渠道单 Key，Key 并发上限为 1
+func concurrencyTestConfig() config.Config {
+	return config.Config{
+		Upstream: []config.UpstreamConfig{
+			{
+				Name:       "limited",
+				BaseURL:    "https://limited.example.com",
+				APIKeys:    []string{"k1"},
+				APIKeyMeta: map[string]config.APIKeyMeta{"k1": {MaxConcurrency: 1}},
+				Status:     "active",
+				Priority:   1,
+			},
+		},
+		LoadBalance: "failover",
+	}
+}
+
+func waitQueueDepth(t *testing.T, s *ChannelScheduler, pool string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.GetConcurrencyQueueDepth(pool) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth=%d, want %d", s.GetConcurrencyQueueDepth(pool), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSelectSlot_SkipsSaturatedKey(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{
+				Name:       "a",
+				BaseURL:    "https://a.example.com",
+				APIKeys:    []string{"k1", "k2"},
+				APIKeyMeta: map[string]config.APIKeyMeta{"k1": {MaxConcurrency: 1}},
+				Status:     "active",
+				Priority:   1,
+			},
+		},
+		LoadBalance: "failover",
+	}
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	first, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil || first.APIKey != "k1" {
+		t.Fatalf("first selection = %+v, %v; want k1", first, err)
+	}
+
+	second, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil || second.APIKey != "k2" {
+		t.Fatalf("second selection = %+v, %v; want k2 while k1 saturated", second, err)
+	}
+
+	first.Release()
+	first.Release() // 重复释放不应多减计数
+	second.Release()
+
+	third, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil || third.APIKey != "k1" {
+		t.Fatalf("third selection = %+v, %v; want k1 after release", third, err)
+	}
+	third.Release()
+}
+
+func TestSelectSlot_ChannelConcurrencyLimit(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"a1", "a2"}, MaxConcurrency: 1, Status: "active", Priority: 1},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", Priority: 2},
+		},
+		LoadBalance: "failover",
+	}
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	first, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil || first.APIKey != "a1" {
+		t.Fatalf("first selection = %+v, %v; want a1", first, err)
+	}
+	defer first.Release()
+
+	// 渠道 a 合计并发已满：a2 同样不可用
+	second, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil || second.APIKey != "b1" {
+		t.Fatalf("second selection = %+v, %v; want b1 while channel a saturated", second, err)
+	}
+	second.Release()
+}
+
+func TestSelectSlot_QueuesFIFOUntilRelease(t *testing.T) {
+	s, cleanup := createTestScheduler(t, concurrencyTestConfig())
+	defer cleanup()
+
+	if !s.IsMultiSlotMode(false) {
+		t.Fatalf("expected multi-slot mode when concurrency limit configured")
+	}
+
+	held, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+
+	type outcome struct {
+		name string
+		sel  *SlotSelectionResult
+		err  error
+	}
+	results := make(chan outcome, 2)
+	hooked := make(chan string, 2)
+	start := func(name string) {
+		ctx := WithQueueHook(context.Background(), func(time.Time) { hooked <- name })
+		go func() {
+			sel, err := s.SelectSlot(ctx, "", nil, false)
+			results <- outcome{name, sel, err}
+		}()
+	}
+
+	start("first")
+	if name := <-hooked; name != "first" {
+		t.Fatalf("queue hook = %s, want first", name)
+	}
+	start("second")
+	if name := <-hooked; name != "second" {
+		t.Fatalf("queue hook = %s, want second", name)
+	}
+	waitQueueDepth(t, s, poolMessages, 2)
+
+	time.Sleep(20 * time.Millisecond)
+	held.Release()
+
+	got := <-results
+	if got.err != nil || got.name != "first" {
+		t.Fatalf("first dequeued = %s (%v), want first", got.name, got.err)
+	}
+	if got.sel.QueueWait <= 0 {
+		t.Fatalf("expected QueueWait > 0, got %v", got.sel.QueueWait)
+	}
+	select {
+	case early := <-results:
+		t.Fatalf("%s acquired slot before release", early.name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	got.sel.Release()
+	got = <-results
+	if got.err != nil || got.name != "second" {
+		t.Fatalf("second dequeued = %s (%v), want second", got.name, got.err)
+	}
+	got.sel.Release()
+	waitQueueDepth(t, s, poolMessages, 0)
+}
+
+func TestSelectSlot_QueueTimeoutAndFull(t *testing.T) {
+	s, cleanup := createTestScheduler(t, concurrencyTestConfig())
+	defer cleanup()
+
+	held, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	defer held.Release()
+
+	s.SetConcurrencyQueue(1, 50*time.Millisecond)
+	if _, err := s.SelectSlot(context.Background(), "", nil, false); !errors.Is(err, ErrConcurrencyQueueTimeout) {
+		t.Fatalf("err=%v, want ErrConcurrencyQueueTimeout", err)
+	}
+	if depth := s.GetConcurrencyQueueDepth(poolMessages); depth != 0 {
+		t.Fatalf("queue depth after timeout = %d, want 0", depth)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.SelectSlot(ctx, "", nil, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want context.Canceled", err)
+	}
+
+	s.SetConcurrencyQueue(0, time.Second)
+	if _, err := s.SelectSlot(context.Background(), "", nil, false); !errors.Is(err, ErrConcurrencyQueueFull) {
+		t.Fatalf("err=%v, want ErrConcurrencyQueueFull", err)
+	}
+}
+
+func TestSelectSlotUncounted_IgnoresSaturation(t *testing.T) {
+	s, cleanup := createTestScheduler(t, concurrencyTestConfig())
+	defer cleanup()
+
+	held, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	defer held.Release()
+
+	s.SetConcurrencyQueue(0, time.Second)
+	sel, err := s.SelectSlotUncounted(context.Background(), "", nil, false)
+	if err != nil || sel.APIKey != "k1" {
+		t.Fatalf("SelectSlotUncounted = %+v, %v; want k1 without queueing", sel, err)
+	}
+	sel.Release() // 未占用额度，Release 为空操作
+
+	// 未占用额度：原占用仍有效，计数型选择依旧拒绝
+	if _, err := s.SelectSlot(context.Background(), "", nil, false); !errors.Is(err, ErrConcurrencyQueueFull) {
+		t.Fatalf("err=%v, want ErrConcurrencyQueueFull", err)
+	}
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// 成本比较使用的参考 token 组合：输入:输出 = 4:1，输入中 60% 命中缓存读取、10% 写入缓存，
+// 接近启用 Prompt Cache 的多轮对话请求；缓存价格差异较大的模型间比较更贴近实际账单
+const (
+	costReferenceInputTokens         = 300_000
+	costReferenceCacheCreationTokens = 100_000
+	costReferenceCacheReadTokens     = 600_000
+	costReferenceOutputTokens        = 250_000
+)
+
+// CostEstimator 模型成本估算（由 pricing.Service 实现）
+type CostEstimator interface {
+	EstimateCostUSD(model string, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int) float64
+}
+
+// SetCostEstimator 设置 cheapest 模式使用的成本估算器
+func (s *ChannelScheduler) SetCostEstimator(estimator CostEstimator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.costEstimator = estimator
+}
+
+type requestModelKey struct{}
+
+// WithRequestModel 在 context 中携带请求模型，供调度器按模型计算成本
+func WithRequestModel(ctx context.Context, model string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, requestModelKey{}, model)
+}
+
+// requestModel 读取 context 中的请求模型
+func requestModel(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	model, _ := ctx.Value(requestModelKey{}).(string)
+	return model
+}
+
+// effectiveCost 渠道的有效成本：应用模型重定向后的模型价格（含缓存读写价格）× 渠道价格倍率
+// 未知请求模型或未设置估算器时仅比较价格倍率
+func (s *ChannelScheduler) effectiveCost(model string, upstream *config.UpstreamConfig) float64 {
+	base := 1.0
+	if model != "" && s.costEstimator != nil {
+		base = s.costEstimator.EstimateCostUSD(s.mappedModel(model, upstream), costReferenceInputTokens, costReferenceOutputTokens,
+			costReferenceCacheCreationTokens, costReferenceCacheReadTokens)
+	}
+	return base * upstream.GetPriceMultiplier()
+}
+
+// chooseSlotByCost 选择有效成本最低的槽位；成本相同的槽位间按 Rendezvous Hash 稳定映射
+func (s *ChannelScheduler) chooseSlotByCost(model, userID string, candidates []slotCandidate) slotCandidate {
+	costByChannel := make(map[int]float64, len(candidates))
+	lowest := math.Inf(1)
+	for _, c := range candidates {
+		cost, ok := costByChannel[c.channelIndex]
+		if !ok {
+			cost = s.effectiveCost(model, c.upstream)
+			costByChannel[c.channelIndex] = cost
+		}
+		if cost < lowest {
+			lowest = cost
+		}
+	}
+
+	cheapest := make([]slotCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if costByChannel[c.channelIndex] <= lowest*(1+1e-9) {
+			cheapest = append(cheapest, c)
+		}
+	}
+	return chooseSlotByRendezvous(userID, cheapest)
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// fakeCostEstimator 按模型返回固定的每百万 token 价格
+type fakeCostEstimator map[string]float64
+
+func (f fakeCostEstimator) EstimateCostUSD(model string, inputTokens, outputTokens, cacheCreationTokens, cacheReadTokens int) float64 {
+	return f[model] * float64(inputTokens+outputTokens+cacheCreationTokens+cacheReadTokens) / 1_000_000
+}
+
+func cheapestTestConfig() config.Config {
+	return config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "official", BaseURL: "https://a.example.com", APIKeys: []string{"a1"}, Status: "active", Priority: 1},
+			{Name: "discount", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", Priority: 2, PriceMultiplier: 0.5},
+			{Name: "cheap-model", BaseURL: "https://c.example.com", APIKeys: []string{"c1"}, Status: "active", Priority: 3, PriceMultiplier: 0.8,
+				ModelMapping: map[string]string{"claude-opus": "claude-haiku"}},
+		},
+		LoadBalance: "cheapest",
+	}
+}
+
+func TestSelectSlot_CheapestAppliesMultiplierAndModelMapping(t *testing.T) {
+	s, cleanup := createTestScheduler(t, cheapestTestConfig())
+	defer cleanup()
+	s.SetCostEstimator(fakeCostEstimator{"claude-opus": 10, "claude-sonnet": 10, "claude-haiku": 1})
+
+	// claude-sonnet: official=10, discount=5, cheap-model 未映射=8
+	sel, err := s.SelectSlot(WithRequestModel(context.Background(), "claude-sonnet"), "user-1", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" || sel.Reason != "cheapest" {
+		t.Fatalf("got %s (%s), want b1 (cheapest)", sel.APIKey, sel.Reason)
+	}
+
+	// claude-opus: cheap-model 映射到 haiku 后 1×0.8 最便宜
+	sel, err = s.SelectSlot(WithRequestModel(context.Background(), "claude-opus"), "user-1", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "c1" {
+		t.Fatalf("got %s, want c1 via model mapping", sel.APIKey)
+	}
+
+	// 最便宜的槽位已失败：退到次便宜的渠道
+	sel, err = s.SelectSlot(WithRequestModel(context.Background(), "claude-sonnet"), "user-1", map[string]bool{slotID(1, "b1"): true}, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "c1" {
+		t.Fatalf("got %s, want c1 after cheapest slot failed", sel.APIKey)
+	}
+}
+
+func TestSelectSlot_CheapestWithoutModelComparesMultiplier(t *testing.T) {
+	s, cleanup := createTestScheduler(t, cheapestTestConfig())
+	defer cleanup()
+
+	// 未设置估算器且请求模型未知：仅比较价格倍率
+	sel, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" {
+		t.Fatalf("got %s, want b1 (lowest multiplier)", sel.APIKey)
+	}
+}
+
+func TestSelectSlot_CheapestTieUsesRendezvous(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"a1", "a2"}, Status: "active", PriceMultiplier: 0.5},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", PriceMultiplier: 0.5},
+			{Name: "c", BaseURL: "https://c.example.com", APIKeys: []string{"c1"}, Status: "active"},
+		},
+		LoadBalance: "cheapest",
+	}
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	hits := map[string]int{}
+	for i := 0; i < 200; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		sel, err := s.SelectSlot(context.Background(), userID, nil, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		again, _ := s.SelectSlot(context.Background(), userID, nil, false)
+		if again.APIKey != sel.APIKey {
+			t.Fatalf("user %s not stable: %s vs %s", userID, sel.APIKey, again.APIKey)
+		}
+		hits[sel.APIKey]++
+	}
+	if hits["c1"] != 0 || hits["a1"] == 0 || hits["a2"] == 0 || hits["b1"] == 0 {
+		t.Fatalf("unexpected distribution: %v", hits)
+	}
+}
+
+func TestSelectChannel_Cheapest(t *testing.T) {
+	s, cleanup := createTestScheduler(t, cheapestTestConfig())
+	defer cleanup()
+	s.SetCostEstimator(fakeCostEstimator{"claude-opus": 10, "claude-haiku": 1})
+
+	sel, err := s.SelectChannel(WithRequestModel(context.Background(), "claude-opus"), "", map[int]bool{}, false)
+	if err != nil {
+		t.Fatalf("SelectChannel: %v", err)
+	}
+	if sel.ChannelIndex != 2 || sel.Reason != "cheapest" {
+		t.Fatalf("got channel %d (%s), want 2 (cheapest)", sel.ChannelIndex, sel.Reason)
+	}
+}
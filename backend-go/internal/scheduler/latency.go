@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+)
+
+const (
+	// latencyMinSamples 槽位 TTFB 样本少于该值时视为冷启动，优先探测
+	latencyMinSamples = 3
+	// latencyStaleAfter 槽位超过该时长没有新样本时视为过期，重新探测一次
+	latencyStaleAfter = 10 * time.Minute
+	// latencyTolerance 与最快槽位 EWMA 相差在该倍数以内的槽位视为同等快速
+	latencyTolerance = 1.2
+)
+
+// poolMetricsManager 获取渠道池对应的指标管理器
+func (s *ChannelScheduler) poolMetricsManager(pool string) *metrics.MetricsManager {
+	switch pool {
+	case poolResponses:
+		return s.responsesMetricsManager
+	case poolGemini:
+		return s.geminiMetricsManager
+	case poolEmbeddings:
+		return s.embeddingsMetricsManager
+	default:
+		return s.messagesMetricsManager
+	}
+}
+
+// topPriorityBand 返回候选中优先级最高（数字最小）的一档
+// 未配置优先级的渠道（默认按索引排序）同属一档，排在其中最小索引对应的位置，
+// 否则默认配置下每档只有一个渠道，latency 模式将退化为故障转移
+func topPriorityBand(candidates []slotCandidate) []slotCandidate {
+	unsetBand := -1
+	for _, c := range candidates {
+		if c.channel.PriorityUnset && (unsetBand < 0 || c.channel.Priority < unsetBand) {
+			unsetBand = c.channel.Priority
+		}
+	}
+	bandOf := func(c slotCandidate) int {
+		if c.channel.PriorityUnset {
+			return unsetBand
+		}
+		return c.channel.Priority
+	}
+
+	top := bandOf(candidates[0])
+	for _, c := range candidates[1:] {
+		if p := bandOf(c); p < top {
+			top = p
+		}
+	}
+	band := make([]slotCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if bandOf(c) == top {
+			band = append(band, c)
+		}
+	}
+	return band
+}
+
+// chooseSlotByLatency 在最高优先级档内选择 TTFB 最快的槽位
+// 冷启动/统计过期的槽位优先探测；其余槽位中 EWMA 在最快值 latencyTolerance 倍以内的视为同等快速，
+// 有 userID 时在其中按 Rendezvous Hash 稳定映射（保持 Prompt Cache 命中），否则直接选最快者
+// 候选未指定 apiKey 时（渠道级选择）按渠道全部启用 Key 聚合统计
+func (s *ChannelScheduler) chooseSlotByLatency(pool, userID string, candidates []slotCandidate) (slotCandidate, string) {
+	band := topPriorityBand(candidates)
+	metricsManager := s.poolMetricsManager(pool)
+	if metricsManager == nil {
+		return chooseSlotByRendezvous(userID, band), "latency_explore"
+	}
+
+	now := time.Now()
+	ewma := make([]float64, len(band))
+	var cold []slotCandidate
+	fastest := -1
+	for i, c := range band {
+		keys := []string{c.apiKey}
+		if c.apiKey == "" {
+			keys = c.upstream.GetEnabledAPIKeys()
+		}
+		stats := metricsManager.GetChannelLatencyStats(c.upstream.GetAllBaseURLs(), keys)
+		if stats.SampleCount < latencyMinSamples || now.Sub(stats.LastSample()) > latencyStaleAfter {
+			cold = append(cold, c)
+			ewma[i] = -1
+			continue
+		}
+		ewma[i] = stats.EWMAMs
+		if fastest < 0 || ewma[i] < ewma[fastest] {
+			fastest = i
+		}
+	}
+
+	if len(cold) > 0 {
+		return chooseSlotByRendezvous(userID, cold), "latency_explore"
+	}
+	if userID == "" {
+		return band[fastest], "latency_fastest"
+	}
+	var fast []slotCandidate
+	for i, c := range band {
+		if ewma[i] <= ewma[fastest]*latencyTolerance {
+			fast = append(fast, c)
+		}
+	}
+	return chooseSlotByRendezvous(userID, fast), "latency_fastest"
+}
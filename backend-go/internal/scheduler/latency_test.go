@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// latencyTestConfig 渠道 a/b/c 同属优先级 1，渠道 d 为优先级 2 的备用渠道
+func latencyTestConfig() config.Config {
+	return config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"a1"}, Status: "active", Priority: 1},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", Priority: 1},
+			{Name: "c", BaseURL: "https://c.example.com", APIKeys: []string{"c1"}, Status: "active", Priority: 1},
+			{Name: "d", BaseURL: "https://d.example.com", APIKeys: []string{"d1"}, Status: "active", Priority: 2},
+		},
+		LoadBalance: "latency",
+	}
+}
+
+func recordTTFB(s *ChannelScheduler, baseURL, apiKey string, ttfb time.Duration, n int) {
+	for i := 0; i < n; i++ {
+		s.RecordLatency(baseURL, apiKey, false, ttfb)
+	}
+}
+
+func TestSelectSlot_LatencyPrefersFastestInBand(t *testing.T) {
+	s, cleanup := createTestScheduler(t, latencyTestConfig())
+	defer cleanup()
+
+	recordTTFB(s, "https://a.example.com", "a1", 900*time.Millisecond, 5)
+	recordTTFB(s, "https://b.example.com", "b1", 200*time.Millisecond, 5)
+	recordTTFB(s, "https://c.example.com", "c1", 220*time.Millisecond, 5)
+	// 低优先级档即使更快也不参与
+	recordTTFB(s, "https://d.example.com", "d1", 50*time.Millisecond, 5)
+
+	sel, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" || sel.Reason != "latency_fastest" {
+		t.Fatalf("got %s (%s), want b1 (latency_fastest)", sel.APIKey, sel.Reason)
+	}
+
+	// 有 userID 时在同等快速的槽位（b/c 在 20% 容差内）间稳定分布，不落到慢渠道 a
+	hits := map[string]int{}
+	for i := 0; i < 200; i++ {
+		sel, err := s.SelectSlot(context.Background(), fmt.Sprintf("user-%d", i), nil, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		hits[sel.APIKey]++
+	}
+	if hits["a1"] != 0 || hits["d1"] != 0 || hits["b1"] == 0 || hits["c1"] == 0 {
+		t.Fatalf("unexpected distribution: %v", hits)
+	}
+}
+
+func TestSelectSlot_LatencyExploresColdAndFallsBackToNextBand(t *testing.T) {
+	s, cleanup := createTestScheduler(t, latencyTestConfig())
+	defer cleanup()
+
+	recordTTFB(s, "https://a.example.com", "a1", 200*time.Millisecond, 5)
+	recordTTFB(s, "https://b.example.com", "b1", 200*time.Millisecond, 5)
+	recordTTFB(s, "https://c.example.com", "c1", 200*time.Millisecond, 1)
+
+	// c 样本不足：优先探测
+	sel, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "c1" || sel.Reason != "latency_explore" {
+		t.Fatalf("got %s (%s), want c1 (latency_explore)", sel.APIKey, sel.Reason)
+	}
+
+	// 最高优先级档全部失败：进入下一档
+	failed := map[string]bool{slotID(0, "a1"): true, slotID(1, "b1"): true, slotID(2, "c1"): true}
+	sel, err = s.SelectSlot(context.Background(), "", failed, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "d1" {
+		t.Fatalf("got %s, want d1 from next priority band", sel.APIKey)
+	}
+}
+
+func TestSelectChannel_Latency(t *testing.T) {
+	s, cleanup := createTestScheduler(t, latencyTestConfig())
+	defer cleanup()
+
+	recordTTFB(s, "https://a.example.com", "a1", 800*time.Millisecond, 5)
+	recordTTFB(s, "https://b.example.com", "b1", 400*time.Millisecond, 5)
+	recordTTFB(s, "https://c.example.com", "c1", 150*time.Millisecond, 5)
+
+	sel, err := s.SelectChannel(context.Background(), "", map[int]bool{}, false)
+	if err != nil {
+		t.Fatalf("SelectChannel: %v", err)
+	}
+	if sel.ChannelIndex != 2 || sel.Reason != "latency_fastest" {
+		t.Fatalf("got channel %d (%s), want 2 (latency_fastest)", sel.ChannelIndex, sel.Reason)
+	}
+}
+
+func TestSelectSlot_LatencyUnsetPrioritiesShareBand(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"a1"}, Status: "active"},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active"},
+		},
+		LoadBalance: "latency",
+	}
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	recordTTFB(s, "https://a.example.com", "a1", 900*time.Millisecond, 5)
+	recordTTFB(s, "https://b.example.com", "b1", 200*time.Millisecond, 5)
+
+	// 未配置优先级的渠道同属一档：更快的第二个渠道胜出
+	sel, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" || sel.Reason != "latency_fastest" {
+		t.Fatalf("got %s (%s), want b1 (latency_fastest)", sel.APIKey, sel.Reason)
+	}
+
+	ch, err := s.SelectChannel(context.Background(), "", map[int]bool{}, false)
+	if err != nil {
+		t.Fatalf("SelectChannel: %v", err)
+	}
+	if ch.ChannelIndex != 1 {
+		t.Fatalf("got channel %d, want 1", ch.ChannelIndex)
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"log"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// mappedModel 返回请求模型经渠道 ModelMapping（及全局映射）重定向后的上游模型
+func (s *ChannelScheduler) mappedModel(model string, upstream *config.UpstreamConfig) string {
+	var globalMapping map[string]string
+	if s.configManager != nil {
+		globalMapping = s.configManager.GetGlobalModelMapping()
+	}
+	return config.RedirectModelWithGlobal(model, upstream, globalMapping)
+}
+
+// filterChannelsByModel 过滤无法服务请求模型的渠道（按重定向后的模型匹配 supportedModels）
+// 请求模型未知时不过滤
+func (s *ChannelScheduler) filterChannelsByModel(channels []ChannelInfo, model string, upstreamByIndex func(int) *config.UpstreamConfig) []ChannelInfo {
+	if model == "" {
+		return channels
+	}
+	filtered := make([]ChannelInfo, 0, len(channels))
+	for _, ch := range channels {
+		upstream := upstreamByIndex(ch.Index)
+		if upstream == nil || len(upstream.SupportedModels) == 0 {
+			filtered = append(filtered, ch)
+			continue
+		}
+		if mapped := s.mappedModel(model, upstream); !upstream.SupportsModel(mapped) {
+			log.Printf("[Scheduler-Model] 跳过不支持该模型的渠道: [%d] %s (模型: %s)", ch.Index, ch.Name, mapped)
+			continue
+		}
+		filtered = append(filtered, ch)
+	}
+	return filtered
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+func modelFilterTestConfig() config.Config {
+	return config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "gpt-only", BaseURL: "https://a.example.com", APIKeys: []string{"a1"}, Status: "active", Priority: 1, SupportedModels: []string{"gpt-*"}},
+			{Name: "claude", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", Priority: 2, SupportedModels: []string{"claude-*"}},
+			{Name: "mapped", BaseURL: "https://c.example.com", APIKeys: []string{"c1"}, Status: "active", Priority: 3, SupportedModels: []string{"glm-4.6"},
+				ModelMapping: map[string]string{"claude-sonnet": "glm-4.6"}},
+		},
+		GeminiUpstream: []config.UpstreamConfig{
+			{Name: "flash", BaseURL: "https://g1.example.com", APIKeys: []string{"g1"}, Status: "active", Priority: 1, SupportedModels: []string{"gemini-*-flash"}},
+			{Name: "any", BaseURL: "https://g2.example.com", APIKeys: []string{"g2"}, Status: "active", Priority: 2},
+		},
+	}
+}
+
+func TestSelectSlot_SkipsChannelsNotSupportingModel(t *testing.T) {
+	s, cleanup := createTestScheduler(t, modelFilterTestConfig())
+	defer cleanup()
+
+	sel, err := s.SelectSlot(WithRequestModel(context.Background(), "claude-opus"), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" {
+		t.Fatalf("got %s, want b1", sel.APIKey)
+	}
+
+	// 经 ModelMapping 重定向后匹配：claude 渠道失败后落到 mapped 渠道
+	sel, err = s.SelectSlot(WithRequestModel(context.Background(), "claude-sonnet"), "", map[string]bool{slotID(1, "b1"): true}, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "c1" {
+		t.Fatalf("got %s, want c1 via model mapping", sel.APIKey)
+	}
+
+	// 没有渠道可服务该模型
+	if _, err := s.SelectSlot(WithRequestModel(context.Background(), "llama-3"), "", nil, false); err == nil {
+		t.Fatalf("expected error when no channel supports the model")
+	}
+
+	// 未知模型不过滤
+	if sel, err := s.SelectSlot(context.Background(), "", nil, false); err != nil || sel.APIKey != "a1" {
+		t.Fatalf("got %+v (%v), want a1 without model filter", sel, err)
+	}
+}
+
+func TestSelectSlot_ModelFilterOverridesAffinity(t *testing.T) {
+	s, cleanup := createTestScheduler(t, modelFilterTestConfig())
+	defer cleanup()
+
+	s.SetTraceAffinitySlot("user-1", 0, 0)
+	sel, err := s.SelectSlot(WithRequestModel(context.Background(), "claude-opus"), "user-1", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	if sel.APIKey != "b1" || sel.Reason == "trace_affinity" {
+		t.Fatalf("got %s (%s), want b1 without affinity", sel.APIKey, sel.Reason)
+	}
+}
+
+func TestSelectChannel_SkipsChannelsNotSupportingModel(t *testing.T) {
+	s, cleanup := createTestScheduler(t, modelFilterTestConfig())
+	defer cleanup()
+
+	sel, err := s.SelectChannel(WithRequestModel(context.Background(), "claude-opus"), "", map[int]bool{}, false)
+	if err != nil {
+		t.Fatalf("SelectChannel: %v", err)
+	}
+	if sel.ChannelIndex != 1 {
+		t.Fatalf("got channel %d, want 1", sel.ChannelIndex)
+	}
+}
+
+func TestSelectGeminiSlot_SkipsChannelsNotSupportingModel(t *testing.T) {
+	s, cleanup := createTestScheduler(t, modelFilterTestConfig())
+	defer cleanup()
+
+	sel, err := s.SelectGeminiSlot(WithRequestModel(context.Background(), "gemini-2.5-pro"), "", nil)
+	if err != nil {
+		t.Fatalf("SelectGeminiSlot: %v", err)
+	}
+	if sel.APIKey != "g2" {
+		t.Fatalf("got %s, want g2", sel.APIKey)
+	}
+
+	sel, err = s.SelectGeminiSlot(WithRequestModel(context.Background(), "gemini-2.5-flash"), "", nil)
+	if err != nil {
+		t.Fatalf("SelectGeminiSlot: %v", err)
+	}
+	if sel.APIKey != "g1" {
+		t.Fatalf("got %s, want g1", sel.APIKey)
+	}
+}
@@ -0,0 +1,281 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/metrics"
+)
+
+// slotPoolSpec 渠道池的槽位选择依赖（Messages / Responses / Gemini / Embeddings 共用同一套选择流程）
+type slotPoolSpec struct {
+	name     string // 渠道池标识（负载均衡策略、加权轮询与并发计数按池隔离）
+	logTag   string // 日志标签前缀，如 Scheduler、Scheduler-Gemini
+	label    string // 错误信息中的渠道类型，如 " Gemini "（Messages/Responses 为空）
+	metrics  *metrics.MetricsManager
+	channels func() []ChannelInfo                   // 活跃渠道列表（按优先级排序）
+	upstream func(index int) *config.UpstreamConfig // 按索引获取上游配置副本
+
+	uncounted bool // 不检查并发上限也不占用额度（models 列表、资源管理等轻量请求）
+}
+
+// poolSpec 获取渠道池的槽位选择依赖
+func (s *ChannelScheduler) poolSpec(pool string) slotPoolSpec {
+	switch pool {
+	case poolGemini:
+		return slotPoolSpec{
+			name:     poolGemini,
+			logTag:   "Scheduler-Gemini",
+			label:    " Gemini ",
+			metrics:  s.geminiMetricsManager,
+			channels: s.getActiveGeminiChannels,
+			upstream: s.getGeminiUpstreamByIndex,
+		}
+	case poolEmbeddings:
+		return slotPoolSpec{
+			name:     poolEmbeddings,
+			logTag:   "Scheduler-Embeddings",
+			label:    " Embeddings ",
+			metrics:  s.embeddingsMetricsManager,
+			channels: s.getActiveEmbeddingsChannels,
+			upstream: s.getEmbeddingsUpstreamByIndex,
+		}
+	default:
+		isResponses := pool == poolResponses
+		return slotPoolSpec{
+			name:     slotPool(isResponses),
+			logTag:   "Scheduler",
+			metrics:  s.getMetricsManager(isResponses),
+			channels: func() []ChannelInfo { return s.getActiveChannels(isResponses) },
+			upstream: func(index int) *config.UpstreamConfig { return s.getUpstreamByIndex(index, isResponses) },
+		}
+	}
+}
+
+// selectPoolSlot 在渠道池内选择槽位；所有可用槽位并发已满时排队等待
+func (s *ChannelScheduler) selectPoolSlot(ctx context.Context, spec slotPoolSpec, userID string, failedSlots map[string]bool) (*SlotSelectionResult, error) {
+	return s.selectWithQueue(ctx, spec.name, func() (*SlotSelectionResult, error) {
+		return s.selectPoolSlotOnce(ctx, spec, userID, failedSlots)
+	})
+}
+
+// selectPoolSlotUncounted 在渠道池内选择槽位，不排队也不占用并发额度
+func (s *ChannelScheduler) selectPoolSlotUncounted(ctx context.Context, spec slotPoolSpec, userID string, failedSlots map[string]bool) (*SlotSelectionResult, error) {
+	spec.uncounted = true
+	return s.selectPoolSlotOnce(ctx, spec, userID, failedSlots)
+}
+
+// selectPoolSlotOnce 执行一次槽位选择并占用并发额度，所有可用槽位已满时返回 errSlotsSaturated
+func (s *ChannelScheduler) selectPoolSlotOnce(ctx context.Context, spec slotPoolSpec, userID string, failedSlots map[string]bool) (*SlotSelectionResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	activeChannels := spec.channels()
+	if len(activeChannels) == 0 {
+		return nil, fmt.Errorf("没有可用的活跃%s渠道", spec.label)
+	}
+	model := requestModel(ctx)
+	activeChannels = s.filterChannelsByModel(activeChannels, model, spec.upstream)
+	if len(activeChannels) == 0 {
+		return nil, fmt.Errorf("没有可服务模型 %s 的%s渠道", model, spec.label)
+	}
+
+	metricsManager := spec.metrics
+	pool := spec.name
+
+	buildSlots := func(channels []ChannelInfo) (healthy []slotCandidate, all []slotCandidate, saturated int) {
+		for _, ch := range channels {
+			if ch.Status != "active" {
+				continue
+			}
+			upstream := spec.upstream(ch.Index)
+			if upstream == nil || len(upstream.APIKeys) == 0 {
+				continue
+			}
+			for keyIndex, apiKey := range upstream.APIKeys {
+				if apiKey == "" {
+					continue
+				}
+				if upstream.IsAPIKeyDisabled(apiKey) {
+					continue
+				}
+				if failedSlots != nil && failedSlots[slotID(ch.Index, apiKey)] {
+					continue
+				}
+				if s.configManager != nil && s.configManager.IsKeyFailed(apiKey) {
+					continue
+				}
+				if !spec.uncounted && s.concurrency.saturated(pool, ch.Index, apiKey, upstream) {
+					saturated++
+					continue
+				}
+				candidate := slotCandidate{
+					channelIndex: ch.Index,
+					keyIndex:     keyIndex,
+					apiKey:       apiKey,
+					upstream:     upstream,
+					channel:      ch,
+				}
+				all = append(all, candidate)
+				if metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey) {
+					healthy = append(healthy, candidate)
+				}
+			}
+		}
+		return healthy, all, saturated
+	}
+
+	// 0. 促销期：限定在促销渠道的 slots 内做选择（优先健康 slots）
+	promotedChannel := s.findPromotedPoolChannel(spec, activeChannels)
+	if promotedChannel != nil {
+		if upstream := spec.upstream(promotedChannel.Index); upstream != nil && len(upstream.APIKeys) > 0 {
+			promotedOnly := []ChannelInfo{*promotedChannel}
+			healthy, all, _ := buildSlots(promotedOnly)
+			if len(healthy) == 0 {
+				healthy = all
+			}
+			if len(healthy) > 0 {
+				chosen := chooseSlotByRendezvous(userID, healthy)
+				if result := s.acquireResult(spec, chosen, "promotion_priority"); result != nil {
+					log.Printf("[%s-Promotion] 促销期优先选择槽位: [%d] %s (user: %s)", spec.logTag, chosen.channelIndex, upstream.Name, maskUserID(userID))
+					return result, nil
+				}
+			}
+		}
+	}
+
+	// 1. Trace 亲和：命中到具体槽位
+	if userID != "" {
+		if preferredCh, preferredKeyIdx, ok := s.traceAffinity.GetPreferredSlot(userID); ok && preferredCh >= 0 && preferredKeyIdx >= 0 {
+			upstream := spec.upstream(preferredCh)
+			if upstream != nil && preferredKeyIdx < len(upstream.APIKeys) {
+				apiKey := upstream.APIKeys[preferredKeyIdx]
+				if apiKey != "" && (failedSlots == nil || !failedSlots[slotID(preferredCh, apiKey)]) &&
+					!upstream.IsAPIKeyDisabled(apiKey) &&
+					(s.configManager == nil || !s.configManager.IsKeyFailed(apiKey)) &&
+					(metricsManager == nil || metricsManager.IsKeyHealthy(upstream.BaseURL, apiKey)) {
+					// 仅 active 渠道可用
+					for _, ch := range activeChannels {
+						if ch.Index == preferredCh && ch.Status == "active" {
+							candidate := slotCandidate{channelIndex: preferredCh, keyIndex: preferredKeyIdx, apiKey: apiKey, upstream: upstream, channel: ch}
+							if result := s.acquireResult(spec, candidate, "trace_affinity"); result != nil {
+								log.Printf("[%s-Affinity] Trace亲和选择槽位: [%d] %s (user: %s)", spec.logTag, preferredCh, upstream.Name, maskUserID(userID))
+								return result, nil
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// 2. Rendezvous Hash：在所有健康槽位里稳定选择（weighted/latency/cheapest 模式按策略选择）
+	healthy, all, saturated := buildSlots(activeChannels)
+	candidates := healthy
+	fallback := false
+	if len(candidates) == 0 {
+		candidates = all
+		fallback = true
+	}
+	if len(candidates) == 0 {
+		if saturated > 0 {
+			return nil, errSlotsSaturated
+		}
+		return nil, fmt.Errorf("所有%s槽位都不可用", spec.label)
+	}
+
+	return s.chooseAndAcquire(spec, userID, model, candidates, fallback)
+}
+
+// findPromotedPoolChannel 查找渠道池中处于促销期的渠道
+func (s *ChannelScheduler) findPromotedPoolChannel(spec slotPoolSpec, activeChannels []ChannelInfo) *ChannelInfo {
+	for i := range activeChannels {
+		ch := &activeChannels[i]
+		if ch.Status != "active" {
+			continue
+		}
+		upstream := spec.upstream(ch.Index)
+		if upstream != nil && config.IsChannelInPromotion(upstream) {
+			log.Printf("[%s-Promotion] 找到促销渠道: [%d] %s (promotionUntil: %v)", spec.logTag, ch.Index, upstream.Name, upstream.PromotionUntil)
+			return ch
+		}
+	}
+	return nil
+}
+
+// activeSlotCount 获取渠道池中可用槽位（active 渠道的启用 Key）数量
+func (s *ChannelScheduler) activeSlotCount(spec slotPoolSpec) int {
+	count := 0
+	for _, ch := range spec.channels() {
+		if ch.Status != "active" {
+			continue
+		}
+		upstream := spec.upstream(ch.Index)
+		if upstream == nil {
+			continue
+		}
+		for _, k := range upstream.APIKeys {
+			if k != "" && !upstream.IsAPIKeyDisabled(k) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// isMultiSlotPool 判断渠道池是否为多槽位模式
+// 配置了并发上限的渠道需经调度器计数与排队，同样走多槽位模式
+func (s *ChannelScheduler) isMultiSlotPool(spec slotPoolSpec) bool {
+	if s.activeSlotCount(spec) > 1 {
+		return true
+	}
+	for _, ch := range spec.channels() {
+		if ch.Status == "active" && spec.upstream(ch.Index).HasConcurrencyLimit() {
+			return true
+		}
+	}
+	return false
+}
+
+// activeChannelInfos 筛选非 disabled 渠道并按优先级排序（数字越小优先级越高，未设置时按索引）
+// suspended 渠道也在活跃序列中，由健康检查过滤
+func activeChannelInfos(upstreams []config.UpstreamConfig) []ChannelInfo {
+	var activeChannels []ChannelInfo
+	for i, upstream := range upstreams {
+		status := upstream.Status
+		if status == "" {
+			status = "active" // 默认为活跃
+		}
+		if status == "disabled" {
+			continue
+		}
+		priority := upstream.Priority
+		if priority == 0 {
+			priority = i // 默认优先级为索引
+		}
+		activeChannels = append(activeChannels, ChannelInfo{
+			Index:         i,
+			Name:          upstream.Name,
+			Priority:      priority,
+			PriorityUnset: upstream.Priority == 0,
+			Status:        status,
+		})
+	}
+
+	sort.Slice(activeChannels, func(i, j int) bool {
+		return activeChannels[i].Priority < activeChannels[j].Priority
+	})
+	return activeChannels
+}
+
+// upstreamAt 按索引返回上游配置副本，避免指向 slice 元素的指针在 slice 重分配后失效
+func upstreamAt(upstreams []config.UpstreamConfig, index int) *config.UpstreamConfig {
+	if index >= 0 && index < len(upstreams) {
+		upstream := upstreams[index]
+		return &upstream
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// TestSelectEmbeddingsSlot_SharedPipeline Embeddings 渠道池走与其他渠道池相同的选择流程：
+// 模型过滤、负载均衡策略与并发上限按池独立生效
+func TestSelectEmbeddingsSlot_SharedPipeline(t *testing.T) {
+	cfg := config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "messages", BaseURL: "https://m.example.com", APIKeys: []string{"m1"}, Status: "active", MaxConcurrency: 1},
+		},
+		EmbeddingsUpstream: []config.UpstreamConfig{
+			{Name: "small", BaseURL: "https://e1.example.com", APIKeys: []string{"e1"}, Status: "active", Priority: 1, MaxConcurrency: 1,
+				SupportedModels: []string{"text-embedding-3-*"}},
+			{Name: "large", BaseURL: "https://e2.example.com", APIKeys: []string{"e2"}, Status: "active", Priority: 2},
+			{Name: "off", BaseURL: "https://e3.example.com", APIKeys: []string{"e3"}, Status: "disabled", Priority: 0},
+		},
+		EmbeddingsLoadBalance: "failover",
+	}
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	if got := s.GetActiveEmbeddingsSlotCount(); got != 2 {
+		t.Fatalf("GetActiveEmbeddingsSlotCount=%d, want 2 (disabled channel excluded)", got)
+	}
+
+	// Messages 渠道池占满不影响 Embeddings 渠道池
+	held, err := s.SelectSlot(context.Background(), "", nil, false)
+	if err != nil {
+		t.Fatalf("SelectSlot: %v", err)
+	}
+	defer held.Release()
+
+	ctx := WithRequestModel(context.Background(), "text-embedding-3-small")
+	first, err := s.SelectEmbeddingsSlot(ctx, "", nil)
+	if err != nil || first.APIKey != "e1" {
+		t.Fatalf("first selection = %+v, %v; want e1", first, err)
+	}
+	second, err := s.SelectEmbeddingsSlot(ctx, "", nil)
+	if err != nil || second.APIKey != "e2" {
+		t.Fatalf("second selection = %+v, %v; want e2 while e1 saturated", second, err)
+	}
+	first.Release()
+	second.Release()
+
+	other := WithRequestModel(context.Background(), "bge-m3")
+	sel, err := s.SelectEmbeddingsSlot(other, "", map[string]bool{slotID(1, "e2"): true})
+	if err == nil || !strings.Contains(err.Error(), "Embeddings") {
+		t.Fatalf("selection = %+v, err=%v; want Embeddings pool error", sel, err)
+	}
+}
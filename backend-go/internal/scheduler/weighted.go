@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"math"
+	"sync"
+)
+
+// 渠道池标识（负载均衡策略与加权轮询状态按池隔离）
+const (
+	poolMessages   = "messages"
+	poolResponses  = "responses"
+	poolGemini     = "gemini"
+	poolEmbeddings = "embeddings"
+)
+
+func slotPool(isResponses bool) string {
+	if isResponses {
+		return poolResponses
+	}
+	return poolMessages
+}
+
+// loadBalanceStrategy 读取渠道池的负载均衡策略
+func (s *ChannelScheduler) loadBalanceStrategy(pool string) string {
+	if s.configManager == nil {
+		return "failover"
+	}
+	cfg := s.configManager.GetConfig()
+	switch pool {
+	case poolResponses:
+		return cfg.ResponsesLoadBalance
+	case poolGemini:
+		return cfg.GeminiLoadBalance
+	case poolEmbeddings:
+		return cfg.EmbeddingsLoadBalance
+	default:
+		return cfg.LoadBalance
+	}
+}
+
+// chooseSlot 按渠道池的负载均衡策略在候选槽位中选择，返回选择原因
+// model 为请求模型（cheapest 模式计算成本使用，可为空）；fallback 表示候选集为不健康槽位（降级选择）
+func (s *ChannelScheduler) chooseSlot(pool, userID, model string, candidates []slotCandidate, fallback bool) (slotCandidate, string) {
+	var chosen slotCandidate
+	var reason string
+	switch s.loadBalanceStrategy(pool) {
+	case "weighted":
+		if userID == "" {
+			chosen, reason = s.weightedRR.next(pool, candidates), "weighted_round_robin"
+		} else {
+			chosen, reason = chooseSlotByWeightedRendezvous(userID, candidates), "weighted_rendezvous"
+		}
+		if fallback {
+			reason = "weighted_fallback"
+		}
+	case "latency":
+		chosen, reason = s.chooseSlotByLatency(pool, userID, candidates)
+		if fallback {
+			reason = "latency_fallback"
+		}
+	case "cheapest":
+		chosen, reason = s.chooseSlotByCost(model, userID, candidates), "cheapest"
+		if fallback {
+			reason = "cheapest_fallback"
+		}
+	default:
+		chosen, reason = chooseSlotByRendezvous(userID, candidates), "rendezvous_hash"
+		if fallback {
+			reason = "rendezvous_fallback"
+		}
+	}
+	return chosen, reason
+}
+
+// channelWeight 渠道权重，未配置或非正数时为 1
+func channelWeight(c slotCandidate) float64 {
+	if c.upstream == nil || c.upstream.Weight <= 0 {
+		return 1
+	}
+	return float64(c.upstream.Weight)
+}
+
+// slotWeights 计算每个候选槽位的权重：渠道权重在其候选 Key 间均分
+// 这样同一渠道的部分 Key 不可用时，渠道整体的流量占比保持不变
+func slotWeights(candidates []slotCandidate) []float64 {
+	keysPerChannel := make(map[int]int, len(candidates))
+	for _, c := range candidates {
+		keysPerChannel[c.channelIndex]++
+	}
+	weights := make([]float64, len(candidates))
+	for i, c := range candidates {
+		weights[i] = channelWeight(c) / float64(keysPerChannel[c.channelIndex])
+	}
+	return weights
+}
+
+// chooseSlotByWeightedRendezvous 加权 Rendezvous Hash：同一用户稳定映射到同一槽位，
+// 不同用户按权重比例分布。score = w / -ln(u)，u 为 (0,1) 内的均匀哈希值
+func chooseSlotByWeightedRendezvous(userID string, candidates []slotCandidate) slotCandidate {
+	weights := slotWeights(candidates)
+	best := 0
+	bestScore := math.Inf(-1)
+	for i, c := range candidates {
+		u := (float64(mix64(hash64(userID+"|"+slotID(c.channelIndex, c.apiKey)))>>11) + 0.5) / (1 << 53)
+		score := weights[i] / -math.Log(u)
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	return candidates[best]
+}
+
+// mix64 splitmix64 终混函数：FNV 对仅尾部不同的输入高位扩散不足，换算为均匀分布前需再混合
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// weightedRoundRobin 平滑加权轮询（nginx SWRR）：按权重比例交错分配，避免同一槽位连续命中
+type weightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[string]float64 // key: 渠道池 + 槽位标识
+}
+
+// next 选择下一个槽位：各候选当前值加上自身权重，选最大者并减去总权重
+func (w *weightedRoundRobin) next(pool string, candidates []slotCandidate) slotCandidate {
+	weights := slotWeights(candidates)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.current == nil {
+		w.current = make(map[string]float64)
+	}
+
+	best := -1
+	var bestKey string
+	total := 0.0
+	for i, c := range candidates {
+		key := pool + "|" + slotID(c.channelIndex, c.apiKey)
+		w.current[key] += weights[i]
+		total += weights[i]
+		if best < 0 || w.current[key] > w.current[bestKey] {
+			best = i
+			bestKey = key
+		}
+	}
+	w.current[bestKey] -= total
+	return candidates[best]
+}
@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/BenedictKing/claude-proxy/internal/config"
+)
+
+// weightedTestConfig 三个渠道按 70/20/10 分配，渠道 0 有两个 Key
+func weightedTestConfig() config.Config {
+	return config.Config{
+		Upstream: []config.UpstreamConfig{
+			{Name: "a", BaseURL: "https://a.example.com", APIKeys: []string{"a1", "a2"}, Status: "active", Priority: 1, Weight: 70},
+			{Name: "b", BaseURL: "https://b.example.com", APIKeys: []string{"b1"}, Status: "active", Priority: 2, Weight: 20},
+			{Name: "c", BaseURL: "https://c.example.com", APIKeys: []string{"c1"}, Status: "active", Priority: 3, Weight: 10},
+		},
+		LoadBalance:       "weighted",
+		GeminiLoadBalance: "failover",
+	}
+}
+
+func TestSelectSlot_WeightedRoundRobin(t *testing.T) {
+	s, cleanup := createTestScheduler(t, weightedTestConfig())
+	defer cleanup()
+
+	channelHits := map[int]int{}
+	keyHits := map[string]int{}
+	longestRun, run, last := 0, 0, -1
+	for i := 0; i < 100; i++ {
+		sel, err := s.SelectSlot(context.Background(), "", nil, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		if sel.Reason != "weighted_round_robin" {
+			t.Fatalf("reason = %q, want weighted_round_robin", sel.Reason)
+		}
+		channelHits[sel.ChannelIndex]++
+		keyHits[sel.APIKey]++
+		if sel.ChannelIndex == last {
+			run++
+		} else {
+			run = 1
+		}
+		last = sel.ChannelIndex
+		if sel.ChannelIndex != 0 && run > longestRun {
+			longestRun = run
+		}
+	}
+
+	if channelHits[0] != 70 || channelHits[1] != 20 || channelHits[2] != 10 {
+		t.Fatalf("channel hits = %v, want 70/20/10", channelHits)
+	}
+	// 渠道权重在 Key 间均分
+	if keyHits["a1"] != 35 || keyHits["a2"] != 35 {
+		t.Fatalf("key hits = %v, want a1=a2=35", keyHits)
+	}
+	// 平滑加权：低权重渠道不会连续命中
+	if longestRun > 1 {
+		t.Fatalf("low-weight channels should be interleaved, longest run = %d", longestRun)
+	}
+}
+
+func TestSelectSlot_WeightedRendezvous(t *testing.T) {
+	s, cleanup := createTestScheduler(t, weightedTestConfig())
+	defer cleanup()
+
+	const users = 20000
+	channelHits := map[int]int{}
+	for i := 0; i < users; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		sel, err := s.SelectSlot(context.Background(), userID, nil, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		if sel.Reason != "weighted_rendezvous" {
+			t.Fatalf("reason = %q, want weighted_rendezvous", sel.Reason)
+		}
+		channelHits[sel.ChannelIndex]++
+
+		// 同一用户稳定映射
+		if i%1000 == 0 {
+			again, _ := s.SelectSlot(context.Background(), userID, nil, false)
+			if again.ChannelIndex != sel.ChannelIndex || again.APIKey != sel.APIKey {
+				t.Fatalf("user %s moved from %d:%s to %d:%s", userID, sel.ChannelIndex, sel.APIKey, again.ChannelIndex, again.APIKey)
+			}
+		}
+	}
+
+	for idx, want := range map[int]float64{0: 0.7, 1: 0.2, 2: 0.1} {
+		got := float64(channelHits[idx]) / users
+		if math.Abs(got-want) > 0.02 {
+			t.Fatalf("channel %d share = %.3f, want %.2f±0.02 (hits=%v)", idx, got, want, channelHits)
+		}
+	}
+}
+
+func TestSelectSlot_WeightedKeepsChannelShareWhenKeyFails(t *testing.T) {
+	s, cleanup := createTestScheduler(t, weightedTestConfig())
+	defer cleanup()
+
+	// 渠道 0 的一个 Key 在本次请求中失败，剩余 Key 承担整个渠道的权重
+	failed := map[string]bool{slotID(0, "a1"): true}
+	channelHits := map[int]int{}
+	for i := 0; i < 100; i++ {
+		sel, err := s.SelectSlot(context.Background(), "", failed, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		if sel.APIKey == "a1" {
+			t.Fatalf("failed slot should be skipped")
+		}
+		channelHits[sel.ChannelIndex]++
+	}
+	if channelHits[0] != 70 || channelHits[1] != 20 || channelHits[2] != 10 {
+		t.Fatalf("channel hits = %v, want 70/20/10", channelHits)
+	}
+}
+
+func TestSelectSlot_FailoverModeIgnoresWeight(t *testing.T) {
+	cfg := weightedTestConfig()
+	cfg.LoadBalance = "failover"
+	s, cleanup := createTestScheduler(t, cfg)
+	defer cleanup()
+
+	for i := 0; i < 10; i++ {
+		sel, err := s.SelectSlot(context.Background(), "", nil, false)
+		if err != nil {
+			t.Fatalf("SelectSlot: %v", err)
+		}
+		if sel.Reason != "rendezvous_hash" || sel.APIKey != "a1" {
+			t.Fatalf("failover mode should keep deterministic priority order, got %s (%s)", sel.APIKey, sel.Reason)
+		}
+	}
+}
+
+func TestSelectChannel_Weighted(t *testing.T) {
+	s, cleanup := createTestScheduler(t, weightedTestConfig())
+	defer cleanup()
+
+	channelHits := map[int]int{}
+	for i := 0; i < 100; i++ {
+		sel, err := s.SelectChannel(context.Background(), "", map[int]bool{}, false)
+		if err != nil {
+			t.Fatalf("SelectChannel: %v", err)
+		}
+		channelHits[sel.ChannelIndex]++
+	}
+	if channelHits[0] != 70 || channelHits[1] != 20 || channelHits[2] != 10 {
+		t.Fatalf("channel hits = %v, want 70/20/10", channelHits)
+	}
+
+	// 权重只影响 Messages 池；Gemini 池仍为 failover
+	if got := s.loadBalanceStrategy(poolGemini); got != "failover" {
+		t.Fatalf("gemini strategy = %q, want failover", got)
+	}
+}
@@ -1,8 +1,10 @@
 package session
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
@@ -23,10 +25,27 @@ type Session struct {
 	TotalTokens    int
 }
 
+// StoredResponse 已存储的响应（用于 GET/DELETE /v1/responses/{id}）
+type StoredResponse struct {
+	ID         string
+	SessionID  string
+	Response   json.RawMessage       // 返回给客户端的响应对象
+	InputItems []types.ResponsesItem // 本轮请求的输入项
+	CreatedAt  time.Time
+}
+
+// backgroundJob 运行中的后台响应任务
+type backgroundJob struct {
+	cancel   context.CancelFunc
+	canceled bool
+}
+
 // SessionManager 会话管理器
 type SessionManager struct {
-	sessions        map[string]*Session // sessionID → Session
-	responseMapping map[string]string   // responseID → sessionID
+	sessions        map[string]*Session        // sessionID → Session
+	responseMapping map[string]string          // responseID → sessionID
+	responses       map[string]*StoredResponse // responseID → StoredResponse
+	background      map[string]*backgroundJob  // responseID → 运行中的后台任务
 	mu              sync.RWMutex
 
 	// 清理配置
@@ -40,6 +59,8 @@ func NewSessionManager(maxAge time.Duration, maxMessages int, maxTokens int) *Se
 	sm := &SessionManager{
 		sessions:        make(map[string]*Session),
 		responseMapping: make(map[string]string),
+		responses:       make(map[string]*StoredResponse),
+		background:      make(map[string]*backgroundJob),
 		maxAge:          maxAge,
 		maxMessages:     maxMessages,
 		maxTokens:       maxTokens,
@@ -93,6 +114,125 @@ func (sm *SessionManager) RecordResponseMapping(responseID, sessionID string) {
 	log.Printf("[Session-Mapping] 记录映射: %s -> %s", responseID, sessionID)
 }
 
+// StoreResponse 存储响应对象及其输入项，输入项缺少 ID 时自动生成
+func (sm *SessionManager) StoreResponse(sessionID, responseID string, response []byte, inputItems []types.ResponsesItem) {
+	sm.StoreResponseIf(sessionID, responseID, response, inputItems, func(json.RawMessage) bool { return true })
+}
+
+// StoreResponseIf 在锁内校验当前存储后写入响应对象，allow 返回 false 时保持原样
+// current 为 nil 表示尚未存储；返回值表示是否已写入
+func (sm *SessionManager) StoreResponseIf(sessionID, responseID string, response []byte, inputItems []types.ResponsesItem, allow func(current json.RawMessage) bool) bool {
+	items := make([]types.ResponsesItem, len(inputItems))
+	copy(items, inputItems)
+	for i := range items {
+		if items[i].ID == "" {
+			items[i].ID = generateID("msg")
+		}
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var current json.RawMessage
+	if stored, exists := sm.responses[responseID]; exists {
+		current = stored.Response
+	}
+	if !allow(current) {
+		return false
+	}
+	sm.responses[responseID] = &StoredResponse{
+		ID:         responseID,
+		SessionID:  sessionID,
+		Response:   append(json.RawMessage(nil), response...),
+		InputItems: items,
+		CreatedAt:  time.Now(),
+	}
+	return true
+}
+
+// GetResponse 获取已存储的响应
+func (sm *SessionManager) GetResponse(responseID string) (*StoredResponse, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	stored, exists := sm.responses[responseID]
+	if !exists {
+		return nil, fmt.Errorf("响应不存在: %s", responseID)
+	}
+	return stored, nil
+}
+
+// UpdateStoredResponseIf 在锁内基于当前响应对象计算并替换（读-改-写原子完成，保留会话与输入项）
+// update 返回 false 时保持原样；返回值表示是否已更新
+func (sm *SessionManager) UpdateStoredResponseIf(responseID string, update func(current json.RawMessage) ([]byte, bool)) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	stored, exists := sm.responses[responseID]
+	if !exists {
+		return false, fmt.Errorf("响应不存在: %s", responseID)
+	}
+	response, ok := update(stored.Response)
+	if !ok {
+		return false, nil
+	}
+	updated := *stored
+	updated.Response = append(json.RawMessage(nil), response...)
+	sm.responses[responseID] = &updated
+	return true, nil
+}
+
+// RegisterBackground 登记后台响应任务，cancel 用于中止上游请求
+func (sm *SessionManager) RegisterBackground(responseID string, cancel context.CancelFunc) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.background[responseID] = &backgroundJob{cancel: cancel}
+}
+
+// CancelBackground 取消运行中的后台任务，任务不存在（未登记或已结束）时返回 false
+func (sm *SessionManager) CancelBackground(responseID string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	job, exists := sm.background[responseID]
+	if !exists {
+		return false
+	}
+	job.canceled = true
+	job.cancel()
+	log.Printf("[Session-Background] 取消后台响应: %s", responseID)
+	return true
+}
+
+// FinishBackground 注销后台任务，返回任务是否已被取消
+func (sm *SessionManager) FinishBackground(responseID string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	job, exists := sm.background[responseID]
+	if !exists {
+		return false
+	}
+	delete(sm.background, responseID)
+	job.cancel()
+	return job.canceled
+}
+
+// DeleteResponse 删除已存储的响应，删除后不可再作为 previous_response_id 使用
+func (sm *SessionManager) DeleteResponse(responseID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, exists := sm.responses[responseID]; !exists {
+		return fmt.Errorf("响应不存在: %s", responseID)
+	}
+	delete(sm.responses, responseID)
+	delete(sm.responseMapping, responseID)
+	log.Printf("[Session-Delete] 删除响应: %s", responseID)
+	return nil
+}
+
 // AppendMessage 追加消息到会话
 func (sm *SessionManager) AppendMessage(sessionID string, item types.ResponsesItem, tokensUsed int) error {
 	sm.mu.Lock()
@@ -192,6 +332,19 @@ func (sm *SessionManager) cleanup() {
 		}
 	}
 
+	// 清理孤立的已存储响应（后台任务尚未关联会话的响应按创建时间过期）
+	for responseID, stored := range sm.responses {
+		if stored.SessionID == "" {
+			if now.Sub(stored.CreatedAt) > sm.maxAge {
+				delete(sm.responses, responseID)
+			}
+			continue
+		}
+		if _, exists := sm.sessions[stored.SessionID]; !exists {
+			delete(sm.responses, responseID)
+		}
+	}
+
 	if removedSessions > 0 || removedMappings > 0 {
 		log.Printf("[Session-Cleanup] 清理完成: 删除 %d 个会话, %d 个映射", removedSessions, removedMappings)
 		log.Printf("[Session-Stats] 当前活跃会话: %d 个, 映射: %d 个", len(sm.sessions), len(sm.responseMapping))
@@ -204,8 +357,9 @@ func (sm *SessionManager) GetStats() map[string]interface{} {
 	defer sm.mu.RUnlock()
 
 	return map[string]interface{}{
-		"total_sessions": len(sm.sessions),
-		"total_mappings": len(sm.responseMapping),
+		"total_sessions":  len(sm.sessions),
+		"total_mappings":  len(sm.responseMapping),
+		"total_responses": len(sm.responses),
 	}
 }
 
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// ResourceOwner 上游资源（文件、缓存等）的归属槽位
+// 资源只存在于创建它的 Key 下，后续引用必须路由回同一 (渠道, Key)
+type ResourceOwner struct {
+	ChannelIndex int
+	APIKey       string
+	ExpiresAt    time.Time
+}
+
+// ResourceAffinityManager 管理资源名与归属槽位的映射
+type ResourceAffinityManager struct {
+	mu          sync.RWMutex
+	owners      map[string]*ResourceOwner // key: 资源名，如 files/abc、cachedContents/xyz
+	defaultTTL  time.Duration
+	lastCleanup time.Time
+}
+
+// NewResourceAffinityManager 创建资源亲和性管理器
+// defaultTTL 用于无法得知资源过期时间的场景
+func NewResourceAffinityManager(defaultTTL time.Duration) *ResourceAffinityManager {
+	if defaultTTL <= 0 {
+		defaultTTL = 48 * time.Hour
+	}
+	return &ResourceAffinityManager{
+		owners:      make(map[string]*ResourceOwner),
+		defaultTTL:  defaultTTL,
+		lastCleanup: time.Now(),
+	}
+}
+
+// Set 记录资源归属；ttl <= 0 时使用默认 TTL
+func (m *ResourceAffinityManager) Set(name string, channelIndex int, apiKey string, ttl time.Duration) {
+	if name == "" || apiKey == "" {
+		return
+	}
+	if ttl <= 0 {
+		ttl = m.defaultTTL
+	}
+
+	m.mu.Lock()
+	m.owners[name] = &ResourceOwner{
+		ChannelIndex: channelIndex,
+		APIKey:       apiKey,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	// 写入时顺带清理过期记录，避免额外的后台 goroutine
+	if time.Since(m.lastCleanup) > 10*time.Minute {
+		m.cleanupLocked()
+	}
+	m.mu.Unlock()
+
+	if affinityDebug {
+		log.Printf("[Affinity-Resource] 记录资源归属: %s -> 渠道[%d] (TTL: %v)", name, channelIndex, ttl)
+	}
+}
+
+// Get 获取资源归属，不存在或已过期时返回 false
+func (m *ResourceAffinityManager) Get(name string) (ResourceOwner, bool) {
+	if name == "" {
+		return ResourceOwner{}, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	owner, exists := m.owners[name]
+	if !exists || owner == nil || time.Now().After(owner.ExpiresAt) {
+		return ResourceOwner{}, false
+	}
+	return *owner, true
+}
+
+// Remove 移除资源归属记录（资源被删除时）
+func (m *ResourceAffinityManager) Remove(name string) {
+	m.mu.Lock()
+	delete(m.owners, name)
+	m.mu.Unlock()
+}
+
+// Cleanup 清理过期的资源记录
+func (m *ResourceAffinityManager) Cleanup() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cleanupLocked()
+}
+
+func (m *ResourceAffinityManager) cleanupLocked() int {
+	now := time.Now()
+	cleaned := 0
+	for name, owner := range m.owners {
+		if owner == nil || now.After(owner.ExpiresAt) {
+			delete(m.owners, name)
+			cleaned++
+		}
+	}
+	m.lastCleanup = now
+
+	if affinityDebug && cleaned > 0 {
+		log.Printf("[Affinity-Resource] 清理了 %d 条过期资源记录", cleaned)
+	}
+	return cleaned
+}
+
+// Size 返回当前资源记录数量
+func (m *ResourceAffinityManager) Size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.owners)
+}
@@ -24,6 +24,7 @@ type GeminiRequest struct {
 	Tools             []GeminiTool            `json:"tools,omitempty"`
 	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
 	SafetySettings    []GeminiSafetySetting   `json:"safetySettings,omitempty"`
+	CachedContent     string                  `json:"cachedContent,omitempty"` // 上下文缓存引用，如 cachedContents/xyz
 }
 
 // GeminiContent Gemini 内容
@@ -40,6 +41,10 @@ type GeminiPart struct {
 	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
 	FileData         *GeminiFileData         `json:"fileData,omitempty"`
 	Thought          bool                    `json:"thought,omitempty"` // 是否为 thinking 内容
+
+	// 代码执行（codeExecution 工具）生成的代码与执行结果
+	ExecutableCode      *GeminiExecutableCode      `json:"executableCode,omitempty"`
+	CodeExecutionResult *GeminiCodeExecutionResult `json:"codeExecutionResult,omitempty"`
 }
 
 // UnmarshalJSON 自定义反序列化，兼容部分客户端将 thoughtSignature 放在 part 层级的情况（而非 functionCall 内部）。
@@ -135,6 +140,18 @@ func (fc *GeminiFunctionCall) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// GeminiExecutableCode 模型生成并由服务端执行的代码
+type GeminiExecutableCode struct {
+	Language string `json:"language,omitempty"` // PYTHON
+	Code     string `json:"code"`
+}
+
+// GeminiCodeExecutionResult 代码执行结果
+type GeminiCodeExecutionResult struct {
+	Outcome string `json:"outcome,omitempty"` // OUTCOME_OK, OUTCOME_FAILED, OUTCOME_DEADLINE_EXCEEDED
+	Output  string `json:"output,omitempty"`
+}
+
 // GeminiFunctionResponse 函数响应
 type GeminiFunctionResponse struct {
 	Name     string                 `json:"name"`
@@ -142,8 +159,76 @@ type GeminiFunctionResponse struct {
 }
 
 // GeminiTool 工具定义
+// 同一条目可同时包含函数声明与服务端工具（googleSearch、codeExecution 等）
 type GeminiTool struct {
-	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+	FunctionDeclarations  []GeminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+	GoogleSearch          json.RawMessage             `json:"googleSearch,omitempty"`          // 联网搜索（Gemini 2.0+）
+	GoogleSearchRetrieval json.RawMessage             `json:"googleSearchRetrieval,omitempty"` // 联网搜索（Gemini 1.5）
+	CodeExecution         json.RawMessage             `json:"codeExecution,omitempty"`         // 代码执行
+	Others                map[string]json.RawMessage  `json:"-"`                               // 其他未建模的工具（urlContext 等），原样透传
+}
+
+// geminiToolFields GeminiTool 已建模字段（含 snake_case 别名）
+var geminiToolFields = map[string]string{
+	"functionDeclarations":    "functionDeclarations",
+	"function_declarations":   "functionDeclarations",
+	"googleSearch":            "googleSearch",
+	"google_search":           "googleSearch",
+	"googleSearchRetrieval":   "googleSearchRetrieval",
+	"google_search_retrieval": "googleSearchRetrieval",
+	"codeExecution":           "codeExecution",
+	"code_execution":          "codeExecution",
+}
+
+// UnmarshalJSON 自定义反序列化：兼容 snake_case 字段名，并保留未建模的工具
+func (t *GeminiTool) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*t = GeminiTool{}
+	for key, value := range raw {
+		switch geminiToolFields[key] {
+		case "functionDeclarations":
+			if err := json.Unmarshal(value, &t.FunctionDeclarations); err != nil {
+				return err
+			}
+		case "googleSearch":
+			t.GoogleSearch = value
+		case "googleSearchRetrieval":
+			t.GoogleSearchRetrieval = value
+		case "codeExecution":
+			t.CodeExecution = value
+		default:
+			if t.Others == nil {
+				t.Others = make(map[string]json.RawMessage)
+			}
+			t.Others[key] = value
+		}
+	}
+	return nil
+}
+
+// MarshalJSON 自定义序列化：输出已建模字段与透传的其他工具
+func (t GeminiTool) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(t.Others)+4)
+	for key, value := range t.Others {
+		out[key] = value
+	}
+	if len(t.FunctionDeclarations) > 0 {
+		out["functionDeclarations"] = t.FunctionDeclarations
+	}
+	if len(t.GoogleSearch) > 0 {
+		out["googleSearch"] = t.GoogleSearch
+	}
+	if len(t.GoogleSearchRetrieval) > 0 {
+		out["googleSearchRetrieval"] = t.GoogleSearchRetrieval
+	}
+	if len(t.CodeExecution) > 0 {
+		out["codeExecution"] = t.CodeExecution
+	}
+	return json.Marshal(out)
 }
 
 // GeminiFunctionDeclaration 函数声明
@@ -243,6 +328,8 @@ type GeminiGenerationConfig struct {
 	MaxOutputTokens    int                   `json:"maxOutputTokens,omitempty"`
 	StopSequences      []string              `json:"stopSequences,omitempty"`
 	ResponseMimeType   string                `json:"responseMimeType,omitempty"`   // "application/json" / "text/plain"
+	ResponseSchema     interface{}           `json:"responseSchema,omitempty"`     // 结构化输出 schema（OpenAPI 子集）
+	ResponseJsonSchema interface{}           `json:"responseJsonSchema,omitempty"` // 结构化输出 schema（JSON Schema）
 	ResponseModalities []string              `json:"responseModalities,omitempty"` // ["TEXT", "IMAGE", "AUDIO"]
 	ThinkingConfig     *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
 }
@@ -278,6 +365,41 @@ type GeminiCandidate struct {
 	FinishReason  string               `json:"finishReason,omitempty"` // "STOP", "MAX_TOKENS", "SAFETY", "RECITATION"
 	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
 	Index         int                  `json:"index,omitempty"`
+	// GroundingMetadata googleSearch 工具的搜索查询、来源与正文引用
+	GroundingMetadata *GeminiGroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+// GeminiGroundingMetadata 联网搜索依据
+type GeminiGroundingMetadata struct {
+	WebSearchQueries  []string                 `json:"webSearchQueries,omitempty"`
+	GroundingChunks   []GeminiGroundingChunk   `json:"groundingChunks,omitempty"`
+	GroundingSupports []GeminiGroundingSupport `json:"groundingSupports,omitempty"`
+	SearchEntryPoint  map[string]interface{}   `json:"searchEntryPoint,omitempty"`
+}
+
+// GeminiGroundingChunk 搜索来源
+type GeminiGroundingChunk struct {
+	Web *GeminiGroundingWeb `json:"web,omitempty"`
+}
+
+// GeminiGroundingWeb 网页来源
+type GeminiGroundingWeb struct {
+	URI   string `json:"uri"`
+	Title string `json:"title,omitempty"`
+}
+
+// GeminiGroundingSupport 正文片段与来源的对应关系
+type GeminiGroundingSupport struct {
+	Segment               GeminiSegment `json:"segment"`
+	GroundingChunkIndices []int         `json:"groundingChunkIndices,omitempty"`
+}
+
+// GeminiSegment 正文片段（startIndex/endIndex 为 UTF-8 字节偏移）
+type GeminiSegment struct {
+	PartIndex  int    `json:"partIndex,omitempty"`
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex"`
+	Text       string `json:"text,omitempty"`
 }
 
 // GeminiPromptFeedback 提示反馈
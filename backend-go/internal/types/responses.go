@@ -10,6 +10,7 @@ type ResponsesRequest struct {
 	PreviousResponseID string                 `json:"previous_response_id,omitempty"`
 	Store              *bool                  `json:"store,omitempty"`             // 默认 true
 	MaxTokens          int                    `json:"max_tokens,omitempty"`        // 最大 tokens
+	MaxOutputTokens    int                    `json:"max_output_tokens,omitempty"` // 最大输出 tokens（Responses 官方字段）
 	Temperature        float64                `json:"temperature,omitempty"`       // 温度参数
 	TopP               float64                `json:"top_p,omitempty"`             // top_p 参数
 	FrequencyPenalty   float64                `json:"frequency_penalty,omitempty"` // 频率惩罚
@@ -20,6 +21,9 @@ type ResponsesRequest struct {
 	StreamOptions      interface{}            `json:"stream_options,omitempty"`    // 流式选项
 	Reasoning          map[string]interface{} `json:"reasoning,omitempty"`
 	ReasoningEffort    string                 `json:"reasoning_effort,omitempty"`
+	Tools              []interface{}          `json:"tools,omitempty"`       // 工具定义（function 等）
+	ToolChoice         interface{}            `json:"tool_choice,omitempty"` // 工具选择策略
+	Text               map[string]interface{} `json:"text,omitempty"`        // 文本输出配置（text.format 结构化输出）
 
 	// TransformerMetadata 转换器元数据（仅内存使用，不序列化）
 	// 用于在单次请求的转换流程中保留原始格式信息，如 system 数组格式等
@@ -29,16 +33,32 @@ type ResponsesRequest struct {
 
 // ResponsesItem Responses API 消息项
 type ResponsesItem struct {
-	Type    string      `json:"type"`           // message, text, tool_call, tool_result
+	Type    string      `json:"type"`           // message, text, tool_call, tool_result, reasoning, function_call
 	Role    string      `json:"role,omitempty"` // user, assistant (用于 type=message)
 	Content interface{} `json:"content"`        // string 或 []ContentBlock
 	ToolUse *ToolUse    `json:"tool_use,omitempty"`
+
+	// function_call / function_call_output / reasoning 字段
+	ID        string         `json:"id,omitempty"`
+	Status    string         `json:"status,omitempty"`
+	CallID    string         `json:"call_id,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Arguments string         `json:"arguments,omitempty"`
+	Output    interface{}    `json:"output,omitempty"`
+	Summary   []ContentBlock `json:"summary,omitempty"`
+
+	// web_search_call / code_interpreter_call 字段
+	Action      map[string]interface{} `json:"action,omitempty"`
+	Code        string                 `json:"code,omitempty"`
+	ContainerID string                 `json:"container_id,omitempty"`
+	Outputs     []interface{}          `json:"outputs,omitempty"`
 }
 
 // ContentBlock 内容块（用于嵌套 content 数组）
 type ContentBlock struct {
-	Type string `json:"type"` // input_text, output_text
-	Text string `json:"text"`
+	Type        string        `json:"type"` // input_text, output_text, summary_text
+	Text        string        `json:"text"`
+	Annotations []interface{} `json:"annotations,omitempty"` // output_text 引用（url_citation）
 }
 
 // ToolUse 工具使用定义
@@ -2,14 +2,22 @@
 
 // ClaudeRequest Claude 请求结构
 type ClaudeRequest struct {
-	Model       string                 `json:"model"`
-	Messages    []ClaudeMessage        `json:"messages"`
-	System      interface{}            `json:"system,omitempty"` // string 或 content 数组
-	MaxTokens   int                    `json:"max_tokens,omitempty"`
-	Temperature float64                `json:"temperature,omitempty"`
-	Stream      bool                   `json:"stream,omitempty"`
-	Tools       []ClaudeTool           `json:"tools,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"` // Claude Code CLI 等客户端发送的元数据
+	Model        string                 `json:"model"`
+	Messages     []ClaudeMessage        `json:"messages"`
+	System       interface{}            `json:"system,omitempty"` // string 或 content 数组
+	MaxTokens    int                    `json:"max_tokens,omitempty"`
+	Temperature  float64                `json:"temperature,omitempty"`
+	Stream       bool                   `json:"stream,omitempty"`
+	Tools        []ClaudeTool           `json:"tools,omitempty"`
+	Thinking     *ClaudeThinking        `json:"thinking,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`      // Claude Code CLI 等客户端发送的元数据
+	OutputFormat map[string]interface{} `json:"output_format,omitempty"` // 结构化输出（json_schema）
+}
+
+// ClaudeThinking Claude 扩展思考配置
+type ClaudeThinking struct {
+	Type         string `json:"type"` // enabled, disabled
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
 }
 
 // ClaudeMessage Claude 消息
@@ -22,25 +30,35 @@ type ClaudeMessage struct {
 // 用于 Claude API 请求，会序列化到 JSON（仅在发送给 Anthropic 时有效）
 type CacheControl struct {
 	Type string `json:"type,omitempty"` // "ephemeral"
+	TTL  string `json:"ttl,omitempty"`  // "5m"（默认）| "1h"
 }
 
 // ClaudeContent Claude 内容块
 type ClaudeContent struct {
-	Type         string        `json:"type"` // text, tool_use, tool_result
+	Type         string        `json:"type"` // text, thinking, tool_use, tool_result, server_tool_use, web_search_tool_result, code_execution_tool_result
 	Text         string        `json:"text,omitempty"`
+	Thinking     string        `json:"thinking,omitempty"`
 	ID           string        `json:"id,omitempty"`
 	Name         string        `json:"name,omitempty"`
 	Input        interface{}   `json:"input,omitempty"`
 	ToolUseID    string        `json:"tool_use_id,omitempty"`
+	Content      interface{}   `json:"content,omitempty"`   // 服务端工具结果（web_search_tool_result 等）
+	Citations    []interface{} `json:"citations,omitempty"` // 文本引用（web_search_result_location 等）
 	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 // ClaudeTool Claude 工具定义
+// Type 为空或 custom 时为自定义函数工具；其余为服务端工具（如 web_search_20250305）
 type ClaudeTool struct {
-	Name         string        `json:"name"`
-	Description  string        `json:"description,omitempty"`
-	InputSchema  interface{}   `json:"input_schema"`
-	CacheControl *CacheControl `json:"cache_control,omitempty"`
+	Type           string                 `json:"type,omitempty"`
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description,omitempty"`
+	InputSchema    interface{}            `json:"input_schema,omitempty"`
+	MaxUses        int                    `json:"max_uses,omitempty"`        // web_search: 最大搜索次数
+	AllowedDomains []string               `json:"allowed_domains,omitempty"` // web_search: 域名白名单
+	BlockedDomains []string               `json:"blocked_domains,omitempty"` // web_search: 域名黑名单
+	UserLocation   map[string]interface{} `json:"user_location,omitempty"`   // web_search: 用户位置
+	CacheControl   *CacheControl          `json:"cache_control,omitempty"`
 }
 
 // ClaudeResponse Claude 响应
@@ -62,14 +80,19 @@ type OpenAIRequest struct {
 	Stream              bool            `json:"stream,omitempty"`
 	Tools               []OpenAITool    `json:"tools,omitempty"`
 	ToolChoice          string          `json:"tool_choice,omitempty"`
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"`
+	ResponseFormat      interface{}     `json:"response_format,omitempty"`
+	WebSearchOptions    interface{}     `json:"web_search_options,omitempty"` // 联网搜索（search 模型）
 }
 
 // OpenAIMessage OpenAI 消息
 type OpenAIMessage struct {
-	Role       string           `json:"role"`
-	Content    interface{}      `json:"content"` // string 或 null
-	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string           `json:"tool_call_id,omitempty"`
+	Role             string           `json:"role"`
+	Content          interface{}      `json:"content"` // string 或 null
+	ToolCalls        []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"` // 推理内容（DeepSeek 等兼容上游在响应中返回）
+	Annotations      []interface{}    `json:"annotations,omitempty"`       // 联网搜索引用（url_citation）
 }
 
 // OpenAIToolCall OpenAI 工具调用
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials AWS 访问凭证
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // 临时凭证（STS）时使用
+}
+
+const awsSigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// AWSURIEncode 按 SigV4 规则编码（仅保留 A-Z a-z 0-9 - _ . ~ 不编码）
+func AWSURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// SignAWSRequestV4 使用 AWS Signature Version 4 为请求签名
+// 签名覆盖 host、x-amz-date、content-type（若存在）与 x-amz-security-token（临时凭证），
+// 会设置 X-Amz-Date / Authorization 等头部；body 需与实际发送的请求体一致
+func SignAWSRequestV4(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// 1. 规范请求
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	signedHeaderValues := map[string]string{
+		"host":       host,
+		"x-amz-date": amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		signedHeaderValues["content-type"] = ct
+	}
+	if creds.SessionToken != "" {
+		signedHeaderValues["x-amz-security-token"] = creds.SessionToken
+	}
+	headerNames := make([]string, 0, len(signedHeaderValues))
+	for name := range signedHeaderValues {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signedHeaderValues[name]) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL.EscapedPath()),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	// 2. 待签名字符串
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{awsSigV4Algorithm, amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	// 3. 派生签名密钥并计算签名
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsCanonicalURI 对已编码的路径逐段再次编码（非 S3 服务的 SigV4 规则）
+func awsCanonicalURI(escapedPath string) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		segments[i] = AWSURIEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery 构建规范查询字符串（按键名、键值排序）
+func awsCanonicalQuery(query map[string][]string) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, AWSURIEncode(key)+"="+AWSURIEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
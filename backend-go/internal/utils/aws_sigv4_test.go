@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 使用 AWS 官方文档中的签名示例（IAM ListUsers）验证签名算法
+func TestSignAWSRequestV4_OfficialExample(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	SignAWSRequestV4(req, nil, AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Fatalf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSignAWSRequestV4_SessionTokenAndEscapedPath(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-west-2.amazonaws.com/model/x/invoke", nil)
+	req.URL.Path = "/model/us.anthropic.claude-v2:1/invoke"
+	req.URL.RawPath = "/model/us.anthropic.claude-v2%3A1/invoke"
+
+	if got := awsCanonicalURI(req.URL.EscapedPath()); got != "/model/us.anthropic.claude-v2%253A1/invoke" {
+		t.Fatalf("canonical uri = %q", got)
+	}
+
+	SignAWSRequestV4(req, []byte(`{}`), AWSCredentials{AccessKeyID: "AK", SecretAccessKey: "SK", SessionToken: "TOKEN"},
+		"us-west-2", "bedrock", time.Now())
+	if req.Header.Get("X-Amz-Security-Token") != "TOKEN" {
+		t.Fatalf("missing session token header")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("unexpected Authorization: %s", auth)
+	}
+}
@@ -70,6 +70,14 @@ func SetGeminiAuthenticationHeader(headers http.Header, apiKey string) {
 	headers.Set("x-goog-api-key", apiKey)
 }
 
+// SetAzureAuthenticationHeader 设置 Azure OpenAI 认证头部（api-key）
+func SetAzureAuthenticationHeader(headers http.Header, apiKey string) {
+	headers.Del("authorization")
+	headers.Del("x-api-key")
+	headers.Del("x-goog-api-key")
+	headers.Set("api-key", apiKey)
+}
+
 // EnsureCompatibleUserAgent 确保兼容的User-Agent（仅在必要时设置）
 func EnsureCompatibleUserAgent(headers http.Header, serviceType string) {
 	userAgent := headers.Get("User-Agent")
@@ -156,6 +156,21 @@ func TestSetGeminiAuthenticationHeader(t *testing.T) {
 	}
 }
 
+func TestSetAzureAuthenticationHeader(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer client-token")
+	headers.Set("x-api-key", "client-key")
+
+	SetAzureAuthenticationHeader(headers, "azure-key")
+
+	if got := headers.Get("api-key"); got != "azure-key" {
+		t.Errorf("api-key = %v, want azure-key", got)
+	}
+	if headers.Get("authorization") != "" || headers.Get("x-api-key") != "" {
+		t.Errorf("client auth headers should be removed, got %v", headers)
+	}
+}
+
 func TestEnsureCompatibleUserAgent(t *testing.T) {
 	tests := []struct {
 		name            string
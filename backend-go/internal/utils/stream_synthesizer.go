@@ -74,12 +74,14 @@ func (s *StreamSynthesizer) ProcessLine(line string) {
 	switch s.serviceType {
 	case "gemini":
 		s.processGemini(data)
-	case "openai":
+	case "openai", "azure-openai":
 		s.processOpenAI(data)
 	case "claude":
 		s.processClaude(data)
 	case "responses":
 		s.processResponses(data)
+	case "completions":
+		s.processCompletions(data)
 	}
 }
 
@@ -302,6 +304,19 @@ func (s *StreamSynthesizer) processOpenAI(data map[string]interface{}) {
 	}
 }
 
+// processCompletions 处理OpenAI Completions格式（choices[].text）
+func (s *StreamSynthesizer) processCompletions(data map[string]interface{}) {
+	choices, ok := data["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return
+	}
+	if choice, ok := choices[0].(map[string]interface{}); ok {
+		if text, ok := choice["text"].(string); ok {
+			s.synthesizedContent.WriteString(text)
+		}
+	}
+}
+
 // processClaude 处理Claude格式
 func (s *StreamSynthesizer) processClaude(data map[string]interface{}) {
 	eventType, _ := data["type"].(string)
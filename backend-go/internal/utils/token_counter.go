@@ -327,3 +327,79 @@ func estimateResponsesItemTokens(item types.ResponsesItem) int {
 
 	return total
 }
+
+// ============== Gemini API Token 估算 ==============
+
+// EstimateGeminiRequestTokens 从 Gemini 请求体估算输入 token
+// 支持 generateContent 请求体与 countTokens 请求体（contents 或 generateContentRequest）
+func EstimateGeminiRequestTokens(bodyBytes []byte) int {
+	if len(bodyBytes) == 0 {
+		return 0
+	}
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return EstimateTokens(string(bodyBytes))
+	}
+
+	// countTokens 支持包装完整的 generateContentRequest
+	if inner, ok := req["generateContentRequest"].(map[string]interface{}); ok {
+		req = inner
+	}
+
+	total := 0
+
+	if system, ok := req["systemInstruction"].(map[string]interface{}); ok {
+		total += estimateGeminiPartsTokens(system["parts"])
+	}
+
+	if contents, ok := req["contents"].([]interface{}); ok {
+		for _, item := range contents {
+			if content, ok := item.(map[string]interface{}); ok {
+				// 每条消息额外开销约 4 tokens
+				total += 4 + estimateGeminiPartsTokens(content["parts"])
+			}
+		}
+	}
+
+	// tools (每个函数声明约 100-200 tokens)
+	if tools, ok := req["tools"].([]interface{}); ok {
+		for _, tool := range tools {
+			if t, ok := tool.(map[string]interface{}); ok {
+				if decls, ok := t["functionDeclarations"].([]interface{}); ok {
+					total += len(decls) * 150
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+// estimateGeminiPartsTokens 估算 Gemini parts 数组的 token
+func estimateGeminiPartsTokens(parts interface{}) int {
+	arr, ok := parts.([]interface{})
+	if !ok {
+		return 0
+	}
+
+	total := 0
+	for _, p := range arr {
+		part, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if text, ok := part["text"].(string); ok {
+			total += EstimateTokens(text)
+			continue
+		}
+		// functionCall / functionResponse 序列化后估算；inlineData 等二进制内容不计入
+		for _, key := range []string{"functionCall", "functionResponse"} {
+			if v, ok := part[key]; ok {
+				data, _ := json.Marshal(v)
+				total += EstimateTokens(string(data))
+			}
+		}
+	}
+	return total
+}
@@ -223,3 +223,24 @@ func TestEstimateRequestTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestEstimateGeminiRequestTokens(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog"
+	plain := `{"contents":[{"role":"user","parts":[{"text":"` + text + `"}]}]}`
+	wrapped := `{"generateContentRequest":{"model":"models/gemini-pro","contents":[{"role":"user","parts":[{"text":"` + text + `"}]}]}}`
+	withSystem := `{"systemInstruction":{"parts":[{"text":"` + text + `"}]},"contents":[{"role":"user","parts":[{"text":"` + text + `"}]}]}`
+
+	base := EstimateGeminiRequestTokens([]byte(plain))
+	if base != EstimateTokens(text)+4 {
+		t.Fatalf("plain = %d, want %d", base, EstimateTokens(text)+4)
+	}
+	if got := EstimateGeminiRequestTokens([]byte(wrapped)); got != base {
+		t.Fatalf("wrapped = %d, want %d", got, base)
+	}
+	if got := EstimateGeminiRequestTokens([]byte(withSystem)); got != base+EstimateTokens(text) {
+		t.Fatalf("withSystem = %d, want %d", got, base+EstimateTokens(text))
+	}
+	if got := EstimateGeminiRequestTokens(nil); got != 0 {
+		t.Fatalf("empty = %d", got)
+	}
+}
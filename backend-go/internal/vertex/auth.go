@@ -0,0 +1,243 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BenedictKing/claude-proxy/internal/httpclient"
+)
+
+// ============== Service Account OAuth2（JWT-bearer 授权） ==============
+//
+// 渠道的 API 密钥为 Google 服务账号 JSON，代理使用其私钥签发 JWT，
+// 向 token 端点换取 access token 并缓存至过期前。
+
+const (
+	// DefaultTokenURL Google OAuth2 token 端点
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+	// cloudPlatformScope Vertex AI 所需的 OAuth2 scope
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// assertionLifetime JWT 断言有效期（Google 上限为 1 小时）
+	assertionLifetime = time.Hour
+	// tokenRefreshSkew 提前刷新时间，避免请求途中 token 过期
+	tokenRefreshSkew    = 5 * time.Minute
+	tokenRequestTimeout = 30 * time.Second
+)
+
+// ServiceAccount Google 服务账号凭证（仅解析所需字段）
+type ServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// ParseServiceAccount 解析服务账号 JSON
+func ParseServiceAccount(raw string) (*ServiceAccount, error) {
+	var sa ServiceAccount
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &sa); err != nil {
+		return nil, fmt.Errorf("Vertex 密钥应为服务账号 JSON: %w", err)
+	}
+	if sa.Type != "" && sa.Type != "service_account" {
+		return nil, fmt.Errorf("不支持的凭证类型: %s", sa.Type)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, errors.New("服务账号 JSON 缺少 client_email 或 private_key")
+	}
+	if sa.ProjectID == "" {
+		return nil, errors.New("服务账号 JSON 缺少 project_id")
+	}
+	return &sa, nil
+}
+
+var (
+	tokenURLOverrideMu sync.RWMutex
+	tokenURLOverride   string
+)
+
+// SetTokenURLOverride 设置全局 token 端点（优先于服务账号 JSON 中的 token_uri，空值表示不覆盖）
+func SetTokenURLOverride(tokenURL string) {
+	tokenURLOverrideMu.Lock()
+	defer tokenURLOverrideMu.Unlock()
+	tokenURLOverride = strings.TrimSpace(tokenURL)
+}
+
+// tokenURL 返回换取 token 使用的端点：全局覆盖 > token_uri > 默认端点
+func (sa *ServiceAccount) tokenURL() string {
+	tokenURLOverrideMu.RLock()
+	override := tokenURLOverride
+	tokenURLOverrideMu.RUnlock()
+	if override != "" {
+		return override
+	}
+	if sa.TokenURI != "" {
+		return sa.TokenURI
+	}
+	return DefaultTokenURL
+}
+
+// cachedToken 单个服务账号的 token 缓存
+type cachedToken struct {
+	mu        sync.Mutex // 串行化同一账号的刷新，避免并发重复换取
+	token     string
+	expiresAt time.Time
+}
+
+var (
+	tokenCacheMu sync.Mutex
+	tokenCache   = make(map[string]*cachedToken)
+	// now 时间来源（测试时可替换）
+	now = time.Now
+)
+
+// AccessToken 返回服务账号的 access token（命中缓存时直接返回）
+func AccessToken(ctx context.Context, rawServiceAccount string) (string, error) {
+	sa, err := ParseServiceAccount(rawServiceAccount)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(sa.ClientEmail + "\x00" + sa.PrivateKeyID + "\x00" + sa.tokenURL()))
+	cacheKey := hex.EncodeToString(sum[:])
+
+	tokenCacheMu.Lock()
+	entry, ok := tokenCache[cacheKey]
+	if !ok {
+		entry = &cachedToken{}
+		tokenCache[cacheKey] = entry
+	}
+	tokenCacheMu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.token != "" && now().Add(tokenRefreshSkew).Before(entry.expiresAt) {
+		return entry.token, nil
+	}
+
+	token, expiresIn, err := fetchAccessToken(ctx, sa)
+	if err != nil {
+		return "", err
+	}
+	entry.token = token
+	entry.expiresAt = now().Add(expiresIn)
+	return token, nil
+}
+
+// fetchAccessToken 通过 JWT-bearer 授权换取 access token
+func fetchAccessToken(ctx context.Context, sa *ServiceAccount) (string, time.Duration, error) {
+	tokenURL := sa.tokenURL()
+	assertion, err := signJWTAssertion(sa, tokenURL, now())
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtBearerGrantType)
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("创建 token 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpclient.GetManager().GetStandardClient(tokenRequestTimeout, false).Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("请求 token 端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("读取 token 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token 端点返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("解析 token 响应失败: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, errors.New("token 响应缺少 access_token")
+	}
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = assertionLifetime
+	}
+	return tokenResp.AccessToken, expiresIn, nil
+}
+
+// signJWTAssertion 使用服务账号私钥签发 RS256 JWT 断言
+func signJWTAssertion(sa *ServiceAccount, audience string, issuedAt time.Time) (string, error) {
+	key, err := parsePrivateKey(sa.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if sa.PrivateKeyID != "" {
+		header["kid"] = sa.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": cloudPlatformScope,
+		"aud":   audience,
+		"iat":   issuedAt.Unix(),
+		"exp":   issuedAt.Add(assertionLifetime).Unix(),
+	}
+
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("签名 JWT 失败: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey 解析 PEM 格式的 RSA 私钥（PKCS#8 或 PKCS#1）
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("服务账号 private_key 不是合法的 PEM")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("服务账号 private_key 不是 RSA 私钥")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析服务账号 private_key 失败: %w", err)
+	}
+	return key, nil
+}
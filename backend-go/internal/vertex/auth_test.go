@@ -0,0 +1,152 @@
+package vertex
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServiceAccount 生成测试用服务账号 JSON 及其公钥
+func newTestServiceAccount(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	sa := map[string]string{
+		"type":           "service_account",
+		"project_id":     "demo-project",
+		"private_key_id": "kid-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "proxy@demo-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	}
+	raw, _ := json.Marshal(sa)
+	return string(raw), &key.PublicKey
+}
+
+// newTokenServer 启动 token 端点替身，校验 JWT 断言并返回 access token
+func newTokenServer(t *testing.T, pub **rsa.PublicKey, hits *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != jwtBearerGrantType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(*pub, crypto.SHA256, digest[:], sig); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims map[string]interface{}
+		_ = json.Unmarshal(claimsJSON, &claims)
+		if claims["iss"] != "proxy@demo-project.iam.gserviceaccount.com" || claims["scope"] != cloudPlatformScope ||
+			claims["aud"] != "http://"+r.Host+r.URL.Path {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"bad claims"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"ya29.token-%d","expires_in":3600,"token_type":"Bearer"}`, atomic.LoadInt32(hits))
+	}))
+}
+
+func TestAccessToken_MintsAndCaches(t *testing.T) {
+	var hits int32
+	var pub *rsa.PublicKey
+	srv := newTokenServer(t, &pub, &hits)
+	defer srv.Close()
+
+	raw, key := newTestServiceAccount(t, srv.URL+"/token")
+	pub = key
+
+	base := time.Now()
+	now = func() time.Time { return base }
+	defer func() { now = time.Now }()
+
+	token, err := AccessToken(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("AccessToken: %v", err)
+	}
+	if token != "ya29.token-1" {
+		t.Fatalf("token=%q", token)
+	}
+
+	if token, _ = AccessToken(context.Background(), raw); token != "ya29.token-1" || atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected cached token, got %q after %d hits", token, hits)
+	}
+
+	// 临近过期（剩余时间小于刷新提前量）时重新换取
+	now = func() time.Time { return base.Add(56 * time.Minute) }
+	if token, _ = AccessToken(context.Background(), raw); token != "ya29.token-2" || atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("expected refreshed token, got %q after %d hits", token, hits)
+	}
+}
+
+func TestAccessToken_TokenURLOverride(t *testing.T) {
+	var hits int32
+	var pub *rsa.PublicKey
+	srv := newTokenServer(t, &pub, &hits)
+	defer srv.Close()
+
+	raw, key := newTestServiceAccount(t, "http://127.0.0.1:1/unreachable")
+	pub = key
+
+	SetTokenURLOverride(srv.URL + "/override")
+	defer SetTokenURLOverride("")
+
+	if _, err := AccessToken(context.Background(), raw); err != nil {
+		t.Fatalf("AccessToken: %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("override endpoint not used")
+	}
+}
+
+func TestAccessToken_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer srv.Close()
+
+	raw, _ := newTestServiceAccount(t, srv.URL+"/token")
+	if _, err := AccessToken(context.Background(), raw); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("expected token endpoint error, got %v", err)
+	}
+
+	for _, bad := range []string{"sk-plain-key", `{"type":"authorized_user"}`, `{"client_email":"a","private_key":"b"}`} {
+		if _, err := AccessToken(context.Background(), bad); err == nil {
+			t.Fatalf("expected parse error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package vertex 提供 Google Vertex AI 渠道的认证与端点构建
+package vertex
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// AnthropicVersion Vertex 上 Claude 模型要求的 anthropic_version
+	AnthropicVersion = "vertex-2023-10-16"
+	// DefaultLocation 无法从 baseURL 推断区域时使用的默认区域
+	DefaultLocation = "us-central1"
+
+	PublisherGoogle    = "google"
+	PublisherAnthropic = "anthropic"
+)
+
+// PublisherForModel 根据模型名判断发布方（claude-* 为 anthropic，其余为 google）
+func PublisherForModel(model string) string {
+	if strings.HasPrefix(strings.ToLower(model), "claude") {
+		return PublisherAnthropic
+	}
+	return PublisherGoogle
+}
+
+// LocationFromBaseURL 从 baseURL 推断区域
+// {location}-aiplatform.googleapis.com → location；aiplatform.googleapis.com → global；其他 → 默认区域
+func LocationFromBaseURL(baseURL string) string {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "#"))
+	if err != nil {
+		return DefaultLocation
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "aiplatform.googleapis.com" {
+		return "global"
+	}
+	if idx := strings.Index(host, "-aiplatform."); idx > 0 {
+		return host[:idx]
+	}
+	return DefaultLocation
+}
+
+// ModelAction 返回发布方对应的调用方法
+// google: generateContent / streamGenerateContent?alt=sse
+// anthropic: rawPredict / streamRawPredict
+func ModelAction(publisher string, stream bool) string {
+	if publisher == PublisherAnthropic {
+		if stream {
+			return "streamRawPredict"
+		}
+		return "rawPredict"
+	}
+	if stream {
+		return "streamGenerateContent?alt=sse"
+	}
+	return "generateContent"
+}
+
+// BuildModelURL 构建模型调用 URL
+// {baseURL}/v1/projects/{project}/locations/{location}/publishers/{publisher}/models/{model}:{action}
+func BuildModelURL(baseURL, project, publisher, model string, stream bool) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(baseURL, "#"), "/")
+	base = strings.TrimSuffix(base, "/v1")
+	return fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/%s/models/%s:%s",
+		base, url.PathEscape(project), LocationFromBaseURL(baseURL), publisher, url.PathEscape(model), ModelAction(publisher, stream))
+}
@@ -0,0 +1,61 @@
+package vertex
+
+import "testing"
+
+func TestBuildModelURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		publisher string
+		model     string
+		stream    bool
+		want      string
+	}{
+		{
+			name:      "regional gemini",
+			baseURL:   "https://europe-west4-aiplatform.googleapis.com",
+			publisher: PublisherGoogle,
+			model:     "gemini-2.5-pro",
+			want:      "https://europe-west4-aiplatform.googleapis.com/v1/projects/p1/locations/europe-west4/publishers/google/models/gemini-2.5-pro:generateContent",
+		},
+		{
+			name:      "regional gemini stream",
+			baseURL:   "https://us-central1-aiplatform.googleapis.com/v1/",
+			publisher: PublisherGoogle,
+			model:     "gemini-2.5-flash",
+			stream:    true,
+			want:      "https://us-central1-aiplatform.googleapis.com/v1/projects/p1/locations/us-central1/publishers/google/models/gemini-2.5-flash:streamGenerateContent?alt=sse",
+		},
+		{
+			name:      "global claude stream",
+			baseURL:   "https://aiplatform.googleapis.com",
+			publisher: PublisherAnthropic,
+			model:     "claude-sonnet-4@20250514",
+			stream:    true,
+			want:      "https://aiplatform.googleapis.com/v1/projects/p1/locations/global/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict",
+		},
+		{
+			name:      "custom host falls back to default location",
+			baseURL:   "http://127.0.0.1:9000",
+			publisher: PublisherAnthropic,
+			model:     "claude-3-5-haiku",
+			want:      "http://127.0.0.1:9000/v1/projects/p1/locations/us-central1/publishers/anthropic/models/claude-3-5-haiku:rawPredict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildModelURL(tt.baseURL, "p1", tt.publisher, tt.model, tt.stream); got != tt.want {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisherForModel(t *testing.T) {
+	if PublisherForModel("Claude-opus-4@20250514") != PublisherAnthropic {
+		t.Fatalf("claude models should use anthropic publisher")
+	}
+	if PublisherForModel("gemini-2.5-pro") != PublisherGoogle {
+		t.Fatalf("gemini models should use google publisher")
+	}
+}
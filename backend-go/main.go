@@ -11,10 +11,14 @@
 	"syscall"
 	"time"
 
+	"github.com/BenedictKing/claude-proxy/internal/batch"
 	"github.com/BenedictKing/claude-proxy/internal/billing"
 	"github.com/BenedictKing/claude-proxy/internal/cache"
 	"github.com/BenedictKing/claude-proxy/internal/config"
+	"github.com/BenedictKing/claude-proxy/internal/converters"
 	"github.com/BenedictKing/claude-proxy/internal/handlers"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/chat"
+	"github.com/BenedictKing/claude-proxy/internal/handlers/embeddings"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/gemini"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/messages"
 	"github.com/BenedictKing/claude-proxy/internal/handlers/responses"
@@ -26,6 +30,7 @@
 	"github.com/BenedictKing/claude-proxy/internal/scheduler"
 	"github.com/BenedictKing/claude-proxy/internal/session"
 	"github.com/BenedictKing/claude-proxy/internal/usage"
+	"github.com/BenedictKing/claude-proxy/internal/vertex"
 	"github.com/BenedictKing/claude-proxy/internal/warmup"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -66,6 +71,12 @@ func main() {
 	}
 	defer cfgManager.Close()
 
+	// 跨协议思考深度映射使用全局思考预算表（配置热更新实时生效）
+	converters.SetReasoningBudgetsSource(cfgManager.GetGlobalReasoningBudgets)
+
+	// Vertex 渠道的服务账号 token 端点（用于私有部署或本地替身）
+	vertex.SetTokenURLOverride(envCfg.VertexTokenURL)
+
 	// 初始化会话管理器（Responses API 专用）
 	sessionManager := session.NewSessionManager(
 		24*time.Hour, // 24小时过期
@@ -78,25 +89,27 @@ func main() {
 	metricsRetention := time.Duration(envCfg.MetricsRetentionDays) * 24 * time.Hour
 	keyCircuitLogStore := metrics.NewMemoryKeyCircuitLogStore(metricsRetention)
 
-	// 初始化多渠道调度器（Messages、Responses、Gemini 使用独立的指标管理器）
-	var messagesMetricsManager, responsesMetricsManager, geminiMetricsManager *metrics.MetricsManager
+	// 初始化多渠道调度器（Messages、Responses、Gemini、Embeddings 使用独立的指标管理器）
+	var messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, embeddingsMetricsManager *metrics.MetricsManager
 	messagesMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 	responsesMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 	geminiMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
+	embeddingsMetricsManager = metrics.NewMetricsManagerWithConfig(envCfg.MetricsWindowSize, envCfg.MetricsFailureThreshold)
 	messagesMetricsManager.SetRetentionDays(envCfg.MetricsRetentionDays)
 	responsesMetricsManager.SetRetentionDays(envCfg.MetricsRetentionDays)
 	geminiMetricsManager.SetRetentionDays(envCfg.MetricsRetentionDays)
+	embeddingsMetricsManager.SetRetentionDays(envCfg.MetricsRetentionDays)
 	traceAffinityManager := session.NewTraceAffinityManager()
 
 	// 初始化 URL 管理器（非阻塞，动态排序）
 	urlManager := warmup.NewURLManager(30*time.Second, 3) // 30秒冷却期，连续3次失败后移到末尾
 	log.Printf("[URLManager-Init] URL管理器已初始化 (冷却期: 30秒, 最大连续失败: 3)")
 
-	channelScheduler := scheduler.NewChannelScheduler(cfgManager, messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, traceAffinityManager, urlManager)
+	channelScheduler := scheduler.NewChannelScheduler(cfgManager, messagesMetricsManager, responsesMetricsManager, geminiMetricsManager, embeddingsMetricsManager, traceAffinityManager, urlManager)
 	log.Printf("[Scheduler-Init] 多渠道调度器已初始化 (失败率阈值: %.0f%%, 滑动窗口: %d)",
 		messagesMetricsManager.GetFailureThreshold()*100, messagesMetricsManager.GetWindowSize())
 
-	// 初始化 /v1/models 响应缓存（模型列表变化频率低，使用较长 TTL）
+	// 初始化 /v1/models、/v1beta/models 响应缓存（模型列表变化频率低，使用较长 TTL）
 	modelsCacheMetrics := &metrics.CacheMetrics{}
 	modelsResponseCache := cache.NewHTTPResponseCache(200, 10*time.Minute, modelsCacheMetrics)
 
@@ -117,6 +130,10 @@ func main() {
 	}
 	pricingService := pricing.NewService(pricingInterval)
 	log.Printf("[Pricing-Init] 价格表服务已初始化 (更新间隔: %s)", pricingInterval)
+	// cheapest 负载均衡模式按价格表估算各渠道成本
+	channelScheduler.SetCostEstimator(pricingService)
+	// 渠道/Key maxConcurrency 已满时的排队参数
+	channelScheduler.SetConcurrencyQueue(envCfg.ConcurrencyQueueSize, time.Duration(envCfg.ConcurrencyQueueTimeout)*time.Second)
 
 	if envCfg.IsBillingEnabled() {
 		billingClient = billing.NewClient(envCfg.SweAgentBillingURL)
@@ -163,10 +180,11 @@ func main() {
 		messagesAPI := apiGroup.Group("/messages")
 		responsesAPI := apiGroup.Group("/responses")
 		geminiAPI := apiGroup.Group("/gemini")
+		embeddingsAPI := apiGroup.Group("/embeddings")
 		adminAPI := apiGroup.Group("/admin")
 
 		// 上游探测工具（用于管理台辅助配置）
-		adminAPI.POST("/upstream/models", handlers.ProbeUpstreamModels())
+		adminAPI.POST("/upstream/models", handlers.ProbeUpstreamModels(cfgManager))
 
 		// Messages 渠道管理
 		apiGroup.GET("/messages/channels", messages.GetUpstreams(cfgManager))
@@ -183,6 +201,7 @@ func main() {
 		apiGroup.PATCH("/messages/channels/:id/status", messages.SetChannelStatus(cfgManager))
 		apiGroup.POST("/messages/channels/:id/resume", handlers.ResumeChannel(channelScheduler, false))
 		apiGroup.POST("/messages/channels/:id/promotion", messages.SetChannelPromotion(cfgManager))
+		apiGroup.PUT("/loadbalance", messages.UpdateLoadBalance(cfgManager))
 		apiGroup.GET("/messages/channels/metrics", handlers.GetChannelMetricsWithConfig(messagesMetricsManager, cfgManager, false, requestLogStore))
 		apiGroup.GET("/messages/channels/metrics/history", handlers.GetChannelMetricsHistory(messagesMetricsManager, cfgManager, false))
 		apiGroup.GET("/messages/channels/:id/keys/metrics/history", handlers.GetChannelKeyMetricsHistory(messagesMetricsManager, cfgManager, false))
@@ -210,6 +229,7 @@ func main() {
 		apiGroup.PATCH("/responses/channels/:id/status", responses.SetChannelStatus(cfgManager))
 		apiGroup.POST("/responses/channels/:id/resume", handlers.ResumeChannel(channelScheduler, true))
 		apiGroup.POST("/responses/channels/:id/promotion", handlers.SetResponsesChannelPromotion(cfgManager))
+		apiGroup.PUT("/responses/loadbalance", responses.UpdateLoadBalance(cfgManager))
 		apiGroup.GET("/responses/channels/metrics", handlers.GetChannelMetricsWithConfig(responsesMetricsManager, cfgManager, true, requestLogStore))
 		apiGroup.GET("/responses/channels/metrics/history", handlers.GetChannelMetricsHistory(responsesMetricsManager, cfgManager, true))
 		apiGroup.GET("/responses/channels/:id/keys/metrics/history", handlers.GetChannelKeyMetricsHistory(responsesMetricsManager, cfgManager, true))
@@ -241,6 +261,28 @@ func main() {
 		apiGroup.GET("/gemini/ping/:id", gemini.PingChannel(cfgManager))
 		apiGroup.GET("/gemini/ping", gemini.PingAllChannels(cfgManager))
 
+		// Embeddings 渠道管理
+		apiGroup.GET("/embeddings/channels", embeddings.GetUpstreams(cfgManager))
+		apiGroup.POST("/embeddings/channels", embeddings.AddUpstream(cfgManager))
+		apiGroup.PUT("/embeddings/channels/:id", embeddings.UpdateUpstream(cfgManager, channelScheduler))
+		apiGroup.DELETE("/embeddings/channels/:id", embeddings.DeleteUpstream(cfgManager))
+		apiGroup.POST("/embeddings/channels/:id/keys", embeddings.AddApiKey(cfgManager))
+		apiGroup.DELETE("/embeddings/channels/:id/keys/:apiKey", embeddings.DeleteApiKey(cfgManager))
+		apiGroup.POST("/embeddings/channels/:id/keys/:apiKey/top", embeddings.MoveApiKeyToTop(cfgManager))
+		apiGroup.POST("/embeddings/channels/:id/keys/:apiKey/bottom", embeddings.MoveApiKeyToBottom(cfgManager))
+
+		// Embeddings 多渠道调度 API
+		apiGroup.POST("/embeddings/channels/reorder", embeddings.ReorderChannels(cfgManager))
+		apiGroup.PATCH("/embeddings/channels/:id/status", embeddings.SetChannelStatus(cfgManager))
+		apiGroup.POST("/embeddings/channels/:id/promotion", embeddings.SetChannelPromotion(cfgManager))
+		apiGroup.PUT("/embeddings/loadbalance", embeddings.UpdateLoadBalance(cfgManager))
+		apiGroup.GET("/embeddings/channels/metrics", handlers.GetEmbeddingsChannelMetrics(embeddingsMetricsManager, cfgManager, requestLogStore))
+		apiGroup.GET("/embeddings/channels/metrics/history", handlers.GetEmbeddingsChannelMetricsHistory(embeddingsMetricsManager, cfgManager))
+		apiGroup.GET("/embeddings/channels/:id/keys/metrics/history", handlers.GetEmbeddingsChannelKeyMetricsHistory(embeddingsMetricsManager, cfgManager))
+		apiGroup.GET("/embeddings/global/stats/history", handlers.GetGlobalStatsHistory(embeddingsMetricsManager))
+		apiGroup.GET("/embeddings/ping/:id", embeddings.PingChannel(cfgManager))
+		apiGroup.GET("/embeddings/ping", embeddings.PingAllChannels(cfgManager))
+
 		// Fuzzy 模式设置
 		apiGroup.GET("/settings/fuzzy-mode", handlers.GetFuzzyMode(cfgManager))
 		apiGroup.PUT("/settings/fuzzy-mode", handlers.SetFuzzyMode(cfgManager))
@@ -257,42 +299,51 @@ func main() {
 		responsesAPI.GET("/logs", requestLogsHandler.GetLogs)
 		responsesAPI.GET("/logs/:id", requestLogsHandler.GetLogDetail)
 		geminiAPI.GET("/logs", requestLogsHandler.GetLogs)
+		embeddingsAPI.GET("/logs", requestLogsHandler.GetLogs)
 		geminiAPI.GET("/logs/:id", requestLogsHandler.GetLogDetail)
+		embeddingsAPI.GET("/logs/:id", requestLogsHandler.GetLogDetail)
 
 		// Key 熔断日志 & 重置（每个 key 仅保留 1 条熔断时日志）
 		// 注意：/keys/:apiKey 已用于按 key 字符串操作；此处用 /keys/index/:keyIndex 避免 Gin 路由通配冲突
 		messagesAPI.GET("/channels/:id/keys/index/:keyIndex/circuit-log", handlers.GetKeyCircuitLog(keyCircuitLogStore, cfgManager, "messages"))
 		responsesAPI.GET("/channels/:id/keys/index/:keyIndex/circuit-log", handlers.GetKeyCircuitLog(keyCircuitLogStore, cfgManager, "responses"))
 		geminiAPI.GET("/channels/:id/keys/index/:keyIndex/circuit-log", handlers.GetKeyCircuitLog(keyCircuitLogStore, cfgManager, "gemini"))
+		embeddingsAPI.GET("/channels/:id/keys/index/:keyIndex/circuit-log", handlers.GetKeyCircuitLog(keyCircuitLogStore, cfgManager, "embeddings"))
 
 		messagesAPI.POST("/channels/:id/keys/index/:keyIndex/reset", handlers.ResetKeyCircuitState(channelScheduler, cfgManager, "messages", requestLogStore))
 		responsesAPI.POST("/channels/:id/keys/index/:keyIndex/reset", handlers.ResetKeyCircuitState(channelScheduler, cfgManager, "responses", requestLogStore))
 		geminiAPI.POST("/channels/:id/keys/index/:keyIndex/reset", handlers.ResetKeyCircuitState(channelScheduler, cfgManager, "gemini", requestLogStore))
+		embeddingsAPI.POST("/channels/:id/keys/index/:keyIndex/reset", handlers.ResetKeyCircuitState(channelScheduler, cfgManager, "embeddings", requestLogStore))
 
 		// 批量重置 Key 统计与状态（当前渠道下全部 key）
 		messagesAPI.POST("/channels/:id/keys/reset", handlers.ResetAllKeysCircuitState(channelScheduler, cfgManager, "messages", requestLogStore))
 		responsesAPI.POST("/channels/:id/keys/reset", handlers.ResetAllKeysCircuitState(channelScheduler, cfgManager, "responses", requestLogStore))
 		geminiAPI.POST("/channels/:id/keys/reset", handlers.ResetAllKeysCircuitState(channelScheduler, cfgManager, "gemini", requestLogStore))
+		embeddingsAPI.POST("/channels/:id/keys/reset", handlers.ResetAllKeysCircuitState(channelScheduler, cfgManager, "embeddings", requestLogStore))
 
 		messagesAPI.POST("/channels/:id/keys/index/:keyIndex/reset-state", handlers.ResetKeyCircuitStatus(channelScheduler, cfgManager, "messages"))
 		responsesAPI.POST("/channels/:id/keys/index/:keyIndex/reset-state", handlers.ResetKeyCircuitStatus(channelScheduler, cfgManager, "responses"))
 		geminiAPI.POST("/channels/:id/keys/index/:keyIndex/reset-state", handlers.ResetKeyCircuitStatus(channelScheduler, cfgManager, "gemini"))
+		embeddingsAPI.POST("/channels/:id/keys/index/:keyIndex/reset-state", handlers.ResetKeyCircuitStatus(channelScheduler, cfgManager, "embeddings"))
 
 		// 批量重置 Key 状态（当前渠道下全部 key）
 		messagesAPI.POST("/channels/:id/keys/reset-state", handlers.ResetAllKeysCircuitStatus(channelScheduler, cfgManager, "messages"))
 		responsesAPI.POST("/channels/:id/keys/reset-state", handlers.ResetAllKeysCircuitStatus(channelScheduler, cfgManager, "responses"))
 		geminiAPI.POST("/channels/:id/keys/reset-state", handlers.ResetAllKeysCircuitStatus(channelScheduler, cfgManager, "gemini"))
+		embeddingsAPI.POST("/channels/:id/keys/reset-state", handlers.ResetAllKeysCircuitStatus(channelScheduler, cfgManager, "embeddings"))
 
 		// Key 元信息（启用/禁用）
 		messagesAPI.PATCH("/channels/:id/keys/index/:keyIndex/meta", handlers.PatchAPIKeyMeta(cfgManager, "messages"))
 		responsesAPI.PATCH("/channels/:id/keys/index/:keyIndex/meta", handlers.PatchAPIKeyMeta(cfgManager, "responses"))
 		geminiAPI.PATCH("/channels/:id/keys/index/:keyIndex/meta", handlers.PatchAPIKeyMeta(cfgManager, "gemini"))
+		embeddingsAPI.PATCH("/channels/:id/keys/index/:keyIndex/meta", handlers.PatchAPIKeyMeta(cfgManager, "embeddings"))
 
 		// 实时请求 API
 		liveRequestsHandler := handlers.NewLiveRequestsHandler(liveRequestManager)
 		messagesAPI.GET("/live", liveRequestsHandler.GetLiveRequests)
 		responsesAPI.GET("/live", liveRequestsHandler.GetLiveRequests)
 		geminiAPI.GET("/live", liveRequestsHandler.GetLiveRequests)
+		embeddingsAPI.GET("/live", liveRequestsHandler.GetLiveRequests)
 	}
 
 	// 代理端点 - Messages API
@@ -300,6 +351,21 @@ func main() {
 	r.POST("/v1/messages", messagesHandler)
 	r.POST("/v1/messages/count_tokens", messages.CountTokensHandler(envCfg, cfgManager, channelScheduler))
 
+	// 代理端点 - Message Batches API（本地排队，逐条经 Messages 处理器重放）
+	batchEngine := gin.New()
+	batchEngine.Use(gin.Recovery())
+	batchEngine.POST("/v1/messages", messagesHandler)
+	batchManager, err := batch.NewManager(envCfg.BatchDir, envCfg.BatchConcurrency, batchEngine)
+	if err != nil {
+		log.Fatalf("[Batch-Init] 初始化批次管理器失败: %v", err)
+	}
+	r.POST("/v1/messages/batches", messages.CreateBatchHandler(envCfg, batchManager))
+	r.GET("/v1/messages/batches", messages.ListBatchesHandler(envCfg, batchManager))
+	r.GET("/v1/messages/batches/:id", messages.GetBatchHandler(envCfg, batchManager))
+	r.DELETE("/v1/messages/batches/:id", messages.DeleteBatchHandler(envCfg, batchManager))
+	r.POST("/v1/messages/batches/:id/cancel", messages.CancelBatchHandler(envCfg, batchManager))
+	r.GET("/v1/messages/batches/:id/results", messages.BatchResultsHandler(envCfg, batchManager))
+
 	// 代理端点 - Models API（转发到上游）
 	r.GET("/v1/models", messages.ModelsHandler(envCfg, cfgManager, channelScheduler, modelsResponseCache))
 	r.GET("/v1/models/:model", messages.ModelsDetailHandler(envCfg, cfgManager, channelScheduler))
@@ -307,13 +373,40 @@ func main() {
 	// 代理端点 - Responses API
 	responsesHandler := responses.NewHandler(envCfg, cfgManager, sessionManager, channelScheduler, billingClient, billingHandler, liveRequestManager, keyCircuitLogStore, requestLogStore)
 	r.POST("/v1/responses", responsesHandler)
+	r.GET("/v1/responses", responsesHandler) // WebSocket 传输
 	r.POST("/v1/responses/compact", responses.CompactHandler(envCfg, cfgManager, sessionManager, channelScheduler))
+	r.GET("/v1/responses/:id", responses.GetResponseHandler(envCfg, sessionManager))
+	r.DELETE("/v1/responses/:id", responses.DeleteResponseHandler(envCfg, sessionManager))
+	r.GET("/v1/responses/:id/input_items", responses.InputItemsHandler(envCfg, sessionManager))
+	r.POST("/v1/responses/:id/cancel", responses.CancelResponseHandler(envCfg, sessionManager))
+
+	// 代理端点 - OpenAI Chat Completions API（转换后复用 Messages/Responses 渠道池）
+	r.POST("/v1/chat/completions", chat.NewHandler(envCfg, channelScheduler, messagesHandler, responsesHandler))
+
+	// 代理端点 - OpenAI Embeddings API（独立渠道池）
+	r.POST("/v1/embeddings", embeddings.NewHandler(envCfg, cfgManager, channelScheduler, liveRequestManager, keyCircuitLogStore, requestLogStore))
 
 	// 代理端点 - Gemini API (原生协议)
 	// 使用通配符捕获 model:action 格式，如 gemini-pro:generateContent
 	// 路径格式：/v1beta/models/{model}:generateContent (Gemini 原生格式)
+	// 同时支持 countTokens / embedContent / batchEmbedContents
 	geminiHandler := gemini.NewHandler(envCfg, cfgManager, channelScheduler, liveRequestManager, keyCircuitLogStore, requestLogStore)
 	r.POST("/v1beta/models/*modelAction", geminiHandler)
+	r.GET("/v1beta/models", gemini.ModelsHandler(envCfg, cfgManager, channelScheduler, modelsResponseCache))
+	r.GET("/v1beta/models/*model", gemini.ModelsDetailHandler(envCfg, cfgManager, channelScheduler, modelsResponseCache))
+
+	// 代理端点 - Gemini Files API / cachedContents（透传到 Gemini 原生渠道，资源与 Key 保持亲和）
+	geminiResourceHandler := gemini.NewResourceHandler(envCfg, cfgManager, channelScheduler)
+	r.GET("/v1beta/files", geminiResourceHandler)
+	r.GET("/v1beta/files/*name", geminiResourceHandler)
+	r.DELETE("/v1beta/files/*name", geminiResourceHandler)
+	r.POST("/upload/v1beta/files", geminiResourceHandler)
+	r.PUT("/upload/v1beta/files", geminiResourceHandler)
+	r.GET("/v1beta/cachedContents", geminiResourceHandler)
+	r.POST("/v1beta/cachedContents", geminiResourceHandler)
+	r.GET("/v1beta/cachedContents/*name", geminiResourceHandler)
+	r.PATCH("/v1beta/cachedContents/*name", geminiResourceHandler)
+	r.DELETE("/v1beta/cachedContents/*name", geminiResourceHandler)
 
 	// 静态文件服务 (嵌入的前端)
 	if envCfg.EnableWebUI {
@@ -348,9 +441,17 @@ func main() {
 	fmt.Printf("[Server-Info] 管理界面: http://localhost:%d\n", envCfg.Port)
 	fmt.Printf("[Server-Info] API 地址: http://localhost:%d/v1\n", envCfg.Port)
 	fmt.Printf("[Server-Info] Claude Messages: POST /v1/messages\n")
+	fmt.Printf("[Server-Info] Claude Message Batches: /v1/messages/batches\n")
 	fmt.Printf("[Server-Info] Codex Responses: POST /v1/responses\n")
+	fmt.Printf("[Server-Info] Codex Responses WebSocket: GET /v1/responses (Upgrade: websocket)\n")
+	fmt.Printf("[Server-Info] OpenAI Chat: POST /v1/chat/completions\n")
+	fmt.Printf("[Server-Info] OpenAI Embeddings: POST /v1/embeddings\n")
 	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:generateContent\n")
 	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:streamGenerateContent\n")
+	fmt.Printf("[Server-Info] Gemini API: POST /v1beta/models/{model}:countTokens|embedContent|batchEmbedContents\n")
+	fmt.Printf("[Server-Info] Gemini Models: GET /v1beta/models, GET /v1beta/models/{model}\n")
+	fmt.Printf("[Server-Info] Gemini Files: /v1beta/files, /upload/v1beta/files\n")
+	fmt.Printf("[Server-Info] Gemini cachedContents: /v1beta/cachedContents\n")
 	fmt.Printf("[Server-Info] 健康检查: GET /health\n")
 	fmt.Printf("[Server-Info] 环境: %s\n", envCfg.Env)
 	// 计费模式提示
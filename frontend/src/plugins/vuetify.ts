@@ -106,6 +106,9 @@ import {
   mdiPulse,
   mdiFormatListBulleted,
   mdiViewDashboard,
+  mdiScaleBalance,
+  mdiWeight,
+  mdiFilterOutline,
 } from '@mdi/js'
 
 // 图标名称到 SVG path 的映射 (使用 kebab-case)
@@ -221,6 +224,9 @@ const iconMap: Record<string, string> = {
   'clock-outline': mdiClockOutline,
   'paperclip': mdiPaperclip,
   'eye-dropper': mdiEyedropper,
+  'scale-balance': mdiScaleBalance,
+  'weight': mdiWeight,
+  'filter-outline': mdiFilterOutline,
 
   // 主题切换
   'weather-night': mdiWeatherNight,
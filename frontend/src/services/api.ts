@@ -52,6 +52,23 @@ export interface TimeWindowStats {
   cacheCreationTokens?: number
   cacheReadTokens?: number
   cacheHitRate?: number
+  costCents?: number         // 按官方价格估算的成本（美分）
+}
+
+// 按渠道价格倍率估算的成本节省（美分，统计窗口见 stats.savingsWindow）
+export interface CostSavings {
+  costCents: number
+  effectiveCostCents: number
+  savingsCents: number
+}
+
+// 首字节时间（TTFB）统计
+export interface LatencyStats {
+  sampleCount: number
+  ewmaMs: number
+  p50Ms: number
+  p95Ms: number
+  lastSampleAt?: string
 }
 
 export interface KeyMetrics {
@@ -67,10 +84,12 @@ export interface KeyMetrics {
   circuitBroken: boolean
   suspendUntil?: string
   suspendReason?: string
+  ttfb?: LatencyStats
 }
 
 export interface APIKeyMeta {
   disabled?: boolean
+  maxConcurrency?: number // Key 最大并发数（0 或未设置表示不限制）
   description?: string
 }
 
@@ -92,13 +111,17 @@ export interface ChannelMetrics {
     '6h': TimeWindowStats
     '24h': TimeWindowStats
   }
+  // 首字节时间统计（latency 负载均衡依据）
+  ttfb?: LatencyStats
+  // 成本节省估算（相对官方价格）
+  costSavings?: CostSavings
   // Key 级指标（按配置 key 顺序）
   keyMetrics?: KeyMetrics[]
 }
 
 export interface Channel {
   name: string
-  serviceType: 'openai' | 'gemini' | 'claude' | 'responses'
+  serviceType: 'openai' | 'gemini' | 'claude' | 'responses' | 'completions' | 'bedrock' | 'azure-openai' | 'vertex'
   baseUrl: string
   baseUrls?: string[]                // 多 BaseURL 支持（failover 模式）
   apiKeys: string[]
@@ -107,6 +130,7 @@ export interface Channel {
   website?: string
   insecureSkipVerify?: boolean
   modelMapping?: Record<string, string>
+  supportedModels?: string[] // 可服务的模型（通配符或 /正则/，按重定向后的模型匹配，为空表示全部）
   latency?: number
   status?: ChannelStatus | ''
   health?: 'healthy' | 'error' | 'unknown'
@@ -114,11 +138,15 @@ export interface Channel {
   pinned?: boolean
   // 多渠道调度相关字段
   priority?: number          // 渠道优先级（数字越小优先级越高）
+  weight?: number            // 渠道权重（weighted 负载均衡模式下按权重分配流量）
+  priceMultiplier?: number   // 价格倍率（相对官方价格，如 0.3 表示三折；cheapest 模式依据）
+  maxConcurrency?: number    // 渠道最大并发数（所有 Key 合计，0 或未设置表示不限制）
   metrics?: ChannelMetrics   // 实时指标
   suspendReason?: string     // 熔断原因
   promotionUntil?: string    // 促销期截止时间（ISO 格式）
   latencyTestTime?: number   // 延迟测试时间戳（用于 5 分钟后自动清除显示）
   lowQuality?: boolean       // 低质量渠道标记：启用后强制本地估算 token，偏差>5%时使用本地值
+  apiVersion?: string        // Azure OpenAI 特定：api-version 查询参数
   injectDummyThoughtSignature?: boolean  // Gemini 特定：为 functionCall 注入 dummy thoughtSignature（兼容第三方 API）
   stripThoughtSignature?: boolean        // Gemini 特定：移除 thoughtSignature 字段（兼容旧版 Gemini API）
 }
@@ -135,6 +163,7 @@ export interface GlobalModelMappingSettings {
 
 export interface GlobalReasoningMappingSettings {
   globalReasoningMapping: Record<string, string>
+  globalReasoningBudgets?: Record<string, number> // effort 档位 → 思考预算（跨协议转换使用）
 }
 
 // 渠道仪表盘响应（合并 channels + metrics + stats）
@@ -150,6 +179,8 @@ export interface ChannelDashboardResponse {
     failureThreshold: number
     windowSize: number
     circuitRecoveryTime: string
+    estimatedSavingsCents?: number  // 全部渠道相对官方价格的节省估算（美分）
+    savingsWindow?: string
   }
   recentActivity?: ChannelRecentActivity[]  // 最近 15 分钟分段活跃度
 }
@@ -358,11 +389,15 @@ export interface LiveRequest {
   startTime: string
   apiType: string
   isStreaming: boolean
+  queuedAt?: string    // 所有槽位并发已满、正在排队时的入队时间
+  queueWaitMs?: number // 获得槽位前的排队时长
 }
 
 export interface LiveRequestsResponse {
   requests: LiveRequest[]
   count: number
+  queueDepth: number     // 正在排队等待槽位的请求数
+  maxQueueWaitMs: number // 排队中请求的最长已等待时间
 }
 
 // ============== 上游模型列表类型 ==============
@@ -384,6 +419,7 @@ export interface ProbeUpstreamModelsResponse {
   statusCode?: number
   upstreamError?: string
   models?: ModelsResponse
+  supportedModelsUpdated?: boolean // 已将探测结果写入渠道 supportedModels
 }
 
 /**
@@ -765,11 +801,22 @@ class ApiService {
   async probeUpstreamModels(
     baseUrl: string,
     apiKey: string,
-    opts?: { insecureSkipVerify?: boolean }
+    opts?: {
+      insecureSkipVerify?: boolean
+      // 同时将探测结果写入指定渠道的 supportedModels
+      fillSupportedModels?: { channelType: ApiType | 'embeddings'; channelIndex: number }
+    }
   ): Promise<ProbeUpstreamModelsResponse> {
     return this.request('/admin/upstream/models', {
       method: 'POST',
-      body: JSON.stringify({ baseUrl, apiKey, insecureSkipVerify: opts?.insecureSkipVerify })
+      body: JSON.stringify({
+        baseUrl,
+        apiKey,
+        insecureSkipVerify: opts?.insecureSkipVerify,
+        fillSupportedModels: opts?.fillSupportedModels ? true : undefined,
+        channelType: opts?.fillSupportedModels?.channelType,
+        channelIndex: opts?.fillSupportedModels?.channelIndex
+      })
     })
   }
 
@@ -780,6 +827,13 @@ class ApiService {
     })
   }
 
+  async setAPIKeyMaxConcurrency(apiType: ApiType, channelId: number, keyIndex: number, maxConcurrency: number): Promise<void> {
+    await this.request(`/${apiType}/channels/${channelId}/keys/index/${keyIndex}/meta`, {
+      method: 'PATCH',
+      body: JSON.stringify({ maxConcurrency })
+    })
+  }
+
   // ============== Responses 多渠道调度 API ==============
 
   // 重新排序 Responses 渠道优先级
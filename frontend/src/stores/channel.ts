@@ -57,19 +57,19 @@ export const useChannelStore = defineStore('channel', () => {
   const channelsData = ref<ChannelsResponse>({
     channels: [],
     current: -1,
-    loadBalance: 'round-robin'
+    loadBalance: 'failover'
   })
 
   const responsesChannelsData = ref<ChannelsResponse>({
     channels: [],
     current: -1,
-    loadBalance: 'round-robin'
+    loadBalance: 'failover'
   })
 
   const geminiChannelsData = ref<ChannelsResponse>({
     channels: [],
     current: -1,
-    loadBalance: 'round-robin'
+    loadBalance: 'failover'
   })
 
   // Dashboard 数据缓存结构（每个 tab 独立缓存）
@@ -464,17 +464,17 @@ export const useChannelStore = defineStore('channel', () => {
     channelsData.value = {
       channels: [],
       current: -1,
-      loadBalance: 'round-robin'
+      loadBalance: 'failover'
     }
     responsesChannelsData.value = {
       channels: [],
       current: -1,
-      loadBalance: 'round-robin'
+      loadBalance: 'failover'
     }
     geminiChannelsData.value = {
       channels: [],
       current: -1,
-      loadBalance: 'round-robin'
+      loadBalance: 'failover'
     }
 
     // 清空所有 tab 的独立缓存